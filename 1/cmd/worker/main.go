@@ -0,0 +1,24 @@
+// entry point to notification worker
+package main
+
+import (
+	"github.com/ds124wfegd/WB_L3/1/config"
+	"github.com/ds124wfegd/WB_L3/1/internal/appServer"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logrus.SetFormatter(new(logrus.JSONFormatter))
+
+	viperInstance, err := config.LoadConfig()
+	if err != nil {
+		logrus.Fatalf("Cannot load config. Error: {%s}", err.Error())
+	}
+
+	cfg, err := config.ParseConfig(viperInstance)
+	if err != nil {
+		logrus.Fatalf("Cannot parse config. Error: {%s}", err.Error())
+	}
+
+	appServer.NewWorker(cfg)
+}
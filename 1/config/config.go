@@ -1,90 +1,136 @@
-// Ininicializing common application configuration
-package config
-
-import (
-	"log"
-	"os"
-	"time"
-
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Server ServerConfig
-	Redis  RedisConfig
-	Rabbit RabbitMQConfig
-}
-
-type ServerConfig struct {
-	AppVersion   string `json:"appVersion"`
-	Host         string `json:"host" validate:"required"`
-	Port         string `json:"port" validate:"required"`
-	Timeout      time.Duration
-	Idle_timeout time.Duration
-	Env          string `json:"environment"`
-}
-
-type RedisConfig struct {
-	URL      string `json:"URL"`
-	Host     string `json:"host" validate:"required"`
-	Port     int    `json:"port" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	DB       int    `json:"db" validate:"required"`
-
-	// Настройки пула соединений
-	MaxRetries   int
-	PoolSize     int
-	MinIdleConns int
-	MaxIdleConns int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	PoolTimeout  time.Duration
-	IdleTimeout  time.Duration
-}
-
-type RabbitMQConfig struct {
-	URL          string `json:"url"`
-	Host         string `json:"host"`
-	Port         int    `json:"port"`
-	Username     string `json:"username"`
-	Password     string `json:"password"`
-	ExchangeName string `json:"exchange_name"`
-	QueueName    string `json:"queue_name"`
-	VirtualHost  string `json:"virtual_host"`
-}
-
-func LoadConfig() (*viper.Viper, error) {
-
-	viperInstance := viper.New()
-
-	viperInstance.AddConfigPath("./config")
-	viperInstance.SetConfigName("config")
-	viperInstance.SetConfigType("yaml")
-
-	err := viperInstance.ReadInConfig()
-
-	if err != nil {
-		return nil, err
-	}
-	return viperInstance, nil
-}
-
-func ParseConfig(v *viper.Viper) (*Config, error) {
-
-	var c Config
-
-	err := v.Unmarshal(&c)
-	if err != nil {
-		log.Fatalf("unable to decode config into struct, %v", err)
-		return nil, err
-	}
-	return &c, nil
-}
-
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+// Ininicializing common application configuration
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Server      ServerConfig
+	Redis       RedisConfig
+	Rabbit      RabbitMQConfig
+	Unsubscribe UnsubscribeConfig
+	Worker      WorkerConfig
+	Import      ImportConfig
+}
+
+type ServerConfig struct {
+	AppVersion   string `json:"appVersion"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required"`
+	Timeout      time.Duration
+	Idle_timeout time.Duration
+	Env          string `json:"environment"`
+}
+
+type RedisConfig struct {
+	URL      string `json:"URL"`
+	Host     string `json:"host" validate:"required"`
+	Port     int    `json:"port" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	DB       int    `json:"db" validate:"required"`
+
+	// Mode - single (host и port), sentinel (master_name и addrs сентинелов)
+	// или cluster (addrs узлов кластера)
+	Mode             string   `mapstructure:"mode"`
+	MasterName       string   `mapstructure:"master_name"`
+	Addrs            []string `mapstructure:"addrs"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+	// Чтение с реплик: route_by_latency - с ближайшего узла, route_randomly - со случайного,
+	// read_only - с реплик слота (только cluster). Запись всегда идёт на мастер
+	ReadOnly       bool `mapstructure:"read_only"`
+	RouteByLatency bool `mapstructure:"route_by_latency"`
+	RouteRandomly  bool `mapstructure:"route_randomly"`
+
+	// Настройки пула соединений
+	MaxRetries   int
+	PoolSize     int
+	MinIdleConns int
+	MaxIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+	IdleTimeout  time.Duration
+}
+
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+type RabbitMQConfig struct {
+	URL          string `json:"url"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	ExchangeName string `json:"exchange_name"`
+	QueueName    string `json:"queue_name"`
+	VirtualHost  string `json:"virtual_host"`
+}
+
+// UnsubscribeConfig задает ключ подписи, адрес и срок действия ссылок отписки
+type UnsubscribeConfig struct {
+	Secret  string        `mapstructure:"secret"`
+	BaseURL string        `mapstructure:"base_url"`
+	TTL     time.Duration `mapstructure:"ttl"` // по умолчанию 90 дней
+}
+
+// WorkerConfig - настройки бинарника worker: планировщик делится на шарды,
+// которые экземпляры разбирают между собой через аренды в Redis
+type WorkerConfig struct {
+	InstanceID   string        `mapstructure:"instance_id"`   // по умолчанию hostname
+	Shards       int           `mapstructure:"shards"`        // общее число шардов, одинаковое у всех экземпляров
+	LeaseTTL     time.Duration `mapstructure:"lease_ttl"`     // время жизни аренды шарда без продления
+	ScanInterval time.Duration `mapstructure:"scan_interval"` // период проверки просроченных уведомлений
+	MetricsAddr  string        `mapstructure:"metrics_addr"`  // адрес /metrics, пусто - не запускать
+	// ShutdownTimeout - сколько при остановке ждать начатые отправки, по умолчанию 30s
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// ImportConfig - ограничения загрузки файла для POST /api/v1/notify/import
+type ImportConfig struct {
+	MaxFileSize int64         `mapstructure:"max_file_size"` // байт, по умолчанию 64 МБ
+	Timeout     time.Duration `mapstructure:"timeout"`       // на чтение и обработку файла вместо общего таймаута сервера
+}
+
+func LoadConfig() (*viper.Viper, error) {
+
+	viperInstance := viper.New()
+
+	viperInstance.AddConfigPath("./config")
+	viperInstance.SetConfigName("config")
+	viperInstance.SetConfigType("yaml")
+
+	err := viperInstance.ReadInConfig()
+
+	if err != nil {
+		return nil, err
+	}
+	return viperInstance, nil
+}
+
+func ParseConfig(v *viper.Viper) (*Config, error) {
+
+	var c Config
+
+	err := v.Unmarshal(&c)
+	if err != nil {
+		log.Fatalf("unable to decode config into struct, %v", err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,188 @@
+// launching the worker: RabbitMQ consumer and sharded scheduler
+package appServer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/config"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/rabbitMQ"
+	"github.com/ds124wfegd/WB_L3/1/internal/scheduler"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// NewWorker запускает доставку уведомлений: потребитель RabbitMQ отправляет уведомления,
+// срок которых наступил, а планировщик добирает пропущенные. Планировщик делит уведомления
+// на шарды, поэтому экземпляры worker можно добавлять независимо от API.
+func NewWorker(cfg *config.Config) {
+
+	logrus.SetFormatter(new(logrus.JSONFormatter))
+
+	deps := newDependencies(cfg)
+	defer deps.Close()
+
+	instanceID := workerInstanceID(cfg)
+	coordinator := scheduler.NewCoordinator(deps.redisClient, instanceID, cfg.Worker.Shards, cfg.Worker.LeaseTTL)
+
+	// ctx останавливает получение новой работы, sendCtx прерывает уже начатые отправки:
+	// при остановке сначала отменяется ctx, а sendCtx - только если отправки не успели за срок
+	ctx, stopPulling := context.WithCancel(context.Background())
+	sendCtx, abortSends := context.WithCancel(context.Background())
+	defer abortSends()
+	var wg sync.WaitGroup
+
+	if err := deps.rabbitMQ.Consume(ctx, deliveryHandler(sendCtx, deps.notifications)); err != nil {
+		logrus.Fatalf("Failed to consume RabbitMQ queue: %s", err.Error())
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		coordinator.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		startBackgroundProcessor(ctx, sendCtx, deps.notifications, coordinator, cfg.Worker.ScanInterval)
+	}()
+
+	metricsServer := startMetricsServer(cfg.Worker.MetricsAddr, deps)
+
+	logrus.Printf("Worker %s Started", instanceID)
+
+	waitForSignal()
+
+	logrus.Print("Worker Shutting Down")
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(context.Background()); err != nil {
+			logrus.Errorf("error occured on metrics server shutting down: %s", err.Error())
+		}
+	}
+
+	stopPulling()
+
+	shutdownTimeout := cfg.Worker.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	if !drain(shutdownTimeout, &wg, deps.rabbitMQ) {
+		// Прерванные отправки оставляют уведомления pending, их сообщения возвращаются в очередь
+		logrus.Warnf("In-flight deliveries did not finish in %s, aborting them", shutdownTimeout)
+		abortSends()
+		if !drain(abortGracePeriod, &wg, deps.rabbitMQ) {
+			logrus.Errorf("Deliveries did not stop after abort, exiting anyway")
+		}
+	}
+}
+
+// Сроки остановки worker: сколько по умолчанию ждать начатые отправки и сколько - их
+// завершения после прерывания
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	abortGracePeriod       = 5 * time.Second
+)
+
+// drain ждет, пока потребитель RabbitMQ и фоновые циклы закончат начатую работу;
+// false - не закончили за timeout
+func drain(timeout time.Duration, wg *sync.WaitGroup, consumer *rabbitMQ.RabbitMQ) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if err := consumer.Drain(ctx); err != nil {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// startMetricsServer отдает метрики доставки на /metrics; без адреса сервер не запускается
+func startMetricsServer(addr string, deps *dependencies) *http.Server {
+	if addr == "" {
+		return nil
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	registry.MustRegister(deps.deliveryStats.Collectors()...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 3 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("error occured while running metrics server: %s", err.Error())
+		}
+	}()
+
+	return server
+}
+
+// deliveryHandler подтверждает сообщения, которые нельзя обработать, чтобы они не
+// возвращались в очередь бесконечно; ошибки хранилища приводят к повторной доставке
+func deliveryHandler(ctx context.Context, useCase service.NotificationUseCase) func(message []byte) error {
+	return func(message []byte) error {
+		var notification entity.Notification
+		if err := json.Unmarshal(message, &notification); err != nil || notification.ID == "" {
+			logrus.Errorf("Skipping malformed notification message: %s", string(message))
+			return nil
+		}
+
+		return useCase.DeliverNotification(ctx, notification.ID)
+	}
+}
+
+// startBackgroundProcessor добирает просроченные уведомления, пока не отменен ctx. Отправки
+// идут в sendCtx, а после отмены ctx оставшиеся в скане уведомления не берутся: их отправит
+// следующий экземпляр
+func startBackgroundProcessor(ctx, sendCtx context.Context, useCase service.NotificationUseCase, coordinator *scheduler.Coordinator, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			owns := func(id string) bool {
+				return ctx.Err() == nil && coordinator.Owns(id)
+			}
+			if err := useCase.ProcessScheduledNotifications(sendCtx, owns); err != nil {
+				logrus.Errorf("Error processing scheduled notifications: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func workerInstanceID(cfg *config.Config) string {
+	if cfg.Worker.InstanceID != "" {
+		return cfg.Worker.InstanceID
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return uuid.New().String()
+}
@@ -0,0 +1,108 @@
+// Package channel - провайдеры доставки уведомлений (email, Telegram и т.п.)
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+// Sender отправляет уведомление через конкретного провайдера
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, notification *entity.Notification, message string) error
+}
+
+// ThrottledError - провайдер отклонил отправку из-за превышения частоты.
+// RetryAfter - задержка, которую провайдер просит выждать; 0, если подсказки не было.
+type ThrottledError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: rate limited, retry after %s", e.Provider, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s: rate limited", e.Provider)
+}
+
+// AsThrottled достает ThrottledError из цепочки ошибок
+func AsThrottled(err error) (*ThrottledError, bool) {
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		return throttled, true
+	}
+	return nil, false
+}
+
+// CheckResponse превращает ответ HTTP API провайдера в ошибку. На 429 задержка берется
+// из заголовка Retry-After или из поля parameters.retry_after тела ответа (формат Bot API Telegram).
+func CheckResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := parseRetryAfterHeader(resp.Header.Get("Retry-After"), time.Now())
+		if retryAfter == 0 {
+			retryAfter = parseTelegramRetryAfter(body)
+		}
+		return &ThrottledError{Provider: provider, RetryAfter: retryAfter}
+	}
+
+	return fmt.Errorf("%s: unexpected status %d: %s", provider, resp.StatusCode, string(body))
+}
+
+// parseRetryAfterHeader разбирает Retry-After в секундах или в виде HTTP-даты
+func parseRetryAfterHeader(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// parseTelegramRetryAfter читает {"ok":false,"error_code":429,"parameters":{"retry_after":N}}
+func parseTelegramRetryAfter(body []byte) time.Duration {
+	var resp struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Parameters.RetryAfter <= 0 {
+		return 0
+	}
+	return time.Duration(resp.Parameters.RetryAfter) * time.Second
+}
+
+// LogSender печатает уведомления в stdout вместо реальной отправки
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Name() string {
+	return "log"
+}
+
+func (s *LogSender) Send(ctx context.Context, notification *entity.Notification, message string) error {
+	fmt.Printf("Sending notification to user %s: %s - %s\n",
+		notification.UserID, notification.Title, message)
+	return nil
+}
@@ -0,0 +1,45 @@
+package channel
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics считает результаты отправки по провайдерам
+type Metrics struct {
+	throttled *prometheus.CounterVec
+	retries   *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_provider_throttled_total",
+			Help: "Number of sends rejected by a provider due to rate limiting.",
+		}, []string{"provider"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notifier_delivery_retries_total",
+			Help: "Number of notifications rescheduled after a failed send.",
+		}, []string{"provider", "reason"}),
+	}
+}
+
+// Collectors возвращает метрики для регистрации в реестре Prometheus
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.throttled, m.retries}
+}
+
+// Throttled учитывает отказ провайдера из-за превышения частоты. Безопасен для nil.
+func (m *Metrics) Throttled(provider string) {
+	if m == nil {
+		return
+	}
+	m.throttled.WithLabelValues(provider).Inc()
+}
+
+// Retried учитывает повторную постановку уведомления: reason - throttled или error
+func (m *Metrics) Retried(provider, reason string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(provider, reason).Inc()
+}
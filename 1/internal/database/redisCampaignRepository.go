@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Поля хеша счетчиков кампании: <вариант>:<счетчик>
+const (
+	statAssigned  = "assigned"
+	statDelivered = "delivered"
+	statRead      = "read"
+)
+
+type redisCampaignRepository struct {
+	client redis.UniversalClient
+}
+
+func NewRedisCampaignRepository(client redis.UniversalClient) CampaignRepository {
+	return &redisCampaignRepository{client: client}
+}
+
+func campaignKey(id string) string {
+	return fmt.Sprintf("campaign:%s", id)
+}
+
+// campaignAssignmentsKey - хеш пользователь -> выданный вариант
+func campaignAssignmentsKey(id string) string {
+	return fmt.Sprintf("campaign_assignments:%s", id)
+}
+
+// campaignStatsKey - хеш счетчиков вариантов
+func campaignStatsKey(id string) string {
+	return fmt.Sprintf("campaign_stats:%s", id)
+}
+
+// campaignReadsKey - множество уже учтенных прочитанных уведомлений
+func campaignReadsKey(id string) string {
+	return fmt.Sprintf("campaign_reads:%s", id)
+}
+
+// campaignTranslationsKey - хеш <язык>:<вариант> -> JSON переведенного шаблона
+func campaignTranslationsKey(id string) string {
+	return fmt.Sprintf("campaign_translations:%s", id)
+}
+
+func statField(variant, stat string) string {
+	return variant + ":" + stat
+}
+
+func (r *redisCampaignRepository) Create(ctx context.Context, campaign *entity.Campaign) error {
+	data, err := json.Marshal(campaign)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, campaignKey(campaign.ID), data, 0).Err()
+}
+
+func (r *redisCampaignRepository) GetByID(ctx context.Context, id string) (*entity.Campaign, error) {
+	data, err := r.client.Get(ctx, campaignKey(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var campaign entity.Campaign
+	err = json.Unmarshal([]byte(data), &campaign)
+	return &campaign, err
+}
+
+func (r *redisCampaignRepository) Update(ctx context.Context, campaign *entity.Campaign) error {
+	return r.Create(ctx, campaign)
+}
+
+func (r *redisCampaignRepository) AssignVariant(ctx context.Context, campaignID, userID, variant string) (string, error) {
+	key := campaignAssignmentsKey(campaignID)
+
+	assigned, err := r.client.HSetNX(ctx, key, userID, variant).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to assign variant: %w", err)
+	}
+	if assigned {
+		return variant, nil
+	}
+
+	existing, err := r.client.HGet(ctx, key, userID).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get assigned variant: %w", err)
+	}
+	return existing, nil
+}
+
+func (r *redisCampaignRepository) IncrementAssigned(ctx context.Context, campaignID, variant string) error {
+	return r.client.HIncrBy(ctx, campaignStatsKey(campaignID), statField(variant, statAssigned), 1).Err()
+}
+
+func (r *redisCampaignRepository) IncrementDelivered(ctx context.Context, campaignID, variant string) error {
+	return r.client.HIncrBy(ctx, campaignStatsKey(campaignID), statField(variant, statDelivered), 1).Err()
+}
+
+func (r *redisCampaignRepository) RecordRead(ctx context.Context, campaignID, variant, notificationID string) (bool, error) {
+	added, err := r.client.SAdd(ctx, campaignReadsKey(campaignID), notificationID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record read: %w", err)
+	}
+	if added == 0 {
+		return false, nil
+	}
+
+	if err := r.client.HIncrBy(ctx, campaignStatsKey(campaignID), statField(variant, statRead), 1).Err(); err != nil {
+		return false, fmt.Errorf("failed to record read: %w", err)
+	}
+	return true, nil
+}
+
+func (r *redisCampaignRepository) GetStats(ctx context.Context, campaignID string) (*entity.CampaignStats, error) {
+	values, err := r.client.HGetAll(ctx, campaignStatsKey(campaignID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign stats: %w", err)
+	}
+
+	counter := func(variant, stat string) int64 {
+		value, _ := strconv.ParseInt(values[statField(variant, stat)], 10, 64)
+		return value
+	}
+
+	stats := &entity.CampaignStats{CampaignID: campaignID}
+	for _, variant := range entity.Variants {
+		stats.Variants = append(stats.Variants, &entity.CampaignVariantStats{
+			Variant:   variant,
+			Assigned:  counter(variant, statAssigned),
+			Delivered: counter(variant, statDelivered),
+			Read:      counter(variant, statRead),
+		})
+	}
+
+	return stats, nil
+}
+
+func (r *redisCampaignRepository) SetTranslation(ctx context.Context, campaignID, language string, templates map[string]entity.CampaignTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(templates))
+	for variant, template := range templates {
+		data, err := json.Marshal(template)
+		if err != nil {
+			return err
+		}
+		values[language+":"+variant] = data
+	}
+
+	if err := r.client.HSet(ctx, campaignTranslationsKey(campaignID), values).Err(); err != nil {
+		return fmt.Errorf("failed to save translation: %w", err)
+	}
+	return nil
+}
+
+func (r *redisCampaignRepository) GetTranslations(ctx context.Context, campaignID string) (entity.CampaignTranslations, error) {
+	values, err := r.client.HGetAll(ctx, campaignTranslationsKey(campaignID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get translations: %w", err)
+	}
+
+	translations := make(entity.CampaignTranslations)
+	for field, value := range values {
+		language, variant, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		var template entity.CampaignTemplate
+		if err := json.Unmarshal([]byte(value), &template); err != nil {
+			continue
+		}
+		if translations[language] == nil {
+			translations[language] = make(map[string]entity.CampaignTemplate, len(entity.Variants))
+		}
+		translations[language][variant] = template
+	}
+
+	return translations, nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// importTTL - сколько хранятся отчет импорта и ошибки строк для скачивания
+const importTTL = 7 * 24 * time.Hour
+
+type redisImportRepository struct {
+	client redis.UniversalClient
+}
+
+func NewRedisImportRepository(client redis.UniversalClient) ImportRepository {
+	return &redisImportRepository{client: client}
+}
+
+// importKey не попадает под шаблон notification:*, по которому ищутся уведомления
+func importKey(id string) string {
+	return fmt.Sprintf("notify_import:%s", id)
+}
+
+// importErrorsKey - список ошибок строк в JSON, в порядке строк файла
+func importErrorsKey(id string) string {
+	return fmt.Sprintf("notify_import_errors:%s", id)
+}
+
+func (r *redisImportRepository) SaveReport(ctx context.Context, report *entity.ImportReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, importKey(report.ID), data, importTTL)
+	pipe.Expire(ctx, importErrorsKey(report.ID), importTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *redisImportRepository) GetReport(ctx context.Context, id string) (*entity.ImportReport, error) {
+	data, err := r.client.Get(ctx, importKey(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var report entity.ImportReport
+	err = json.Unmarshal([]byte(data), &report)
+	return &report, err
+}
+
+func (r *redisImportRepository) AppendErrors(ctx context.Context, id string, rows []entity.ImportRowError) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+
+	key := importErrorsKey(id)
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, importTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *redisImportRepository) GetErrors(ctx context.Context, id string, offset, limit int64) ([]entity.ImportRowError, error) {
+	values, err := r.client.LRange(ctx, importErrorsKey(id), offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]entity.ImportRowError, 0, len(values))
+	for _, value := range values {
+		var row entity.ImportRowError
+		if err := json.Unmarshal([]byte(value), &row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
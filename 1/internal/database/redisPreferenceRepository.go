@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type redisPreferenceRepository struct {
+	client redis.UniversalClient
+}
+
+func NewRedisPreferenceRepository(client redis.UniversalClient) PreferenceRepository {
+	return &redisPreferenceRepository{client: client}
+}
+
+// preferencesKey - хеш категория -> "1"/"0" с подписками пользователя
+func preferencesKey(userID string) string {
+	return fmt.Sprintf("preferences:%s", userID)
+}
+
+// legacyOptOutKey - множество категорий, от которых пользователь отписался до появления хеша настроек
+func legacyOptOutKey(userID string) string {
+	return fmt.Sprintf("optout:%s", userID)
+}
+
+// languageKey - выбранный пользователем язык уведомлений
+func languageKey(userID string) string {
+	return fmt.Sprintf("language:%s", userID)
+}
+
+func (r *redisPreferenceRepository) GetPreferences(ctx context.Context, userID string) (map[string]bool, error) {
+	if err := r.migrateLegacyOptOut(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	values, err := r.client.HGetAll(ctx, preferencesKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	categories := make(map[string]bool, len(values))
+	for category, value := range values {
+		optIn, err := strconv.ParseBool(value)
+		if err != nil {
+			continue
+		}
+		categories[category] = optIn
+	}
+
+	return categories, nil
+}
+
+func (r *redisPreferenceRepository) SetPreferences(ctx context.Context, userID string, categories map[string]bool) error {
+	if len(categories) == 0 {
+		return nil
+	}
+
+	// Старые отписки переносятся до записи, иначе новый хеш их бы скрыл
+	if err := r.migrateLegacyOptOut(ctx, userID); err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(categories))
+	for category, optIn := range categories {
+		values[category] = strconv.FormatBool(optIn)
+	}
+
+	if err := r.client.HSet(ctx, preferencesKey(userID), values).Err(); err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
+
+// migrateLegacyOptOut переносит отписки из множества optout:<user> в хеш настроек и удаляет множество.
+// Уже сохраненные в хеше значения не перезаписываются: они заданы позже старых отписок.
+func (r *redisPreferenceRepository) migrateLegacyOptOut(ctx context.Context, userID string) error {
+	legacy, err := r.client.SMembers(ctx, legacyOptOutKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get legacy opt-outs: %w", err)
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	pipe := r.client.TxPipeline()
+	for _, category := range legacy {
+		category = entity.NormalizeCategory(category)
+		if entity.IsOptionalCategory(category) {
+			pipe.HSetNX(ctx, preferencesKey(userID), category, strconv.FormatBool(false))
+		}
+	}
+	pipe.Del(ctx, legacyOptOutKey(userID))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to migrate legacy opt-outs: %w", err)
+	}
+	return nil
+}
+
+func (r *redisPreferenceRepository) GetLanguage(ctx context.Context, userID string) (string, error) {
+	language, err := r.client.Get(ctx, languageKey(userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get language: %w", err)
+	}
+	return language, nil
+}
+
+func (r *redisPreferenceRepository) SetLanguage(ctx context.Context, userID, language string) error {
+	if err := r.client.Set(ctx, languageKey(userID), language, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save language: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestPreferenceRepository(t *testing.T) (PreferenceRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisPreferenceRepository(client), mr
+}
+
+// TestGetPreferencesMigratesLegacyOptOut проверяет, что отписки из старого множества optout:<user>
+// переносятся в хеш настроек, а "general" становится отпиской от категории по умолчанию
+func TestGetPreferencesMigratesLegacyOptOut(t *testing.T) {
+	repo, mr := newTestPreferenceRepository(t)
+	ctx := context.Background()
+
+	mr.SAdd(legacyOptOutKey("user-1"), "general", entity.CategoryMarketing)
+
+	stored, err := repo.GetPreferences(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if optIn, ok := stored[entity.DefaultCategory]; !ok || optIn {
+		t.Fatalf("legacy general opt-out should disable %s, got %v", entity.DefaultCategory, stored)
+	}
+	if optIn, ok := stored[entity.CategoryMarketing]; !ok || optIn {
+		t.Fatalf("legacy marketing opt-out was lost, got %v", stored)
+	}
+	if mr.Exists(legacyOptOutKey("user-1")) {
+		t.Fatal("legacy opt-out set should be removed after migration")
+	}
+}
+
+// TestSetPreferencesKeepsNewerChoice проверяет, что перенос старых отписок при записи
+// не перезаписывает выбор, сделанный пользователем в новых настройках
+func TestSetPreferencesKeepsNewerChoice(t *testing.T) {
+	repo, mr := newTestPreferenceRepository(t)
+	ctx := context.Background()
+
+	mr.SAdd(legacyOptOutKey("user-1"), "general")
+	mr.HSet(preferencesKey("user-1"), entity.CategorySystem, "true")
+
+	if err := repo.SetPreferences(ctx, "user-1", map[string]bool{entity.CategoryMarketing: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.GetPreferences(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored[entity.CategorySystem] || !stored[entity.CategoryMarketing] {
+		t.Fatalf("stored preferences = %v, want system and marketing enabled", stored)
+	}
+}
@@ -1,23 +1,66 @@
-package database
-
-import (
-	"context"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/1/internal/entity"
-)
-
-type NotificationRepository interface {
-	Create(ctx context.Context, notification *entity.Notification) error
-	GetByID(ctx context.Context, id string) (*entity.Notification, error)
-	Update(ctx context.Context, notification *entity.Notification) error
-	Delete(ctx context.Context, id string) error
-	GetPendingNotifications(ctx context.Context) ([]*entity.Notification, error)
-	GetAllNotifications(ctx context.Context) ([]*entity.Notification, error)
-}
-
-type CacheRepository interface {
-	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
-	Get(ctx context.Context, key string) (string, error)
-	Delete(ctx context.Context, key string) error
-}
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *entity.Notification) error
+	GetByID(ctx context.Context, id string) (*entity.Notification, error)
+	Update(ctx context.Context, notification *entity.Notification) error
+	Delete(ctx context.Context, id string) error
+	GetPendingNotifications(ctx context.Context) ([]*entity.Notification, error)
+	GetAllNotifications(ctx context.Context) ([]*entity.Notification, error)
+
+	// Lock не дает двум экземплярам worker одновременно отправлять одно уведомление
+	Lock(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	Unlock(ctx context.Context, id string) error
+}
+
+// PreferenceRepository хранит явно заданные пользователем подписки на категории уведомлений
+type PreferenceRepository interface {
+	GetPreferences(ctx context.Context, userID string) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userID string, categories map[string]bool) error
+	// GetLanguage возвращает язык уведомлений пользователя или пустую строку, если он не выбран
+	GetLanguage(ctx context.Context, userID string) (string, error)
+	SetLanguage(ctx context.Context, userID, language string) error
+}
+
+// CampaignRepository хранит A/B-кампании, выданные пользователям варианты и счетчики вариантов
+type CampaignRepository interface {
+	Create(ctx context.Context, campaign *entity.Campaign) error
+	GetByID(ctx context.Context, id string) (*entity.Campaign, error)
+	Update(ctx context.Context, campaign *entity.Campaign) error
+
+	// AssignVariant запоминает вариант пользователя и возвращает ранее выданный, если он уже есть
+	AssignVariant(ctx context.Context, campaignID, userID, variant string) (string, error)
+	IncrementAssigned(ctx context.Context, campaignID, variant string) error
+	IncrementDelivered(ctx context.Context, campaignID, variant string) error
+	// RecordRead учитывает прочтение уведомления один раз и сообщает, было ли оно новым
+	RecordRead(ctx context.Context, campaignID, variant, notificationID string) (bool, error)
+	GetStats(ctx context.Context, campaignID string) (*entity.CampaignStats, error)
+
+	// SetTranslation сохраняет перевод шаблонов вариантов на язык, заменяя прежний перевод этих вариантов
+	SetTranslation(ctx context.Context, campaignID, language string, templates map[string]entity.CampaignTemplate) error
+	GetTranslations(ctx context.Context, campaignID string) (entity.CampaignTranslations, error)
+}
+
+// ImportRepository хранит отчеты пакетного импорта уведомлений и ошибки их строк
+type ImportRepository interface {
+	SaveReport(ctx context.Context, report *entity.ImportReport) error
+	// GetReport возвращает nil, если отчета нет или срок его хранения истек
+	GetReport(ctx context.Context, id string) (*entity.ImportReport, error)
+	// AppendErrors дописывает ошибки строк в конец отчета
+	AppendErrors(ctx context.Context, id string, rows []entity.ImportRowError) error
+	// GetErrors возвращает до limit ошибок, начиная с offset, в порядке строк файла
+	GetErrors(ctx context.Context, id string, offset, limit int64) ([]entity.ImportRowError, error)
+}
+
+type CacheRepository interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Варианты шаблона в A/B-кампании
+const (
+	VariantA = "A"
+	VariantB = "B"
+)
+
+// Variants перечисляет варианты шаблона кампании
+var Variants = []string{VariantA, VariantB}
+
+// DefaultMinSample - сколько доставленных уведомлений каждого варианта нужно,
+// прежде чем победитель будет выбран автоматически
+const DefaultMinSample = 100
+
+// CampaignTemplate - заголовок и текст уведомления одного варианта
+type CampaignTemplate struct {
+	Title   string `json:"title" binding:"required"`
+	Message string `json:"message" binding:"required"`
+}
+
+// Campaign - рассылка с двумя вариантами шаблона. Получатели делятся между вариантами
+// в пропорции SplitA; после выбора победителя все новые получатели получают его шаблон.
+type Campaign struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Category  string           `json:"category"`
+	Language  string           `json:"language"` // язык шаблонов TemplateA и TemplateB
+	TemplateA CampaignTemplate `json:"template_a"`
+	TemplateB CampaignTemplate `json:"template_b"`
+	SplitA    int              `json:"split_a"` // доля получателей варианта A, в процентах
+	// AutoPromote включает автоматический выбор победителя по доле прочтений
+	AutoPromote bool       `json:"auto_promote"`
+	MinSample   int        `json:"min_sample"`
+	Winner      string     `json:"winner,omitempty"`
+	PromotedAt  *time.Time `json:"promoted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type CampaignRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Category    string           `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing system"`
+	Language    string           `json:"language,omitempty" binding:"omitempty,oneof=en ru uk"`
+	TemplateA   CampaignTemplate `json:"template_a" binding:"required"`
+	TemplateB   CampaignTemplate `json:"template_b" binding:"required"`
+	SplitA      *int             `json:"split_a,omitempty" binding:"omitempty,min=0,max=100"`
+	AutoPromote bool             `json:"auto_promote"`
+	MinSample   int              `json:"min_sample,omitempty" binding:"omitempty,min=1"`
+}
+
+type PromoteRequest struct {
+	Variant string `json:"variant" binding:"required,oneof=A B"`
+}
+
+// CampaignVariantStats - показатели одного варианта кампании
+type CampaignVariantStats struct {
+	Variant      string  `json:"variant"`
+	Assigned     int64   `json:"assigned"`  // уведомлений поставлено в очередь
+	Delivered    int64   `json:"delivered"` // уведомлений отправлено
+	Read         int64   `json:"read"`      // уведомлений прочитано
+	DeliveryRate float64 `json:"delivery_rate"`
+	ReadRate     float64 `json:"read_rate"` // доля прочитанных среди отправленных
+}
+
+type CampaignStats struct {
+	CampaignID string                  `json:"campaign_id"`
+	Winner     string                  `json:"winner,omitempty"`
+	Variants   []*CampaignVariantStats `json:"variants"`
+}
+
+func IsValidVariant(variant string) bool {
+	return variant == VariantA || variant == VariantB
+}
+
+// Template возвращает шаблон варианта
+func (c *Campaign) Template(variant string) CampaignTemplate {
+	if variant == VariantB {
+		return c.TemplateB
+	}
+	return c.TemplateA
+}
+
+// ChooseVariant выбирает вариант для пользователя. Выбор детерминирован: один и тот же
+// пользователь попадает в одну и ту же группу, пока победитель не выбран.
+func (c *Campaign) ChooseVariant(userID string) string {
+	if c.Winner != "" {
+		return c.Winner
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(c.ID + ":" + userID))
+	if int(hash.Sum32()%100) < c.SplitA {
+		return VariantA
+	}
+	return VariantB
+}
+
+// CalculateRates заполняет доли доставки и прочтения по счетчикам
+func (s *CampaignVariantStats) CalculateRates() {
+	s.DeliveryRate, s.ReadRate = 0, 0
+	if s.Assigned > 0 {
+		s.DeliveryRate = float64(s.Delivered) / float64(s.Assigned)
+	}
+	if s.Delivered > 0 {
+		s.ReadRate = float64(s.Read) / float64(s.Delivered)
+	}
+}
+
+// Leader возвращает вариант с большей долей прочтений, если у каждого варианта
+// не меньше minSample доставленных уведомлений; при равенстве победителя нет
+func (s *CampaignStats) Leader(minSample int) string {
+	var leader *CampaignVariantStats
+	tie := false
+	for _, variant := range s.Variants {
+		if variant.Delivered < int64(minSample) {
+			return ""
+		}
+		switch {
+		case leader == nil || variant.ReadRate > leader.ReadRate:
+			leader, tie = variant, false
+		case variant.ReadRate == leader.ReadRate:
+			tie = true
+		}
+	}
+
+	if leader == nil || tie {
+		return ""
+	}
+	return leader.Variant
+}
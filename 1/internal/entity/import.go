@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Форматы файла пакетного импорта уведомлений
+const (
+	ImportFormatCSV   = "csv"   // первая строка - заголовок с именами колонок
+	ImportFormatJSONL = "jsonl" // по одному NotificationRequest в JSON на строку
+)
+
+// Статусы импорта
+const (
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed" // импорт прерван ошибкой хранилища или очереди, часть строк не обработана
+)
+
+// ImportRowError - строка файла, которая не прошла проверку и не была запланирована
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReport - итог импорта файла уведомлений. Ошибки строк хранятся отдельно
+// и скачиваются по ErrorReportURL, чтобы отчет большого файла не раздувал ответ
+type ImportReport struct {
+	ID         string    `json:"id"`
+	Format     string    `json:"format"`
+	Status     string    `json:"status"`
+	Total      int       `json:"total"`     // строк с данными, без заголовка и пустых строк
+	Scheduled  int       `json:"scheduled"` // поставлены в очередь
+	Skipped    int       `json:"skipped"`   // сохранены, но пользователь отписан от категории
+	Failed     int       `json:"failed"`    // строк с ошибками
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	ErrorReportURL string `json:"error_report_url,omitempty"`
+}
+
+// Validate повторяет правила binding для NotificationRequest: строки импорта
+// проверяются без gin, но должны приниматься так же, как POST /notify
+func (r *NotificationRequest) Validate() error {
+	if r.UserID == "" {
+		return errors.New("user_id is required")
+	}
+	if r.CampaignID == "" {
+		if r.Title == "" {
+			return errors.New("title is required")
+		}
+		if r.Message == "" {
+			return errors.New("message is required")
+		}
+	}
+	if r.SendTime.IsZero() {
+		return errors.New("send_time is required")
+	}
+	if r.Category != "" && !IsValidCategory(r.Category) {
+		return fmt.Errorf("unknown category %q", r.Category)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package entity
+
+import "fmt"
+
+// DefaultLanguage - язык шаблонов кампании и уведомлений, если другой не указан
+const DefaultLanguage = "en"
+
+// Languages перечисляет языки, на которые переводятся шаблоны кампаний
+var Languages = []string{"en", "ru", "uk"}
+
+// languageFallback - следующий язык цепочки, если перевода на язык нет: uk -> ru -> en
+var languageFallback = map[string]string{
+	"uk": "ru",
+	"ru": "en",
+}
+
+func IsValidLanguage(language string) bool {
+	for _, supported := range Languages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackChain возвращает языки в порядке, в котором ищется перевод для языка пользователя;
+// цепочка всегда заканчивается языком по умолчанию
+func FallbackChain(language string) []string {
+	if !IsValidLanguage(language) {
+		language = DefaultLanguage
+	}
+
+	chain := []string{language}
+	for next, ok := languageFallback[language]; ok; next, ok = languageFallback[next] {
+		chain = append(chain, next)
+	}
+	if chain[len(chain)-1] != DefaultLanguage {
+		chain = append(chain, DefaultLanguage)
+	}
+	return chain
+}
+
+// CampaignTranslations - переводы шаблонов кампании: язык -> вариант -> шаблон
+type CampaignTranslations map[string]map[string]CampaignTemplate
+
+// TranslationRequest задает перевод шаблонов кампании на один язык; достаточно одного варианта
+type TranslationRequest struct {
+	TemplateA *CampaignTemplate `json:"template_a,omitempty"`
+	TemplateB *CampaignTemplate `json:"template_b,omitempty"`
+}
+
+func (r *TranslationRequest) Validate() error {
+	if r.TemplateA == nil && r.TemplateB == nil {
+		return fmt.Errorf("template_a or template_b is required")
+	}
+	return nil
+}
+
+// Templates возвращает переданные в запросе шаблоны по вариантам
+func (r *TranslationRequest) Templates() map[string]CampaignTemplate {
+	templates := make(map[string]CampaignTemplate, len(Variants))
+	if r.TemplateA != nil {
+		templates[VariantA] = *r.TemplateA
+	}
+	if r.TemplateB != nil {
+		templates[VariantB] = *r.TemplateB
+	}
+	return templates
+}
+
+// LanguageCompleteness - полнота перевода кампании на язык. ServedFrom показывает,
+// на каком языке получатель увидит каждый вариант с учетом цепочки языков.
+type LanguageCompleteness struct {
+	Language     string            `json:"language"`
+	Translated   []string          `json:"translated"`
+	Missing      []string          `json:"missing"`
+	Completeness float64           `json:"completeness"` // доля переведенных вариантов
+	ServedFrom   map[string]string `json:"served_from"`
+}
+
+type TranslationReport struct {
+	CampaignID   string                  `json:"campaign_id"`
+	BaseLanguage string                  `json:"base_language"`
+	Translations CampaignTranslations    `json:"translations"`
+	Languages    []*LanguageCompleteness `json:"languages"`
+}
+
+// Localize выбирает шаблон варианта на первом языке цепочки, для которого есть перевод.
+// Исходные шаблоны кампании считаются переводом на ее базовый язык.
+func (c *Campaign) Localize(translations CampaignTranslations, variant, language string) (CampaignTemplate, string) {
+	for _, candidate := range FallbackChain(language) {
+		if template, ok := c.translation(translations, variant, candidate); ok {
+			return template, candidate
+		}
+	}
+	return c.Template(variant), c.BaseLanguage()
+}
+
+// BaseLanguage - язык исходных шаблонов кампании; у старых кампаний он не записан
+func (c *Campaign) BaseLanguage() string {
+	if c.Language == "" {
+		return DefaultLanguage
+	}
+	return c.Language
+}
+
+func (c *Campaign) translation(translations CampaignTranslations, variant, language string) (CampaignTemplate, bool) {
+	if language == c.BaseLanguage() {
+		return c.Template(variant), true
+	}
+	template, ok := translations[language][variant]
+	return template, ok
+}
+
+// TranslationReport считает полноту переводов по всем поддерживаемым языкам
+func (c *Campaign) TranslationReport(translations CampaignTranslations) *TranslationReport {
+	report := &TranslationReport{
+		CampaignID:   c.ID,
+		BaseLanguage: c.BaseLanguage(),
+		Translations: translations,
+		Languages:    make([]*LanguageCompleteness, 0, len(Languages)),
+	}
+
+	for _, language := range Languages {
+		completeness := &LanguageCompleteness{
+			Language:   language,
+			Translated: []string{},
+			Missing:    []string{},
+			ServedFrom: make(map[string]string, len(Variants)),
+		}
+		for _, variant := range Variants {
+			if _, ok := c.translation(translations, variant, language); ok {
+				completeness.Translated = append(completeness.Translated, variant)
+			} else {
+				completeness.Missing = append(completeness.Missing, variant)
+			}
+			_, completeness.ServedFrom[variant] = c.Localize(translations, variant, language)
+		}
+		completeness.Completeness = float64(len(completeness.Translated)) / float64(len(Variants))
+		report.Languages = append(report.Languages, completeness)
+	}
+
+	return report
+}
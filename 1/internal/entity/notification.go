@@ -1,31 +1,55 @@
-package entity
-
-import (
-	"time"
-)
-
-type Notification struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Title     string    `json:"title"`
-	Message   string    `json:"message"`
-	SendTime  time.Time `json:"send_time"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Attempts  int       `json:"attempts"`
-}
-
-type NotificationRequest struct {
-	UserID   string    `json:"user_id" binding:"required"`
-	Title    string    `json:"title" binding:"required"`
-	Message  string    `json:"message" binding:"required"`
-	SendTime time.Time `json:"send_time" binding:"required"`
-}
-
-const (
-	StatusPending   = "pending"
-	StatusSent      = "sent"
-	StatusFailed    = "failed"
-	StatusCancelled = "cancelled"
-)
+package entity
+
+import (
+	"time"
+)
+
+type Notification struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	SendTime  time.Time `json:"send_time"`
+	Status    string    `json:"status"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Attempts  int       `json:"attempts"`
+
+	// CampaignID и Variant заполняются для уведомлений A/B-кампании
+	CampaignID string     `json:"campaign_id,omitempty"`
+	Variant    string     `json:"variant,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	// Language - язык, на котором шаблон кампании был подставлен при отправке
+	Language string `json:"language,omitempty"`
+}
+
+type NotificationRequest struct {
+	UserID   string    `json:"user_id" binding:"required"`
+	Title    string    `json:"title" binding:"required_without=CampaignID"`
+	Message  string    `json:"message" binding:"required_without=CampaignID"`
+	SendTime time.Time `json:"send_time" binding:"required"`
+	Category string    `json:"category,omitempty" binding:"omitempty,oneof=transactional marketing system"`
+	// CampaignID - кампания, шаблон которой используется вместо Title и Message
+	CampaignID string `json:"campaign_id,omitempty"`
+}
+
+const (
+	StatusPending   = "pending"
+	StatusSent      = "sent"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusSkipped   = "skipped" // пользователь отписался от категории уведомления
+)
+
+// DefaultCategory используется, если категория уведомления не указана
+const DefaultCategory = CategorySystem
+
+// NormalizeCategory сводит категории, сохраненные до появления подписок ("general" и произвольные строки),
+// к DefaultCategory, чтобы такие уведомления и отписки от них продолжали работать
+func NormalizeCategory(category string) string {
+	if IsValidCategory(category) {
+		return category
+	}
+	return DefaultCategory
+}
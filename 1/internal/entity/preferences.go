@@ -0,0 +1,94 @@
+package entity
+
+import "fmt"
+
+// Категории уведомлений
+const (
+	CategoryTransactional = "transactional" // подтверждения и квитанции, отключить нельзя
+	CategoryMarketing     = "marketing"     // рассылки, требуют явного согласия
+	CategorySystem        = "system"        // служебные оповещения, включены по умолчанию
+)
+
+// Categories перечисляет все категории уведомлений
+var Categories = []string{CategoryTransactional, CategoryMarketing, CategorySystem}
+
+// defaultOptIn - состояние подписки, если пользователь не менял настройки
+var defaultOptIn = map[string]bool{
+	CategoryTransactional: true,
+	CategoryMarketing:     false,
+	CategorySystem:        true,
+}
+
+// UserPreferences - подписки пользователя на категории уведомлений
+type UserPreferences struct {
+	UserID     string          `json:"user_id"`
+	Categories map[string]bool `json:"categories"`
+	Language   string          `json:"language"` // язык уведомлений, по умолчанию DefaultLanguage
+}
+
+type PreferencesRequest struct {
+	Categories map[string]bool `json:"categories"`
+	Language   string          `json:"language,omitempty"`
+}
+
+func IsValidCategory(category string) bool {
+	_, ok := defaultOptIn[category]
+	return ok
+}
+
+// IsOptionalCategory сообщает, может ли пользователь отписаться от категории
+func IsOptionalCategory(category string) bool {
+	return IsValidCategory(category) && category != CategoryTransactional
+}
+
+// NewUserPreferences дополняет сохраненные настройки значениями по умолчанию
+func NewUserPreferences(userID string, stored map[string]bool, language string) *UserPreferences {
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	prefs := &UserPreferences{
+		UserID:     userID,
+		Categories: make(map[string]bool, len(defaultOptIn)),
+		Language:   language,
+	}
+
+	for category, optIn := range defaultOptIn {
+		prefs.Categories[category] = optIn
+	}
+	for category, optIn := range stored {
+		if IsOptionalCategory(category) {
+			prefs.Categories[category] = optIn
+		}
+	}
+
+	return prefs
+}
+
+// Allows проверяет, подписан ли пользователь на категорию
+func (p *UserPreferences) Allows(category string) bool {
+	if category == CategoryTransactional {
+		return true
+	}
+	return p.Categories[category]
+}
+
+// Validate проверяет, что запрос меняет только существующие и отключаемые категории
+// и выбирает поддерживаемый язык
+func (r *PreferencesRequest) Validate() error {
+	if len(r.Categories) == 0 && r.Language == "" {
+		return fmt.Errorf("categories or language is required")
+	}
+	if r.Language != "" && !IsValidLanguage(r.Language) {
+		return fmt.Errorf("unsupported language %q", r.Language)
+	}
+	for category, optIn := range r.Categories {
+		if !IsValidCategory(category) {
+			return fmt.Errorf("unknown notification category %q", category)
+		}
+		if !optIn && !IsOptionalCategory(category) {
+			return fmt.Errorf("%s notifications cannot be disabled", category)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "testing"
+
+// TestLegacyCategoryDelivered проверяет, что уведомления со старой категорией "general"
+// доставляются пользователю, не менявшему настройки
+func TestLegacyCategoryDelivered(t *testing.T) {
+	prefs := NewUserPreferences("user-1", nil, "")
+
+	for _, category := range []string{"", "general", "promo"} {
+		normalized := NormalizeCategory(category)
+		if !prefs.Allows(normalized) {
+			t.Errorf("category %q -> %q is not delivered by default", category, normalized)
+		}
+	}
+	if NormalizeCategory(CategoryMarketing) != CategoryMarketing {
+		t.Error("known categories must stay unchanged")
+	}
+}
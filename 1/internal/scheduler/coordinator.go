@@ -0,0 +1,219 @@
+// Распределение шардов планировщика между экземплярами worker
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	membersKey     = "scheduler:members"
+	shardKeyPrefix = "scheduler:shard:"
+)
+
+// renewScript продлевает аренду, только если она все еще принадлежит этому экземпляру
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript удаляет аренду, только если она принадлежит этому экземпляру
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Coordinator делит уведомления на shards шардов и держит аренды части из них.
+// Живые экземпляры отмечаются в общем ZSET, и каждый берет не больше ceil(shards/N)
+// шардов, поэтому при добавлении экземпляра лишние аренды освобождаются и
+// перераспределяются, а шарды упавшего экземпляра подхватываются после истечения аренды.
+type Coordinator struct {
+	client     redis.UniversalClient
+	instanceID string
+	shards     int
+	leaseTTL   time.Duration
+
+	mu    sync.RWMutex
+	owned map[int]bool
+}
+
+func NewCoordinator(client redis.UniversalClient, instanceID string, shards int, leaseTTL time.Duration) *Coordinator {
+	if shards <= 0 {
+		shards = 1
+	}
+	if leaseTTL <= 0 {
+		leaseTTL = 15 * time.Second
+	}
+
+	return &Coordinator{
+		client:     client,
+		instanceID: instanceID,
+		shards:     shards,
+		leaseTTL:   leaseTTL,
+		owned:      make(map[int]bool),
+	}
+}
+
+// ShardOf возвращает номер шарда уведомления
+func (c *Coordinator) ShardOf(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(c.shards))
+}
+
+// Owns сообщает, обрабатывает ли этот экземпляр уведомление с данным id
+func (c *Coordinator) Owns(id string) bool {
+	shard := c.ShardOf(id)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.owned[shard]
+}
+
+// Owned возвращает отсортированный список арендованных шардов
+func (c *Coordinator) Owned() []int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	shards := make([]int, 0, len(c.owned))
+	for shard := range c.owned {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
+
+// Run продлевает и перераспределяет аренды, пока не отменен ctx, после чего освобождает их
+func (c *Coordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.leaseTTL / 3)
+	defer ticker.Stop()
+
+	c.rebalance(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			c.release()
+			return
+		case <-ticker.C:
+			c.rebalance(ctx)
+		}
+	}
+}
+
+func (c *Coordinator) rebalance(ctx context.Context) {
+	members, err := c.heartbeat(ctx)
+	if err != nil {
+		logrus.Errorf("scheduler: failed to register instance %s: %s", c.instanceID, err.Error())
+		return
+	}
+	target := (c.shards + members - 1) / members
+
+	owned := make(map[int]bool, target)
+	for shard := range c.snapshot() {
+		renewed, err := renewScript.Run(ctx, c.client, []string{shardKey(shard)}, c.instanceID, c.leaseTTL.Milliseconds()).Int()
+		if err != nil {
+			logrus.Errorf("scheduler: failed to renew shard %d: %s", shard, err.Error())
+			continue
+		}
+		if renewed == 1 {
+			owned[shard] = true
+		}
+	}
+
+	// Экземпляров стало больше - отдаем лишние шарды
+	for shard := range owned {
+		if len(owned) <= target {
+			break
+		}
+		if err := releaseScript.Run(ctx, c.client, []string{shardKey(shard)}, c.instanceID).Err(); err != nil {
+			logrus.Errorf("scheduler: failed to release shard %d: %s", shard, err.Error())
+			continue
+		}
+		delete(owned, shard)
+	}
+
+	// Начинаем с разных шардов, чтобы экземпляры реже конкурировали за одни и те же аренды
+	start := c.ShardOf(c.instanceID)
+	for i := 0; i < c.shards && len(owned) < target; i++ {
+		shard := (start + i) % c.shards
+		if owned[shard] {
+			continue
+		}
+		acquired, err := c.client.SetNX(ctx, shardKey(shard), c.instanceID, c.leaseTTL).Result()
+		if err != nil {
+			logrus.Errorf("scheduler: failed to acquire shard %d: %s", shard, err.Error())
+			continue
+		}
+		if acquired {
+			owned[shard] = true
+		}
+	}
+
+	c.mu.Lock()
+	c.owned = owned
+	c.mu.Unlock()
+}
+
+// heartbeat отмечает экземпляр живым и возвращает число живых экземпляров
+func (c *Coordinator) heartbeat(ctx context.Context) (int, error) {
+	now := time.Now()
+	stale := now.Add(-c.leaseTTL).UnixMilli()
+
+	pipe := c.client.TxPipeline()
+	pipe.ZAdd(ctx, membersKey, &redis.Z{Score: float64(now.UnixMilli()), Member: c.instanceID})
+	pipe.ZRemRangeByScore(ctx, membersKey, "-inf", fmt.Sprintf("%d", stale))
+	count := pipe.ZCard(ctx, membersKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	if count.Val() < 1 {
+		return 1, nil
+	}
+	return int(count.Val()), nil
+}
+
+// release отдает все аренды при остановке, чтобы другие экземпляры не ждали их истечения
+func (c *Coordinator) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for shard := range c.snapshot() {
+		if err := releaseScript.Run(ctx, c.client, []string{shardKey(shard)}, c.instanceID).Err(); err != nil {
+			logrus.Errorf("scheduler: failed to release shard %d: %s", shard, err.Error())
+		}
+	}
+	if err := c.client.ZRem(ctx, membersKey, c.instanceID).Err(); err != nil {
+		logrus.Errorf("scheduler: failed to unregister instance %s: %s", c.instanceID, err.Error())
+	}
+
+	c.mu.Lock()
+	c.owned = make(map[int]bool)
+	c.mu.Unlock()
+}
+
+func (c *Coordinator) snapshot() map[int]bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	owned := make(map[int]bool, len(c.owned))
+	for shard := range c.owned {
+		owned[shard] = true
+	}
+	return owned
+}
+
+func shardKey(shard int) string {
+	return fmt.Sprintf("%s%d", shardKeyPrefix, shard)
+}
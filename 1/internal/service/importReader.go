@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+// maxImportLineSize ограничивает строку JSONL: длинное сообщение помещается, весь файл в одной строке - нет
+const maxImportLineSize = 1 << 20
+
+// csvImportColumns сопоставляет допустимые заголовки CSV с полями NotificationRequest
+var csvImportColumns = map[string]string{
+	"user":        "user_id",
+	"user_id":     "user_id",
+	"title":       "title",
+	"message":     "message",
+	"send_time":   "send_time",
+	"category":    "category",
+	"campaign_id": "campaign_id",
+}
+
+// importRowError - строку нельзя разобрать, но чтение файла можно продолжить
+type importRowError struct {
+	line int
+	err  error
+}
+
+func (e *importRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// importReader отдает строки файла по одной; io.EOF - строки закончились,
+// *importRowError - строка пропускается, любая другая ошибка прерывает импорт
+type importReader interface {
+	Next() (int, *entity.NotificationRequest, error)
+}
+
+func newImportReader(format string, r io.Reader) (importReader, error) {
+	switch format {
+	case entity.ImportFormatCSV:
+		return newCSVImportReader(r)
+	case entity.ImportFormatJSONL:
+		return newJSONLImportReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q, expected csv or jsonl", ErrInvalidImport, format)
+	}
+}
+
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newCSVImportReader читает заголовок; без user_id и send_time, а без campaign_id
+// и без title с message файл отклоняется целиком
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		field, ok := csvImportColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, ok := columns[field]; ok {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidImport, name)
+		}
+		columns[field] = i
+	}
+
+	required := []string{"user_id", "send_time"}
+	if _, ok := columns["campaign_id"]; !ok {
+		required = append(required, "title", "message")
+	}
+	for _, field := range required {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImport, field)
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) Next() (int, *entity.NotificationRequest, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, nil, &importRowError{line: parseErr.StartLine, err: parseErr.Err}
+		}
+		return 0, nil, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	req := &entity.NotificationRequest{
+		UserID:     r.field(record, "user_id"),
+		Title:      r.field(record, "title"),
+		Message:    r.field(record, "message"),
+		Category:   r.field(record, "category"),
+		CampaignID: r.field(record, "campaign_id"),
+	}
+	if sendTime := r.field(record, "send_time"); sendTime != "" {
+		parsed, err := time.Parse(time.RFC3339, sendTime)
+		if err != nil {
+			return line, nil, &importRowError{line: line, err: fmt.Errorf("send_time %q is not an RFC 3339 time", sendTime)}
+		}
+		req.SendTime = parsed
+	}
+
+	return line, req, nil
+}
+
+// field возвращает значение колонки; в короткой строке недостающие колонки пусты
+func (r *csvImportReader) field(record []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLImportReader(r io.Reader) *jsonlImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+	return &jsonlImportReader{scanner: scanner}
+}
+
+func (r *jsonlImportReader) Next() (int, *entity.NotificationRequest, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if r.line == 1 {
+			text = bytes.TrimPrefix(text, []byte("\ufeff"))
+		}
+		if len(text) == 0 {
+			continue
+		}
+
+		var req entity.NotificationRequest
+		if err := json.Unmarshal(text, &req); err != nil {
+			return r.line, nil, &importRowError{line: r.line, err: fmt.Errorf("invalid JSON: %v", err)}
+		}
+		return r.line, &req, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return 0, nil, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImport, r.line+1, maxImportLineSize)
+		}
+		return 0, nil, err
+	}
+	return 0, nil, io.EOF
+}
@@ -1,15 +1,54 @@
-package service
-
-import (
-	"context"
-
-	"github.com/ds124wfegd/WB_L3/1/internal/entity"
-)
-
-type NotificationUseCase interface {
-	CreateNotification(ctx context.Context, req *entity.NotificationRequest) (*entity.Notification, error)
-	GetNotification(ctx context.Context, id string) (*entity.Notification, error)
-	CancelNotification(ctx context.Context, id string) error
-	ProcessScheduledNotifications(ctx context.Context) error
-	GetAllNotifications(ctx context.Context) ([]*entity.Notification, error)
-}
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+type NotificationUseCase interface {
+	CreateNotification(ctx context.Context, req *entity.NotificationRequest) (*entity.Notification, error)
+	GetNotification(ctx context.Context, id string) (*entity.Notification, error)
+	CancelNotification(ctx context.Context, id string) error
+	// ProcessScheduledNotifications отправляет просроченные уведомления, для которых owns возвращает true;
+	// nil означает все уведомления
+	ProcessScheduledNotifications(ctx context.Context, owns func(id string) bool) error
+	// DeliverNotification отправляет уведомление, полученное worker из очереди
+	DeliverNotification(ctx context.Context, id string) error
+	GetAllNotifications(ctx context.Context) ([]*entity.Notification, error)
+	// MarkRead отмечает отправленное уведомление прочитанным; nil означает, что уведомление не найдено
+	MarkRead(ctx context.Context, id string) (*entity.Notification, error)
+
+	// CheckUnsubscribe проверяет токен из ссылки отписки и возвращает категорию, ничего не меняя
+	CheckUnsubscribe(ctx context.Context, token string) (string, error)
+	// Unsubscribe проверяет токен из ссылки отписки и возвращает категорию, от которой отписан пользователь
+	Unsubscribe(ctx context.Context, token string) (string, error)
+}
+
+// CampaignUseCase управляет A/B-кампаниями: шаблоны вариантов, показатели и выбор победителя
+type CampaignUseCase interface {
+	CreateCampaign(ctx context.Context, req *entity.CampaignRequest) (*entity.Campaign, error)
+	GetCampaign(ctx context.Context, id string) (*entity.Campaign, error)
+	GetCampaignStats(ctx context.Context, id string) (*entity.CampaignStats, error)
+	// PromoteVariant вручную делает вариант победителем: новые получатели получают только его
+	PromoteVariant(ctx context.Context, id, variant string) (*entity.Campaign, error)
+
+	// SetTranslation сохраняет перевод шаблонов кампании на язык и возвращает полноту переводов
+	SetTranslation(ctx context.Context, id, language string, req *entity.TranslationRequest) (*entity.TranslationReport, error)
+	GetTranslations(ctx context.Context, id string) (*entity.TranslationReport, error)
+}
+
+type PreferenceUseCase interface {
+	GetPreferences(ctx context.Context, userID string) (*entity.UserPreferences, error)
+	UpdatePreferences(ctx context.Context, userID string, req *entity.PreferencesRequest) (*entity.UserPreferences, error)
+}
+
+// ImportUseCase планирует уведомления из загруженного файла и хранит отчет об ошибках строк
+type ImportUseCase interface {
+	// ImportNotifications читает файл потоком: в памяти держится только текущая строка и пачка ошибок
+	ImportNotifications(ctx context.Context, format string, r io.Reader) (*entity.ImportReport, error)
+	// GetReport возвращает nil, если импорт не найден
+	GetReport(ctx context.Context, id string) (*entity.ImportReport, error)
+	GetErrors(ctx context.Context, id string, offset, limit int64) ([]entity.ImportRowError, error)
+}
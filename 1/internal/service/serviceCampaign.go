@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/database"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+var (
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	// ErrBaseLanguage - шаблоны на базовом языке кампании задаются при ее создании
+	ErrBaseLanguage = errors.New("language is the base language of the campaign")
+)
+
+// defaultSplitA - доля варианта A, если она не указана в запросе
+const defaultSplitA = 50
+
+type campaignUseCase struct {
+	repo database.CampaignRepository
+}
+
+func NewCampaignUseCase(repo database.CampaignRepository) CampaignUseCase {
+	return &campaignUseCase{repo: repo}
+}
+
+func (uc *campaignUseCase) CreateCampaign(ctx context.Context, req *entity.CampaignRequest) (*entity.Campaign, error) {
+	category := req.Category
+	if category == "" {
+		category = entity.DefaultCategory
+	}
+	splitA := defaultSplitA
+	if req.SplitA != nil {
+		splitA = *req.SplitA
+	}
+	minSample := req.MinSample
+	if minSample <= 0 {
+		minSample = entity.DefaultMinSample
+	}
+	language := req.Language
+	if language == "" {
+		language = entity.DefaultLanguage
+	}
+
+	campaign := &entity.Campaign{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Category:    category,
+		Language:    language,
+		TemplateA:   req.TemplateA,
+		TemplateB:   req.TemplateB,
+		SplitA:      splitA,
+		AutoPromote: req.AutoPromote,
+		MinSample:   minSample,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := uc.repo.Create(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+func (uc *campaignUseCase) GetCampaign(ctx context.Context, id string) (*entity.Campaign, error) {
+	return uc.repo.GetByID(ctx, id)
+}
+
+func (uc *campaignUseCase) GetCampaignStats(ctx context.Context, id string) (*entity.CampaignStats, error) {
+	campaign, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+
+	stats, err := uc.repo.GetStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range stats.Variants {
+		variant.CalculateRates()
+	}
+	stats.Winner = campaign.Winner
+
+	return stats, nil
+}
+
+func (uc *campaignUseCase) PromoteVariant(ctx context.Context, id, variant string) (*entity.Campaign, error) {
+	campaign, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+
+	if err := promote(ctx, uc.repo, campaign, variant); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// SetTranslation переводит шаблоны на язык; перевод на базовый язык кампании
+// заменил бы ее исходные шаблоны, поэтому он не принимается
+func (uc *campaignUseCase) SetTranslation(ctx context.Context, id, language string, req *entity.TranslationRequest) (*entity.TranslationReport, error) {
+	if !entity.IsValidLanguage(language) {
+		return nil, ErrUnsupportedLanguage
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	campaign, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	if language == campaign.BaseLanguage() {
+		return nil, ErrBaseLanguage
+	}
+
+	if err := uc.repo.SetTranslation(ctx, id, language, req.Templates()); err != nil {
+		return nil, err
+	}
+
+	return uc.translationReport(ctx, campaign)
+}
+
+func (uc *campaignUseCase) GetTranslations(ctx context.Context, id string) (*entity.TranslationReport, error) {
+	campaign, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+
+	return uc.translationReport(ctx, campaign)
+}
+
+func (uc *campaignUseCase) translationReport(ctx context.Context, campaign *entity.Campaign) (*entity.TranslationReport, error) {
+	translations, err := uc.repo.GetTranslations(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	return campaign.TranslationReport(translations), nil
+}
+
+// autoPromote выбирает победителя кампании, если это разрешено и вариантам хватает данных
+func autoPromote(ctx context.Context, repo database.CampaignRepository, campaignID string) error {
+	campaign, err := repo.GetByID(ctx, campaignID)
+	if err != nil || campaign == nil {
+		return err
+	}
+	if !campaign.AutoPromote || campaign.Winner != "" {
+		return nil
+	}
+
+	stats, err := repo.GetStats(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	for _, variant := range stats.Variants {
+		variant.CalculateRates()
+	}
+
+	leader := stats.Leader(campaign.MinSample)
+	if leader == "" {
+		return nil
+	}
+	return promote(ctx, repo, campaign, leader)
+}
+
+func promote(ctx context.Context, repo database.CampaignRepository, campaign *entity.Campaign, variant string) error {
+	now := time.Now()
+	campaign.Winner = variant
+	campaign.PromotedAt = &now
+	campaign.UpdatedAt = now
+
+	return repo.Update(ctx, campaign)
+}
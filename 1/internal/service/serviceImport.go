@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/database"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidImport - файл нельзя разобрать: неизвестный формат, неверный заголовок или слишком длинная строка
+var ErrInvalidImport = errors.New("invalid import file")
+
+// importErrorBatch - сколько ошибок строк копится перед записью в хранилище
+const importErrorBatch = 100
+
+type importUseCase struct {
+	notifications NotificationUseCase
+	repo          database.ImportRepository
+}
+
+func NewImportUseCase(notifications NotificationUseCase, repo database.ImportRepository) ImportUseCase {
+	return &importUseCase{notifications: notifications, repo: repo}
+}
+
+// ImportNotifications планирует строки по мере чтения тем же CreateNotification, что и POST /notify.
+// Строка с ошибкой попадает в отчет и не мешает остальным; ошибка хранилища или очереди
+// прерывает импорт, и уже запланированные строки остаются запланированными.
+func (uc *importUseCase) ImportNotifications(ctx context.Context, format string, r io.Reader) (*entity.ImportReport, error) {
+	rows, err := newImportReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entity.ImportReport{
+		ID:        uuid.New().String(),
+		Format:    format,
+		CreatedAt: time.Now(),
+	}
+
+	pending := make([]entity.ImportRowError, 0, importErrorBatch)
+	flush := func(ctx context.Context) error {
+		if err := uc.repo.AppendErrors(ctx, report.ID, pending); err != nil {
+			return fmt.Errorf("failed to save import errors: %w", err)
+		}
+		pending = pending[:0]
+		return nil
+	}
+	reject := func(line int, err error) error {
+		report.Failed++
+		pending = append(pending, entity.ImportRowError{Line: line, Error: err.Error()})
+		if len(pending) < importErrorBatch {
+			return nil
+		}
+		return flush(ctx)
+	}
+
+	for {
+		line, req, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var rowErr *importRowError
+			if !errors.As(err, &rowErr) {
+				return uc.abort(ctx, report, flush, err)
+			}
+			report.Total++
+			if err := reject(rowErr.line, rowErr.err); err != nil {
+				return uc.abort(ctx, report, flush, err)
+			}
+			continue
+		}
+
+		report.Total++
+		if err := req.Validate(); err != nil {
+			if err := reject(line, err); err != nil {
+				return uc.abort(ctx, report, flush, err)
+			}
+			continue
+		}
+
+		notification, err := uc.notifications.CreateNotification(ctx, req)
+		if err != nil {
+			if !errors.Is(err, ErrCampaignNotFound) {
+				return uc.abort(ctx, report, flush, fmt.Errorf("line %d: %w", line, err))
+			}
+			if err := reject(line, err); err != nil {
+				return uc.abort(ctx, report, flush, err)
+			}
+			continue
+		}
+
+		if notification.Status == entity.StatusSkipped {
+			report.Skipped++
+		} else {
+			report.Scheduled++
+		}
+	}
+
+	if err := flush(ctx); err != nil {
+		return uc.abort(ctx, report, flush, err)
+	}
+
+	report.Status = entity.ImportStatusCompleted
+	report.FinishedAt = time.Now()
+	if err := uc.repo.SaveReport(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to save import report: %w", err)
+	}
+
+	return report, nil
+}
+
+// abort сохраняет отчет о прерванном импорте, чтобы было видно, докуда дошла обработка.
+// Запрос к этому моменту мог быть уже отменен, поэтому отчет пишется без его отмены.
+func (uc *importUseCase) abort(ctx context.Context, report *entity.ImportReport, flush func(context.Context) error, cause error) (*entity.ImportReport, error) {
+	ctx = context.WithoutCancel(ctx)
+
+	report.Status = entity.ImportStatusFailed
+	report.FinishedAt = time.Now()
+
+	errs := []error{cause}
+	if err := flush(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := uc.repo.SaveReport(ctx, report); err != nil {
+		errs = append(errs, fmt.Errorf("failed to save import report: %w", err))
+	}
+	return report, errors.Join(errs...)
+}
+
+func (uc *importUseCase) GetReport(ctx context.Context, id string) (*entity.ImportReport, error) {
+	return uc.repo.GetReport(ctx, id)
+}
+
+func (uc *importUseCase) GetErrors(ctx context.Context, id string, offset, limit int64) ([]entity.ImportRowError, error) {
+	return uc.repo.GetErrors(ctx, id, offset, limit)
+}
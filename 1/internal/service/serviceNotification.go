@@ -1,118 +1,482 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/1/internal/database"
-	"github.com/ds124wfegd/WB_L3/1/internal/entity"
-	"github.com/ds124wfegd/WB_L3/1/internal/rabbitMQ"
-
-	"github.com/google/uuid"
-)
-
-type notificationUseCase struct {
-	repo        database.NotificationRepository
-	queue       rabbitMQ.Queue
-	maxAttempts int
-}
-
-func NewNotificationUseCase(repo database.NotificationRepository, q rabbitMQ.Queue, maxAttempts int) NotificationUseCase {
-	return &notificationUseCase{
-		repo:        repo,
-		queue:       q,
-		maxAttempts: maxAttempts,
-	}
-}
-
-func (uc *notificationUseCase) CreateNotification(ctx context.Context, req *entity.NotificationRequest) (*entity.Notification, error) {
-	notification := &entity.Notification{
-		ID:        uuid.New().String(),
-		UserID:    req.UserID,
-		Title:     req.Title,
-		Message:   req.Message,
-		SendTime:  req.SendTime,
-		Status:    entity.StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Attempts:  0,
-	}
-
-	if err := uc.repo.Create(ctx, notification); err != nil {
-		return nil, err
-	}
-
-	// Schedule notification in queue with context
-	delay := notification.SendTime.Sub(time.Now())
-	if delay > 0 {
-		if err := uc.queue.PublishWithDelay(ctx, notification, delay); err != nil {
-			return nil, err
-		}
-	} else {
-		// Если время уже настало, отправляем сразу
-		if err := uc.queue.Publish(ctx, notification); err != nil {
-			return nil, err
-		}
-	}
-
-	return notification, nil
-}
-
-func (uc *notificationUseCase) GetNotification(ctx context.Context, id string) (*entity.Notification, error) {
-	return uc.repo.GetByID(ctx, id)
-}
-
-func (uc *notificationUseCase) CancelNotification(ctx context.Context, id string) error {
-	notification, err := uc.repo.GetByID(ctx, id)
-	if err != nil {
-		return err
-	}
-
-	if notification == nil {
-		return fmt.Errorf("notification not found")
-	}
-
-	notification.Status = entity.StatusCancelled
-	notification.UpdatedAt = time.Now()
-
-	return uc.repo.Update(ctx, notification)
-}
-
-func (uc *notificationUseCase) ProcessScheduledNotifications(ctx context.Context) error {
-	pending, err := uc.repo.GetPendingNotifications(ctx)
-	if err != nil {
-		return err
-	}
-
-	now := time.Now()
-	for _, notification := range pending {
-		if notification.SendTime.Before(now) || notification.SendTime.Equal(now) {
-			if err := uc.sendNotification(ctx, notification); err != nil {
-				fmt.Printf("Failed to send notification %s: %v\n", notification.ID, err)
-			}
-		}
-	}
-
-	return nil
-}
-
-func (uc *notificationUseCase) sendNotification(ctx context.Context, notification *entity.Notification) error {
-	// Симуляция отправки сообщений в <...>
-	fmt.Printf("Sending notification to user %s: %s - %s\n",
-		notification.UserID, notification.Title, notification.Message)
-
-	// В будущем тут может быть реализация отправки сообщений в mail.ru
-	notification.Status = entity.StatusSent
-	notification.UpdatedAt = time.Now()
-
-	return uc.repo.Update(ctx, notification)
-}
-
-func (s *notificationUseCase) GetAllNotifications(ctx context.Context) ([]*entity.Notification, error) {
-	notifications, err := s.repo.GetAllNotifications(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get notifications from repository: %w", err)
-	}
-	return notifications, nil
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/channel"
+	"github.com/ds124wfegd/WB_L3/1/internal/database"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/rabbitMQ"
+
+	"github.com/google/uuid"
+)
+
+// deliveryLockTTL ограничивает блокировку уведомления, если отправивший его worker упал
+const deliveryLockTTL = time.Minute
+
+// statusWriteTimeout ограничивает запись результата отправки, когда контекст доставки уже отменен
+const statusWriteTimeout = 5 * time.Second
+
+// Экспоненциальная задержка повтора после ошибки провайдера без подсказки о задержке
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 30 * time.Minute
+)
+
+// ErrNotificationNotSent - прочитанным можно отметить только отправленное уведомление
+var ErrNotificationNotSent = errors.New("notification has not been sent")
+
+type notificationUseCase struct {
+	repo        database.NotificationRepository
+	prefs       database.PreferenceRepository
+	campaigns   database.CampaignRepository
+	queue       rabbitMQ.Queue
+	signer      *UnsubscribeSigner
+	sender      channel.Sender
+	metrics     *channel.Metrics
+	maxAttempts int
+}
+
+func NewNotificationUseCase(repo database.NotificationRepository, prefs database.PreferenceRepository, campaigns database.CampaignRepository, q rabbitMQ.Queue, signer *UnsubscribeSigner, sender channel.Sender, metrics *channel.Metrics, maxAttempts int) NotificationUseCase {
+	return &notificationUseCase{
+		repo:        repo,
+		prefs:       prefs,
+		campaigns:   campaigns,
+		queue:       q,
+		signer:      signer,
+		sender:      sender,
+		metrics:     metrics,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (uc *notificationUseCase) CreateNotification(ctx context.Context, req *entity.NotificationRequest) (*entity.Notification, error) {
+	category := req.Category
+	if category == "" {
+		category = entity.DefaultCategory
+	}
+
+	notification := &entity.Notification{
+		ID:        uuid.New().String(),
+		UserID:    req.UserID,
+		Title:     req.Title,
+		Message:   req.Message,
+		SendTime:  req.SendTime,
+		Status:    entity.StatusPending,
+		Category:  category,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Attempts:  0,
+	}
+
+	if req.CampaignID != "" {
+		if err := uc.applyCampaign(ctx, notification, req.CampaignID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Пользователь не подписан на категорию - сохраняем уведомление, но не ставим в очередь
+	allowed, err := uc.allows(ctx, notification.UserID, notification.Category)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		notification.Status = entity.StatusSkipped
+	}
+
+	if err := uc.repo.Create(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return notification, nil
+	}
+
+	if notification.CampaignID != "" {
+		if err := uc.campaigns.IncrementAssigned(ctx, notification.CampaignID, notification.Variant); err != nil {
+			return nil, err
+		}
+	}
+
+	// Schedule notification in queue with context
+	delay := notification.SendTime.Sub(time.Now())
+	if delay > 0 {
+		if err := uc.queue.PublishWithDelay(ctx, notification, delay); err != nil {
+			return nil, err
+		}
+	} else {
+		// Если время уже настало, отправляем сразу
+		if err := uc.queue.Publish(ctx, notification); err != nil {
+			return nil, err
+		}
+	}
+
+	return notification, nil
+}
+
+func (uc *notificationUseCase) GetNotification(ctx context.Context, id string) (*entity.Notification, error) {
+	return uc.repo.GetByID(ctx, id)
+}
+
+func (uc *notificationUseCase) CancelNotification(ctx context.Context, id string) error {
+	notification, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if notification == nil {
+		return fmt.Errorf("notification not found")
+	}
+
+	notification.Status = entity.StatusCancelled
+	notification.UpdatedAt = time.Now()
+
+	return uc.repo.Update(ctx, notification)
+}
+
+func (uc *notificationUseCase) ProcessScheduledNotifications(ctx context.Context, owns func(id string) bool) error {
+	pending, err := uc.repo.GetPendingNotifications(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, notification := range pending {
+		if owns != nil && !owns(notification.ID) {
+			continue
+		}
+		if notification.SendTime.Before(now) || notification.SendTime.Equal(now) {
+			if err := uc.deliver(ctx, notification.ID); err != nil {
+				fmt.Printf("Failed to send notification %s: %v\n", notification.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (uc *notificationUseCase) DeliverNotification(ctx context.Context, id string) error {
+	return uc.deliver(ctx, id)
+}
+
+// deliver отправляет уведомление под блокировкой: одно и то же уведомление может прийти
+// из очереди и из планировщика, а также обрабатываться несколькими экземплярами worker
+func (uc *notificationUseCase) deliver(ctx context.Context, id string) error {
+	locked, err := uc.repo.Lock(ctx, id, deliveryLockTTL)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		unlockCtx, cancel := statusContext(ctx)
+		defer cancel()
+		uc.repo.Unlock(unlockCtx, id)
+	}()
+
+	// Перечитываем под блокировкой: уведомление могли отменить или уже отправить
+	notification, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if notification == nil || notification.Status != entity.StatusPending || notification.SendTime.After(time.Now()) {
+		return nil
+	}
+
+	return uc.sendNotification(ctx, notification)
+}
+
+func (uc *notificationUseCase) sendNotification(ctx context.Context, notification *entity.Notification) error {
+	// Пользователь мог отписаться уже после планирования уведомления
+	allowed, err := uc.allows(ctx, notification.UserID, notificationCategory(notification))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		notification.Status = entity.StatusSkipped
+		notification.UpdatedAt = time.Now()
+		return uc.repo.Update(ctx, notification)
+	}
+
+	if err := uc.localize(ctx, notification); err != nil {
+		return err
+	}
+
+	message, err := uc.renderMessage(notification)
+	if err != nil {
+		return err
+	}
+
+	sendErr := uc.sender.Send(ctx, notification, message)
+	// Отправку прервала остановка worker: уведомление остается pending, сообщение вернется в очередь
+	if sendErr != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Результат отправки записывается и после отмены ctx, иначе при остановке worker
+	// отправленное уведомление останется pending и уйдет повторно
+	statusCtx, cancel := statusContext(ctx)
+	defer cancel()
+
+	if sendErr != nil {
+		return uc.reschedule(statusCtx, notification, sendErr)
+	}
+
+	notification.Status = entity.StatusSent
+	notification.UpdatedAt = time.Now()
+
+	if err := uc.repo.Update(statusCtx, notification); err != nil {
+		return err
+	}
+
+	if notification.CampaignID != "" {
+		return uc.campaigns.IncrementDelivered(statusCtx, notification.CampaignID, notification.Variant)
+	}
+	return nil
+}
+
+// statusContext не отменяется вместе с ctx, но ограничен statusWriteTimeout
+func statusContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), statusWriteTimeout)
+}
+
+// reschedule откладывает уведомление после ошибки провайдера. Если провайдер сообщил, через сколько
+// повторить (429 с retry_after), ждем ровно столько и не тратим попытку: уведомление не виновато
+// в превышении лимита. Иначе попытка засчитывается и задержка растет экспоненциально.
+func (uc *notificationUseCase) reschedule(ctx context.Context, notification *entity.Notification, sendErr error) error {
+	provider := uc.sender.Name()
+	now := time.Now()
+	notification.UpdatedAt = now
+
+	var delay time.Duration
+	reason := "error"
+	if throttled, ok := channel.AsThrottled(sendErr); ok {
+		uc.metrics.Throttled(provider)
+		reason = "throttled"
+		delay = throttled.RetryAfter
+	}
+
+	if delay <= 0 {
+		notification.Attempts++
+		if notification.Attempts >= uc.maxAttempts {
+			fmt.Printf("Giving up on notification %s after %d attempts: %v\n", notification.ID, notification.Attempts, sendErr)
+			notification.Status = entity.StatusFailed
+			return uc.repo.Update(ctx, notification)
+		}
+		delay = retryDelay(notification.Attempts)
+	}
+
+	fmt.Printf("Failed to send notification %s via %s, retrying in %s: %v\n", notification.ID, provider, delay, sendErr)
+	uc.metrics.Retried(provider, reason)
+
+	notification.SendTime = now.Add(delay)
+	if err := uc.repo.Update(ctx, notification); err != nil {
+		return err
+	}
+
+	// Если публикация не удалась, уведомление подберет планировщик по SendTime
+	if err := uc.queue.PublishWithDelay(ctx, notification, delay); err != nil {
+		fmt.Printf("Failed to requeue notification %s: %v\n", notification.ID, err)
+	}
+	return nil
+}
+
+// retryDelay - задержка перед повтором после attempt неудачных попыток
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// applyCampaign подставляет в уведомление шаблон варианта кампании. Пользователь,
+// уже получавший уведомления кампании, получает тот же вариант, что и раньше.
+func (uc *notificationUseCase) applyCampaign(ctx context.Context, notification *entity.Notification, campaignID string) error {
+	campaign, err := uc.campaigns.GetByID(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if campaign == nil {
+		return ErrCampaignNotFound
+	}
+
+	variant, err := uc.campaigns.AssignVariant(ctx, campaign.ID, notification.UserID, campaign.ChooseVariant(notification.UserID))
+	if err != nil {
+		return err
+	}
+
+	template := campaign.Template(variant)
+	notification.Title = template.Title
+	notification.Message = template.Message
+	notification.Category = campaign.Category
+	notification.CampaignID = campaign.ID
+	notification.Variant = variant
+
+	return nil
+}
+
+func (uc *notificationUseCase) MarkRead(ctx context.Context, id string) (*entity.Notification, error) {
+	notification, err := uc.repo.GetByID(ctx, id)
+	if err != nil || notification == nil {
+		return nil, err
+	}
+	if notification.Status != entity.StatusSent {
+		return nil, ErrNotificationNotSent
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now()
+	notification.ReadAt = &now
+	notification.UpdatedAt = now
+	if err := uc.repo.Update(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	if notification.CampaignID == "" {
+		return notification, nil
+	}
+
+	counted, err := uc.campaigns.RecordRead(ctx, notification.CampaignID, notification.Variant, notification.ID)
+	if err != nil {
+		return nil, err
+	}
+	if counted {
+		// Ошибка выбора победителя не должна мешать отметке прочтения: он будет выбран при следующем прочтении
+		if err := autoPromote(ctx, uc.campaigns, notification.CampaignID); err != nil {
+			fmt.Printf("Failed to promote campaign %s winner: %v\n", notification.CampaignID, err)
+		}
+	}
+
+	return notification, nil
+}
+
+// allows проверяет подписку пользователя на категорию с учетом значений по умолчанию
+func (uc *notificationUseCase) allows(ctx context.Context, userID, category string) (bool, error) {
+	stored, err := uc.prefs.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return entity.NewUserPreferences(userID, stored, "").Allows(category), nil
+}
+
+// renderMessage дописывает к тексту уведомления подписанную ссылку отписки от его категории.
+// От транзакционных уведомлений отписаться нельзя, поэтому ссылка к ним не добавляется.
+func (uc *notificationUseCase) renderMessage(notification *entity.Notification) (string, error) {
+	category := notificationCategory(notification)
+	if !entity.IsOptionalCategory(category) {
+		return notification.Message, nil
+	}
+
+	link, err := uc.signer.Link(notification.UserID, category)
+	if err != nil {
+		return "", err
+	}
+
+	footer := unsubscribeFooters[entity.DefaultLanguage]
+	for _, language := range entity.FallbackChain(notification.Language) {
+		if localized, ok := unsubscribeFooters[language]; ok {
+			footer = localized
+			break
+		}
+	}
+
+	return notification.Message + "\n\n" + fmt.Sprintf(footer, category, link), nil
+}
+
+// unsubscribeFooters - строка со ссылкой отписки на языках уведомлений: категория, ссылка
+var unsubscribeFooters = map[string]string{
+	"en": "Unsubscribe from %s notifications: %s",
+	"ru": "Отписаться от уведомлений категории %s: %s",
+	"uk": "Відписатися від сповіщень категорії %s: %s",
+}
+
+// localize подставляет шаблон кампании на языке получателя. Язык выбирается при отправке,
+// поэтому смена языка или новый перевод действуют и на уже запланированные уведомления.
+// Если перевода нет, используется следующий язык цепочки entity.FallbackChain.
+func (uc *notificationUseCase) localize(ctx context.Context, notification *entity.Notification) error {
+	language, err := uc.prefs.GetLanguage(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	if language == "" {
+		language = entity.DefaultLanguage
+	}
+	notification.Language = language
+
+	if notification.CampaignID == "" {
+		return nil
+	}
+
+	campaign, err := uc.campaigns.GetByID(ctx, notification.CampaignID)
+	if err != nil {
+		return err
+	}
+	if campaign == nil {
+		// Кампанию удалили - отправляем шаблон, подставленный при создании
+		return nil
+	}
+	translations, err := uc.campaigns.GetTranslations(ctx, campaign.ID)
+	if err != nil {
+		return err
+	}
+
+	template, served := campaign.Localize(translations, notification.Variant, language)
+	notification.Title = template.Title
+	notification.Message = template.Message
+	notification.Language = served
+	return nil
+}
+
+func (uc *notificationUseCase) CheckUnsubscribe(ctx context.Context, token string) (string, error) {
+	_, category, err := uc.parseUnsubscribe(token)
+	return category, err
+}
+
+func (uc *notificationUseCase) Unsubscribe(ctx context.Context, token string) (string, error) {
+	userID, category, err := uc.parseUnsubscribe(token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := uc.prefs.SetPreferences(ctx, userID, map[string]bool{category: false}); err != nil {
+		return "", err
+	}
+
+	return category, nil
+}
+
+// parseUnsubscribe проверяет токен и то, что от его категории можно отписаться
+func (uc *notificationUseCase) parseUnsubscribe(token string) (string, string, error) {
+	userID, category, err := uc.signer.Parse(token)
+	if err != nil {
+		return "", "", err
+	}
+	if !entity.IsOptionalCategory(category) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return userID, category, nil
+}
+
+// notificationCategory учитывает уведомления, созданные до появления категорий
+func notificationCategory(notification *entity.Notification) string {
+	return entity.NormalizeCategory(notification.Category)
+}
+
+func (s *notificationUseCase) GetAllNotifications(ctx context.Context) ([]*entity.Notification, error) {
+	notifications, err := s.repo.GetAllNotifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications from repository: %w", err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/database"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+type preferenceUseCase struct {
+	repo database.PreferenceRepository
+}
+
+func NewPreferenceUseCase(repo database.PreferenceRepository) PreferenceUseCase {
+	return &preferenceUseCase{repo: repo}
+}
+
+func (uc *preferenceUseCase) GetPreferences(ctx context.Context, userID string) (*entity.UserPreferences, error) {
+	stored, err := uc.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	language, err := uc.repo.GetLanguage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.NewUserPreferences(userID, stored, language), nil
+}
+
+func (uc *preferenceUseCase) UpdatePreferences(ctx context.Context, userID string, req *entity.PreferencesRequest) (*entity.UserPreferences, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.SetPreferences(ctx, userID, req.Categories); err != nil {
+		return nil, err
+	}
+	if req.Language != "" {
+		if err := uc.repo.SetLanguage(ctx, userID, req.Language); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.GetPreferences(ctx, userID)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+	ErrExpiredUnsubscribeToken = errors.New("unsubscribe token has expired")
+)
+
+// defaultUnsubscribeTTL - срок жизни ссылки отписки, если он не задан в конфигурации
+const defaultUnsubscribeTTL = 90 * 24 * time.Hour
+
+// unsubscribePayload - данные, зашитые в ссылку отписки
+type unsubscribePayload struct {
+	UserID   string `json:"u"`
+	Category string `json:"c"`
+	Expires  int64  `json:"e"` // unix-время, после которого ссылка не принимается
+}
+
+// UnsubscribeSigner выпускает и проверяет подписанные токены отписки.
+// Токен имеет вид base64url(payload).base64url(HMAC-SHA256(payload)) и действует ttl
+// с момента отправки письма.
+type UnsubscribeSigner struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewUnsubscribeSigner создает подписчика ссылок; ttl <= 0 означает срок по умолчанию (90 дней)
+func NewUnsubscribeSigner(secret, baseURL string, ttl time.Duration) *UnsubscribeSigner {
+	if ttl <= 0 {
+		ttl = defaultUnsubscribeTTL
+	}
+	return &UnsubscribeSigner{
+		secret:  []byte(secret),
+		baseURL: strings.TrimRight(baseURL, "/"),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (s *UnsubscribeSigner) Token(userID, category string) (string, error) {
+	data, err := json.Marshal(unsubscribePayload{
+		UserID:   userID,
+		Category: category,
+		Expires:  s.now().Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal unsubscribe payload: %w", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload)), nil
+}
+
+// Link возвращает публичную ссылку отписки вида <base_url>/u/<token>
+func (s *UnsubscribeSigner) Link(userID, category string) (string, error) {
+	token, err := s.Token(userID, category)
+	if err != nil {
+		return "", err
+	}
+	return s.baseURL + "/u/" + token, nil
+}
+
+// Parse проверяет подпись и срок токена и возвращает пользователя и категорию.
+// Токены без срока, выпущенные до его появления, не принимаются.
+func (s *UnsubscribeSigner) Parse(token string) (string, string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	var p unsubscribePayload
+	if err := json.Unmarshal(data, &p); err != nil || p.UserID == "" || p.Category == "" || p.Expires == 0 {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	if !s.now().Before(time.Unix(p.Expires, 0)) {
+		return "", "", ErrExpiredUnsubscribeToken
+	}
+
+	return p.UserID, p.Category, nil
+}
+
+func (s *UnsubscribeSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
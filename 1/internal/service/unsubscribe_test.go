@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+)
+
+// TestUnsubscribeTokenRoundTrip проверяет, что выпущенный токен разбирается обратно
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	signer := NewUnsubscribeSigner("secret", "http://localhost:8080/", time.Hour)
+
+	link, err := signer.Link("user-1", entity.CategoryMarketing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, ok := strings.CutPrefix(link, "http://localhost:8080/u/")
+	if !ok {
+		t.Fatalf("unexpected link %q", link)
+	}
+
+	userID, category, err := signer.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse = %v", err)
+	}
+	if userID != "user-1" || category != entity.CategoryMarketing {
+		t.Fatalf("Parse = %q, %q", userID, category)
+	}
+}
+
+// TestUnsubscribeTokenRejected проверяет, что подделанный, чужой и просроченный токены не принимаются
+func TestUnsubscribeTokenRejected(t *testing.T) {
+	issued := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	signer := NewUnsubscribeSigner("secret", "", time.Hour)
+	signer.now = func() time.Time { return issued }
+
+	token, err := signer.Token("user-1", entity.CategoryMarketing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	other := NewUnsubscribeSigner("other-secret", "", time.Hour)
+	other.now = signer.now
+
+	cases := []struct {
+		name   string
+		signer *UnsubscribeSigner
+		token  string
+		now    time.Time
+		want   error
+	}{
+		{"tampered payload", signer, payload + "x." + signature, issued, ErrInvalidUnsubscribeToken},
+		{"no signature", signer, payload, issued, ErrInvalidUnsubscribeToken},
+		{"another secret", other, token, issued, ErrInvalidUnsubscribeToken},
+		{"expired", signer, token, issued.Add(time.Hour), ErrExpiredUnsubscribeToken},
+		{"valid", signer, token, issued.Add(time.Hour - time.Second), nil},
+	}
+
+	for _, tc := range cases {
+		now := tc.now
+		tc.signer.now = func() time.Time { return now }
+		if _, _, err := tc.signer.Parse(tc.token); !errors.Is(err, tc.want) {
+			t.Errorf("%s: Parse = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CampaignHandler struct {
+	service service.CampaignUseCase
+}
+
+func NewCampaignHandler(service service.CampaignUseCase) *CampaignHandler {
+	return &CampaignHandler{service: service}
+}
+
+func (h *CampaignHandler) CreateCampaign(c *gin.Context) {
+	var req entity.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err := h.service.CreateCampaign(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func (h *CampaignHandler) GetCampaign(c *gin.Context) {
+	campaign, err := h.service.GetCampaign(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if campaign == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// GetCampaignStats возвращает доли доставки и прочтения каждого варианта
+func (h *CampaignHandler) GetCampaignStats(c *gin.Context) {
+	stats, err := h.service.GetCampaignStats(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *CampaignHandler) PromoteVariant(c *gin.Context) {
+	var req entity.PromoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, err := h.service.PromoteVariant(c.Request.Context(), c.Param("id"), req.Variant)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// SetTranslation сохраняет перевод шаблонов кампании на язык из пути и возвращает полноту переводов
+func (h *CampaignHandler) SetTranslation(c *gin.Context) {
+	var req entity.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.SetTranslation(c.Request.Context(), c.Param("id"), c.Param("language"), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTranslations возвращает переводы кампании и полноту перевода на каждый язык
+func (h *CampaignHandler) GetTranslations(c *gin.Context) {
+	report, err := h.service.GetTranslations(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *CampaignHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrCampaignNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if errors.Is(err, service.ErrUnsupportedLanguage) || errors.Is(err, service.ErrBaseLanguage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,210 @@
+package transport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/config"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Значения по умолчанию, если в конфигурации раздел Import не задан
+const (
+	defaultImportMaxFileSize = 64 << 20
+	defaultImportTimeout     = 10 * time.Minute
+)
+
+// importErrorsPage - сколько ошибок строк читается из хранилища за раз при скачивании отчета
+const importErrorsPage = 500
+
+type ImportHandler struct {
+	service     service.ImportUseCase
+	maxFileSize int64
+	timeout     time.Duration
+}
+
+func NewImportHandler(service service.ImportUseCase, cfg config.ImportConfig) *ImportHandler {
+	h := &ImportHandler{service: service, maxFileSize: cfg.MaxFileSize, timeout: cfg.Timeout}
+	if h.maxFileSize <= 0 {
+		h.maxFileSize = defaultImportMaxFileSize
+	}
+	if h.timeout <= 0 {
+		h.timeout = defaultImportTimeout
+	}
+	return h
+}
+
+// ImportNotifications принимает multipart-форму с полем file (CSV или JSONL) и планирует
+// уведомления построчно, не загружая файл в память целиком. Формат берется из ?format=
+// или из расширения файла. Ошибочные строки пропускаются и доступны в отчете об ошибках.
+func (h *ImportHandler) ImportNotifications(c *gin.Context) {
+	// Файл читается и обрабатывается дольше, чем разрешают общие таймауты сервера
+	deadline := time.Now().Add(h.timeout)
+	controller := http.NewResponseController(c.Writer)
+	_ = controller.SetReadDeadline(deadline)
+	_ = controller.SetWriteDeadline(deadline)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected multipart/form-data with a file field"})
+		return
+	}
+
+	// Части формы читаются по порядку: все до поля file пропускаются
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file field is required"})
+			return
+		}
+		if err != nil {
+			h.respondReadError(c, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		format := strings.ToLower(c.Query("format"))
+		if format == "" {
+			format = importFormat(part.FileName())
+		}
+
+		report, err := h.service.ImportNotifications(c.Request.Context(), format, part)
+		part.Close()
+		if err != nil {
+			h.respondImportError(c, report, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, withErrorReportURL(report))
+		return
+	}
+}
+
+// GetImport возвращает итог импорта
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	report, err := h.service.GetReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, withErrorReportURL(report))
+}
+
+// GetImportErrors отдает ошибки строк файлом CSV (line,error), читая их из хранилища частями
+func (h *ImportHandler) GetImportErrors(c *gin.Context) {
+	id := c.Param("id")
+
+	report, err := h.service.GetReport(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+
+	// Первая часть читается до заголовков, чтобы недоступное хранилище дало 500, а не пустой файл
+	rows, err := h.service.GetErrors(c.Request.Context(), id, 0, importErrorsPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, id))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"line", "error"})
+
+	for offset := int64(0); ; {
+		for _, row := range rows {
+			w.Write([]string{strconv.Itoa(row.Line), row.Error})
+		}
+		w.Flush()
+
+		if len(rows) < importErrorsPage {
+			return
+		}
+
+		offset += importErrorsPage
+		rows, err = h.service.GetErrors(c.Request.Context(), id, offset, importErrorsPage)
+		if err != nil {
+			// Заголовки уже отправлены, остается только записать ошибку в журнал запроса
+			c.Error(err)
+			return
+		}
+	}
+}
+
+// respondImportError отвечает на ошибку импорта; если он успел начаться, в ответ входит отчет
+func (h *ImportHandler) respondImportError(c *gin.Context, report *entity.ImportReport, err error) {
+	status := http.StatusInternalServerError
+	body := gin.H{"error": err.Error()}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		status = http.StatusRequestEntityTooLarge
+		body["error"] = fmt.Sprintf("file is larger than %d bytes", tooLarge.Limit)
+	case errors.Is(err, service.ErrInvalidImport):
+		status = http.StatusBadRequest
+	}
+
+	if report != nil {
+		body["import"] = withErrorReportURL(report)
+	}
+	c.JSON(status, body)
+}
+
+func (h *ImportHandler) respondReadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file is larger than %d bytes", tooLarge.Limit)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// importFormat определяет формат по расширению файла
+func importFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return entity.ImportFormatCSV
+	case ".jsonl", ".ndjson":
+		return entity.ImportFormatJSONL
+	default:
+		return ""
+	}
+}
+
+// withErrorReportURL добавляет ссылку на скачивание ошибок, если они есть
+func withErrorReportURL(report *entity.ImportReport) *entity.ImportReport {
+	if report != nil && report.Failed > 0 {
+		report.ErrorReportURL = fmt.Sprintf("/api/v1/notify/import/%s/errors", report.ID)
+	}
+	return report
+}
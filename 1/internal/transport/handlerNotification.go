@@ -1,78 +1,142 @@
-package transport
-
-import (
-	"net/http"
-
-	"github.com/ds124wfegd/WB_L3/1/internal/entity"
-	"github.com/ds124wfegd/WB_L3/1/internal/service"
-
-	"github.com/gin-gonic/gin"
-)
-
-type NotificationHandler struct {
-	service service.NotificationUseCase
-}
-
-func NewNotificationHandler(service service.NotificationUseCase) *NotificationHandler {
-	return &NotificationHandler{service: service}
-}
-
-func (h *NotificationHandler) CreateNotification(c *gin.Context) {
-	var req entity.NotificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	notification, err := h.service.CreateNotification(c.Request.Context(), &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, notification)
-}
-
-func (h *NotificationHandler) GetNotification(c *gin.Context) {
-	id := c.Param("id")
-
-	notification, err := h.service.GetNotification(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if notification == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, notification)
-}
-
-func (h *NotificationHandler) CancelNotification(c *gin.Context) {
-	id := c.Param("id")
-
-	if err := h.service.CancelNotification(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Notification cancelled"})
-}
-
-func (h *NotificationHandler) GetNotifications(c *gin.Context) {
-	notifications, err := h.service.GetAllNotifications(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get notifications",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"notifications": notifications,
-		"count":         len(notifications),
-	})
-}
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	service service.NotificationUseCase
+}
+
+func NewNotificationHandler(service service.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func (h *NotificationHandler) CreateNotification(c *gin.Context) {
+	var req entity.NotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	notification, err := h.service.CreateNotification(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrCampaignNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, notification)
+}
+
+func (h *NotificationHandler) GetNotification(c *gin.Context) {
+	id := c.Param("id")
+
+	notification, err := h.service.GetNotification(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if notification == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (h *NotificationHandler) CancelNotification(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.CancelNotification(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification cancelled"})
+}
+
+// MarkRead отмечает уведомление прочитанным; для уведомлений кампании это учитывается в доле прочтений варианта
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	notification, err := h.service.MarkRead(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotificationNotSent) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if notification == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	notifications, err := h.service.GetAllNotifications(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get notifications",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"count":         len(notifications),
+	})
+}
+
+// UnsubscribeConfirm - публичная ссылка из уведомления. GET только показывает форму
+// подтверждения: почтовые сканеры открывают ссылки заранее, и отписка по GET срабатывала бы без ведома пользователя
+func (h *NotificationHandler) UnsubscribeConfirm(c *gin.Context) {
+	category, err := h.service.CheckUnsubscribe(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		unsubscribeError(c, err)
+		return
+	}
+
+	page := fmt.Sprintf(`<form method="post"><p>Unsubscribe from %s notifications?</p><button type="submit">Unsubscribe</button></form>`,
+		html.EscapeString(category))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// Unsubscribe отписывает пользователя от категории по POST из формы подтверждения
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	category, err := h.service.Unsubscribe(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		unsubscribeError(c, err)
+		return
+	}
+
+	page := fmt.Sprintf("<p>You have been unsubscribed from %s notifications.</p>", html.EscapeString(category))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+func unsubscribeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExpiredUnsubscribeToken):
+		c.Data(http.StatusGone, "text/html; charset=utf-8", []byte("<p>This unsubscribe link has expired. Use the link from a recent notification.</p>"))
+	case errors.Is(err, service.ErrInvalidUnsubscribeToken):
+		c.Data(http.StatusBadRequest, "text/html; charset=utf-8", []byte("<p>This unsubscribe link is invalid.</p>"))
+	default:
+		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte("<p>Failed to unsubscribe, please try again later.</p>"))
+	}
+}
@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/database"
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// preferenceRecorder запоминает сохраненные настройки подписок
+type preferenceRecorder struct {
+	database.PreferenceRepository
+	saved []map[string]bool
+}
+
+func (r *preferenceRecorder) SetPreferences(ctx context.Context, userID string, categories map[string]bool) error {
+	r.saved = append(r.saved, categories)
+	return nil
+}
+
+// TestUnsubscribeRequiresPost проверяет, что открытие ссылки только показывает подтверждение,
+// а отписка выполняется по POST
+func TestUnsubscribeRequiresPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := service.NewUnsubscribeSigner("secret", "", time.Hour)
+	token, err := signer.Token("user-1", entity.CategoryMarketing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefs := &preferenceRecorder{}
+	handler := NewNotificationHandler(service.NewNotificationUseCase(nil, prefs, nil, nil, signer, nil, nil, 3))
+	router := gin.New()
+	router.GET("/u/:token", handler.UnsubscribeConfirm)
+	router.POST("/u/:token", handler.Unsubscribe)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/u/"+token, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(prefs.saved) != 0 {
+		t.Fatalf("GET must not unsubscribe, saved %v", prefs.saved)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/u/"+token, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(prefs.saved) != 1 || prefs.saved[0][entity.CategoryMarketing] {
+		t.Fatalf("POST should opt out of %s, saved %v", entity.CategoryMarketing, prefs.saved)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/u/"+token+"x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("tampered token status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
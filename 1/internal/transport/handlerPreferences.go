@@ -0,0 +1,48 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/1/internal/entity"
+	"github.com/ds124wfegd/WB_L3/1/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PreferenceHandler struct {
+	service service.PreferenceUseCase
+}
+
+func NewPreferenceHandler(service service.PreferenceUseCase) *PreferenceHandler {
+	return &PreferenceHandler{service: service}
+}
+
+func (h *PreferenceHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.service.GetPreferences(c.Request.Context(), c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *PreferenceHandler) UpdatePreferences(c *gin.Context) {
+	var req entity.PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(c.Request.Context(), c.Param("user_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
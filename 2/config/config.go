@@ -1,98 +1,154 @@
-// Ininicializing common application configuration
-package config
-
-import (
-	"log"
-	"os"
-	"time"
-
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	App      AppConfig      `mapstructure:"app"`
-}
-
-type ServerConfig struct {
-	AppVersion   string `json:"appVersion"`
-	Host         string `json:"host" validate:"required"`
-	Port         string `json:"port" validate:"required"`
-	Timeout      time.Duration
-	Idle_timeout time.Duration
-	Env          string `json:"environment"`
-	Mode         string `mapstructure:"mode"`
-}
-
-type RedisConfig struct {
-	URL      string `json:"URL"`
-	Host     string `json:"host" validate:"required"`
-	Port     int    `json:"port" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	DB       int    `json:"db" validate:"required"`
-
-	// Настройки пула соединений
-	MaxRetries   int
-	PoolSize     int
-	MinIdleConns int
-	MaxIdleConns int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	PoolTimeout  time.Duration
-}
-
-type DatabaseConfig struct {
-	Host            string        `mapstructure:"host"`
-	Port            int           `mapstructure:"port"`
-	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
-	DBName          string        `mapstructure:"dbname"`
-	SSLMode         string        `mapstructure:"sslmode"`
-	MaxOpenConns    int           `mapstructure:"max_open_conns"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
-	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
-}
-
-type AppConfig struct {
-	ShortURLLength int           `mapstructure:"short_url_length"`
-	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
-	BaseURL        string        `mapstructure:"base_url"`
-}
-
-func LoadConfig() (*viper.Viper, error) {
-
-	viperInstance := viper.New()
-
-	viperInstance.AddConfigPath("./config")
-	viperInstance.SetConfigName("config")
-	viperInstance.SetConfigType("yaml")
-
-	err := viperInstance.ReadInConfig()
-
-	if err != nil {
-		return nil, err
-	}
-	return viperInstance, nil
-}
-
-func ParseConfig(v *viper.Viper) (*Config, error) {
-
-	var c Config
-
-	err := v.Unmarshal(&c)
-	if err != nil {
-		log.Fatalf("unable to decode config into struct, %v", err)
-		return nil, err
-	}
-	return &c, nil
-}
-
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+// Ininicializing common application configuration
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Server   ServerConfig   `mapstructure:"server"`
+	Database DatabaseConfig `mapstructure:"database"`
+	Redis    RedisConfig    `mapstructure:"redis"`
+	App      AppConfig      `mapstructure:"app"`
+	Export   ExportConfig   `mapstructure:"export"`
+	Privacy  PrivacyConfig  `mapstructure:"privacy"`
+}
+
+type ServerConfig struct {
+	AppVersion   string `json:"appVersion"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required"`
+	Timeout      time.Duration
+	Idle_timeout time.Duration
+	Env          string `json:"environment"`
+	Mode         string `mapstructure:"mode"`
+	LogLevel     string `mapstructure:"log_level"` // debug, info, warn or error, applied without a restart
+
+	// Proxies allowed to pass the client IP in X-Forwarded-For, none if empty
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+type RedisConfig struct {
+	URL      string `json:"URL"`
+	Host     string `json:"host" validate:"required"`
+	Port     int    `json:"port" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	DB       int    `json:"db" validate:"required"`
+
+	// Настройки пула соединений
+	MaxRetries   int
+	PoolSize     int
+	MinIdleConns int
+	MaxIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+type DatabaseConfig struct {
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port"`
+	User            string        `mapstructure:"user"`
+	Password        string        `mapstructure:"password"`
+	DBName          string        `mapstructure:"dbname"`
+	SSLMode         string        `mapstructure:"sslmode"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+}
+
+type AppConfig struct {
+	ShortURLLength int           `mapstructure:"short_url_length"`
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	BaseURL        string        `mapstructure:"base_url"`
+
+	// Timeout for fetching the destination page when tagging a new link
+	MetadataFetchTimeout time.Duration `mapstructure:"metadata_fetch_timeout"`
+
+	Canonical CanonicalConfig `mapstructure:"canonical"`
+}
+
+// CanonicalConfig controls how destinations are normalized before links are deduplicated
+type CanonicalConfig struct {
+	UpgradeHTTPS bool          `mapstructure:"upgrade_https"`
+	ProbeTimeout time.Duration `mapstructure:"probe_timeout"`
+	// Query parameters to strip, a trailing "*" matches a prefix
+	StripParams []string `mapstructure:"strip_params"`
+}
+
+// ExportConfig controls streaming of click events to external analytics stores,
+// a sink is enabled when its address (ClickHouse) or project (BigQuery) is set
+type ExportConfig struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	BatchSize   int           `mapstructure:"batch_size"`
+	SettleDelay time.Duration `mapstructure:"settle_delay"`
+
+	ClickHouse ClickHouseConfig `mapstructure:"clickhouse"`
+	BigQuery   BigQueryConfig   `mapstructure:"bigquery"`
+}
+
+type ClickHouseConfig struct {
+	URL      string        `mapstructure:"url"`
+	Database string        `mapstructure:"database"`
+	Table    string        `mapstructure:"table"`
+	User     string        `mapstructure:"user"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+type BigQueryConfig struct {
+	Project     string        `mapstructure:"project"`
+	Dataset     string        `mapstructure:"dataset"`
+	Table       string        `mapstructure:"table"`
+	AccessToken string        `mapstructure:"access_token"`
+	TokenFile   string        `mapstructure:"token_file"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+// PrivacyConfig controls which visitor data is stored with a click, applied without a restart
+type PrivacyConfig struct {
+	IPMode string `mapstructure:"ip_mode"` // full, truncate, hash or drop
+	IPSalt string `mapstructure:"ip_salt"` // key of the hash mode, changing it breaks grouping by address
+	// HonorDNT stores clicks of visitors sending DNT: 1 or Sec-GPC: 1 without user agent, IP and referer
+	HonorDNT bool `mapstructure:"honor_dnt"`
+}
+
+func LoadConfig() (*viper.Viper, error) {
+
+	viperInstance := viper.New()
+
+	viperInstance.AddConfigPath("./config")
+	viperInstance.SetConfigName("config")
+	viperInstance.SetConfigType("yaml")
+
+	err := viperInstance.ReadInConfig()
+
+	if err != nil {
+		return nil, err
+	}
+	return viperInstance, nil
+}
+
+func ParseConfig(v *viper.Viper) (*Config, error) {
+
+	var c Config
+
+	// the error is returned rather than fatal, ParseConfig also runs on config reload
+	err := v.Unmarshal(&c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+	return &c, nil
+}
+
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
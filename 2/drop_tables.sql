@@ -1,6 +1,11 @@
-DROP INDEX IF EXISTS idx_clicks_timestamp;
-DROP INDEX IF EXISTS idx_clicks_short_url;
-DROP INDEX IF EXISTS idx_urls_short_url;
-
-DROP TABLE IF EXISTS clicks;
-DROP TABLE IF EXISTS urls;
+DROP INDEX IF EXISTS idx_url_tags_tag;
+DROP INDEX IF EXISTS idx_clicks_seq;
+DROP INDEX IF EXISTS idx_clicks_timestamp;
+DROP INDEX IF EXISTS idx_clicks_short_url;
+DROP INDEX IF EXISTS idx_urls_short_url;
+DROP INDEX IF EXISTS idx_urls_skeleton;
+
+DROP TABLE IF EXISTS export_checkpoints;
+DROP TABLE IF EXISTS url_tags;
+DROP TABLE IF EXISTS clicks;
+DROP TABLE IF EXISTS urls;
@@ -1,20 +1,62 @@
-CREATE TABLE IF NOT EXISTS urls (
-    id VARCHAR(36) PRIMARY KEY,
-    original_url TEXT NOT NULL,
-    short_url VARCHAR(50) UNIQUE NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    clicks INTEGER DEFAULT 0
-);
-
-CREATE TABLE IF NOT EXISTS clicks (
-    id VARCHAR(36) PRIMARY KEY,
-    short_url VARCHAR(50) NOT NULL,
-    user_agent TEXT,
-    ip_address VARCHAR(45),
-    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (short_url) REFERENCES urls(short_url) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_urls_short_url ON urls(short_url);
-CREATE INDEX IF NOT EXISTS idx_clicks_short_url ON clicks(short_url);
-CREATE INDEX IF NOT EXISTS idx_clicks_timestamp ON clicks(timestamp);
\ No newline at end of file
+CREATE TABLE IF NOT EXISTS urls (
+    id VARCHAR(36) PRIMARY KEY,
+    original_url TEXT NOT NULL,
+    canonical_url TEXT NOT NULL DEFAULT '',
+    short_url VARCHAR(50) UNIQUE NOT NULL,
+    skeleton VARCHAR(200) NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    clicks INTEGER DEFAULT 0,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    allowed_referers TEXT[] NOT NULL DEFAULT '{}',
+    -- per-link overrides of the card shown by messenger link previews
+    preview_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    preview_title TEXT NOT NULL DEFAULT '',
+    preview_description TEXT NOT NULL DEFAULT '',
+    preview_image TEXT NOT NULL DEFAULT '',
+    -- clicks of a private link are stored without user agent, IP and referer
+    private BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS clicks (
+    id VARCHAR(36) PRIMARY KEY,
+    seq BIGSERIAL NOT NULL,
+    short_url VARCHAR(50) NOT NULL,
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    referer TEXT NOT NULL DEFAULT '',
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    blocked BOOLEAN NOT NULL DEFAULT FALSE,
+    block_reason VARCHAR(20) NOT NULL DEFAULT '',
+    -- a private link or a Do-Not-Track visitor, user_agent, ip_address and referer are empty
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (short_url) REFERENCES urls(short_url) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS url_tags (
+    short_url VARCHAR(50) NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (short_url, tag),
+    FOREIGN KEY (short_url) REFERENCES urls(short_url) ON DELETE CASCADE
+);
+
+-- Position of every sink exporting clicks to external analytics, by clicks.seq
+CREATE TABLE IF NOT EXISTS export_checkpoints (
+    sink VARCHAR(50) PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Databases created before lookalike detection get the column empty, the service fills it
+-- on startup (URLService.BackfillSkeletons): the skeleton is computed in Go, not in SQL
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS skeleton VARCHAR(200) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_urls_short_url ON urls(short_url);
+CREATE INDEX IF NOT EXISTS idx_urls_skeleton ON urls(skeleton);
+CREATE INDEX IF NOT EXISTS idx_urls_canonical_url ON urls(canonical_url);
+CREATE INDEX IF NOT EXISTS idx_clicks_short_url ON clicks(short_url);
+CREATE INDEX IF NOT EXISTS idx_clicks_timestamp ON clicks(timestamp);
+CREATE INDEX IF NOT EXISTS idx_clicks_seq ON clicks(seq);
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags(tag);
\ No newline at end of file
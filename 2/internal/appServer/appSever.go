@@ -1,106 +1,230 @@
-// launching the server, DB, kafka, postgres
-package appServer
-
-import (
-	"context"
-	"crypto/tls"
-	"log"
-
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/2/config"
-	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
-	redisRepo "github.com/ds124wfegd/WB_L3/2/internal/database/redis"
-	database "github.com/ds124wfegd/WB_L3/2/internal/pkg/postgres"
-	"github.com/ds124wfegd/WB_L3/2/internal/pkg/redis"
-	"github.com/ds124wfegd/WB_L3/2/internal/service"
-	"github.com/ds124wfegd/WB_L3/2/internal/transport"
-	"github.com/gin-gonic/gin"
-
-	"github.com/sirupsen/logrus"
-)
-
-type Server struct {
-	httpServer *http.Server
-}
-
-func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:              ":" + cfg.Server.Port,
-		Handler:           handler,
-		MaxHeaderBytes:    1 << 20,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      cfg.Server.Timeout,
-		IdleTimeout:       cfg.Server.Idle_timeout,
-		ReadHeaderTimeout: 3 * time.Second,
-		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
-		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
-	}
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-func NewServer(cfg *config.Config) {
-
-	logrus.SetFormatter(new(logrus.JSONFormatter))
-
-	db, err := database.NewPostgresDB(&cfg.Database)
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer db.Close()
-
-	redisClient := redis.NewRedisClient(&cfg.Redis)
-	defer redisClient.Close()
-
-	urlRepo := postgres.NewURLRepository(db)
-	analyticsRepo := postgres.NewAnalyticsRepository(db)
-	cacheRepo := redisRepo.NewCacheRepository(redisClient, cfg.App.CacheTTL)
-
-	urlService := service.NewURLService(
-		urlRepo,
-		analyticsRepo,
-		cacheRepo,
-		&service.URLServiceConfig{
-			ShortURLLength: cfg.App.ShortURLLength,
-			BaseURL:        cfg.App.BaseURL,
-			CacheTTL:       cfg.App.CacheTTL,
-		},
-	)
-
-	analyticsService := service.NewAnalyticsService(analyticsRepo, urlRepo)
-
-	urlHandler := transport.NewURLHandler(urlService)
-	analyticsHandler := transport.NewAnalyticsHandler(analyticsService)
-
-	if cfg.Server.Mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	srv := new(Server)
-	go func() {
-		if err := srv.Run(cfg, transport.InitRoutes(urlHandler, analyticsHandler)); err != nil {
-			logrus.Fatalf("error occured while running http server: %s", err.Error())
-		}
-	}()
-
-	logrus.Print("App Started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
-
-	logrus.Print("App Shutting Down")
-
-	if err := srv.Shutdown(context.Background()); err != nil {
-		logrus.Errorf("error occured on server shutting down: %s", err.Error())
-	}
-
-}
+// launching the server, DB, kafka, postgres
+package appServer
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/config"
+	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
+	redisRepo "github.com/ds124wfegd/WB_L3/2/internal/database/redis"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/canonical"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/classifier"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/export"
+	database "github.com/ds124wfegd/WB_L3/2/internal/pkg/postgres"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/privacy"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/redis"
+	"github.com/ds124wfegd/WB_L3/2/internal/service"
+	"github.com/ds124wfegd/WB_L3/2/internal/transport"
+	"github.com/gin-gonic/gin"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Server struct {
+	httpServer *http.Server
+}
+
+func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
+	s.httpServer = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		MaxHeaderBytes:    1 << 20,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      cfg.Server.Timeout,
+		IdleTimeout:       cfg.Server.Idle_timeout,
+		ReadHeaderTimeout: 3 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
+		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func NewServer(cfg *config.Config, watcher *config.Watcher) {
+
+	logrus.SetFormatter(new(logrus.JSONFormatter))
+	setLogLevel(cfg.Server.LogLevel)
+
+	db, err := database.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	redisClient := redis.NewRedisClient(&cfg.Redis)
+	defer redisClient.Close()
+
+	urlRepo := postgres.NewURLRepository(db)
+	analyticsRepo := postgres.NewAnalyticsRepository(db)
+	cacheRepo := redisRepo.NewCacheRepository(redisClient, cfg.App.CacheTTL)
+
+	taggingService := service.NewTaggingService(
+		urlRepo,
+		classifier.NewFetcher(cfg.App.MetadataFetchTimeout),
+		classifier.NewRuleClassifier(),
+		cfg.App.MetadataFetchTimeout,
+	)
+
+	// https probes go through the same fetcher that refuses private addresses
+	normalizer := canonical.New(canonical.Options{
+		UpgradeHTTPS: cfg.App.Canonical.UpgradeHTTPS,
+		ProbeTimeout: cfg.App.Canonical.ProbeTimeout,
+		StripParams:  cfg.App.Canonical.StripParams,
+	}, classifier.NewFetcher(cfg.App.Canonical.ProbeTimeout))
+
+	policy, err := privacyPolicy(&cfg.Privacy)
+	if err != nil {
+		log.Fatalf("Invalid privacy config: %v", err)
+	}
+
+	urlService := service.NewURLService(
+		urlRepo,
+		analyticsRepo,
+		cacheRepo,
+		taggingService,
+		normalizer,
+		&service.URLServiceConfig{
+			ShortURLLength: cfg.App.ShortURLLength,
+			BaseURL:        cfg.App.BaseURL,
+			CacheTTL:       cfg.App.CacheTTL,
+			Privacy:        policy,
+		},
+	)
+
+	// Until the backfill finishes, old links are not protected from lookalike aliases
+	if updated, err := urlService.BackfillSkeletons(); err != nil {
+		logrus.Errorf("Failed to backfill short URL skeletons: %v", err)
+	} else if updated > 0 {
+		logrus.Infof("Backfilled skeletons of %d short URLs", updated)
+	}
+
+	analyticsService := service.NewAnalyticsService(analyticsRepo, urlRepo)
+
+	exportCtx, stopExport := context.WithCancel(context.Background())
+	defer stopExport()
+	if sinks := exportSinks(&cfg.Export); len(sinks) > 0 {
+		exporter := service.NewClickExporter(postgres.NewExportRepository(db), sinks, clickExporterConfig(&cfg.Export))
+		go exporter.Run(exportCtx)
+
+		// sinks are built once, adding or removing one takes a restart
+		watcher.Subscribe("click export", func(cfg *config.Config) {
+			exporter.UpdateConfig(clickExporterConfig(&cfg.Export))
+		})
+	}
+
+	watcher.Subscribe("privacy", func(cfg *config.Config) {
+		policy, err := privacyPolicy(&cfg.Privacy)
+		if err != nil {
+			logrus.Errorf("Privacy config not applied: %v", err)
+			return
+		}
+		urlService.UpdatePrivacy(policy)
+	})
+	watcher.Subscribe("log level", func(cfg *config.Config) {
+		setLogLevel(cfg.Server.LogLevel)
+	})
+	if err := watcher.Start(); err != nil {
+		logrus.Warnf("Config hot reload disabled: %v", err)
+	}
+	defer watcher.Close()
+
+	urlHandler := transport.NewURLHandler(urlService)
+	analyticsHandler := transport.NewAnalyticsHandler(analyticsService)
+
+	if cfg.Server.Mode == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router, err := transport.InitRoutes(urlHandler, analyticsHandler, cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to initialize routes: %v", err)
+	}
+
+	srv := new(Server)
+	go func() {
+		if err := srv.Run(cfg, router); err != nil {
+			logrus.Fatalf("error occured while running http server: %s", err.Error())
+		}
+	}()
+
+	logrus.Print("App Started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	<-quit
+
+	logrus.Print("App Shutting Down")
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		logrus.Errorf("error occured on server shutting down: %s", err.Error())
+	}
+
+}
+
+func clickExporterConfig(cfg *config.ExportConfig) service.ClickExporterConfig {
+	return service.ClickExporterConfig{
+		Interval:    cfg.Interval,
+		BatchSize:   cfg.BatchSize,
+		SettleDelay: cfg.SettleDelay,
+	}
+}
+
+func privacyPolicy(cfg *config.PrivacyConfig) (*privacy.Policy, error) {
+	return privacy.New(privacy.Options{
+		IPMode:   cfg.IPMode,
+		IPSalt:   cfg.IPSalt,
+		HonorDNT: cfg.HonorDNT,
+	})
+}
+
+// setLogLevel applies server.log_level, an empty or unknown level means info
+func setLogLevel(level string) {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		if level != "" {
+			logrus.Warnf("Unknown log level %q, using info", level)
+		}
+		parsed = logrus.InfoLevel
+	}
+	logrus.SetLevel(parsed)
+}
+
+// exportSinks builds the configured analytics sinks, none if export is not set up
+func exportSinks(cfg *config.ExportConfig) []export.Sink {
+	var sinks []export.Sink
+
+	if cfg.ClickHouse.URL != "" {
+		sinks = append(sinks, export.NewClickHouseSink(export.ClickHouseOptions{
+			URL:      cfg.ClickHouse.URL,
+			Database: cfg.ClickHouse.Database,
+			Table:    cfg.ClickHouse.Table,
+			User:     cfg.ClickHouse.User,
+			Password: cfg.ClickHouse.Password,
+			Timeout:  cfg.ClickHouse.Timeout,
+		}))
+	}
+
+	if cfg.BigQuery.Project != "" {
+		sinks = append(sinks, export.NewBigQuerySink(export.BigQueryOptions{
+			Project:     cfg.BigQuery.Project,
+			Dataset:     cfg.BigQuery.Dataset,
+			Table:       cfg.BigQuery.Table,
+			AccessToken: cfg.BigQuery.AccessToken,
+			TokenFile:   cfg.BigQuery.TokenFile,
+			Timeout:     cfg.BigQuery.Timeout,
+		}))
+	}
+
+	return sinks
+}
@@ -1,91 +1,163 @@
-package postgres
-
-import (
-	"database/sql"
-
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-)
-
-type AnalyticsRepository struct {
-	db *sql.DB
-}
-
-func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
-	return &AnalyticsRepository{db: db}
-}
-
-func (r *AnalyticsRepository) RecordClick(click *entity.Click) error {
-	query := `INSERT INTO clicks (id, short_url, user_agent, ip_address, timestamp) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(query, click.ID, click.ShortURL, click.UserAgent, click.IPAddress, click.Timestamp)
-	return err
-}
-
-func (r *AnalyticsRepository) GetAnalytics(shortURL string) (*entity.Analytics, error) {
-	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM urls WHERE short_url = $1)", shortURL).Scan(&exists)
-	if err != nil {
-		return nil, err
-	}
-	if !exists {
-		return nil, sql.ErrNoRows
-	}
-
-	var totalClicks int
-	err = r.db.QueryRow("SELECT clicks FROM urls WHERE short_url = $1", shortURL).Scan(&totalClicks)
-	if err != nil {
-		return nil, err
-	}
-
-	dailyQuery := `
-        SELECT DATE(timestamp) as date, COUNT(*) as clicks 
-        FROM clicks 
-        WHERE short_url = $1 
-        GROUP BY DATE(timestamp) 
-        ORDER BY date DESC
-        LIMIT 30
-    `
-	rows, err := r.db.Query(dailyQuery, shortURL)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var dailyStats []entity.DailyStat
-	for rows.Next() {
-		var stat entity.DailyStat
-		err := rows.Scan(&stat.Date, &stat.Clicks)
-		if err != nil {
-			return nil, err
-		}
-		dailyStats = append(dailyStats, stat)
-	}
-
-	uaQuery := `
-        SELECT user_agent, COUNT(*) as clicks 
-        FROM clicks 
-        WHERE short_url = $1 
-        GROUP BY user_agent 
-        ORDER BY clicks DESC
-    `
-	uaRows, err := r.db.Query(uaQuery, shortURL)
-	if err != nil {
-		return nil, err
-	}
-	defer uaRows.Close()
-
-	var userAgents []entity.UserAgentStat
-	for uaRows.Next() {
-		var ua entity.UserAgentStat
-		err := uaRows.Scan(&ua.UserAgent, &ua.Clicks)
-		if err != nil {
-			return nil, err
-		}
-		userAgents = append(userAgents, ua)
-	}
-
-	return &entity.Analytics{
-		TotalClicks: totalClicks,
-		DailyStats:  dailyStats,
-		UserAgents:  userAgents,
-	}, nil
-}
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+func (r *AnalyticsRepository) RecordClick(click *entity.Click) error {
+	query := `INSERT INTO clicks (id, short_url, user_agent, ip_address, referer, timestamp, blocked, block_reason, anonymous) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.Exec(query, click.ID, click.ShortURL, click.UserAgent, click.IPAddress, click.Referer, click.Timestamp, click.Blocked, click.BlockReason, click.Anonymous)
+	return err
+}
+
+func (r *AnalyticsRepository) GetAnalytics(shortURL string) (*entity.Analytics, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM urls WHERE short_url = $1)", shortURL).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	var totalClicks int
+	err = r.db.QueryRow("SELECT clicks FROM urls WHERE short_url = $1", shortURL).Scan(&totalClicks)
+	if err != nil {
+		return nil, err
+	}
+
+	dailyQuery := `
+        SELECT DATE(timestamp) as date, COUNT(*) as clicks 
+        FROM clicks 
+        WHERE short_url = $1 AND NOT blocked
+        GROUP BY DATE(timestamp) 
+        ORDER BY date DESC
+        LIMIT 30
+    `
+	rows, err := r.db.Query(dailyQuery, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dailyStats []entity.DailyStat
+	for rows.Next() {
+		var stat entity.DailyStat
+		err := rows.Scan(&stat.Date, &stat.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		dailyStats = append(dailyStats, stat)
+	}
+
+	uaQuery := `
+        SELECT user_agent, COUNT(*) as clicks 
+        FROM clicks 
+        WHERE short_url = $1 AND NOT blocked AND NOT anonymous
+        GROUP BY user_agent 
+        ORDER BY clicks DESC
+    `
+	uaRows, err := r.db.Query(uaQuery, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer uaRows.Close()
+
+	var userAgents []entity.UserAgentStat
+	for uaRows.Next() {
+		var ua entity.UserAgentStat
+		err := uaRows.Scan(&ua.UserAgent, &ua.Clicks)
+		if err != nil {
+			return nil, err
+		}
+		userAgents = append(userAgents, ua)
+	}
+
+	var anonymousClicks int
+	err = r.db.QueryRow("SELECT COUNT(*) FROM clicks WHERE short_url = $1 AND NOT blocked AND anonymous", shortURL).Scan(&anonymousClicks)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedReasons, err := r.getBlockedReasons(shortURL)
+	if err != nil {
+		return nil, err
+	}
+	blockedAttempts := 0
+	for _, stat := range blockedReasons {
+		blockedAttempts += stat.Attempts
+	}
+
+	return &entity.Analytics{
+		TotalClicks:     totalClicks,
+		DailyStats:      dailyStats,
+		UserAgents:      userAgents,
+		AnonymousClicks: anonymousClicks,
+		BlockedAttempts: blockedAttempts,
+		BlockedReasons:  blockedReasons,
+	}, nil
+}
+
+// getBlockedReasons counts attempts denied by the link access control by reason
+func (r *AnalyticsRepository) getBlockedReasons(shortURL string) ([]entity.BlockedStat, error) {
+	query := `
+        SELECT block_reason, COUNT(*) as attempts
+        FROM clicks
+        WHERE short_url = $1 AND blocked
+        GROUP BY block_reason
+        ORDER BY attempts DESC
+    `
+	rows, err := r.db.Query(query, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]entity.BlockedStat, 0)
+	for rows.Next() {
+		var stat entity.BlockedStat
+		if err := rows.Scan(&stat.Reason, &stat.Attempts); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetTagStats aggregates links and clicks by category tag
+func (r *AnalyticsRepository) GetTagStats() ([]entity.TagStat, error) {
+	query := `
+        SELECT t.tag, COUNT(*) as links, COALESCE(SUM(u.clicks), 0) as clicks
+        FROM url_tags t
+        JOIN urls u ON u.short_url = t.short_url
+        GROUP BY t.tag
+        ORDER BY clicks DESC, t.tag
+    `
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]entity.TagStat, 0)
+	for rows.Next() {
+		var stat entity.TagStat
+		if err := rows.Scan(&stat.Tag, &stat.Links, &stat.Clicks); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) ExportRepositoryInterface {
+	return &ExportRepository{db: db}
+}
+
+// ExportBatch locks the sink checkpoint and passes write up to limit clicks recorded after it and
+// before settledBefore. The checkpoint moves only if write succeeds, so a batch interrupted by a
+// failure is written again. Exported is 0 if another instance holds the checkpoint.
+func (r *ExportRepository) ExportBatch(ctx context.Context, sink string, settledBefore time.Time, limit int, write func([]entity.Click) error) (int, error) {
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO export_checkpoints (sink) VALUES ($1) ON CONFLICT DO NOTHING`, sink); err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	err = tx.QueryRowContext(ctx, `SELECT last_seq FROM export_checkpoints WHERE sink = $1 FOR UPDATE SKIP LOCKED`, sink).Scan(&lastSeq)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+        SELECT seq, id, short_url, COALESCE(user_agent, ''), COALESCE(ip_address, ''), referer, timestamp, blocked, block_reason
+        FROM clicks
+        WHERE seq > $1 AND timestamp < $2
+        ORDER BY seq
+        LIMIT $3
+    `
+	rows, err := tx.QueryContext(ctx, query, lastSeq, settledBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	clicks := make([]entity.Click, 0, limit)
+	for rows.Next() {
+		var click entity.Click
+		if err := rows.Scan(&lastSeq, &click.ID, &click.ShortURL, &click.UserAgent, &click.IPAddress,
+			&click.Referer, &click.Timestamp, &click.Blocked, &click.BlockReason); err != nil {
+			return 0, err
+		}
+		clicks = append(clicks, click)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if len(clicks) == 0 {
+		return 0, nil
+	}
+
+	if err := write(clicks); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE export_checkpoints SET last_seq = $1, updated_at = NOW() WHERE sink = $2`, lastSeq, sink); err != nil {
+		return 0, err
+	}
+
+	return len(clicks), tx.Commit()
+}
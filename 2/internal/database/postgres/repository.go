@@ -1,26 +1,50 @@
-package postgres
-
-import (
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-)
-
-type URLRepositoryInterface interface {
-	Create(url *entity.URL) error
-	GetByShortURL(shortURL string) (*entity.URL, error)
-	Exists(shortURL string) (bool, error)
-	GetAll() ([]entity.URL, error)
-	IncrementClicks(shortURL string) error
-}
-
-type AnalyticsRepositoryInterface interface {
-	RecordClick(click *entity.Click) error
-	GetAnalytics(shortURL string) (*entity.Analytics, error)
-}
-
-type CacheRepository interface {
-	SetURL(shortURL string, url *entity.URL) error
-	GetURL(shortURL string) (*entity.URL, error)
-	DeleteURL(shortURL string) error
-	IncrementPopularity(shortURL string) error
-	GetPopularURLs(count int) ([]string, error)
-}
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+// ErrDuplicateShortURL is returned by Create when the short URL is already taken
+var ErrDuplicateShortURL = errors.New("short URL already exists")
+
+type URLRepositoryInterface interface {
+	Create(url *entity.URL) error
+	GetByShortURL(shortURL string) (*entity.URL, error)
+	Exists(shortURL string) (bool, error)
+	ExistsSkeleton(skeleton string) (bool, error)
+	// BackfillSkeletons fills the empty skeleton of links created before lookalike detection
+	// and returns how many links were updated
+	BackfillSkeletons(skeleton func(shortURL string) string) (int, error)
+	// GetByCanonicalURL returns the oldest unrestricted public link to canonicalURL, nil if there is none
+	GetByCanonicalURL(canonicalURL string) (*entity.URL, error)
+	GetAll(tag string) ([]entity.URL, error)
+	IncrementClicks(shortURL string) error
+	SetMetadata(shortURL, title, description, image string, tags []string) error
+	SetPreview(shortURL string, preview *entity.LinkPreview) error
+	SetPrivate(shortURL string, private bool) error
+}
+
+type AnalyticsRepositoryInterface interface {
+	RecordClick(click *entity.Click) error
+	GetAnalytics(shortURL string) (*entity.Analytics, error)
+	GetTagStats() ([]entity.TagStat, error)
+}
+
+// ExportRepositoryInterface reads clicks for external analytics stores, each sink has its own checkpoint
+type ExportRepositoryInterface interface {
+	ExportBatch(ctx context.Context, sink string, settledBefore time.Time, limit int, write func([]entity.Click) error) (int, error)
+}
+
+type CacheRepository interface {
+	SetURL(shortURL string, url *entity.URL) error
+	GetURL(shortURL string) (*entity.URL, error)
+	DeleteURL(shortURL string) error
+	IncrementPopularity(shortURL string) error
+	GetPopularURLs(count int) ([]string, error)
+	LockAlias(key string, ttl time.Duration) (string, error)
+	UnlockAlias(key, token string) error
+}
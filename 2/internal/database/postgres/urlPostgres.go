@@ -1,67 +1,256 @@
-package postgres
-
-import (
-	"database/sql"
-
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-
-	_ "github.com/lib/pq"
-)
-
-type URLRepository struct {
-	db *sql.DB
-}
-
-func NewURLRepository(db *sql.DB) URLRepositoryInterface {
-	return &URLRepository{db: db}
-}
-
-func (r *URLRepository) Create(url *entity.URL) error {
-	query := `INSERT INTO urls (id, original_url, short_url, created_at) VALUES ($1, $2, $3, $4)`
-	_, err := r.db.Exec(query, url.ID, url.OriginalURL, url.ShortURL, url.CreatedAt)
-	return err
-}
-
-func (r *URLRepository) GetByShortURL(shortURL string) (*entity.URL, error) {
-	var url entity.URL
-	query := `SELECT id, original_url, short_url, created_at, clicks FROM urls WHERE short_url = $1`
-	err := r.db.QueryRow(query, shortURL).Scan(&url.ID, &url.OriginalURL, &url.ShortURL, &url.CreatedAt, &url.Clicks)
-	if err != nil {
-		return nil, err
-	}
-	return &url, nil
-}
-
-func (r *URLRepository) Exists(shortURL string) (bool, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM urls WHERE short_url = $1`
-	err := r.db.QueryRow(query, shortURL).Scan(&count)
-	return count > 0, err
-}
-
-func (r *URLRepository) GetAll() ([]entity.URL, error) {
-	query := `SELECT id, original_url, short_url, created_at, clicks FROM urls ORDER BY created_at DESC`
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var urls []entity.URL
-	for rows.Next() {
-		var url entity.URL
-		err := rows.Scan(&url.ID, &url.OriginalURL, &url.ShortURL, &url.CreatedAt, &url.Clicks)
-		if err != nil {
-			return nil, err
-		}
-		urls = append(urls, url)
-	}
-
-	return urls, nil
-}
-
-func (r *URLRepository) IncrementClicks(shortURL string) error {
-	query := `UPDATE urls SET clicks = clicks + 1 WHERE short_url = $1`
-	_, err := r.db.Exec(query, shortURL)
-	return err
-}
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the SQLSTATE postgres reports for a UNIQUE constraint conflict
+const uniqueViolation = "23505"
+
+type URLRepository struct {
+	db *sql.DB
+}
+
+func NewURLRepository(db *sql.DB) URLRepositoryInterface {
+	return &URLRepository{db: db}
+}
+
+func (r *URLRepository) Create(url *entity.URL) error {
+	allowedIPs, allowedReferers := []string{}, []string{}
+	if url.Access != nil {
+		allowedIPs, allowedReferers = url.Access.AllowedIPs, url.Access.AllowedReferers
+	}
+
+	var preview entity.LinkPreview
+	if url.Preview != nil {
+		preview = *url.Preview
+	}
+
+	query := `INSERT INTO urls (id, original_url, canonical_url, short_url, skeleton, created_at, allowed_ips, allowed_referers,
+                  preview_disabled, preview_title, preview_description, preview_image, private)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	_, err := r.db.Exec(query, url.ID, url.OriginalURL, url.CanonicalURL, url.ShortURL, url.Skeleton, url.CreatedAt, pq.Array(allowedIPs), pq.Array(allowedReferers),
+		preview.Disabled, preview.Title, preview.Description, preview.Image, url.Private)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateShortURL
+	}
+	return err
+}
+
+func (r *URLRepository) GetByShortURL(shortURL string) (*entity.URL, error) {
+	var url entity.URL
+	var access entity.AccessControl
+	var preview entity.LinkPreview
+	query := `
+        SELECT id, original_url, canonical_url, short_url, title, description, image_url, created_at, clicks, allowed_ips, allowed_referers,
+               preview_disabled, preview_title, preview_description, preview_image, private
+        FROM urls WHERE short_url = $1
+    `
+	err := r.db.QueryRow(query, shortURL).Scan(&url.ID, &url.OriginalURL, &url.CanonicalURL, &url.ShortURL, &url.Title, &url.Description, &url.Image, &url.CreatedAt, &url.Clicks,
+		pq.Array(&access.AllowedIPs), pq.Array(&access.AllowedReferers),
+		&preview.Disabled, &preview.Title, &preview.Description, &preview.Image, &url.Private)
+	if err != nil {
+		return nil, err
+	}
+	if !access.IsEmpty() {
+		url.Access = &access
+	}
+	if !preview.IsEmpty() {
+		url.Preview = &preview
+	}
+	return &url, nil
+}
+
+func (r *URLRepository) Exists(shortURL string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM urls WHERE short_url = $1`
+	err := r.db.QueryRow(query, shortURL).Scan(&count)
+	return count > 0, err
+}
+
+// ExistsSkeleton reports whether a visually confusable short URL is already taken
+func (r *URLRepository) ExistsSkeleton(skeleton string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM urls WHERE skeleton = $1`
+	err := r.db.QueryRow(query, skeleton).Scan(&count)
+	return count > 0, err
+}
+
+// skeletonBackfillBatch bounds the rows read per query while backfilling skeletons
+const skeletonBackfillBatch = 500
+
+func (r *URLRepository) BackfillSkeletons(skeleton func(shortURL string) string) (int, error) {
+	updated := 0
+	lastID := ""
+	for {
+		rows, err := r.db.Query(`SELECT id, short_url FROM urls WHERE skeleton = '' AND id > $1 ORDER BY id LIMIT $2`,
+			lastID, skeletonBackfillBatch)
+		if err != nil {
+			return updated, err
+		}
+
+		batch := make(map[string]string, skeletonBackfillBatch)
+		for rows.Next() {
+			var id, shortURL string
+			if err := rows.Scan(&id, &shortURL); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			batch[id] = shortURL
+			lastID = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			return updated, nil
+		}
+
+		for id, shortURL := range batch {
+			if _, err := r.db.Exec(`UPDATE urls SET skeleton = $1 WHERE id = $2 AND skeleton = ''`, skeleton(shortURL), id); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
+// GetByCanonicalURL looks only at links without access control or privacy, such a link is never reused
+func (r *URLRepository) GetByCanonicalURL(canonicalURL string) (*entity.URL, error) {
+	var url entity.URL
+	query := `
+        SELECT id, original_url, canonical_url, short_url, title, created_at, clicks
+        FROM urls
+        WHERE canonical_url = $1 AND cardinality(allowed_ips) = 0 AND cardinality(allowed_referers) = 0 AND NOT private
+        ORDER BY created_at
+        LIMIT 1
+    `
+	err := r.db.QueryRow(query, canonicalURL).Scan(&url.ID, &url.OriginalURL, &url.CanonicalURL, &url.ShortURL, &url.Title, &url.CreatedAt, &url.Clicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
+// GetAll returns links with their tags, only the ones tagged with tag if it is set
+func (r *URLRepository) GetAll(tag string) ([]entity.URL, error) {
+	query := `
+        SELECT u.id, u.original_url, u.canonical_url, u.short_url, u.title, u.description, u.image_url, u.created_at, u.clicks, u.allowed_ips, u.allowed_referers,
+               u.preview_disabled, u.preview_title, u.preview_description, u.preview_image, u.private,
+               COALESCE(ARRAY_AGG(t.tag ORDER BY t.tag) FILTER (WHERE t.tag IS NOT NULL), '{}')
+        FROM urls u
+        LEFT JOIN url_tags t ON t.short_url = u.short_url
+        WHERE $1 = '' OR EXISTS (SELECT 1 FROM url_tags f WHERE f.short_url = u.short_url AND f.tag = $1)
+        GROUP BY u.id
+        ORDER BY u.created_at DESC
+    `
+	rows, err := r.db.Query(query, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []entity.URL
+	for rows.Next() {
+		var url entity.URL
+		var access entity.AccessControl
+		var preview entity.LinkPreview
+		err := rows.Scan(&url.ID, &url.OriginalURL, &url.CanonicalURL, &url.ShortURL, &url.Title, &url.Description, &url.Image, &url.CreatedAt, &url.Clicks,
+			pq.Array(&access.AllowedIPs), pq.Array(&access.AllowedReferers),
+			&preview.Disabled, &preview.Title, &preview.Description, &preview.Image, &url.Private, pq.Array(&url.Tags))
+		if err != nil {
+			return nil, err
+		}
+		if !access.IsEmpty() {
+			url.Access = &access
+		}
+		if !preview.IsEmpty() {
+			url.Preview = &preview
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, nil
+}
+
+func (r *URLRepository) IncrementClicks(shortURL string) error {
+	query := `UPDATE urls SET clicks = clicks + 1 WHERE short_url = $1`
+	_, err := r.db.Exec(query, shortURL)
+	return err
+}
+
+// SetMetadata stores the destination page title, description and image and replaces the link tags
+func (r *URLRepository) SetMetadata(shortURL, title, description, image string, tags []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE urls SET title = $1, description = $2, image_url = $3 WHERE short_url = $4`
+	if _, err := tx.Exec(query, title, description, image, shortURL); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM url_tags WHERE short_url = $1`, shortURL); err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		query := `INSERT INTO url_tags (short_url, tag) SELECT $1, UNNEST($2::text[]) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, shortURL, pq.Array(tags)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetPreview replaces the preview settings of a link, sql.ErrNoRows if there is no such link
+func (r *URLRepository) SetPreview(shortURL string, preview *entity.LinkPreview) error {
+	query := `
+        UPDATE urls SET preview_disabled = $1, preview_title = $2, preview_description = $3, preview_image = $4
+        WHERE short_url = $5
+    `
+	result, err := r.db.Exec(query, preview.Disabled, preview.Title, preview.Description, preview.Image, shortURL)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetPrivate switches the privacy of a link, sql.ErrNoRows if there is no such link
+func (r *URLRepository) SetPrivate(shortURL string, private bool) error {
+	result, err := r.db.Exec(`UPDATE urls SET private = $1 WHERE short_url = $2`, private, shortURL)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
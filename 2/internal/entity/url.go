@@ -1,47 +1,137 @@
-package entity
-
-import "time"
-
-type ShortenRequest struct {
-	URL         string `json:"url" binding:"required"`
-	CustomShort string `json:"custom_short,omitempty"`
-}
-
-type URL struct {
-	ID          string    `json:"id"`
-	OriginalURL string    `json:"original_url"`
-	ShortURL    string    `json:"short_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	Clicks      int       `json:"clicks"`
-}
-
-type Click struct {
-	ID        string    `json:"id"`
-	ShortURL  string    `json:"short_url"`
-	UserAgent string    `json:"user_agent"`
-	IPAddress string    `json:"ip_address"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-type Analytics struct {
-	TotalClicks int             `json:"total_clicks"`
-	DailyStats  []DailyStat     `json:"daily_stats"`
-	UserAgents  []UserAgentStat `json:"user_agents"`
-}
-
-type DailyStat struct {
-	Date   string `json:"date"`
-	Clicks int    `json:"clicks"`
-}
-
-type UserAgentStat struct {
-	UserAgent string `json:"user_agent"`
-	Clicks    int    `json:"clicks"`
-}
-
-type ShortenResponse struct {
-	ShortURL     string    `json:"short_url"`
-	OriginalURL  string    `json:"original_url"`
-	CreatedAt    time.Time `json:"created_at"`
-	ShortURLFull string    `json:"short_url_full"`
-}
+package entity
+
+import "time"
+
+type ShortenRequest struct {
+	URL         string         `json:"url" binding:"required"`
+	CustomShort string         `json:"custom_short,omitempty"`
+	Access      *AccessControl `json:"access,omitempty"`
+	Preview     *LinkPreview   `json:"preview,omitempty"`
+	// Private links never store the user agent, IP or referer of a click
+	Private bool `json:"private,omitempty"`
+}
+
+// AccessControl restricts who can follow a link: client IPs or CIDR ranges and Referer domains
+// (subdomains included). Both lists must match when both are set, an empty list allows everyone.
+type AccessControl struct {
+	AllowedIPs      []string `json:"allowed_ips,omitempty"`
+	AllowedReferers []string `json:"allowed_referers,omitempty"`
+}
+
+func (a *AccessControl) IsEmpty() bool {
+	return a == nil || (len(a.AllowedIPs) == 0 && len(a.AllowedReferers) == 0)
+}
+
+// LinkPreview configures the card messengers and social networks show for a link. Empty fields
+// fall back to the metadata of the destination page; a disabled preview sends preview bots
+// straight to the destination, so they build the card themselves.
+type LinkPreview struct {
+	Disabled    bool   `json:"disabled,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// PrivacySettings is the per-link privacy toggle
+type PrivacySettings struct {
+	Private bool `json:"private"`
+}
+
+func (p *LinkPreview) IsEmpty() bool {
+	return p == nil || *p == LinkPreview{}
+}
+
+// PreviewCard is the Open Graph / Twitter Card data served to a link preview bot
+type PreviewCard struct {
+	ShortURL    string
+	Destination string
+	Title       string
+	Description string
+	Image       string
+	Disabled    bool
+}
+
+type URL struct {
+	ID           string    `json:"id"`
+	OriginalURL  string    `json:"original_url"`
+	CanonicalURL string    `json:"canonical_url,omitempty"` // empty for links created before normalization
+	ShortURL     string    `json:"short_url"`
+	Skeleton     string    `json:"-"`
+	Title        string    `json:"title,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Image        string    `json:"image,omitempty"`
+	Tags         []string  `json:"tags"`
+	CreatedAt    time.Time `json:"created_at"`
+	Clicks       int       `json:"clicks"`
+	// Access is nil for links anyone can follow
+	Access *AccessControl `json:"access,omitempty"`
+	// Preview is nil when the card is built from the destination page as is
+	Preview *LinkPreview `json:"preview,omitempty"`
+	// Private clicks are counted but stored without visitor data
+	Private bool `json:"private,omitempty"`
+}
+
+// Destination is the address a redirect leads to, the canonical URL if the link has one
+func (u *URL) Destination() string {
+	if u.CanonicalURL != "" {
+		return u.CanonicalURL
+	}
+	return u.OriginalURL
+}
+
+type Click struct {
+	ID        string    `json:"id"`
+	ShortURL  string    `json:"short_url"`
+	UserAgent string    `json:"user_agent"`
+	IPAddress string    `json:"ip_address"`
+	Referer   string    `json:"referer,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Blocked attempts are denied by the link access control and don't count as clicks
+	Blocked     bool   `json:"blocked"`
+	BlockReason string `json:"block_reason,omitempty"`
+	// Anonymous clicks come from a private link or a Do-Not-Track visitor and carry no visitor data
+	Anonymous bool `json:"anonymous,omitempty"`
+}
+
+type Analytics struct {
+	TotalClicks int             `json:"total_clicks"`
+	DailyStats  []DailyStat     `json:"daily_stats"`
+	UserAgents  []UserAgentStat `json:"user_agents"` // anonymous clicks are left out
+	// AnonymousClicks are part of TotalClicks and DailyStats
+	AnonymousClicks int `json:"anonymous_clicks"`
+
+	BlockedAttempts int           `json:"blocked_attempts"`
+	BlockedReasons  []BlockedStat `json:"blocked_reasons"`
+}
+
+type BlockedStat struct {
+	Reason   string `json:"reason"`
+	Attempts int    `json:"attempts"`
+}
+
+type DailyStat struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+type UserAgentStat struct {
+	UserAgent string `json:"user_agent"`
+	Clicks    int    `json:"clicks"`
+}
+
+type TagStat struct {
+	Tag    string `json:"tag"`
+	Links  int    `json:"links"`
+	Clicks int    `json:"clicks"`
+}
+
+type ShortenResponse struct {
+	ShortURL        string    `json:"short_url"`
+	OriginalURL     string    `json:"original_url"`
+	CanonicalURL    string    `json:"canonical_url"`
+	CreatedAt       time.Time `json:"created_at"`
+	ShortURLFull    string    `json:"short_url_full"`
+	ShortURLDisplay string    `json:"short_url_display"`
+	// Existing is set when an earlier link to the same canonical URL was returned instead of a new one
+	Existing bool `json:"existing,omitempty"`
+}
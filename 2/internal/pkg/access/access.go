@@ -0,0 +1,155 @@
+// Per-link restrictions on who may follow a short URL: client IP ranges and Referer domains
+package access
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Reasons a redirect was blocked, stored with the blocked attempt
+const (
+	ReasonIP      = "ip"
+	ReasonReferer = "referer"
+)
+
+var (
+	ErrInvalidIP     = errors.New("allowed IP must be an address or a CIDR range")
+	ErrInvalidDomain = errors.New("allowed referer must be a domain name")
+)
+
+// Rule is a compiled allowlist. An empty list doesn't restrict anything.
+type Rule struct {
+	networks []*net.IPNet
+	domains  []string
+}
+
+// Normalize validates the allowlists and returns them in the stored form:
+// addresses as CIDR ranges and referer domains as lowercase punycode
+func Normalize(ips, referers []string) ([]string, []string, error) {
+	normIPs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		network, err := parseNetwork(ip)
+		if err != nil {
+			return nil, nil, err
+		}
+		normIPs = append(normIPs, network.String())
+	}
+
+	normReferers := make([]string, 0, len(referers))
+	for _, referer := range referers {
+		domain, err := parseDomain(referer)
+		if err != nil {
+			return nil, nil, err
+		}
+		normReferers = append(normReferers, domain)
+	}
+
+	return normIPs, normReferers, nil
+}
+
+// New compiles allowlists, usually the ones returned by Normalize
+func New(ips, referers []string) (*Rule, error) {
+	normIPs, domains, err := Normalize(ips, referers)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &Rule{domains: domains}
+	for _, ip := range normIPs {
+		_, network, _ := net.ParseCIDR(ip)
+		rule.networks = append(rule.networks, network)
+	}
+	return rule, nil
+}
+
+// Check returns an empty reason if the request is allowed, ReasonIP or ReasonReferer otherwise.
+// A request without a Referer is blocked when referers are restricted.
+func (r *Rule) Check(ipAddress, referer string) string {
+	if len(r.networks) > 0 && !r.allowsIP(net.ParseIP(ipAddress)) {
+		return ReasonIP
+	}
+	if len(r.domains) > 0 && !r.allowsReferer(referer) {
+		return ReasonReferer
+	}
+	return ""
+}
+
+func (r *Rule) allowsIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range r.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsReferer matches the Referer host against the domains and their subdomains
+func (r *Rule) allowsReferer(referer string) bool {
+	u, err := url.Parse(referer)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host, err := idna.Lookup.ToASCII(strings.TrimSuffix(u.Hostname(), "."))
+	if err != nil {
+		return false
+	}
+	host = strings.ToLower(host)
+
+	for _, domain := range r.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNetwork(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, ErrInvalidIP
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// parseDomain accepts a bare domain, "*.domain" or a URL and returns the domain in punycode
+func parseDomain(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return "", ErrInvalidDomain
+		}
+		value = u.Hostname()
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "*."), ".")
+
+	if value == "" || strings.ContainsAny(value, "/:@ ") || net.ParseIP(value) != nil {
+		return "", ErrInvalidDomain
+	}
+
+	domain, err := idna.Lookup.ToASCII(value)
+	if err != nil {
+		return "", ErrInvalidDomain
+	}
+	return strings.ToLower(domain), nil
+}
@@ -0,0 +1,176 @@
+// Normalization and lookalike detection for custom short URL aliases
+package alias
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is counted in code points, short_url column is VARCHAR(50)
+const MaxLength = 32
+
+var (
+	ErrEmpty        = errors.New("alias is empty")
+	ErrTooLong      = errors.New("alias is too long")
+	ErrInvalidRune  = errors.New("alias contains a forbidden character")
+	ErrMixedScripts = errors.New("alias mixes letters from different scripts")
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// Canonical returns the form an alias is stored and looked up by
+func Canonical(alias string) string {
+	return norm.NFC.String(strings.TrimSpace(alias))
+}
+
+// Normalize converts the alias to NFC and checks that it consists only of letters,
+// digits, emoji, '-' and '_', with all letters belonging to a single script
+func Normalize(alias string) (string, error) {
+	alias = Canonical(alias)
+	if alias == "" {
+		return "", ErrEmpty
+	}
+	if !utf8.ValidString(alias) {
+		return "", ErrInvalidRune
+	}
+	if utf8.RuneCountInString(alias) > MaxLength {
+		return "", ErrTooLong
+	}
+
+	runes := []rune(alias)
+	for i := range runes {
+		if !allowedRune(runes, i) {
+			return "", ErrInvalidRune
+		}
+	}
+
+	if !singleScript(runes) {
+		return "", ErrMixedScripts
+	}
+
+	return alias, nil
+}
+
+func allowedRune(runes []rune, i int) bool {
+	r := runes[i]
+	switch {
+	case r == '-' || r == '_':
+		return true
+	case unicode.IsLetter(r) || unicode.Is(unicode.Nd, r):
+		return true
+	case unicode.Is(unicode.M, r):
+		// combining marks and emoji modifiers must follow something they can modify
+		return i > 0 && !isSeparator(runes[i-1])
+	case isEmoji(r):
+		return true
+	case r == zeroWidthJoiner:
+		// only inside emoji sequences like 👩‍💻, never to glue letters
+		return i > 0 && i < len(runes)-1 && isEmoji(runes[i-1]) && isEmoji(runes[i+1])
+	}
+	return false
+}
+
+func isSeparator(r rune) bool {
+	return r == '-' || r == '_' || r == zeroWidthJoiner
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case unicode.Is(unicode.So, r):
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // skin tone modifiers
+		return true
+	case r >= 0xFE0E && r <= 0xFE0F: // text/emoji presentation selectors
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag sequences of subdivision flags
+		return true
+	}
+	return false
+}
+
+// singleScript allows Common and Inherited characters everywhere, and the
+// Han/Hiragana/Katakana combination used by Japanese
+func singleScript(runes []rune) bool {
+	seen := ""
+	for _, r := range runes {
+		script := scriptOf(r)
+		if script == "" {
+			continue
+		}
+		if script == "Hiragana" || script == "Katakana" {
+			script = "Han"
+		}
+		if seen == "" {
+			seen = script
+			continue
+		}
+		if seen != script {
+			return false
+		}
+	}
+	return true
+}
+
+func scriptOf(r rune) string {
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Skeleton maps an alias to a form in which visually confusable aliases are equal:
+// compatibility forms and invisible characters are dropped, case is folded,
+// diacritics on Latin, Greek and Cyrillic letters are removed and lookalike
+// letters are replaced with their Latin counterparts
+func Skeleton(alias string) string {
+	decomposed := []rune(norm.NFKD.String(alias))
+
+	var b strings.Builder
+	var base rune
+	for _, r := range decomposed {
+		if isIgnorable(r) {
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) && unicode.In(base, unicode.Latin, unicode.Greek, unicode.Cyrillic) {
+			continue
+		}
+		if !unicode.Is(unicode.M, r) {
+			base = r
+		}
+
+		if mapped, ok := caseSensitiveConfusables[r]; ok {
+			b.WriteRune(mapped)
+			continue
+		}
+		r = unicode.ToLower(r)
+		if mapped, ok := confusables[r]; ok {
+			r = mapped
+		}
+		b.WriteRune(r)
+	}
+
+	skeleton := sequenceConfusables.Replace(b.String())
+
+	return norm.NFC.String(skeleton)
+}
+
+func isIgnorable(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner || r == '\u200b' || r == '\u200c' || r == '\u2060' || r == '\ufeff':
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r == '\u00ad': // soft hyphen
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package alias
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name  string
+		alias string
+		want  string
+		err   error
+	}{
+		{"ascii", "  promo-2024_a ", "promo-2024_a", nil},
+		{"nfc", "cafe\u0301", "café", nil},
+		{"cyrillic", "скидка", "скидка", nil},
+		{"japanese", "ひらがなカタカナ漢字", "ひらがなカタカナ漢字", nil},
+		{"emoji sequence", "dev-\U0001F469\u200d\U0001F4BB", "dev-\U0001F469\u200d\U0001F4BB", nil},
+		{"empty", "   ", "", ErrEmpty},
+		{"too long", "abcdefghijklmnopqrstuvwxyz0123456", "", ErrTooLong},
+		{"slash", "a/b", "", ErrInvalidRune},
+		{"joiner between letters", "pay\u200dpal", "", ErrInvalidRune},
+		{"leading mark", "\u0301a", "", ErrInvalidRune},
+		{"mark after separator", "a-\u0301b", "", ErrInvalidRune},
+		{"mixed scripts", "pаypal", "", ErrMixedScripts}, // Cyrillic а
+	}
+
+	for _, tc := range cases {
+		got, err := Normalize(tc.alias)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: Normalize(%q) error = %v, want %v", tc.name, tc.alias, err, tc.err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: Normalize(%q) = %q, want %q", tc.name, tc.alias, got, tc.want)
+		}
+	}
+}
+
+func TestSkeletonConfusable(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+	}{
+		{"case", "PayPal", "paypal"},
+		{"capital I and l", "Iogin", "login"},
+		{"digits", "g00gle", "google"},
+		{"cyrillic", "раураl", "paypal"},
+		{"greek", "αpple", "apple"},
+		{"diacritics", "cafè", "cafe"},
+		{"fullwidth", "ｐａｙ", "pay"},
+		{"invisible", "pay\u200bpal", "paypal"},
+		{"soft hyphen", "pay\u00adpal", "paypal"},
+	}
+
+	for _, tc := range cases {
+		if Skeleton(tc.a) != Skeleton(tc.b) {
+			t.Errorf("%s: Skeleton(%q) = %q, Skeleton(%q) = %q, want equal",
+				tc.name, tc.a, Skeleton(tc.a), tc.b, Skeleton(tc.b))
+		}
+	}
+}
+
+func TestSkeletonDistinct(t *testing.T) {
+	cases := [][2]string{
+		{"paypal", "paypai"},
+		{"sale", "sales"},
+		{"промо", "прома"},
+	}
+
+	for _, tc := range cases {
+		if Skeleton(tc[0]) == Skeleton(tc[1]) {
+			t.Errorf("Skeleton(%q) == Skeleton(%q) = %q, want different", tc[0], tc[1], Skeleton(tc[0]))
+		}
+	}
+}
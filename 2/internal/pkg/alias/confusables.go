@@ -0,0 +1,91 @@
+package alias
+
+import "strings"
+
+// caseSensitiveConfusables are checked before case folding, because folding
+// would turn them into letters they don't look like (I -> i)
+var caseSensitiveConfusables = map[rune]rune{
+	'I':      'l',
+	'\u0406': 'l', // CYRILLIC CAPITAL LETTER BYELORUSSIAN-UKRAINIAN I
+	'\u0399': 'l', // GREEK CAPITAL LETTER IOTA
+	'\u04c0': 'l', // CYRILLIC LETTER PALOCHKA
+	'\u0392': 'b', // GREEK CAPITAL LETTER BETA
+	'\u0397': 'h', // GREEK CAPITAL LETTER ETA
+	'\u039d': 'n', // GREEK CAPITAL LETTER NU
+	'\u03a1': 'p', // GREEK CAPITAL LETTER RHO
+	'\u0412': 'b', // CYRILLIC CAPITAL LETTER VE
+	'\u041d': 'h', // CYRILLIC CAPITAL LETTER EN
+	'\u041c': 'm', // CYRILLIC CAPITAL LETTER EM
+	'\u0422': 't', // CYRILLIC CAPITAL LETTER TE
+}
+
+// confusables maps lowercase lookalikes to Latin letters, a subset of
+// Unicode confusables.txt covering Cyrillic, Greek and IPA homoglyphs
+var confusables = map[rune]rune{
+	'0': 'o',
+	'1': 'l',
+	'|': 'l',
+
+	// Cyrillic
+	'\u0430': 'a', // CYRILLIC SMALL LETTER A
+	'\u0432': 'b', // CYRILLIC SMALL LETTER VE
+	'\u044c': 'b', // CYRILLIC SMALL LETTER SOFT SIGN
+	'\u0441': 'c', // CYRILLIC SMALL LETTER ES
+	'\u0501': 'd', // CYRILLIC SMALL LETTER KOMI DE
+	'\u0435': 'e', // CYRILLIC SMALL LETTER IE
+	'\u0433': 'r', // CYRILLIC SMALL LETTER GHE
+	'\u04bb': 'h', // CYRILLIC SMALL LETTER SHHA
+	'\u043d': 'h', // CYRILLIC SMALL LETTER EN
+	'\u0456': 'i', // CYRILLIC SMALL LETTER BYELORUSSIAN-UKRAINIAN I
+	'\u0458': 'j', // CYRILLIC SMALL LETTER JE
+	'\u043a': 'k', // CYRILLIC SMALL LETTER KA
+	'\u04cf': 'l', // CYRILLIC SMALL LETTER PALOCHKA
+	'\u043c': 'm', // CYRILLIC SMALL LETTER EM
+	'\u043f': 'n', // CYRILLIC SMALL LETTER PE
+	'\u043e': 'o', // CYRILLIC SMALL LETTER O
+	'\u0440': 'p', // CYRILLIC SMALL LETTER ER
+	'\u051b': 'q', // CYRILLIC SMALL LETTER QA
+	'\u0455': 's', // CYRILLIC SMALL LETTER DZE
+	'\u0442': 't', // CYRILLIC SMALL LETTER TE
+	'\u0443': 'y', // CYRILLIC SMALL LETTER U
+	'\u0445': 'x', // CYRILLIC SMALL LETTER HA
+	'\u051d': 'w', // CYRILLIC SMALL LETTER WE
+	'\u0461': 'w', // CYRILLIC SMALL LETTER OMEGA
+
+	// Greek
+	'\u03b1': 'a', // GREEK SMALL LETTER ALPHA
+	'\u03b2': 'b', // GREEK SMALL LETTER BETA
+	'\u03f2': 'c', // GREEK LUNATE SIGMA SYMBOL
+	'\u03b5': 'e', // GREEK SMALL LETTER EPSILON
+	'\u03b3': 'y', // GREEK SMALL LETTER GAMMA
+	'\u03b7': 'n', // GREEK SMALL LETTER ETA
+	'\u03b9': 'i', // GREEK SMALL LETTER IOTA
+	'\u03ba': 'k', // GREEK SMALL LETTER KAPPA
+	'\u03bd': 'v', // GREEK SMALL LETTER NU
+	'\u03bf': 'o', // GREEK SMALL LETTER OMICRON
+	'\u03c1': 'p', // GREEK SMALL LETTER RHO
+	'\u03c4': 't', // GREEK SMALL LETTER TAU
+	'\u03c5': 'u', // GREEK SMALL LETTER UPSILON
+	'\u03c7': 'x', // GREEK SMALL LETTER CHI
+	'\u03c9': 'w', // GREEK SMALL LETTER OMEGA
+
+	// Latin extensions and IPA
+	'\u0131': 'i', // LATIN SMALL LETTER DOTLESS I
+	'\u0237': 'j', // LATIN SMALL LETTER DOTLESS J
+	'\u0251': 'a', // LATIN SMALL LETTER ALPHA
+	'\u0261': 'g', // LATIN SMALL LETTER SCRIPT G
+	'\u0269': 'i', // LATIN SMALL LETTER IOTA
+	'\u029f': 'l', // LATIN LETTER SMALL CAPITAL L
+	'\u026a': 'i', // LATIN LETTER SMALL CAPITAL I
+	'\u1d0f': 'o', // LATIN LETTER SMALL CAPITAL O
+	'\u1d1c': 'u', // LATIN LETTER SMALL CAPITAL U
+	'\u1d20': 'v', // LATIN LETTER SMALL CAPITAL V
+	'\u1d21': 'w', // LATIN LETTER SMALL CAPITAL W
+	'\u1d22': 'z', // LATIN LETTER SMALL CAPITAL Z
+}
+
+// sequenceConfusables catches letter pairs that read as a single letter
+var sequenceConfusables = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+)
@@ -0,0 +1,149 @@
+// Normalization of destination URLs into a canonical form, so that links to the same page can be deduplicated
+package canonical
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedURL = errors.New("canonical form requires an absolute URL with a host")
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Prober checks that rawURL answers, used to decide whether http can be upgraded to https
+type Prober interface {
+	Probe(ctx context.Context, rawURL string) error
+}
+
+type Options struct {
+	// UpgradeHTTPS switches http links to https when the https version answers a HEAD probe
+	UpgradeHTTPS bool
+	ProbeTimeout time.Duration
+
+	// StripParams are query parameters removed from the URL, case-insensitive.
+	// A trailing "*" matches a prefix, e.g. "utm_*".
+	StripParams []string
+}
+
+type Normalizer struct {
+	prober       Prober
+	upgradeHTTPS bool
+	probeTimeout time.Duration
+	stripExact   map[string]struct{}
+	stripPrefix  []string
+}
+
+func New(opts Options, prober Prober) *Normalizer {
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = 2 * time.Second
+	}
+
+	n := &Normalizer{
+		prober:       prober,
+		upgradeHTTPS: opts.UpgradeHTTPS && prober != nil,
+		probeTimeout: opts.ProbeTimeout,
+		stripExact:   make(map[string]struct{}, len(opts.StripParams)),
+	}
+	for _, param := range opts.StripParams {
+		param = strings.ToLower(strings.TrimSpace(param))
+		switch {
+		case param == "" || param == "*":
+		case strings.HasSuffix(param, "*"):
+			n.stripPrefix = append(n.stripPrefix, strings.TrimSuffix(param, "*"))
+		default:
+			n.stripExact[param] = struct{}{}
+		}
+	}
+	return n
+}
+
+// Normalize lowercases the scheme and host, drops the default port and tracking parameters
+// and, if enabled, upgrades http to https. The order of the remaining parameters is kept.
+func (n *Normalizer) Normalize(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Hostname() == "" {
+		return "", ErrUnsupportedURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	setHost(u, strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), u.Port())
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = n.stripQuery(u.RawQuery)
+	if u.RawQuery == "" {
+		u.ForceQuery = false
+	}
+
+	if n.upgradeHTTPS && u.Scheme == "http" && u.Port() == "" {
+		secure := *u
+		secure.Scheme = "https"
+
+		probeCtx, cancel := context.WithTimeout(ctx, n.probeTimeout)
+		defer cancel()
+		if n.prober.Probe(probeCtx, secure.String()) == nil {
+			u = &secure
+		}
+	}
+
+	return u.String(), nil
+}
+
+// setHost writes host back to u without the port if it is the default one for the scheme
+func setHost(u *url.URL, host, port string) {
+	if port == "" || defaultPorts[u.Scheme] == port {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u.Host = host
+		return
+	}
+	u.Host = net.JoinHostPort(host, port)
+}
+
+// stripQuery drops tracking parameters without re-encoding the ones that stay
+func (n *Normalizer) stripQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	kept := make([]string, 0)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !n.isTracking(strings.ToLower(key)) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func (n *Normalizer) isTracking(key string) bool {
+	if _, ok := n.stripExact[key]; ok {
+		return true
+	}
+	for _, prefix := range n.stripPrefix {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package classifier
+
+import (
+	"strings"
+)
+
+const (
+	TagNews  = "news"
+	TagVideo = "video"
+	TagShop  = "shop"
+)
+
+// Classifier assigns category tags to a link by its destination metadata
+type Classifier interface {
+	Classify(meta *Metadata) []string
+}
+
+// Rule describes one category: a link matches it by its host, by og:type prefix,
+// or by at least minKeywordHits keywords in title, description and meta keywords
+type Rule struct {
+	Tag      string
+	Hosts    []string
+	OGTypes  []string
+	Keywords []string
+}
+
+const minKeywordHits = 2
+
+type RuleClassifier struct {
+	rules []Rule
+}
+
+func NewRuleClassifier(rules ...Rule) *RuleClassifier {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return &RuleClassifier{rules: rules}
+}
+
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Tag: TagNews,
+			Hosts: []string{
+				"bbc.com", "bbc.co.uk", "cnn.com", "reuters.com", "nytimes.com", "theguardian.com",
+				"ria.ru", "tass.ru", "rbc.ru", "lenta.ru", "interfax.ru", "kommersant.ru",
+			},
+			OGTypes:  []string{"article"},
+			Keywords: []string{"news", "breaking", "headline", "новости", "главное", "срочно"},
+		},
+		{
+			Tag:      TagVideo,
+			Hosts:    []string{"youtube.com", "youtu.be", "vimeo.com", "twitch.tv", "rutube.ru", "tiktok.com"},
+			OGTypes:  []string{"video"},
+			Keywords: []string{"video", "watch", "stream", "episode", "видео", "смотреть", "трансляция"},
+		},
+		{
+			Tag: TagShop,
+			Hosts: []string{
+				"amazon.com", "ebay.com", "aliexpress.com", "etsy.com",
+				"ozon.ru", "wildberries.ru", "market.yandex.ru", "avito.ru",
+			},
+			OGTypes:  []string{"product", "og:product"},
+			Keywords: []string{"buy", "price", "cart", "shop", "sale", "купить", "цена", "корзина", "магазин", "скидка"},
+		},
+	}
+}
+
+func (c *RuleClassifier) Classify(meta *Metadata) []string {
+	host := strings.TrimPrefix(strings.ToLower(meta.Host), "www.")
+	text := strings.ToLower(strings.Join(append([]string{meta.Title, meta.Description}, meta.Keywords...), " "))
+
+	tags := make([]string, 0)
+	for _, rule := range c.rules {
+		if matchHost(host, rule.Hosts) || matchPrefix(meta.OGType, rule.OGTypes) || countKeywords(text, rule.Keywords) >= minKeywordHits {
+			tags = append(tags, rule.Tag)
+		}
+	}
+	return tags
+}
+
+func matchHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPrefix(value string, prefixes []string) bool {
+	if value == "" {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func countKeywords(text string, keywords []string) int {
+	hits := 0
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			hits++
+		}
+	}
+	return hits
+}
@@ -0,0 +1,224 @@
+// Fetching of destination page metadata and its classification into link categories
+package classifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const maxBodySize = 512 << 10
+
+var ErrForbiddenAddress = errors.New("destination resolves to a private address")
+
+type Metadata struct {
+	URL         string
+	Host        string
+	Title       string
+	Description string
+	Keywords    []string
+	OGType      string
+	SiteName    string
+	Image       string // absolute http(s) URL of og:image or twitter:image
+}
+
+// Fetcher downloads the <head> of a page. It refuses to connect to loopback and
+// private networks, because the URL comes from an untrusted user.
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher(timeout time.Duration) *Fetcher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+			},
+		},
+	}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "url-shortener-preview/1.0")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// host after redirects, so that youtu.be -> youtube.com is classified by the final page
+	meta := &Metadata{URL: rawURL, Host: resp.Request.URL.Hostname()}
+
+	if resp.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return meta, nil
+	}
+
+	parseHead(io.LimitReader(resp.Body, maxBodySize), meta)
+	meta.Image = resolveImage(resp.Request.URL, meta.Image)
+	return meta, nil
+}
+
+// Probe sends a HEAD request to rawURL and returns an error unless the server answers
+// over the same scheme without a server error. Methods not allowed for HEAD still count as an answer.
+func (f *Fetcher) Probe(ctx context.Context, rawURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "url-shortener-preview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	// a redirect back to http means the https version isn't really served
+	if resp.Request.URL.Scheme != req.URL.Scheme {
+		return fmt.Errorf("redirected to %s", resp.Request.URL.Scheme)
+	}
+	return nil
+}
+
+// parseHead reads title and meta tags until the document body starts
+func parseHead(r io.Reader, meta *Metadata) {
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return
+		case html.TextToken:
+			if inTitle && meta.Title == "" {
+				meta.Title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "body":
+				return
+			case "meta":
+				if hasAttr {
+					applyMeta(tokenizer, meta)
+				}
+			}
+		}
+	}
+}
+
+func applyMeta(tokenizer *html.Tokenizer, meta *Metadata) {
+	var key, content string
+	for {
+		name, value, more := tokenizer.TagAttr()
+		switch string(name) {
+		case "name", "property":
+			key = strings.ToLower(string(value))
+		case "content":
+			content = strings.TrimSpace(string(value))
+		}
+		if !more {
+			break
+		}
+	}
+
+	switch key {
+	case "description", "og:description", "twitter:description":
+		if meta.Description == "" {
+			meta.Description = content
+		}
+	case "og:title", "twitter:title":
+		if meta.Title == "" {
+			meta.Title = content
+		}
+	case "og:image", "og:image:url", "og:image:secure_url", "twitter:image":
+		if meta.Image == "" {
+			meta.Image = content
+		}
+	case "keywords":
+		for _, keyword := range strings.Split(content, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				meta.Keywords = append(meta.Keywords, keyword)
+			}
+		}
+	case "og:type":
+		meta.OGType = strings.ToLower(content)
+	case "og:site_name":
+		meta.SiteName = content
+	}
+}
+
+// resolveImage makes a relative image address absolute against the page URL,
+// images that aren't served over http(s) are dropped
+func resolveImage(page *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+	resolved := page.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+const bigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+type BigQueryOptions struct {
+	Project string
+	Dataset string
+	Table   string
+	// OAuth access token, or a file with one that is re-read before every batch,
+	// so a sidecar can keep a short-lived token fresh
+	AccessToken string
+	TokenFile   string
+	Timeout     time.Duration
+}
+
+// BigQuerySink streams clicks with the tabledata.insertAll API. The click ID is passed as
+// insertId, BigQuery drops rows with an insertId it has already seen in the last minutes.
+type BigQuerySink struct {
+	opts   BigQueryOptions
+	client *http.Client
+}
+
+func NewBigQuerySink(opts BigQueryOptions) *BigQuerySink {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Table == "" {
+		opts.Table = "clicks"
+	}
+
+	return &BigQuerySink{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (s *BigQuerySink) Name() string {
+	return "bigquery"
+}
+
+type bigQueryRow struct {
+	InsertID string        `json:"insertId"`
+	JSON     *entity.Click `json:"json"`
+}
+
+type bigQueryInsertRequest struct {
+	Rows []bigQueryRow `json:"rows"`
+}
+
+type bigQueryInsertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+func (s *BigQuerySink) Write(ctx context.Context, clicks []entity.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+
+	payload := bigQueryInsertRequest{Rows: make([]bigQueryRow, len(clicks))}
+	for i := range clicks {
+		payload.Rows[i] = bigQueryRow{InsertID: clicks[i].ID, JSON: &clicks[i]}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("bigquery: encode clicks: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigQueryEndpoint,
+		url.PathEscape(s.opts.Project), url.PathEscape(s.opts.Dataset), url.PathEscape(s.opts.Table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bigquery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(s.Name(), resp); err != nil {
+		return err
+	}
+
+	// insertAll answers 200 even when some rows are rejected, the whole batch is retried then
+	var result bigQueryInsertResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("bigquery: decode response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		reason := "unknown"
+		if len(first.Errors) > 0 {
+			reason = first.Errors[0].Reason + ": " + first.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery: %d rows rejected, row %d: %s", len(result.InsertErrors), first.Index, reason)
+	}
+
+	return nil
+}
+
+func (s *BigQuerySink) token() (string, error) {
+	if s.opts.TokenFile == "" {
+		return s.opts.AccessToken, nil
+	}
+
+	data, err := os.ReadFile(s.opts.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("bigquery: read token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
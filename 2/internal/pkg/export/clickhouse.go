@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+type ClickHouseOptions struct {
+	URL      string // HTTP interface, e.g. http://clickhouse:8123
+	Database string
+	Table    string
+	User     string
+	Password string
+	Timeout  time.Duration
+}
+
+// ClickHouseSink inserts clicks through the ClickHouse HTTP interface in JSONEachRow format.
+// Retried batches carry the same insert_deduplication_token, and the table is expected to be
+// a ReplacingMergeTree ordered by id, so a click written twice is collapsed into one row.
+type ClickHouseSink struct {
+	opts   ClickHouseOptions
+	client *http.Client
+}
+
+func NewClickHouseSink(opts ClickHouseOptions) *ClickHouseSink {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Table == "" {
+		opts.Table = "clicks"
+	}
+
+	return &ClickHouseSink{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (s *ClickHouseSink) Name() string {
+	return "clickhouse"
+}
+
+func (s *ClickHouseSink) Write(ctx context.Context, clicks []entity.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i := range clicks {
+		if err := encoder.Encode(&clicks[i]); err != nil {
+			return fmt.Errorf("clickhouse: encode click: %w", err)
+		}
+	}
+
+	table := s.opts.Table
+	if s.opts.Database != "" {
+		table = s.opts.Database + "." + table
+	}
+
+	params := url.Values{}
+	params.Set("query", fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table))
+	params.Set("insert_deduplication_token", batchToken(clicks))
+	params.Set("date_time_input_format", "best_effort")
+
+	endpoint := strings.TrimRight(s.opts.URL, "/") + "/?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("clickhouse: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.opts.User != "" {
+		req.Header.Set("X-ClickHouse-User", s.opts.User)
+		req.Header.Set("X-ClickHouse-Key", s.opts.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("clickhouse: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(s.Name(), resp)
+}
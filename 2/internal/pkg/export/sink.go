@@ -0,0 +1,43 @@
+// Streaming of click events to external analytics stores
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+)
+
+// maxErrorBody limits how much of a failed response is kept in the error
+const maxErrorBody = 4 << 10
+
+// Sink writes a batch of clicks to an analytics store. A batch may be written again after
+// a failure, so sinks deduplicate rows by the click ID.
+type Sink interface {
+	// Name identifies the sink checkpoint, it must not change between restarts
+	Name() string
+	Write(ctx context.Context, clicks []entity.Click) error
+}
+
+// batchToken is a stable key of a batch: the same clicks give the same token on a retry
+func batchToken(clicks []entity.Click) string {
+	hash := sha256.New()
+	for _, click := range clicks {
+		io.WriteString(hash, click.ID)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func checkResponse(sink string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("%s: unexpected status %d: %s", sink, resp.StatusCode, strings.TrimSpace(string(body)))
+}
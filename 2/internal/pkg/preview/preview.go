@@ -0,0 +1,41 @@
+// Detection of link preview bots that messengers and social networks send to build a card for a shared link
+package preview
+
+import "strings"
+
+// bots are lowercase User-Agent fragments of link preview crawlers. Search engine crawlers
+// are not in the list: they index the destination and should follow the redirect.
+var bots = []string{
+	"facebookexternalhit", // Facebook, Instagram, Messenger and iMessage
+	"facebot",
+	"twitterbot",
+	"telegrambot",
+	"whatsapp",
+	"slackbot-linkexpanding",
+	"slack-imgproxy",
+	"discordbot",
+	"linkedinbot",
+	"skypeuripreview",
+	"microsoftpreview", // Teams and Outlook
+	"vkshare",
+	"viber",
+	"redditbot",
+	"pinterestbot",
+	"mastodon",
+	"embedly",
+	"iframely",
+}
+
+// IsBot reports whether userAgent belongs to a link preview bot
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return false
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, bot := range bots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
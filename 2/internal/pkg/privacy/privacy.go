@@ -0,0 +1,95 @@
+// Anonymization of visitor data before clicks are stored
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// IP storage modes
+const (
+	IPFull     = "full"     // stored as is
+	IPTruncate = "truncate" // IPv4 keeps the /24 network, IPv6 the /48
+	IPHash     = "hash"     // keyed hash, clicks from one address can still be grouped
+	IPDrop     = "drop"     // not stored
+)
+
+var ErrHashSaltRequired = errors.New("privacy: ip_mode hash requires ip_salt, an unkeyed hash of an IPv4 address is easy to reverse")
+
+// hashLength is the number of hex characters kept, it fits clicks.ip_address
+const hashLength = 32
+
+type Options struct {
+	IPMode string
+	IPSalt string
+	// HonorDNT records clicks of visitors sending DNT: 1 or Sec-GPC: 1 without user agent, IP and referer
+	HonorDNT bool
+}
+
+// Policy decides what is kept from a visit, the zero value stores everything
+type Policy struct {
+	ipMode   string
+	salt     []byte
+	honorDNT bool
+}
+
+func New(opts Options) (*Policy, error) {
+	switch opts.IPMode {
+	case "":
+		opts.IPMode = IPFull
+	case IPFull, IPTruncate, IPDrop:
+	case IPHash:
+		if opts.IPSalt == "" {
+			return nil, ErrHashSaltRequired
+		}
+	default:
+		return nil, fmt.Errorf("privacy: unknown ip_mode %q, expected full, truncate, hash or drop", opts.IPMode)
+	}
+
+	return &Policy{
+		ipMode:   opts.IPMode,
+		salt:     []byte(opts.IPSalt),
+		honorDNT: opts.HonorDNT,
+	}, nil
+}
+
+// IP returns the address in the form it may be stored
+func (p *Policy) IP(address string) string {
+	if p == nil || address == "" {
+		return address
+	}
+
+	switch p.ipMode {
+	case IPTruncate:
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return ""
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String()
+	case IPHash:
+		mac := hmac.New(sha256.New, p.salt)
+		mac.Write([]byte(address))
+		return hex.EncodeToString(mac.Sum(nil))[:hashLength]
+	case IPDrop:
+		return ""
+	}
+	return address
+}
+
+// Anonymous reports whether a visit with doNotTrack set must be stored without personal data
+func (p *Policy) Anonymous(doNotTrack bool) bool {
+	return p != nil && p.honorDNT && doNotTrack
+}
+
+// DoNotTrack reports whether the request opts out of tracking with DNT or Global Privacy Control
+func DoNotTrack(header http.Header) bool {
+	return header.Get("DNT") == "1" || header.Get("Sec-GPC") == "1"
+}
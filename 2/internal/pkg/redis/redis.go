@@ -1,27 +1,27 @@
-package redis
-
-import (
-	"fmt"
-	"log"
-
-	"github.com/ds124wfegd/WB_L3/2/config"
-	"github.com/redis/go-redis/v9"
-)
-
-func NewRedisClient(cfg *config.RedisConfig) *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		MaxRetries:   cfg.MaxRetries,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdleConns,
-		DialTimeout:  cfg.DialTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		PoolTimeout:  cfg.PoolTimeout,
-	})
-
-	log.Println("Successfully connected to Redis")
-	return client
-}
+package redis
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ds124wfegd/WB_L3/2/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func NewRedisClient(cfg *config.RedisConfig) *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		MaxRetries:   cfg.MaxRetries,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolTimeout:  cfg.PoolTimeout,
+	})
+
+	log.Println("Successfully connected to Redis")
+	return client
+}
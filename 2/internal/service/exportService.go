@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/export"
+	"github.com/sirupsen/logrus"
+)
+
+type ClickExporterConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// Clicks younger than SettleDelay wait for the next run, so that a click whose
+	// transaction commits late isn't skipped by the checkpoint
+	SettleDelay time.Duration
+}
+
+// ClickExporterImpl moves click events from Postgres to external analytics stores in batches,
+// so heavy analytical queries don't run against the operational database. Delivery is
+// at least once: every sink deduplicates retried batches by the click ID.
+type ClickExporterImpl struct {
+	exportRepo postgres.ExportRepositoryInterface
+	sinks      []export.Sink
+	cfg        ClickExporterConfig
+
+	updates chan ClickExporterConfig
+}
+
+func NewClickExporter(exportRepo postgres.ExportRepositoryInterface, sinks []export.Sink, cfg ClickExporterConfig) ClickExporter {
+	return &ClickExporterImpl{
+		exportRepo: exportRepo,
+		sinks:      sinks,
+		cfg:        withExporterDefaults(cfg),
+		updates:    make(chan ClickExporterConfig, 1),
+	}
+}
+
+func withExporterDefaults(cfg ClickExporterConfig) ClickExporterConfig {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.SettleDelay < 0 {
+		cfg.SettleDelay = 0
+	}
+	return cfg
+}
+
+// UpdateConfig hands the settings over to Run without waiting for it,
+// settings that Run hasn't picked up yet are replaced by the newer ones
+func (e *ClickExporterImpl) UpdateConfig(cfg ClickExporterConfig) {
+	select {
+	case <-e.updates:
+	default:
+	}
+	e.updates <- withExporterDefaults(cfg)
+}
+
+func (e *ClickExporterImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, sink := range e.sinks {
+			e.drain(ctx, sink)
+		}
+
+		if !e.waitTick(ctx, ticker) {
+			return
+		}
+	}
+}
+
+// waitTick waits for the next export run applying settings updates meanwhile,
+// returns false once ctx is cancelled
+func (e *ClickExporterImpl) waitTick(ctx context.Context, ticker *time.Ticker) bool {
+	for {
+		select {
+		case cfg := <-e.updates:
+			if cfg.Interval != e.cfg.Interval {
+				ticker.Reset(cfg.Interval)
+			}
+			e.cfg = cfg
+			logrus.WithFields(logrus.Fields{
+				"interval":   cfg.Interval.String(),
+				"batch_size": cfg.BatchSize,
+			}).Info("click export settings updated")
+		case <-ticker.C:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// drain exports batches until the sink catches up; a failed batch is retried on the next tick
+func (e *ClickExporterImpl) drain(ctx context.Context, sink export.Sink) {
+	settledBefore := time.Now().Add(-e.cfg.SettleDelay)
+
+	for ctx.Err() == nil {
+		exported, err := e.exportRepo.ExportBatch(ctx, sink.Name(), settledBefore, e.cfg.BatchSize, func(clicks []entity.Click) error {
+			return sink.Write(ctx, clicks)
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("sink", sink.Name()).Error("failed to export clicks")
+			return
+		}
+		if exported > 0 {
+			logrus.WithFields(logrus.Fields{"sink": sink.Name(), "clicks": exported}).Debug("clicks exported")
+		}
+		if exported < e.cfg.BatchSize {
+			return
+		}
+	}
+}
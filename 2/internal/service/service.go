@@ -1,29 +1,76 @@
-package service
-
-import (
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-)
-
-type URLService interface {
-	Shorten(url, customShort string) (*entity.ShortenResponse, error)
-	Redirect(shortURL, userAgent, ipAddress string) (string, error)
-	GetAllURLs() ([]entity.URL, error)
-}
-
-type AnalyticsService interface {
-	GetAnalytics(shortURL string) (*entity.Analytics, error)
-}
-
-var (
-	ErrInvalidURL     = &ServiceError{"invalid URL"}
-	ErrShortURLExists = &ServiceError{"short URL already exists"}
-	ErrURLNotFound    = &ServiceError{"URL not found"}
-)
-
-type ServiceError struct {
-	message string
-}
-
-func (e *ServiceError) Error() string {
-	return e.message
-}
+package service
+
+import (
+	"context"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/privacy"
+)
+
+type URLService interface {
+	Shorten(url, customShort string, access *entity.AccessControl, preview *entity.LinkPreview, private bool) (*entity.ShortenResponse, error)
+	// Redirect returns ErrAccessDenied if the link access control rejects the client IP or Referer.
+	// doNotTrack is set when the visitor opted out of tracking, the privacy policy decides whether it is honored.
+	Redirect(shortURL, userAgent, ipAddress, referer string, doNotTrack bool) (string, error)
+	// Preview returns the card for a link preview bot, the request is not counted as a click
+	Preview(shortURL, ipAddress, referer string) (*entity.PreviewCard, error)
+	// SetPreview replaces the preview settings of a link, an empty preview restores the defaults
+	SetPreview(shortURL string, preview *entity.LinkPreview) (*entity.LinkPreview, error)
+	// SetPrivate switches recording of visitor data for the clicks of a link
+	SetPrivate(shortURL string, private bool) (*entity.PrivacySettings, error)
+	// UpdatePrivacy applies a new privacy policy to the following clicks, used by config hot reload
+	UpdatePrivacy(policy *privacy.Policy)
+	GetAllURLs(tag string) ([]entity.URL, error)
+	// BackfillSkeletons computes the lookalike skeleton of links stored before it existed
+	BackfillSkeletons() (int, error)
+}
+
+type AnalyticsService interface {
+	GetAnalytics(shortURL string) (*entity.Analytics, error)
+	GetTagStats() ([]entity.TagStat, error)
+}
+
+type TaggingService interface {
+	TagURL(shortURL, originalURL string)
+}
+
+// ClickExporter streams click events to external analytics stores until ctx is cancelled
+type ClickExporter interface {
+	Run(ctx context.Context)
+	// UpdateConfig applies new settings from the next tick, used by config hot reload
+	UpdateConfig(cfg ClickExporterConfig)
+}
+
+var (
+	ErrInvalidURL      = &ServiceError{"invalid URL"}
+	ErrShortURLExists  = &ServiceError{"short URL already exists"}
+	ErrURLNotFound     = &ServiceError{"URL not found"}
+	ErrInvalidAlias    = &ServiceError{"invalid custom short URL"}
+	ErrAliasConfusable = &ServiceError{"custom short URL is too similar to an existing one"}
+	ErrInvalidAccess   = &ServiceError{"invalid access control"}
+	ErrAccessDenied    = &ServiceError{"access to the URL is restricted"}
+	ErrInvalidPreview  = &ServiceError{"invalid link preview"}
+)
+
+// AliasConflictError is returned by Shorten when the custom short URL can't be claimed,
+// Suggestions lists free aliases close to the requested one
+type AliasConflictError struct {
+	Err         *ServiceError
+	Suggestions []string
+}
+
+func (e *AliasConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AliasConflictError) Unwrap() error {
+	return e.Err
+}
+
+type ServiceError struct {
+	message string
+}
+
+func (e *ServiceError) Error() string {
+	return e.message
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/classifier"
+	"github.com/sirupsen/logrus"
+)
+
+type TaggingServiceImpl struct {
+	urlRepo    postgres.URLRepositoryInterface
+	fetcher    *classifier.Fetcher
+	classifier classifier.Classifier
+	timeout    time.Duration
+}
+
+func NewTaggingService(
+	urlRepo postgres.URLRepositoryInterface,
+	fetcher *classifier.Fetcher,
+	classifier classifier.Classifier,
+	timeout time.Duration,
+) TaggingService {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &TaggingServiceImpl{
+		urlRepo:    urlRepo,
+		fetcher:    fetcher,
+		classifier: classifier,
+		timeout:    timeout,
+	}
+}
+
+// TagURL fetches the destination page and stores its title, description and image
+// for link previews and its category tags.
+// If the page can't be fetched the link is still classified by its host.
+func (s *TaggingServiceImpl) TagURL(shortURL, originalURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	meta, err := s.fetcher.Fetch(ctx, originalURL)
+	if err != nil {
+		logrus.WithError(err).WithField("short_url", shortURL).Warn("failed to fetch destination metadata")
+	}
+	if meta == nil {
+		meta = &classifier.Metadata{URL: originalURL, Host: hostOf(originalURL)}
+	}
+
+	tags := s.classifier.Classify(meta)
+	if err := s.urlRepo.SetMetadata(shortURL, meta.Title, meta.Description, meta.Image, tags); err != nil {
+		logrus.WithError(err).WithField("short_url", shortURL).Error("failed to save link tags")
+	}
+}
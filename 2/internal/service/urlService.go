@@ -1,143 +1,538 @@
-package service
-
-import (
-	"math/rand"
-	"net/url"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-	"github.com/google/uuid"
-)
-
-type URLServiceImpl struct {
-	urlRepo       postgres.URLRepositoryInterface
-	analyticsRepo postgres.AnalyticsRepositoryInterface
-	cacheRepo     postgres.CacheRepository
-	config        *URLServiceConfig
-}
-
-type URLServiceConfig struct {
-	ShortURLLength int
-	BaseURL        string
-	CacheTTL       time.Duration
-}
-
-func NewURLService(
-	urlRepo postgres.URLRepositoryInterface,
-	analyticsRepo postgres.AnalyticsRepositoryInterface,
-	cacheRepo postgres.CacheRepository,
-	config *URLServiceConfig,
-) URLService {
-	return &URLServiceImpl{
-		urlRepo:       urlRepo,
-		analyticsRepo: analyticsRepo,
-		cacheRepo:     cacheRepo,
-		config:        config,
-	}
-}
-
-const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-
-func (s *URLServiceImpl) generateShortURL() string {
-	rand.Seed(time.Now().UnixNano())
-	shortURL := make([]byte, s.config.ShortURLLength)
-	for i := range shortURL {
-		shortURL[i] = charset[rand.Intn(len(charset))]
-	}
-	return string(shortURL)
-}
-
-func (s *URLServiceImpl) Shorten(originalURL, customShort string) (*entity.ShortenResponse, error) {
-	if _, err := url.ParseRequestURI(originalURL); err != nil {
-		return nil, ErrInvalidURL
-	}
-
-	var shortURL string
-	if customShort != "" {
-		shortURL = customShort
-		exists, err := s.urlRepo.Exists(shortURL)
-		if err != nil {
-			return nil, err
-		}
-		if exists {
-			return nil, ErrShortURLExists
-		}
-	} else {
-		for {
-			shortURL = s.generateShortURL()
-			exists, err := s.urlRepo.Exists(shortURL)
-			if err != nil {
-				return nil, err
-			}
-			if !exists {
-				break
-			}
-		}
-	}
-
-	url := &entity.URL{
-		ID:          uuid.New().String(),
-		OriginalURL: originalURL,
-		ShortURL:    shortURL,
-		CreatedAt:   time.Now(),
-		Clicks:      0,
-	}
-
-	if err := s.urlRepo.Create(url); err != nil {
-		return nil, err
-	}
-
-	s.cacheRepo.SetURL(shortURL, url)
-
-	return &entity.ShortenResponse{
-		ShortURL:     shortURL,
-		OriginalURL:  originalURL,
-		CreatedAt:    url.CreatedAt,
-		ShortURLFull: s.config.BaseURL + "/s/" + shortURL,
-	}, nil
-}
-
-func (s *URLServiceImpl) Redirect(shortURL, userAgent, ipAddress string) (string, error) {
-	var originalURL string
-	cachedURL, err := s.cacheRepo.GetURL(shortURL)
-	if err == nil {
-		originalURL = cachedURL.OriginalURL
-	} else {
-		url, err := s.urlRepo.GetByShortURL(shortURL)
-		if err != nil {
-			return "", ErrURLNotFound
-		}
-		originalURL = url.OriginalURL
-
-		s.cacheRepo.SetURL(shortURL, url)
-	}
-
-	go s.recordClick(shortURL, userAgent, ipAddress)
-
-	s.cacheRepo.IncrementPopularity(shortURL)
-
-	return originalURL, nil
-}
-
-func (s *URLServiceImpl) recordClick(shortURL, userAgent, ipAddress string) {
-	click := &entity.Click{
-		ID:        uuid.New().String(),
-		ShortURL:  shortURL,
-		UserAgent: userAgent,
-		IPAddress: ipAddress,
-		Timestamp: time.Now(),
-	}
-
-	if err := s.analyticsRepo.RecordClick(click); err != nil {
-		return
-	}
-
-	if err := s.urlRepo.IncrementClicks(shortURL); err != nil {
-		return
-	}
-}
-
-func (s *URLServiceImpl) GetAllURLs() ([]entity.URL, error) {
-	return s.urlRepo.GetAll()
-}
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/access"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/alias"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/canonical"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/privacy"
+	"github.com/google/uuid"
+	"golang.org/x/net/idna"
+)
+
+type URLServiceImpl struct {
+	urlRepo       postgres.URLRepositoryInterface
+	analyticsRepo postgres.AnalyticsRepositoryInterface
+	cacheRepo     postgres.CacheRepository
+	tagger        TaggingService
+	normalizer    *canonical.Normalizer
+	config        *URLServiceConfig
+	privacy       atomic.Pointer[privacy.Policy]
+
+	// BaseURL with the domain in punycode for links and in unicode for display
+	asciiBaseURL   string
+	displayBaseURL string
+}
+
+type URLServiceConfig struct {
+	ShortURLLength int
+	BaseURL        string
+	CacheTTL       time.Duration
+	// Privacy is applied to every click before it is stored, nil stores clicks as they are
+	Privacy *privacy.Policy
+}
+
+func NewURLService(
+	urlRepo postgres.URLRepositoryInterface,
+	analyticsRepo postgres.AnalyticsRepositoryInterface,
+	cacheRepo postgres.CacheRepository,
+	tagger TaggingService,
+	normalizer *canonical.Normalizer,
+	config *URLServiceConfig,
+) URLService {
+	s := &URLServiceImpl{
+		urlRepo:        urlRepo,
+		analyticsRepo:  analyticsRepo,
+		cacheRepo:      cacheRepo,
+		tagger:         tagger,
+		normalizer:     normalizer,
+		config:         config,
+		asciiBaseURL:   convertHost(config.BaseURL, idna.Lookup.ToASCII),
+		displayBaseURL: convertHost(config.BaseURL, idna.Display.ToUnicode),
+	}
+	s.privacy.Store(config.Privacy)
+	return s
+}
+
+const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+const (
+	// aliasLockTTL bounds how long a crashed request can keep a custom alias locked
+	aliasLockTTL = 5 * time.Second
+
+	maxAliasSuggestions     = 3
+	aliasSuggestionAttempts = 10
+
+	// Messengers cut longer texts anyway
+	maxPreviewTitle       = 200
+	maxPreviewDescription = 500
+)
+
+func (s *URLServiceImpl) generateShortURL() string {
+	rand.Seed(time.Now().UnixNano())
+	shortURL := make([]byte, s.config.ShortURLLength)
+	for i := range shortURL {
+		shortURL[i] = charset[rand.Intn(len(charset))]
+	}
+	return string(shortURL)
+}
+
+func (s *URLServiceImpl) Shorten(originalURL, customShort string, accessControl *entity.AccessControl, preview *entity.LinkPreview, private bool) (*entity.ShortenResponse, error) {
+	originalURL, err := toASCIIURL(originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	accessControl, err = normalizeAccess(accessControl)
+	if err != nil {
+		return nil, ErrInvalidAccess
+	}
+
+	preview, err = normalizePreview(preview)
+	if err != nil {
+		return nil, ErrInvalidPreview
+	}
+
+	canonicalURL, err := s.normalizer.Normalize(context.Background(), originalURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	// A plain link to a page that was already shortened is reused. Custom aliases, access-controlled,
+	// private links and links with their own preview are always created, the caller asked for a specific link.
+	if customShort == "" && accessControl == nil && preview == nil && !private {
+		existing, err := s.urlRepo.GetByCanonicalURL(canonicalURL)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return s.shortenResponse(existing, true), nil
+		}
+	}
+
+	var shortURL string
+	if customShort != "" {
+		shortURL, err = alias.Normalize(customShort)
+		if err != nil {
+			return nil, ErrInvalidAlias
+		}
+
+		// Lookalike aliases share a skeleton, so a single lock serializes claims of all of them.
+		// If Redis is unavailable the UNIQUE constraint on short_url still rejects the second claim.
+		skeleton := alias.Skeleton(shortURL)
+		token, err := s.cacheRepo.LockAlias(skeleton, aliasLockTTL)
+		if err == nil && token == "" {
+			return nil, s.aliasConflict(ErrShortURLExists, shortURL)
+		}
+		if token != "" {
+			defer s.cacheRepo.UnlockAlias(skeleton, token)
+		}
+
+		if err := s.checkAlias(shortURL); err != nil {
+			var conflict *ServiceError
+			if errors.As(err, &conflict) {
+				return nil, s.aliasConflict(conflict, shortURL)
+			}
+			return nil, err
+		}
+	} else {
+		for {
+			shortURL = s.generateShortURL()
+			exists, err := s.urlRepo.Exists(shortURL)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				break
+			}
+		}
+	}
+
+	url := &entity.URL{
+		ID:           uuid.New().String(),
+		OriginalURL:  originalURL,
+		CanonicalURL: canonicalURL,
+		ShortURL:     shortURL,
+		Skeleton:     alias.Skeleton(shortURL),
+		CreatedAt:    time.Now(),
+		Clicks:       0,
+		Access:       accessControl,
+		Preview:      preview,
+		Private:      private,
+	}
+
+	if err := s.urlRepo.Create(url); err != nil {
+		if customShort != "" && errors.Is(err, postgres.ErrDuplicateShortURL) {
+			return nil, s.aliasConflict(ErrShortURLExists, shortURL)
+		}
+		return nil, err
+	}
+
+	s.cacheRepo.SetURL(shortURL, url)
+
+	go s.tagger.TagURL(shortURL, canonicalURL)
+
+	return s.shortenResponse(url, false), nil
+}
+
+func (s *URLServiceImpl) shortenResponse(link *entity.URL, existing bool) *entity.ShortenResponse {
+	return &entity.ShortenResponse{
+		ShortURL:        link.ShortURL,
+		OriginalURL:     link.OriginalURL,
+		CanonicalURL:    link.Destination(),
+		CreatedAt:       link.CreatedAt,
+		ShortURLFull:    s.asciiBaseURL + "/s/" + url.PathEscape(link.ShortURL),
+		ShortURLDisplay: s.displayBaseURL + "/s/" + link.ShortURL,
+		Existing:        existing,
+	}
+}
+
+func (s *URLServiceImpl) BackfillSkeletons() (int, error) {
+	return s.urlRepo.BackfillSkeletons(alias.Skeleton)
+}
+
+// checkAlias returns ErrShortURLExists or ErrAliasConfusable if shortURL can't be claimed
+func (s *URLServiceImpl) checkAlias(shortURL string) error {
+	exists, err := s.urlRepo.Exists(shortURL)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrShortURLExists
+	}
+	similar, err := s.urlRepo.ExistsSkeleton(alias.Skeleton(shortURL))
+	if err != nil {
+		return err
+	}
+	if similar {
+		return ErrAliasConfusable
+	}
+	return nil
+}
+
+func (s *URLServiceImpl) aliasConflict(err *ServiceError, shortURL string) error {
+	return &AliasConflictError{Err: err, Suggestions: s.suggestAliases(shortURL)}
+}
+
+// suggestAliases returns up to maxAliasSuggestions free aliases derived from a taken one:
+// numbered variants first, then random numeric suffixes. Digits keep the alias single-script.
+func (s *URLServiceImpl) suggestAliases(shortURL string) []string {
+	suggestions := []string{}
+	for i := 1; i <= aliasSuggestionAttempts && len(suggestions) < maxAliasSuggestions; i++ {
+		suffix := "-" + strconv.Itoa(i)
+		if i > maxAliasSuggestions {
+			suffix = "-" + strconv.Itoa(100+rand.Intn(900))
+		}
+
+		base := []rune(shortURL)
+		if limit := alias.MaxLength - len(suffix); len(base) > limit {
+			base = base[:limit]
+		}
+
+		candidate, err := alias.Normalize(string(base) + suffix)
+		if err != nil {
+			continue
+		}
+		if s.checkAlias(candidate) == nil {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+func (s *URLServiceImpl) Redirect(shortURL, userAgent, ipAddress, referer string, doNotTrack bool) (string, error) {
+	shortURL = alias.Canonical(shortURL)
+
+	url, err := s.cacheRepo.GetURL(shortURL)
+	if err != nil {
+		url, err = s.urlRepo.GetByShortURL(shortURL)
+		if err != nil {
+			return "", ErrURLNotFound
+		}
+
+		s.cacheRepo.SetURL(shortURL, url)
+	}
+
+	// access is checked against the real address, only the stored click is anonymized
+	click := s.newClick(url, userAgent, ipAddress, referer, doNotTrack)
+
+	if reason := checkAccess(url.Access, ipAddress, referer); reason != "" {
+		click.Blocked = true
+		click.BlockReason = reason
+		go s.analyticsRepo.RecordClick(click)
+		return "", ErrAccessDenied
+	}
+
+	go s.recordClick(click)
+
+	s.cacheRepo.IncrementPopularity(shortURL)
+
+	return url.Destination(), nil
+}
+
+// Preview builds the card from the destination page metadata and the link's own preview settings.
+// A link with access control gets no card unless the bot itself passes the check, so a restricted
+// destination isn't disclosed in a chat where the link is shared.
+func (s *URLServiceImpl) Preview(shortURL, ipAddress, referer string) (*entity.PreviewCard, error) {
+	shortURL = alias.Canonical(shortURL)
+
+	link, err := s.urlRepo.GetByShortURL(shortURL)
+	if err != nil {
+		return nil, ErrURLNotFound
+	}
+
+	if reason := checkAccess(link.Access, ipAddress, referer); reason != "" {
+		return nil, ErrAccessDenied
+	}
+
+	card := &entity.PreviewCard{
+		ShortURL:    s.asciiBaseURL + "/s/" + url.PathEscape(link.ShortURL),
+		Destination: link.Destination(),
+		Title:       link.Title,
+		Description: link.Description,
+		Image:       link.Image,
+	}
+
+	if preview := link.Preview; preview != nil {
+		card.Disabled = preview.Disabled
+		if preview.Title != "" {
+			card.Title = preview.Title
+		}
+		if preview.Description != "" {
+			card.Description = preview.Description
+		}
+		if preview.Image != "" {
+			card.Image = preview.Image
+		}
+	}
+
+	if card.Title == "" {
+		card.Title = hostOf(card.Destination)
+	}
+	card.Title = truncate(card.Title, maxPreviewTitle)
+	card.Description = truncate(card.Description, maxPreviewDescription)
+
+	return card, nil
+}
+
+func (s *URLServiceImpl) SetPreview(shortURL string, preview *entity.LinkPreview) (*entity.LinkPreview, error) {
+	shortURL = alias.Canonical(shortURL)
+
+	preview, err := normalizePreview(preview)
+	if err != nil {
+		return nil, ErrInvalidPreview
+	}
+	if preview == nil {
+		preview = &entity.LinkPreview{}
+	}
+
+	if err := s.urlRepo.SetPreview(shortURL, preview); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+
+	// the cached link carries the old preview settings
+	s.cacheRepo.DeleteURL(shortURL)
+
+	return preview, nil
+}
+
+// newClick builds the click in the form it may be stored: without visitor data for a private link
+// or a Do-Not-Track visitor, with the IP reduced by the privacy policy otherwise
+func (s *URLServiceImpl) newClick(link *entity.URL, userAgent, ipAddress, referer string, doNotTrack bool) *entity.Click {
+	click := &entity.Click{
+		ID:        uuid.New().String(),
+		ShortURL:  link.ShortURL,
+		Timestamp: time.Now(),
+	}
+
+	policy := s.privacy.Load()
+	if link.Private || policy.Anonymous(doNotTrack) {
+		click.Anonymous = true
+		return click
+	}
+
+	click.UserAgent = userAgent
+	click.IPAddress = policy.IP(ipAddress)
+	click.Referer = referer
+	return click
+}
+
+// SetPrivate switches the link between recording visitor data and counting clicks only.
+// Clicks stored before the switch keep the data they were stored with.
+func (s *URLServiceImpl) SetPrivate(shortURL string, private bool) (*entity.PrivacySettings, error) {
+	shortURL = alias.Canonical(shortURL)
+
+	if err := s.urlRepo.SetPrivate(shortURL, private); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+
+	// the cached link carries the old setting
+	s.cacheRepo.DeleteURL(shortURL)
+
+	return &entity.PrivacySettings{Private: private}, nil
+}
+
+func (s *URLServiceImpl) UpdatePrivacy(policy *privacy.Policy) {
+	s.privacy.Store(policy)
+}
+
+func (s *URLServiceImpl) recordClick(click *entity.Click) {
+	shortURL := click.ShortURL
+
+	if err := s.analyticsRepo.RecordClick(click); err != nil {
+		return
+	}
+
+	if err := s.urlRepo.IncrementClicks(shortURL); err != nil {
+		return
+	}
+}
+
+// normalizeAccess validates the access control and returns it in the stored form, nil if it restricts nothing
+func normalizeAccess(accessControl *entity.AccessControl) (*entity.AccessControl, error) {
+	if accessControl.IsEmpty() {
+		return nil, nil
+	}
+
+	ips, referers, err := access.Normalize(accessControl.AllowedIPs, accessControl.AllowedReferers)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AccessControl{AllowedIPs: ips, AllowedReferers: referers}, nil
+}
+
+// normalizePreview validates the preview settings and returns them trimmed, nil if they change nothing
+func normalizePreview(preview *entity.LinkPreview) (*entity.LinkPreview, error) {
+	if preview.IsEmpty() {
+		return nil, nil
+	}
+
+	normalized := &entity.LinkPreview{
+		Disabled:    preview.Disabled,
+		Title:       strings.TrimSpace(preview.Title),
+		Description: strings.TrimSpace(preview.Description),
+		Image:       strings.TrimSpace(preview.Image),
+	}
+
+	if utf8.RuneCountInString(normalized.Title) > maxPreviewTitle ||
+		utf8.RuneCountInString(normalized.Description) > maxPreviewDescription {
+		return nil, ErrInvalidPreview
+	}
+
+	if normalized.Image != "" {
+		u, err := url.ParseRequestURI(normalized.Image)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, ErrInvalidPreview
+		}
+	}
+
+	if normalized.IsEmpty() {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// truncate shortens s to max runes, ending it with an ellipsis
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// checkAccess returns the reason the request is blocked, or an empty string if it is allowed.
+// Rules are validated on creation, so a rule that doesn't compile blocks everyone rather than no one.
+func checkAccess(accessControl *entity.AccessControl, ipAddress, referer string) string {
+	if accessControl.IsEmpty() {
+		return ""
+	}
+
+	rule, err := access.New(accessControl.AllowedIPs, accessControl.AllowedReferers)
+	if err != nil {
+		return access.ReasonIP
+	}
+	return rule.Check(ipAddress, referer)
+}
+
+func (s *URLServiceImpl) GetAllURLs(tag string) ([]entity.URL, error) {
+	return s.urlRepo.GetAll(strings.ToLower(strings.TrimSpace(tag)))
+}
+
+// toASCIIURL validates the URL and converts an internationalized domain to punycode,
+// so that a redirect always points to the host the user actually typed
+func toASCIIURL(rawURL string) (string, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	host := u.Hostname()
+	if host == "" || net.ParseIP(host) != nil {
+		return rawURL, nil
+	}
+
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(asciiHost, host) {
+		return rawURL, nil
+	}
+
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(asciiHost, port)
+	} else {
+		u.Host = asciiHost
+	}
+	return u.String(), nil
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// convertHost rewrites the domain of rawURL with convert, leaving rawURL as is if it can't be parsed
+func convertHost(rawURL string, convert func(string) (string, error)) string {
+	rawURL = strings.TrimRight(rawURL, "/")
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return rawURL
+	}
+
+	host, err := convert(u.Hostname())
+	if err != nil {
+		return rawURL
+	}
+
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+	return u.String()
+}
@@ -1,68 +1,168 @@
-package transport
-
-import (
-	"net/http"
-
-	"github.com/ds124wfegd/WB_L3/2/internal/entity"
-	"github.com/ds124wfegd/WB_L3/2/internal/service"
-	"github.com/gin-gonic/gin"
-)
-
-type Handler interface {
-	RegisterRoutes(router *gin.RouterGroup)
-}
-
-type URLHandler struct {
-	urlService service.URLService
-}
-
-func NewURLHandler(urlService service.URLService) *URLHandler {
-	return &URLHandler{
-		urlService: urlService,
-	}
-}
-
-func (h *URLHandler) ShortenURL(c *gin.Context) {
-	var req entity.ShortenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
-
-	response, err := h.urlService.Shorten(req.URL, req.CustomShort)
-	if err != nil {
-		switch err {
-		case service.ErrInvalidURL:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL"})
-		case service.ErrShortURLExists:
-			c.JSON(http.StatusConflict, gin.H{"error": "Custom short URL already exists"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create URL"})
-		}
-		return
-	}
-
-	c.JSON(http.StatusCreated, response)
-}
-
-func (h *URLHandler) RedirectURL(c *gin.Context) {
-	shortURL := c.Param("short_url")
-
-	originalURL, err := h.urlService.Redirect(shortURL, c.GetHeader("User-Agent"), c.ClientIP())
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
-		return
-	}
-
-	c.Redirect(http.StatusFound, originalURL)
-}
-
-func (h *URLHandler) GetURLs(c *gin.Context) {
-	urls, err := h.urlService.GetAllURLs()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get URLs"})
-		return
-	}
-
-	c.JSON(http.StatusOK, urls)
-}
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/2/internal/entity"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/preview"
+	"github.com/ds124wfegd/WB_L3/2/internal/pkg/privacy"
+	"github.com/ds124wfegd/WB_L3/2/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const invalidPreviewMessage = "Preview title must be up to 200 characters, description up to 500 and image an http(s) URL"
+
+type Handler interface {
+	RegisterRoutes(router *gin.RouterGroup)
+}
+
+type URLHandler struct {
+	urlService service.URLService
+}
+
+func NewURLHandler(urlService service.URLService) *URLHandler {
+	return &URLHandler{
+		urlService: urlService,
+	}
+}
+
+func (h *URLHandler) ShortenURL(c *gin.Context) {
+	var req entity.ShortenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	response, err := h.urlService.Shorten(req.URL, req.CustomShort, req.Access, req.Preview, req.Private)
+	if err != nil {
+		suggestions := []string{}
+		var conflict *service.AliasConflictError
+		if errors.As(err, &conflict) {
+			suggestions = conflict.Suggestions
+		}
+
+		switch {
+		case errors.Is(err, service.ErrInvalidURL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid URL"})
+		case errors.Is(err, service.ErrInvalidAccess):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Allowed IPs must be addresses or CIDR ranges and allowed referers domain names"})
+		case errors.Is(err, service.ErrInvalidPreview):
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidPreviewMessage})
+		case errors.Is(err, service.ErrInvalidAlias):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Custom short URL may contain letters of one script, digits, emoji, '-' and '_' only"})
+		case errors.Is(err, service.ErrShortURLExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Custom short URL already exists", "suggestions": suggestions})
+		case errors.Is(err, service.ErrAliasConfusable):
+			c.JSON(http.StatusConflict, gin.H{"error": "Custom short URL looks too similar to an existing one", "suggestions": suggestions})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create URL"})
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if response.Existing {
+		status = http.StatusOK
+	}
+	c.JSON(status, response)
+}
+
+func (h *URLHandler) RedirectURL(c *gin.Context) {
+	shortURL := c.Param("short_url")
+
+	// bots get a card and people a redirect from the same URL, caches must keep them apart
+	c.Header("Vary", "User-Agent")
+	if preview.IsBot(c.GetHeader("User-Agent")) {
+		h.previewURL(c, shortURL)
+		return
+	}
+
+	originalURL, err := h.urlService.Redirect(shortURL, c.GetHeader("User-Agent"), c.ClientIP(), c.GetHeader("Referer"), privacy.DoNotTrack(c.Request.Header))
+	if err != nil {
+		if errors.Is(err, service.ErrAccessDenied) {
+			c.HTML(http.StatusForbidden, "forbidden.html", nil)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, originalURL)
+}
+
+// previewURL answers a link preview bot with Open Graph and Twitter Card tags instead of a redirect,
+// so that the chat shows the destination card and the bot isn't counted as a click
+func (h *URLHandler) previewURL(c *gin.Context, shortURL string) {
+	card, err := h.urlService.Preview(shortURL, c.ClientIP(), c.GetHeader("Referer"))
+	if err != nil {
+		if errors.Is(err, service.ErrAccessDenied) {
+			c.HTML(http.StatusForbidden, "forbidden.html", nil)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+
+	if card.Disabled {
+		c.Redirect(http.StatusFound, card.Destination)
+		return
+	}
+
+	c.HTML(http.StatusOK, "preview.html", card)
+}
+
+// UpdatePreview replaces the preview card settings of a link
+func (h *URLHandler) UpdatePreview(c *gin.Context) {
+	var req entity.LinkPreview
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	updated, err := h.urlService.SetPreview(c.Param("short_url"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidPreview):
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidPreviewMessage})
+		case errors.Is(err, service.ErrURLNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preview"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// UpdatePrivacy switches recording of visitor data for the clicks of a link
+func (h *URLHandler) UpdatePrivacy(c *gin.Context) {
+	var req entity.PrivacySettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	updated, err := h.urlService.SetPrivate(c.Param("short_url"), req.Private)
+	if err != nil {
+		if errors.Is(err, service.ErrURLNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update privacy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *URLHandler) GetURLs(c *gin.Context) {
+	urls, err := h.urlService.GetAllURLs(c.Query("tag"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get URLs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, urls)
+}
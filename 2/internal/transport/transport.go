@@ -1,50 +1,57 @@
-package transport
-
-import (
-	"github.com/gin-gonic/gin"
-)
-
-func InitRoutes(urlHandler *URLHandler, analyticsHandler *AnalyticsHandler) *gin.Engine {
-	router := gin.Default()
-
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	router.Static("/static", "/app/internal/web/templates")
-	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
-
-	router.GET("/", func(c *gin.Context) {
-		c.File("/app/internal/web/templates/index.html")
-	})
-
-	api := router.Group("/")
-	urlHandler.RegisterRoutes(api)
-	analyticsHandler.RegisterRoutes(api)
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":   "ok",
-			"service":  "url-shortener",
-			"database": "connected",
-			"redis":    "connected",
-		})
-	})
-	return router
-}
-
-func (h *URLHandler) RegisterRoutes(router *gin.RouterGroup) {
-	router.POST("/shorten", h.ShortenURL)
-	router.GET("/s/:short_url", h.RedirectURL)
-	router.GET("/urls", h.GetURLs)
-}
+package transport
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// InitRoutes builds the router. Only trustedProxies may set the client IP with X-Forwarded-For,
+// otherwise link IP restrictions could be bypassed with a forged header.
+func InitRoutes(urlHandler *URLHandler, analyticsHandler *AnalyticsHandler, trustedProxies []string) (*gin.Engine, error) {
+	router := gin.Default()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+
+	router.Use(func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+
+	router.Static("/static", "/app/internal/web/templates")
+	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
+
+	router.GET("/", func(c *gin.Context) {
+		c.File("/app/internal/web/templates/index.html")
+	})
+
+	api := router.Group("/")
+	urlHandler.RegisterRoutes(api)
+	analyticsHandler.RegisterRoutes(api)
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":   "ok",
+			"service":  "url-shortener",
+			"database": "connected",
+			"redis":    "connected",
+		})
+	})
+	return router, nil
+}
+
+func (h *URLHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/shorten", h.ShortenURL)
+	router.GET("/s/:short_url", h.RedirectURL)
+	router.GET("/urls", h.GetURLs)
+	router.PUT("/urls/:short_url/preview", h.UpdatePreview)
+	router.PUT("/urls/:short_url/privacy", h.UpdatePrivacy)
+}
@@ -25,18 +25,21 @@ async function shortenURL() {
         const data = await response.json();
 
         if (response.ok) {
-            const shortURL = `${window.location.origin}/s/${data.short_url}`;
+            const shortURL = `${window.location.origin}/s/${encodeURIComponent(data.short_url)}`;
             resultDiv.innerHTML = `
                 <div class="success">
                     <strong>✅ Short URL created!</strong><br>
-                    <a href="${shortURL}" target="_blank" class="short-url">${shortURL}</a><br>
+                    <a href="${shortURL}" target="_blank" class="short-url">${window.location.origin}/s/${data.short_url}</a><br>
                     <small>Original: ${data.original_url}</small>
                 </div>
             `;
             document.getElementById('shortenForm').reset();
             loadURLs();
         } else {
-            resultDiv.innerHTML = `<div class="error">Error: ${data.error}</div>`;
+            const hint = data.suggestions && data.suggestions.length
+                ? `<br><small>Available: ${data.suggestions.join(', ')}</small>`
+                : '';
+            resultDiv.innerHTML = `<div class="error">Error: ${data.error}${hint}</div>`;
         }
     } catch (error) {
         resultDiv.innerHTML = `<div class="error">Network error: ${error.message}</div>`;
@@ -59,10 +62,11 @@ async function loadURLs() {
             urlsList.innerHTML = urls.map(url => `
                 <div class="url-item">
                     <div class="url-info">
-                        <a href="/s/${url.short_url}" target="_blank" class="short-url">
+                        <a href="/s/${encodeURIComponent(url.short_url)}" target="_blank" class="short-url">
                             ${window.location.origin}/s/${url.short_url}
                         </a>
-                        <div class="original-url">${url.original_url}</div>
+                        <div class="original-url">${url.title ? url.title + ' — ' : ''}${url.original_url}</div>
+                        ${url.tags && url.tags.length ? `<small>🏷️ ${url.tags.join(', ')}</small><br>` : ''}
                         <small>👆 Clicks: ${url.clicks} | 📅 Created: ${new Date(url.created_at).toLocaleDateString()}</small>
                     </div>
                 </div>
@@ -85,13 +89,14 @@ async function loadAnalytics() {
     }
 
     try {
-        const response = await fetch(`/analytics/${shortURL}`);
+        const response = await fetch(`/analytics/${encodeURIComponent(shortURL)}`);
         const analytics = await response.json();
 
         if (response.ok) {
             analyticsResult.innerHTML = `
                 <div class="analytics-section">
                     <h3>📊 Total Clicks: ${analytics.total_clicks}</h3>
+                    ${analytics.blocked_attempts > 0 ? `<p>🚫 Blocked attempts: ${analytics.blocked_attempts}</p>` : ''}
                 </div>
                 
                 <div class="analytics-section">
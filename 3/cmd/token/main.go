@@ -0,0 +1,32 @@
+// Выпускает токен пользователя для заголовка "Authorization: Bearer": так же его подписывает шлюз после входа.
+// Ключ берётся из AUTH_SECRET или auth.secret в config.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/config"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/usertoken"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	user := flag.String("user", "", "имя пользователя")
+	ttl := flag.Duration("ttl", 24*time.Hour, "срок действия токена")
+	flag.Parse()
+
+	var secret string
+	if viperInstance, err := config.LoadConfig(); err == nil {
+		if cfg, err := config.ParseConfig(viperInstance); err == nil {
+			secret = cfg.Auth.Secret
+		}
+	}
+
+	token, err := usertoken.New(config.GetEnv("AUTH_SECRET", secret)).Sign(*user, time.Now().Add(*ttl))
+	if err != nil {
+		logrus.Fatalf("Cannot issue token. Error: {%s}", err.Error())
+	}
+	fmt.Println(token)
+}
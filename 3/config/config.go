@@ -1,84 +1,175 @@
-package config
-
-import (
-	"log"
-	"os"
-	"time"
-
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	Redis  RedisConfig  `mapstructure:"redis"`
-	App    AppConfig    `mapstructure:"app"`
-}
-
-type ServerConfig struct {
-	AppVersion   string `json:"appVersion"`
-	Host         string `json:"host" validate:"required"`
-	Port         string `json:"port" validate:"required"`
-	Timeout      time.Duration
-	Idle_timeout time.Duration
-	Env          string `json:"environment"`
-	Mode         string `mapstructure:"mode"`
-}
-
-type RedisConfig struct {
-	URL      string `json:"URL"`
-	Host     string `json:"host" validate:"required"`
-	Port     int    `json:"port" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	DB       int    `json:"db" validate:"required"`
-
-	// Настройки пула соединений
-	MaxRetries   int
-	PoolSize     int
-	MinIdleConns int
-	MaxIdleConns int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	PoolTimeout  time.Duration
-}
-
-type AppConfig struct {
-	ShortURLLength int           `mapstructure:"short_url_length"`
-	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
-	BaseURL        string        `mapstructure:"base_url"`
-}
-
-func LoadConfig() (*viper.Viper, error) {
-
-	viperInstance := viper.New()
-
-	viperInstance.AddConfigPath("./config")
-	viperInstance.SetConfigName("config")
-	viperInstance.SetConfigType("yaml")
-
-	err := viperInstance.ReadInConfig()
-
-	if err != nil {
-		return nil, err
-	}
-	return viperInstance, nil
-}
-
-func ParseConfig(v *viper.Viper) (*Config, error) {
-
-	var c Config
-
-	err := v.Unmarshal(&c)
-	if err != nil {
-		log.Fatalf("unable to decode config into struct, %v", err)
-		return nil, err
-	}
-	return &c, nil
-}
-
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Server    ServerConfig    `mapstructure:"server"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	App       AppConfig       `mapstructure:"app"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Presence  PresenceConfig  `mapstructure:"presence"`
+	RBAC      RBACConfig      `mapstructure:"rbac"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Search    SearchConfig    `mapstructure:"search"`
+	Shortener ShortenerConfig `mapstructure:"shortener"`
+}
+
+type ServerConfig struct {
+	AppVersion   string `json:"appVersion"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required"`
+	Timeout      time.Duration
+	Idle_timeout time.Duration
+	Env          string `json:"environment"`
+	Mode         string `mapstructure:"mode"`
+	LogLevel     string `mapstructure:"log_level"` // debug, info, warn или error; меняется без перезапуска
+}
+
+type RedisConfig struct {
+	URL      string `json:"URL"`
+	Host     string `json:"host" validate:"required"`
+	Port     int    `json:"port" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	DB       int    `json:"db" validate:"required"`
+
+	// Mode - single (host и port) или sentinel (master_name и addrs сентинелов).
+	// Redis Cluster не поддерживается: комментарий, его ветка, черновик и поисковый
+	// индекс меняются одной транзакцией, а их ключи лежат в разных слотах
+	Mode             string   `mapstructure:"mode"`
+	MasterName       string   `mapstructure:"master_name"`
+	Addrs            []string `mapstructure:"addrs"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+	// Чтение с реплик: route_by_latency - с ближайшего узла, route_randomly - со случайного.
+	// Запись всегда идёт на мастер; базу, кроме 0, такой клиент не выбирает
+	RouteByLatency bool `mapstructure:"route_by_latency"`
+	RouteRandomly  bool `mapstructure:"route_randomly"`
+
+	// Настройки пула соединений
+	MaxRetries   int
+	PoolSize     int
+	MinIdleConns int
+	MaxIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	// RedisModeCluster распознаётся только для того, чтобы отклонить его с понятной ошибкой
+	RedisModeCluster = "cluster"
+)
+
+type AppConfig struct {
+	ShortURLLength int           `mapstructure:"short_url_length"`
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	BaseURL        string        `mapstructure:"base_url"`
+
+	// Сводки веток: число предложений и время жизни в кэше
+	SummarySentences int           `mapstructure:"summary_sentences"`
+	SummaryTTL       time.Duration `mapstructure:"summary_ttl"`
+
+	// Сколько хранится черновик ответа с последнего автосохранения
+	DraftTTL time.Duration `mapstructure:"draft_ttl"`
+}
+
+// RetentionConfig - политика очистки неактивных веток
+type RetentionConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Action         string        `mapstructure:"action"` // delete или archive
+	InactiveMonths int           `mapstructure:"inactive_months"`
+	DryRun         bool          `mapstructure:"dry_run"` // только отчёт, без удаления
+	MaxThreads     int           `mapstructure:"max_threads"`
+	Interval       time.Duration `mapstructure:"interval"`
+	ArchiveMonths  int           `mapstructure:"archive_months"` // срок хранения архива ветки
+}
+
+// PresenceConfig - зрители и набор текста в ветках
+type PresenceConfig struct {
+	ViewerTTL   time.Duration `mapstructure:"viewer_ttl"`   // зритель пропадает без heartbeat
+	TypingTTL   time.Duration `mapstructure:"typing_ttl"`   // признак набора гаснет без обновления
+	UpdateRate  float64       `mapstructure:"update_rate"`  // сообщений в секунду от одного клиента
+	UpdateBurst int           `mapstructure:"update_burst"` // сверх этого лишние сообщения отбрасываются
+}
+
+// RBACConfig - роли пользователей; пользователь берётся из подписанного токена (AuthConfig)
+type RBACConfig struct {
+	DefaultRole string   `mapstructure:"default_role"` // роль пользователя, которому её не назначали
+	Admins      []string `mapstructure:"admins"`       // всегда администраторы, через API их роль не меняется
+}
+
+// AuthConfig - проверка пользователя: шлюз после входа выдаёт токен "Authorization: Bearer", подписанный secret.
+// Переменная окружения AUTH_SECRET заменяет secret; без ключа все запросы анонимные
+type AuthConfig struct {
+	Secret string `mapstructure:"secret"`
+}
+
+// SearchConfig - перестроение поискового индекса через /admin/search/reindex
+type SearchConfig struct {
+	ReindexChunkSize int `mapstructure:"reindex_chunk_size"` // комментариев за один проход SSCAN
+}
+
+// ShortenerConfig - сервис сокращения ссылок для POST /comments/:id/share; пустой url выключает короткие ссылки
+type ShortenerConfig struct {
+	URL     string        `mapstructure:"url"`     // адрес API сервиса, например http://url-shortener:8080
+	Timeout time.Duration `mapstructure:"timeout"` // на один запрос сокращения
+}
+
+func LoadConfig() (*viper.Viper, error) {
+
+	viperInstance := viper.New()
+
+	viperInstance.AddConfigPath("./config")
+	viperInstance.SetConfigName("config")
+	viperInstance.SetConfigType("yaml")
+
+	err := viperInstance.ReadInConfig()
+
+	if err != nil {
+		return nil, err
+	}
+	return viperInstance, nil
+}
+
+func ParseConfig(v *viper.Viper) (*Config, error) {
+
+	var c Config
+
+	// Ошибка возвращается, а не завершает процесс: ParseConfig вызывается и при перезагрузке
+	err := v.Unmarshal(&c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+
+	switch c.Redis.Mode {
+	case "", RedisModeSingle:
+	case RedisModeSentinel:
+		if c.Redis.MasterName == "" || len(c.Redis.Addrs) == 0 {
+			return nil, fmt.Errorf("redis: sentinel mode requires master_name and addrs")
+		}
+		if (c.Redis.RouteByLatency || c.Redis.RouteRandomly) && c.Redis.DB != 0 {
+			return nil, fmt.Errorf("redis: reading from replicas supports only db 0, got %d", c.Redis.DB)
+		}
+	case RedisModeCluster:
+		return nil, fmt.Errorf("redis: cluster mode is not supported: comment, thread, draft and search index keys " +
+			"are updated in one transaction and live in different hash slots; use single or sentinel")
+	default:
+		return nil, fmt.Errorf("redis: expected mode single or sentinel, got %q", c.Redis.Mode)
+	}
+	return &c, nil
+}
+
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -1,85 +1,165 @@
-package appServer
-
-import (
-	"context"
-	"crypto/tls"
-	"log"
-
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/3/config"
-	"github.com/ds124wfegd/WB_L3/3/internal/database"
-	"github.com/ds124wfegd/WB_L3/3/internal/pkg/redis"
-	"github.com/ds124wfegd/WB_L3/3/internal/service"
-	"github.com/ds124wfegd/WB_L3/3/internal/transport"
-	"github.com/gin-gonic/gin"
-
-	"github.com/sirupsen/logrus"
-)
-
-type Server struct {
-	httpServer *http.Server
-}
-
-func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:              ":" + cfg.Server.Port,
-		Handler:           handler,
-		MaxHeaderBytes:    1 << 20,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      cfg.Server.Timeout,
-		IdleTimeout:       cfg.Server.Idle_timeout,
-		ReadHeaderTimeout: 3 * time.Second,
-		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
-		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
-	}
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-func NewServer(cfg *config.Config) {
-
-	logrus.SetFormatter(new(logrus.JSONFormatter))
-
-	redisClient := redis.NewRedisClient(&cfg.Redis)
-	defer redisClient.Close()
-
-	repo, err := database.NewCommentRepository(redisClient)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
-	}
-	log.Println("Successfully connected to Redis")
-
-	service := service.NewCommentService(repo)
-
-	if cfg.Server.Mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	srv := new(Server)
-	go func() {
-		if err := srv.Run(cfg, transport.InitRoutes(service)); err != nil {
-			logrus.Fatalf("error occured while running http server: %s", err.Error())
-		}
-	}()
-
-	logrus.Print("App Started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
-
-	logrus.Print("App Shutting Down")
-
-	if err := srv.Shutdown(context.Background()); err != nil {
-		logrus.Errorf("error occured on server shutting down: %s", err.Error())
-	}
-
-}
+package appServer
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/config"
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/redis"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/shortener"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/summarizer"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/usertoken"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+	"github.com/ds124wfegd/WB_L3/3/internal/transport"
+	"github.com/ds124wfegd/WB_L3/3/internal/worker"
+	"github.com/gin-gonic/gin"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Server struct {
+	httpServer *http.Server
+}
+
+func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
+	s.httpServer = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		MaxHeaderBytes:    1 << 20,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      cfg.Server.Timeout,
+		IdleTimeout:       cfg.Server.Idle_timeout,
+		ReadHeaderTimeout: 3 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
+		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func NewServer(cfg *config.Config, watcher *config.Watcher) {
+
+	logrus.SetFormatter(new(logrus.JSONFormatter))
+	setLogLevel(cfg.Server.LogLevel)
+
+	redisClient := redis.NewRedisClient(&cfg.Redis)
+	defer redisClient.Close()
+
+	repo, err := database.NewCommentRepository(redisClient)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	log.Println("Successfully connected to Redis")
+
+	presenceService := service.NewPresenceService(database.NewPresenceRepository(redisClient), repo, cfg.Presence.ViewerTTL, cfg.Presence.TypingTTL)
+	draftService := service.NewDraftService(repo, cfg.App.DraftTTL)
+	roleService := service.NewRoleService(database.NewRoleRepository(redisClient), entity.Role(cfg.RBAC.DefaultRole), cfg.RBAC.Admins)
+	searchIndexService := service.NewSearchIndexService(repo, cfg.Search.ReindexChunkSize)
+	// Без адреса сервиса сокращения ссылок POST /comments/:id/share отвечает 503, постоянные ссылки работают
+	var linkShortener shortener.Shortener
+	if cfg.Shortener.URL != "" {
+		linkShortener = shortener.NewClient(cfg.Shortener.URL, cfg.Shortener.Timeout)
+	}
+	shareService := service.NewShareService(repo, linkShortener, cfg.App.BaseURL)
+	service := service.NewCommentService(repo, summarizer.NewExtractive(cfg.App.SummarySentences), cfg.App.SummaryTTL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cfg.Retention.Enabled {
+		policy := retentionPolicy(cfg.Retention)
+		if err := policy.Validate(); err != nil {
+			log.Fatalf("Invalid retention config: %v", err)
+		}
+		retentionWorker := worker.NewRetentionWorker(service, policy, cfg.Retention.Interval)
+		go retentionWorker.Start(ctx)
+
+		// Включение и выключение очистки требует перезапуска, политика и интервал - нет
+		watcher.Subscribe("retention worker", func(cfg *config.Config) {
+			policy := retentionPolicy(cfg.Retention)
+			if err := policy.Validate(); err != nil {
+				logrus.Errorf("Retention config not applied: %v", err)
+				return
+			}
+			retentionWorker.Update(policy, cfg.Retention.Interval)
+		})
+	}
+
+	watcher.Subscribe("drafts", func(cfg *config.Config) {
+		draftService.SetTTL(cfg.App.DraftTTL)
+	})
+	watcher.Subscribe("log level", func(cfg *config.Config) {
+		setLogLevel(cfg.Server.LogLevel)
+	})
+	if err := watcher.Start(); err != nil {
+		logrus.Warnf("Config hot reload disabled: %v", err)
+	}
+	defer watcher.Close()
+
+	// Без ключа подписи токенов пользователи не опознаются: писать и модерировать нельзя никому
+	userTokens := usertoken.New(config.GetEnv("AUTH_SECRET", cfg.Auth.Secret))
+	if !userTokens.Enabled() {
+		logrus.Warn("auth.secret is not set: every request is anonymous and read-only")
+	}
+
+	presenceHub := transport.NewPresenceHub(presenceService, cfg.Presence.UpdateRate, cfg.Presence.UpdateBurst)
+	go presenceHub.Run(ctx)
+
+	if cfg.Server.Mode == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	srv := new(Server)
+	go func() {
+		if err := srv.Run(cfg, transport.InitRoutes(service, draftService, roleService, searchIndexService, shareService, presenceHub, userTokens)); err != nil {
+			logrus.Fatalf("error occured while running http server: %s", err.Error())
+		}
+	}()
+
+	logrus.Print("App Started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	<-quit
+
+	logrus.Print("App Shutting Down")
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		logrus.Errorf("error occured on server shutting down: %s", err.Error())
+	}
+
+}
+
+func retentionPolicy(cfg config.RetentionConfig) entity.RetentionPolicy {
+	return entity.RetentionPolicy{
+		Action:         entity.RetentionAction(cfg.Action),
+		InactiveMonths: cfg.InactiveMonths,
+		DryRun:         cfg.DryRun,
+		MaxThreads:     cfg.MaxThreads,
+		ArchiveMonths:  cfg.ArchiveMonths,
+	}
+}
+
+// setLogLevel применяет server.log_level; пустой или неизвестный уровень - info
+func setLogLevel(level string) {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		if level != "" {
+			logrus.Warnf("Unknown log level %q, using info", level)
+		}
+		parsed = logrus.InfoLevel
+	}
+	logrus.SetLevel(parsed)
+}
@@ -1,333 +1,521 @@
-package database
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"sort"
-	"strconv"
-	"strings"
-
-	"github.com/ds124wfegd/WB_L3/3/internal/entity"
-	"github.com/redis/go-redis/v9"
-)
-
-type CommentRepository struct {
-	client *redis.Client
-	ctx    context.Context
-}
-
-func NewCommentRepository(redisClient *redis.Client) (*CommentRepository, error) {
-
-	ctx := context.Background()
-
-	// Проверка подключения
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		return nil, err
-	}
-
-	return &CommentRepository{
-		client: redisClient,
-		ctx:    ctx,
-	}, nil
-}
-
-func (r *CommentRepository) Create(comment entity.Comment) error {
-	// Сохраняем комментарий
-	commentKey := fmt.Sprintf("comment:%s", comment.ID)
-	if err := r.client.Set(r.ctx, commentKey, &comment, 0).Err(); err != nil {
-		return err
-	}
-
-	// Добавляем в индекс по родителю
-	if comment.ParentID == "" {
-		// Корневой комментарий
-		if err := r.client.SAdd(r.ctx, "comments:root", comment.ID).Err(); err != nil {
-			return err
-		}
-	} else {
-		// Дочерний комментарий
-		parentKey := fmt.Sprintf("comment:%s:children", comment.ParentID)
-		if err := r.client.SAdd(r.ctx, parentKey, comment.ID).Err(); err != nil {
-			return err
-		}
-	}
-
-	// Добавляем в индекс для поиска
-	searchKey := "comments:all"
-	if err := r.client.SAdd(r.ctx, searchKey, comment.ID).Err(); err != nil {
-		return err
-	}
-
-	// Индексируем для поиска по тексту и автору
-	if err := r.indexCommentForSearch(&comment); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *CommentRepository) GetByID(id string) (*entity.Comment, bool) {
-	commentKey := fmt.Sprintf("comment:%s", id)
-	data, err := r.client.Get(r.ctx, commentKey).Bytes()
-	if err != nil {
-		if err == redis.Nil {
-			return nil, false
-		}
-		return nil, false
-	}
-
-	var comment entity.Comment
-	if err := json.Unmarshal(data, &comment); err != nil {
-		return nil, false
-	}
-
-	return &comment, true
-}
-
-func (r *CommentRepository) GetChildren(parentID string, page, pageSize int, sortBy string) ([]entity.Comment, int) {
-	var children []entity.Comment
-	var childIDs []string
-
-	if parentID == "" {
-		// Корневые комментарии
-		ids, err := r.client.SMembers(r.ctx, "comments:root").Result()
-		if err != nil {
-			return children, 0
-		}
-		childIDs = ids
-	} else {
-		// Дочерние комментарии
-		parentKey := fmt.Sprintf("comment:%s:children", parentID)
-		ids, err := r.client.SMembers(r.ctx, parentKey).Result()
-		if err != nil {
-			return children, 0
-		}
-		childIDs = ids
-	}
-
-	// Получаем комментарии по ID
-	for _, id := range childIDs {
-		if comment, exists := r.GetByID(id); exists {
-			children = append(children, *comment)
-		}
-	}
-
-	// Сортировка
-	switch sortBy {
-	case "created_at_desc":
-		sort.Slice(children, func(i, j int) bool {
-			return children[i].CreatedAt.After(children[j].CreatedAt)
-		})
-	case "created_at_asc":
-		sort.Slice(children, func(i, j int) bool {
-			return children[i].CreatedAt.Before(children[j].CreatedAt)
-		})
-	case "author":
-		sort.Slice(children, func(i, j int) bool {
-			return strings.ToLower(children[i].Author) < strings.ToLower(children[j].Author)
-		})
-	}
-
-	// Пагинация
-	total := len(children)
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
-
-	start := (page - 1) * pageSize
-	end := start + pageSize
-
-	if start >= total {
-		return []entity.Comment{}, total
-	}
-	if end > total {
-		end = total
-	}
-
-	return children[start:end], total
-}
-
-func (r *CommentRepository) Delete(id string) error {
-	// Рекурсивное удаление
-	var deleteRecursive func(string) error
-	deleteRecursive = func(commentID string) error {
-		// Удаляем дочерние комментарии
-		childrenKey := fmt.Sprintf("comment:%s:children", commentID)
-		childIDs, err := r.client.SMembers(r.ctx, childrenKey).Result()
-		if err != nil && err != redis.Nil {
-			return err
-		}
-
-		for _, childID := range childIDs {
-			if err := deleteRecursive(childID); err != nil {
-				return err
-			}
-		}
-
-		// Удаляем из индексов
-		comment, exists := r.GetByID(commentID)
-		if exists {
-			// Удаляем из родительского индекса
-			if comment.ParentID == "" {
-				r.client.SRem(r.ctx, "comments:root", commentID)
-			} else {
-				parentKey := fmt.Sprintf("comment:%s:children", comment.ParentID)
-				r.client.SRem(r.ctx, parentKey, commentID)
-			}
-
-			// Удаляем из поискового индекса
-			r.client.SRem(r.ctx, "comments:all", commentID)
-			r.removeCommentFromSearchIndex(comment)
-		}
-
-		// Удаляем сам комментарий и его children set
-		r.client.Del(r.ctx, fmt.Sprintf("comment:%s", commentID))
-		r.client.Del(r.ctx, childrenKey)
-
-		return nil
-	}
-
-	return deleteRecursive(id)
-}
-
-func (r *CommentRepository) Search(query string, page, pageSize int) ([]entity.Comment, int) {
-	allComments, err := r.GetAllComments()
-	if err != nil {
-		return []entity.Comment{}, 0
-	}
-
-	var results []entity.Comment
-	query = strings.ToLower(query)
-
-	for _, comment := range allComments {
-		if strings.Contains(strings.ToLower(comment.Text), query) ||
-			strings.Contains(strings.ToLower(comment.Author), query) {
-			results = append(results, comment)
-		}
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].CreatedAt.After(results[j].CreatedAt)
-	})
-
-	total := len(results)
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
-
-	start := (page - 1) * pageSize
-	end := start + pageSize
-
-	if start >= total {
-		return []entity.Comment{}, total
-	}
-	if end > total {
-		end = total
-	}
-
-	return results[start:end], total
-}
-
-func (r *CommentRepository) BuildTree(parentID string, depth int) []entity.Comment {
-	if depth > 10 {
-		return []entity.Comment{}
-	}
-
-	children, _ := r.GetChildren(parentID, 1, 1000, "created_at_asc") // Получаем все дочерние без пагинации
-	var tree []entity.Comment
-
-	for _, child := range children {
-		node := child
-		node.Children = r.BuildTree(node.ID, depth+1)
-		tree = append(tree, node)
-	}
-
-	return tree
-}
-
-func (r *CommentRepository) GetAllComments() ([]entity.Comment, error) {
-	ids, err := r.client.SMembers(r.ctx, "comments:all").Result()
-	if err != nil {
-		return nil, err
-	}
-
-	var comments []entity.Comment
-	for _, id := range ids {
-		if comment, exists := r.GetByID(id); exists {
-			comments = append(comments, *comment)
-		}
-	}
-
-	return comments, nil
-}
-
-func (r *CommentRepository) indexCommentForSearch(comment *entity.Comment) error {
-	// Индексируем по словам в тексте (упрощенная версия)
-	words := strings.Fields(strings.ToLower(comment.Text))
-	for _, word := range words {
-		if len(word) > 2 { // Игнорируем короткие слова
-			key := fmt.Sprintf("search:text:%s", word)
-			r.client.SAdd(r.ctx, key, comment.ID)
-		}
-	}
-
-	// Индексируем по автору
-	authorKey := fmt.Sprintf("search:author:%s", strings.ToLower(comment.Author))
-	r.client.SAdd(r.ctx, authorKey, comment.ID)
-
-	return nil
-}
-
-func (r *CommentRepository) removeCommentFromSearchIndex(comment *entity.Comment) error {
-	words := strings.Fields(strings.ToLower(comment.Text))
-	for _, word := range words {
-		if len(word) > 2 {
-			key := fmt.Sprintf("search:text:%s", word)
-			r.client.SRem(r.ctx, key, comment.ID)
-		}
-	}
-
-	authorKey := fmt.Sprintf("search:author:%s", strings.ToLower(comment.Author))
-	r.client.SRem(r.ctx, authorKey, comment.ID)
-
-	return nil
-}
-
-// Дополнительные методы для управления Redis
-func (r *CommentRepository) FlushAll() error {
-	return r.client.FlushAll(r.ctx).Err()
-}
-
-func (r *CommentRepository) GetStats() (map[string]string, error) {
-	stats := make(map[string]string)
-
-	// Количество корневых комментариев
-	rootCount, err := r.client.SCard(r.ctx, "comments:root").Result()
-	if err != nil {
-		return nil, err
-	}
-	stats["root_comments"] = strconv.FormatInt(rootCount, 10)
-
-	// Общее количество комментариев
-	totalCount, err := r.client.SCard(r.ctx, "comments:all").Result()
-	if err != nil {
-		return nil, err
-	}
-	stats["total_comments"] = strconv.FormatInt(totalCount, 10)
-
-	// Информация о Redis
-	info, err := r.client.Info(r.ctx).Result()
-	if err != nil {
-		return nil, err
-	}
-	stats["redis_info"] = info
-
-	return stats, nil
-}
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+type CommentRepository struct {
+	client redis.UniversalClient
+	ctx    context.Context
+}
+
+func NewCommentRepository(redisClient redis.UniversalClient) (*CommentRepository, error) {
+
+	ctx := context.Background()
+
+	// Проверка подключения
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	return &CommentRepository{
+		client: redisClient,
+		ctx:    ctx,
+	}, nil
+}
+
+// Create сохраняет комментарий и в той же транзакции MULTI удаляет черновик автора
+// под этим родителем: черновик не переживает опубликованный ответ и не теряется без него
+func (r *CommentRepository) Create(comment entity.Comment) error {
+	commentKey := fmt.Sprintf("comment:%s", comment.ID)
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		// Сохраняем комментарий
+		pipe.Set(r.ctx, commentKey, &comment, 0)
+
+		// Добавляем в индекс по родителю
+		if comment.ParentID == "" {
+			// Корневой комментарий
+			pipe.SAdd(r.ctx, "comments:root", comment.ID)
+		} else {
+			// Дочерний комментарий
+			pipe.SAdd(r.ctx, fmt.Sprintf("comment:%s:children", comment.ParentID), comment.ID)
+		}
+
+		// Добавляем в индекс для поиска
+		pipe.SAdd(r.ctx, "comments:all", comment.ID)
+
+		pipe.Del(r.ctx, draftKey(comment.Author, comment.ParentID))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Индексируем для поиска по тексту и автору
+	if err := r.indexCommentForSearch(&comment); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CommentRepository) GetByID(id string) (*entity.Comment, bool) {
+	commentKey := fmt.Sprintf("comment:%s", id)
+	data, err := r.client.Get(r.ctx, commentKey).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false
+		}
+		return nil, false
+	}
+
+	var comment entity.Comment
+	if err := json.Unmarshal(data, &comment); err != nil {
+		return nil, false
+	}
+	// Комментарии, сохранённые до появления ревизий, считаются первой ревизией
+	if comment.Revision == 0 {
+		comment.Revision = 1
+	}
+
+	return &comment, true
+}
+
+// ErrCommentNotFound возвращается Update, если комментария нет
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Update меняет текст комментария, только если его текущая ревизия равна revision.
+// Ключ комментария отслеживается через WATCH, так что две правки одной ревизии
+// не могут пройти обе. При конфликте возвращается актуальная версия и entity.ErrRevisionConflict.
+func (r *CommentRepository) Update(id string, revision int64, text string) (*entity.Comment, error) {
+	commentKey := fmt.Sprintf("comment:%s", id)
+
+	var previous, updated entity.Comment
+	err := r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+		current, exists := r.getWithin(tx, commentKey)
+		if !exists {
+			return ErrCommentNotFound
+		}
+		if current.Revision != revision {
+			updated = *current
+			return entity.ErrRevisionConflict
+		}
+
+		previous = *current
+		updated = *current
+		updated.Text = text
+		updated.Revision++
+		updated.UpdatedAt = time.Now()
+
+		_, err := tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(r.ctx, commentKey, &updated, 0)
+			return nil
+		})
+		return err
+	}, commentKey)
+
+	switch {
+	case errors.Is(err, redis.TxFailedErr):
+		// Ключ изменился между чтением и записью: отдаём то, что записал победитель
+		latest, exists := r.GetByID(id)
+		if !exists {
+			return nil, ErrCommentNotFound
+		}
+		return latest, entity.ErrRevisionConflict
+	case errors.Is(err, entity.ErrRevisionConflict):
+		return &updated, err
+	case err != nil:
+		return nil, err
+	}
+
+	// Переиндексируем текст для поиска
+	r.removeCommentFromSearchIndex(&previous)
+	r.indexCommentForSearch(&updated)
+
+	return &updated, nil
+}
+
+func (r *CommentRepository) getWithin(tx *redis.Tx, commentKey string) (*entity.Comment, bool) {
+	data, err := tx.Get(r.ctx, commentKey).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var comment entity.Comment
+	if err := json.Unmarshal(data, &comment); err != nil {
+		return nil, false
+	}
+	if comment.Revision == 0 {
+		comment.Revision = 1
+	}
+
+	return &comment, true
+}
+
+func (r *CommentRepository) GetChildren(parentID string, page, pageSize int, sortBy string) ([]entity.Comment, int) {
+	var children []entity.Comment
+	var childIDs []string
+
+	if parentID == "" {
+		// Корневые комментарии
+		ids, err := r.client.SMembers(r.ctx, "comments:root").Result()
+		if err != nil {
+			return children, 0
+		}
+		childIDs = ids
+	} else {
+		// Дочерние комментарии
+		parentKey := fmt.Sprintf("comment:%s:children", parentID)
+		ids, err := r.client.SMembers(r.ctx, parentKey).Result()
+		if err != nil {
+			return children, 0
+		}
+		childIDs = ids
+	}
+
+	// Получаем комментарии по ID
+	for _, id := range childIDs {
+		if comment, exists := r.GetByID(id); exists {
+			children = append(children, *comment)
+		}
+	}
+
+	// Сортировка
+	switch sortBy {
+	case "created_at_desc":
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].CreatedAt.After(children[j].CreatedAt)
+		})
+	case "created_at_asc":
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].CreatedAt.Before(children[j].CreatedAt)
+		})
+	case "author":
+		sort.Slice(children, func(i, j int) bool {
+			return strings.ToLower(children[i].Author) < strings.ToLower(children[j].Author)
+		})
+	}
+
+	// Закреплённые идут первыми, порядок внутри групп сохраняется
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Pinned && !children[j].Pinned
+	})
+
+	// Пагинация
+	total := len(children)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+
+	if start >= total {
+		return []entity.Comment{}, total
+	}
+	if end > total {
+		end = total
+	}
+
+	return children[start:end], total
+}
+
+func (r *CommentRepository) Delete(id string) error {
+	// Рекурсивное удаление
+	var deleteRecursive func(string) error
+	deleteRecursive = func(commentID string) error {
+		// Удаляем дочерние комментарии
+		childrenKey := fmt.Sprintf("comment:%s:children", commentID)
+		childIDs, err := r.client.SMembers(r.ctx, childrenKey).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		for _, childID := range childIDs {
+			if err := deleteRecursive(childID); err != nil {
+				return err
+			}
+		}
+
+		// Удаляем из индексов
+		comment, exists := r.GetByID(commentID)
+		if exists {
+			// Удаляем из родительского индекса
+			if comment.ParentID == "" {
+				r.client.SRem(r.ctx, "comments:root", commentID)
+			} else {
+				parentKey := fmt.Sprintf("comment:%s:children", comment.ParentID)
+				r.client.SRem(r.ctx, parentKey, commentID)
+			}
+
+			// Удаляем из поискового индекса
+			r.client.SRem(r.ctx, "comments:all", commentID)
+			r.removeCommentFromSearchIndex(comment)
+		}
+
+		// Удаляем сам комментарий и его children set
+		r.client.Del(r.ctx, fmt.Sprintf("comment:%s", commentID))
+		r.client.Del(r.ctx, childrenKey)
+
+		return nil
+	}
+
+	return deleteRecursive(id)
+}
+
+// Search ищет по индексу активной версии. Пока индекс не перестраивали анализатором,
+// работает прежний поиск подстроки перебором всех комментариев
+func (r *CommentRepository) Search(query string, page, pageSize int) ([]entity.Comment, int) {
+	active, _, err := r.searchVersions()
+	if err != nil {
+		return []entity.Comment{}, 0
+	}
+
+	var results []entity.Comment
+	if active == 0 {
+		results, err = r.scanSearch(query)
+	} else {
+		results, err = r.indexSearch(active, query)
+	}
+	if err != nil {
+		return []entity.Comment{}, 0
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	total := len(results)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+
+	if start >= total {
+		return []entity.Comment{}, total
+	}
+	if end > total {
+		end = total
+	}
+
+	return results[start:end], total
+}
+
+func (r *CommentRepository) scanSearch(query string) ([]entity.Comment, error) {
+	allComments, err := r.GetAllComments()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []entity.Comment
+	query = strings.ToLower(query)
+
+	for _, comment := range allComments {
+		if strings.Contains(strings.ToLower(comment.Text), query) ||
+			strings.Contains(strings.ToLower(comment.Author), query) {
+			results = append(results, comment)
+		}
+	}
+
+	return results, nil
+}
+
+func (r *CommentRepository) BuildTree(parentID string, depth int) []entity.Comment {
+	if depth > 10 {
+		return []entity.Comment{}
+	}
+
+	children, _ := r.GetChildren(parentID, 1, 1000, "created_at_asc") // Получаем все дочерние без пагинации
+	var tree []entity.Comment
+
+	for _, child := range children {
+		node := child
+		node.Children = r.BuildTree(node.ID, depth+1)
+		tree = append(tree, node)
+	}
+
+	return tree
+}
+
+func (r *CommentRepository) GetAllComments() ([]entity.Comment, error) {
+	ids, err := r.client.SMembers(r.ctx, "comments:all").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []entity.Comment
+	for _, id := range ids {
+		if comment, exists := r.GetByID(id); exists {
+			comments = append(comments, *comment)
+		}
+	}
+
+	return comments, nil
+}
+
+// Дополнительные методы для управления Redis
+func (r *CommentRepository) FlushAll() error {
+	return r.client.FlushAll(r.ctx).Err()
+}
+
+func (r *CommentRepository) GetStats() (map[string]string, error) {
+	stats := make(map[string]string)
+
+	// Количество корневых комментариев
+	rootCount, err := r.client.SCard(r.ctx, "comments:root").Result()
+	if err != nil {
+		return nil, err
+	}
+	stats["root_comments"] = strconv.FormatInt(rootCount, 10)
+
+	// Общее количество комментариев
+	totalCount, err := r.client.SCard(r.ctx, "comments:all").Result()
+	if err != nil {
+		return nil, err
+	}
+	stats["total_comments"] = strconv.FormatInt(totalCount, 10)
+
+	// Информация о Redis
+	info, err := r.client.Info(r.ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	stats["redis_info"] = info
+
+	return stats, nil
+}
+
+// Сводки хранятся по ключу summary:<thread>:<revision>, поэтому изменение ветки
+// само по себе делает старую сводку недостижимой, а TTL убирает её из Redis
+func (r *CommentRepository) GetSummary(thread, revision string) (*entity.ThreadSummary, bool) {
+	data, err := r.client.Get(r.ctx, fmt.Sprintf("summary:%s:%s", thread, revision)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var summary entity.ThreadSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, false
+	}
+
+	return &summary, true
+}
+
+func (r *CommentRepository) SaveSummary(summary *entity.ThreadSummary, ttl time.Duration) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("summary:%s:%s", summary.Thread, summary.Revision)
+	return r.client.Set(r.ctx, key, data, ttl).Err()
+}
+
+// GetRootIDs возвращает ID корневых комментариев, то есть всех веток
+func (r *CommentRepository) GetRootIDs() ([]string, error) {
+	return r.client.SMembers(r.ctx, "comments:root").Result()
+}
+
+// ArchiveThread сохраняет ветку одним сжатым значением и удаляет её рабочие ключи,
+// так что в памяти остаётся только архивная копия. Копия живёт до archive.ExpiresAt,
+// индекс comments:archived упорядочен по этому сроку и при каждой записи очищается от истёкших веток
+func (r *CommentRepository) ArchiveThread(archive *entity.ArchivedThread) error {
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	ttl := time.Until(archive.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("archive of thread %s has already expired", archive.Thread)
+	}
+
+	key := fmt.Sprintf("archive:thread:%s", archive.Thread)
+	if err := r.client.Set(r.ctx, key, buf.Bytes(), ttl).Err(); err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(r.ctx, "comments:archived", redis.Z{
+		Score:  float64(archive.ExpiresAt.Unix()),
+		Member: archive.Thread,
+	})
+	pipe.ZRemRangeByScore(r.ctx, "comments:archived", "-inf", strconv.FormatInt(archive.ArchivedAt.Unix(), 10))
+	if _, err := pipe.Exec(r.ctx); err != nil {
+		return err
+	}
+
+	return r.Delete(archive.Thread)
+}
+
+func (r *CommentRepository) GetArchivedThread(thread string) (*entity.ArchivedThread, bool) {
+	data, err := r.client.Get(r.ctx, fmt.Sprintf("archive:thread:%s", thread)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	var archive entity.ArchivedThread
+	if err := json.NewDecoder(zr).Decode(&archive); err != nil {
+		return nil, false
+	}
+
+	return &archive, true
+}
+
+func (r *CommentRepository) SaveRetentionReport(report *entity.RetentionReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(r.ctx, "retention:last_report", data, 0).Err()
+}
+
+func (r *CommentRepository) GetRetentionReport() (*entity.RetentionReport, bool) {
+	data, err := r.client.Get(r.ctx, "retention:last_report").Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var report entity.RetentionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, false
+	}
+
+	return &report, true
+}
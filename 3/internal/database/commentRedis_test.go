@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCommentRepository(t *testing.T) (*CommentRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	repo, err := NewCommentRepository(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, mr
+}
+
+// TestArchiveThreadExpires проверяет, что архив ветки удаляется по истечении срока
+// и истёкшие ветки не копятся в индексе архива
+func TestArchiveThreadExpires(t *testing.T) {
+	repo, mr := newTestCommentRepository(t)
+
+	now := time.Now()
+	if err := repo.ArchiveThread(&entity.ArchivedThread{Thread: "old", ArchivedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl := mr.TTL("archive:thread:old"); ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("archive TTL = %v, want (0, 1h]", ttl)
+	}
+	if _, ok := repo.GetArchivedThread("old"); !ok {
+		t.Fatal("archive should be readable before it expires")
+	}
+
+	mr.FastForward(2 * time.Hour)
+	if _, ok := repo.GetArchivedThread("old"); ok {
+		t.Fatal("archive should be gone after it expires")
+	}
+
+	// Индекс упорядочен по сроку, поэтому "old" вычищается при следующей записи
+	later := now.Add(2 * time.Hour)
+	if err := repo.ArchiveThread(&entity.ArchivedThread{Thread: "new", ArchivedAt: later, ExpiresAt: later.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	members, err := mr.ZMembers("comments:archived")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != "new" {
+		t.Fatalf("archive index = %v, want [new]", members)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+// draftKey - ключ черновика автора под родителем; корневой черновик хранится под "root".
+// Автор приводится к нижнему регистру так же, как в поисковом индексе.
+func draftKey(author, parentID string) string {
+	if parentID == "" {
+		parentID = "root"
+	}
+	return fmt.Sprintf("draft:%s:%s", strings.ToLower(author), parentID)
+}
+
+// SaveDraft перезаписывает черновик и продлевает его жизнь на ttl
+func (r *CommentRepository) SaveDraft(draft *entity.Draft, ttl time.Duration) error {
+	return r.client.Set(r.ctx, draftKey(draft.Author, draft.ParentID), draft, ttl).Err()
+}
+
+func (r *CommentRepository) GetDraft(author, parentID string) (*entity.Draft, bool) {
+	data, err := r.client.Get(r.ctx, draftKey(author, parentID)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var draft entity.Draft
+	if err := json.Unmarshal(data, &draft); err != nil {
+		return nil, false
+	}
+
+	return &draft, true
+}
+
+// DeleteDraft удаляет черновик; false - черновика не было
+func (r *CommentRepository) DeleteDraft(author, parentID string) (bool, error) {
+	deleted, err := r.client.Del(r.ctx, draftKey(author, parentID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted > 0, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+func threadSettingsKey(thread string) string {
+	return fmt.Sprintf("thread:%s:settings", thread)
+}
+
+// SetPinned закрепляет или открепляет комментарий. Ключ отслеживается через WATCH, чтобы
+// одновременная правка текста не затёрла отметку; ревизия не меняется - текст тот же.
+func (r *CommentRepository) SetPinned(id string, pinned bool) (*entity.Comment, error) {
+	commentKey := fmt.Sprintf("comment:%s", id)
+
+	var updated entity.Comment
+	err := r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+		current, exists := r.getWithin(tx, commentKey)
+		if !exists {
+			return ErrCommentNotFound
+		}
+
+		updated = *current
+		updated.Pinned = pinned
+
+		_, err := tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(r.ctx, commentKey, &updated, 0)
+			return nil
+		})
+		return err
+	}, commentKey)
+	if errors.Is(err, redis.TxFailedErr) {
+		// Комментарий поменяли между чтением и записью - повторяем с новой версией
+		return r.SetPinned(id, pinned)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// GetThreadRoot возвращает ID корневого комментария ветки, в которой находится комментарий
+func (r *CommentRepository) GetThreadRoot(id string) (string, bool) {
+	for {
+		comment, exists := r.GetByID(id)
+		if !exists {
+			return "", false
+		}
+		if comment.ParentID == "" {
+			return comment.ID, true
+		}
+		id = comment.ParentID
+	}
+}
+
+// GetThreadSettings возвращает настройки ветки; у ветки без настроек они нулевые
+func (r *CommentRepository) GetThreadSettings(thread string) (*entity.ThreadSettings, error) {
+	settings := &entity.ThreadSettings{Thread: thread}
+
+	data, err := r.client.Get(r.ctx, threadSettingsKey(thread)).Bytes()
+	if err == redis.Nil {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *CommentRepository) SaveThreadSettings(settings *entity.ThreadSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(r.ctx, threadSettingsKey(settings.Thread), data, 0).Err()
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+// presenceChannel - канал, в который публикуется ID ветки при изменении присутствия,
+// чтобы все экземпляры сервиса разослали новое состояние своим клиентам
+const presenceChannel = "presence:updates"
+
+// PresenceRepository хранит присутствие в ZSET по ветке: участник - сессия, score - момент,
+// когда она истечёт без обновления. Сами ключи тоже живут с TTL и пропадают вместе с последним зрителем.
+type PresenceRepository struct {
+	client redis.UniversalClient
+	ctx    context.Context
+}
+
+func NewPresenceRepository(redisClient redis.UniversalClient) *PresenceRepository {
+	return &PresenceRepository{
+		client: redisClient,
+		ctx:    context.Background(),
+	}
+}
+
+func viewersKey(thread string) string {
+	return fmt.Sprintf("presence:%s:viewers", thread)
+}
+
+func typingKey(thread string) string {
+	return fmt.Sprintf("presence:%s:typing", thread)
+}
+
+// namesKey - имена сессий, разрешивших их показывать
+func namesKey(thread string) string {
+	return fmt.Sprintf("presence:%s:names", thread)
+}
+
+// Touch отмечает, что сессия смотрит ветку ещё ttl
+func (r *PresenceRepository) Touch(thread, session, name string, ttl time.Duration) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(r.ctx, viewersKey(thread), redis.Z{Score: expiresAt(ttl), Member: session})
+		pipe.Expire(r.ctx, viewersKey(thread), ttl)
+		if name != "" {
+			pipe.HSet(r.ctx, namesKey(thread), session, name)
+			pipe.Expire(r.ctx, namesKey(thread), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// SetTyping отмечает, что сессия набирает текст ещё ttl
+func (r *PresenceRepository) SetTyping(thread, session string, ttl time.Duration) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(r.ctx, typingKey(thread), redis.Z{Score: expiresAt(ttl), Member: session})
+		pipe.Expire(r.ctx, typingKey(thread), ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *PresenceRepository) StopTyping(thread, session string) error {
+	return r.client.ZRem(r.ctx, typingKey(thread), session).Err()
+}
+
+func (r *PresenceRepository) Leave(thread, session string) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(r.ctx, viewersKey(thread), session)
+		pipe.ZRem(r.ctx, typingKey(thread), session)
+		pipe.HDel(r.ctx, namesKey(thread), session)
+		return nil
+	})
+	return err
+}
+
+// Snapshot убирает истёкшие сессии и возвращает текущее состояние ветки
+func (r *PresenceRepository) Snapshot(thread string) (*entity.PresenceSnapshot, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	alive := &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}
+
+	var viewersCmd, typingCmd, expiredCmd *redis.StringSliceCmd
+	_, err := r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		expiredCmd = pipe.ZRangeByScore(r.ctx, viewersKey(thread), &redis.ZRangeBy{Min: "-inf", Max: now})
+		viewersCmd = pipe.ZRangeByScore(r.ctx, viewersKey(thread), alive)
+		typingCmd = pipe.ZRangeByScore(r.ctx, typingKey(thread), alive)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expired := expiredCmd.Val(); len(expired) > 0 {
+		r.removeExpired(thread, now, expired)
+	}
+
+	viewers, typing := viewersCmd.Val(), typingCmd.Val()
+	snapshot := &entity.PresenceSnapshot{
+		Thread:      thread,
+		Viewing:     len(viewers),
+		Viewers:     []string{},
+		Typing:      len(typing),
+		TypingNames: []string{},
+	}
+	if len(viewers) == 0 {
+		return snapshot, nil
+	}
+
+	names, err := r.client.HMGet(r.ctx, namesKey(thread), viewers...).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessionNames := make(map[string]string, len(viewers))
+	for i, name := range names {
+		if name, ok := name.(string); ok && name != "" {
+			sessionNames[viewers[i]] = name
+		}
+	}
+
+	snapshot.Viewers = uniqueNames(viewers, sessionNames)
+	snapshot.TypingNames = uniqueNames(typing, sessionNames)
+	return snapshot, nil
+}
+
+func (r *PresenceRepository) removeExpired(thread, now string, sessions []string) {
+	members := make([]interface{}, len(sessions))
+	for i, session := range sessions {
+		members[i] = session
+	}
+
+	r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(r.ctx, viewersKey(thread), "-inf", now)
+		pipe.ZRemRangeByScore(r.ctx, typingKey(thread), "-inf", now)
+		pipe.HDel(r.ctx, namesKey(thread), sessions...)
+		pipe.ZRem(r.ctx, typingKey(thread), members...)
+		return nil
+	})
+}
+
+// Publish сообщает всем экземплярам сервиса, что присутствие в ветке изменилось
+func (r *PresenceRepository) Publish(thread string) error {
+	return r.client.Publish(r.ctx, presenceChannel, thread).Err()
+}
+
+// Subscribe возвращает ID веток с изменившимся присутствием до отмены ctx
+func (r *PresenceRepository) Subscribe(ctx context.Context) <-chan string {
+	pubsub := r.client.Subscribe(ctx, presenceChannel)
+	threads := make(chan string)
+
+	go func() {
+		defer close(threads)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case threads <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return threads
+}
+
+func expiresAt(ttl time.Duration) float64 {
+	return float64(time.Now().Add(ttl).UnixMilli())
+}
+
+func uniqueNames(sessions []string, sessionNames map[string]string) []string {
+	seen := make(map[string]struct{})
+	names := make([]string, 0)
+	for _, session := range sessions {
+		name, ok := sessionNames[session]
+		if !ok {
+			continue
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -1,13 +1,32 @@
-package database
-
-import "github.com/ds124wfegd/WB_L3/3/internal/entity"
-
-type Repository interface {
-	Create(comment entity.Comment) error
-	GetByID(id string) (*entity.Comment, bool)
-	GetChildren(parentID string, page, pageSize int, sortBy string) ([]entity.Comment, int)
-	Delete(id string) error
-	Search(query string, page, pageSize int) ([]entity.Comment, int)
-	BuildTree(parentID string, depth int) []entity.Comment
-	GetAllComments() ([]entity.Comment, error)
-}
+package database
+
+import (
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+type Repository interface {
+	Create(comment entity.Comment) error
+	GetByID(id string) (*entity.Comment, bool)
+	Update(id string, revision int64, text string) (*entity.Comment, error)
+	GetChildren(parentID string, page, pageSize int, sortBy string) ([]entity.Comment, int)
+	Delete(id string) error
+	Search(query string, page, pageSize int) ([]entity.Comment, int)
+	BuildTree(parentID string, depth int) []entity.Comment
+	GetAllComments() ([]entity.Comment, error)
+	GetSummary(thread, revision string) (*entity.ThreadSummary, bool)
+	SaveSummary(summary *entity.ThreadSummary, ttl time.Duration) error
+	GetRootIDs() ([]string, error)
+	ArchiveThread(archive *entity.ArchivedThread) error
+	GetArchivedThread(thread string) (*entity.ArchivedThread, bool)
+	SaveRetentionReport(report *entity.RetentionReport) error
+	GetRetentionReport() (*entity.RetentionReport, bool)
+	SaveDraft(draft *entity.Draft, ttl time.Duration) error
+	GetDraft(author, parentID string) (*entity.Draft, bool)
+	DeleteDraft(author, parentID string) (bool, error)
+	SetPinned(id string, pinned bool) (*entity.Comment, error)
+	GetThreadRoot(id string) (string, bool)
+	GetThreadSettings(thread string) (*entity.ThreadSettings, error)
+	SaveThreadSettings(settings *entity.ThreadSettings) error
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+// rolesKey - хеш пользователь -> роль; имена хранятся в нижнем регистре, как авторы в поиске
+const rolesKey = "roles"
+
+// RoleRepository хранит роли, назначенные пользователям через API
+type RoleRepository struct {
+	client redis.UniversalClient
+	ctx    context.Context
+}
+
+func NewRoleRepository(redisClient redis.UniversalClient) *RoleRepository {
+	return &RoleRepository{
+		client: redisClient,
+		ctx:    context.Background(),
+	}
+}
+
+// GetRole возвращает назначенную роль; false - роль пользователю не назначали
+func (r *RoleRepository) GetRole(user string) (entity.Role, bool, error) {
+	role, err := r.client.HGet(r.ctx, rolesKey, strings.ToLower(user)).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return entity.Role(role), true, nil
+}
+
+func (r *RoleRepository) SetRole(user string, role entity.Role) error {
+	return r.client.HSet(r.ctx, rolesKey, strings.ToLower(user), string(role)).Err()
+}
+
+// DeleteRole возвращает пользователю роль по умолчанию; false - роль не была назначена
+func (r *RoleRepository) DeleteRole(user string) (bool, error) {
+	deleted, err := r.client.HDel(r.ctx, rolesKey, strings.ToLower(user)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted > 0, nil
+}
+
+func (r *RoleRepository) GetAll() (map[string]entity.Role, error) {
+	values, err := r.client.HGetAll(r.ctx, rolesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]entity.Role, len(values))
+	for user, role := range values {
+		roles[user] = entity.Role(role)
+	}
+	return roles, nil
+}
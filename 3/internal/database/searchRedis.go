@@ -0,0 +1,312 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/analyzer"
+	"github.com/redis/go-redis/v9"
+)
+
+// Поисковый индекс хранится версиями: версия N лежит под ключами search:vN:text:<терм>
+// и search:vN:author:<автор>, версия 0 - индекс до анализатора, под search:text:<слово>.
+// Поиск читает версию из search:active_version, а пока идёт перестроение, изменения
+// комментариев пишутся и в строящуюся версию из search:building_version.
+const (
+	searchActiveVersionKey   = "search:active_version"
+	searchBuildingVersionKey = "search:building_version"
+	reindexJobKey            = "search:reindex:job"
+
+	// Сколько ключей старой версии удаляется за один UNLINK
+	searchDeleteBatch = 500
+)
+
+func searchPrefix(version int) string {
+	if version == 0 {
+		return "search"
+	}
+	return fmt.Sprintf("search:v%d", version)
+}
+
+func searchTextKey(version int, term string) string {
+	return fmt.Sprintf("%s:text:%s", searchPrefix(version), term)
+}
+
+func searchAuthorKey(version int, author string) string {
+	return fmt.Sprintf("%s:author:%s", searchPrefix(version), strings.ToLower(author))
+}
+
+// searchTerms возвращает термы текста в том виде, в каком их хранит индекс версии version
+func searchTerms(version int, text string) []string {
+	if version > 0 {
+		return analyzer.Tokens(text)
+	}
+
+	// Версия 0: слова как есть, короткие игнорируются
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		if len(word) > 2 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// searchVersions возвращает рабочую версию индекса и строящуюся, -1 если перестроения нет
+func (r *CommentRepository) searchVersions() (active, building int, err error) {
+	values, err := r.client.MGet(r.ctx, searchActiveVersionKey, searchBuildingVersionKey).Result()
+	if err != nil {
+		return 0, -1, err
+	}
+
+	active, building = 0, -1
+	if value, ok := values[0].(string); ok {
+		if active, err = strconv.Atoi(value); err != nil {
+			return 0, -1, fmt.Errorf("invalid %s: %w", searchActiveVersionKey, err)
+		}
+	}
+	if value, ok := values[1].(string); ok {
+		if building, err = strconv.Atoi(value); err != nil {
+			return 0, -1, fmt.Errorf("invalid %s: %w", searchBuildingVersionKey, err)
+		}
+	}
+	return active, building, nil
+}
+
+func (r *CommentRepository) addToSearchIndex(pipe redis.Pipeliner, version int, comment *entity.Comment) {
+	for _, term := range searchTerms(version, comment.Text) {
+		pipe.SAdd(r.ctx, searchTextKey(version, term), comment.ID)
+	}
+	pipe.SAdd(r.ctx, searchAuthorKey(version, comment.Author), comment.ID)
+}
+
+func (r *CommentRepository) removeFromSearchIndex(pipe redis.Pipeliner, version int, comment *entity.Comment) {
+	for _, term := range searchTerms(version, comment.Text) {
+		pipe.SRem(r.ctx, searchTextKey(version, term), comment.ID)
+	}
+	pipe.SRem(r.ctx, searchAuthorKey(version, comment.Author), comment.ID)
+}
+
+// indexCommentForSearch добавляет комментарий в рабочий индекс и, во время перестроения,
+// в строящийся: комментарий, созданный после того как перестроение его прочитало, не потеряется
+func (r *CommentRepository) indexCommentForSearch(comment *entity.Comment) error {
+	active, building, err := r.searchVersions()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		r.addToSearchIndex(pipe, active, comment)
+		if building >= 0 && building != active {
+			r.addToSearchIndex(pipe, building, comment)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *CommentRepository) removeCommentFromSearchIndex(comment *entity.Comment) error {
+	active, building, err := r.searchVersions()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		r.removeFromSearchIndex(pipe, active, comment)
+		if building >= 0 && building != active {
+			r.removeFromSearchIndex(pipe, building, comment)
+		}
+		return nil
+	})
+	return err
+}
+
+// indexSearch находит комментарии, содержащие все термы запроса, и комментарии автора,
+// имя которого совпадает с запросом
+func (r *CommentRepository) indexSearch(version int, query string) ([]entity.Comment, error) {
+	pipe := r.client.Pipeline()
+
+	var textCmd *redis.StringSliceCmd
+	if terms := analyzer.Tokens(query); len(terms) > 0 {
+		keys := make([]string, 0, len(terms))
+		for _, term := range terms {
+			keys = append(keys, searchTextKey(version, term))
+		}
+		textCmd = pipe.SInter(r.ctx, keys...)
+	}
+	authorCmd := pipe.SMembers(r.ctx, searchAuthorKey(version, strings.TrimSpace(query)))
+
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	ids := authorCmd.Val()
+	if textCmd != nil {
+		ids = append(ids, textCmd.Val()...)
+	}
+
+	seen := make(map[string]bool, len(ids))
+	var results []entity.Comment
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if comment, exists := r.GetByID(id); exists {
+			results = append(results, *comment)
+		}
+	}
+
+	return results, nil
+}
+
+// BeginReindex выбирает версию для нового индекса, очищает её от остатков прерванного
+// перестроения и включает запись изменений комментариев в неё.
+// Возвращает строящуюся версию и рабочую, по которой ищут до подмены.
+func (r *CommentRepository) BeginReindex() (version, previous int, err error) {
+	active, building, err := r.searchVersions()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	version = active + 1
+	if building > active {
+		// Прерванное перестроение: его версия ещё не читалась, её можно строить заново
+		version = building
+	}
+
+	// Очищаем до того, как включить двойную запись, иначе свежие изменения удалились бы вместе с остатками
+	if err := r.DeleteSearchVersion(version); err != nil {
+		return 0, 0, err
+	}
+	if err := r.client.Set(r.ctx, searchBuildingVersionKey, version, 0).Err(); err != nil {
+		return 0, 0, err
+	}
+
+	return version, active, nil
+}
+
+// CountComments возвращает число сохранённых комментариев
+func (r *CommentRepository) CountComments() (int64, error) {
+	return r.client.SCard(r.ctx, "comments:all").Result()
+}
+
+// ScanCommentIDs читает ID комментариев порциями; обход закончен, когда возвращённый курсор равен 0.
+// Один ID может вернуться дважды, если множество менялось во время обхода.
+func (r *CommentRepository) ScanCommentIDs(cursor uint64, count int64) ([]string, uint64, error) {
+	return r.client.SScan(r.ctx, "comments:all", cursor, "", count).Result()
+}
+
+// IndexComments добавляет комментарии в индекс версии version и возвращает, сколько
+// из них нашлось: комментарий могли удалить после того, как его ID прочитали
+func (r *CommentRepository) IndexComments(version int, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, fmt.Sprintf("comment:%s", id))
+	}
+	values, err := r.client.MGet(r.ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	_, err = r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for _, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			var comment entity.Comment
+			if err := json.Unmarshal([]byte(data), &comment); err != nil {
+				continue
+			}
+			r.addToSearchIndex(pipe, version, &comment)
+			indexed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return indexed, nil
+}
+
+// SwapSearchIndex делает версию version рабочей и выключает двойную запись одной транзакцией
+func (r *CommentRepository) SwapSearchIndex(version int) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(r.ctx, searchActiveVersionKey, version, 0)
+		pipe.Del(r.ctx, searchBuildingVersionKey)
+		return nil
+	})
+	return err
+}
+
+// AbortReindex выключает запись в версию version и удаляет всё, что успели в неё записать
+func (r *CommentRepository) AbortReindex(version int) error {
+	if err := r.client.Del(r.ctx, searchBuildingVersionKey).Err(); err != nil {
+		return err
+	}
+	return r.DeleteSearchVersion(version)
+}
+
+// DeleteSearchVersion удаляет ключи индекса версии version порциями через SCAN и UNLINK,
+// не блокируя Redis на больших индексах
+func (r *CommentRepository) DeleteSearchVersion(version int) error {
+	prefix := searchPrefix(version)
+	for _, pattern := range []string{prefix + ":text:*", prefix + ":author:*"} {
+		iter := r.client.Scan(r.ctx, 0, pattern, searchDeleteBatch).Iterator()
+
+		batch := make([]string, 0, searchDeleteBatch)
+		for iter.Next(r.ctx) {
+			batch = append(batch, iter.Val())
+			if len(batch) == searchDeleteBatch {
+				if err := r.client.Unlink(r.ctx, batch...).Err(); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := r.client.Unlink(r.ctx, batch...).Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *CommentRepository) SaveReindexJob(job *entity.ReindexJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(r.ctx, reindexJobKey, data, 0).Err()
+}
+
+func (r *CommentRepository) GetReindexJob() (*entity.ReindexJob, bool) {
+	data, err := r.client.Get(r.ctx, reindexJobKey).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var job entity.ReindexJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, false
+	}
+
+	return &job, true
+}
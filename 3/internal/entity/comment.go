@@ -1,44 +1,98 @@
-package entity
-
-import (
-	"encoding/json"
-	"time"
-)
-
-type Comment struct {
-	ID        string    `json:"id"`
-	ParentID  string    `json:"parent_id,omitempty"`
-	Author    string    `json:"author"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Children  []Comment `json:"children,omitempty"`
-}
-
-type CreateCommentRequest struct {
-	ParentID string `json:"parent_id"`
-	Author   string `json:"author"`
-	Text     string `json:"text"`
-}
-
-type CommentsResponse struct {
-	Comments []Comment `json:"comments"`
-	Total    int       `json:"total"`
-	Page     int       `json:"page"`
-	PageSize int       `json:"page_size"`
-}
-
-type SearchRequest struct {
-	Query string `json:"query"`
-	Page  int    `json:"page"`
-	Size  int    `json:"size"`
-}
-
-// Для сериализации в Redis
-func (c *Comment) MarshalBinary() ([]byte, error) {
-	return json.Marshal(c)
-}
-
-func (c *Comment) UnmarshalBinary(data []byte) error {
-	return json.Unmarshal(data, c)
-}
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrRevisionConflict - комментарий изменили после того, как клиент прочитал его ревизию
+var ErrRevisionConflict = errors.New("comment was modified by someone else")
+
+// ErrThreadLocked - в закрытую ветку отвечают только модераторы
+var ErrThreadLocked = errors.New("thread is locked")
+
+type Comment struct {
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Revision  int64     `json:"revision"`         // растёт на единицу при каждом изменении
+	Pinned    bool      `json:"pinned,omitempty"` // закреплённые показываются первыми среди ответов
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Children  []Comment `json:"children,omitempty"`
+}
+
+type CreateCommentRequest struct {
+	ParentID string `json:"parent_id"`
+	Author   string `json:"author"`
+	Text     string `json:"text"`
+}
+
+// UpdateCommentRequest - правка текста; Revision - ревизия, которую видел клиент,
+// её можно передать и в заголовке If-Match
+type UpdateCommentRequest struct {
+	Text     string `json:"text"`
+	Revision int64  `json:"revision"`
+}
+
+type PinCommentRequest struct {
+	Pinned bool `json:"pinned"`
+}
+
+// ThreadSettings - настройки ветки, их меняют модераторы
+type ThreadSettings struct {
+	Thread    string    `json:"thread"`
+	Locked    bool      `json:"locked"` // новые ответы могут писать только модераторы
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+type ThreadSettingsRequest struct {
+	Locked bool `json:"locked"`
+}
+
+// PurgeRequest - удалить все комментарии автора вместе с ответами на них
+type PurgeRequest struct {
+	Author string `json:"author"`
+}
+
+type PurgeResult struct {
+	Author   string `json:"author"`
+	Comments int    `json:"comments"` // удалено комментариев автора, без учёта ответов
+}
+
+type CommentsResponse struct {
+	Comments []Comment `json:"comments"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+}
+
+// ThreadSummary - сводка обсуждения ветки, Revision меняется при любом изменении ветки
+type ThreadSummary struct {
+	Thread       string    `json:"thread"`
+	Revision     string    `json:"revision"`
+	Summarizer   string    `json:"summarizer"`
+	Summary      string    `json:"summary"`
+	Comments     int       `json:"comments"`
+	Participants []string  `json:"participants"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	Cached       bool      `json:"cached"`
+}
+
+type SearchRequest struct {
+	Query string `json:"query"`
+	Page  int    `json:"page"`
+	Size  int    `json:"size"`
+}
+
+// Для сериализации в Redis
+func (c *Comment) MarshalBinary() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *Comment) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Draft - неотправленный ответ пользователя. Черновик один на автора и родителя:
+// пустой ParentID - черновик нового корневого комментария.
+type Draft struct {
+	Author    string    `json:"author"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Text      string    `json:"text"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type SaveDraftRequest struct {
+	Author   string `json:"author"`
+	ParentID string `json:"parent_id"`
+	Text     string `json:"text"`
+}
+
+func (d *Draft) MarshalBinary() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+func (d *Draft) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, d)
+}
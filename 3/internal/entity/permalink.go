@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// maxSlugLength - длина текстовой части ссылки в символах; слово на границе не обрезается
+const maxSlugLength = 60
+
+// defaultSlug - слаг комментария без букв и цифр
+const defaultSlug = "comment"
+
+// CommentPermalink - постоянная ссылка на комментарий.
+// Комментарий ищется только по id, слаг нужен для читаемости ссылки и её можно менять.
+type CommentPermalink struct {
+	CommentID string `json:"comment_id"`
+	Thread    string `json:"thread"` // корень ветки, его ответы показываются вокруг комментария
+	Slug      string `json:"slug"`
+	Permalink string `json:"permalink"`
+	DeepLink  string `json:"deep_link"`           // страница ветки с выделенным комментарием
+	ShortURL  string `json:"short_url,omitempty"` // короткая ссылка из сервиса сокращения на Permalink
+}
+
+// CommentSlug строит слаг из первых слов текста: буквы и цифры в нижнем регистре, остальное - дефисы
+func CommentSlug(text string) string {
+	var b strings.Builder
+	length, lastWord := 0, 0
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		word = strings.ToLower(word)
+		wordLength := len([]rune(word))
+		if length > 0 && length+1+wordLength > maxSlugLength {
+			break
+		}
+		if length > 0 {
+			b.WriteByte('-')
+			length++
+		}
+		b.WriteString(word)
+		length += wordLength
+		lastWord = wordLength
+	}
+
+	// Первое слово длиннее лимита режется по символам
+	if lastWord > maxSlugLength {
+		return string([]rune(b.String())[:maxSlugLength])
+	}
+	if b.Len() == 0 {
+		return defaultSlug
+	}
+	return b.String()
+}
+
+// PermalinkPath - путь постоянной ссылки, /c/<id>/<слаг>
+func PermalinkPath(id, slug string) string {
+	return "/c/" + url.PathEscape(id) + "/" + url.PathEscape(slug)
+}
+
+// DeepLinkPath - путь страницы ветки, которая открывается с выделенным комментарием
+func DeepLinkPath(thread, id string) string {
+	query := url.Values{}
+	query.Set("thread", thread)
+	query.Set("comment", id)
+	return "/?" + query.Encode() + "#comment-" + url.PathEscape(id)
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCommentSlug(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Hello, World!", want: "hello-world"},
+		{text: "  Отличная статья — спасибо автору  ", want: "отличная-статья-спасибо-автору"},
+		{text: "Go 1.22 released", want: "go-1-22-released"},
+		{text: "!!! ???", want: defaultSlug},
+		{text: "", want: defaultSlug},
+	}
+
+	for _, tt := range tests {
+		if got := CommentSlug(tt.text); got != tt.want {
+			t.Errorf("CommentSlug(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCommentSlugLength(t *testing.T) {
+	slug := CommentSlug(strings.Repeat("слово ", 30))
+	if utf8.RuneCountInString(slug) > maxSlugLength {
+		t.Fatalf("slug has %d characters, limit %d", utf8.RuneCountInString(slug), maxSlugLength)
+	}
+	if strings.HasSuffix(slug, "-") || strings.HasSuffix(slug, "сл") {
+		t.Errorf("slug %q cut in the middle of a word", slug)
+	}
+
+	long := CommentSlug(strings.Repeat("я", 100))
+	if utf8.RuneCountInString(long) != maxSlugLength {
+		t.Errorf("single long word gives %d characters, want %d", utf8.RuneCountInString(long), maxSlugLength)
+	}
+}
+
+func TestDeepLinkPath(t *testing.T) {
+	got := DeepLinkPath("root-1", "reply-2")
+	want := "/?comment=reply-2&thread=root-1#comment-reply-2"
+	if got != want {
+		t.Errorf("DeepLinkPath = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package entity
+
+import "errors"
+
+var ErrInvalidVisibility = errors.New("visibility must be visible, anonymous or invisible")
+
+// PresenceVisibility - что остальные зрители ветки знают о пользователе
+type PresenceVisibility string
+
+const (
+	PresenceVisible   PresenceVisibility = "visible"   // учитывается и показывается по имени
+	PresenceAnonymous PresenceVisibility = "anonymous" // учитывается без имени
+	PresenceInvisible PresenceVisibility = "invisible" // не учитывается вовсе, набор текста не виден
+)
+
+func (v PresenceVisibility) Validate() error {
+	switch v {
+	case PresenceVisible, PresenceAnonymous, PresenceInvisible:
+		return nil
+	}
+	return ErrInvalidVisibility
+}
+
+// PresenceViewer - пользователь, открывший ветку
+type PresenceViewer struct {
+	Name       string
+	Visibility PresenceVisibility
+}
+
+// DisplayName - имя, которое можно показать другим; пустое, если пользователь его скрыл
+func (v PresenceViewer) DisplayName() string {
+	if v.Visibility != PresenceVisible {
+		return ""
+	}
+	return v.Name
+}
+
+// PresenceSnapshot - кто сейчас смотрит ветку и кто пишет ответ.
+// Viewers и TypingNames содержат только имена пользователей с видимостью visible.
+type PresenceSnapshot struct {
+	Thread      string   `json:"thread"`
+	Viewing     int      `json:"viewing"`
+	Viewers     []string `json:"viewers"`
+	Typing      int      `json:"typing"`
+	TypingNames []string `json:"typing_names"`
+}
+
+// Сообщения WebSocket присутствия
+const (
+	PresenceEventSnapshot = "presence"    // сервер -> клиент: новое состояние ветки
+	PresenceEventTyping   = "typing"      // клиент -> сервер: пользователь набирает текст
+	PresenceEventIdle     = "stop_typing" // клиент -> сервер: пользователь перестал набирать
+)
+
+type PresenceEvent struct {
+	Type string `json:"type"`
+	*PresenceSnapshot
+}
+
+type PresenceMessage struct {
+	Type string `json:"type"`
+}
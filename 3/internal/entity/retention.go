@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+type RetentionAction string
+
+const (
+	// RetentionDelete удаляет неактивную ветку целиком
+	RetentionDelete RetentionAction = "delete"
+	// RetentionArchive переносит ветку в сжатый архив и удаляет её из рабочих ключей
+	RetentionArchive RetentionAction = "archive"
+)
+
+var ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+
+// DefaultArchiveMonths - срок хранения архива ветки, если он не задан в политике
+const DefaultArchiveMonths = 24
+
+// RetentionPolicy - правило очистки веток, в которых не было активности InactiveMonths месяцев
+type RetentionPolicy struct {
+	Action         RetentionAction `json:"action"`
+	InactiveMonths int             `json:"inactive_months"`
+	DryRun         bool            `json:"dry_run"`
+	MaxThreads     int             `json:"max_threads"` // ограничение за один запуск, 0 - без ограничений
+	// ArchiveMonths - сколько хранится архив ветки, 0 - DefaultArchiveMonths
+	ArchiveMonths int `json:"archive_months"`
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.Action != RetentionDelete && p.Action != RetentionArchive {
+		return ErrInvalidRetentionPolicy
+	}
+	if p.InactiveMonths <= 0 || p.MaxThreads < 0 || p.ArchiveMonths < 0 {
+		return ErrInvalidRetentionPolicy
+	}
+	return nil
+}
+
+// Cutoff - ветки без активности после этого момента подлежат очистке
+func (p RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, -p.InactiveMonths, 0)
+}
+
+// ArchiveExpiry - момент, после которого архив ветки, созданный в archivedAt, удаляется
+func (p RetentionPolicy) ArchiveExpiry(archivedAt time.Time) time.Time {
+	months := p.ArchiveMonths
+	if months == 0 {
+		months = DefaultArchiveMonths
+	}
+	return archivedAt.AddDate(0, months, 0)
+}
+
+// RetentionCandidate - ветка, попавшая под политику
+type RetentionCandidate struct {
+	Thread       string    `json:"thread"`
+	Author       string    `json:"author"`
+	Comments     int       `json:"comments"`
+	Bytes        int       `json:"bytes"` // примерный объём комментариев в Redis
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// RetentionReport - результат запуска политики; при DryRun ничего не изменяется
+type RetentionReport struct {
+	Policy         RetentionPolicy      `json:"policy"`
+	Cutoff         time.Time            `json:"cutoff"`
+	StartedAt      time.Time            `json:"started_at"`
+	FinishedAt     time.Time            `json:"finished_at"`
+	ThreadsScanned int                  `json:"threads_scanned"`
+	ThreadsMatched int                  `json:"threads_matched"`
+	ThreadsApplied int                  `json:"threads_applied"`
+	Comments       int                  `json:"comments"`
+	Bytes          int                  `json:"bytes"`
+	Candidates     []RetentionCandidate `json:"candidates"`
+	Errors         []string             `json:"errors,omitempty"`
+}
+
+// ArchivedThread - ветка в архиве вместе со всеми ответами
+type ArchivedThread struct {
+	Thread     string    `json:"thread"`
+	ArchivedAt time.Time `json:"archived_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // после этого момента архив удаляется из Redis
+	Comments   []Comment `json:"comments"`
+}
+
+// RetentionRunRequest - запуск политики вручную; без явного dry_run=false ничего не удаляется
+type RetentionRunRequest struct {
+	Action         RetentionAction `json:"action"`
+	InactiveMonths int             `json:"inactive_months"`
+	DryRun         *bool           `json:"dry_run"`
+	MaxThreads     int             `json:"max_threads"`
+	ArchiveMonths  int             `json:"archive_months"`
+}
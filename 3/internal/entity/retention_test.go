@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionArchiveExpiry(t *testing.T) {
+	archivedAt := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		months int
+		want   time.Time
+	}{
+		{0, archivedAt.AddDate(0, DefaultArchiveMonths, 0)},
+		{6, time.Date(2026, 7, 31, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		policy := RetentionPolicy{Action: RetentionArchive, InactiveMonths: 12, ArchiveMonths: tt.months}
+		if got := policy.ArchiveExpiry(archivedAt); !got.Equal(tt.want) {
+			t.Errorf("ArchiveMonths=%d: ArchiveExpiry = %v, want %v", tt.months, got, tt.want)
+		}
+	}
+
+	negative := RetentionPolicy{Action: RetentionArchive, InactiveMonths: 12, ArchiveMonths: -1}
+	if err := negative.Validate(); err != ErrInvalidRetentionPolicy {
+		t.Errorf("Validate with negative ArchiveMonths = %v, want %v", err, ErrInvalidRetentionPolicy)
+	}
+}
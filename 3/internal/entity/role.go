@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrForbidden   = errors.New("action is not allowed for your role")
+	ErrInvalidRole = errors.New("role must be reader, author, moderator or admin")
+)
+
+type Role string
+
+const (
+	RoleReader    Role = "reader"    // только читает
+	RoleAuthor    Role = "author"    // пишет и правит свои комментарии
+	RoleModerator Role = "moderator" // правит и удаляет чужие, закрепляет, меняет настройки веток
+	RoleAdmin     Role = "admin"     // чистит комментарии целиком и раздаёт роли
+)
+
+// Roles - роли по возрастанию прав
+var Roles = []Role{RoleReader, RoleAuthor, RoleModerator, RoleAdmin}
+
+type Permission string
+
+const (
+	PermRead           Permission = "read"
+	PermPost           Permission = "post"
+	PermEditOwn        Permission = "edit_own"
+	PermEditAny        Permission = "edit_any"
+	PermDeleteOwn      Permission = "delete_own"
+	PermDeleteAny      Permission = "delete_any"
+	PermPin            Permission = "pin"
+	PermThreadSettings Permission = "thread_settings" // закрывать ветку и писать в закрытую
+	PermPurge          Permission = "purge"           // удалять все комментарии автора и запускать очистку веток
+	PermManageRoles    Permission = "manage_roles"
+)
+
+// PermissionMatrix - права каждой роли; каждая следующая роль включает права предыдущей
+var PermissionMatrix = map[Role][]Permission{
+	RoleReader:    {PermRead},
+	RoleAuthor:    {PermRead, PermPost, PermEditOwn, PermDeleteOwn},
+	RoleModerator: {PermRead, PermPost, PermEditOwn, PermDeleteOwn, PermEditAny, PermDeleteAny, PermPin, PermThreadSettings},
+	RoleAdmin: {PermRead, PermPost, PermEditOwn, PermDeleteOwn, PermEditAny, PermDeleteAny, PermPin, PermThreadSettings,
+		PermPurge, PermManageRoles},
+}
+
+func (r Role) Validate() error {
+	if _, ok := PermissionMatrix[r]; !ok {
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, granted := range PermissionMatrix[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor - пользователь, от имени которого выполняется запрос; пустое имя - аноним
+type Actor struct {
+	Name string `json:"name,omitempty"`
+	Role Role   `json:"role"`
+}
+
+func (a Actor) Can(permission Permission) bool {
+	return a.Role.Can(permission)
+}
+
+// Owns проверяет, что комментарий написал сам пользователь; имена сравниваются без учёта регистра
+func (a Actor) Owns(comment *Comment) bool {
+	return a.Name != "" && strings.EqualFold(a.Name, comment.Author)
+}
+
+// CanModify - свой комментарий можно менять с правом own или any, чужой - только с правом any
+func (a Actor) CanModify(comment *Comment, own, any Permission) bool {
+	if a.Owns(comment) && a.Can(own) {
+		return true
+	}
+	return a.Can(any)
+}
+
+// RoleAssignment - роль, назначенная пользователю; Configured - администратор из конфигурации,
+// его роль через API не меняется
+type RoleAssignment struct {
+	User       string `json:"user"`
+	Role       Role   `json:"role"`
+	Configured bool   `json:"configured,omitempty"`
+}
+
+type RolesResponse struct {
+	DefaultRole Role                  `json:"default_role"`
+	Matrix      map[Role][]Permission `json:"matrix"`
+	Assignments []RoleAssignment      `json:"assignments"`
+}
+
+type SetRoleRequest struct {
+	Role Role `json:"role"`
+}
@@ -0,0 +1,90 @@
+package entity
+
+import "testing"
+
+func TestPermissionMatrix(t *testing.T) {
+	tests := []struct {
+		role    Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{
+			role:    RoleReader,
+			allowed: []Permission{PermRead},
+			denied:  []Permission{PermPost, PermEditOwn, PermEditAny, PermDeleteAny, PermPin, PermThreadSettings, PermPurge, PermManageRoles},
+		},
+		{
+			role:    RoleAuthor,
+			allowed: []Permission{PermRead, PermPost, PermEditOwn, PermDeleteOwn},
+			denied:  []Permission{PermEditAny, PermDeleteAny, PermPin, PermThreadSettings, PermPurge, PermManageRoles},
+		},
+		{
+			role:    RoleModerator,
+			allowed: []Permission{PermPost, PermEditAny, PermDeleteAny, PermPin, PermThreadSettings},
+			denied:  []Permission{PermPurge, PermManageRoles},
+		},
+		{
+			role:    RoleAdmin,
+			allowed: []Permission{PermPost, PermEditAny, PermDeleteAny, PermPin, PermThreadSettings, PermPurge, PermManageRoles},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, permission := range tt.allowed {
+			if !tt.role.Can(permission) {
+				t.Errorf("%s should have %s", tt.role, permission)
+			}
+		}
+		for _, permission := range tt.denied {
+			if tt.role.Can(permission) {
+				t.Errorf("%s should not have %s", tt.role, permission)
+			}
+		}
+	}
+}
+
+// Каждая роль включает права предыдущей, иначе повышение роли отнимало бы права
+func TestRolesAreCumulative(t *testing.T) {
+	for i := 1; i < len(Roles); i++ {
+		for _, permission := range PermissionMatrix[Roles[i-1]] {
+			if !Roles[i].Can(permission) {
+				t.Errorf("%s lacks %s granted to %s", Roles[i], permission, Roles[i-1])
+			}
+		}
+	}
+}
+
+func TestRoleValidate(t *testing.T) {
+	for _, role := range Roles {
+		if err := role.Validate(); err != nil {
+			t.Errorf("%s: unexpected error %v", role, err)
+		}
+	}
+	for _, role := range []Role{"", "owner", "Admin"} {
+		if err := role.Validate(); err != ErrInvalidRole {
+			t.Errorf("%q: expected ErrInvalidRole, got %v", role, err)
+		}
+	}
+}
+
+func TestActorCanModify(t *testing.T) {
+	comment := &Comment{ID: "1", Author: "Alice"}
+
+	tests := []struct {
+		name  string
+		actor Actor
+		want  bool
+	}{
+		{"author edits own comment", Actor{Name: "alice", Role: RoleAuthor}, true},
+		{"author edits someone else's comment", Actor{Name: "bob", Role: RoleAuthor}, false},
+		{"reader edits own comment", Actor{Name: "Alice", Role: RoleReader}, false},
+		{"moderator edits someone else's comment", Actor{Name: "bob", Role: RoleModerator}, true},
+		{"anonymous reader", Actor{Role: RoleReader}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.actor.CanModify(comment, PermEditOwn, PermEditAny); got != tt.want {
+			t.Errorf("%s: CanModify = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+type ReindexStatus string
+
+const (
+	ReindexRunning   ReindexStatus = "running"
+	ReindexCompleted ReindexStatus = "completed"
+	ReindexFailed    ReindexStatus = "failed"
+	// ReindexInterrupted - процесс остановился во время перестроения; поиск остался на прежнем индексе
+	ReindexInterrupted ReindexStatus = "interrupted"
+)
+
+// ReindexJob - перестроение поискового индекса из сохранённых комментариев. Новый индекс
+// строится под своей версией ключей рядом с рабочим и подменяет его только целиком,
+// поэтому поиск во время перестроения продолжает работать по старому индексу.
+type ReindexJob struct {
+	ID              string        `json:"id"`
+	Status          ReindexStatus `json:"status"`
+	Version         int           `json:"version"`          // версия строящегося индекса
+	PreviousVersion int           `json:"previous_version"` // версия, по которой ищут до подмены
+	ChunkSize       int           `json:"chunk_size"`
+	Total           int64         `json:"total"`     // комментариев на момент запуска
+	Processed       int           `json:"processed"` // прочитано из хранилища
+	Indexed         int           `json:"indexed"`   // проиндексировано; меньше Processed, если комментарий удалили во время работы
+	Progress        float64       `json:"progress"`  // доля от 0 до 1
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
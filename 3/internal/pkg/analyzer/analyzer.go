@@ -0,0 +1,95 @@
+// Разбор текста комментариев на термы для поискового индекса: слова приводятся
+// к нижнему регистру, стоп-слова отбрасываются, окончания русских и английских слов обрезаются
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minTermLength - более короткие слова не индексируются
+const minTermLength = 3
+
+// minStemLength - окончание не обрезается, если от слова останется меньше
+const minStemLength = 3
+
+var stopWords = toSet(
+	// английские
+	"the", "and", "for", "are", "but", "not", "you", "all", "any", "can", "had", "her", "was", "one",
+	"our", "out", "has", "have", "this", "that", "with", "from", "they", "will", "would", "there",
+	"their", "what", "about", "which", "when", "were", "been", "into", "than", "then", "them",
+	"these", "those", "your", "its", "also", "just",
+	// русские
+	"это", "как", "так", "что", "все", "она", "они", "оно", "его", "для", "вот", "уже", "или",
+	"был", "была", "были", "было", "быть", "нет", "еще", "ещё", "если", "когда", "даже", "ему",
+	"меня", "тебя", "только", "тоже", "чтобы", "этот", "эта", "эти", "там", "тут", "где", "при",
+	"без", "над", "под", "про", "через", "мне", "нам", "вам", "вас", "нас", "них", "ним", "кто",
+	"чем", "себя", "потом", "очень", "можно", "надо",
+)
+
+// Окончания от длинных к коротким: обрезается первое подошедшее
+var russianEndings = []string{
+	"иями",
+	"ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими", "ией", "иев", "ием", "иях", "иям",
+	"ии", "ий", "ия", "ию", "ие", "ая", "яя", "ое", "ее", "ые", "ой", "ей", "ый", "ом", "ем",
+	"ам", "ям", "ах", "ях", "ов", "ев", "ью",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь",
+}
+
+var englishEndings = []string{"ing", "ies", "ed", "es", "ly", "s"}
+
+// Tokens возвращает термы текста без повторов в порядке первого появления.
+// Запрос разбирается той же функцией, что и комментарии, поэтому "комментарии"
+// находит "комментарием", а "comments" - "commented"
+func Tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "ё", "е")
+		if utf8.RuneCountInString(word) < minTermLength || stopWords[word] {
+			continue
+		}
+
+		term := Stem(word)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Stem обрезает окончание слова в нижнем регистре
+func Stem(word string) string {
+	endings := englishEndings
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			endings = russianEndings
+			break
+		}
+	}
+
+	for _, ending := range endings {
+		if !strings.HasSuffix(word, ending) {
+			continue
+		}
+		stem := strings.TrimSuffix(word, ending)
+		if utf8.RuneCountInString(stem) >= minStemLength {
+			return stem
+		}
+	}
+	return word
+}
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Комментарии к комментарию, комментариев!", []string{"комментар"}},
+		{"This comment was commented", []string{"comment"}},
+		{"Ещё одна ёлка", []string{"одн", "елк"}},
+		{"и в на к", []string{}},
+		{"go1.24 released", []string{"go1", "releas"}},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestStemKeepsShortWords - от слова не остаётся огрызок короче трёх букв
+func TestStemKeepsShortWords(t *testing.T) {
+	for _, word := range []string{"уже", "bus", "дома"} {
+		if got := Stem(word); len([]rune(got)) < minStemLength {
+			t.Errorf("Stem(%q) = %q", word, got)
+		}
+	}
+}
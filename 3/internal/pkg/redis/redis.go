@@ -1,27 +1,62 @@
-package redis
-
-import (
-	"fmt"
-	"log"
-
-	"github.com/ds124wfegd/WB_L3/3/config"
-	"github.com/redis/go-redis/v9"
-)
-
-func NewRedisClient(cfg *config.RedisConfig) *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		MaxRetries:   cfg.MaxRetries,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdleConns,
-		DialTimeout:  cfg.DialTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		PoolTimeout:  cfg.PoolTimeout,
-	})
-
-	log.Println("Successfully connected to Redis")
-	return client
-}
+package redis
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ds124wfegd/WB_L3/3/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient создаёт клиента для режима из redis.mode. В режиме sentinel клиент
+// узнаёт адрес мастера у сентинелов и переподключается к новому мастеру после failover;
+// репозитории работают через redis.UniversalClient и от режима не зависят.
+// Остальные режимы, в том числе cluster, отклоняет config.ParseConfig.
+func NewRedisClient(cfg *config.RedisConfig) redis.UniversalClient {
+	var client redis.UniversalClient
+
+	switch cfg.Mode {
+	case config.RedisModeSentinel:
+		opts := &redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			RouteByLatency:   cfg.RouteByLatency,
+			RouteRandomly:    cfg.RouteRandomly,
+			MaxRetries:       cfg.MaxRetries,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+			MaxIdleConns:     cfg.MaxIdleConns,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+			PoolTimeout:      cfg.PoolTimeout,
+		}
+		// Читать с реплик умеет только клиент поверх всех узлов группы
+		if cfg.RouteByLatency || cfg.RouteRandomly {
+			client = redis.NewFailoverClusterClient(opts)
+		} else {
+			client = redis.NewFailoverClient(opts)
+		}
+	case "", config.RedisModeSingle:
+		client = redis.NewClient(&redis.Options{
+			Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			MaxRetries:   cfg.MaxRetries,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			PoolTimeout:  cfg.PoolTimeout,
+		})
+	default:
+		log.Fatalf("Unsupported redis mode %q", cfg.Mode)
+	}
+
+	log.Println("Successfully connected to Redis")
+	return client
+}
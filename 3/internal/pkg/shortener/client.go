@@ -0,0 +1,102 @@
+package shortener
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Preview - карточка ссылки, которую сервис сокращения показывает в мессенджерах
+type Preview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Shortener выдаёт короткую ссылку на URL.
+// Повторный запрос того же URL возвращает прежнюю ссылку: сервис сокращения сравнивает канонический вид.
+type Shortener interface {
+	Shorten(ctx context.Context, longURL string, preview *Preview) (string, error)
+}
+
+// Client ходит в сервис сокращения ссылок (сервис 2) по HTTP
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+type shortenRequest struct {
+	URL     string   `json:"url"`
+	Preview *Preview `json:"preview,omitempty"`
+}
+
+type shortenResponse struct {
+	ShortURL     string `json:"short_url"`
+	ShortURLFull string `json:"short_url_full"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Shorten вызывает POST /shorten; 201 - новая ссылка, 200 - уже существующая
+func (c *Client) Shorten(ctx context.Context, longURL string, preview *Preview) (string, error) {
+	body, err := json.Marshal(shortenRequest{URL: longURL, Preview: preview})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/shorten", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("shortener request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("shortener response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return "", fmt.Errorf("shortener: %s: %s", resp.Status, e.Error)
+		}
+		return "", fmt.Errorf("shortener: %s", resp.Status)
+	}
+
+	var result shortenResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("shortener response: %w", err)
+	}
+	if result.ShortURLFull != "" {
+		return result.ShortURLFull, nil
+	}
+	if result.ShortURL == "" {
+		return "", fmt.Errorf("shortener: empty short url in response")
+	}
+	return c.baseURL + "/s/" + url.PathEscape(result.ShortURL), nil
+}
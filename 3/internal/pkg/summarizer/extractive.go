@@ -0,0 +1,132 @@
+package summarizer
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+const defaultMaxSentences = 3
+
+var stopWords = map[string]struct{}{
+	"the": {}, "and": {}, "for": {}, "are": {}, "but": {}, "not": {}, "you": {}, "this": {}, "that": {},
+	"with": {}, "have": {}, "was": {}, "were": {}, "from": {}, "they": {}, "will": {}, "what": {}, "there": {},
+	"это": {}, "как": {}, "так": {}, "что": {}, "для": {}, "все": {}, "она": {}, "они": {}, "его": {},
+	"еще": {}, "уже": {}, "или": {}, "если": {}, "только": {}, "когда": {}, "даже": {}, "тоже": {}, "чтобы": {},
+}
+
+// Extractive выбирает из обсуждения самые характерные предложения: предложение
+// оценивается суммой частот его значимых слов по всей ветке, делённой на длину.
+// Выбранные предложения возвращаются в порядке их появления в ветке.
+type Extractive struct {
+	maxSentences int
+}
+
+func NewExtractive(maxSentences int) *Extractive {
+	if maxSentences <= 0 {
+		maxSentences = defaultMaxSentences
+	}
+	return &Extractive{maxSentences: maxSentences}
+}
+
+func (e *Extractive) Name() string {
+	return "extractive"
+}
+
+type sentence struct {
+	text  string
+	words []string
+	order int
+	score float64
+}
+
+func (e *Extractive) Summarize(comments []entity.Comment) (string, error) {
+	var sentences []sentence
+	frequency := make(map[string]int)
+
+	for _, comment := range comments {
+		for _, text := range splitSentences(comment.Text) {
+			words := significantWords(text)
+			if len(words) == 0 {
+				continue
+			}
+			for _, word := range words {
+				frequency[word]++
+			}
+			sentences = append(sentences, sentence{text: text, words: words, order: len(sentences)})
+		}
+	}
+
+	if len(sentences) == 0 {
+		return "", nil
+	}
+
+	for i := range sentences {
+		total := 0
+		for _, word := range sentences[i].words {
+			total += frequency[word]
+		}
+		sentences[i].score = float64(total) / float64(len(sentences[i].words))
+	}
+
+	sort.SliceStable(sentences, func(i, j int) bool {
+		return sentences[i].score > sentences[j].score
+	})
+	if len(sentences) > e.maxSentences {
+		sentences = sentences[:e.maxSentences]
+	}
+	sort.Slice(sentences, func(i, j int) bool {
+		return sentences[i].order < sentences[j].order
+	})
+
+	parts := make([]string, 0, len(sentences))
+	for _, s := range sentences {
+		parts = append(parts, s.text)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			sentences = append(sentences, s)
+		}
+		current.Reset()
+	}
+
+	for _, r := range text {
+		current.WriteRune(r)
+		switch r {
+		case '.', '!', '?', '\n':
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// significantWords, как и поисковый индекс, пропускает слова короче трёх символов
+func significantWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := fields[:0]
+	for _, word := range fields {
+		if len([]rune(word)) <= 2 {
+			continue
+		}
+		if _, stop := stopWords[word]; stop {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
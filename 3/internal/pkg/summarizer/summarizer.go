@@ -0,0 +1,12 @@
+package summarizer
+
+import (
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+// Summarizer строит краткое содержание обсуждения.
+// Name входит в ключ кэша, поэтому смена реализации не отдаёт устаревшие сводки.
+type Summarizer interface {
+	Name() string
+	Summarize(comments []entity.Comment) (string, error)
+}
@@ -0,0 +1,82 @@
+// Package usertoken выпускает и проверяет токены пользователя: имя и срок действия,
+// подписанные HMAC-SHA256 общим со шлюзом ключом. Формат: <имя base64url>.<срок unix>.<подпись base64url>
+package usertoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("invalid user token")
+	ErrExpired = errors.New("user token expired")
+)
+
+// Signer подписывает и проверяет токены; без ключа любой токен недействителен
+type Signer struct {
+	secret []byte
+}
+
+func New(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Enabled сообщает, задан ли ключ подписи
+func (s *Signer) Enabled() bool {
+	return len(s.secret) > 0
+}
+
+// Sign выпускает токен пользователя user, действующий до expires
+func (s *Signer) Sign(user string, expires time.Time) (string, error) {
+	if !s.Enabled() {
+		return "", errors.New("user token secret is not configured")
+	}
+	if user == "" {
+		return "", errors.New("user is required")
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(user)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// Verify проверяет подпись и срок токена и возвращает имя пользователя
+func (s *Signer) Verify(token string, now time.Time) (string, error) {
+	if !s.Enabled() {
+		return "", ErrInvalid
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, s.mac(parts[0]+"."+parts[1])) {
+		return "", ErrInvalid
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return "", ErrExpired
+	}
+
+	user, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(user) == 0 {
+		return "", ErrInvalid
+	}
+	return string(user), nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,52 @@
+package usertoken
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer := New("secret")
+	now := time.Now()
+
+	token, err := signer.Sign("Алиса.admin", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := signer.Verify(token, now); err != nil || user != "Алиса.admin" {
+		t.Errorf("Verify = %q, %v", user, err)
+	}
+	if _, err := signer.Verify(token, now.Add(2*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired token: %v, want %v", err, ErrExpired)
+	}
+	if _, err := New("other").Verify(token, now); !errors.Is(err, ErrInvalid) {
+		t.Errorf("token of another key: %v, want %v", err, ErrInvalid)
+	}
+}
+
+// TestVerifyRejectsTampering - нельзя поменять имя или продлить срок, сохранив подпись
+func TestVerifyRejectsTampering(t *testing.T) {
+	signer := New("secret")
+	now := time.Now()
+
+	alice, _ := signer.Sign("alice", now.Add(time.Hour))
+	admin, _ := signer.Sign("admin", now.Add(time.Hour))
+	a, b := strings.Split(alice, "."), strings.Split(admin, ".")
+
+	for _, token := range []string{
+		b[0] + "." + a[1] + "." + a[2],
+		a[0] + ".9999999999." + a[2],
+		a[0] + "." + a[1],
+		"",
+	} {
+		if _, err := signer.Verify(token, now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Verify(%q) = %v, want %v", token, err, ErrInvalid)
+		}
+	}
+
+	if _, err := New("").Sign("alice", now.Add(time.Hour)); err == nil {
+		t.Error("Sign without a secret should fail")
+	}
+}
@@ -1,89 +1,223 @@
-package service
-
-import (
-	"errors"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/3/internal/entity"
-
-	"github.com/google/uuid"
-)
-
-func (s *CommentService) CreateComment(req entity.CreateCommentRequest) (*entity.Comment, error) {
-	if req.Author == "" || req.Text == "" {
-		return nil, errors.New("author and text are required")
-	}
-
-	// Если указан parent_id, проверяем что родитель существует
-	if req.ParentID != "" {
-		if _, exists := s.repo.GetByID(req.ParentID); !exists {
-			return nil, errors.New("parent comment not found")
-		}
-	}
-
-	comment := entity.Comment{
-		ID:        uuid.New().String(),
-		ParentID:  req.ParentID,
-		Author:    req.Author,
-		Text:      req.Text,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := s.repo.Create(comment); err != nil {
-		return nil, err
-	}
-
-	return &comment, nil
-}
-
-func (s *CommentService) GetComments(parentID string, page, pageSize int, sortBy string) (*entity.CommentsResponse, error) {
-	comments, total := s.repo.GetChildren(parentID, page, pageSize, sortBy)
-
-	response := &entity.CommentsResponse{
-		Comments: comments,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
-	}
-
-	return response, nil
-}
-
-func (s *CommentService) GetCommentTree(parentID string) ([]entity.Comment, error) {
-	tree := s.repo.BuildTree(parentID, 0)
-	return tree, nil
-}
-
-func (s *CommentService) DeleteComment(id string) error {
-	if _, exists := s.repo.GetByID(id); !exists {
-		return errors.New("comment not found")
-	}
-
-	if err := s.repo.Delete(id); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *CommentService) SearchComments(query string, page, pageSize int) (*entity.CommentsResponse, error) {
-	if query == "" {
-		return nil, errors.New("search query is required")
-	}
-
-	results, total := s.repo.Search(query, page, pageSize)
-
-	response := &entity.CommentsResponse{
-		Comments: results,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
-	}
-
-	return response, nil
-}
-
-func (s *CommentService) GetStats() (map[string]string, error) {
-	return s.repo.GetStats()
-}
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// CreateComment публикует комментарий от имени actor; писать от чужого имени нельзя,
+// а в закрытую ветку отвечают только роли с правом менять настройки веток
+func (s *CommentService) CreateComment(actor entity.Actor, req entity.CreateCommentRequest) (*entity.Comment, error) {
+	if !actor.Can(entity.PermPost) {
+		return nil, entity.ErrForbidden
+	}
+	if req.Author == "" {
+		req.Author = actor.Name
+	}
+	if req.Author == "" || req.Text == "" {
+		return nil, errors.New("author and text are required")
+	}
+	if !strings.EqualFold(req.Author, actor.Name) {
+		return nil, entity.ErrForbidden
+	}
+
+	// Если указан parent_id, проверяем что родитель существует
+	if req.ParentID != "" {
+		thread, exists := s.repo.GetThreadRoot(req.ParentID)
+		if !exists {
+			return nil, errors.New("parent comment not found")
+		}
+
+		settings, err := s.repo.GetThreadSettings(thread)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Locked && !actor.Can(entity.PermThreadSettings) {
+			return nil, entity.ErrThreadLocked
+		}
+	}
+
+	comment := entity.Comment{
+		ID:        uuid.New().String(),
+		ParentID:  req.ParentID,
+		Author:    req.Author,
+		Text:      req.Text,
+		Revision:  1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.repo.Create(comment); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func (s *CommentService) GetComments(parentID string, page, pageSize int, sortBy string) (*entity.CommentsResponse, error) {
+	comments, total := s.repo.GetChildren(parentID, page, pageSize, sortBy)
+
+	response := &entity.CommentsResponse{
+		Comments: comments,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	return response, nil
+}
+
+func (s *CommentService) GetCommentTree(parentID string) ([]entity.Comment, error) {
+	tree := s.repo.BuildTree(parentID, 0)
+	return tree, nil
+}
+
+// UpdateComment меняет текст комментария, если клиент видел его последнюю ревизию.
+// При конфликте вместе с entity.ErrRevisionConflict возвращается актуальная версия.
+// Свой комментарий правит автор, чужой - модератор.
+func (s *CommentService) UpdateComment(actor entity.Actor, id string, req entity.UpdateCommentRequest) (*entity.Comment, error) {
+	if req.Text == "" {
+		return nil, errors.New("text is required")
+	}
+	if req.Revision <= 0 {
+		return nil, ErrRevisionRequired
+	}
+
+	current, exists := s.repo.GetByID(id)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	if !actor.CanModify(current, entity.PermEditOwn, entity.PermEditAny) {
+		return nil, entity.ErrForbidden
+	}
+
+	comment, err := s.repo.Update(id, req.Revision, req.Text)
+	if errors.Is(err, database.ErrCommentNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// DeleteComment удаляет комментарий вместе с ответами: свой - автор, чужой - модератор
+func (s *CommentService) DeleteComment(actor entity.Actor, id string) error {
+	comment, exists := s.repo.GetByID(id)
+	if !exists {
+		return ErrCommentNotFound
+	}
+	if !actor.CanModify(comment, entity.PermDeleteOwn, entity.PermDeleteAny) {
+		return entity.ErrForbidden
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *CommentService) SearchComments(query string, page, pageSize int) (*entity.CommentsResponse, error) {
+	if query == "" {
+		return nil, errors.New("search query is required")
+	}
+
+	results, total := s.repo.Search(query, page, pageSize)
+
+	response := &entity.CommentsResponse{
+		Comments: results,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	return response, nil
+}
+
+func (s *CommentService) GetStats() (map[string]string, error) {
+	return s.repo.GetStats()
+}
+
+// PinComment закрепляет ответ первым среди ответов на тот же комментарий
+func (s *CommentService) PinComment(actor entity.Actor, id string, pinned bool) (*entity.Comment, error) {
+	if !actor.Can(entity.PermPin) {
+		return nil, entity.ErrForbidden
+	}
+
+	comment, err := s.repo.SetPinned(id, pinned)
+	if errors.Is(err, database.ErrCommentNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// GetThreadSettings возвращает настройки ветки, в которой находится комментарий
+func (s *CommentService) GetThreadSettings(id string) (*entity.ThreadSettings, error) {
+	thread, exists := s.repo.GetThreadRoot(id)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	return s.repo.GetThreadSettings(thread)
+}
+
+// UpdateThreadSettings меняет настройки ветки, в которой находится комментарий
+func (s *CommentService) UpdateThreadSettings(actor entity.Actor, id string, req entity.ThreadSettingsRequest) (*entity.ThreadSettings, error) {
+	if !actor.Can(entity.PermThreadSettings) {
+		return nil, entity.ErrForbidden
+	}
+
+	thread, exists := s.repo.GetThreadRoot(id)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	settings := &entity.ThreadSettings{
+		Thread:    thread,
+		Locked:    req.Locked,
+		UpdatedBy: actor.Name,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.SaveThreadSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// PurgeAuthor удаляет все комментарии автора вместе с ответами на них, например после спама
+func (s *CommentService) PurgeAuthor(actor entity.Actor, author string) (*entity.PurgeResult, error) {
+	if !actor.Can(entity.PermPurge) {
+		return nil, entity.ErrForbidden
+	}
+	author = strings.TrimSpace(author)
+	if author == "" {
+		return nil, errors.New("author is required")
+	}
+
+	comments, err := s.repo.GetAllComments()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.PurgeResult{Author: author}
+	for _, comment := range comments {
+		if !strings.EqualFold(comment.Author, author) {
+			continue
+		}
+		// Ответ мог уйти вместе с удалённым выше комментарием того же автора
+		if _, exists := s.repo.GetByID(comment.ID); !exists {
+			continue
+		}
+		if err := s.repo.Delete(comment.ID); err != nil {
+			return result, err
+		}
+		result.Comments++
+	}
+
+	return result, nil
+}
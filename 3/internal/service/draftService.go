@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+var ErrDraftNotFound = errors.New("draft not found")
+
+// maxDraftLength ограничивает черновик, чтобы автосохранение не превращалось в хранилище файлов
+const maxDraftLength = 20000
+
+// DraftService автосохраняет неотправленные ответы, чтобы они переживали перезагрузку страницы.
+// Черновик живёт ttl с последнего сохранения; публикация комментария удаляет его в CommentRepository.Create.
+type DraftService struct {
+	repo *database.CommentRepository
+	ttl  atomic.Int64 // time.Duration; меняется при перезагрузке конфигурации
+}
+
+// defaultDraftTTL - срок черновика, если он не задан в конфигурации
+const defaultDraftTTL = 72 * time.Hour
+
+func NewDraftService(repo *database.CommentRepository, ttl time.Duration) *DraftService {
+	s := &DraftService{
+		repo: repo,
+	}
+	s.SetTTL(ttl)
+	return s
+}
+
+// SetTTL меняет срок хранения для черновиков, сохранённых после вызова
+func (s *DraftService) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultDraftTTL
+	}
+	s.ttl.Store(int64(ttl))
+}
+
+func (s *DraftService) SaveDraft(req entity.SaveDraftRequest) (*entity.Draft, error) {
+	author := strings.TrimSpace(req.Author)
+	if author == "" {
+		return nil, errors.New("author is required")
+	}
+	if len(req.Text) > maxDraftLength {
+		return nil, errors.New("draft is too long")
+	}
+	if req.ParentID != "" {
+		if _, exists := s.repo.GetByID(req.ParentID); !exists {
+			return nil, errors.New("parent comment not found")
+		}
+	}
+
+	// Пустой текст - пользователь стёр ответ, хранить нечего
+	if strings.TrimSpace(req.Text) == "" {
+		if _, err := s.repo.DeleteDraft(author, req.ParentID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	now := time.Now()
+	ttl := time.Duration(s.ttl.Load())
+	draft := &entity.Draft{
+		Author:    author,
+		ParentID:  req.ParentID,
+		Text:      req.Text,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.repo.SaveDraft(draft, ttl); err != nil {
+		return nil, err
+	}
+
+	return draft, nil
+}
+
+func (s *DraftService) GetDraft(author, parentID string) (*entity.Draft, error) {
+	draft, exists := s.repo.GetDraft(strings.TrimSpace(author), parentID)
+	if !exists {
+		return nil, ErrDraftNotFound
+	}
+
+	return draft, nil
+}
+
+func (s *DraftService) DeleteDraft(author, parentID string) error {
+	deleted, err := s.repo.DeleteDraft(strings.TrimSpace(author), parentID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDraftNotFound
+	}
+
+	return nil
+}
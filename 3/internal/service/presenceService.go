@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+// PresenceService ведёт учёт зрителей веток и набирающих ответ.
+// Невидимые пользователи в Redis не попадают: их не видно ни в счётчике, ни в наборе текста.
+type PresenceService struct {
+	repo      *database.PresenceRepository
+	comments  *database.CommentRepository
+	viewerTTL time.Duration
+	typingTTL time.Duration
+}
+
+func NewPresenceService(repo *database.PresenceRepository, comments *database.CommentRepository, viewerTTL, typingTTL time.Duration) *PresenceService {
+	if viewerTTL <= 0 {
+		viewerTTL = 30 * time.Second
+	}
+	if typingTTL <= 0 {
+		typingTTL = 5 * time.Second
+	}
+
+	return &PresenceService{
+		repo:      repo,
+		comments:  comments,
+		viewerTTL: viewerTTL,
+		typingTTL: typingTTL,
+	}
+}
+
+func (s *PresenceService) ViewerTTL() time.Duration { return s.viewerTTL }
+func (s *PresenceService) TypingTTL() time.Duration { return s.typingTTL }
+
+// Join отмечает сессию зрителем ветки
+func (s *PresenceService) Join(thread, session string, viewer entity.PresenceViewer) error {
+	if err := viewer.Visibility.Validate(); err != nil {
+		return err
+	}
+	if _, exists := s.comments.GetByID(thread); !exists {
+		return ErrCommentNotFound
+	}
+	if viewer.Visibility == entity.PresenceInvisible {
+		return nil
+	}
+
+	if err := s.repo.Touch(thread, session, viewer.DisplayName(), s.viewerTTL); err != nil {
+		return err
+	}
+	return s.repo.Publish(thread)
+}
+
+// Heartbeat продлевает присутствие. Счётчик от этого не меняется, поэтому без публикации.
+func (s *PresenceService) Heartbeat(thread, session string, viewer entity.PresenceViewer) error {
+	if viewer.Visibility == entity.PresenceInvisible {
+		return nil
+	}
+	return s.repo.Touch(thread, session, viewer.DisplayName(), s.viewerTTL)
+}
+
+// SetTyping включает или выключает признак набора текста; started - признак только что
+// включился, и остальным надо сообщить. Пока пользователь печатает, клиент повторяет
+// событие, и признак просто продлевается.
+func (s *PresenceService) SetTyping(thread, session string, viewer entity.PresenceViewer, typing, started bool) error {
+	if viewer.Visibility == entity.PresenceInvisible {
+		return nil
+	}
+
+	if !typing {
+		if err := s.repo.StopTyping(thread, session); err != nil {
+			return err
+		}
+		return s.repo.Publish(thread)
+	}
+
+	if err := s.repo.SetTyping(thread, session, s.typingTTL); err != nil {
+		return err
+	}
+	if !started {
+		return nil
+	}
+	return s.repo.Publish(thread)
+}
+
+func (s *PresenceService) Leave(thread, session string, viewer entity.PresenceViewer) error {
+	if viewer.Visibility == entity.PresenceInvisible {
+		return nil
+	}
+
+	if err := s.repo.Leave(thread, session); err != nil {
+		return err
+	}
+	return s.repo.Publish(thread)
+}
+
+func (s *PresenceService) Snapshot(thread string) (*entity.PresenceSnapshot, error) {
+	if _, exists := s.comments.GetByID(thread); !exists {
+		return nil, ErrCommentNotFound
+	}
+	return s.repo.Snapshot(thread)
+}
+
+// Updates - ветки, в которых изменилось присутствие, в том числе на других экземплярах сервиса
+func (s *PresenceService) Updates(ctx context.Context) <-chan string {
+	return s.repo.Subscribe(ctx)
+}
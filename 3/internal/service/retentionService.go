@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+var ErrNoRetentionReport = errors.New("retention has not been run yet")
+
+// ApplyRetention находит ветки, в которых не было активности дольше, чем позволяет
+// политика, и удаляет или архивирует их. В режиме DryRun только строится отчёт.
+// Последний отчёт сохраняется и доступен через GetRetentionReport.
+func (s *CommentService) ApplyRetention(policy entity.RetentionPolicy) (*entity.RetentionReport, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Запуски по расписанию и вручную не должны обрабатывать одни и те же ветки одновременно
+	s.retentionMu.Lock()
+	defer s.retentionMu.Unlock()
+
+	now := time.Now()
+	report := &entity.RetentionReport{
+		Policy:     policy,
+		Cutoff:     policy.Cutoff(now),
+		StartedAt:  now,
+		Candidates: make([]entity.RetentionCandidate, 0),
+	}
+
+	roots, err := s.repo.GetRootIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+
+	threads := make(map[string][]entity.Comment)
+	for _, id := range roots {
+		root, exists := s.repo.GetByID(id)
+		if !exists {
+			continue
+		}
+		report.ThreadsScanned++
+
+		comments := flattenThread(*root, s.repo.BuildTree(root.ID, 0))
+		lastActivity := threadLastActivity(comments)
+		if !lastActivity.Before(report.Cutoff) {
+			continue
+		}
+
+		threads[root.ID] = comments
+		report.Candidates = append(report.Candidates, entity.RetentionCandidate{
+			Thread:       root.ID,
+			Author:       root.Author,
+			Comments:     len(comments),
+			Bytes:        threadSize(comments),
+			LastActivity: lastActivity,
+		})
+	}
+
+	// Сначала самые старые ветки, чтобы при ограничении MaxThreads очищались именно они
+	sort.Slice(report.Candidates, func(i, j int) bool {
+		return report.Candidates[i].LastActivity.Before(report.Candidates[j].LastActivity)
+	})
+
+	report.ThreadsMatched = len(report.Candidates)
+	if policy.MaxThreads > 0 && len(report.Candidates) > policy.MaxThreads {
+		report.Candidates = report.Candidates[:policy.MaxThreads]
+	}
+
+	for _, candidate := range report.Candidates {
+		if !policy.DryRun {
+			if err := s.applyRetentionAction(policy, candidate.Thread, threads[candidate.Thread]); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", candidate.Thread, err))
+				continue
+			}
+		}
+
+		report.ThreadsApplied++
+		report.Comments += candidate.Comments
+		report.Bytes += candidate.Bytes
+	}
+
+	report.FinishedAt = time.Now()
+	if err := s.repo.SaveRetentionReport(report); err != nil {
+		return report, fmt.Errorf("failed to save retention report: %w", err)
+	}
+
+	return report, nil
+}
+
+func (s *CommentService) GetRetentionReport() (*entity.RetentionReport, error) {
+	report, exists := s.repo.GetRetentionReport()
+	if !exists {
+		return nil, ErrNoRetentionReport
+	}
+	return report, nil
+}
+
+func (s *CommentService) GetArchivedThread(thread string) (*entity.ArchivedThread, error) {
+	archive, exists := s.repo.GetArchivedThread(thread)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	return archive, nil
+}
+
+func (s *CommentService) applyRetentionAction(policy entity.RetentionPolicy, thread string, comments []entity.Comment) error {
+	switch policy.Action {
+	case entity.RetentionArchive:
+		now := time.Now()
+		return s.repo.ArchiveThread(&entity.ArchivedThread{
+			Thread:     thread,
+			ArchivedAt: now,
+			ExpiresAt:  policy.ArchiveExpiry(now),
+			Comments:   comments,
+		})
+	case entity.RetentionDelete:
+		return s.repo.Delete(thread)
+	}
+	return entity.ErrInvalidRetentionPolicy
+}
+
+// threadLastActivity - время последнего создания или изменения комментария в ветке
+func threadLastActivity(comments []entity.Comment) time.Time {
+	var last time.Time
+	for _, comment := range comments {
+		if comment.CreatedAt.After(last) {
+			last = comment.CreatedAt
+		}
+		if comment.UpdatedAt.After(last) {
+			last = comment.UpdatedAt
+		}
+	}
+	return last
+}
+
+func threadSize(comments []entity.Comment) int {
+	size := 0
+	for _, comment := range comments {
+		data, err := json.Marshal(&comment)
+		if err == nil {
+			size += len(data)
+		}
+	}
+	return size
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+var (
+	ErrRoleNotAssigned  = errors.New("role is not assigned")
+	ErrConfiguredAdmin  = errors.New("role of an admin from the config can't be changed")
+	ErrUserNameRequired = errors.New("user is required")
+)
+
+// RoleService определяет роль пользователя: администраторы из конфигурации, затем роль,
+// назначенная через API, иначе роль по умолчанию. Анонимный пользователь только читает.
+type RoleService struct {
+	repo        *database.RoleRepository
+	defaultRole entity.Role
+	admins      map[string]bool
+}
+
+func NewRoleService(repo *database.RoleRepository, defaultRole entity.Role, admins []string) *RoleService {
+	if defaultRole.Validate() != nil {
+		defaultRole = entity.RoleAuthor
+	}
+
+	configured := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		if admin = strings.ToLower(strings.TrimSpace(admin)); admin != "" {
+			configured[admin] = true
+		}
+	}
+
+	return &RoleService{
+		repo:        repo,
+		defaultRole: defaultRole,
+		admins:      configured,
+	}
+}
+
+// Actor возвращает пользователя запроса с его ролью
+func (s *RoleService) Actor(user string) (entity.Actor, error) {
+	user = strings.TrimSpace(user)
+	if user == "" {
+		return entity.Actor{Role: entity.RoleReader}, nil
+	}
+	if s.admins[strings.ToLower(user)] {
+		return entity.Actor{Name: user, Role: entity.RoleAdmin}, nil
+	}
+
+	role, assigned, err := s.repo.GetRole(user)
+	if err != nil {
+		return entity.Actor{}, err
+	}
+	// Роль, которой больше нет в матрице, не должна давать права
+	if !assigned || role.Validate() != nil {
+		role = s.defaultRole
+	}
+
+	return entity.Actor{Name: user, Role: role}, nil
+}
+
+func (s *RoleService) ListRoles() (*entity.RolesResponse, error) {
+	assigned, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &entity.RolesResponse{
+		DefaultRole: s.defaultRole,
+		Matrix:      entity.PermissionMatrix,
+		Assignments: make([]entity.RoleAssignment, 0, len(assigned)+len(s.admins)),
+	}
+	for admin := range s.admins {
+		response.Assignments = append(response.Assignments, entity.RoleAssignment{User: admin, Role: entity.RoleAdmin, Configured: true})
+	}
+	for user, role := range assigned {
+		if !s.admins[user] {
+			response.Assignments = append(response.Assignments, entity.RoleAssignment{User: user, Role: role})
+		}
+	}
+	sort.Slice(response.Assignments, func(i, j int) bool {
+		return response.Assignments[i].User < response.Assignments[j].User
+	})
+
+	return response, nil
+}
+
+func (s *RoleService) SetRole(user string, role entity.Role) (*entity.RoleAssignment, error) {
+	user = strings.TrimSpace(user)
+	if user == "" {
+		return nil, ErrUserNameRequired
+	}
+	if err := role.Validate(); err != nil {
+		return nil, err
+	}
+	if s.admins[strings.ToLower(user)] {
+		return nil, ErrConfiguredAdmin
+	}
+
+	if err := s.repo.SetRole(user, role); err != nil {
+		return nil, err
+	}
+
+	return &entity.RoleAssignment{User: strings.ToLower(user), Role: role}, nil
+}
+
+// RemoveRole возвращает пользователю роль по умолчанию
+func (s *RoleService) RemoveRole(user string) error {
+	user = strings.TrimSpace(user)
+	if s.admins[strings.ToLower(user)] {
+		return ErrConfiguredAdmin
+	}
+
+	deleted, err := s.repo.DeleteRole(user)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRoleNotAssigned
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrReindexRunning = errors.New("search reindex is already running")
+	ErrNoReindexJob   = errors.New("search reindex has not been run yet")
+)
+
+// SearchIndexService перестраивает поисковый индекс из сохранённых комментариев, например
+// после изменения анализатора. Новый индекс строится порциями под следующей версией ключей
+// и подменяет рабочий целиком, поэтому поиск не прерывается и не видит недостроенный индекс.
+type SearchIndexService struct {
+	repo      *database.CommentRepository
+	chunkSize int
+
+	mu      sync.Mutex
+	running bool
+}
+
+func NewSearchIndexService(repo *database.CommentRepository, chunkSize int) *SearchIndexService {
+	if chunkSize <= 0 {
+		chunkSize = 500
+	}
+
+	return &SearchIndexService{
+		repo:      repo,
+		chunkSize: chunkSize,
+	}
+}
+
+// StartReindex запускает перестроение в фоне и сразу возвращает задачу;
+// прогресс отдаёт GetReindexJob
+func (s *SearchIndexService) StartReindex() (*entity.ReindexJob, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrReindexRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	job, err := s.begin()
+	if err != nil {
+		s.done()
+		return nil, err
+	}
+
+	snapshot := *job
+	go s.run(job)
+
+	return &snapshot, nil
+}
+
+// GetReindexJob возвращает последнюю задачу. Задача, записанная как выполняющаяся,
+// которую этот процесс не ведёт, оборвалась вместе с прежним процессом.
+func (s *SearchIndexService) GetReindexJob() (*entity.ReindexJob, error) {
+	job, exists := s.repo.GetReindexJob()
+	if !exists {
+		return nil, ErrNoReindexJob
+	}
+
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	if job.Status == entity.ReindexRunning && !running {
+		job.Status = entity.ReindexInterrupted
+	}
+
+	return job, nil
+}
+
+func (s *SearchIndexService) begin() (*entity.ReindexJob, error) {
+	total, err := s.repo.CountComments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	version, previous, err := s.repo.BeginReindex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reindex: %w", err)
+	}
+
+	job := &entity.ReindexJob{
+		ID:              uuid.New().String(),
+		Status:          entity.ReindexRunning,
+		Version:         version,
+		PreviousVersion: previous,
+		ChunkSize:       s.chunkSize,
+		Total:           total,
+		StartedAt:       time.Now(),
+	}
+	if err := s.repo.SaveReindexJob(job); err != nil {
+		s.repo.AbortReindex(version)
+		return nil, fmt.Errorf("failed to save reindex job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (s *SearchIndexService) run(job *entity.ReindexJob) {
+	defer s.done()
+
+	var cursor uint64
+	for {
+		ids, next, err := s.repo.ScanCommentIDs(cursor, int64(s.chunkSize))
+		if err != nil {
+			s.fail(job, fmt.Errorf("failed to read comments: %w", err))
+			return
+		}
+
+		indexed, err := s.repo.IndexComments(job.Version, ids)
+		if err != nil {
+			s.fail(job, fmt.Errorf("failed to index comments: %w", err))
+			return
+		}
+
+		job.Processed += len(ids)
+		job.Indexed += indexed
+		job.Progress = reindexProgress(job)
+		s.save(job)
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if err := s.repo.SwapSearchIndex(job.Version); err != nil {
+		s.fail(job, fmt.Errorf("failed to switch search index: %w", err))
+		return
+	}
+
+	// После подмены старую версию уже никто не читает
+	if err := s.repo.DeleteSearchVersion(job.PreviousVersion); err != nil {
+		logrus.WithError(err).WithField("version", job.PreviousVersion).Warn("failed to delete previous search index")
+	}
+
+	job.Status = entity.ReindexCompleted
+	job.Progress = 1
+	job.FinishedAt = time.Now()
+	s.save(job)
+
+	logrus.WithFields(logrus.Fields{
+		"version":   job.Version,
+		"processed": job.Processed,
+		"indexed":   job.Indexed,
+		"duration":  job.FinishedAt.Sub(job.StartedAt).String(),
+	}).Info("Search reindex completed")
+}
+
+// fail отменяет перестроение: поиск остаётся на прежней версии, недостроенная удаляется
+func (s *SearchIndexService) fail(job *entity.ReindexJob, err error) {
+	if abortErr := s.repo.AbortReindex(job.Version); abortErr != nil {
+		logrus.WithError(abortErr).WithField("version", job.Version).Warn("failed to clean up search index")
+	}
+
+	job.Status = entity.ReindexFailed
+	job.Error = err.Error()
+	job.FinishedAt = time.Now()
+	s.save(job)
+
+	logrus.WithError(err).WithField("version", job.Version).Error("Search reindex failed")
+}
+
+func (s *SearchIndexService) save(job *entity.ReindexJob) {
+	if err := s.repo.SaveReindexJob(job); err != nil {
+		logrus.WithError(err).Warn("failed to save reindex progress")
+	}
+}
+
+func (s *SearchIndexService) done() {
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
+
+// reindexProgress не доходит до 1 до подмены: SSCAN может вернуть ID повторно,
+// а комментарии, созданные во время работы, увеличивают Processed сверх Total
+func reindexProgress(job *entity.ReindexJob) float64 {
+	if job.Total <= 0 {
+		return 0.99
+	}
+
+	progress := float64(job.Processed) / float64(job.Total)
+	if progress > 0.99 {
+		progress = 0.99
+	}
+	return progress
+}
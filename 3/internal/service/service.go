@@ -1,15 +1,35 @@
-package service
-
-import (
-	"github.com/ds124wfegd/WB_L3/3/internal/database"
-)
-
-type CommentService struct {
-	repo *database.CommentRepository
-}
-
-func NewCommentService(repo *database.CommentRepository) *CommentService {
-	return &CommentService{
-		repo: repo,
-	}
-}
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/summarizer"
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrRevisionRequired = errors.New("revision is required, pass it in the body or the If-Match header")
+)
+
+type CommentService struct {
+	repo       *database.CommentRepository
+	summarizer summarizer.Summarizer
+	summaryTTL time.Duration
+
+	retentionMu sync.Mutex
+}
+
+func NewCommentService(repo *database.CommentRepository, summarizer summarizer.Summarizer, summaryTTL time.Duration) *CommentService {
+	if summaryTTL <= 0 {
+		summaryTTL = 24 * time.Hour
+	}
+
+	return &CommentService{
+		repo:       repo,
+		summarizer: summarizer,
+		summaryTTL: summaryTTL,
+	}
+}
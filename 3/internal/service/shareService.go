@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/database"
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/shortener"
+)
+
+var (
+	ErrSharingDisabled = errors.New("link shortener is not configured")
+	ErrShortenerFailed = errors.New("link shortener is unavailable")
+)
+
+// previewLength - сколько символов текста комментария попадает в карточку ссылки
+const previewLength = 200
+
+// ShareService строит постоянные ссылки на комментарии и сокращает их через сервис сокращения ссылок
+type ShareService struct {
+	repo      *database.CommentRepository
+	shortener shortener.Shortener // nil - короткие ссылки выключены
+	baseURL   string
+}
+
+func NewShareService(repo *database.CommentRepository, shortener shortener.Shortener, baseURL string) *ShareService {
+	return &ShareService{
+		repo:      repo,
+		shortener: shortener,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Permalink возвращает постоянную ссылку на комментарий и ссылку на его место в ветке
+func (s *ShareService) Permalink(id string) (*entity.CommentPermalink, error) {
+	comment, exists := s.repo.GetByID(id)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	return s.permalink(comment)
+}
+
+func (s *ShareService) permalink(comment *entity.Comment) (*entity.CommentPermalink, error) {
+	thread, exists := s.repo.GetThreadRoot(comment.ID)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	slug := entity.CommentSlug(comment.Text)
+	return &entity.CommentPermalink{
+		CommentID: comment.ID,
+		Thread:    thread,
+		Slug:      slug,
+		Permalink: s.baseURL + entity.PermalinkPath(comment.ID, slug),
+		DeepLink:  s.baseURL + entity.DeepLinkPath(thread, comment.ID),
+	}, nil
+}
+
+// Share добавляет к постоянной ссылке короткую. Сокращается Permalink, а не DeepLink:
+// короткая ссылка продолжит работать, если поменяется разметка страницы ветки.
+func (s *ShareService) Share(ctx context.Context, id string) (*entity.CommentPermalink, error) {
+	if s.shortener == nil {
+		return nil, ErrSharingDisabled
+	}
+
+	comment, exists := s.repo.GetByID(id)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	permalink, err := s.permalink(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &shortener.Preview{
+		Title:       "Комментарий " + comment.Author,
+		Description: truncate(comment.Text, previewLength),
+	}
+	permalink.ShortURL, err = s.shortener.Shorten(ctx, permalink.Permalink, preview)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrShortenerFailed, err)
+	}
+	return permalink, nil
+}
+
+// truncate обрезает текст до limit символов, добавляя многоточие
+func truncate(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+)
+
+// GetThreadSummary возвращает сводку ветки, начинающейся с комментария thread.
+// Сводка пересчитывается только при изменении ветки: ревизия - хэш ID и времени
+// изменения всех комментариев ветки.
+func (s *CommentService) GetThreadSummary(thread string) (*entity.ThreadSummary, error) {
+	root, exists := s.repo.GetByID(thread)
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	comments := flattenThread(*root, s.repo.BuildTree(root.ID, 0))
+	revision := threadRevision(s.summarizer.Name(), comments)
+
+	if cached, ok := s.repo.GetSummary(thread, revision); ok {
+		cached.Cached = true
+		return cached, nil
+	}
+
+	text, err := s.summarizer.Summarize(comments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize thread: %w", err)
+	}
+
+	summary := &entity.ThreadSummary{
+		Thread:       thread,
+		Revision:     revision,
+		Summarizer:   s.summarizer.Name(),
+		Summary:      text,
+		Comments:     len(comments),
+		Participants: participants(comments),
+		GeneratedAt:  time.Now(),
+	}
+
+	if err := s.repo.SaveSummary(summary, s.summaryTTL); err != nil {
+		return nil, fmt.Errorf("failed to cache thread summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// flattenThread раскладывает дерево в список в порядке обхода в глубину
+func flattenThread(root entity.Comment, children []entity.Comment) []entity.Comment {
+	root.Children = nil
+	comments := []entity.Comment{root}
+
+	for _, child := range children {
+		comments = append(comments, flattenThread(child, child.Children)...)
+	}
+
+	return comments
+}
+
+func threadRevision(summarizerName string, comments []entity.Comment) string {
+	hash := sha256.New()
+	hash.Write([]byte(summarizerName))
+	for _, comment := range comments {
+		hash.Write([]byte(comment.ID))
+		hash.Write([]byte(strconv.FormatInt(comment.UpdatedAt.UnixNano(), 10)))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func participants(comments []entity.Comment) []string {
+	seen := make(map[string]struct{})
+	authors := make([]string, 0)
+	for _, comment := range comments {
+		if _, ok := seen[comment.Author]; ok {
+			continue
+		}
+		seen[comment.Author] = struct{}{}
+		authors = append(authors, comment.Author)
+	}
+
+	sort.Strings(authors)
+	return authors
+}
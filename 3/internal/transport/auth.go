@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/usertoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authHeader несёт токен пользователя "Bearer <token>"; токен выдаёт шлюз после входа,
+// подписывая его ключом auth.secret. Имени пользователя без подписи сервис не доверяет
+const authHeader = "Authorization"
+
+const actorKey = "actor"
+
+// ActorResolver определяет роль пользователя запроса
+type ActorResolver interface {
+	Actor(user string) (entity.Actor, error)
+}
+
+// Identify кладёт в контекст пользователя запроса с его ролью; без токена пользователь - анонимный читатель,
+// а с недействительным или просроченным токеном запрос отклоняется (401)
+func Identify(roles ActorResolver, tokens *usertoken.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user string
+		if header := c.GetHeader(authHeader); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "expected a Bearer token"})
+				return
+			}
+
+			var err error
+			if user, err = tokens.Verify(strings.TrimSpace(token), time.Now()); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		actor, err := roles.Actor(user)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(actorKey, actor)
+		c.Next()
+	}
+}
+
+// RequirePermission пропускает запрос, только если у роли пользователя есть право permission
+func RequirePermission(permission entity.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !actorFrom(c).Can(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error(), "permission": permission})
+			return
+		}
+		c.Next()
+	}
+}
+
+func actorFrom(c *gin.Context) entity.Actor {
+	if actor, ok := c.Get(actorKey); ok {
+		return actor.(entity.Actor)
+	}
+	return entity.Actor{Role: entity.RoleReader}
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/usertoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubResolver map[string]entity.Role
+
+func (r stubResolver) Actor(user string) (entity.Actor, error) {
+	if user == "broken" {
+		return entity.Actor{}, errors.New("redis is down")
+	}
+	if user == "" {
+		return entity.Actor{Role: entity.RoleReader}, nil
+	}
+	role, ok := r[user]
+	if !ok {
+		role = entity.RoleAuthor
+	}
+	return entity.Actor{Name: user, Role: role}, nil
+}
+
+var testTokens = usertoken.New("test-secret")
+
+func bearer(t *testing.T, tokens *usertoken.Signer, user string, expires time.Time) string {
+	t.Helper()
+	token, err := tokens.Sign(user, expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func newAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	roles := stubResolver{"reader": entity.RoleReader, "mod": entity.RoleModerator, "root": entity.RoleAdmin}
+
+	router := gin.New()
+	router.Use(Identify(roles, testTokens))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/comments", RequirePermission(entity.PermPost), ok)
+	router.PUT("/comments/:id/pin", RequirePermission(entity.PermPin), ok)
+	router.POST("/comments/purge", RequirePermission(entity.PermPurge), ok)
+	router.PUT("/admin/roles/:user", RequirePermission(entity.PermManageRoles), ok)
+	return router
+}
+
+func TestRequirePermission(t *testing.T) {
+	router := newAuthRouter()
+
+	tests := []struct {
+		name   string
+		user   string
+		method string
+		path   string
+		want   int
+	}{
+		{"anonymous can't post", "", http.MethodPost, "/comments", http.StatusForbidden},
+		{"reader can't post", "reader", http.MethodPost, "/comments", http.StatusForbidden},
+		{"default author posts", "alice", http.MethodPost, "/comments", http.StatusOK},
+		{"author can't pin", "alice", http.MethodPut, "/comments/1/pin", http.StatusForbidden},
+		{"moderator pins", "mod", http.MethodPut, "/comments/1/pin", http.StatusOK},
+		{"moderator can't purge", "mod", http.MethodPost, "/comments/purge", http.StatusForbidden},
+		{"admin purges", "root", http.MethodPost, "/comments/purge", http.StatusOK},
+		{"moderator can't manage roles", "mod", http.MethodPut, "/admin/roles/alice", http.StatusForbidden},
+		{"admin manages roles", "root", http.MethodPut, "/admin/roles/alice", http.StatusOK},
+		{"role lookup failure", "broken", http.MethodPost, "/comments", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.user != "" {
+			req.Header.Set(authHeader, bearer(t, testTokens, tt.user, time.Now().Add(time.Hour)))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+// TestIdentifyRejectsSpoofedUser - имя пользователя без действительной подписи не даёт его прав
+func TestIdentifyRejectsSpoofedUser(t *testing.T) {
+	router := newAuthRouter()
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"unsigned user header", "X-User", "root", http.StatusForbidden},
+		{"token signed with another key", authHeader, bearer(t, usertoken.New("gateway-leak"), "root", time.Now().Add(time.Hour)), http.StatusUnauthorized},
+		{"expired token", authHeader, bearer(t, testTokens, "root", time.Now().Add(-time.Minute)), http.StatusUnauthorized},
+		{"not a bearer token", authHeader, "Basic cm9vdDpyb290", http.StatusUnauthorized},
+		{"tampered user", authHeader, "Bearer cm9vdA.9999999999.c2lnbmF0dXJl", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/comments/purge", nil)
+		req.Header.Set(tt.header, tt.value)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+// TestIdentifyWithoutSecret - без ключа подписи токены не принимаются, даже подписанные пустым ключом
+func TestIdentifyWithoutSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Identify(stubResolver{}, usertoken.New("")))
+	router.POST("/comments", RequirePermission(entity.PermPost), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodPost, "/comments", nil)
+	req.Header.Set(authHeader, bearer(t, usertoken.New("x"), "alice", time.Now().Add(time.Hour)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestActorFromWithoutIdentify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if actor := actorFrom(c); actor.Role != entity.RoleReader || actor.Name != "" {
+		t.Errorf("expected anonymous reader, got %+v", actor)
+	}
+}
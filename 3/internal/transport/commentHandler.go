@@ -1,90 +1,287 @@
-package transport
-
-import (
-	"net/http"
-
-	"github.com/ds124wfegd/WB_L3/3/internal/entity"
-
-	"strconv"
-
-	"github.com/gin-gonic/gin"
-)
-
-func (h *CommentHandler) CreateComment(c *gin.Context) {
-	var req entity.CreateCommentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	comment, err := h.service.CreateComment(req)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, comment)
-}
-
-func (h *CommentHandler) GetComments(c *gin.Context) {
-	parentID := c.Query("parent")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "created_at_asc")
-
-	response, err := h.service.GetComments(parentID, page, pageSize, sortBy)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func (h *CommentHandler) GetCommentTree(c *gin.Context) {
-	parentID := c.Query("parent")
-
-	tree, err := h.service.GetCommentTree(parentID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"comments": tree})
-}
-
-func (h *CommentHandler) DeleteComment(c *gin.Context) {
-	id := c.Param("id")
-
-	err := h.service.DeleteComment(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "comment deleted successfully"})
-}
-
-func (h *CommentHandler) SearchComments(c *gin.Context) {
-	query := c.Query("q")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-
-	response, err := h.service.SearchComments(query, page, pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func (h *CommentHandler) GetStats(c *gin.Context) {
-	stats, err := h.service.GetStats()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, stats)
-}
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	var req entity.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.CreateComment(actorFrom(c), req)
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	parentID := c.Query("parent")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	sortBy := c.DefaultQuery("sort_by", "created_at_asc")
+
+	response, err := h.service.GetComments(parentID, page, pageSize, sortBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CommentHandler) GetCommentTree(c *gin.Context) {
+	parentID := c.Query("parent")
+
+	tree, err := h.service.GetCommentTree(parentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": tree})
+}
+
+// UpdateComment правит текст комментария. Ревизия берётся из If-Match или из тела запроса;
+// на устаревшую ревизию отвечает 409 с актуальной версией комментария.
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	var req entity.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		revision, err := parseRevisionTag(ifMatch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header"})
+			return
+		}
+		req.Revision = revision
+	}
+
+	comment, err := h.service.UpdateComment(actorFrom(c), c.Param("id"), req)
+	switch {
+	case errors.Is(err, entity.ErrRevisionConflict):
+		c.Header("ETag", revisionTag(comment.Revision))
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "comment": comment})
+		return
+	case errors.Is(err, service.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, entity.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrRevisionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", revisionTag(comment.Revision))
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.service.DeleteComment(actorFrom(c), id)
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted successfully"})
+}
+
+func (h *CommentHandler) SearchComments(c *gin.Context) {
+	query := c.Query("q")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	response, err := h.service.SearchComments(query, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CommentHandler) GetStats(c *gin.Context) {
+	stats, err := h.service.GetStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *CommentHandler) GetThreadSummary(c *gin.Context) {
+	thread := c.Query("thread")
+	if thread == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "thread is required"})
+		return
+	}
+
+	summary, err := h.service.GetThreadSummary(thread)
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *CommentHandler) GetRetentionReport(c *gin.Context) {
+	report, err := h.service.GetRetentionReport()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RunRetention запускает политику вручную; по умолчанию только строит отчёт
+func (h *CommentHandler) RunRetention(c *gin.Context) {
+	var req entity.RetentionRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := entity.RetentionPolicy{
+		Action:         req.Action,
+		InactiveMonths: req.InactiveMonths,
+		DryRun:         req.DryRun == nil || *req.DryRun,
+		MaxThreads:     req.MaxThreads,
+		ArchiveMonths:  req.ArchiveMonths,
+	}
+
+	report, err := h.service.ApplyRetention(policy)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidRetentionPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *CommentHandler) GetArchivedThread(c *gin.Context) {
+	archive, err := h.service.GetArchivedThread(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, archive)
+}
+
+// PinComment закрепляет или открепляет ответ
+func (h *CommentHandler) PinComment(c *gin.Context) {
+	var req entity.PinCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.PinComment(actorFrom(c), c.Param("id"), req.Pinned)
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) GetThreadSettings(c *gin.Context) {
+	settings, err := h.service.GetThreadSettings(c.Param("id"))
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateThreadSettings меняет настройки ветки; id может быть любым комментарием ветки
+func (h *CommentHandler) UpdateThreadSettings(c *gin.Context) {
+	var req entity.ThreadSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdateThreadSettings(actorFrom(c), c.Param("id"), req)
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// PurgeAuthor удаляет все комментарии автора вместе с ответами на них
+func (h *CommentHandler) PurgeAuthor(c *gin.Context) {
+	var req entity.PurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.PurgeAuthor(actorFrom(c), req.Author)
+	if err != nil {
+		c.JSON(commentErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error(), "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// commentErrorStatus - статус ответа для ошибок прав и отсутствующих комментариев, иначе fallback
+func commentErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, entity.ErrForbidden), errors.Is(err, entity.ErrThreadLocked):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrCommentNotFound):
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// revisionTag представляет ревизию комментария как ETag
+func revisionTag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// parseRevisionTag разбирает значение If-Match вида "3" или W/"3"
+func parseRevisionTag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	return strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+}
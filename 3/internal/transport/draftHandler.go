@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DraftHandler struct {
+	service *service.DraftService
+}
+
+func NewDraftHandler(service *service.DraftService) *DraftHandler {
+	return &DraftHandler{
+		service: service,
+	}
+}
+
+// SaveDraft сохраняет черновик; пустой текст удаляет его и возвращает 204
+func (h *DraftHandler) SaveDraft(c *gin.Context) {
+	var req entity.SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	draft, err := h.service.SaveDraft(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if draft == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+func (h *DraftHandler) GetDraft(c *gin.Context) {
+	author := c.Query("author")
+	if author == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "author is required"})
+		return
+	}
+
+	draft, err := h.service.GetDraft(author, c.Query("parent"))
+	if err != nil {
+		c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+func (h *DraftHandler) DeleteDraft(c *gin.Context) {
+	author := c.Query("author")
+	if author == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "author is required"})
+		return
+	}
+
+	if err := h.service.DeleteDraft(author, c.Query("parent")); err != nil {
+		c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "draft deleted successfully"})
+}
+
+func draftErrorStatus(err error) int {
+	if errors.Is(err, service.ErrDraftNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
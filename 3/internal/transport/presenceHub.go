@@ -0,0 +1,369 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	presenceWriteWait    = 10 * time.Second
+	presenceMaxMessage   = 512
+	presenceMaxName      = 64
+	presenceClientBuffer = 8
+)
+
+var presenceUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// PresenceHub держит WebSocket-соединения зрителей на этом экземпляре и рассылает им
+// состояние веток: при изменениях (в том числе на других экземплярах - через Redis)
+// и раз в полпериода набора текста, чтобы истёкший признак пропадал у клиентов.
+type PresenceHub struct {
+	service *service.PresenceService
+	limit   rate.Limit
+	burst   int
+
+	mu      sync.Mutex
+	threads map[string]map[*presenceClient]struct{}
+	last    map[string][]byte // последнее разосланное состояние ветки
+}
+
+type presenceClient struct {
+	conn    *websocket.Conn
+	send    chan []byte
+	thread  string
+	session string
+	viewer  entity.PresenceViewer
+
+	// limiter ограничивает частоту сообщений клиента, лишние отбрасываются
+	limiter  *rate.Limiter
+	typingAt time.Time
+}
+
+func NewPresenceHub(service *service.PresenceService, updatesPerSecond float64, burst int) *PresenceHub {
+	if updatesPerSecond <= 0 {
+		updatesPerSecond = 2
+	}
+	if burst <= 0 {
+		burst = 5
+	}
+
+	return &PresenceHub{
+		service: service,
+		limit:   rate.Limit(updatesPerSecond),
+		burst:   burst,
+		threads: make(map[string]map[*presenceClient]struct{}),
+		last:    make(map[string][]byte),
+	}
+}
+
+func (h *PresenceHub) Run(ctx context.Context) {
+	updates := h.service.Updates(ctx)
+	ticker := time.NewTicker(h.service.TypingTTL() / 2)
+	defer ticker.Stop()
+
+	logrus.Info("Presence hub started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Presence hub stopped")
+			return
+		case thread, ok := <-updates:
+			if !ok {
+				return
+			}
+			h.broadcast(thread)
+		case <-ticker.C:
+			for _, thread := range h.localThreads() {
+				h.broadcast(thread)
+			}
+		}
+	}
+}
+
+// GetPresence возвращает состояние ветки без подписки
+func (h *PresenceHub) GetPresence(c *gin.Context) {
+	snapshot, err := h.service.Snapshot(c.Query("thread"))
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ServeWS подключает зрителя ветки: ?thread=<id>&name=<имя>&visibility=visible|anonymous|invisible.
+// Клиент присылает {"type":"typing"} во время набора и {"type":"stop_typing"}, сервер - {"type":"presence",...}.
+func (h *PresenceHub) ServeWS(c *gin.Context) {
+	thread := c.Query("thread")
+	viewer := entity.PresenceViewer{
+		Name:       strings.TrimSpace(c.Query("name")),
+		Visibility: entity.PresenceVisibility(c.DefaultQuery("visibility", string(entity.PresenceAnonymous))),
+	}
+	if viewer.Visibility == entity.PresenceVisible && viewer.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required for visible presence"})
+		return
+	}
+	if utf8.RuneCountInString(viewer.Name) > presenceMaxName {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is too long"})
+		return
+	}
+
+	session := uuid.New().String()
+	if err := h.service.Join(thread, session, viewer); err != nil {
+		switch {
+		case errors.Is(err, service.ErrCommentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrInvalidVisibility):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	conn, err := presenceUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту ошибкой
+		h.leave(thread, session, viewer)
+		return
+	}
+
+	client := &presenceClient{
+		conn:    conn,
+		send:    make(chan []byte, presenceClientBuffer),
+		thread:  thread,
+		session: session,
+		viewer:  viewer,
+		limiter: rate.NewLimiter(h.limit, h.burst),
+	}
+	h.register(client)
+
+	go h.writePump(client)
+	h.sendCurrent(client)
+	h.readPump(client)
+}
+
+func (h *PresenceHub) readPump(client *presenceClient) {
+	defer func() {
+		h.unregister(client)
+		client.conn.Close()
+		h.leave(client.thread, client.session, client.viewer)
+	}()
+
+	ttl := h.service.ViewerTTL()
+	client.conn.SetReadLimit(presenceMaxMessage)
+	client.conn.SetReadDeadline(time.Now().Add(ttl))
+	client.conn.SetPongHandler(func(string) error {
+		client.conn.SetReadDeadline(time.Now().Add(ttl))
+		if err := h.service.Heartbeat(client.thread, client.session, client.viewer); err != nil {
+			logrus.Errorf("Failed to refresh presence in thread %s: %v", client.thread, err)
+		}
+		return nil
+	})
+
+	for {
+		_, data, err := client.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logrus.Debugf("Presence connection closed: %v", err)
+			}
+			return
+		}
+
+		if !client.limiter.Allow() {
+			continue
+		}
+
+		var msg entity.PresenceMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		h.handleMessage(client, msg)
+	}
+}
+
+func (h *PresenceHub) handleMessage(client *presenceClient, msg entity.PresenceMessage) {
+	var err error
+
+	switch msg.Type {
+	case entity.PresenceEventTyping:
+		typingTTL := h.service.TypingTTL()
+		since := time.Since(client.typingAt)
+		// Пока признак жив, продлеваем его не чаще раза в полпериода
+		if since < typingTTL/2 {
+			return
+		}
+		client.typingAt = time.Now()
+		err = h.service.SetTyping(client.thread, client.session, client.viewer, true, since >= typingTTL)
+	case entity.PresenceEventIdle:
+		if client.typingAt.IsZero() {
+			return
+		}
+		client.typingAt = time.Time{}
+		err = h.service.SetTyping(client.thread, client.session, client.viewer, false, false)
+	default:
+		return
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to update typing in thread %s: %v", client.thread, err)
+	}
+}
+
+func (h *PresenceHub) writePump(client *presenceClient) {
+	ticker := time.NewTicker(h.service.ViewerTTL() / 3)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(presenceWriteWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(presenceWriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *PresenceHub) leave(thread, session string, viewer entity.PresenceViewer) {
+	if err := h.service.Leave(thread, session, viewer); err != nil {
+		logrus.Errorf("Failed to remove presence in thread %s: %v", thread, err)
+	}
+}
+
+func (h *PresenceHub) register(client *presenceClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients, ok := h.threads[client.thread]
+	if !ok {
+		clients = make(map[*presenceClient]struct{})
+		h.threads[client.thread] = clients
+	}
+	clients[client] = struct{}{}
+}
+
+func (h *PresenceHub) unregister(client *presenceClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.threads[client.thread]
+	if _, ok := clients[client]; !ok {
+		return
+	}
+	delete(clients, client)
+	close(client.send)
+
+	if len(clients) == 0 {
+		delete(h.threads, client.thread)
+		delete(h.last, client.thread)
+	}
+}
+
+func (h *PresenceHub) localThreads() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	threads := make([]string, 0, len(h.threads))
+	for thread := range h.threads {
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+// broadcast рассылает состояние ветки, если у этого экземпляра есть её зрители и оно изменилось
+func (h *PresenceHub) broadcast(thread string) {
+	h.mu.Lock()
+	_, local := h.threads[thread]
+	h.mu.Unlock()
+	if !local {
+		return
+	}
+
+	payload, err := h.snapshotPayload(thread)
+	if err != nil {
+		logrus.Errorf("Failed to load presence of thread %s: %v", thread, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if bytes.Equal(h.last[thread], payload) {
+		return
+	}
+	h.last[thread] = payload
+
+	for client := range h.threads[thread] {
+		deliver(client, payload)
+	}
+}
+
+// sendCurrent отправляет новому клиенту текущее состояние, даже если оно не изменилось
+func (h *PresenceHub) sendCurrent(client *presenceClient) {
+	payload, err := h.snapshotPayload(client.thread)
+	if err != nil {
+		logrus.Errorf("Failed to load presence of thread %s: %v", client.thread, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.threads[client.thread][client]; ok {
+		deliver(client, payload)
+	}
+}
+
+func (h *PresenceHub) snapshotPayload(thread string) ([]byte, error) {
+	snapshot, err := h.service.Snapshot(thread)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entity.PresenceEvent{Type: entity.PresenceEventSnapshot, PresenceSnapshot: snapshot})
+}
+
+// deliver не блокирует рассылку из-за медленного клиента: он пропустит это состояние
+// и получит следующее
+func deliver(client *presenceClient, payload []byte) {
+	select {
+	case client.send <- payload:
+	default:
+	}
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	service *service.RoleService
+}
+
+func NewRoleHandler(service *service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		service: service,
+	}
+}
+
+// ListRoles отдаёт матрицу прав и назначенные роли
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+func (h *RoleHandler) SetRole(c *gin.Context) {
+	var req entity.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignment, err := h.service.SetRole(c.Param("user"), req.Role)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// RemoveRole возвращает пользователю роль по умолчанию
+func (h *RoleHandler) RemoveRole(c *gin.Context) {
+	if err := h.service.RemoveRole(c.Param("user")); err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role removed successfully"})
+}
+
+func roleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrInvalidRole), errors.Is(err, service.ErrUserNameRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrConfiguredAdmin):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrRoleNotAssigned):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
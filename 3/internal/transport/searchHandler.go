@@ -0,0 +1,45 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	service *service.SearchIndexService
+}
+
+func NewSearchHandler(service *service.SearchIndexService) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+	}
+}
+
+// StartReindex запускает перестроение поискового индекса; ход работы отдаёт GetReindexJob
+func (h *SearchHandler) StartReindex(c *gin.Context) {
+	job, err := h.service.StartReindex()
+	if err != nil {
+		if errors.Is(err, service.ErrReindexRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *SearchHandler) GetReindexJob(c *gin.Context) {
+	job, err := h.service.GetReindexJob()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
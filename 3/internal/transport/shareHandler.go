@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareHandler struct {
+	service *service.ShareService
+}
+
+func NewShareHandler(service *service.ShareService) *ShareHandler {
+	return &ShareHandler{
+		service: service,
+	}
+}
+
+func (h *ShareHandler) GetPermalink(c *gin.Context) {
+	permalink, err := h.service.Permalink(c.Param("id"))
+	if err != nil {
+		c.JSON(shareErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, permalink)
+}
+
+// ShareComment возвращает постоянную ссылку вместе с короткой из сервиса сокращения ссылок
+func (h *ShareHandler) ShareComment(c *gin.Context) {
+	permalink, err := h.service.Share(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(shareErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, permalink)
+}
+
+// OpenPermalink переводит с постоянной ссылки на страницу ветки; слаг в пути не проверяется
+func (h *ShareHandler) OpenPermalink(c *gin.Context) {
+	permalink, err := h.service.Permalink(c.Param("id"))
+	if err != nil {
+		c.JSON(shareErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, permalink.DeepLink)
+}
+
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrSharingDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrShortenerFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
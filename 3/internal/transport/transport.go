@@ -1,39 +1,77 @@
-package transport
-
-import (
-	"github.com/ds124wfegd/WB_L3/3/internal/service"
-
-	"github.com/gin-gonic/gin"
-)
-
-func InitRoutes(service *service.CommentService) *gin.Engine {
-	handler := NewCommentHandler(service)
-	router := gin.Default()
-
-	api := router.Group("/comments")
-	{
-		api.POST("", handler.CreateComment)
-		api.GET("", handler.GetComments)
-		api.GET("/tree", handler.GetCommentTree)
-		api.DELETE("/:id", handler.DeleteComment)
-		api.GET("/search", handler.SearchComments)
-		api.GET("/stats", handler.GetStats)
-	}
-
-	router.Static("/static", "/app/internal/web/templates")
-	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
-
-	router.GET("/", func(c *gin.Context) {
-		c.File("/app/internal/web/templates/index.html")
-	})
-
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":   "ok",
-			"service":  "url-shortener",
-			"database": "connected",
-			"redis":    "connected",
-		})
-	})
-	return router
-}
+package transport
+
+import (
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/pkg/usertoken"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoutes(service *service.CommentService, drafts *service.DraftService, roles *service.RoleService, search *service.SearchIndexService, share *service.ShareService, presence *PresenceHub, tokens *usertoken.Signer) *gin.Engine {
+	handler := NewCommentHandler(service)
+	draftHandler := NewDraftHandler(drafts)
+	roleHandler := NewRoleHandler(roles)
+	searchHandler := NewSearchHandler(search)
+	shareHandler := NewShareHandler(share)
+	router := gin.Default()
+
+	// Права на чужие комментарии проверяет сервис: middleware не знает автора комментария
+	api := router.Group("/comments")
+	api.Use(Identify(roles, tokens))
+	{
+		api.POST("", RequirePermission(entity.PermPost), handler.CreateComment)
+		api.GET("", handler.GetComments)
+		api.GET("/tree", handler.GetCommentTree)
+		api.PUT("/:id", handler.UpdateComment)
+		api.DELETE("/:id", handler.DeleteComment)
+		api.PUT("/:id/pin", RequirePermission(entity.PermPin), handler.PinComment)
+		api.GET("/:id/settings", handler.GetThreadSettings)
+		api.PUT("/:id/settings", RequirePermission(entity.PermThreadSettings), handler.UpdateThreadSettings)
+		api.POST("/purge", RequirePermission(entity.PermPurge), handler.PurgeAuthor)
+		api.GET("/search", handler.SearchComments)
+		api.GET("/stats", handler.GetStats)
+		api.GET("/summary", handler.GetThreadSummary)
+		api.GET("/retention", RequirePermission(entity.PermPurge), handler.GetRetentionReport)
+		api.POST("/retention/run", RequirePermission(entity.PermPurge), handler.RunRetention)
+		api.GET("/archive/:id", handler.GetArchivedThread)
+		api.GET("/:id/permalink", shareHandler.GetPermalink)
+		api.POST("/:id/share", shareHandler.ShareComment)
+		api.GET("/presence", presence.GetPresence)
+		api.GET("/presence/ws", presence.ServeWS)
+		api.GET("/drafts", draftHandler.GetDraft)
+		api.PUT("/drafts", draftHandler.SaveDraft)
+		api.DELETE("/drafts", draftHandler.DeleteDraft)
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(Identify(roles, tokens), RequirePermission(entity.PermManageRoles))
+	{
+		admin.GET("/roles", roleHandler.ListRoles)
+		admin.PUT("/roles/:user", roleHandler.SetRole)
+		admin.DELETE("/roles/:user", roleHandler.RemoveRole)
+		admin.POST("/search/reindex", searchHandler.StartReindex)
+		admin.GET("/search/reindex", searchHandler.GetReindexJob)
+	}
+
+	router.Static("/static", "/app/internal/web/templates")
+	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
+
+	router.GET("/", func(c *gin.Context) {
+		c.File("/app/internal/web/templates/index.html")
+	})
+
+	// Постоянные ссылки на комментарии, на них ведут короткие ссылки из POST /comments/:id/share
+	router.GET("/c/:id", shareHandler.OpenPermalink)
+	router.GET("/c/:id/*slug", shareHandler.OpenPermalink)
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":   "ok",
+			"service":  "url-shortener",
+			"database": "connected",
+			"redis":    "connected",
+		})
+	})
+	return router
+}
@@ -14,6 +14,7 @@ window.showReplyForm = showReplyForm;
 window.hideReplyForm = hideReplyForm;
 window.createReply = createReply;
 window.deleteComment = deleteComment;
+window.shareComment = shareComment;
 window.searchComments = searchComments;
 window.showAllComments = showAllComments;
 window.changePage = changePage;
@@ -28,7 +29,7 @@ document.addEventListener('DOMContentLoaded', function() {
 function initializeApp() {
     console.log('🚀 Initializing application...');
     bindEvents();
-    loadComments();
+    openDeepLink();
     console.log('✅ Application initialized successfully');
 }
 
@@ -224,6 +225,7 @@ function changeSort(sortBy) {
 function renderComment(comment, depth, container) {
     const commentDiv = document.createElement('div');
     commentDiv.className = 'comment' + (depth > 0 ? ' reply' : '');
+    commentDiv.id = 'comment-' + comment.id;
     commentDiv.style.marginLeft = (depth * 40) + 'px';
 
     commentDiv.innerHTML = `
@@ -235,6 +237,7 @@ function renderComment(comment, depth, container) {
         <div class="actions">
             <button class="btn btn-reply" onclick="showReplyForm('${comment.id}')">Ответить</button>
             <button class="btn btn-delete" onclick="deleteComment('${comment.id}')">Удалить</button>
+            <button class="btn" onclick="shareComment('${comment.id}')">Поделиться</button>
             ${currentParent === '' ? `<button class="btn btn-view" onclick="viewReplies('${comment.id}')">Показать ответы</button>` : ''}
         </div>
         <div id="reply-form-${comment.id}" class="reply-form hidden">
@@ -253,6 +256,48 @@ function renderComment(comment, depth, container) {
     }
 }
 
+// Постоянная ссылка /c/<id> приводит на /?thread=<корень>&comment=<id>: открываем ветку и выделяем комментарий
+async function openDeepLink() {
+    const params = new URLSearchParams(window.location.search);
+    const thread = params.get('thread');
+    const commentId = params.get('comment');
+    if (!thread) {
+        loadComments();
+        return;
+    }
+
+    // Корень ветки в дереве ответов не показывается, для него открываем список корневых комментариев
+    await loadComments(thread === commentId ? '' : thread);
+    const target = document.getElementById('comment-' + commentId);
+    if (target) {
+        target.classList.add('highlighted');
+        target.scrollIntoView({ behavior: 'smooth', block: 'center' });
+    }
+}
+
+// Короткая ссылка на комментарий через сервис сокращения ссылок
+async function shareComment(commentId) {
+    try {
+        const response = await fetch(`/comments/${commentId}/share`, {
+            method: 'POST',
+            headers: userHeaders({})
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.error || `HTTP error! status: ${response.status}`);
+        }
+
+        const link = data.short_url || data.permalink;
+        if (navigator.clipboard) {
+            await navigator.clipboard.writeText(link).catch(() => {});
+        }
+        prompt('Ссылка на комментарий:', link);
+    } catch (error) {
+        console.error('Ошибка при создании ссылки:', error);
+        alert('Не удалось создать ссылку: ' + error.message);
+    }
+}
+
 // Просмотр ответов
 function viewReplies(commentId) {
     console.log('Viewing replies for:', commentId);
@@ -294,9 +339,9 @@ async function createComment() {
 
         const response = await fetch('/comments', {
             method: 'POST',
-            headers: {
+            headers: userHeaders({
                 'Content-Type': 'application/json',
-            },
+            }),
             body: JSON.stringify(requestBody)
         });
 
@@ -369,9 +414,9 @@ async function createReply(parentId) {
     try {
         const response = await fetch('/comments', {
             method: 'POST',
-            headers: {
+            headers: userHeaders({
                 'Content-Type': 'application/json',
-            },
+            }),
             body: JSON.stringify({
                 author: author,
                 text: text,
@@ -395,6 +440,14 @@ async function createReply(parentId) {
 }
 
 // Удаление комментария
+// Сервер определяет пользователя по подписанному токену; его сохраняет страница входа шлюза.
+// Без токена комментарии только читаются
+function userHeaders(headers) {
+    const token = localStorage.getItem('authToken');
+    if (token) headers['Authorization'] = 'Bearer ' + token;
+    return headers;
+}
+
 async function deleteComment(commentId) {
     if (!confirm('Удалить комментарий и все ответы?')) {
         return;
@@ -402,7 +455,8 @@ async function deleteComment(commentId) {
 
     try {
         const response = await fetch('/comments/' + commentId, {
-            method: 'DELETE'
+            method: 'DELETE',
+            headers: userHeaders({})
         });
 
         if (response.ok) {
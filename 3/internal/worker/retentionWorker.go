@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/3/internal/entity"
+	"github.com/ds124wfegd/WB_L3/3/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetentionWorker периодически применяет политику хранения, чтобы объём данных
+// комментариев в Redis не рос бесконечно
+type RetentionWorker struct {
+	service  *service.CommentService
+	policy   entity.RetentionPolicy
+	interval time.Duration
+
+	updates chan retentionSettings
+}
+
+type retentionSettings struct {
+	policy   entity.RetentionPolicy
+	interval time.Duration
+}
+
+func NewRetentionWorker(service *service.CommentService, policy entity.RetentionPolicy, interval time.Duration) *RetentionWorker {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	return &RetentionWorker{
+		service:  service,
+		policy:   policy,
+		interval: interval,
+		updates:  make(chan retentionSettings, 1),
+	}
+}
+
+// Update меняет политику и интервал с ближайшего тика; вызывается при перезагрузке конфигурации.
+// Непрочитанные прежние настройки заменяются, так что worker получит только последние.
+func (w *RetentionWorker) Update(policy entity.RetentionPolicy, interval time.Duration) {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	select {
+	case <-w.updates:
+	default:
+	}
+	w.updates <- retentionSettings{policy: policy, interval: interval}
+}
+
+func (w *RetentionWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	logrus.Infof("Retention worker started: %s threads inactive for %d months every %s (dry run: %t)",
+		w.policy.Action, w.policy.InactiveMonths, w.interval, w.policy.DryRun)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Retention worker stopped")
+			return
+		case settings := <-w.updates:
+			if settings.interval != w.interval {
+				ticker.Reset(settings.interval)
+			}
+			w.policy, w.interval = settings.policy, settings.interval
+			logrus.Infof("Retention worker updated: %s threads inactive for %d months every %s (dry run: %t)",
+				w.policy.Action, w.policy.InactiveMonths, w.interval, w.policy.DryRun)
+		case <-ticker.C:
+			w.run()
+		}
+	}
+}
+
+func (w *RetentionWorker) run() {
+	report, err := w.service.ApplyRetention(w.policy)
+	if err != nil {
+		logrus.Errorf("Retention run failed: %v", err)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"action":   report.Policy.Action,
+		"dry_run":  report.Policy.DryRun,
+		"scanned":  report.ThreadsScanned,
+		"matched":  report.ThreadsMatched,
+		"applied":  report.ThreadsApplied,
+		"comments": report.Comments,
+		"bytes":    report.Bytes,
+		"errors":   len(report.Errors),
+	}).Info("Retention run finished")
+}
@@ -1,14 +1,75 @@
-package main
-
-import (
-	"github.com/ds124wfegd/WB_L3/4/config"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
-)
-
-func main() {
-	processor.StartImageProcessorConsumer(
-		[]string{config.GetEnv("KAFKA_BROKERS", "localhost:9094")},
-		config.GetEnv("KAFKA_TOPIC", "images"),
-		config.GetEnv("KAFKA_GROUP_ID", "image-processor-service"),
-	)
-}
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/scaling"
+)
+
+func main() {
+	brokers := config.GetEnv("KAFKA_BROKERS", "localhost:9094")
+
+	// Из общего config.yaml процессору нужны профили кодирования и настройки масштабирования
+	viperInstance, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Cannot load config: %v", err)
+	}
+	cfg, err := config.ParseConfig(viperInstance)
+	if err != nil {
+		log.Fatalf("Cannot parse config: %v", err)
+	}
+	encoders, err := processor.NewEncoderProfiles(cfg.Encoder)
+	if err != nil {
+		log.Fatalf("Invalid encoder profiles: %v", err)
+	}
+	log.Printf("Encoder profiles: %v", encoders.Names())
+
+	results := kafka.NewProducer(brokers)
+	defer results.Close()
+
+	topic := config.GetEnv("KAFKA_TOPIC", contract.TopicImageTasks)
+	groupID := config.GetEnv("KAFKA_GROUP_ID", "image-processor-service")
+
+	// SCALING_PAUSED=true запускает реплику на паузе, не меняя общий config.yaml
+	paused := cfg.Scaling.Paused || config.GetEnv("SCALING_PAUSED", "") == "true"
+	monitor := scaling.NewMonitor(topic, groupID, func(ctx context.Context) (*kafka.GroupLag, error) {
+		return kafka.FetchGroupLag(ctx, []string{brokers}, topic, groupID)
+	}, cfg.Scaling.LagInterval, paused)
+	go monitor.Run(context.Background())
+
+	if cfg.Scaling.Listen != "" {
+		go func() {
+			log.Printf("Scaling signals served on %s", cfg.Scaling.Listen)
+			if err := http.ListenAndServe(cfg.Scaling.Listen, monitor.Handler()); err != nil {
+				log.Printf("Scaling signals server stopped: %v", err)
+			}
+		}()
+	}
+
+	resultsTopic := config.GetEnv("KAFKA_RESULTS_TOPIC", contract.TopicImageResults)
+	deliveriesTopic := config.GetEnv("KAFKA_DELIVERIES_TOPIC", contract.TopicImageDeliveries)
+
+	// Выгрузка во внешние хранилища идёт в своей группе и не задерживает обработку
+	go processor.StartDeliveryConsumer(context.Background(), []string{brokers},
+		deliveriesTopic,
+		resultsTopic,
+		config.GetEnv("KAFKA_DELIVERY_GROUP_ID", "image-delivery-service"),
+		results)
+
+	processor.StartImageProcessorConsumer(
+		[]string{brokers},
+		topic,
+		resultsTopic,
+		deliveriesTopic,
+		groupID,
+		results,
+		encoders,
+		monitor,
+	)
+}
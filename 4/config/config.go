@@ -1,66 +1,121 @@
-// Ininicializing common application configuration
-package config
-
-import (
-	"log"
-	"os"
-	"time"
-
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	App    AppConfig    `mapstructure:"app"`
-}
-
-type ServerConfig struct {
-	AppVersion   string `json:"appVersion"`
-	Host         string `json:"host" validate:"required"`
-	Port         string `json:"port" validate:"required"`
-	Timeout      time.Duration
-	Idle_timeout time.Duration
-	Env          string `json:"environment"`
-	Mode         string `mapstructure:"mode"`
-}
-
-type AppConfig struct {
-	ShortURLLength int           `mapstructure:"short_url_length"`
-	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
-	BaseURL        string        `mapstructure:"base_url"`
-}
-
-func LoadConfig() (*viper.Viper, error) {
-
-	viperInstance := viper.New()
-
-	viperInstance.AddConfigPath("./config")
-	viperInstance.SetConfigName("config")
-	viperInstance.SetConfigType("yaml")
-
-	err := viperInstance.ReadInConfig()
-
-	if err != nil {
-		return nil, err
-	}
-	return viperInstance, nil
-}
-
-func ParseConfig(v *viper.Viper) (*Config, error) {
-
-	var c Config
-
-	err := v.Unmarshal(&c)
-	if err != nil {
-		log.Fatalf("unable to decode config into struct, %v", err)
-		return nil, err
-	}
-	return &c, nil
-}
-
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+// Ininicializing common application configuration
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Server     ServerConfig     `mapstructure:"server"`
+	App        AppConfig        `mapstructure:"app"`
+	Encoder    EncoderConfig    `mapstructure:"encoder"`
+	Similarity SimilarityConfig `mapstructure:"similarity"`
+	Scaling    ScalingConfig    `mapstructure:"scaling"`
+	Tenants    []TenantConfig   `mapstructure:"tenants"`
+}
+
+type ServerConfig struct {
+	AppVersion   string `json:"appVersion"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required"`
+	Timeout      time.Duration
+	Idle_timeout time.Duration
+	Env          string `json:"environment"`
+	Mode         string `mapstructure:"mode"`
+}
+
+type AppConfig struct {
+	ShortURLLength int           `mapstructure:"short_url_length"`
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	BaseURL        string        `mapstructure:"base_url"`
+}
+
+// EncoderConfig - именованные профили кодирования вариантов изображения.
+// Operations задаёт профиль по умолчанию для типа операции (resize, thumbnail, watermark),
+// остальные операции кодируются профилем DefaultProfile
+type EncoderConfig struct {
+	DefaultProfile string                    `mapstructure:"default_profile"`
+	Operations     map[string]string         `mapstructure:"operations"`
+	Profiles       map[string]EncoderProfile `mapstructure:"profiles"`
+}
+
+type EncoderProfile struct {
+	JPEG JPEGEncoderConfig `mapstructure:"jpeg"`
+	PNG  PNGEncoderConfig  `mapstructure:"png"`
+	WebP WebPEncoderConfig `mapstructure:"webp"`
+}
+
+type JPEGEncoderConfig struct {
+	Quality     int  `mapstructure:"quality"`
+	Progressive bool `mapstructure:"progressive"`
+}
+
+type PNGEncoderConfig struct {
+	// Compression: default, none, best_speed, best_compression
+	Compression string `mapstructure:"compression"`
+}
+
+type WebPEncoderConfig struct {
+	Lossless bool `mapstructure:"lossless"`
+	Quality  int  `mapstructure:"quality"`
+}
+
+// SimilarityConfig - поиск похожих изображений по перцептивному хешу
+type SimilarityConfig struct {
+	// MaxDistance - порог расстояния Хэмминга между pHash по умолчанию, из 64 бит
+	MaxDistance int `mapstructure:"max_distance"`
+}
+
+// ScalingConfig - сигналы процессора для автоскейлера, см. пакет scaling
+type ScalingConfig struct {
+	Listen      string        `mapstructure:"listen"`       // адрес HTTP с /scaling и /metrics, пусто - отключено
+	LagInterval time.Duration `mapstructure:"lag_interval"` // как часто опрашивать смещения группы
+	// Paused - процессор запускается, не беря задачи, пока его не возобновят через POST /scaling/resume
+	Paused bool `mapstructure:"paused"`
+}
+
+// TenantConfig - арендатор и SHA-256 его ключа API в hex (echo -n "$KEY" | sha256sum);
+// сам ключ в конфигурации не хранится
+type TenantConfig struct {
+	ID           string `mapstructure:"id"`
+	APIKeySHA256 string `mapstructure:"api_key_sha256"`
+}
+
+func LoadConfig() (*viper.Viper, error) {
+
+	viperInstance := viper.New()
+
+	viperInstance.AddConfigPath("./config")
+	viperInstance.SetConfigName("config")
+	viperInstance.SetConfigType("yaml")
+
+	err := viperInstance.ReadInConfig()
+
+	if err != nil {
+		return nil, err
+	}
+	return viperInstance, nil
+}
+
+func ParseConfig(v *viper.Viper) (*Config, error) {
+
+	var c Config
+
+	err := v.Unmarshal(&c)
+	if err != nil {
+		log.Fatalf("unable to decode config into struct, %v", err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -1,84 +1,148 @@
-// launching the server, DB, kafka, postgres
-package appServer
-
-import (
-	"context"
-	"crypto/tls"
-	"log"
-
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/4/config"
-	"github.com/ds124wfegd/WB_L3/4/internal/database"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
-	"github.com/ds124wfegd/WB_L3/4/internal/service"
-	"github.com/ds124wfegd/WB_L3/4/internal/transport"
-	"github.com/gin-gonic/gin"
-
-	"github.com/sirupsen/logrus"
-)
-
-type Server struct {
-	httpServer *http.Server
-}
-
-func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:              ":" + cfg.Server.Port,
-		Handler:           handler,
-		MaxHeaderBytes:    1 << 20,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      cfg.Server.Timeout,
-		IdleTimeout:       cfg.Server.Idle_timeout,
-		ReadHeaderTimeout: 3 * time.Second,
-		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
-		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
-	}
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-func NewServer(cfg *config.Config) {
-
-	logrus.SetFormatter(new(logrus.JSONFormatter))
-
-	fileStorage := storage.NewFileStorage("./storage")
-	imgRepo := database.NewImageRepository(fileStorage)
-	kafkaProducer := kafka.NewProducer("kafka:9092")
-	imgProcessor := processor.NewImageProcessor()
-	imgService := service.NewImageService(imgRepo, kafkaProducer, imgProcessor)
-	imgHandler := transport.NewImageHandler(imgService)
-
-	if cfg.Server.Mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	srv := new(Server)
-	go func() {
-		if err := srv.Run(cfg, transport.InitRoutes(imgHandler)); err != nil {
-			logrus.Fatalf("error occured while running http server: %s", err.Error())
-		}
-	}()
-
-	logrus.Print("App Started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
-
-	logrus.Print("App Shutting Down")
-
-	if err := srv.Shutdown(context.Background()); err != nil {
-		logrus.Errorf("error occured on server shutting down: %s", err.Error())
-	}
-
-}
+// launching the server, DB, kafka, postgres
+package appServer
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/database"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/secret"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+	"github.com/ds124wfegd/WB_L3/4/internal/service"
+	"github.com/ds124wfegd/WB_L3/4/internal/transport"
+	"github.com/gin-gonic/gin"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Server struct {
+	httpServer *http.Server
+}
+
+func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
+	s.httpServer = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		MaxHeaderBytes:    1 << 20,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      cfg.Server.Timeout,
+		IdleTimeout:       cfg.Server.Idle_timeout,
+		ReadHeaderTimeout: 3 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
+		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func NewServer(cfg *config.Config) {
+
+	logrus.SetFormatter(new(logrus.JSONFormatter))
+
+	fileStorage := storage.NewFileStorage("./storage")
+	imgRepo := database.NewImageRepository(fileStorage)
+	kafkaBrokers := config.GetEnv("KAFKA_BROKERS", "kafka:9092")
+	kafkaProducer := kafka.NewProducer(kafkaBrokers)
+	watermarkRepo := database.NewWatermarkRepository(fileStorage)
+	destinationRepo := database.NewDestinationRepository(fileStorage)
+	hashIndex := database.NewHashIndex(fileStorage)
+
+	// Ключ шифрования учётных данных внешних хранилищ; без него выгрузка отключена
+	deliveryCipher, err := secret.NewCipher(config.GetEnv("DELIVERY_ENCRYPTION_KEY", ""))
+	if err != nil {
+		logrus.Warnf("Output delivery disabled: %v", err)
+		deliveryCipher = nil
+	}
+
+	// Профили кодирования проверяются при старте: с ошибкой в конфигурации API не запускается
+	encoders, err := processor.NewEncoderProfiles(cfg.Encoder)
+	if err != nil {
+		logrus.Fatalf("Invalid encoder profiles: %v", err)
+	}
+	logrus.Infof("Encoder profiles: %v", encoders.Names())
+
+	imgService := service.NewImageService(imgRepo, destinationRepo, hashIndex, kafkaProducer, encoders, cfg.Similarity.MaxDistance)
+	imgHandler := transport.NewImageHandler(imgService)
+	watermarkService := service.NewWatermarkService(watermarkRepo)
+	watermarkHandler := transport.NewWatermarkHandler(watermarkService)
+	destinationService := service.NewDestinationService(destinationRepo, deliveryCipher)
+	destinationHandler := transport.NewDestinationHandler(destinationService)
+
+	tenantKeys, err := transport.NewTenantKeys(cfg.Tenants)
+	if err != nil {
+		logrus.Fatalf("Invalid tenants config: %v", err)
+	}
+	if len(tenantKeys) == 0 {
+		logrus.Warn("No tenant API keys configured, watermarks and destinations are unavailable")
+	}
+
+	// Итоги обработки приходят от процессора, который разворачивается отдельно
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go kafka.Consume(ctx, []string{kafkaBrokers},
+		config.GetEnv("KAFKA_RESULTS_TOPIC", contract.TopicImageResults),
+		config.GetEnv("KAFKA_RESULTS_GROUP_ID", "image-api-results"),
+		resultHandler(imgService))
+
+	if cfg.Server.Mode == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	srv := new(Server)
+	go func() {
+		if err := srv.Run(cfg, transport.InitRoutes(imgHandler, watermarkHandler, destinationHandler, tenantKeys)); err != nil {
+			logrus.Fatalf("error occured while running http server: %s", err.Error())
+		}
+	}()
+
+	logrus.Print("App Started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+	<-quit
+
+	logrus.Print("App Shutting Down")
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		logrus.Errorf("error occured on server shutting down: %s", err.Error())
+	}
+
+}
+
+// resultHandler применяет итог обработки или выгрузки; неразбираемые сообщения пропускаются,
+// чтобы не блокировать партицию
+func resultHandler(images service.ImageService) func(value []byte) error {
+	return func(value []byte) error {
+		messageType, err := contract.MessageType(value)
+		if err != nil {
+			return err
+		}
+		if messageType == contract.MessageTypeDeliveryReport {
+			report, err := contract.DecodeDeliveryReport(value)
+			if err != nil {
+				return err
+			}
+			return images.ApplyDeliveries(&report)
+		}
+
+		result, err := contract.DecodeImageResult(value)
+		if err != nil {
+			return err
+		}
+		return images.ApplyResult(&result)
+	}
+}
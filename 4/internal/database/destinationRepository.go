@@ -0,0 +1,104 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+)
+
+func NewDestinationRepository(storage storage.FileStorage) DestinationRepository {
+	return &fileDestinationRepository{storage: storage}
+}
+
+func (r *fileDestinationRepository) Save(destination *entity.Destination) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	destinations, err := r.List(destination.TenantID)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range destinations {
+		if destinations[i].ID == destination.ID {
+			destinations[i] = *destination
+			replaced = true
+		}
+	}
+	if !replaced {
+		destinations = append(destinations, *destination)
+	}
+
+	return r.write(destination.TenantID, destinations)
+}
+
+func (r *fileDestinationRepository) Get(tenantID, id string) (*entity.Destination, error) {
+	destinations, err := r.List(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range destinations {
+		if destinations[i].ID == id {
+			return &destinations[i], nil
+		}
+	}
+	return nil, entity.ErrDestinationNotFound
+}
+
+func (r *fileDestinationRepository) List(tenantID string) ([]entity.Destination, error) {
+	reader, err := r.storage.Get(r.getTenantPath(tenantID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []entity.Destination{}, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	var destinations []entity.Destination
+	if err := json.NewDecoder(reader).Decode(&destinations); err != nil {
+		return nil, err
+	}
+
+	return destinations, nil
+}
+
+func (r *fileDestinationRepository) Delete(tenantID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	destinations, err := r.List(tenantID)
+	if err != nil {
+		return err
+	}
+
+	kept := destinations[:0]
+	for _, destination := range destinations {
+		if destination.ID != id {
+			kept = append(kept, destination)
+		}
+	}
+	if len(kept) == len(destinations) {
+		return entity.ErrDestinationNotFound
+	}
+
+	return r.write(tenantID, kept)
+}
+
+func (r *fileDestinationRepository) write(tenantID string, destinations []entity.Destination) error {
+	data, err := json.Marshal(destinations)
+	if err != nil {
+		return err
+	}
+	return r.storage.Save(r.getTenantPath(tenantID), bytes.NewReader(data))
+}
+
+func (r *fileDestinationRepository) getTenantPath(tenantID string) string {
+	return filepath.Join("destinations", tenantID+".json")
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+)
+
+// NewHashIndex хранит хеши арендатора одним файлом: поиск похожих всё равно сравнивает
+// исходное изображение со всеми изображениями арендатора
+func NewHashIndex(storage storage.FileStorage) HashIndex {
+	return &fileHashIndex{storage: storage}
+}
+
+func (r *fileHashIndex) Save(tenantID, imageID string, hashes entity.ImageHashes) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index, err := r.List(tenantID)
+	if err != nil {
+		return err
+	}
+	index[imageID] = hashes
+
+	return r.write(tenantID, index)
+}
+
+func (r *fileHashIndex) Delete(tenantID, imageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index, err := r.List(tenantID)
+	if err != nil {
+		return err
+	}
+	if _, ok := index[imageID]; !ok {
+		return nil
+	}
+	delete(index, imageID)
+
+	return r.write(tenantID, index)
+}
+
+func (r *fileHashIndex) List(tenantID string) (map[string]entity.ImageHashes, error) {
+	reader, err := r.storage.Get(r.getTenantPath(tenantID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]entity.ImageHashes{}, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	index := make(map[string]entity.ImageHashes)
+	if err := json.NewDecoder(reader).Decode(&index); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func (r *fileHashIndex) write(tenantID string, index map[string]entity.ImageHashes) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return r.storage.Save(r.getTenantPath(tenantID), bytes.NewReader(data))
+}
+
+func (r *fileHashIndex) getTenantPath(tenantID string) string {
+	return filepath.Join("hashes", tenantID+".json")
+}
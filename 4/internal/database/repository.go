@@ -1,20 +1,60 @@
-package database
-
-import (
-	"io"
-
-	"github.com/ds124wfegd/WB_L3/4/internal/entity"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
-)
-
-type ImageRepository interface {
-	Save(image *entity.Image) error
-	FindByID(id string) (*entity.Image, error)
-	Delete(id string) error
-	SaveFile(id string, format string, file io.Reader) error
-	GetFilePath(id string, format string) string
-}
-
-type fileImageRepository struct {
-	storage storage.FileStorage
-}
+package database
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+)
+
+type ImageRepository interface {
+	Save(image *entity.Image) error
+	FindByID(id string) (*entity.Image, error)
+	Delete(id string) error
+	SaveFile(id string, format string, file io.Reader) error
+	GetFilePath(id string, format string) string
+}
+
+type fileImageRepository struct {
+	storage storage.FileStorage
+}
+
+// WatermarkRepository хранит версии водяных знаков арендаторов
+type WatermarkRepository interface {
+	Save(config *entity.WatermarkConfig, mark io.Reader, ext string) error
+	Latest(tenantID string) (*entity.WatermarkConfig, error)
+	Get(tenantID string, version int) (*entity.WatermarkConfig, error)
+	List(tenantID string) ([]entity.WatermarkConfig, error)
+	OpenImage(config *entity.WatermarkConfig) (io.ReadCloser, error)
+}
+
+type fileWatermarkRepository struct {
+	storage storage.FileStorage
+	mu      sync.Mutex
+}
+
+// HashIndex хранит перцептивные хеши обработанных изображений по арендаторам
+type HashIndex interface {
+	Save(tenantID, imageID string, hashes entity.ImageHashes) error
+	Delete(tenantID, imageID string) error
+	List(tenantID string) (map[string]entity.ImageHashes, error)
+}
+
+type fileHashIndex struct {
+	storage storage.FileStorage
+	mu      sync.Mutex
+}
+
+// DestinationRepository хранит назначения выгрузки арендаторов
+type DestinationRepository interface {
+	Save(destination *entity.Destination) error
+	Get(tenantID, id string) (*entity.Destination, error)
+	List(tenantID string) ([]entity.Destination, error)
+	Delete(tenantID, id string) error
+}
+
+type fileDestinationRepository struct {
+	storage storage.FileStorage
+	mu      sync.Mutex
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+)
+
+func NewWatermarkRepository(storage storage.FileStorage) WatermarkRepository {
+	return &fileWatermarkRepository{storage: storage}
+}
+
+// Save присваивает конфигурации следующий номер версии. Файл водяного знака
+// записывается раньше индекса, чтобы индекс никогда не ссылался на несуществующий файл.
+func (r *fileWatermarkRepository) Save(config *entity.WatermarkConfig, mark io.Reader, ext string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions, err := r.List(config.TenantID)
+	if err != nil {
+		return err
+	}
+
+	config.Version = 1
+	if len(versions) > 0 {
+		config.Version = versions[len(versions)-1].Version + 1
+	}
+	config.CreatedAt = time.Now().UTC()
+
+	if mark != nil {
+		config.ImageFile = filepath.Join(r.getTenantDir(config.TenantID), fmt.Sprintf("v%d%s", config.Version, ext))
+		if err := r.storage.Save(config.ImageFile, mark); err != nil {
+			return err
+		}
+	}
+
+	versions = append(versions, *config)
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+
+	return r.storage.Save(r.getIndexPath(config.TenantID), bytes.NewReader(data))
+}
+
+func (r *fileWatermarkRepository) Latest(tenantID string) (*entity.WatermarkConfig, error) {
+	versions, err := r.List(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, entity.ErrWatermarkNotFound
+	}
+
+	latest := versions[len(versions)-1]
+	return &latest, nil
+}
+
+func (r *fileWatermarkRepository) Get(tenantID string, version int) (*entity.WatermarkConfig, error) {
+	versions, err := r.List(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		if versions[i].Version == version {
+			return &versions[i], nil
+		}
+	}
+	return nil, entity.ErrWatermarkNotFound
+}
+
+func (r *fileWatermarkRepository) List(tenantID string) ([]entity.WatermarkConfig, error) {
+	reader, err := r.storage.Get(r.getIndexPath(tenantID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []entity.WatermarkConfig{}, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	var versions []entity.WatermarkConfig
+	if err := json.NewDecoder(reader).Decode(&versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (r *fileWatermarkRepository) OpenImage(config *entity.WatermarkConfig) (io.ReadCloser, error) {
+	if config.ImageFile == "" {
+		return nil, entity.ErrWatermarkNotFound
+	}
+	return r.storage.Get(config.ImageFile)
+}
+
+func (r *fileWatermarkRepository) getTenantDir(tenantID string) string {
+	return filepath.Join("watermarks", tenantID)
+}
+
+func (r *fileWatermarkRepository) getIndexPath(tenantID string) string {
+	return filepath.Join(r.getTenantDir(tenantID), "versions.json")
+}
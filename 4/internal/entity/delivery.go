@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+)
+
+const (
+	DestinationS3   = "s3"
+	DestinationSFTP = "sftp"
+)
+
+const (
+	DeliveryPending   = contract.DeliveryPending
+	DeliveryDelivered = contract.DeliveryDelivered
+	DeliveryFailed    = contract.DeliveryFailed
+)
+
+var (
+	ErrDestinationNotFound = errors.New("delivery destination not found")
+	ErrInvalidDestination  = errors.New("invalid delivery destination")
+	ErrDeliveryDisabled    = errors.New("delivery is disabled: encryption key is not configured")
+)
+
+// Destination - внешнее хранилище клиента, куда выгружаются готовые варианты.
+// Учётные данные хранятся только в зашифрованном виде.
+type Destination struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name,omitempty"`
+	Endpoint  string    `json:"endpoint"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	UseSSL    bool      `json:"use_ssl,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	EncryptedCredentials string `json:"encrypted_credentials,omitempty"`
+}
+
+// Public возвращает копию без зашифрованных учётных данных для ответа API
+func (d Destination) Public() Destination {
+	d.EncryptedCredentials = ""
+	return d
+}
+
+// DestinationCredentials - расшифрованные секреты: ключи S3 либо пароль/ключ SFTP.
+// HostKey - открытый ключ сервера SFTP в формате authorized_keys, с ним сверяется подключение.
+type DestinationCredentials struct {
+	AccessKey  string `json:"access_key,omitempty"`
+	SecretKey  string `json:"secret_key,omitempty"`
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	HostKey    string `json:"host_key,omitempty"`
+}
+
+// DestinationRequest - данные для регистрации назначения
+type DestinationRequest struct {
+	Type     string `json:"type" binding:"required,oneof=s3 sftp"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint" binding:"required"`
+	Bucket   string `json:"bucket"`
+	Region   string `json:"region"`
+	UseSSL   bool   `json:"use_ssl"`
+	Path     string `json:"path"`
+	Username string `json:"username"`
+
+	DestinationCredentials
+}
+
+// Validate проверяет, что для выбранного типа переданы все обязательные поля
+func (r *DestinationRequest) Validate() error {
+	switch r.Type {
+	case DestinationS3:
+		if r.Bucket == "" || r.AccessKey == "" || r.SecretKey == "" {
+			return ErrInvalidDestination
+		}
+	case DestinationSFTP:
+		if r.Username == "" || r.HostKey == "" || (r.Password == "" && r.PrivateKey == "") {
+			return ErrInvalidDestination
+		}
+	default:
+		return ErrInvalidDestination
+	}
+	return nil
+}
+
+// DeliveryResult - итог выгрузки вариантов изображения в одно назначение
+type DeliveryResult = contract.DeliveryResult
+
+// DeliveryReport - итоги выгрузки, присланные процессором после обработки
+type DeliveryReport = contract.DeliveryReport
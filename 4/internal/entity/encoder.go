@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"errors"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+)
+
+var ErrUnknownEncoderProfile = errors.New("unknown encoder profile")
+
+type EncoderSettings = contract.EncoderSettings
+
+// EncoderSelection - профили кодирования, выбранные при загрузке: Default применяется
+// ко всем операциям, Operations переопределяет его для отдельных типов операций
+type EncoderSelection struct {
+	Default    string
+	Operations map[string]string
+}
+
+// For возвращает профиль, выбранный для типа операции; пустая строка - выбор по конфигурации
+func (s EncoderSelection) For(operation string) string {
+	if profile := s.Operations[operation]; profile != "" {
+		return profile
+	}
+	return s.Default
+}
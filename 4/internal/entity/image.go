@@ -1,30 +1,46 @@
-package entity
-
-type Image struct {
-	ID      string            `json:"id"`
-	Status  string            `json:"status"`
-	Formats map[string]string `json:"formats,omitempty"`
-}
-
-type Operation struct {
-	Type   string `json:"type"`
-	Width  int    `json:"width,omitempty"`
-	Height int    `json:"height,omitempty"`
-	Text   string `json:"text,omitempty"`
-}
-
-type ProcessingTask struct {
-	ImageID    string      `json:"image_id"`
-	Operations []Operation `json:"operations"`
-}
-
-type UploadResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
-}
-
-type ImageResponse struct {
-	ID      string            `json:"id"`
-	Status  string            `json:"status"`
-	Formats map[string]string `json:"formats,omitempty"`
-}
+package entity
+
+import "github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+
+type Image struct {
+	ID         string                      `json:"id"`
+	TenantID   string                      `json:"tenant_id,omitempty"`
+	Status     string                      `json:"status"`
+	Formats    map[string]string           `json:"formats,omitempty"`
+	Watermarks map[string]AppliedWatermark `json:"watermarks,omitempty"`
+	Profiles   map[string]string           `json:"profiles,omitempty"`
+	Deliveries []DeliveryResult            `json:"deliveries,omitempty"`
+	Hashes     *ImageHashes                `json:"hashes,omitempty"`
+	Manifest   *Manifest                   `json:"manifest,omitempty"`
+	Error      string                      `json:"error,omitempty"`
+	// Preview - крошечная копия (data URI), построенная при загрузке для заглушки в интерфейсе
+	Preview string `json:"preview,omitempty"`
+}
+
+// Сообщения между API и процессором описаны в пакете contract
+type (
+	Operation        = contract.Operation
+	ProcessingTask   = contract.ImageTask
+	ProcessingResult = contract.ImageResult
+	Manifest         = contract.Manifest
+	ManifestVariant  = contract.ManifestVariant
+	ManifestSource   = contract.ManifestSource
+)
+
+type UploadResponse struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Preview string `json:"preview,omitempty"`
+}
+
+type ImageResponse struct {
+	ID         string                      `json:"id"`
+	Status     string                      `json:"status"`
+	Formats    map[string]string           `json:"formats,omitempty"`
+	Watermarks map[string]AppliedWatermark `json:"watermarks,omitempty"`
+	Profiles   map[string]string           `json:"profiles,omitempty"`
+	Deliveries []DeliveryResult            `json:"deliveries,omitempty"`
+	Manifest   *Manifest                   `json:"manifest,omitempty"`
+	Error      string                      `json:"error,omitempty"`
+	Preview    string                      `json:"preview,omitempty"`
+}
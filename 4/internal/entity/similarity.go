@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"errors"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+)
+
+type ImageHashes = contract.ImageHashes
+
+var (
+	ErrImageNotFound  = errors.New("image not found")
+	ErrImageNotHashed = errors.New("image has no perceptual hash yet")
+)
+
+// SimilarImage - ранее обработанное изображение того же арендатора, похожее на исходное.
+// Distance - расстояние Хэмминга между pHash, по нему отбираются и сортируются результаты;
+// DHashDistance помогает отличить точный дубль от просто похожего снимка
+type SimilarImage struct {
+	ID            string `json:"id"`
+	Distance      int    `json:"distance"`
+	DHashDistance int    `json:"dhash_distance"`
+}
+
+type SimilarImagesResponse struct {
+	ID          string         `json:"id"`
+	MaxDistance int            `json:"max_distance"`
+	Similar     []SimilarImage `json:"similar"`
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+)
+
+const (
+	// DefaultTenant используется для запросов без ключа API арендатора
+	DefaultTenant = "default"
+	// DefaultWatermark в операции означает "последняя версия водяного знака арендатора"
+	DefaultWatermark = "default"
+)
+
+const (
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+	PositionCenter      = "center"
+)
+
+var (
+	ErrWatermarkNotFound = errors.New("watermark not found")
+	ErrInvalidWatermark  = errors.New("invalid watermark configuration")
+	ErrInvalidTenant     = errors.New("invalid tenant id")
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// WatermarkConfig - неизменяемая версия настроек водяного знака арендатора.
+// Каждая загрузка создаёт новую версию, старые остаются для истории.
+type WatermarkConfig struct {
+	TenantID  string    `json:"tenant_id"`
+	Version   int       `json:"version"`
+	Text      string    `json:"text,omitempty"`
+	ImageFile string    `json:"image_file,omitempty"`
+	Position  string    `json:"position"`
+	Opacity   float64   `json:"opacity"`
+	Scale     float64   `json:"scale"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WatermarkRequest - настройки, которые арендатор передаёт при загрузке
+type WatermarkRequest struct {
+	Text     string  `form:"text"`
+	Position string  `form:"position"`
+	Opacity  float64 `form:"opacity"`
+	Scale    float64 `form:"scale"`
+}
+
+// AppliedWatermark фиксирует, какая версия водяного знака попала в вариант изображения
+type AppliedWatermark = contract.AppliedWatermark
+
+// ApplyDefaults подставляет значения по умолчанию и проверяет настройки
+func (w *WatermarkConfig) ApplyDefaults() error {
+	if w.Position == "" {
+		w.Position = PositionBottomRight
+	}
+	if w.Opacity == 0 {
+		w.Opacity = 0.5
+	}
+	if w.Scale == 0 {
+		w.Scale = 0.2
+	}
+
+	switch w.Position {
+	case PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight, PositionCenter:
+	default:
+		return ErrInvalidWatermark
+	}
+	if w.Opacity < 0 || w.Opacity > 1 || w.Scale < 0 || w.Scale > 1 {
+		return ErrInvalidWatermark
+	}
+	return nil
+}
+
+// ValidTenantID проверяет, что идентификатор арендатора безопасно использовать в пути хранилища
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
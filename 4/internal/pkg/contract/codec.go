@@ -0,0 +1,198 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	MessageTypeImageTask   = "image.task"
+	MessageTypeImageResult = "image.result"
+	// Выгрузка во внешние хранилища выполняется после обработки отдельным сообщением
+	MessageTypeDeliveryTask   = "image.delivery"
+	MessageTypeDeliveryReport = "image.delivery_report"
+)
+
+// Версии схем. Необязательное поле можно добавить без смены версии: старый читатель
+// его проигнорирует. Новая версия нужна, если меняется смысл или обязательность поля;
+// тогда сначала разворачивается потребитель, умеющий её читать, затем отправитель.
+const (
+	// ImageTaskV1 - задача без конверта, как её отправляли до появления контракта
+	ImageTaskV1 = 1
+	// ImageTaskV2 - задача в конверте с типом и версией
+	ImageTaskV2 = 2
+
+	ImageResultV1    = 1
+	DeliveryTaskV1   = 1
+	DeliveryReportV1 = 1
+
+	CurrentImageTaskVersion      = ImageTaskV2
+	CurrentImageResultVersion    = ImageResultV1
+	CurrentDeliveryTaskVersion   = DeliveryTaskV1
+	CurrentDeliveryReportVersion = DeliveryReportV1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported message version")
+	ErrUnexpectedType     = errors.New("unexpected message type")
+)
+
+// Envelope - общая обёртка сообщений
+type Envelope struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version"`
+	SentAt  time.Time       `json:"sent_at"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func EncodeImageTask(task ImageTask) ([]byte, error) {
+	return encode(MessageTypeImageTask, CurrentImageTaskVersion, task)
+}
+
+// DecodeImageTask читает задачу любой поддерживаемой версии и возвращает эту версию
+func DecodeImageTask(data []byte) (ImageTask, int, error) {
+	var task ImageTask
+
+	envelope, err := decode(data, MessageTypeImageTask, CurrentImageTaskVersion)
+	if err != nil {
+		return task, 0, err
+	}
+
+	// Сообщение без конверта - задача версии 1 целиком
+	if envelope == nil {
+		if err := json.Unmarshal(data, &task); err != nil {
+			return task, 0, fmt.Errorf("failed to decode image task v%d: %w", ImageTaskV1, err)
+		}
+		return task, ImageTaskV1, validateImageTask(task)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &task); err != nil {
+		return task, envelope.Version, fmt.Errorf("failed to decode image task v%d: %w", envelope.Version, err)
+	}
+	return task, envelope.Version, validateImageTask(task)
+}
+
+func EncodeImageResult(result ImageResult) ([]byte, error) {
+	return encode(MessageTypeImageResult, CurrentImageResultVersion, result)
+}
+
+func DecodeImageResult(data []byte) (ImageResult, error) {
+	var result ImageResult
+
+	envelope, err := decode(data, MessageTypeImageResult, CurrentImageResultVersion)
+	if err != nil {
+		return result, err
+	}
+	if envelope == nil {
+		return result, fmt.Errorf("%w: image result without envelope", ErrUnsupportedVersion)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &result); err != nil {
+		return result, fmt.Errorf("failed to decode image result v%d: %w", envelope.Version, err)
+	}
+	if result.ImageID == "" {
+		return result, fmt.Errorf("image result without image_id")
+	}
+	return result, nil
+}
+
+func EncodeDeliveryTask(task DeliveryTask) ([]byte, error) {
+	return encode(MessageTypeDeliveryTask, CurrentDeliveryTaskVersion, task)
+}
+
+func DecodeDeliveryTask(data []byte) (DeliveryTask, error) {
+	var task DeliveryTask
+
+	envelope, err := decode(data, MessageTypeDeliveryTask, CurrentDeliveryTaskVersion)
+	if err != nil {
+		return task, err
+	}
+	if envelope == nil {
+		return task, fmt.Errorf("%w: delivery task without envelope", ErrUnsupportedVersion)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &task); err != nil {
+		return task, fmt.Errorf("failed to decode delivery task v%d: %w", envelope.Version, err)
+	}
+	if task.ImageID == "" {
+		return task, fmt.Errorf("delivery task without image_id")
+	}
+	return task, nil
+}
+
+func EncodeDeliveryReport(report DeliveryReport) ([]byte, error) {
+	return encode(MessageTypeDeliveryReport, CurrentDeliveryReportVersion, report)
+}
+
+func DecodeDeliveryReport(data []byte) (DeliveryReport, error) {
+	var report DeliveryReport
+
+	envelope, err := decode(data, MessageTypeDeliveryReport, CurrentDeliveryReportVersion)
+	if err != nil {
+		return report, err
+	}
+	if envelope == nil {
+		return report, fmt.Errorf("%w: delivery report without envelope", ErrUnsupportedVersion)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &report); err != nil {
+		return report, fmt.Errorf("failed to decode delivery report v%d: %w", envelope.Version, err)
+	}
+	if report.ImageID == "" {
+		return report, fmt.Errorf("delivery report without image_id")
+	}
+	return report, nil
+}
+
+// MessageType возвращает тип сообщения в конверте; у сообщения без конверта он пустой.
+// Нужен потребителю топика, в который пишутся сообщения нескольких типов
+func MessageType(data []byte) (string, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", fmt.Errorf("failed to decode message: %w", err)
+	}
+	return envelope.Type, nil
+}
+
+func encode(messageType string, version int, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", messageType, err)
+	}
+
+	return json.Marshal(Envelope{
+		Type:    messageType,
+		Version: version,
+		SentAt:  time.Now().UTC(),
+		Payload: data,
+	})
+}
+
+// decode проверяет конверт; nil без ошибки означает сообщение без конверта
+func decode(data []byte, messageType string, maxVersion int) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	if envelope.Type == "" && envelope.Version == 0 && envelope.Payload == nil {
+		return nil, nil
+	}
+	if envelope.Type != messageType {
+		return nil, fmt.Errorf("%w: %q, want %q", ErrUnexpectedType, envelope.Type, messageType)
+	}
+	if envelope.Version < 1 || envelope.Version > maxVersion {
+		return nil, fmt.Errorf("%w: %s v%d, supported up to v%d", ErrUnsupportedVersion, messageType, envelope.Version, maxVersion)
+	}
+
+	return &envelope, nil
+}
+
+func validateImageTask(task ImageTask) error {
+	if task.ImageID == "" {
+		return fmt.Errorf("image task without image_id")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package contract
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeImageTaskVersions проверяет, что процессор читает и новые, и старые сообщения
+func TestDecodeImageTaskVersions(t *testing.T) {
+	task := ImageTask{
+		ImageID:    "img-1",
+		TenantID:   "acme",
+		Operations: []Operation{{Type: "resize", Width: 800, Height: 600}},
+		Deliveries: []string{"dst-1"},
+	}
+
+	t.Run("current version", func(t *testing.T) {
+		data, err := EncodeImageTask(task)
+		require.NoError(t, err)
+
+		decoded, version, err := DecodeImageTask(data)
+		require.NoError(t, err)
+		assert.Equal(t, CurrentImageTaskVersion, version)
+		assert.Equal(t, task, decoded)
+	})
+
+	t.Run("legacy message without envelope", func(t *testing.T) {
+		legacy := []byte(`{"image_id":"img-1","tenant_id":"acme","operations":[{"type":"resize","width":800,"height":600}],"deliveries":["dst-1"]}`)
+
+		decoded, version, err := DecodeImageTask(legacy)
+		require.NoError(t, err)
+		assert.Equal(t, ImageTaskV1, version)
+		assert.Equal(t, task, decoded)
+	})
+
+	t.Run("unknown fields are ignored", func(t *testing.T) {
+		data := []byte(`{"type":"image.task","version":2,"payload":{"image_id":"img-1","priority":"high"}}`)
+
+		decoded, _, err := DecodeImageTask(data)
+		require.NoError(t, err)
+		assert.Equal(t, "img-1", decoded.ImageID)
+	})
+
+	t.Run("newer version is rejected", func(t *testing.T) {
+		data := []byte(`{"type":"image.task","version":3,"payload":{"image_id":"img-1"}}`)
+
+		_, _, err := DecodeImageTask(data)
+		assert.True(t, errors.Is(err, ErrUnsupportedVersion))
+	})
+
+	t.Run("result is not a task", func(t *testing.T) {
+		data, err := EncodeImageResult(ImageResult{ImageID: "img-1", Status: StatusCompleted})
+		require.NoError(t, err)
+
+		_, _, err = DecodeImageTask(data)
+		assert.True(t, errors.Is(err, ErrUnexpectedType))
+	})
+
+	t.Run("task without image id", func(t *testing.T) {
+		_, _, err := DecodeImageTask([]byte(`{"operations":[]}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestImageResultRoundTrip(t *testing.T) {
+	result := ImageResult{
+		ImageID:    "img-1",
+		Status:     StatusCompleted,
+		Formats:    map[string]string{"thumbnail": "storage/processed/img-1/thumbnail"},
+		Watermarks: map[string]AppliedWatermark{"watermark": {TenantID: "acme", Version: 2}},
+	}
+
+	data, err := EncodeImageResult(result)
+	require.NoError(t, err)
+
+	decoded, err := DecodeImageResult(data)
+	require.NoError(t, err)
+	assert.Equal(t, result.Formats, decoded.Formats)
+	assert.Equal(t, result.Watermarks, decoded.Watermarks)
+	assert.Equal(t, StatusCompleted, decoded.Status)
+}
+
+func TestDeliveryMessagesRoundTrip(t *testing.T) {
+	task := DeliveryTask{
+		ImageID:      "img-1",
+		TenantID:     "acme",
+		Destinations: []string{"dst-1"},
+		Files:        []DeliveryFile{{Name: "thumbnail", Path: "storage/processed/img-1/thumbnail"}},
+	}
+	data, err := EncodeDeliveryTask(task)
+	require.NoError(t, err)
+	decoded, err := DecodeDeliveryTask(data)
+	require.NoError(t, err)
+	assert.Equal(t, task, decoded)
+
+	report := DeliveryReport{ImageID: "img-1", Deliveries: []DeliveryResult{{DestinationID: "dst-1", Status: DeliveryDelivered, Attempts: 1}}}
+	data, err = EncodeDeliveryReport(report)
+	require.NoError(t, err)
+
+	// Отчёт о выгрузке идёт в топик итогов, и потребитель различает сообщения по типу
+	messageType, err := MessageType(data)
+	require.NoError(t, err)
+	assert.Equal(t, MessageTypeDeliveryReport, messageType)
+	_, err = DecodeImageResult(data)
+	assert.ErrorIs(t, err, ErrUnexpectedType)
+
+	decodedReport, err := DecodeDeliveryReport(data)
+	require.NoError(t, err)
+	assert.Equal(t, report, decodedReport)
+}
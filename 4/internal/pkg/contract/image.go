@@ -0,0 +1,150 @@
+// Package contract описывает сообщения между API загрузки и процессором изображений.
+// Оба бинарника собираются из этого пакета, поэтому схема меняется в одном месте,
+// а номер версии в конверте позволяет разворачивать их по отдельности.
+package contract
+
+import "time"
+
+const (
+	// TopicImageTasks - задачи обработки от API к процессору
+	TopicImageTasks = "image-processing"
+	// TopicImageResults - итоги обработки и выгрузки от процессора к API
+	TopicImageResults = "image-results"
+	// TopicImageDeliveries - задачи выгрузки готовых вариантов во внешние хранилища
+	TopicImageDeliveries = "image-deliveries"
+)
+
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+const (
+	// DeliveryPending - выгрузка поставлена в очередь и ещё не выполнена
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type Operation struct {
+	Type   string `json:"type"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Text   string `json:"text,omitempty"`
+	// Watermark ссылается на сохранённый водяной знак арендатора:
+	// "default" - последняя версия, число - конкретная версия
+	Watermark string `json:"watermark,omitempty"`
+	// Profile - профиль кодирования результата; пустой у задач, поставленных
+	// до появления профилей, тогда процессор берёт профиль из своей конфигурации
+	Profile string `json:"profile,omitempty"`
+}
+
+// ImageTask - задача обработки загруженного изображения
+type ImageTask struct {
+	ImageID    string      `json:"image_id"`
+	TenantID   string      `json:"tenant_id,omitempty"`
+	Operations []Operation `json:"operations"`
+	// Deliveries - идентификаторы назначений арендатора, куда выгрузить готовые варианты
+	Deliveries []string `json:"deliveries,omitempty"`
+}
+
+// AppliedWatermark фиксирует, какая версия водяного знака попала в вариант изображения
+type AppliedWatermark struct {
+	TenantID string `json:"tenant_id"`
+	Version  int    `json:"version"`
+}
+
+// DeliveryResult - итог выгрузки вариантов изображения в одно назначение
+type DeliveryResult struct {
+	DestinationID string     `json:"destination_id"`
+	Type          string     `json:"type,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	Files         []string   `json:"files,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// DeliveryFile - готовый файл изображения в общем хранилище
+type DeliveryFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// DeliveryTask - выгрузка готовых вариантов в назначения арендатора. Её выполняет отдельный
+// потребитель, чтобы медленное хранилище клиента не задерживало обработку изображений
+type DeliveryTask struct {
+	ImageID      string         `json:"image_id"`
+	TenantID     string         `json:"tenant_id,omitempty"`
+	Destinations []string       `json:"destinations"`
+	Files        []DeliveryFile `json:"files"`
+}
+
+// DeliveryReport - итоги выгрузки; API заменяет ими статусы pending в метаданных изображения
+type DeliveryReport struct {
+	ImageID    string           `json:"image_id"`
+	Deliveries []DeliveryResult `json:"deliveries"`
+}
+
+// ImageHashes - перцептивные хеши оригинала: 64 бита в шестнадцатеричной записи
+type ImageHashes struct {
+	PHash string `json:"phash"`
+	DHash string `json:"dhash"`
+}
+
+// EncoderSettings - настройки кодировщика, которыми записан вариант
+type EncoderSettings struct {
+	JPEGQuality    int    `json:"jpeg_quality,omitempty"`
+	PNGCompression string `json:"png_compression,omitempty"`
+}
+
+// ManifestVariant описывает файл варианта изображения: по размеру и SHA-256 получатель
+// проверяет целостность, по размерам и формату выбирает подходящий вариант
+type ManifestVariant struct {
+	Name        string          `json:"name"` // resized, thumbnail, watermark
+	File        string          `json:"file"` // имя файла рядом с манифестом
+	Format      string          `json:"format"`
+	ContentType string          `json:"content_type"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Bytes       int64           `json:"bytes"`
+	SHA256      string          `json:"sha256"`
+	Profile     string          `json:"profile"`
+	Encoder     EncoderSettings `json:"encoder"`
+}
+
+// ManifestSource - оригинал, из которого получены варианты
+type ManifestSource struct {
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ManifestFile - имя манифеста в каталоге вариантов изображения и в назначениях выгрузки
+const ManifestFile = "manifest.json"
+
+// Manifest - манифест, который процессор кладёт рядом с вариантами изображения
+type Manifest struct {
+	ImageID     string            `json:"image_id"`
+	TenantID    string            `json:"tenant_id,omitempty"`
+	Source      ManifestSource    `json:"source"`
+	Variants    []ManifestVariant `json:"variants"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// ImageResult - итог обработки, который API записывает в метаданные изображения;
+// Profiles - профиль кодирования, которым записан каждый вариант
+type ImageResult struct {
+	ImageID     string                      `json:"image_id"`
+	TenantID    string                      `json:"tenant_id,omitempty"`
+	Status      string                      `json:"status"`
+	Formats     map[string]string           `json:"formats,omitempty"`
+	Watermarks  map[string]AppliedWatermark `json:"watermarks,omitempty"`
+	Profiles    map[string]string           `json:"profiles,omitempty"`
+	Deliveries  []DeliveryResult            `json:"deliveries,omitempty"`
+	Hashes      *ImageHashes                `json:"hashes,omitempty"`   // нет у процессоров старше поиска похожих
+	Manifest    *Manifest                   `json:"manifest,omitempty"` // нет у процессоров старше манифестов
+	Error       string                      `json:"error,omitempty"`
+	ProcessedAt time.Time                   `json:"processed_at"`
+}
@@ -0,0 +1,190 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/database"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/secret"
+)
+
+// File - готовый вариант изображения в локальном хранилище
+type File struct {
+	Name string
+	Path string
+}
+
+// uploader - подключение к одному назначению на время одной попытки выгрузки
+type uploader interface {
+	Upload(ctx context.Context, key string, r io.Reader, size int64) error
+	Close() error
+}
+
+// permanentError помечает ошибки, которые не исчезнут при повторе:
+// неверные учётные данные, отказ в доступе, несуществующий бакет
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func isTransient(err error) bool {
+	var p *permanentError
+	return !errors.As(err, &p) && !errors.Is(err, context.Canceled)
+}
+
+// Deliverer выгружает варианты изображения во внешние хранилища клиентов.
+// Временные сбои (сеть, 5xx, 429) повторяются с экспоненциальной задержкой.
+type Deliverer struct {
+	destinations database.DestinationRepository
+	cipher       *secret.Cipher
+	maxAttempts  int
+	baseDelay    time.Duration
+}
+
+// NewDeliverer создаёт Deliverer; без cipher все выгрузки завершаются ошибкой ErrDeliveryDisabled
+func NewDeliverer(destinations database.DestinationRepository, cipher *secret.Cipher, maxAttempts int, baseDelay time.Duration) *Deliverer {
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	if baseDelay <= 0 {
+		baseDelay = 2 * time.Second
+	}
+
+	return &Deliverer{
+		destinations: destinations,
+		cipher:       cipher,
+		maxAttempts:  maxAttempts,
+		baseDelay:    baseDelay,
+	}
+}
+
+// Deliver выгружает файлы в назначение арендатора по ключам <path>/<imageID>/<вариант>.
+// Ошибка не возвращается, а записывается в результат, чтобы попасть в метаданные изображения.
+func (d *Deliverer) Deliver(ctx context.Context, tenantID, destinationID, imageID string, files []File) entity.DeliveryResult {
+	result := entity.DeliveryResult{
+		DestinationID: destinationID,
+		Status:        entity.DeliveryFailed,
+	}
+
+	if d.cipher == nil {
+		result.Error = entity.ErrDeliveryDisabled.Error()
+		return result
+	}
+
+	destination, err := d.destinations.Get(tenantID, destinationID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Type = destination.Type
+
+	creds, err := d.credentials(destination)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		result.Attempts = attempt
+
+		keys, err := d.upload(ctx, destination, creds, imageID, files)
+		if err == nil {
+			now := time.Now().UTC()
+			result.Status = entity.DeliveryDelivered
+			result.Files = keys
+			result.DeliveredAt = &now
+			return result
+		}
+
+		lastErr = err
+		if !isTransient(err) || attempt == d.maxAttempts {
+			break
+		}
+
+		delay := d.baseDelay * time.Duration(1<<(attempt-1))
+		select {
+		case <-ctx.Done():
+			result.Error = ctx.Err().Error()
+			return result
+		case <-time.After(delay):
+		}
+	}
+
+	result.Error = lastErr.Error()
+	return result
+}
+
+func (d *Deliverer) credentials(destination *entity.Destination) (*entity.DestinationCredentials, error) {
+	plaintext, err := d.cipher.Decrypt(destination.EncryptedCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	var creds entity.DestinationCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return &creds, nil
+}
+
+// upload выполняет одну попытку: подключается и выгружает все файлы.
+// Повтор перезаписывает уже выгруженные файлы, поэтому он безопасен.
+func (d *Deliverer) upload(ctx context.Context, destination *entity.Destination, creds *entity.DestinationCredentials, imageID string, files []File) ([]string, error) {
+	var up uploader
+	var err error
+	switch destination.Type {
+	case entity.DestinationS3:
+		up, err = newS3Uploader(destination, creds)
+	case entity.DestinationSFTP:
+		up, err = newSFTPUploader(destination, creds)
+	default:
+		err = permanent(entity.ErrInvalidDestination)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer up.Close()
+
+	keys := make([]string, 0, len(files))
+	for _, f := range files {
+		key := path.Join(destination.Path, imageID, f.Name)
+		if err := uploadFile(ctx, up, key, f.Path); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", f.Name, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func uploadFile(ctx context.Context, up uploader, key, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return permanent(err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return permanent(err)
+	}
+
+	return up.Upload(ctx, key, file, info.Size())
+}
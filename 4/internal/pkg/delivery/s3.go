@@ -0,0 +1,58 @@
+package delivery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// s3Uploader работает с любым S3-совместимым хранилищем (AWS, MinIO, Yandex Object Storage)
+type s3Uploader struct {
+	client *minio.Client
+	bucket string
+}
+
+func newS3Uploader(destination *entity.Destination, creds *entity.DestinationCredentials) (uploader, error) {
+	if err := s3utils.CheckValidBucketName(destination.Bucket); err != nil {
+		return nil, permanent(err)
+	}
+
+	client, err := minio.New(destination.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(creds.AccessKey, creds.SecretKey, ""),
+		Secure: destination.UseSSL,
+		Region: destination.Region,
+		// повторы выполняет Deliverer, встроенные повторы клиента их бы умножили
+		MaxRetries: 1,
+	})
+	if err != nil {
+		return nil, permanent(err)
+	}
+
+	return &s3Uploader{client: client, bucket: destination.Bucket}, nil
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := u.client.PutObject(ctx, u.bucket, strings.TrimPrefix(key, "/"), r, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err == nil {
+		return nil
+	}
+
+	// 4xx, кроме таймаута и троттлинга, означают ошибку конфигурации назначения
+	status := minio.ToErrorResponse(err).StatusCode
+	if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+		return permanent(err)
+	}
+	return err
+}
+
+func (u *s3Uploader) Close() error {
+	return nil
+}
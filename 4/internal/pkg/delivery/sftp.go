@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const sftpDialTimeout = 15 * time.Second
+
+type sftpUploader struct {
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// newSFTPUploader подключается только к серверу с ключом, сохранённым при регистрации назначения
+func newSFTPUploader(destination *entity.Destination, creds *entity.DestinationCredentials) (uploader, error) {
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(creds.HostKey))
+	if err != nil {
+		return nil, permanent(err)
+	}
+
+	var auth []ssh.AuthMethod
+	if creds.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(creds.PrivateKey))
+		if err != nil {
+			return nil, permanent(err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if creds.Password != "" {
+		auth = append(auth, ssh.Password(creds.Password))
+	}
+
+	addr := destination.Endpoint
+	if !strings.Contains(addr, ":") {
+		addr += ":22"
+	}
+
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            destination.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         sftpDialTimeout,
+	})
+	if err != nil {
+		// ошибки аутентификации и несовпадение ключа хоста повтором не исправить
+		if strings.Contains(err.Error(), "unable to authenticate") || strings.Contains(err.Error(), "host key mismatch") {
+			return nil, permanent(err)
+		}
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &sftpUploader{conn: conn, client: client}, nil
+}
+
+func (u *sftpUploader) Upload(ctx context.Context, key string, r io.Reader, size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := u.client.MkdirAll(path.Dir(key)); err != nil {
+		return classifySFTPError(err)
+	}
+
+	file, err := u.client.Create(key)
+	if err != nil {
+		return classifySFTPError(err)
+	}
+
+	if _, err := file.ReadFrom(r); err != nil {
+		file.Close()
+		return classifySFTPError(err)
+	}
+
+	return classifySFTPError(file.Close())
+}
+
+func (u *sftpUploader) Close() error {
+	u.client.Close()
+	return u.conn.Close()
+}
+
+func classifySFTPError(err error) error {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+		return permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package kafka
+
+import (
+	"context"
+	"log"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consume читает topic в составе группы groupID, пока не отменён ctx.
+// Смещение фиксируется только после handle, поэтому при рестарте сообщение
+// будет прочитано повторно, а не потеряно; handle должен быть идемпотентным.
+func Consume(ctx context.Context, brokers []string, topic, groupID string, handle func(value []byte) error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  brokers,
+		Topic:    topic,
+		GroupID:  groupID,
+		MinBytes: 1,
+		MaxBytes: 10e6, // 10MB
+	})
+	defer reader.Close()
+
+	log.Printf("Consuming %s as %s", topic, groupID)
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error reading message from %s: %v", topic, err)
+			continue
+		}
+
+		if err := handle(msg.Value); err != nil {
+			log.Printf("Failed to handle message from %s [partition %d, offset %d]: %v",
+				msg.Topic, msg.Partition, msg.Offset, err)
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to commit offset %d of %s: %v", msg.Offset, topic, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PartitionLag - отставание группы на одной партиции
+type PartitionLag struct {
+	Partition int   `json:"partition"`
+	Committed int64 `json:"committed"` // -1, если группа ещё ничего не фиксировала
+	Latest    int64 `json:"latest"`    // смещение следующего записанного сообщения
+	Lag       int64 `json:"lag"`
+}
+
+// GroupLag - сколько сообщений topic группа ещё не обработала
+type GroupLag struct {
+	Topic      string         `json:"topic"`
+	GroupID    string         `json:"group_id"`
+	Total      int64          `json:"total"`
+	Partitions []PartitionLag `json:"partitions"`
+}
+
+// FetchGroupLag сравнивает зафиксированные смещения группы с концом каждой партиции topic
+func FetchGroupLag(ctx context.Context, brokers []string, topic, groupID string) (*GroupLag, error) {
+	client := &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second}
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	if len(metadata.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if metadata.Topics[0].Error != nil {
+		return nil, fmt.Errorf("topic %s: %w", topic, metadata.Topics[0].Error)
+	}
+
+	partitions := make([]int, 0, len(metadata.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, 0, 2*len(metadata.Topics[0].Partitions))
+	for _, p := range metadata.Topics[0].Partitions {
+		partitions = append(partitions, p.ID)
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", committed.Error)
+	}
+
+	committedBy := make(map[int]int64)
+	for _, p := range committed.Topics[topic] {
+		if p.Error == nil {
+			committedBy[p.Partition] = p.CommittedOffset
+		}
+	}
+
+	lag := &GroupLag{Topic: topic, GroupID: groupID, Partitions: make([]PartitionLag, 0, len(partitions))}
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", p.Partition, p.Error)
+		}
+
+		c, ok := committedBy[p.Partition]
+		if !ok {
+			c = -1
+		}
+		partition := PartitionLag{
+			Partition: p.Partition,
+			Committed: c,
+			Latest:    p.LastOffset,
+			Lag:       partitionLag(p.FirstOffset, p.LastOffset, c),
+		}
+		lag.Partitions = append(lag.Partitions, partition)
+		lag.Total += partition.Lag
+	}
+
+	return lag, nil
+}
+
+// partitionLag считает отставание так же, как читает процессор: без зафиксированного смещения
+// группа начинает с первого сохранённого сообщения (StartOffset: FirstOffset), а смещение,
+// которое уже удалено по retention, тоже означает чтение с начала
+func partitionLag(first, latest, committed int64) int64 {
+	if committed < first {
+		committed = first
+	}
+	if committed > latest {
+		return 0
+	}
+	return latest - committed
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/database"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/delivery"
+	producer "github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/secret"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+)
+
+// deliveryTimeout ограничивает выгрузку в одно назначение вместе со всеми повторами
+const deliveryTimeout = 5 * time.Minute
+
+// deliverer выгружает файлы в одно назначение арендатора (см. delivery.Deliverer)
+type deliverer interface {
+	Deliver(ctx context.Context, tenantID, destinationID, imageID string, files []delivery.File) entity.DeliveryResult
+}
+
+// StartDeliveryConsumer выполняет задачи выгрузки из topic и отправляет итоги в resultsTopic.
+// У выгрузки своя группа потребителей: медленное хранилище клиента задерживает только
+// следующие выгрузки, а не обработку изображений. Смещение фиксируется после выгрузки,
+// поэтому прерванная выгрузка повторится на другой реплике.
+func StartDeliveryConsumer(ctx context.Context, brokers []string, topic, resultsTopic, groupID string, results producer.Producer) {
+	fileStorage := storage.NewFileStorage("./storage")
+	uploader := delivery.NewDeliverer(database.NewDestinationRepository(fileStorage), deliveryCipher(), 3, 2*time.Second)
+
+	producer.Consume(ctx, brokers, topic, groupID, func(value []byte) error {
+		task, err := contract.DecodeDeliveryTask(value)
+		if err != nil {
+			return err
+		}
+		publishDeliveryReport(results, resultsTopic, deliver(ctx, uploader, task))
+		return nil
+	})
+}
+
+// deliver выгружает файлы задачи во все её назначения. Сбой выгрузки не делает обработку
+// неуспешной: он фиксируется в метаданных изображения.
+func deliver(ctx context.Context, uploader deliverer, task contract.DeliveryTask) contract.DeliveryReport {
+	files := make([]delivery.File, 0, len(task.Files))
+	for _, file := range task.Files {
+		files = append(files, delivery.File{Name: file.Name, Path: file.Path})
+	}
+
+	tenantID := task.TenantID
+	if tenantID == "" {
+		tenantID = entity.DefaultTenant
+	}
+
+	report := contract.DeliveryReport{
+		ImageID:    task.ImageID,
+		Deliveries: make([]entity.DeliveryResult, 0, len(task.Destinations)),
+	}
+	for _, destinationID := range task.Destinations {
+		deliveryCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		result := uploader.Deliver(deliveryCtx, tenantID, destinationID, task.ImageID, files)
+		cancel()
+
+		if result.Status != entity.DeliveryDelivered {
+			log.Printf("Delivery of %s to %s failed after %d attempts: %s",
+				task.ImageID, destinationID, result.Attempts, result.Error)
+		}
+		report.Deliveries = append(report.Deliveries, result)
+	}
+
+	return report
+}
+
+// pendingDeliveries - статусы назначений задачи до выгрузки; без готовых вариантов выгружать нечего
+func pendingDeliveries(task entity.ProcessingTask, results map[string]string) []entity.DeliveryResult {
+	if len(task.Deliveries) == 0 || len(results) == 0 {
+		return nil
+	}
+
+	deliveries := make([]entity.DeliveryResult, 0, len(task.Deliveries))
+	for _, destinationID := range task.Deliveries {
+		deliveries = append(deliveries, entity.DeliveryResult{DestinationID: destinationID, Status: entity.DeliveryPending})
+	}
+	return deliveries
+}
+
+// deliveryFiles - готовые варианты и манифест рядом с ними, если его удалось записать
+func deliveryFiles(formats map[string]string) []contract.DeliveryFile {
+	files := make([]contract.DeliveryFile, 0, len(formats)+1)
+	for name, path := range formats {
+		files = append(files, contract.DeliveryFile{Name: name, Path: path})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	if len(files) > 0 {
+		manifestPath := filepath.Join(filepath.Dir(files[0].Path), contract.ManifestFile)
+		if _, err := os.Stat(manifestPath); err == nil {
+			files = append(files, contract.DeliveryFile{Name: contract.ManifestFile, Path: manifestPath})
+		}
+	}
+	return files
+}
+
+// enqueueDelivery ставит выгрузку готовых вариантов в очередь. Если поставить её не удалось,
+// назначения сразу помечаются неуспешными, чтобы не остаться в pending
+func enqueueDelivery(results producer.Producer, topic, resultsTopic string, task entity.ProcessingTask, result *entity.ProcessingResult) {
+	if len(result.Deliveries) == 0 {
+		return
+	}
+
+	message, err := contract.EncodeDeliveryTask(contract.DeliveryTask{
+		ImageID:      task.ImageID,
+		TenantID:     task.TenantID,
+		Destinations: task.Deliveries,
+		Files:        deliveryFiles(result.Formats),
+	})
+	if err == nil {
+		err = results.SendMessage(topic, task.ImageID, message)
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to enqueue delivery of %s: %v", task.ImageID, err)
+	report := contract.DeliveryReport{ImageID: task.ImageID}
+	for _, destinationID := range task.Deliveries {
+		report.Deliveries = append(report.Deliveries, entity.DeliveryResult{
+			DestinationID: destinationID,
+			Status:        entity.DeliveryFailed,
+			Error:         err.Error(),
+		})
+	}
+	publishDeliveryReport(results, resultsTopic, report)
+}
+
+func publishDeliveryReport(results producer.Producer, topic string, report contract.DeliveryReport) {
+	message, err := contract.EncodeDeliveryReport(report)
+	if err != nil {
+		log.Printf("Failed to encode delivery report for %s: %v", report.ImageID, err)
+		return
+	}
+	if err := results.SendMessage(topic, report.ImageID, message); err != nil {
+		log.Printf("Failed to publish delivery report for %s: %v", report.ImageID, err)
+	}
+}
+
+// deliveryCipher читает ключ шифрования учётных данных назначений;
+// без ключа обработка работает, но выгрузка во внешние хранилища отключена
+func deliveryCipher() *secret.Cipher {
+	cipher, err := secret.NewCipher(config.GetEnv("DELIVERY_ENCRYPTION_KEY", ""))
+	if err != nil {
+		log.Printf("Output delivery disabled: %v", err)
+		return nil
+	}
+	return cipher
+}
@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/delivery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeliverer выгружает только в "dst-ok" и запоминает арендатора и файлы
+type fakeDeliverer struct {
+	tenants []string
+	files   []delivery.File
+}
+
+func (d *fakeDeliverer) Deliver(ctx context.Context, tenantID, destinationID, imageID string, files []delivery.File) entity.DeliveryResult {
+	d.tenants = append(d.tenants, tenantID)
+	d.files = files
+	if _, ok := ctx.Deadline(); !ok {
+		return entity.DeliveryResult{DestinationID: destinationID, Status: entity.DeliveryFailed, Error: "no deadline"}
+	}
+	if destinationID != "dst-ok" {
+		return entity.DeliveryResult{DestinationID: destinationID, Status: entity.DeliveryFailed, Attempts: 3, Error: "unreachable"}
+	}
+	return entity.DeliveryResult{DestinationID: destinationID, Status: entity.DeliveryDelivered, Attempts: 1}
+}
+
+// recordingProducer запоминает сообщения и отклоняет отправку в failTopic
+type recordingProducer struct {
+	failTopic string
+	messages  map[string][][]byte
+}
+
+func (p *recordingProducer) SendMessage(topic string, key string, value []byte) error {
+	if topic == p.failTopic {
+		return errors.New("broker unavailable")
+	}
+	if p.messages == nil {
+		p.messages = make(map[string][][]byte)
+	}
+	p.messages[topic] = append(p.messages[topic], value)
+	return nil
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+// TestDeliverReportsEveryDestination - сбой одного назначения не мешает другим, у каждой выгрузки свой срок
+func TestDeliverReportsEveryDestination(t *testing.T) {
+	uploader := &fakeDeliverer{}
+	report := deliver(context.Background(), uploader, contract.DeliveryTask{
+		ImageID:      "img-1",
+		Destinations: []string{"dst-down", "dst-ok"},
+		Files:        []contract.DeliveryFile{{Name: "thumbnail", Path: "storage/processed/img-1/thumbnail"}},
+	})
+
+	assert.Equal(t, "img-1", report.ImageID)
+	require.Len(t, report.Deliveries, 2)
+	assert.Equal(t, entity.DeliveryFailed, report.Deliveries[0].Status)
+	assert.Equal(t, "unreachable", report.Deliveries[0].Error)
+	assert.Equal(t, entity.DeliveryDelivered, report.Deliveries[1].Status)
+	assert.Equal(t, []string{entity.DefaultTenant, entity.DefaultTenant}, uploader.tenants)
+	assert.Equal(t, []delivery.File{{Name: "thumbnail", Path: "storage/processed/img-1/thumbnail"}}, uploader.files)
+}
+
+// TestEnqueueDelivery - итог обработки не ждёт выгрузки: назначения в pending, выгрузка в очереди
+func TestEnqueueDelivery(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "processed", "img-1")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, contract.ManifestFile), []byte("{}"), 0644))
+
+	task := entity.ProcessingTask{ImageID: "img-1", TenantID: "acme", Deliveries: []string{"dst-ok"}}
+	formats := map[string]string{"thumbnail": filepath.Join(dir, "thumbnail"), "resized": filepath.Join(dir, "resized")}
+	result := &entity.ProcessingResult{ImageID: "img-1", Formats: formats, Deliveries: pendingDeliveries(task, formats), ProcessedAt: time.Now()}
+	require.Len(t, result.Deliveries, 1)
+	assert.Equal(t, entity.DeliveryPending, result.Deliveries[0].Status)
+
+	results := &recordingProducer{}
+	enqueueDelivery(results, contract.TopicImageDeliveries, contract.TopicImageResults, task, result)
+
+	require.Len(t, results.messages[contract.TopicImageDeliveries], 1)
+	assert.Empty(t, results.messages[contract.TopicImageResults])
+	queued, err := contract.DecodeDeliveryTask(results.messages[contract.TopicImageDeliveries][0])
+	require.NoError(t, err)
+	assert.Equal(t, "acme", queued.TenantID)
+	assert.Equal(t, []string{"dst-ok"}, queued.Destinations)
+	names := make([]string, 0, len(queued.Files))
+	for _, file := range queued.Files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"resized", "thumbnail", contract.ManifestFile}, names)
+}
+
+// TestEnqueueDeliveryFailure - если выгрузку не удалось поставить, назначения помечаются неуспешными
+func TestEnqueueDeliveryFailure(t *testing.T) {
+	task := entity.ProcessingTask{ImageID: "img-1", Deliveries: []string{"dst-ok"}}
+	formats := map[string]string{"thumbnail": "storage/processed/img-1/thumbnail"}
+	result := &entity.ProcessingResult{ImageID: "img-1", Formats: formats, Deliveries: pendingDeliveries(task, formats)}
+
+	results := &recordingProducer{failTopic: contract.TopicImageDeliveries}
+	enqueueDelivery(results, contract.TopicImageDeliveries, contract.TopicImageResults, task, result)
+
+	require.Len(t, results.messages[contract.TopicImageResults], 1)
+	report, err := contract.DecodeDeliveryReport(results.messages[contract.TopicImageResults][0])
+	require.NoError(t, err)
+	require.Len(t, report.Deliveries, 1)
+	assert.Equal(t, entity.DeliveryFailed, report.Deliveries[0].Status)
+}
@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+)
+
+// DefaultEncoderProfile - профиль, которым процессор кодирует варианты без конфигурации профилей
+const DefaultEncoderProfile = "default"
+
+// operationTypes - операции, для которых в конфигурации можно задать профиль
+var operationTypes = map[string]bool{"resize": true, "thumbnail": true, "watermark": true}
+
+var pngCompression = map[string]png.CompressionLevel{
+	"":                 png.DefaultCompression,
+	"default":          png.DefaultCompression,
+	"none":             png.NoCompression,
+	"best_speed":       png.BestSpeed,
+	"best_compression": png.BestCompression,
+}
+
+type encoderProfile struct {
+	jpeg    config.JPEGEncoderConfig
+	png     png.CompressionLevel
+	pngName string // название уровня сжатия для манифеста
+	webp    config.WebPEncoderConfig
+}
+
+// EncoderProfiles - проверенные профили кодирования из конфигурации
+type EncoderProfiles struct {
+	defaultProfile string
+	operations     map[string]string
+	profiles       map[string]encoderProfile
+}
+
+// NewEncoderProfiles проверяет профили при старте: ошибка в любом профиле делает
+// конфигурацию недействительной, а не всплывает при обработке первой задачи
+func NewEncoderProfiles(cfg config.EncoderConfig) (*EncoderProfiles, error) {
+	if len(cfg.Profiles) == 0 {
+		return DefaultEncoderProfiles(), nil
+	}
+
+	e := &EncoderProfiles{
+		defaultProfile: strings.ToLower(cfg.DefaultProfile),
+		operations:     make(map[string]string, len(cfg.Operations)),
+		profiles:       make(map[string]encoderProfile, len(cfg.Profiles)),
+	}
+
+	for name, profile := range cfg.Profiles {
+		name = strings.ToLower(name)
+
+		if profile.JPEG.Quality < 0 || profile.JPEG.Quality > 100 {
+			return nil, fmt.Errorf("encoder profile %q: jpeg quality %d is out of range 1-100", name, profile.JPEG.Quality)
+		}
+		if profile.JPEG.Quality == 0 {
+			profile.JPEG.Quality = jpeg.DefaultQuality
+		}
+		pngName := strings.ToLower(profile.PNG.Compression)
+		level, ok := pngCompression[pngName]
+		if !ok {
+			return nil, fmt.Errorf("encoder profile %q: unknown png compression %q", name, profile.PNG.Compression)
+		}
+		if pngName == "" {
+			pngName = "default"
+		}
+		if profile.WebP.Quality < 0 || profile.WebP.Quality > 100 {
+			return nil, fmt.Errorf("encoder profile %q: webp quality %d is out of range 0-100", name, profile.WebP.Quality)
+		}
+
+		// Стандартный кодировщик пишет только baseline JPEG, а WebP вариантов процессор
+		// пока не создаёт: такие настройки сохраняются в профиле, но не меняют результат
+		if profile.JPEG.Progressive {
+			log.Printf("Encoder profile %q: progressive JPEG is not supported, baseline is written", name)
+		}
+
+		e.profiles[name] = encoderProfile{jpeg: profile.JPEG, png: level, pngName: pngName, webp: profile.WebP}
+	}
+
+	if e.defaultProfile == "" {
+		return nil, fmt.Errorf("encoder default_profile is not set")
+	}
+	if _, ok := e.profiles[e.defaultProfile]; !ok {
+		return nil, fmt.Errorf("encoder default_profile %q: %w", e.defaultProfile, entity.ErrUnknownEncoderProfile)
+	}
+
+	for operation, name := range cfg.Operations {
+		operation, name = strings.ToLower(operation), strings.ToLower(name)
+		if !operationTypes[operation] {
+			return nil, fmt.Errorf("encoder operations: unknown operation %q", operation)
+		}
+		if _, ok := e.profiles[name]; !ok {
+			return nil, fmt.Errorf("encoder operations: %s uses profile %q: %w", operation, name, entity.ErrUnknownEncoderProfile)
+		}
+		e.operations[operation] = name
+	}
+
+	return e, nil
+}
+
+// DefaultEncoderProfiles повторяет прежние настройки: JPEG качества 90, PNG со сжатием по умолчанию
+func DefaultEncoderProfiles() *EncoderProfiles {
+	return &EncoderProfiles{
+		defaultProfile: DefaultEncoderProfile,
+		operations:     map[string]string{},
+		profiles: map[string]encoderProfile{
+			DefaultEncoderProfile: {
+				jpeg:    config.JPEGEncoderConfig{Quality: 90},
+				png:     png.DefaultCompression,
+				pngName: "default",
+				webp:    config.WebPEncoderConfig{Quality: 90},
+			},
+		},
+	}
+}
+
+// Resolve выбирает профиль операции: запрошенный явно, заданный для типа операции или профиль по умолчанию
+func (e *EncoderProfiles) Resolve(operation, requested string) (string, error) {
+	if requested != "" {
+		name := strings.ToLower(requested)
+		if _, ok := e.profiles[name]; !ok {
+			return "", fmt.Errorf("%w: %q", entity.ErrUnknownEncoderProfile, requested)
+		}
+		return name, nil
+	}
+	if name, ok := e.operations[operation]; ok {
+		return name, nil
+	}
+	return e.defaultProfile, nil
+}
+
+// Names возвращает имена профилей в алфавитном порядке
+func (e *EncoderProfiles) Names() []string {
+	names := make([]string, 0, len(e.profiles))
+	for name := range e.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode записывает изображение в формате format с настройками профиля
+func (e *EncoderProfiles) Encode(w io.Writer, img image.Image, format, name string) error {
+	profile, ok := e.profiles[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", entity.ErrUnknownEncoderProfile, name)
+	}
+
+	if EncodedFormat(format) == "png" {
+		encoder := png.Encoder{CompressionLevel: profile.png}
+		return encoder.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: profile.jpeg.Quality})
+}
+
+// Settings возвращает настройки, с которыми Encode запишет вариант формата format
+func (e *EncoderProfiles) Settings(format, name string) (entity.EncoderSettings, error) {
+	profile, ok := e.profiles[name]
+	if !ok {
+		return entity.EncoderSettings{}, fmt.Errorf("%w: %q", entity.ErrUnknownEncoderProfile, name)
+	}
+
+	if EncodedFormat(format) == "png" {
+		return entity.EncoderSettings{PNGCompression: profile.pngName}, nil
+	}
+	return entity.EncoderSettings{JPEGQuality: profile.jpeg.Quality}, nil
+}
+
+// EncodedFormat - формат, в котором записывается вариант оригинала формата format.
+// GIF сохраняется как PNG, так как обработка может изменить изображение
+func EncodedFormat(format string) string {
+	switch format {
+	case "png", "gif":
+		return "png"
+	default:
+		return "jpeg"
+	}
+}
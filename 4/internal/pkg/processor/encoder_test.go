@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEncoderConfig() config.EncoderConfig {
+	return config.EncoderConfig{
+		DefaultProfile: "balanced",
+		Operations:     map[string]string{"thumbnail": "web"},
+		Profiles: map[string]config.EncoderProfile{
+			"balanced": {JPEG: config.JPEGEncoderConfig{Quality: 90}},
+			"web":      {JPEG: config.JPEGEncoderConfig{Quality: 40}, PNG: config.PNGEncoderConfig{Compression: "best_compression"}},
+		},
+	}
+}
+
+// TestNewEncoderProfilesValidation проверяет, что ошибки конфигурации находятся при старте
+func TestNewEncoderProfilesValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.EncoderConfig)
+	}{
+		{"unknown default profile", func(cfg *config.EncoderConfig) { cfg.DefaultProfile = "missing" }},
+		{"missing default profile", func(cfg *config.EncoderConfig) { cfg.DefaultProfile = "" }},
+		{"jpeg quality out of range", func(cfg *config.EncoderConfig) {
+			cfg.Profiles["web"] = config.EncoderProfile{JPEG: config.JPEGEncoderConfig{Quality: 120}}
+		}},
+		{"unknown png compression", func(cfg *config.EncoderConfig) {
+			cfg.Profiles["web"] = config.EncoderProfile{PNG: config.PNGEncoderConfig{Compression: "max"}}
+		}},
+		{"webp quality out of range", func(cfg *config.EncoderConfig) {
+			cfg.Profiles["web"] = config.EncoderProfile{WebP: config.WebPEncoderConfig{Quality: -1}}
+		}},
+		{"unknown operation", func(cfg *config.EncoderConfig) { cfg.Operations["crop"] = "web" }},
+		{"operation with unknown profile", func(cfg *config.EncoderConfig) { cfg.Operations["resize"] = "missing" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testEncoderConfig()
+			tt.modify(&cfg)
+
+			_, err := NewEncoderProfiles(cfg)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("empty config keeps previous settings", func(t *testing.T) {
+		encoders, err := NewEncoderProfiles(config.EncoderConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{DefaultEncoderProfile}, encoders.Names())
+	})
+}
+
+func TestEncoderProfilesResolve(t *testing.T) {
+	encoders, err := NewEncoderProfiles(testEncoderConfig())
+	require.NoError(t, err)
+
+	profile, err := encoders.Resolve("resize", "")
+	require.NoError(t, err)
+	assert.Equal(t, "balanced", profile)
+
+	profile, err = encoders.Resolve("thumbnail", "")
+	require.NoError(t, err)
+	assert.Equal(t, "web", profile)
+
+	profile, err = encoders.Resolve("thumbnail", "Balanced")
+	require.NoError(t, err)
+	assert.Equal(t, "balanced", profile)
+
+	_, err = encoders.Resolve("resize", "missing")
+	assert.True(t, errors.Is(err, entity.ErrUnknownEncoderProfile))
+}
+
+// TestEncoderProfilesEncode проверяет, что качество JPEG берётся из профиля
+func TestEncoderProfilesEncode(t *testing.T) {
+	encoders, err := NewEncoderProfiles(testEncoderConfig())
+	require.NoError(t, err)
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8(x ^ y), A: 255})
+		}
+	}
+
+	var balanced, web bytes.Buffer
+	require.NoError(t, encoders.Encode(&balanced, img, "jpeg", "balanced"))
+	require.NoError(t, encoders.Encode(&web, img, "jpeg", "web"))
+	assert.Less(t, web.Len(), balanced.Len())
+
+	_, err = jpeg.Decode(&web)
+	require.NoError(t, err)
+
+	err = encoders.Encode(&bytes.Buffer{}, img, "png", "missing")
+	assert.True(t, errors.Is(err, entity.ErrUnknownEncoderProfile))
+}
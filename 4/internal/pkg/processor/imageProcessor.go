@@ -1,228 +1,329 @@
-package processor
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
-	"log"
-	"os"
-	"path/filepath"
-	"time"
-
-	"github.com/disintegration/imaging"
-	"github.com/ds124wfegd/WB_L3/4/internal/entity"
-	"github.com/segmentio/kafka-go"
-)
-
-type ImageProcessor interface {
-	Process(task entity.ProcessingTask) error
-}
-
-type imageProcessor struct {
-	storagePath string
-}
-
-func NewImageProcessor() ImageProcessor {
-	return &imageProcessor{storagePath: "./storage"}
-}
-
-func (p *imageProcessor) Process(task entity.ProcessingTask) error {
-	log.Printf("Processing image: %s", task.ImageID)
-
-	// Загружаем оригинальное изображение
-	originalPath := filepath.Join(p.storagePath, "original", task.ImageID)
-	img, format, err := p.loadImage(originalPath)
-	if err != nil {
-		return fmt.Errorf("failed to load image: %v", err)
-	}
-
-	// Обрабатываем каждую операцию
-	results := make(map[string]string)
-	for _, op := range task.Operations {
-		var processed image.Image
-		var outputFormat string
-
-		switch op.Type {
-		case "resize":
-			processed = imaging.Resize(img, op.Width, op.Height, imaging.Lanczos)
-			outputFormat = "resized"
-		case "thumbnail":
-			processed = imaging.Thumbnail(img, op.Width, op.Height, imaging.Lanczos)
-			outputFormat = "thumbnail"
-		case "watermark":
-			processed = p.addWatermark(img, op.Text)
-			outputFormat = "watermark"
-		default:
-			log.Printf("Unknown operation: %s", op.Type)
-			continue
-		}
-
-		// Сохраняем обработанное изображение
-		outputPath := filepath.Join(p.storagePath, "processed", task.ImageID, outputFormat)
-		if err := p.saveImage(processed, outputPath, format); err != nil {
-			log.Printf("Failed to save %s: %v", outputFormat, err)
-			continue
-		}
-
-		results[outputFormat] = outputPath
-	}
-
-	// Обновляем статус
-	if err := p.updateStatus(task.ImageID, "completed", results); err != nil {
-		return fmt.Errorf("failed to update status: %v", err)
-	}
-
-	log.Printf("Completed processing image: %s", task.ImageID)
-	return nil
-}
-
-func (p *imageProcessor) loadImage(path string) (image.Image, string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, "", err
-	}
-	defer file.Close()
-
-	// Определяем формат по расширению
-	ext := filepath.Ext(path)
-	switch ext {
-	case ".jpg", ".jpeg":
-		img, err := jpeg.Decode(file)
-		return img, "jpeg", err
-	case ".png":
-		img, err := png.Decode(file)
-		return img, "png", err
-	case ".gif":
-		return p.processGif(path)
-	default:
-		return nil, "", fmt.Errorf("unsupported format: %s", ext)
-	}
-}
-
-func (p *imageProcessor) processGif(path string) (image.Image, string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, "", err
-	}
-	defer file.Close()
-
-	// Декодируем GIF
-	gifImg, err := gif.DecodeAll(file)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// Возвращаем первый кадр
-	if len(gifImg.Image) > 0 {
-		return gifImg.Image[0], "gif", nil
-	}
-
-	return nil, "", fmt.Errorf("no frames in GIF")
-}
-
-func (p *imageProcessor) addWatermark(img image.Image, text string) image.Image {
-	// Простая реализация водяного знака
-	dst := imaging.Clone(img)
-	// Здесь можно добавить более сложную логику наложения текста
-	return dst
-}
-
-func (p *imageProcessor) updateStatus(imageID string, status string, formats map[string]string) error {
-	metadataPath := filepath.Join(p.storagePath, "metadata", imageID+".json")
-
-	file, err := os.OpenFile(metadataPath, os.O_RDWR, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	var imageData map[string]interface{}
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&imageData); err != nil {
-		return err
-	}
-
-	imageData["status"] = status
-	imageData["formats"] = formats
-
-	file.Seek(0, 0)
-	file.Truncate(0)
-
-	encoder := json.NewEncoder(file)
-	return encoder.Encode(imageData)
-}
-
-func (p *imageProcessor) saveImage(img image.Image, path string, format string) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	switch format {
-	case "jpeg":
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: 90})
-	case "png":
-		return png.Encode(file, img)
-	case "gif":
-		// Для GIF сохраняем как PNG, так как обработка может изменить изображение
-		return png.Encode(file, img)
-	default:
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: 90})
-	}
-}
-
-func StartImageProcessorConsumer(brokers []string, topic, groupID string) {
-
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        brokers,
-		Topic:          topic,
-		GroupID:        groupID,
-		MinBytes:       10e3, // 10KB
-		MaxBytes:       10e6, // 10MB
-		CommitInterval: time.Second,
-		StartOffset:    kafka.FirstOffset, //-2 FirstOffset
-
-	})
-
-	defer reader.Close()
-
-	processor := NewImageProcessor()
-
-	log.Println("Image processor consumer started...")
-	log.Printf("Connected to Kafka brokers: %s", brokers)
-
-	for {
-		ctx := context.Background()
-		msg, err := reader.ReadMessage(ctx)
-		if err != nil {
-			log.Printf("Error reading message from Kafka: %v", err)
-			continue
-		}
-
-		log.Printf("Received message from topic %s [partition %d, offset %d]: %s\n",
-			msg.Topic, msg.Partition, msg.Offset, string(msg.Value))
-
-		var task entity.ProcessingTask
-		if err := json.Unmarshal(msg.Value, &task); err != nil {
-			log.Printf("Failed to parse task: %v\n", err)
-			continue
-		}
-
-		go func(t entity.ProcessingTask) {
-			if err := processor.Process(t); err != nil {
-				log.Printf("Processing failed for %s: %v\n", t.ImageID, err)
-			} else {
-				log.Printf("Successfully processed image: %s", t.ImageID)
-			}
-		}(task)
-	}
-}
+package processor
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/ds124wfegd/WB_L3/4/internal/database"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	producer "github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/scaling"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/storage"
+	"github.com/segmentio/kafka-go"
+)
+
+// ImageProcessor обрабатывает задачу и возвращает итог, который отправляется обратно в API.
+// Метаданные изображения процессор не трогает: их ведёт API.
+type ImageProcessor interface {
+	Process(task entity.ProcessingTask) (*entity.ProcessingResult, error)
+}
+
+type imageProcessor struct {
+	storagePath string
+	watermarks  database.WatermarkRepository
+	encoders    *EncoderProfiles
+}
+
+func NewImageProcessor(watermarks database.WatermarkRepository, encoders *EncoderProfiles) ImageProcessor {
+	return &imageProcessor{storagePath: "./storage", watermarks: watermarks, encoders: encoders}
+}
+
+func (p *imageProcessor) Process(task entity.ProcessingTask) (*entity.ProcessingResult, error) {
+	log.Printf("Processing image: %s", task.ImageID)
+
+	// Загружаем оригинальное изображение
+	originalPath := filepath.Join(p.storagePath, "original", task.ImageID)
+	img, format, err := p.loadImage(originalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %v", err)
+	}
+
+	// Хеши считаются по оригиналу, чтобы не зависеть от операций и профилей задачи
+	hashes := Hashes(img)
+
+	// Обрабатываем каждую операцию
+	results := make(map[string]string)
+	applied := make(map[string]entity.AppliedWatermark)
+	profiles := make(map[string]string)
+	manifest := &entity.Manifest{
+		ImageID:  task.ImageID,
+		TenantID: task.TenantID,
+		Source:   entity.ManifestSource{Format: format, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()},
+		Variants: make([]entity.ManifestVariant, 0, len(task.Operations)),
+	}
+	for _, op := range task.Operations {
+		var processed image.Image
+		var outputFormat string
+		var mark *entity.AppliedWatermark
+
+		switch op.Type {
+		case "resize":
+			processed = imaging.Resize(img, op.Width, op.Height, imaging.Lanczos)
+			outputFormat = "resized"
+		case "thumbnail":
+			processed = imaging.Thumbnail(img, op.Width, op.Height, imaging.Lanczos)
+			outputFormat = "thumbnail"
+		case "watermark":
+			processed, mark = p.watermark(img, task.TenantID, op)
+			outputFormat = "watermark"
+		default:
+			log.Printf("Unknown operation: %s", op.Type)
+			continue
+		}
+
+		// Сохраняем обработанное изображение
+		profile := p.profile(op)
+		outputPath := filepath.Join(p.storagePath, "processed", task.ImageID, outputFormat)
+		variant, err := p.saveImage(processed, outputPath, format, profile)
+		if err != nil {
+			log.Printf("Failed to save %s: %v", outputFormat, err)
+			continue
+		}
+		variant.Name = outputFormat
+		manifest.Variants = append(manifest.Variants, *variant)
+
+		results[outputFormat] = outputPath
+		profiles[outputFormat] = profile
+		if mark != nil {
+			applied[outputFormat] = *mark
+		}
+	}
+
+	// Манифест без файла всё равно попадает в итог: API отдаст его в статусе изображения
+	manifest.GeneratedAt = time.Now()
+	manifestPath := filepath.Join(p.storagePath, "processed", task.ImageID, contract.ManifestFile)
+	if err := writeManifest(manifestPath, manifest); err != nil {
+		log.Printf("Failed to write manifest of %s: %v", task.ImageID, err)
+	}
+
+	log.Printf("Completed processing image: %s", task.ImageID)
+	return &entity.ProcessingResult{
+		ImageID:     task.ImageID,
+		TenantID:    task.TenantID,
+		Status:      contract.StatusCompleted,
+		Formats:     results,
+		Watermarks:  applied,
+		Profiles:    profiles,
+		Deliveries:  pendingDeliveries(task, results),
+		Hashes:      &hashes,
+		Manifest:    manifest,
+		ProcessedAt: time.Now(),
+	}, nil
+}
+
+func (p *imageProcessor) loadImage(path string) (image.Image, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	// Определяем формат по расширению
+	ext := filepath.Ext(path)
+	switch ext {
+	case ".jpg", ".jpeg":
+		img, err := jpeg.Decode(file)
+		return img, "jpeg", err
+	case ".png":
+		img, err := png.Decode(file)
+		return img, "png", err
+	case ".gif":
+		return p.processGif(path)
+	default:
+		return nil, "", fmt.Errorf("unsupported format: %s", ext)
+	}
+}
+
+func (p *imageProcessor) processGif(path string) (image.Image, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	// Декодируем GIF
+	gifImg, err := gif.DecodeAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Возвращаем первый кадр
+	if len(gifImg.Image) > 0 {
+		return gifImg.Image[0], "gif", nil
+	}
+
+	return nil, "", fmt.Errorf("no frames in GIF")
+}
+
+// profile возвращает профиль кодирования операции. Профиль, которого нет в конфигурации
+// процессора (API и процессор развёрнуты с разными профилями), заменяется профилем по умолчанию
+func (p *imageProcessor) profile(op entity.Operation) string {
+	profile, err := p.encoders.Resolve(op.Type, op.Profile)
+	if err != nil {
+		log.Printf("Operation %s: %v, using default profile", op.Type, err)
+		profile, _ = p.encoders.Resolve(op.Type, "")
+	}
+	return profile
+}
+
+// saveImage записывает вариант и возвращает его описание для манифеста без имени операции
+func (p *imageProcessor) saveImage(img image.Image, path string, format string, profile string) (*entity.ManifestVariant, error) {
+	settings, err := p.encoders.Settings(format, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	written := newChecksumWriter(file)
+	if err := p.encoders.Encode(written, img, format, profile); err != nil {
+		return nil, err
+	}
+
+	encoded := EncodedFormat(format)
+	return &entity.ManifestVariant{
+		File:        filepath.Base(path),
+		Format:      encoded,
+		ContentType: contentTypes[encoded],
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Bytes:       written.bytes,
+		SHA256:      written.sum(),
+		Profile:     profile,
+		Encoder:     settings,
+	}, nil
+}
+
+// StartImageProcessorConsumer читает задачи из topic и отправляет итоги обработки в resultsTopic.
+// Задачи декодируются пакетом contract, поэтому процессор понимает и сообщения старого формата.
+// На паузе monitor процессор выходит из группы, чтобы его партиции сразу забрали другие реплики,
+// и дорабатывает уже взятые задачи; после этого реплику можно выключать без потери задач.
+// Выгрузка во внешние хранилища ставится в deliveriesTopic и выполняется StartDeliveryConsumer.
+func StartImageProcessorConsumer(brokers []string, topic, resultsTopic, deliveriesTopic, groupID string, results producer.Producer, encoders *EncoderProfiles, monitor *scaling.Monitor) {
+	newReader := func() *kafka.Reader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        brokers,
+			Topic:          topic,
+			GroupID:        groupID,
+			MinBytes:       10e3, // 10KB
+			MaxBytes:       10e6, // 10MB
+			CommitInterval: time.Second,
+			StartOffset:    kafka.FirstOffset, //-2 FirstOffset
+
+		})
+	}
+
+	fileStorage := storage.NewFileStorage("./storage")
+	processor := NewImageProcessor(database.NewWatermarkRepository(fileStorage), encoders)
+
+	log.Println("Image processor consumer started...")
+	log.Printf("Connected to Kafka brokers: %s", brokers)
+
+	var reader *kafka.Reader
+	var inFlight sync.WaitGroup
+	for {
+		paused, changed := monitor.State()
+		if paused {
+			if reader != nil {
+				// Close фиксирует прочитанные смещения и выводит реплику из группы
+				if err := reader.Close(); err != nil {
+					log.Printf("Failed to leave consumer group: %v", err)
+				}
+				reader = nil
+			}
+			inFlight.Wait()
+			monitor.SetDrained(true)
+			log.Println("Consumption paused, all taken tasks are processed")
+			<-changed
+			continue
+		}
+		if reader == nil {
+			reader = newReader()
+		}
+
+		// Чтение прерывается, когда потребление ставят на паузу
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-changed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		msg, err := reader.ReadMessage(ctx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error reading message from Kafka: %v", err)
+			}
+			continue
+		}
+
+		log.Printf("Received message from topic %s [partition %d, offset %d]: %s\n",
+			msg.Topic, msg.Partition, msg.Offset, string(msg.Value))
+
+		task, version, err := contract.DecodeImageTask(msg.Value)
+		if err != nil {
+			log.Printf("Failed to parse task: %v\n", err)
+			continue
+		}
+		if version != contract.CurrentImageTaskVersion {
+			log.Printf("Decoded image task %s of legacy version v%d", task.ImageID, version)
+		}
+
+		done := monitor.Begin()
+		inFlight.Add(1)
+		go func(t entity.ProcessingTask) {
+			defer inFlight.Done()
+
+			result, err := processor.Process(t)
+			done(err)
+			if err != nil {
+				log.Printf("Processing failed for %s: %v\n", t.ImageID, err)
+				result = &entity.ProcessingResult{
+					ImageID:     t.ImageID,
+					TenantID:    t.TenantID,
+					Status:      contract.StatusFailed,
+					Error:       err.Error(),
+					ProcessedAt: time.Now(),
+				}
+			} else {
+				log.Printf("Successfully processed image: %s", t.ImageID)
+			}
+
+			publishResult(results, resultsTopic, result)
+			if err == nil {
+				enqueueDelivery(results, deliveriesTopic, resultsTopic, t, result)
+			}
+		}(task)
+	}
+}
+
+func publishResult(results producer.Producer, topic string, result *entity.ProcessingResult) {
+	message, err := contract.EncodeImageResult(*result)
+	if err != nil {
+		log.Printf("Failed to encode result for %s: %v", result.ImageID, err)
+		return
+	}
+	if err := results.SendMessage(topic, result.ImageID, message); err != nil {
+		log.Printf("Failed to publish result for %s: %v", result.ImageID, err)
+	}
+}
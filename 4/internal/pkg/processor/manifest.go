@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+)
+
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+}
+
+// checksumWriter считает размер и SHA-256 того, что кодировщик пишет в файл,
+// чтобы не перечитывать записанный вариант
+type checksumWriter struct {
+	w     io.Writer
+	hash  hash.Hash
+	bytes int64
+}
+
+func newChecksumWriter(w io.Writer) *checksumWriter {
+	return &checksumWriter{w: w, hash: sha256.New()}
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.hash.Write(p[:n])
+	c.bytes += int64(n)
+	return n, err
+}
+
+func (c *checksumWriter) sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// writeManifest записывает манифест через временный файл: получатель не увидит
+// наполовину записанный manifest.json
+func writeManifest(path string, manifest *entity.Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProcessWritesManifest проверяет, что манифест описывает записанные файлы:
+// размер и SHA-256 совпадают с содержимым, а итог обработки несёт тот же манифест
+func TestProcessWritesManifest(t *testing.T) {
+	storagePath := t.TempDir()
+
+	original := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	fillImageWithColor(original, color.RGBA{R: 200, G: 80, B: 40, A: 255})
+	originalPath := filepath.Join(storagePath, "original", "img.png")
+	require.NoError(t, os.MkdirAll(filepath.Dir(originalPath), 0755))
+	file, err := os.Create(originalPath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, original))
+	require.NoError(t, file.Close())
+
+	processor := &imageProcessor{storagePath: storagePath, encoders: DefaultEncoderProfiles()}
+	result, err := processor.Process(entity.ProcessingTask{
+		ImageID: "img.png",
+		Operations: []entity.Operation{
+			{Type: "resize", Width: 160, Height: 120},
+			{Type: "thumbnail", Width: 50, Height: 50},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.Manifest)
+
+	manifest := result.Manifest
+	assert.Equal(t, entity.ManifestSource{Format: "png", Width: 320, Height: 240}, manifest.Source)
+	require.Len(t, manifest.Variants, 2)
+
+	resized := manifest.Variants[0]
+	assert.Equal(t, "resized", resized.Name)
+	assert.Equal(t, "png", resized.Format)
+	assert.Equal(t, "image/png", resized.ContentType)
+	assert.Equal(t, 160, resized.Width)
+	assert.Equal(t, 120, resized.Height)
+	assert.Equal(t, DefaultEncoderProfile, resized.Profile)
+	assert.Equal(t, entity.EncoderSettings{PNGCompression: "default"}, resized.Encoder)
+
+	dir := filepath.Join(storagePath, "processed", "img.png")
+	for _, variant := range manifest.Variants {
+		data, err := os.ReadFile(filepath.Join(dir, variant.File))
+		require.NoError(t, err)
+
+		sum := sha256.Sum256(data)
+		assert.Equal(t, int64(len(data)), variant.Bytes, variant.Name)
+		assert.Equal(t, hex.EncodeToString(sum[:]), variant.SHA256, variant.Name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, contract.ManifestFile))
+	require.NoError(t, err)
+	var stored entity.Manifest
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, manifest.Variants, stored.Variants)
+}
+
+// TestEncoderSettingsFollowFormat проверяет, что в манифест попадают настройки того кодировщика,
+// которым записан вариант: GIF и PNG пишутся в PNG, остальное - в JPEG
+func TestEncoderSettingsFollowFormat(t *testing.T) {
+	encoders, err := NewEncoderProfiles(testEncoderConfig())
+	require.NoError(t, err)
+
+	settings, err := encoders.Settings("jpeg", "web")
+	require.NoError(t, err)
+	assert.Equal(t, entity.EncoderSettings{JPEGQuality: 40}, settings)
+
+	settings, err = encoders.Settings("gif", "web")
+	require.NoError(t, err)
+	assert.Equal(t, entity.EncoderSettings{PNGCompression: "best_compression"}, settings)
+
+	_, err = encoders.Settings("png", "missing")
+	assert.ErrorIs(t, err, entity.ErrUnknownEncoderProfile)
+}
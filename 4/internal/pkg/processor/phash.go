@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+)
+
+const (
+	// phashSize - сторона уменьшенной копии для DCT, phashBits - сторона блока низких частот
+	phashSize = 32
+	phashBits = 8
+)
+
+// dctCos[u][x] - косинусы DCT-II для низких частот u
+var dctCos = func() [phashBits][phashSize]float64 {
+	var table [phashBits][phashSize]float64
+	for u := 0; u < phashBits; u++ {
+		for x := 0; x < phashSize; x++ {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSize))
+		}
+	}
+	return table
+}()
+
+// Hashes считает перцептивные хеши изображения. Оба устойчивы к масштабу, перекодированию
+// и небольшим изменениям яркости, поэтому близкие хеши означают визуально похожие изображения.
+func Hashes(img image.Image) entity.ImageHashes {
+	gray := imaging.Grayscale(img)
+	return entity.ImageHashes{
+		PHash: formatHash(pHash(gray)),
+		DHash: formatHash(dHash(gray)),
+	}
+}
+
+// pHash сравнивает низкие частоты DCT уменьшенной копии с их медианой
+func pHash(gray image.Image) uint64 {
+	small := imaging.Resize(gray, phashSize, phashSize, imaging.Box)
+
+	var pixels [phashSize][phashSize]float64
+	for y := 0; y < phashSize; y++ {
+		for x := 0; x < phashSize; x++ {
+			pixels[y][x] = float64(small.Pix[small.PixOffset(x, y)])
+		}
+	}
+
+	coefficients := make([]float64, 0, phashBits*phashBits)
+	for v := 0; v < phashBits; v++ {
+		for u := 0; u < phashBits; u++ {
+			var sum float64
+			for y := 0; y < phashSize; y++ {
+				for x := 0; x < phashSize; x++ {
+					sum += pixels[y][x] * dctCos[u][x] * dctCos[v][y]
+				}
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	// Постоянная составляющая зависит только от средней яркости, в медиану её не берём
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, coefficient := range coefficients {
+		if coefficient > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// dHash сравнивает яркость соседних по горизонтали точек копии 9x8
+func dHash(gray image.Image) uint64 {
+	small := imaging.Resize(gray, 9, 8, imaging.Box)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if small.Pix[small.PixOffset(x, y)] < small.Pix[small.PixOffset(x+1, y)] {
+				hash |= 1 << uint(y*8+x)
+			}
+		}
+	}
+	return hash
+}
+
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// HammingDistance возвращает число различающихся бит двух хешей в шестнадцатеричной записи
+func HammingDistance(a, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hash %q: %w", a, err)
+	}
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hash %q: %w", b, err)
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testScene рисует градиент с тёмным прямоугольником: у сплошной заливки все хеши одинаковы
+func testScene(width, height int, flipped bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			level := uint8(255 * x / width)
+			if flipped {
+				level = 255 - level
+			}
+			inside := x > width/4 && x < width/2 && y > height/3 && y < 2*height/3
+			if inside != flipped {
+				level /= 4
+			}
+			img.Set(x, y, color.RGBA{R: level, G: level / 2, B: 255 - level, A: 255})
+		}
+	}
+	return img
+}
+
+func hashDistances(t *testing.T, a, b image.Image) (int, int) {
+	t.Helper()
+
+	ha, hb := Hashes(a), Hashes(b)
+	phash, err := HammingDistance(ha.PHash, hb.PHash)
+	require.NoError(t, err)
+	dhash, err := HammingDistance(ha.DHash, hb.DHash)
+	require.NoError(t, err)
+	return phash, dhash
+}
+
+// TestHashesSimilarImages проверяет, что масштаб и яркость почти не меняют хеши
+func TestHashesSimilarImages(t *testing.T) {
+	original := testScene(400, 300, false)
+
+	tests := []struct {
+		name    string
+		variant image.Image
+	}{
+		{name: "same image", variant: original},
+		{name: "downscaled", variant: imaging.Resize(original, 160, 120, imaging.Lanczos)},
+		{name: "brighter", variant: imaging.AdjustBrightness(original, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phash, dhash := hashDistances(t, original, tt.variant)
+			assert.LessOrEqual(t, phash, 6)
+			assert.LessOrEqual(t, dhash, 6)
+		})
+	}
+}
+
+// TestHashesDifferentImages проверяет, что непохожие изображения далеко друг от друга
+func TestHashesDifferentImages(t *testing.T) {
+	phash, dhash := hashDistances(t, testScene(400, 300, false), testScene(400, 300, true))
+	assert.Greater(t, phash, 20)
+	assert.Greater(t, dhash, 20)
+}
+
+func TestHashesFormat(t *testing.T) {
+	hashes := Hashes(testScene(64, 64, false))
+	assert.Len(t, hashes.PHash, 16)
+	assert.Len(t, hashes.DHash, 16)
+}
+
+func TestHammingDistance(t *testing.T) {
+	distance, err := HammingDistance("00000000000000ff", "000000000000000f")
+	require.NoError(t, err)
+	assert.Equal(t, 4, distance)
+
+	_, err = HammingDistance("not a hash", "000000000000000f")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// PreviewSize - наибольшая сторона превью, которое строится прямо при загрузке
+	PreviewSize    = 64
+	previewQuality = 60
+)
+
+// Preview строит крошечную JPEG-копию изображения и возвращает её как data URI,
+// чтобы интерфейс мог показать заглушку сразу, не дожидаясь процессора.
+// Форматы jpeg, png и gif зарегистрированы импортами пакета.
+func Preview(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", err
+	}
+
+	small := imaging.Fit(img, PreviewSize, PreviewSize, imaging.Box)
+	// У JPEG нет прозрачности: прозрачные области PNG и GIF кладём на белый фон
+	small = imaging.Overlay(imaging.New(small.Bounds().Dx(), small.Bounds().Dy(), color.White), small, image.Pt(0, 0), 1)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, small, &jpeg.Options{Quality: previewQuality}); err != nil {
+		return "", err
+	}
+
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPreview проверяет, что превью укладывается в PreviewSize и сохраняет пропорции
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name           string
+		originalWidth  int
+		originalHeight int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{
+			name:           "landscape",
+			originalWidth:  800,
+			originalHeight: 400,
+			expectedWidth:  64,
+			expectedHeight: 32,
+		},
+		{
+			name:           "portrait",
+			originalWidth:  300,
+			originalHeight: 600,
+			expectedWidth:  32,
+			expectedHeight: 64,
+		},
+		{
+			name:           "smaller than preview",
+			originalWidth:  20,
+			originalHeight: 10,
+			expectedWidth:  20,
+			expectedHeight: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := image.NewRGBA(image.Rect(0, 0, tt.originalWidth, tt.originalHeight))
+			fillImageWithColor(original, color.RGBA{R: 50, G: 100, B: 150, A: 255})
+
+			var buf bytes.Buffer
+			require.NoError(t, png.Encode(&buf, original))
+
+			preview, err := Preview(&buf)
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(preview, "data:image/jpeg;base64,"))
+
+			data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(preview, "data:image/jpeg;base64,"))
+			require.NoError(t, err)
+
+			decoded, err := jpeg.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedWidth, decoded.Bounds().Dx())
+			assert.Equal(t, tt.expectedHeight, decoded.Bounds().Dy())
+		})
+	}
+}
+
+// TestPreviewInvalidImage проверяет, что повреждённый файл даёт ошибку, а не пустое превью
+func TestPreviewInvalidImage(t *testing.T) {
+	_, err := Preview(strings.NewReader("not an image"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// watermark накладывает водяной знак по операции. Ссылка на сохранённый водяной знак
+// разрешается в конкретную версию арендатора; если её нет, используется текст операции.
+func (p *imageProcessor) watermark(img image.Image, tenantID string, op entity.Operation) (image.Image, *entity.AppliedWatermark) {
+	if op.Watermark == "" || p.watermarks == nil {
+		return p.addWatermark(img, op.Text), nil
+	}
+
+	if tenantID == "" {
+		tenantID = entity.DefaultTenant
+	}
+
+	config, err := p.resolveWatermark(tenantID, op.Watermark)
+	if err != nil {
+		if !errors.Is(err, entity.ErrWatermarkNotFound) {
+			log.Printf("Failed to resolve watermark %q for tenant %s: %v", op.Watermark, tenantID, err)
+		}
+		return p.addWatermark(img, op.Text), nil
+	}
+
+	processed, err := p.applyWatermark(img, config)
+	if err != nil {
+		log.Printf("Failed to apply watermark v%d for tenant %s: %v", config.Version, tenantID, err)
+		return p.addWatermark(img, op.Text), nil
+	}
+
+	return processed, &entity.AppliedWatermark{TenantID: config.TenantID, Version: config.Version}
+}
+
+func (p *imageProcessor) resolveWatermark(tenantID, ref string) (*entity.WatermarkConfig, error) {
+	if ref == entity.DefaultWatermark {
+		return p.watermarks.Latest(tenantID)
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(ref, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid watermark reference %q", ref)
+	}
+	return p.watermarks.Get(tenantID, version)
+}
+
+func (p *imageProcessor) applyWatermark(img image.Image, config *entity.WatermarkConfig) (image.Image, error) {
+	var mark image.Image
+
+	if config.ImageFile != "" {
+		reader, err := p.watermarks.OpenImage(config)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		decoded, _, err := image.Decode(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		width := int(float64(img.Bounds().Dx()) * config.Scale)
+		if width < 1 {
+			width = 1
+		}
+		mark = imaging.Resize(decoded, width, 0, imaging.Lanczos)
+	} else {
+		mark = renderText(config.Text, img.Bounds().Dy())
+	}
+
+	return overlayWatermark(img, mark, config.Position, config.Opacity), nil
+}
+
+func (p *imageProcessor) addWatermark(img image.Image, text string) image.Image {
+	if text == "" {
+		return imaging.Clone(img)
+	}
+	return overlayWatermark(img, renderText(text, img.Bounds().Dy()), entity.PositionBottomRight, 0.5)
+}
+
+// renderText рисует текст встроенным растровым шрифтом с тенью и масштабирует
+// его пропорционально высоте изображения
+func renderText(text string, imageHeight int) image.Image {
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Face: face}
+	width := drawer.MeasureString(text).Ceil()
+	height := face.Height
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, width+2, height+2))
+	draw.Draw(canvas, canvas.Bounds(), image.Transparent, image.Point{}, draw.Src)
+
+	drawer.Dst = canvas
+	drawer.Src = image.NewUniform(color.Black)
+	drawer.Dot = fixed.P(2, face.Ascent+2)
+	drawer.DrawString(text)
+
+	drawer.Src = image.NewUniform(color.White)
+	drawer.Dot = fixed.P(1, face.Ascent+1)
+	drawer.DrawString(text)
+
+	target := imageHeight / 15
+	if target <= canvas.Bounds().Dy() {
+		return canvas
+	}
+	return imaging.Resize(canvas, 0, target, imaging.NearestNeighbor)
+}
+
+// overlayWatermark вписывает знак в изображение с отступом и накладывает его с прозрачностью
+func overlayWatermark(img image.Image, mark image.Image, position string, opacity float64) image.Image {
+	bounds := img.Bounds()
+	margin := min(bounds.Dx(), bounds.Dy()) / 40
+
+	maxWidth := bounds.Dx() - 2*margin
+	maxHeight := bounds.Dy() - 2*margin
+	if maxWidth < 1 || maxHeight < 1 {
+		return imaging.Clone(img)
+	}
+	if mark.Bounds().Dx() > maxWidth || mark.Bounds().Dy() > maxHeight {
+		mark = imaging.Fit(mark, maxWidth, maxHeight, imaging.Lanczos)
+	}
+
+	size := mark.Bounds().Size()
+	var pos image.Point
+	switch position {
+	case entity.PositionTopLeft:
+		pos = image.Pt(margin, margin)
+	case entity.PositionTopRight:
+		pos = image.Pt(bounds.Dx()-size.X-margin, margin)
+	case entity.PositionBottomLeft:
+		pos = image.Pt(margin, bounds.Dy()-size.Y-margin)
+	case entity.PositionCenter:
+		pos = image.Pt((bounds.Dx()-size.X)/2, (bounds.Dy()-size.Y)/2)
+	default:
+		pos = image.Pt(bounds.Dx()-size.X-margin, bounds.Dy()-size.Y-margin)
+	}
+
+	return imaging.Overlay(img, mark, pos, opacity)
+}
@@ -0,0 +1,75 @@
+package scaling
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Handler отдаёт сигналы автоскейлеру:
+//
+//	GET  /scaling         - Signal в JSON, для KEDA metrics-api (valueLocation: lag)
+//	GET  /metrics         - те же значения в формате Prometheus
+//	POST /scaling/pause   - перестать брать задачи, например из preStop перед уменьшением реплик
+//	POST /scaling/resume  - снова брать задачи
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /scaling", m.serveSignal)
+	mux.HandleFunc("GET /metrics", m.serveMetrics)
+	mux.HandleFunc("POST /scaling/pause", func(w http.ResponseWriter, r *http.Request) {
+		m.Pause()
+		m.serveSignal(w, r)
+	})
+	mux.HandleFunc("POST /scaling/resume", func(w http.ResponseWriter, r *http.Request) {
+		m.Resume()
+		m.serveSignal(w, r)
+	})
+	return mux
+}
+
+func (m *Monitor) serveSignal(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.Snapshot())
+}
+
+func (m *Monitor) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	signal := m.Snapshot()
+	count, seconds := m.processingTotals()
+	labels := fmt.Sprintf("topic=%s,group=%s", strconv.Quote(signal.Topic), strconv.Quote(signal.GroupID))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	gauge(w, "image_processor_consumer_lag", "Tasks in the topic not yet processed by the consumer group.", labels, float64(signal.Lag))
+	gauge(w, "image_processor_consumer_partitions", "Partitions of the tasks topic, replicas above this number stay idle.", labels, float64(signal.Partitions))
+	gauge(w, "image_processor_processing_seconds_avg", "Moving average of the time to process one image.", "", signal.AvgProcessingSeconds)
+	gauge(w, "image_processor_in_flight", "Images being processed by this replica.", "", float64(signal.InFlight))
+	gauge(w, "image_processor_paused", "1 if this replica doesn't take new tasks.", "", boolValue(signal.Paused))
+	gauge(w, "image_processor_drained", "1 if this replica is paused and has finished all taken tasks.", "", boolValue(signal.Drained))
+
+	fmt.Fprintln(w, "# HELP image_processor_processing_seconds Time to process one image.")
+	fmt.Fprintln(w, "# TYPE image_processor_processing_seconds summary")
+	fmt.Fprintf(w, "image_processor_processing_seconds_sum %g\n", seconds)
+	fmt.Fprintf(w, "image_processor_processing_seconds_count %d\n", count)
+
+	fmt.Fprintln(w, "# HELP image_processor_failed_total Images whose processing failed.")
+	fmt.Fprintln(w, "# TYPE image_processor_failed_total counter")
+	fmt.Fprintf(w, "image_processor_failed_total %d\n", signal.Failed)
+}
+
+func gauge(w http.ResponseWriter, name, help, labels string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %g\n", name, labels, value)
+		return
+	}
+	fmt.Fprintf(w, "%s %g\n", name, value)
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
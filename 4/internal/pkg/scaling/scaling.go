@@ -0,0 +1,214 @@
+// Сигналы для внешнего автоскейлера процессоров (HPA через Prometheus Adapter или KEDA)
+// и плавная остановка потребления перед уменьшением числа реплик
+package scaling
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+)
+
+// avgWeight - вес нового замера в скользящем среднем времени обработки:
+// среднее следует за изменением нагрузки за десяток изображений, а не за всю жизнь процесса
+const avgWeight = 0.1
+
+// LagFunc возвращает текущее отставание группы потребителей
+type LagFunc func(ctx context.Context) (*kafka.GroupLag, error)
+
+// Signal - состояние реплики для автоскейлера. Lag общий для всей группы и одинаков
+// на всех репликах, поэтому в KEDA его сравнивают с целевым значением на реплику (AverageValue).
+type Signal struct {
+	Topic                string               `json:"topic"`
+	GroupID              string               `json:"group_id"`
+	Lag                  int64                `json:"lag"`
+	Partitions           int                  `json:"partitions"` // реплик больше, чем партиций, простаивают
+	PartitionLag         []kafka.PartitionLag `json:"partition_lag"`
+	LagUpdatedAt         time.Time            `json:"lag_updated_at"`
+	LagError             string               `json:"lag_error,omitempty"`
+	AvgProcessingSeconds float64              `json:"avg_processing_seconds"`
+	Processed            int64                `json:"processed"`
+	Failed               int64                `json:"failed"`
+	InFlight             int64                `json:"in_flight"`
+	Paused               bool                 `json:"paused"`
+	// Drained - потребление остановлено и обработка взятых задач закончена: реплику можно выключать
+	Drained bool `json:"drained"`
+}
+
+// Monitor собирает отставание группы и время обработки и управляет паузой потребления
+type Monitor struct {
+	fetchLag LagFunc
+	interval time.Duration
+
+	mu             sync.Mutex
+	topic, groupID string
+	lag            *kafka.GroupLag
+	lagErr         error
+	lagAt          time.Time
+	avg            time.Duration
+	processed      int64
+	failed         int64
+	totalSeconds   float64
+	inFlight       int64
+	paused         bool
+	drained        bool
+	changed        chan struct{} // закрывается при каждой смене паузы
+}
+
+func NewMonitor(topic, groupID string, fetchLag LagFunc, interval time.Duration, paused bool) *Monitor {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &Monitor{
+		fetchLag: fetchLag,
+		interval: interval,
+		topic:    topic,
+		groupID:  groupID,
+		paused:   paused,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Run опрашивает отставание группы раз в interval, пока не отменён ctx
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.refreshLag(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) refreshLag(ctx context.Context) {
+	fetchCtx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+
+	lag, err := m.fetchLag(fetchCtx)
+	if err != nil {
+		log.Printf("Failed to fetch consumer lag: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Прежнее значение остаётся в сигнале вместе с ошибкой: автоскейлер не должен
+	// сбрасывать реплики из-за того, что брокер один раз не ответил
+	m.lagErr = err
+	if err == nil {
+		m.lag = lag
+		m.lagAt = time.Now()
+	}
+}
+
+// Begin отмечает начало обработки задачи; возвращённую функцию вызывают по её окончании
+func (m *Monitor) Begin() func(err error) {
+	started := time.Now()
+
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+
+	return func(err error) {
+		elapsed := time.Since(started)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.inFlight--
+		if err != nil {
+			m.failed++
+			return
+		}
+
+		m.processed++
+		m.totalSeconds += elapsed.Seconds()
+		if m.processed == 1 {
+			m.avg = elapsed
+		} else {
+			m.avg += time.Duration(avgWeight * float64(elapsed-m.avg))
+		}
+	}
+}
+
+// Pause останавливает получение новых задач; уже взятые дорабатываются
+func (m *Monitor) Pause() {
+	m.setPaused(true)
+}
+
+func (m *Monitor) Resume() {
+	m.setPaused(false)
+}
+
+func (m *Monitor) setPaused(paused bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused == paused {
+		return
+	}
+	m.paused = paused
+	m.drained = false
+	close(m.changed)
+	m.changed = make(chan struct{})
+
+	log.Printf("Consumption paused: %t", paused)
+}
+
+// State возвращает, стоит ли потребление на паузе, и канал, который закроется при смене состояния
+func (m *Monitor) State() (bool, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.paused, m.changed
+}
+
+// SetDrained отмечается потребителем, когда после паузы закончена обработка всех взятых задач
+func (m *Monitor) SetDrained(drained bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.drained = drained && m.paused
+}
+
+func (m *Monitor) Snapshot() Signal {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	signal := Signal{
+		Topic:                m.topic,
+		GroupID:              m.groupID,
+		LagUpdatedAt:         m.lagAt,
+		AvgProcessingSeconds: m.avg.Seconds(),
+		Processed:            m.processed,
+		Failed:               m.failed,
+		InFlight:             m.inFlight,
+		Paused:               m.paused,
+		Drained:              m.drained,
+	}
+	if m.lag != nil {
+		signal.Lag = m.lag.Total
+		signal.Partitions = len(m.lag.Partitions)
+		signal.PartitionLag = m.lag.Partitions
+	}
+	if m.lagErr != nil {
+		signal.LagError = m.lagErr.Error()
+	}
+
+	return signal
+}
+
+func (m *Monitor) processingTotals() (count int64, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.processed, m.totalSeconds
+}
@@ -0,0 +1,105 @@
+package scaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMonitor(lag *kafka.GroupLag, err error) *Monitor {
+	return NewMonitor("image-tasks", "image-processor-service", func(context.Context) (*kafka.GroupLag, error) {
+		return lag, err
+	}, time.Second, false)
+}
+
+// TestMonitorLagKeptOnError - автоскейлер видит последнее известное отставание и ошибку опроса
+func TestMonitorLagKeptOnError(t *testing.T) {
+	m := newTestMonitor(&kafka.GroupLag{Total: 7, Partitions: []kafka.PartitionLag{{Partition: 0, Lag: 7}}}, nil)
+	m.refreshLag(context.Background())
+
+	m.fetchLag = func(context.Context) (*kafka.GroupLag, error) { return nil, errors.New("broker unavailable") }
+	m.refreshLag(context.Background())
+
+	signal := m.Snapshot()
+	assert.Equal(t, int64(7), signal.Lag)
+	assert.Equal(t, 1, signal.Partitions)
+	assert.Equal(t, "broker unavailable", signal.LagError)
+}
+
+func TestMonitorProcessing(t *testing.T) {
+	m := newTestMonitor(nil, nil)
+
+	done := m.Begin()
+	assert.Equal(t, int64(1), m.Snapshot().InFlight)
+	done(nil)
+
+	m.Begin()(errors.New("failed to load image"))
+
+	signal := m.Snapshot()
+	assert.Equal(t, int64(0), signal.InFlight)
+	assert.Equal(t, int64(1), signal.Processed)
+	assert.Equal(t, int64(1), signal.Failed)
+	assert.Greater(t, signal.AvgProcessingSeconds, 0.0)
+}
+
+// TestMonitorPause проверяет, что потребитель узнаёт о паузе и что drained
+// сбрасывается при возобновлении
+func TestMonitorPause(t *testing.T) {
+	m := newTestMonitor(nil, nil)
+
+	paused, changed := m.State()
+	require.False(t, paused)
+
+	m.Pause()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("pause didn't signal the consumer")
+	}
+
+	m.SetDrained(true)
+	assert.True(t, m.Snapshot().Drained)
+
+	m.Resume()
+	signal := m.Snapshot()
+	assert.False(t, signal.Paused)
+	assert.False(t, signal.Drained)
+
+	// Без паузы реплика не может считаться освобождённой
+	m.SetDrained(true)
+	assert.False(t, m.Snapshot().Drained)
+}
+
+func TestHandler(t *testing.T) {
+	m := newTestMonitor(&kafka.GroupLag{Total: 42, Partitions: []kafka.PartitionLag{{Partition: 0, Lag: 40}, {Partition: 1, Lag: 2}}}, nil)
+	m.refreshLag(context.Background())
+	handler := m.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/scaling/pause", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var signal Signal
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &signal))
+	assert.Equal(t, int64(42), signal.Lag)
+	assert.Equal(t, 2, signal.Partitions)
+	assert.True(t, signal.Paused)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `image_processor_consumer_lag{topic="image-tasks",group="image-processor-service"} 42`)
+	assert.Contains(t, rec.Body.String(), "image_processor_paused 1")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scaling/pause", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNoKey         = errors.New("encryption key is not configured")
+	ErrInvalidSecret = errors.New("invalid encrypted value")
+)
+
+// Cipher шифрует секреты AES-256-GCM. Ключ выводится из произвольной строки через SHA-256,
+// поэтому в окружении достаточно хранить одну достаточно длинную случайную строку.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt возвращает base64(nonce + ciphertext)
+func (c *Cipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(value string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, ErrInvalidSecret
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidSecret
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+func (s *destinationService) CreateDestination(tenantID string, req *entity.DestinationRequest) (*entity.Destination, error) {
+	if s.cipher == nil {
+		return nil, entity.ErrDeliveryDisabled
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Type == entity.DestinationS3 {
+		if err := s3utils.CheckValidBucketName(req.Bucket); err != nil {
+			return nil, fmt.Errorf("%w: %v", entity.ErrInvalidDestination, err)
+		}
+	}
+
+	// Учётные данные шифруются целиком и в открытом виде на диск не попадают
+	plaintext, err := json.Marshal(req.DestinationCredentials)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.cipher.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	destination := &entity.Destination{
+		ID:                   uuid.New().String(),
+		TenantID:             tenantID,
+		Type:                 req.Type,
+		Name:                 req.Name,
+		Endpoint:             req.Endpoint,
+		Bucket:               req.Bucket,
+		Region:               req.Region,
+		UseSSL:               req.UseSSL,
+		Path:                 strings.Trim(req.Path, "/"),
+		Username:             req.Username,
+		CreatedAt:            time.Now().UTC(),
+		EncryptedCredentials: encrypted,
+	}
+
+	if err := s.repo.Save(destination); err != nil {
+		return nil, err
+	}
+
+	public := destination.Public()
+	return &public, nil
+}
+
+func (s *destinationService) ListDestinations(tenantID string) ([]entity.Destination, error) {
+	destinations, err := s.repo.List(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range destinations {
+		destinations[i] = destinations[i].Public()
+	}
+	return destinations, nil
+}
+
+func (s *destinationService) DeleteDestination(tenantID, id string) error {
+	return s.repo.Delete(tenantID, id)
+}
@@ -1,55 +1,230 @@
-package service
-
-import (
-	"mime/multipart"
-
-	"github.com/ds124wfegd/WB_L3/4/internal/entity"
-)
-
-func (s *imageService) ProcessImage(id string, file *multipart.FileHeader) (string, error) {
-	// Сохраняем оригинальное изображение
-	src, err := file.Open()
-	if err != nil {
-		return "", err
-	}
-	defer src.Close()
-
-	// Создаем запись в репозитории
-	image := &entity.Image{
-		ID:     id,
-		Status: "processing",
-	}
-
-	if err := s.repo.Save(image); err != nil {
-		return "", err
-	}
-
-	// Сохраняем файл
-	if err := s.repo.SaveFile(id, "original", src); err != nil {
-		return "", err
-	}
-
-	// Отправляем в Kafka для обработки
-	task := entity.ProcessingTask{
-		ImageID: id,
-		Operations: []entity.Operation{
-			{Type: "resize", Width: 800, Height: 600},
-			{Type: "thumbnail", Width: 150, Height: 150},
-			{Type: "watermark", Text: "Processed"},
-		},
-	}
-
-	if err := s.producer.SendMessage("image-processing", task); err != nil {
-		return "", err
-	}
-
-	return id, nil
-}
-
-func (s *imageService) GetImage(id string) (*entity.Image, error) {
-	return s.repo.FindByID(id)
-}
-
-func (s *imageService) DeleteImage(id string) error {
-	return s.repo.Delete(id)
-}
+package service
+
+import (
+	"io"
+	"log"
+	"mime/multipart"
+	"sort"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/contract"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
+)
+
+func (s *imageService) ProcessImage(id string, tenantID string, deliveries []string, encoders entity.EncoderSelection, file *multipart.FileHeader) (*entity.Image, error) {
+	// Назначения проверяются до сохранения, чтобы не оставлять изображение без задачи
+	for _, destinationID := range deliveries {
+		if _, err := s.destinations.Get(tenantID, destinationID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Профили кодирования тоже проверяются до сохранения
+	operations := []entity.Operation{
+		{Type: "resize", Width: 800, Height: 600},
+		{Type: "thumbnail", Width: 150, Height: 150},
+		{Type: "watermark", Watermark: entity.DefaultWatermark, Text: "Processed"},
+	}
+	for i := range operations {
+		profile, err := s.encoders.Resolve(operations[i].Type, encoders.For(operations[i].Type))
+		if err != nil {
+			return nil, err
+		}
+		operations[i].Profile = profile
+	}
+
+	// Сохраняем оригинальное изображение
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	// Превью строится до постановки задачи, чтобы интерфейс сразу показал заглушку;
+	// неудача не мешает загрузке - полноценную обработку всё равно сделает процессор
+	preview, err := processor.Preview(src)
+	if err != nil {
+		log.Printf("Failed to build preview for %s: %v", id, err)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Создаем запись в репозитории
+	image := &entity.Image{
+		ID:       id,
+		TenantID: tenantID,
+		Status:   contract.StatusProcessing,
+		Preview:  preview,
+	}
+
+	if err := s.repo.Save(image); err != nil {
+		return nil, err
+	}
+
+	// Сохраняем файл
+	if err := s.repo.SaveFile(id, "original", src); err != nil {
+		return nil, err
+	}
+
+	// Отправляем в Kafka для обработки
+	// Водяной знак берётся из сохранённых настроек арендатора,
+	// текст используется, если арендатор их ещё не загрузил
+	task := entity.ProcessingTask{
+		ImageID:    id,
+		TenantID:   tenantID,
+		Deliveries: deliveries,
+		Operations: operations,
+	}
+
+	message, err := contract.EncodeImageTask(task)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.producer.SendMessage(contract.TopicImageTasks, id, message); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// ApplyResult записывает итог обработки, присланный процессором, в метаданные изображения
+func (s *imageService) ApplyResult(result *entity.ProcessingResult) error {
+	image, err := s.repo.FindByID(result.ImageID)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		// Изображение удалили, пока оно обрабатывалось
+		log.Printf("Skipping result for deleted image %s", result.ImageID)
+		return nil
+	}
+
+	image.Status = result.Status
+	image.Formats = result.Formats
+	image.Watermarks = result.Watermarks
+	image.Profiles = result.Profiles
+	image.Deliveries = result.Deliveries
+	image.Hashes = result.Hashes
+	image.Manifest = result.Manifest
+	image.Error = result.Error
+
+	if err := s.repo.Save(image); err != nil {
+		return err
+	}
+
+	if image.Hashes == nil {
+		return nil
+	}
+	return s.hashes.Save(imageTenant(image), image.ID, *image.Hashes)
+}
+
+func (s *imageService) ApplyDeliveries(report *entity.DeliveryReport) error {
+	image, err := s.repo.FindByID(report.ImageID)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		log.Printf("Skipping delivery report for deleted image %s", report.ImageID)
+		return nil
+	}
+
+	for _, result := range report.Deliveries {
+		replaced := false
+		for i := range image.Deliveries {
+			if image.Deliveries[i].DestinationID == result.DestinationID {
+				image.Deliveries[i] = result
+				replaced = true
+			}
+		}
+		if !replaced {
+			image.Deliveries = append(image.Deliveries, result)
+		}
+	}
+
+	return s.repo.Save(image)
+}
+
+func (s *imageService) GetImage(id string) (*entity.Image, error) {
+	return s.repo.FindByID(id)
+}
+
+func (s *imageService) DeleteImage(id string) error {
+	image, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if image != nil {
+		if err := s.hashes.Delete(imageTenant(image), id); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.Delete(id)
+}
+
+func (s *imageService) FindSimilar(id string, maxDistance int) (*entity.SimilarImagesResponse, error) {
+	if maxDistance < 0 {
+		maxDistance = s.maxDistance
+	}
+
+	image, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil {
+		return nil, entity.ErrImageNotFound
+	}
+	if image.Hashes == nil {
+		return nil, entity.ErrImageNotHashed
+	}
+
+	index, err := s.hashes.List(imageTenant(image))
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]entity.SimilarImage, 0)
+	for candidateID, hashes := range index {
+		if candidateID == id {
+			continue
+		}
+
+		distance, err := processor.HammingDistance(image.Hashes.PHash, hashes.PHash)
+		if err != nil {
+			log.Printf("Skipping image %s in hash index: %v", candidateID, err)
+			continue
+		}
+		if distance > maxDistance {
+			continue
+		}
+		dhashDistance, err := processor.HammingDistance(image.Hashes.DHash, hashes.DHash)
+		if err != nil {
+			log.Printf("Skipping image %s in hash index: %v", candidateID, err)
+			continue
+		}
+
+		similar = append(similar, entity.SimilarImage{ID: candidateID, Distance: distance, DHashDistance: dhashDistance})
+	}
+
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Distance != similar[j].Distance {
+			return similar[i].Distance < similar[j].Distance
+		}
+		if similar[i].DHashDistance != similar[j].DHashDistance {
+			return similar[i].DHashDistance < similar[j].DHashDistance
+		}
+		return similar[i].ID < similar[j].ID
+	})
+
+	return &entity.SimilarImagesResponse{ID: id, MaxDistance: maxDistance, Similar: similar}, nil
+}
+
+// imageTenant - арендатор изображения; у загруженных до арендаторов он не записан
+func imageTenant(image *entity.Image) string {
+	if image.TenantID == "" {
+		return entity.DefaultTenant
+	}
+	return image.TenantID
+}
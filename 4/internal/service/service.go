@@ -1,30 +1,72 @@
-package service
-
-import (
-	"mime/multipart"
-
-	"github.com/ds124wfegd/WB_L3/4/internal/database"
-	"github.com/ds124wfegd/WB_L3/4/internal/entity"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
-	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
-)
-
-type ImageService interface {
-	ProcessImage(id string, file *multipart.FileHeader) (string, error)
-	GetImage(id string) (*entity.Image, error)
-	DeleteImage(id string) error
-}
-
-type imageService struct {
-	repo      database.ImageRepository
-	producer  kafka.Producer
-	processor processor.ImageProcessor
-}
-
-func NewImageService(repo database.ImageRepository, producer kafka.Producer, processor processor.ImageProcessor) ImageService {
-	return &imageService{
-		repo:      repo,
-		producer:  producer,
-		processor: processor,
-	}
-}
+package service
+
+import (
+	"mime/multipart"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/database"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/kafka"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/processor"
+	"github.com/ds124wfegd/WB_L3/4/internal/pkg/secret"
+)
+
+type ImageService interface {
+	ProcessImage(id string, tenantID string, deliveries []string, encoders entity.EncoderSelection, file *multipart.FileHeader) (*entity.Image, error)
+	GetImage(id string) (*entity.Image, error)
+	DeleteImage(id string) error
+	ApplyResult(result *entity.ProcessingResult) error
+	// ApplyDeliveries записывает итоги выгрузки поверх статусов pending из итога обработки
+	ApplyDeliveries(report *entity.DeliveryReport) error
+	// FindSimilar ищет изображения арендатора с pHash не дальше maxDistance бит;
+	// при отрицательном maxDistance берётся порог из конфигурации
+	FindSimilar(id string, maxDistance int) (*entity.SimilarImagesResponse, error)
+}
+
+type WatermarkService interface {
+	CreateWatermark(tenantID string, req *entity.WatermarkRequest, file *multipart.FileHeader) (*entity.WatermarkConfig, error)
+	GetWatermark(tenantID string, version int) (*entity.WatermarkConfig, error)
+	ListWatermarks(tenantID string) ([]entity.WatermarkConfig, error)
+}
+
+type DestinationService interface {
+	CreateDestination(tenantID string, req *entity.DestinationRequest) (*entity.Destination, error)
+	ListDestinations(tenantID string) ([]entity.Destination, error)
+	DeleteDestination(tenantID, id string) error
+}
+
+type imageService struct {
+	repo         database.ImageRepository
+	destinations database.DestinationRepository
+	hashes       database.HashIndex
+	producer     kafka.Producer
+	encoders     *processor.EncoderProfiles
+	maxDistance  int
+}
+
+func NewImageService(repo database.ImageRepository, destinations database.DestinationRepository, hashes database.HashIndex, producer kafka.Producer, encoders *processor.EncoderProfiles, maxDistance int) ImageService {
+	return &imageService{
+		repo:         repo,
+		destinations: destinations,
+		hashes:       hashes,
+		producer:     producer,
+		encoders:     encoders,
+		maxDistance:  maxDistance,
+	}
+}
+
+type watermarkService struct {
+	repo database.WatermarkRepository
+}
+
+func NewWatermarkService(repo database.WatermarkRepository) WatermarkService {
+	return &watermarkService{repo: repo}
+}
+
+type destinationService struct {
+	repo   database.DestinationRepository
+	cipher *secret.Cipher
+}
+
+func NewDestinationService(repo database.DestinationRepository, cipher *secret.Cipher) DestinationService {
+	return &destinationService{repo: repo, cipher: cipher}
+}
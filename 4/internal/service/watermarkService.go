@@ -0,0 +1,58 @@
+package service
+
+import (
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+)
+
+func (s *watermarkService) CreateWatermark(tenantID string, req *entity.WatermarkRequest, file *multipart.FileHeader) (*entity.WatermarkConfig, error) {
+	config := &entity.WatermarkConfig{
+		TenantID: tenantID,
+		Text:     strings.TrimSpace(req.Text),
+		Position: req.Position,
+		Opacity:  req.Opacity,
+		Scale:    req.Scale,
+	}
+
+	if config.Text == "" && file == nil {
+		return nil, entity.ErrInvalidWatermark
+	}
+
+	var mark io.Reader
+	var ext string
+	if file != nil {
+		src, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer src.Close()
+
+		mark = src
+		ext = strings.ToLower(filepath.Ext(file.Filename))
+	}
+
+	if err := config.ApplyDefaults(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(config, mark, ext); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (s *watermarkService) GetWatermark(tenantID string, version int) (*entity.WatermarkConfig, error) {
+	if version == 0 {
+		return s.repo.Latest(tenantID)
+	}
+	return s.repo.Get(tenantID, version)
+}
+
+func (s *watermarkService) ListWatermarks(tenantID string) ([]entity.WatermarkConfig, error) {
+	return s.repo.List(tenantID)
+}
@@ -0,0 +1,72 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *DestinationHandler) CreateDestination(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var req entity.DestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	destination, err := h.service.CreateDestination(tenantID, &req)
+	if err != nil {
+		c.JSON(destinationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, destination)
+}
+
+func (h *DestinationHandler) ListDestinations(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	destinations, err := h.service.ListDestinations(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, destinations)
+}
+
+func (h *DestinationHandler) DeleteDestination(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDestination(tenantID, c.Param("id")); err != nil {
+		c.JSON(destinationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Destination deleted successfully"})
+}
+
+func destinationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrDestinationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrInvalidDestination):
+		return http.StatusBadRequest
+	case errors.Is(err, entity.ErrDeliveryDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// destinationRecorder - назначение "dst-1" принадлежит арендатору acme; запоминает,
+// от имени каких арендаторов вызывался сервис
+type destinationRecorder struct {
+	service.DestinationService
+	tenants []string
+	deleted []string
+}
+
+func (s *destinationRecorder) ListDestinations(tenantID string) ([]entity.Destination, error) {
+	s.tenants = append(s.tenants, tenantID)
+	if tenantID != "acme" {
+		return []entity.Destination{}, nil
+	}
+	return []entity.Destination{{ID: "dst-1", TenantID: "acme", Type: entity.DestinationS3}}, nil
+}
+
+func (s *destinationRecorder) DeleteDestination(tenantID, id string) error {
+	s.tenants = append(s.tenants, tenantID)
+	if tenantID != "acme" || id != "dst-1" {
+		return entity.ErrDestinationNotFound
+	}
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func newDestinationRouter(t *testing.T, svc service.DestinationService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TenantAuth(newTestTenantKeys(t)))
+	destinations := router.Group("/destinations", RequireTenant())
+	handler := NewDestinationHandler(svc)
+	destinations.GET("", handler.ListDestinations)
+	destinations.DELETE("/:id", handler.DeleteDestination)
+	return router
+}
+
+// TestDestinationsRequireTenantKey - без ключа API назначения не видны и не удаляются,
+// даже если передать X-Tenant-ID владельца
+func TestDestinationsRequireTenantKey(t *testing.T) {
+	svc := &destinationRecorder{}
+	router := newDestinationRouter(t, svc)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		path := "/destinations"
+		if method == http.MethodDelete {
+			path += "/dst-1"
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(tenantHeader, "acme")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, method)
+	}
+	assert.Empty(t, svc.tenants, "service must not be called")
+}
+
+// TestDestinationsScopedToKeyTenant - ключ другого арендатора не открывает чужие назначения
+func TestDestinationsScopedToKeyTenant(t *testing.T) {
+	svc := &destinationRecorder{}
+	router := newDestinationRouter(t, svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/destinations", nil)
+	req.Header.Set("Authorization", "Bearer "+otherKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "dst-1")
+
+	req = httptest.NewRequest(http.MethodDelete, "/destinations/dst-1", nil)
+	req.Header.Set("Authorization", "Bearer "+otherKey)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, svc.deleted)
+
+	req = httptest.NewRequest(http.MethodDelete, "/destinations/dst-1", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeKey)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"dst-1"}, svc.deleted)
+	assert.Equal(t, []string{"other", "other", "acme"}, svc.tenants)
+}
+
+// uploadRecorder запоминает арендатора и назначения загрузки
+type uploadRecorder struct {
+	service.ImageService
+	tenantID   string
+	deliveries []string
+	called     bool
+}
+
+func (s *uploadRecorder) ProcessImage(id string, tenantID string, deliveries []string, encoders entity.EncoderSelection, file *multipart.FileHeader) (*entity.Image, error) {
+	s.called = true
+	s.tenantID = tenantID
+	s.deliveries = deliveries
+	return &entity.Image{ID: id, TenantID: tenantID, Status: "processing"}, nil
+}
+
+// TestUploadDeliveryRequiresTenantKey - выгрузка в назначения арендатора требует его ключа API,
+// а загрузка без выгрузки остаётся доступной без ключа
+func TestUploadDeliveryRequiresTenantKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		auth       string
+		fields     map[string]string
+		want       int
+		wantTenant string
+	}{
+		{"anonymous upload", "", nil, http.StatusAccepted, entity.DefaultTenant},
+		{"anonymous delivery", "", map[string]string{"deliver_to": "dst-1"}, http.StatusUnauthorized, ""},
+		{"spoofed tenant field", "", map[string]string{"deliver_to": "dst-1", "tenant_id": "acme"}, http.StatusForbidden, ""},
+		{"tenant delivery", "Bearer " + acmeKey, map[string]string{"deliver_to": "dst-1"}, http.StatusAccepted, "acme"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &uploadRecorder{}
+			router := gin.New()
+			router.Use(TenantAuth(newTestTenantKeys(t)))
+			router.POST("/upload", NewImageHandler(svc).UploadImage)
+
+			body, contentType := multipartForm(t, tc.fields, "image")
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.want, rec.Code, rec.Body.String())
+			assert.Equal(t, tc.want == http.StatusAccepted, svc.called)
+			assert.Equal(t, tc.wantTenant, svc.tenantID)
+		})
+	}
+}
@@ -1,13 +1,29 @@
-package transport
-
-import (
-	"github.com/ds124wfegd/WB_L3/4/internal/service"
-)
-
-type ImageHandler struct {
-	service service.ImageService
-}
-
-func NewImageHandler(service service.ImageService) *ImageHandler {
-	return &ImageHandler{service: service}
-}
+package transport
+
+import (
+	"github.com/ds124wfegd/WB_L3/4/internal/service"
+)
+
+type ImageHandler struct {
+	service service.ImageService
+}
+
+func NewImageHandler(service service.ImageService) *ImageHandler {
+	return &ImageHandler{service: service}
+}
+
+type WatermarkHandler struct {
+	service service.WatermarkService
+}
+
+func NewWatermarkHandler(service service.WatermarkService) *WatermarkHandler {
+	return &WatermarkHandler{service: service}
+}
+
+type DestinationHandler struct {
+	service service.DestinationService
+}
+
+func NewDestinationHandler(service service.DestinationService) *DestinationHandler {
+	return &DestinationHandler{service: service}
+}
@@ -1,83 +1,193 @@
-package transport
-
-import (
-	"net/http"
-	"path/filepath"
-
-	"github.com/ds124wfegd/WB_L3/4/internal/entity"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-)
-
-func (h *ImageHandler) UploadImage(c *gin.Context) {
-	file, err := c.FormFile("image")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
-		return
-	}
-
-	// Проверка типа файла
-	ext := filepath.Ext(file.Filename)
-	if !isValidImageType(ext) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image type. Supported: jpg, jpeg, png, gif"})
-		return
-	}
-
-	// Генерация ID
-	id := uuid.New().String()
-
-	// Сохранение и обработка
-	imageID, err := h.service.ProcessImage(id, file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusAccepted, entity.UploadResponse{
-		ID:     imageID,
-		Status: "processing",
-	})
-}
-
-func (h *ImageHandler) GetImage(c *gin.Context) {
-	id := c.Param("id")
-
-	image, err := h.service.GetImage(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		return
-	}
-
-	response := entity.ImageResponse{
-		ID:     image.ID,
-		Status: image.Status,
-	}
-
-	if image.Status == "completed" {
-		response.Formats = image.Formats
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func (h *ImageHandler) DeleteImage(c *gin.Context) {
-	id := c.Param("id")
-
-	err := h.service.DeleteImage(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
-}
-
-func isValidImageType(ext string) bool {
-	validTypes := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
-	}
-	return validTypes[ext]
-}
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *ImageHandler) UploadImage(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		return
+	}
+
+	// Проверка типа файла
+	ext := filepath.Ext(file.Filename)
+	if !isValidImageType(ext) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image type. Supported: jpg, jpeg, png, gif"})
+		return
+	}
+
+	// Назначения выгрузки: повторяющееся поле deliver_to или список через запятую
+	var deliveries []string
+	for _, value := range c.PostFormArray("deliver_to") {
+		for _, destinationID := range strings.Split(value, ",") {
+			if destinationID = strings.TrimSpace(destinationID); destinationID != "" {
+				deliveries = append(deliveries, destinationID)
+			}
+		}
+	}
+	// Выгрузка идёт с учётными данными арендатора, поэтому без его ключа API недоступна
+	if len(deliveries) > 0 && c.GetString(tenantContextKey) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "tenant API key required for deliver_to"})
+		return
+	}
+
+	// Профиль кодирования: поле profile для всех операций, <операция>_profile - для одной
+	encoders := entity.EncoderSelection{
+		Default:    strings.TrimSpace(c.PostForm("profile")),
+		Operations: make(map[string]string),
+	}
+	for _, operation := range []string{"resize", "thumbnail", "watermark"} {
+		if profile := strings.TrimSpace(c.PostForm(operation + "_profile")); profile != "" {
+			encoders.Operations[operation] = profile
+		}
+	}
+
+	// Генерация ID
+	id := uuid.New().String()
+
+	// Сохранение и обработка
+	image, err := h.service.ProcessImage(id, tenantID, deliveries, encoders, file)
+	if err != nil {
+		if errors.Is(err, entity.ErrDestinationNotFound) || errors.Is(err, entity.ErrUnknownEncoderProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, entity.UploadResponse{
+		ID:      image.ID,
+		Status:  image.Status,
+		Preview: image.Preview,
+	})
+}
+
+func (h *ImageHandler) GetImage(c *gin.Context) {
+	id := c.Param("id")
+
+	image, err := h.service.GetImage(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	response := entity.ImageResponse{
+		ID:      image.ID,
+		Status:  image.Status,
+		Preview: image.Preview,
+	}
+
+	if image.Status == "completed" {
+		response.Formats = image.Formats
+		response.Watermarks = image.Watermarks
+		response.Profiles = image.Profiles
+		response.Deliveries = image.Deliveries
+		response.Manifest = image.Manifest
+	}
+	if image.Status == "failed" {
+		response.Error = image.Error
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetImageFile отдаёт готовый вариант изображения (resized, thumbnail, watermark), чтобы
+// другие сервисы могли ссылаться на него, не имея доступа к хранилищу
+func (h *ImageHandler) GetImageFile(c *gin.Context) {
+	image, err := h.service.GetImage(c.Param("id"))
+	if err != nil || image == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	path, ok := image.Formats[c.Param("format")]
+	if image.Status != "completed" || !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image format not found"})
+		return
+	}
+
+	c.File(path)
+}
+
+// GetManifest отдаёт манифест вариантов обработанного изображения с их размерами и SHA-256.
+// У изображений, обработанных до появления манифестов, его нет
+func (h *ImageHandler) GetManifest(c *gin.Context) {
+	image, err := h.service.GetImage(c.Param("id"))
+	if err != nil || image == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	if image.Status != "completed" || image.Manifest == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image manifest not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, image.Manifest)
+}
+
+func (h *ImageHandler) DeleteImage(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.service.DeleteImage(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+// FindSimilar ищет похожие ранее обработанные изображения: ?max_distance=<0..64> бит pHash
+func (h *ImageHandler) FindSimilar(c *gin.Context) {
+	maxDistance := -1
+	if raw := c.Query("max_distance"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 || value > 64 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_distance must be an integer from 0 to 64"})
+			return
+		}
+		maxDistance = value
+	}
+
+	similar, err := h.service.FindSimilar(c.Param("id"), maxDistance)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		case errors.Is(err, entity.ErrImageNotHashed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, similar)
+}
+
+func isValidImageType(ext string) bool {
+	validTypes := map[string]bool{
+		".jpg":  true,
+		".jpeg": true,
+		".png":  true,
+		".gif":  true,
+	}
+	return validTypes[ext]
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tenantHeader     = "X-Tenant-ID"
+	tenantContextKey = "tenant_id"
+)
+
+// TenantKeys сопоставляет SHA-256 ключа API с арендатором
+type TenantKeys map[string]string
+
+// NewTenantKeys проверяет арендаторов из конфигурации; повторный хеш - ошибка,
+// иначе один ключ открывал бы двух арендаторов
+func NewTenantKeys(tenants []config.TenantConfig) (TenantKeys, error) {
+	keys := make(TenantKeys, len(tenants))
+	for _, tenant := range tenants {
+		if !entity.ValidTenantID(tenant.ID) {
+			return nil, fmt.Errorf("tenant %q: %w", tenant.ID, entity.ErrInvalidTenant)
+		}
+		hash := strings.ToLower(tenant.APIKeySHA256)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("tenant %q: api_key_sha256 must be a hex SHA-256", tenant.ID)
+		}
+		if other, exists := keys[hash]; exists {
+			return nil, fmt.Errorf("tenants %q and %q share an API key", other, tenant.ID)
+		}
+		keys[hash] = tenant.ID
+	}
+	return keys, nil
+}
+
+func (k TenantKeys) lookup(apiKey string) (string, bool) {
+	sum := sha256.Sum256([]byte(apiKey))
+	tenantID, ok := k[hex.EncodeToString(sum[:])]
+	return tenantID, ok
+}
+
+// TenantAuth определяет арендатора по ключу API из Authorization: Bearer <key>.
+// Запрос без ключа выполняется от арендатора по умолчанию, неизвестный ключ - 401
+func TenantAuth(keys TenantKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		apiKey, ok := strings.CutPrefix(header, "Bearer ")
+		tenantID, known := keys.lookup(apiKey)
+		if !ok || !known {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+			return
+		}
+
+		c.Set(tenantContextKey, tenantID)
+		c.Next()
+	}
+}
+
+// RequireTenant пропускает только запросы с ключом API арендатора
+func RequireTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(tenantContextKey) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "tenant API key required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tenantFromRequest возвращает арендатора, определённого TenantAuth по ключу API.
+// X-Tenant-ID и поле формы tenant_id больше не выбирают арендатора: если клиент их передал
+// и они не совпадают с арендатором ключа, запрос отклоняется с 403
+func tenantFromRequest(c *gin.Context) (string, bool) {
+	tenantID := c.GetString(tenantContextKey)
+	if tenantID == "" {
+		tenantID = entity.DefaultTenant
+	}
+
+	claimed := c.GetHeader(tenantHeader)
+	if claimed == "" {
+		claimed = c.PostForm("tenant_id")
+	}
+	if claimed != "" && claimed != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "tenant does not match the API key"})
+		return "", false
+	}
+
+	return tenantID, true
+}
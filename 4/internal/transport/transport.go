@@ -1,42 +1,66 @@
-package transport
-
-import (
-	"github.com/gin-gonic/gin"
-)
-
-func InitRoutes(imgHandler *ImageHandler) *gin.Engine {
-	router := gin.Default()
-
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	router.POST("/upload", imgHandler.UploadImage)
-	router.GET("/image/:id", imgHandler.GetImage)
-	router.DELETE("/image/:id", imgHandler.DeleteImage)
-
-	router.Static("/static", "/app/internal/web/templates")
-	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
-
-	router.GET("/", func(c *gin.Context) {
-		c.File("/app/internal/web/templates/index.html")
-	})
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"service": "image-processor-service",
-		})
-	})
-	return router
-}
+package transport
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoutes(imgHandler *ImageHandler, watermarkHandler *WatermarkHandler, destinationHandler *DestinationHandler, tenants TenantKeys) *gin.Engine {
+	router := gin.Default()
+
+	router.Use(func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Tenant-ID")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+	router.Use(TenantAuth(tenants))
+
+	router.POST("/upload", imgHandler.UploadImage)
+	router.GET("/image/:id", imgHandler.GetImage)
+	router.GET("/image/:id/manifest.json", imgHandler.GetManifest)
+	router.GET("/image/:id/:format", imgHandler.GetImageFile)
+	router.DELETE("/image/:id", imgHandler.DeleteImage)
+
+	api := router.Group("/api/v1")
+	{
+		api.GET("/images/:id/similar", imgHandler.FindSimilar)
+	}
+
+	// Водяные знаки арендатора, определённого по ключу API
+	watermarks := router.Group("/watermarks", RequireTenant())
+	{
+		watermarks.POST("", watermarkHandler.CreateWatermark)
+		watermarks.GET("", watermarkHandler.ListWatermarks)
+		watermarks.GET("/:version", watermarkHandler.GetWatermark)
+	}
+
+	// Внешние хранилища арендатора для выгрузки результатов, только по ключу API
+	destinations := router.Group("/destinations", RequireTenant())
+	{
+		destinations.POST("", destinationHandler.CreateDestination)
+		destinations.GET("", destinationHandler.ListDestinations)
+		destinations.DELETE("/:id", destinationHandler.DeleteDestination)
+	}
+
+	router.Static("/static", "/app/internal/web/templates")
+	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
+
+	router.GET("/", func(c *gin.Context) {
+		c.File("/app/internal/web/templates/index.html")
+	})
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":  "ok",
+			"service": "image-processor-service",
+		})
+	})
+	return router
+}
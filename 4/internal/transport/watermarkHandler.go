@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *WatermarkHandler) CreateWatermark(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var req entity.WatermarkRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Картинка водяного знака необязательна: можно ограничиться текстом
+	file, err := c.FormFile("image")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if file != nil && !isValidImageType(filepath.Ext(file.Filename)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image type. Supported: jpg, jpeg, png, gif"})
+		return
+	}
+
+	config, err := h.service.CreateWatermark(tenantID, &req, file)
+	if err != nil {
+		c.JSON(watermarkErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, config)
+}
+
+func (h *WatermarkHandler) ListWatermarks(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListWatermarks(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"tenant_id": tenantID, "versions": versions}
+	if len(versions) > 0 {
+		response["current"] = versions[len(versions)-1].Version
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *WatermarkHandler) GetWatermark(c *gin.Context) {
+	tenantID, ok := tenantFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// "default" возвращает текущую версию, как и ссылка в задаче обработки
+	var version int
+	if ref := c.Param("version"); ref != entity.DefaultWatermark {
+		v, err := strconv.Atoi(ref)
+		if err != nil || v <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid watermark version"})
+			return
+		}
+		version = v
+	}
+
+	config, err := h.service.GetWatermark(tenantID, version)
+	if err != nil {
+		c.JSON(watermarkErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+func watermarkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrWatermarkNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrInvalidWatermark), errors.Is(err, entity.ErrInvalidTenant):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
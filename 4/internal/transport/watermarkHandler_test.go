@@ -0,0 +1,152 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/4/config"
+	"github.com/ds124wfegd/WB_L3/4/internal/entity"
+	"github.com/ds124wfegd/WB_L3/4/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	acmeKey  = "acme-secret-key"
+	otherKey = "other-secret-key"
+)
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// multipartForm собирает форму из полей и файлов files с именем <поле>.png
+func multipartForm(t *testing.T, fields map[string]string, files ...string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	for _, name := range files {
+		part, err := writer.CreateFormFile(name, name+".png")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("png"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+func newTestTenantKeys(t *testing.T) TenantKeys {
+	keys, err := NewTenantKeys([]config.TenantConfig{
+		{ID: "acme", APIKeySHA256: keyHash(acmeKey)},
+		{ID: "other", APIKeySHA256: keyHash(otherKey)},
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+// watermarkRecorder запоминает арендаторов, от имени которых вызывался сервис
+type watermarkRecorder struct {
+	service.WatermarkService
+	tenants []string
+}
+
+func (s *watermarkRecorder) CreateWatermark(tenantID string, req *entity.WatermarkRequest, file *multipart.FileHeader) (*entity.WatermarkConfig, error) {
+	s.tenants = append(s.tenants, tenantID)
+	return &entity.WatermarkConfig{TenantID: tenantID, Version: 1, Text: req.Text}, nil
+}
+
+func (s *watermarkRecorder) ListWatermarks(tenantID string) ([]entity.WatermarkConfig, error) {
+	s.tenants = append(s.tenants, tenantID)
+	return nil, nil
+}
+
+func newWatermarkRouter(t *testing.T, svc service.WatermarkService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TenantAuth(newTestTenantKeys(t)))
+	watermarks := router.Group("/watermarks", RequireTenant())
+	handler := NewWatermarkHandler(svc)
+	watermarks.POST("", handler.CreateWatermark)
+	watermarks.GET("", handler.ListWatermarks)
+	return router
+}
+
+// TestWatermarkTenantFromAPIKey - арендатор берётся из ключа API, а не из заголовка или формы
+func TestWatermarkTenantFromAPIKey(t *testing.T) {
+	cases := []struct {
+		name   string
+		auth   string
+		header string
+		want   int
+	}{
+		{"spoofed header without key", "", "acme", http.StatusUnauthorized},
+		{"unknown key", "Bearer guessed", "acme", http.StatusUnauthorized},
+		{"key of another tenant", "Bearer " + otherKey, "acme", http.StatusForbidden},
+		{"matching header", "Bearer " + acmeKey, "acme", http.StatusOK},
+		{"key only", "Bearer " + acmeKey, "", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &watermarkRecorder{}
+			router := newWatermarkRouter(t, svc)
+
+			req := httptest.NewRequest(http.MethodGet, "/watermarks", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			if tc.header != "" {
+				req.Header.Set(tenantHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.want, rec.Code, rec.Body.String())
+			if tc.want == http.StatusOK {
+				assert.Equal(t, []string{"acme"}, svc.tenants)
+			} else {
+				assert.Empty(t, svc.tenants, "service must not be called")
+			}
+		})
+	}
+}
+
+// TestCreateWatermarkRejectsForeignTenantField - поле формы tenant_id не подменяет арендатора ключа
+func TestCreateWatermarkRejectsForeignTenantField(t *testing.T) {
+	svc := &watermarkRecorder{}
+	router := newWatermarkRouter(t, svc)
+
+	body, contentType := multipartForm(t, map[string]string{"tenant_id": "acme", "text": "mine now"})
+	req := httptest.NewRequest(http.MethodPost, "/watermarks", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+otherKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Empty(t, svc.tenants)
+}
+
+// TestNewTenantKeysValidation - битый хеш или общий ключ у двух арендаторов - ошибка конфигурации
+func TestNewTenantKeysValidation(t *testing.T) {
+	_, err := NewTenantKeys([]config.TenantConfig{{ID: "acme", APIKeySHA256: "not-a-hash"}})
+	assert.Error(t, err)
+
+	_, err = NewTenantKeys([]config.TenantConfig{
+		{ID: "acme", APIKeySHA256: keyHash(acmeKey)},
+		{ID: "other", APIKeySHA256: keyHash(acmeKey)},
+	})
+	assert.Error(t, err)
+
+	_, err = NewTenantKeys([]config.TenantConfig{{ID: "../acme", APIKeySHA256: keyHash(acmeKey)}})
+	assert.Error(t, err)
+}
@@ -158,7 +158,7 @@ class ImageProcessor {
                 uploadTime: uploadTime
             });
             
-            this.addImage(result.id, result.status, file.name, uploadTime);
+            this.addImage(result.id, result.status, file.name, uploadTime, result.preview);
             
             // Начинаем опрос статуса
             this.pollImageStatus(result.id);
@@ -274,12 +274,13 @@ class ImageProcessor {
         return await response.json();
     }
 
-    addImage(id, status, filename = '', uploadTime = new Date()) {
+    addImage(id, status, filename = '', uploadTime = new Date(), preview = '') {
         this.images.set(id, { 
             id, 
             status, 
             filename,
-            uploadTime: uploadTime
+            uploadTime: uploadTime,
+            preview
         });
         this.renderImages();
     }
@@ -414,6 +415,14 @@ class ImageProcessor {
                 img.onclick = () => this.showModal('Original Image (Processing)', originalData.url, 'Image is currently being processed');
                 preview.style.cursor = 'pointer';
                 preview.appendChild(img);
+            } else if (image.preview) {
+                // Превью, построенное сервером при загрузке, пока процессор готовит варианты
+                const img = document.createElement('img');
+                img.src = image.preview;
+                img.className = 'original-preview';
+                img.alt = `Preview ${image.id}`;
+                img.style.filter = 'blur(4px)';
+                preview.appendChild(img);
             } else {
                 preview.innerHTML = `
                     <div class="processing-placeholder">
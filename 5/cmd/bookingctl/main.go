@@ -0,0 +1,393 @@
+// bookingctl — консольная утилита администратора для обслуживания бронирований и очереди задач
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	redisdb "github.com/ds124wfegd/WB_L3/5/internal/database/redis"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/postgres"
+	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
+	"github.com/ds124wfegd/WB_L3/5/pkg/redis"
+)
+
+// dlqKey - DLQ очереди сервиса; в Redis Cluster её ключи под hash tag
+func dlqKey(cfg config.RedisConfig) string {
+	if cfg.Mode == config.RedisModeCluster {
+		return queue.ClusterKeyPrefix + ":dlq"
+	}
+	return queue.DefaultKeyPrefix + ":dlq"
+}
+
+const usage = `Usage: bookingctl <command> [arguments]
+
+Commands:
+  expire [-dry-run]            expire pending bookings whose deadline has passed
+  dlq list [-limit N]          list failed tasks in the dead letter queue
+  dlq stats                    show dead letter queue statistics
+  dlq requeue <task_id>        move a failed task back to the main queue
+  dlq delete <task_id>         permanently remove a failed task
+  dlq migrate                  move DLQ entries stored in the old format into the task index
+  stats [event_id]             recompute booking statistics for one or all events
+  reconcile                    run consistency checks between bookings, events and the DLQ
+  role <email> <user|admin>    change the role of a user
+`
+
+// app содержит зависимости, общие для всех подкоманд
+type app struct {
+	cfg            *config.Config
+	bookingService service.BookingService
+	eventService   service.EventService
+	userService    service.UserService
+	dlq            *queue.DefaultDLQHandler
+	closers        []func() error
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	viperInstance, err := config.LoadConfig()
+	if err != nil {
+		fatalf("cannot load config: %v", err)
+	}
+
+	cfg, err := config.ParseConfig(viperInstance)
+	if err != nil {
+		fatalf("cannot parse config: %v", err)
+	}
+
+	a, err := newApp(cfg)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	defer a.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "expire":
+		err = a.runExpire(ctx, args)
+	case "dlq":
+		err = a.runDLQ(ctx, args)
+	case "stats":
+		err = a.runStats(ctx, args)
+	case "reconcile":
+		err = a.runReconcile(ctx)
+	case "role":
+		err = a.runRole(ctx, args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		a.close()
+		fatalf("%s: %v", cmd, err)
+	}
+}
+
+// newApp подключается к PostgreSQL и Redis по настройкам из config.yaml и собирает сервисный слой.
+// Очередь задач и Telegram-бот не подключаются: утилита не должна рассылать уведомления.
+func newApp(cfg *config.Config) (*app, error) {
+	db, err := postgres.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Реплика не подключается: утилита сразу перечитывает то, что изменила
+	eventRepo := repository.NewEventRepository(db, nil)
+	bookingRepo := repository.NewBookingRepository(db, nil)
+	userRepo := repository.NewUserRepository(db, nil)
+	tierRepo := repository.NewTicketTierRepository(db)
+	poolRepo := repository.NewPartnerPoolRepository(db)
+	promoRepo := repository.NewPromoCodeRepository(db)
+	venueRepo := repository.NewVenueRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	posterRepo := repository.NewEventPosterRepository(db)
+
+	closers := []func() error{db.Close}
+
+	var dlq *queue.DefaultDLQHandler
+	if cfg.Redis.Enabled() {
+		redisClient := redis.NewRedisClient(&cfg.Redis)
+		dlq = queue.NewDefaultDLQHandler(redisClient, dlqKey(cfg.Redis))
+		closers = append(closers, redisClient.Close)
+		// Утилита меняет бронирования в обход API и тоже сбрасывает кэш занятости мест
+		if cfg.Booking.AvailabilityCache {
+			eventRepo = service.WithAvailabilityCache(eventRepo, redisdb.NewAvailabilityCache(redisClient, cfg.Booking.AvailabilityCacheTTL))
+		}
+	}
+
+	a := &app{
+		cfg:            cfg,
+		bookingService: service.NewBookingService(bookingRepo, eventRepo, userRepo, tierRepo, poolRepo, promoRepo, refundRepo, auditRepo, repository.NewTxManager(db), nil, nil, nil),
+		eventService:   service.NewEventService(eventRepo, bookingRepo, venueRepo, poolRepo, posterRepo, nil),
+		userService:    service.NewUserService(userRepo, bookingRepo, auditRepo),
+		dlq:            dlq,
+		closers:        closers,
+	}
+
+	return a, nil
+}
+
+func (a *app) close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+	a.closers = nil
+}
+
+// runExpire переводит просроченные ожидающие бронирования в статус expired
+func (a *app) runExpire(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("expire", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list stale bookings")
+	fs.Parse(args)
+
+	expired, err := a.bookingService.GetExpiredBookings(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "BOOKING\tEVENT\tUSER\tEXPIRED AT")
+	for _, b := range expired {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", b.BookingID, b.EventTitle, b.UserName, b.ExpiresAt.Format(time.RFC3339))
+	}
+	w.Flush()
+
+	if *dryRun || len(expired) == 0 {
+		fmt.Printf("%d stale bookings found\n", len(expired))
+		return nil
+	}
+
+	failed := 0
+	for _, b := range expired {
+		if err := a.bookingService.ExpireBooking(ctx, b.BookingID); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to expire booking %d: %v\n", b.BookingID, err)
+			failed++
+		}
+	}
+
+	fmt.Printf("%d bookings expired, %d failed\n", len(expired)-failed, failed)
+	return nil
+}
+
+// runDLQ управляет задачами в очереди недоставленных сообщений
+func (a *app) runDLQ(ctx context.Context, args []string) error {
+	if a.dlq == nil {
+		return errors.New("redis is not configured")
+	}
+	if len(args) == 0 {
+		return errors.New("missing dlq subcommand")
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+		limit := fs.Int("limit", 50, "maximum number of tasks to show")
+		fs.Parse(args[1:])
+
+		tasks, err := a.dlq.GetFailedTasks(ctx, *limit)
+		if err != nil {
+			return err
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "TASK\tTYPE\tATTEMPTS\tFAILED AT\tERROR")
+		for _, t := range tasks {
+			// Запись без задачи не удаётся ни повторить, ни удалить по ID, но показать её нужно
+			if t.Task == nil {
+				fmt.Fprintf(w, "-\t(malformed entry)\t%d\t%s\t%s\n", t.Attempts, t.FailedAt.Format(time.RFC3339), t.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", t.Task.ID, t.Task.Type, t.Attempts, t.FailedAt.Format(time.RFC3339), t.Error)
+		}
+		return w.Flush()
+
+	case "stats":
+		stats, err := a.dlq.GetDLQStats(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("size:           %d\n", stats.QueueSize)
+		fmt.Printf("oldest failure: %s\n", formatTime(stats.OldestFailure))
+		fmt.Printf("newest failure: %s\n", formatTime(stats.NewestFailure))
+		return nil
+
+	case "migrate":
+		migrated, err := a.dlq.MigrateLegacyEntries(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("migrated %d entries\n", migrated)
+		return nil
+
+	case "requeue", "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("dlq %s requires a task id", args[0])
+		}
+		for _, taskID := range args[1:] {
+			var err error
+			if args[0] == "requeue" {
+				err = a.dlq.RequeueFailedTask(ctx, taskID)
+			} else {
+				err = a.dlq.DeleteFailedTask(ctx, taskID)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("task %s: %sd\n", taskID, args[0])
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown dlq subcommand %q", args[0])
+	}
+}
+
+// runStats пересчитывает статистику бронирований по мероприятиям
+func (a *app) runStats(ctx context.Context, args []string) error {
+	var eventIDs []int64
+	if len(args) > 0 {
+		for _, arg := range args {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid event id %q", arg)
+			}
+			eventIDs = append(eventIDs, id)
+		}
+	} else {
+		events, err := a.eventService.GetAllEvents(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			eventIDs = append(eventIDs, e.ID)
+		}
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "EVENT\tTITLE\tTOTAL\tCONFIRMED\tPENDING\tCANCELLED\tEXPIRED\tAVAILABLE\tUTILIZATION")
+	for _, id := range eventIDs {
+		stats, err := a.eventService.GetEventStats(ctx, id)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", id, err)
+		}
+		b := stats.BookingStats
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f%%\n",
+			stats.Event.ID, stats.Event.Title, stats.Event.TotalSeats,
+			b.ConfirmedSeats, b.PendingSeats, b.CancelledSeats, b.ExpiredSeats,
+			stats.AvailableSeats, stats.UtilizationRate*100)
+	}
+	return w.Flush()
+}
+
+// runReconcile ищет расхождения между бронированиями, мероприятиями и DLQ
+func (a *app) runReconcile(ctx context.Context) error {
+	problems := 0
+	report := func(format string, args ...interface{}) {
+		problems++
+		fmt.Printf("  - "+format+"\n", args...)
+	}
+
+	fmt.Println("stale pending bookings:")
+	expired, err := a.bookingService.GetExpiredBookings(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, b := range expired {
+		report("booking %d is pending but expired at %s (run `bookingctl expire`)", b.BookingID, b.ExpiresAt.Format(time.RFC3339))
+	}
+
+	fmt.Println("overbooked events:")
+	events, err := a.eventService.GetAllEvents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if e.BookedSeats > e.TotalSeats {
+			report("event %d (%s) has %d confirmed seats out of %d", e.ID, e.Title, e.BookedSeats, e.TotalSeats)
+		}
+	}
+
+	if a.dlq != nil {
+		fmt.Println("dead letter queue tasks referencing missing bookings:")
+		tasks, err := a.dlq.GetFailedTasks(ctx, 1000)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if t.Task == nil {
+				report("DLQ entry failed at %s has no task payload", t.FailedAt.Format(time.RFC3339))
+				continue
+			}
+			bookingID := t.Task.GetInt("booking_id")
+			if bookingID == 0 {
+				continue
+			}
+			_, err := a.bookingService.GetBooking(ctx, int64(bookingID))
+			if errors.Is(err, entity.ErrBookingNotFound) {
+				report("task %s references deleted booking %d (run `bookingctl dlq delete %s`)", t.Task.ID, bookingID, t.Task.ID)
+			}
+		}
+	}
+
+	fmt.Printf("%d problems found\n", problems)
+	return nil
+}
+
+// runRole назначает пользователю роль; так выдаются первые права администратора
+func (a *app) runRole(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: role <email> <user|admin>")
+	}
+
+	user, err := a.userService.GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return entity.ErrUserNotFound
+	}
+
+	if err := a.userService.SetUserRole(ctx, user.ID, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s (#%d): role set to %s\n", user.Email, user.ID, args[1])
+	return nil
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "bookingctl: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -1,257 +1,526 @@
-// Ininicializing common application configuration
-package config
-
-import (
-	"log"
-	"os"
-	"time"
-
-	"github.com/spf13/viper"
-)
-
-type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	App      AppConfig      `mapstructure:"app"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Email    EmailConfig    `mapstructure:"email"`
-	Telegram TelegramConfig `mapstructure:"telegram"`
-	Booking  BookingConfig  `mapstructure:"booking"`
-	Worker   WorkerConfig   `mapstructure:"worker"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-}
-
-type ServerConfig struct {
-	AppVersion   string `json:"appVersion"`
-	Host         string `json:"host" validate:"required"`
-	Port         string `json:"port" validate:"required"`
-	Timeout      time.Duration
-	Idle_timeout time.Duration
-	Env          string `json:"environment"`
-	Mode         string `mapstructure:"mode"`
-}
-
-type DatabaseConfig struct {
-	Host            string        `mapstructure:"host"`
-	Port            int           `mapstructure:"port"`
-	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
-	DBName          string        `mapstructure:"dbname"`
-	SSLMode         string        `mapstructure:"sslmode"`
-	MaxOpenConns    int           `mapstructure:"max_open_conns"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
-	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
-}
-
-type AppConfig struct {
-	ShortURLLength int           `mapstructure:"short_url_length"`
-	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
-	BaseURL        string        `mapstructure:"base_url"`
-}
-
-type JWTConfig struct {
-	Secret     string        `mapstructure:"secret"`
-	Expiration time.Duration `mapstructure:"expiration"`
-}
-
-type EmailConfig struct {
-	From     string `mapstructure:"from"`
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	Enabled  bool   `mapstructure:"enabled"`
-}
-
-type TelegramConfig struct {
-	BotToken string `mapstructure:"bot_token"`
-	ChatID   string `mapstructure:"chat_id"`
-	Enabled  bool   `mapstructure:"enabled"`
-}
-
-type BookingConfig struct {
-	DefaultTimeout int `mapstructure:"default_timeout"` // в минутах
-	MaxSeats       int `mapstructure:"max_seats"`
-}
-
-type WorkerConfig struct {
-	CleanupInterval int `mapstructure:"cleanup_interval"` // в минутах
-	BatchSize       int `mapstructure:"batch_size"`
-}
-
-type RedisConfig struct {
-	URL      string `json:"URL"`
-	Host     string `json:"host" validate:"required"`
-	Port     int    `json:"port" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	DB       int    `json:"db" validate:"required"`
-
-	// Настройки пула соединений
-	MaxRetries   int
-	PoolSize     int
-	MinIdleConns int
-	MaxIdleConns int
-	DialTimeout  time.Duration
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	PoolTimeout  time.Duration
-}
-
-func LoadConfig() (*viper.Viper, error) {
-
-	viperInstance := viper.New()
-
-	viperInstance.AddConfigPath("./config")
-	viperInstance.SetConfigName("config")
-	viperInstance.SetConfigType("yaml")
-
-	err := viperInstance.ReadInConfig()
-
-	if err != nil {
-		return nil, err
-	}
-	return viperInstance, nil
-}
-
-func ParseConfig(v *viper.Viper) (*Config, error) {
-
-	var c Config
-
-	err := v.Unmarshal(&c)
-	if err != nil {
-		log.Fatalf("unable to decode config into struct, %v", err)
-		return nil, err
-	}
-	return &c, nil
-}
-
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-/*
-
-
-// GetServerAddress возвращает полный адрес сервера
-func (c *Config) GetServerAddress() string {
-	return c.Server.Host + ":" + c.Server.Port
-}
-
-// IsProduction проверяет, production ли окружение
-func (c *Config) IsProduction() bool {
-	return c.Server.Env == "production"
-}
-
-// IsDevelopment проверяет, development ли окружение
-func (c *Config) IsDevelopment() bool {
-	return c.Server.Env == "development"
-}
-
-// GetDatabaseURL возвращает DSN строку для подключения к БД
-func (c *Config) GetDatabaseURL() string {
-	return "postgres://" + c.Database.User + ":" + c.Database.Password +
-		"@" + c.Database.Host + ":" + strconv.Itoa(c.Database.Port) +
-		"/" + c.Database.DBName + "?sslmode=" + c.Database.SSLMode
-}
-
-// GetEmailConfig возвращает конфигурацию email
-func (c *Config) GetEmailConfig() *EmailConfig {
-	return &c.Email
-}
-
-// GetTelegramConfig возвращает конфигурацию Telegram
-func (c *Config) GetTelegramConfig() *TelegramConfig {
-	return &c.Telegram
-}
-
-
-// setDefaults устанавливает значения по умолчанию
-func setDefaults(v *viper.Viper) {
-	// Server defaults
-	v.SetDefault("server.appVersion", "1.0.0")
-	v.SetDefault("server.host", "localhost")
-	v.SetDefault("server.port", "8080")
-	v.SetDefault("server.timeout", 30*time.Second)
-	v.SetDefault("server.idle_timeout", 60*time.Second)
-	v.SetDefault("server.environment", "development")
-	v.SetDefault("server.mode", "debug")
-
-	// Database defaults
-	v.SetDefault("database.host", "localhost")
-	v.SetDefault("database.port", 5432)
-	v.SetDefault("database.user", "eventbooker_user")
-	v.SetDefault("database.password", "password")
-	v.SetDefault("database.dbname", "eventbooker")
-	v.SetDefault("database.sslmode", "disable")
-	v.SetDefault("database.max_open_conns", 25)
-	v.SetDefault("database.max_idle_conns", 5)
-	v.SetDefault("database.conn_max_lifetime", 5*time.Minute)
-
-	// App defaults
-	v.SetDefault("app.short_url_length", 8)
-	v.SetDefault("app.cache_ttl", 15*time.Minute)
-	v.SetDefault("app.base_url", "http://localhost:8080")
-
-	// JWT defaults
-	v.SetDefault("jwt.secret", "your-super-secret-jwt-key-change-in-production")
-	v.SetDefault("jwt.expiration", 24*time.Hour)
-
-	// Email defaults
-	v.SetDefault("email.from", "noreply@eventbooker.com")
-	v.SetDefault("email.host", "smtp.gmail.com")
-	v.SetDefault("email.port", 587)
-	v.SetDefault("email.enabled", false)
-
-	// Telegram defaults
-	v.SetDefault("telegram.enabled", false)
-
-	// Booking defaults
-	v.SetDefault("booking.default_timeout", 30) // 30 минут
-	v.SetDefault("booking.max_seats", 1000)
-
-	// Worker defaults
-	v.SetDefault("worker.cleanup_interval", 1) // 1 минута
-	v.SetDefault("worker.batch_size", 100)
-}
-
-// GetEnv получает переменную окружения с fallback значением
-func GetEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-// GetEnvInt получает int переменную окружения с fallback значением
-func GetEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
-
-// GetEnvBool получает bool переменную окружения с fallback значением
-func GetEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
-		}
-	}
-	return defaultValue
-}
-
-// GetEnvDuration получает duration переменную окружения с fallback значением
-func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
-		}
-	}
-	return defaultValue
-}
-*/
+// Ininicializing common application configuration
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Config struct {
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	App       AppConfig       `mapstructure:"app"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Email     EmailConfig     `mapstructure:"email"`
+	Telegram  TelegramConfig  `mapstructure:"telegram"`
+	Booking   BookingConfig   `mapstructure:"booking"`
+	Worker    WorkerConfig    `mapstructure:"worker"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Queue     QueueConfig     `mapstructure:"queue"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
+	Images    ImagesConfig    `mapstructure:"images"`
+	GRPC      GRPCConfig      `mapstructure:"grpc"`
+	APIToken  APITokenConfig  `mapstructure:"api_token"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Health    HealthConfig    `mapstructure:"health"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	AdminFeed AdminFeedConfig `mapstructure:"admin_feed"`
+}
+
+type ServerConfig struct {
+	AppVersion   string `json:"appVersion"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required"`
+	Timeout      time.Duration
+	Idle_timeout time.Duration
+	Env          string `json:"environment" mapstructure:"environment"`
+	Mode         string `mapstructure:"mode"`
+	// ShutdownTimeout - срок остановки сервера, планировщиков, воркеров и очереди по SIGTERM
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port"`
+	User            string        `mapstructure:"user"`
+	Password        string        `mapstructure:"password"`
+	DBName          string        `mapstructure:"dbname"`
+	SSLMode         string        `mapstructure:"sslmode"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// ReplicaDSN - реплика только для чтения; на неё уходят тяжёлые выборки админки
+	// (списки, поиск, статистика). Пустая строка - все запросы идут в основную базу
+	ReplicaDSN string `mapstructure:"replica_dsn"`
+}
+
+type AppConfig struct {
+	ShortURLLength int           `mapstructure:"short_url_length"`
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	BaseURL        string        `mapstructure:"base_url"`
+}
+
+type JWTConfig struct {
+	Secret     string        `mapstructure:"secret"`
+	Expiration time.Duration `mapstructure:"expiration"`
+	// Срок токена, по которому администратор действует от имени пользователя
+	ImpersonationExpiration time.Duration `mapstructure:"impersonation_expiration"`
+}
+
+type EmailConfig struct {
+	From     string `mapstructure:"from"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Enabled  bool   `mapstructure:"enabled"`
+	// Timeout ограничивает подключение и весь диалог с SMTP-сервером, по умолчанию 30s
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type TelegramConfig struct {
+	BotToken string `mapstructure:"bot_token"`
+	ChatID   string `mapstructure:"chat_id"`
+	Enabled  bool   `mapstructure:"enabled"`
+
+	// Получение команд и нажатий кнопок: "polling", "webhook" или пусто - бот только отправляет уведомления.
+	// Long polling допустим на одном экземпляре сервиса, для нескольких нужен webhook.
+	Updates       string        `mapstructure:"updates"`
+	WebhookURL    string        `mapstructure:"webhook_url"` // публичный адрес POST /telegram/webhook
+	WebhookSecret string        `mapstructure:"webhook_secret"`
+	PollTimeout   time.Duration `mapstructure:"poll_timeout"`
+}
+
+type BookingConfig struct {
+	DefaultTimeout int `mapstructure:"default_timeout"` // в минутах
+	MaxSeats       int `mapstructure:"max_seats"`
+
+	// Корзина без изменений дольше CartTTL считается брошенной и закрывается
+	CartTTL time.Duration `mapstructure:"cart_ttl"`
+
+	// Таймеры истечения на ключах Redis с TTL и keyspace notifications: бронирование истекает
+	// в срок, а не на ближайшем тике планировщика. Без Redis или без права на CONFIG SET
+	// бронирования по-прежнему истекают по тику
+	ExpiryNotifications bool `mapstructure:"expiry_notifications"`
+
+	// Занятость мест для GET /events кэшируется в Redis и сбрасывается при изменении бронирований;
+	// ключ, сброс которого не удался, живёт не дольше AvailabilityCacheTTL
+	AvailabilityCache    bool          `mapstructure:"availability_cache"`
+	AvailabilityCacheTTL time.Duration `mapstructure:"availability_cache_ttl"`
+
+	// Изменения свободных мест рассылаются через Redis pub/sub и отдаются странице мероприятий
+	// потоком GET /events/:id/availability/stream; без Redis поток отвечает 503
+	AvailabilityStream bool `mapstructure:"availability_stream"`
+
+	// Пороги заполненности мероприятия в процентах, о достижении которых организатор узнаёт
+	// в Telegram и через вебхук event.capacity_threshold; 100 - мероприятие распродано
+	CapacityAlertThresholds []int `mapstructure:"capacity_alert_thresholds"`
+}
+
+type WorkerConfig struct {
+	CleanupInterval int `mapstructure:"cleanup_interval"` // в минутах
+	BatchSize       int `mapstructure:"batch_size"`
+
+	// Пул обработчиков очереди: общее число одновременных задач, лимиты по типам
+	// и сколько ждать выполняющиеся задачи при остановке
+	QueueConcurrency     int            `mapstructure:"queue_concurrency"`
+	QueueTypeConcurrency map[string]int `mapstructure:"queue_type_concurrency"`
+	QueueDrainTimeout    time.Duration  `mapstructure:"queue_drain_timeout"`
+
+	// Задача, обработчик которой не отмечался дольше QueueVisibilityTimeout, возвращается в очередь;
+	// после QueueMaxRecoveries таких возвратов она уходит в DLQ
+	QueueVisibilityTimeout time.Duration `mapstructure:"queue_visibility_timeout"`
+	QueueMaxRecoveries     int           `mapstructure:"queue_max_recoveries"`
+
+	// Срок обработки одной задачи, общий и по типам; должен быть меньше QueueVisibilityTimeout
+	QueueHandlerTimeout     time.Duration            `mapstructure:"queue_handler_timeout"`
+	QueueTypeHandlerTimeout map[string]time.Duration `mapstructure:"queue_type_handler_timeout"`
+
+	// Повторяющиеся задачи очереди в формате cron; пустое выражение отключает задачу
+	CronInterval      time.Duration `mapstructure:"cron_interval"` // как часто реплика проверяет расписания
+	CleanupCron       string        `mapstructure:"cleanup_cron"`
+	ReminderSweepCron string        `mapstructure:"reminder_sweep_cron"`
+
+	// Как часто планировать напоминания о мероприятиях за 24 часа и за час по каждому бронированию
+	ReminderPlanInterval time.Duration `mapstructure:"reminder_plan_interval"`
+
+	// Как часто закрывать брошенные корзины
+	CartExpiryInterval time.Duration `mapstructure:"cart_expiry_interval"`
+}
+
+// QueueConfig выбирает реализацию очереди задач: redis (по умолчанию), kafka, rabbitmq или memory.
+// Пул обработчиков и повторы настраиваются в worker и общие для всех реализаций.
+type QueueConfig struct {
+	Driver   string         `mapstructure:"driver"`
+	Kafka    KafkaConfig    `mapstructure:"kafka"`
+	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+}
+
+type KafkaConfig struct {
+	Brokers    []string `mapstructure:"brokers"`
+	Topic      string   `mapstructure:"topic"`
+	GroupID    string   `mapstructure:"group_id"`
+	DLQTopic   string   `mapstructure:"dlq_topic"`
+	DelayTopic string   `mapstructure:"delay_topic"` // префикс топиков отложенных задач
+}
+
+type RabbitMQConfig struct {
+	URL   string `mapstructure:"url"`
+	Queue string `mapstructure:"queue"`
+	DLQ   string `mapstructure:"dlq"`
+}
+
+type WebhookConfig struct {
+	Timeout     time.Duration `mapstructure:"timeout"`      // таймаут одного запроса
+	MaxAttempts int           `mapstructure:"max_attempts"` // попыток доставки, включая первую
+	RetryDelay  time.Duration `mapstructure:"retry_delay"`  // начальная задержка, удваивается с каждой попыткой
+}
+
+// ImagesConfig - сервис обработки изображений (модуль 4), который строит варианты афиш мероприятий
+type ImagesConfig struct {
+	URL       string        `mapstructure:"url"`        // адрес API сервиса, пусто - загрузка афиш отключена
+	PublicURL string        `mapstructure:"public_url"` // адрес для ссылок на варианты в ответах API, по умолчанию url
+	TenantID  string        `mapstructure:"tenant_id"`  // арендатор, от имени которого загружаются афиши
+	Timeout   time.Duration `mapstructure:"timeout"`
+	MaxSize   int64         `mapstructure:"max_size"` // в байтах
+	// Сколько ждать готовых вариантов: статус обработки проверяется раз в poll_interval
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	PollTimeout  time.Duration `mapstructure:"poll_timeout"`
+}
+
+// GRPCConfig - gRPC API рядом с REST
+type GRPCConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Port            string        `mapstructure:"port"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // после него незавершённые вызовы обрываются
+}
+
+// HealthConfig - проверки зависимостей для /readyz и планировщиков
+type HealthConfig struct {
+	Timeout  time.Duration `mapstructure:"timeout"`  // на одну зависимость
+	Interval time.Duration `mapstructure:"interval"` // между фоновыми проверками
+}
+
+// TracingConfig - экспорт трасс OpenTelemetry в коллектор по OTLP/HTTP
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"` // host:port коллектора, пусто - OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    `mapstructure:"insecure"` // без TLS
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"` // доля новых трасс, 0 - все
+}
+
+// AdminFeedConfig - WebSocket /ws/admin с бронированиями, подтверждениями, истечениями и задачами DLQ
+// в реальном времени. События рассылаются между репликами через Redis pub/sub, без Redis лента отвечает 503
+type AdminFeedConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Адреса страниц, с которых можно подключиться кроме самого сервиса (https://admin.example.com)
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+// APITokenConfig - токены внешних интеграций организаторов
+type APITokenConfig struct {
+	DefaultRateLimit int `mapstructure:"default_rate_limit"` // запросов в минуту, если при выпуске лимит не указан
+}
+
+// RateLimitConfig - ограничение частоты запросов по группам маршрутов /api/v1
+// (auth, events, bookings, users, integrations, admin); корзины хранятся в Redis
+type RateLimitConfig struct {
+	Enabled bool                            `mapstructure:"enabled"`
+	Groups  map[string]RateLimitGroupConfig `mapstructure:"groups"`
+}
+
+// RateLimitGroupConfig - лимиты группы маршрутов; 0 отключает лимит
+type RateLimitGroupConfig struct {
+	PerIP   int           `mapstructure:"per_ip"`   // запросов за period с одного IP
+	PerUser int           `mapstructure:"per_user"` // запросов за period от одного пользователя
+	Period  time.Duration `mapstructure:"period"`
+	Burst   int           `mapstructure:"burst"` // запросов подряд, по умолчанию равен лимиту
+}
+
+type LoggingConfig struct {
+	Level            string             `mapstructure:"level"` // debug, info, warn или error; меняется без перезапуска
+	LogBodies        bool               `mapstructure:"log_bodies"`
+	MaxBodySize      int                `mapstructure:"max_body_size"` // в байтах
+	RedactEmails     bool               `mapstructure:"redact_emails"` // маскировать email в телах
+	RedactFields     []string           `mapstructure:"redact_fields"` // JSON-поля с персональными данными
+	SampleRate       float64            `mapstructure:"sample_rate"`   // доля успешных запросов в журнале, 0 - только ошибки и медленные
+	RouteSampleRates map[string]float64 `mapstructure:"route_sample_rates"`
+	SlowThreshold    time.Duration      `mapstructure:"slow_threshold"` // медленные запросы пишутся всегда
+}
+
+type RedisConfig struct {
+	URL      string `json:"URL"`
+	Host     string `json:"host" validate:"required"`
+	Port     int    `json:"port" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	DB       int    `json:"db" validate:"required"`
+
+	// Mode - single (host и port), sentinel (master_name и addrs сентинелов)
+	// или cluster (addrs узлов кластера)
+	Mode             string   `mapstructure:"mode"`
+	MasterName       string   `mapstructure:"master_name"`
+	Addrs            []string `mapstructure:"addrs"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+	// Чтение с реплик: route_by_latency - с ближайшего узла, route_randomly - со случайного,
+	// read_only - с реплик слота (только cluster). Запись всегда идёт на мастер
+	ReadOnly       bool `mapstructure:"read_only"`
+	RouteByLatency bool `mapstructure:"route_by_latency"`
+	RouteRandomly  bool `mapstructure:"route_randomly"`
+
+	// Настройки пула соединений
+	MaxRetries   int
+	PoolSize     int
+	MinIdleConns int
+	MaxIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// Enabled - задан ли адрес Redis; без него сервис работает без Redis
+func (c RedisConfig) Enabled() bool {
+	switch c.Mode {
+	case RedisModeSentinel, RedisModeCluster:
+		return len(c.Addrs) > 0
+	default:
+		return c.Host != ""
+	}
+}
+
+// LoadConfig читает config/config.yaml поверх значений по умолчанию. Любой ключ можно
+// переопределить переменной окружения: database.password - DATABASE_PASSWORD,
+// worker.queue_concurrency - WORKER_QUEUE_CONCURRENCY
+func LoadConfig() (*viper.Viper, error) {
+
+	viperInstance := viper.New()
+
+	viperInstance.AddConfigPath("./config")
+	viperInstance.SetConfigName("config")
+	viperInstance.SetConfigType("yaml")
+
+	setDefaults(viperInstance)
+	viperInstance.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viperInstance.AutomaticEnv()
+
+	err := viperInstance.ReadInConfig()
+
+	if err != nil {
+		return nil, err
+	}
+	return viperInstance, nil
+}
+
+// ParseConfig разбирает настройки и проверяет их Validate; неверная длительность
+// вроде "5 minutes" возвращается ошибкой разбора
+func ParseConfig(v *viper.Viper) (*Config, error) {
+
+	var c Config
+
+	err := v.Unmarshal(&c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// setDefaults устанавливает значения по умолчанию
+func setDefaults(v *viper.Viper) {
+	// Server defaults
+	v.SetDefault("server.appVersion", "1.0.0")
+	v.SetDefault("server.host", "localhost")
+	v.SetDefault("server.port", "8080")
+	v.SetDefault("server.timeout", 30*time.Second)
+	v.SetDefault("server.idle_timeout", 60*time.Second)
+	v.SetDefault("server.shutdown_timeout", 30*time.Second)
+	v.SetDefault("server.environment", "development")
+	v.SetDefault("server.mode", "debug")
+
+	// Database defaults
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.user", "eventbooker_user")
+	v.SetDefault("database.password", "password")
+	v.SetDefault("database.dbname", "eventbooker")
+	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.max_open_conns", 25)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.conn_max_lifetime", 5*time.Minute)
+	v.SetDefault("database.replica_dsn", "")
+
+	// App defaults
+	v.SetDefault("app.short_url_length", 8)
+	v.SetDefault("app.cache_ttl", 15*time.Minute)
+	v.SetDefault("app.base_url", "http://localhost:8080")
+
+	// JWT defaults
+	v.SetDefault("jwt.secret", "your-super-secret-jwt-key-change-in-production")
+	v.SetDefault("jwt.expiration", 24*time.Hour)
+	v.SetDefault("jwt.impersonation_expiration", 30*time.Minute)
+
+	// Email defaults
+	v.SetDefault("email.from", "noreply@eventbooker.com")
+	v.SetDefault("email.host", "smtp.gmail.com")
+	v.SetDefault("email.port", 587)
+	v.SetDefault("email.enabled", false)
+
+	// Telegram defaults
+	v.SetDefault("telegram.enabled", false)
+	v.SetDefault("telegram.poll_timeout", 50*time.Second)
+
+	// gRPC defaults
+	v.SetDefault("grpc.port", "9090")
+	v.SetDefault("grpc.shutdown_timeout", 10*time.Second)
+
+	// Health defaults
+	v.SetDefault("health.timeout", 2*time.Second)
+	v.SetDefault("health.interval", 15*time.Second)
+
+	// Tracing defaults
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.service_name", "event-booker")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+
+	// Admin feed defaults
+	v.SetDefault("admin_feed.enabled", true)
+
+	// Images defaults
+	v.SetDefault("images.tenant_id", "event-booker")
+	v.SetDefault("images.timeout", 30*time.Second)
+	v.SetDefault("images.max_size", 10<<20)
+	v.SetDefault("images.poll_interval", 5*time.Second)
+	v.SetDefault("images.poll_timeout", 10*time.Minute)
+
+	// Booking defaults
+	v.SetDefault("booking.default_timeout", 30) // 30 минут
+	v.SetDefault("booking.max_seats", 1000)
+	v.SetDefault("booking.cart_ttl", 30*time.Minute)
+	v.SetDefault("booking.expiry_notifications", false)
+	v.SetDefault("booking.availability_cache", false)
+	v.SetDefault("booking.availability_cache_ttl", 30*time.Second)
+	v.SetDefault("booking.availability_stream", true)
+	v.SetDefault("booking.capacity_alert_thresholds", []int{50, 80, 100})
+
+	// Worker defaults
+	v.SetDefault("worker.cleanup_interval", 1) // 1 минута
+	v.SetDefault("worker.batch_size", 100)
+	v.SetDefault("worker.queue_concurrency", 4)
+	v.SetDefault("worker.queue_drain_timeout", 30*time.Second)
+	v.SetDefault("worker.queue_visibility_timeout", 5*time.Minute)
+	v.SetDefault("worker.queue_max_recoveries", 3)
+	v.SetDefault("worker.queue_handler_timeout", 2*time.Minute)
+	v.SetDefault("worker.reminder_plan_interval", 24*time.Hour)
+	v.SetDefault("worker.cart_expiry_interval", time.Minute)
+
+	// Redis defaults: без адреса сервис работает без Redis
+	v.SetDefault("redis.host", "")
+	v.SetDefault("redis.port", 6379)
+	v.SetDefault("redis.password", "")
+	v.SetDefault("redis.db", 0)
+	v.SetDefault("redis.mode", RedisModeSingle)
+
+	// Logging defaults
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.sample_rate", 1.0)
+
+	// Queue defaults
+	v.SetDefault("queue.driver", "redis")
+}
+
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+/*
+
+
+// GetServerAddress возвращает полный адрес сервера
+func (c *Config) GetServerAddress() string {
+	return c.Server.Host + ":" + c.Server.Port
+}
+
+// IsProduction проверяет, production ли окружение
+func (c *Config) IsProduction() bool {
+	return c.Server.Env == "production"
+}
+
+// IsDevelopment проверяет, development ли окружение
+func (c *Config) IsDevelopment() bool {
+	return c.Server.Env == "development"
+}
+
+// GetDatabaseURL возвращает DSN строку для подключения к БД
+func (c *Config) GetDatabaseURL() string {
+	return "postgres://" + c.Database.User + ":" + c.Database.Password +
+		"@" + c.Database.Host + ":" + strconv.Itoa(c.Database.Port) +
+		"/" + c.Database.DBName + "?sslmode=" + c.Database.SSLMode
+}
+
+// GetEmailConfig возвращает конфигурацию email
+func (c *Config) GetEmailConfig() *EmailConfig {
+	return &c.Email
+}
+
+// GetTelegramConfig возвращает конфигурацию Telegram
+func (c *Config) GetTelegramConfig() *TelegramConfig {
+	return &c.Telegram
+}
+
+
+// GetEnv получает переменную окружения с fallback значением
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvInt получает int переменную окружения с fallback значением
+func GetEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvBool получает bool переменную окружения с fallback значением
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvDuration получает duration переменную окружения с fallback значением
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+*/
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadConfigEnvOverride проверяет, что config.yaml проходит проверку
+// и переменная окружения важнее значения из файла
+func TestLoadConfigEnvOverride(t *testing.T) {
+	t.Chdir("..")
+	t.Setenv("DATABASE_HOST", "postgres")
+	t.Setenv("WORKER_QUEUE_CONCURRENCY", "16")
+	t.Setenv("REDIS_HOST", "redis")
+
+	v, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Database.Host != "postgres" {
+		t.Errorf("database.host = %q, want postgres", cfg.Database.Host)
+	}
+	if cfg.Worker.QueueConcurrency != 16 {
+		t.Errorf("worker.queue_concurrency = %d, want 16", cfg.Worker.QueueConcurrency)
+	}
+	if cfg.Redis.Host != "redis" || cfg.Redis.Port != 6379 {
+		t.Errorf("redis = %s:%d, want redis:6379", cfg.Redis.Host, cfg.Redis.Port)
+	}
+}
+
+func TestLoadConfigBadDuration(t *testing.T) {
+	t.Chdir("..")
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "thirty seconds")
+
+	v, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseConfig(v); err == nil {
+		t.Fatal("expected an error for an unparsable duration")
+	}
+}
+
+// TestValidateAggregatesErrors - все ошибки возвращаются одной
+func TestValidateAggregatesErrors(t *testing.T) {
+	cfg := &Config{
+		Server:   ServerConfig{Port: "80a", ShutdownTimeout: -time.Second},
+		Database: DatabaseConfig{Host: "localhost", Port: 70000, DBName: "eventbooker"},
+		JWT:      JWTConfig{Secret: "secret", Expiration: time.Hour},
+		Booking:  BookingConfig{AvailabilityCache: true, CapacityAlertThresholds: []int{80, 120}},
+		Worker:   WorkerConfig{QueueHandlerTimeout: 10 * time.Minute, QueueVisibilityTimeout: 5 * time.Minute},
+		Logging:  LoggingConfig{SampleRate: 1.5},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"server.port",
+		"server.shutdown_timeout",
+		"database.user is required",
+		"database.password is required",
+		"database.port",
+		"worker.queue_handler_timeout",
+		"booking.availability_cache_ttl",
+		"booking.capacity_alert_thresholds",
+		"logging.sample_rate",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+// TestValidateRedisMode - режим Redis и обязательные для него настройки
+func TestValidateRedisMode(t *testing.T) {
+	cases := []struct {
+		name  string
+		redis RedisConfig
+		want  string
+	}{
+		{"unknown mode", RedisConfig{Mode: "replica"}, "redis.mode"},
+		{"sentinel without master", RedisConfig{Mode: RedisModeSentinel, Addrs: []string{"sentinel:26379"}}, "redis.master_name is required"},
+		{"cluster with db", RedisConfig{Mode: RedisModeCluster, Addrs: []string{"redis-1:6379"}, DB: 1}, "redis.db"},
+		{"sentinel replicas with db", RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster", Addrs: []string{"sentinel:26379"}, RouteRandomly: true, DB: 2}, "redis.db"},
+	}
+
+	for _, tc := range cases {
+		cfg := &Config{Redis: tc.redis}
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error mentioning %s, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	cfg := &Config{Redis: RedisConfig{Mode: RedisModeCluster, Addrs: []string{"redis-1:6379", "redis-2:6379"}}}
+	if err := cfg.Validate(); err != nil && strings.Contains(err.Error(), "redis.") {
+		t.Errorf("unexpected redis error for a valid cluster config: %v", err)
+	}
+	if !cfg.Redis.Enabled() {
+		t.Error("cluster config with addrs should enable Redis")
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var queueDrivers = map[string]bool{"": true, "redis": true, "kafka": true, "rabbitmq": true, "memory": true}
+
+// Validate проверяет настройки до запуска сервиса и возвращает все найденные ошибки сразу,
+// чтобы конфигурацию не приходилось исправлять по одной ошибке за перезапуск
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(key, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+	}
+	port := func(key string, value int) {
+		if value < 1 || value > 65535 {
+			errs = append(errs, fmt.Errorf("%s: invalid port %d", key, value))
+		}
+	}
+	portString := func(key, value string) {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid port %q", key, value))
+			return
+		}
+		port(key, n)
+	}
+	// Нулевая длительность означает значение по умолчанию, отрицательная - ошибка
+	duration := func(key string, value time.Duration) {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s: duration must not be negative, got %v", key, value))
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: duration must be positive, got %v", key, value))
+		}
+	}
+
+	portString("server.port", c.Server.Port)
+	duration("server.timeout", c.Server.Timeout)
+	duration("server.idle_timeout", c.Server.Idle_timeout)
+	duration("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	required("database.host", c.Database.Host)
+	required("database.user", c.Database.User)
+	required("database.password", c.Database.Password)
+	required("database.dbname", c.Database.DBName)
+	port("database.port", c.Database.Port)
+	duration("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+
+	required("jwt.secret", c.JWT.Secret)
+	positive("jwt.expiration", c.JWT.Expiration)
+	duration("jwt.impersonation_expiration", c.JWT.ImpersonationExpiration)
+
+	if c.Email.Enabled {
+		required("email.host", c.Email.Host)
+		required("email.from", c.Email.From)
+		port("email.port", c.Email.Port)
+	}
+
+	if c.Telegram.Updates != "" && c.Telegram.Updates != "polling" && c.Telegram.Updates != "webhook" {
+		errs = append(errs, fmt.Errorf("telegram.updates: expected polling, webhook or empty, got %q", c.Telegram.Updates))
+	}
+	if c.Telegram.Updates == "webhook" {
+		required("telegram.webhook_url", c.Telegram.WebhookURL)
+	}
+	duration("telegram.poll_timeout", c.Telegram.PollTimeout)
+
+	if c.Booking.DefaultTimeout < 0 || c.Booking.DefaultTimeout > 1440 {
+		errs = append(errs, fmt.Errorf("booking.default_timeout: expected minutes from 1 to 1440, got %d", c.Booking.DefaultTimeout))
+	}
+	duration("booking.cart_ttl", c.Booking.CartTTL)
+	for _, threshold := range c.Booking.CapacityAlertThresholds {
+		if threshold < 1 || threshold > 100 {
+			errs = append(errs, fmt.Errorf("booking.capacity_alert_thresholds: expected percents from 1 to 100, got %d", threshold))
+		}
+	}
+	// Без срока жизни ключ, сброс которого не удался, устарел бы навсегда
+	if c.Booking.AvailabilityCache {
+		positive("booking.availability_cache_ttl", c.Booking.AvailabilityCacheTTL)
+	}
+
+	duration("worker.queue_drain_timeout", c.Worker.QueueDrainTimeout)
+	duration("worker.queue_visibility_timeout", c.Worker.QueueVisibilityTimeout)
+	duration("worker.queue_handler_timeout", c.Worker.QueueHandlerTimeout)
+	for taskType, timeout := range c.Worker.QueueTypeHandlerTimeout {
+		duration("worker.queue_type_handler_timeout."+taskType, timeout)
+	}
+	duration("worker.cron_interval", c.Worker.CronInterval)
+	duration("worker.reminder_plan_interval", c.Worker.ReminderPlanInterval)
+	duration("worker.cart_expiry_interval", c.Worker.CartExpiryInterval)
+	// Иначе задачу вернут в очередь, пока обработчик ещё работает
+	if c.Worker.QueueHandlerTimeout > 0 && c.Worker.QueueVisibilityTimeout > 0 &&
+		c.Worker.QueueHandlerTimeout >= c.Worker.QueueVisibilityTimeout {
+		errs = append(errs, fmt.Errorf("worker.queue_handler_timeout (%v) must be less than worker.queue_visibility_timeout (%v)",
+			c.Worker.QueueHandlerTimeout, c.Worker.QueueVisibilityTimeout))
+	}
+
+	switch c.Redis.Mode {
+	case "", RedisModeSingle:
+		if c.Redis.Host != "" {
+			port("redis.port", c.Redis.Port)
+		}
+	case RedisModeSentinel:
+		if len(c.Redis.Addrs) > 0 {
+			required("redis.master_name", c.Redis.MasterName)
+		}
+		// Клиент, читающий с реплик, не выбирает базу
+		if (c.Redis.RouteByLatency || c.Redis.RouteRandomly) && c.Redis.DB != 0 {
+			errs = append(errs, fmt.Errorf("redis.db: reading from replicas supports only db 0, got %d", c.Redis.DB))
+		}
+	case RedisModeCluster:
+		// Ключи Redis Cluster живут только в базе 0
+		if c.Redis.DB != 0 {
+			errs = append(errs, fmt.Errorf("redis.db: cluster mode supports only db 0, got %d", c.Redis.DB))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("redis.mode: expected single, sentinel or cluster, got %q", c.Redis.Mode))
+	}
+
+	if !queueDrivers[c.Queue.Driver] {
+		errs = append(errs, fmt.Errorf("queue.driver: unknown driver %q", c.Queue.Driver))
+	}
+	switch c.Queue.Driver {
+	case "kafka":
+		if len(c.Queue.Kafka.Brokers) == 0 {
+			errs = append(errs, errors.New("queue.kafka.brokers is required"))
+		}
+		required("queue.kafka.topic", c.Queue.Kafka.Topic)
+	case "rabbitmq":
+		required("queue.rabbitmq.url", c.Queue.RabbitMQ.URL)
+	}
+
+	if c.Logging.Level != "" {
+		if _, err := logrus.ParseLevel(c.Logging.Level); err != nil {
+			errs = append(errs, fmt.Errorf("logging.level: %w", err))
+		}
+	}
+	duration("logging.slow_threshold", c.Logging.SlowThreshold)
+	// 0 - в журнал попадают только ошибки и медленные запросы
+	sampleRate := func(key string, value float64) {
+		if value < 0 || value > 1 {
+			errs = append(errs, fmt.Errorf("%s: expected a share from 0 to 1, got %v", key, value))
+		}
+	}
+	sampleRate("logging.sample_rate", c.Logging.SampleRate)
+	for route, rate := range c.Logging.RouteSampleRates {
+		sampleRate("logging.route_sample_rates."+route, rate)
+	}
+
+	duration("webhook.timeout", c.Webhook.Timeout)
+	duration("webhook.retry_delay", c.Webhook.RetryDelay)
+
+	if c.Images.URL != "" {
+		positive("images.poll_interval", c.Images.PollInterval)
+		if c.Images.MaxSize <= 0 {
+			errs = append(errs, fmt.Errorf("images.max_size: expected a positive size in bytes, got %d", c.Images.MaxSize))
+		}
+	}
+	duration("images.timeout", c.Images.Timeout)
+	duration("images.poll_timeout", c.Images.PollTimeout)
+
+	if c.GRPC.Enabled {
+		portString("grpc.port", c.GRPC.Port)
+		if c.GRPC.Port == c.Server.Port {
+			errs = append(errs, fmt.Errorf("grpc.port: %s is already used by server.port", c.GRPC.Port))
+		}
+	}
+	duration("grpc.shutdown_timeout", c.GRPC.ShutdownTimeout)
+
+	for name, group := range c.RateLimit.Groups {
+		duration("rate_limit.groups."+name+".period", group.Period)
+	}
+
+	duration("health.timeout", c.Health.Timeout)
+	duration("health.interval", c.Health.Interval)
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio: expected a value from 0 to 1, got %v", c.Tracing.SampleRatio))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay - сколько ждать после последнего события файла: редакторы сохраняют файл
+// несколькими записями, и прочитанный посередине файл оказался бы пустым или обрезанным
+const reloadDelay = 200 * time.Millisecond
+
+// Watcher перечитывает config.yaml при его изменении и передаёт новую конфигурацию
+// подписчикам, чтобы длительности, интервалы и уровень журнала менялись без перезапуска.
+// Настройки подключений (база, Redis, очередь, порты) применяются только при запуске.
+// Конфигурация, не прошедшая Validate, не применяется: остаётся прежняя.
+type Watcher struct {
+	v *viper.Viper
+
+	mu          sync.Mutex
+	current     *Config
+	subscribers []subscriber
+
+	fsWatcher *fsnotify.Watcher
+	done      chan struct{}
+}
+
+type subscriber struct {
+	name string
+	fn   func(*Config)
+}
+
+func NewWatcher(v *viper.Viper, cfg *Config) *Watcher {
+	return &Watcher{
+		v:       v,
+		current: cfg,
+		done:    make(chan struct{}),
+	}
+}
+
+// Subscribe регистрирует fn, которая вызывается с новой конфигурацией после каждой
+// успешной перезагрузки; name попадает в журнал
+func (w *Watcher) Subscribe(name string, fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, subscriber{name: name, fn: fn})
+}
+
+// Current возвращает последнюю применённую конфигурацию
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+// Start начинает следить за файлом конфигурации. Наблюдается каталог, а не сам файл:
+// сохранение через переименование и подмена ConfigMap в Kubernetes заменяют файл целиком.
+func (w *Watcher) Start() error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Clean(w.v.ConfigFileUsed())
+	if err := fsWatcher.Add(filepath.Dir(file)); err != nil {
+		fsWatcher.Close()
+		return err
+	}
+	w.fsWatcher = fsWatcher
+
+	go w.watch(file)
+	return nil
+}
+
+// Close прекращает слежение за файлом
+func (w *Watcher) Close() error {
+	if w.fsWatcher == nil {
+		return nil
+	}
+	err := w.fsWatcher.Close()
+	<-w.done
+	return err
+}
+
+func (w *Watcher) watch(file string) {
+	defer close(w.done)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != file && !isConfigMapSwap(event) {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			logrus.WithError(err).Warn("Config watcher error")
+		case <-timer.C:
+			w.reload(file)
+		}
+	}
+}
+
+// isConfigMapSwap - Kubernetes обновляет смонтированный ConfigMap, переключая ссылку ..data
+func isConfigMapSwap(event fsnotify.Event) bool {
+	return filepath.Base(event.Name) == "..data" && event.Has(fsnotify.Create)
+}
+
+func (w *Watcher) reload(file string) {
+	// Файл могли удалить или ещё не дописать: прежняя конфигурация лучше значений по умолчанию
+	if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+		logrus.WithField("file", file).Warn("Config file is missing or empty, keeping the previous config")
+		return
+	}
+
+	if err := w.v.ReadInConfig(); err != nil {
+		logrus.WithError(err).WithField("file", file).Error("Config reload failed, keeping the previous config")
+		return
+	}
+	cfg, err := ParseConfig(w.v)
+	if err != nil {
+		logrus.WithError(err).WithField("file", file).Error("Config reload rejected, keeping the previous config")
+		return
+	}
+
+	w.mu.Lock()
+	w.current = cfg
+	subscribers := append([]subscriber(nil), w.subscribers...)
+	w.mu.Unlock()
+
+	for _, s := range subscribers {
+		w.notify(s, cfg)
+	}
+
+	logrus.WithField("file", file).Info("Config reloaded")
+}
+
+// notify не даёт панике одного подписчика остановить слежение за файлом
+func (w *Watcher) notify(s subscriber, cfg *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithField("subscriber", s.name).Errorf("Config subscriber panicked: %v", r)
+		}
+	}()
+
+	s.fn(cfg)
+}
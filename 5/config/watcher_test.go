@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWatcherReload проверяет, что изменённый config.yaml доходит до подписчика,
+// а конфигурация, не прошедшая проверку, не применяется
+func TestWatcherReload(t *testing.T) {
+	original, err := os.ReadFile("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config", "config.yaml")
+	write := func(defaultTimeout string) {
+		data := strings.Replace(string(original), "default_timeout: 30", "default_timeout: "+defaultTimeout, 1)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("30")
+	t.Chdir(dir)
+
+	v, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewWatcher(v, cfg)
+	reloaded := make(chan *Config, 10)
+	watcher.Subscribe("test", func(cfg *Config) { reloaded <- cfg })
+	if err := watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+
+	write("45")
+	select {
+	case cfg := <-reloaded:
+		if cfg.Booking.DefaultTimeout != 45 {
+			t.Fatalf("booking.default_timeout = %d, want 45", cfg.Booking.DefaultTimeout)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not delivered")
+	}
+
+	// Больше суток - не проходит Validate
+	write("5000")
+	select {
+	case cfg := <-reloaded:
+		if cfg.Booking.DefaultTimeout == 5000 {
+			t.Fatal("invalid config was delivered to subscribers")
+		}
+	case <-time.After(500 * time.Millisecond):
+	}
+	if got := watcher.Current().Booking.DefaultTimeout; got != 45 {
+		t.Fatalf("current booking.default_timeout = %d, want 45", got)
+	}
+}
@@ -1,174 +1,691 @@
-package appServer
-
-import (
-	"context"
-	"crypto/tls"
-	"log"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/config"
-	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-	"github.com/ds124wfegd/WB_L3/5/internal/transport"
-	"github.com/ds124wfegd/WB_L3/5/internal/worker"
-
-	"github.com/ds124wfegd/WB_L3/5/pkg/postgres"
-	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
-	"github.com/ds124wfegd/WB_L3/5/pkg/redis"
-	"github.com/ds124wfegd/WB_L3/5/pkg/scheduler"
-	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
-
-	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
-)
-
-type Server struct {
-	httpServer *http.Server
-}
-
-func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:              ":" + cfg.Server.Port,
-		Handler:           handler,
-		MaxHeaderBytes:    1 << 20,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      cfg.Server.Timeout,
-		IdleTimeout:       cfg.Server.Idle_timeout,
-		ReadHeaderTimeout: 3 * time.Second,
-		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
-		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
-	}
-	return s.httpServer.ListenAndServe()
-}
-
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-func NewServer(cfg *config.Config) {
-
-	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetOutput(os.Stdout)
-	logrus.SetLevel(logrus.InfoLevel)
-	// Initialize database
-	db, err := postgres.NewPostgresDB(&cfg.Database)
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	defer db.Close()
-
-	// Run database migrations
-	if err := postgres.RunMigrations(db); err != nil {
-		logrus.Fatalf("Failed to run migrations: %v", err)
-	}
-
-	// Initialize repositories
-	eventRepo := repository.NewEventRepository(db)
-	bookingRepo := repository.NewBookingRepository(db)
-	userRepo := repository.NewUserRepository(db)
-
-	// Initialize Telegram bot
-	var telegramBot *telegram.Bot
-	if cfg.Telegram.BotToken != "" {
-		telegramBot = telegram.NewBot(cfg.Telegram.BotToken)
-		logrus.Info("Telegram bot initialized")
-	} else {
-		logrus.Warn("Telegram bot token not provided, notifications disabled")
-	}
-
-	var redisQueue queue.Queue
-	var taskPublisher service.TaskPublisher
-
-	if cfg.Redis.URL != "" {
-		redisConfig := &queue.RedisQueueConfig{
-			Addr:     cfg.Redis.URL,
-			Password: "",
-			DB:       0,
-		}
-
-		retryManager := queue.NewRetryManager(3, 5*time.Second)
-		redisClient := redis.NewRedisClient(&cfg.Redis)
-		defer redisClient.Close()
-		dlqHandler := queue.NewDefaultDLQHandler(redisClient, "event_booking:dlq")
-
-		redisQueue, err = queue.NewRedisQueue(redisConfig, retryManager, dlqHandler)
-		if err != nil {
-			logrus.Errorf("Failed to initialize Redis queue: %v. Continuing without queue...", err)
-		} else {
-			logrus.Info("Redis queue initialized")
-			// Создаем адаптер для очереди
-			taskPublisher = service.NewQueueAdapter(redisQueue)
-		}
-	}
-
-	// Initialize services
-	bookingService := service.NewBookingService(bookingRepo, eventRepo, userRepo, taskPublisher, telegramBot)
-	eventService := service.NewEventService(eventRepo, bookingRepo)
-	userService := service.NewUserService(userRepo, bookingRepo)
-
-	// Initialize task handler if queue is available
-	if redisQueue != nil {
-		taskHandler := queue.NewTaskHandler(bookingService, eventService, userService, telegramBot)
-
-		// Start queue consumer
-		go func() {
-			ctx := context.Background()
-			if err := redisQueue.Subscribe(ctx, taskHandler.HandleTask); err != nil {
-				logrus.Errorf("Queue subscriber error: %v", err)
-			}
-		}()
-		logrus.Info("Queue subscriber started")
-	}
-
-	// Initialize and start scheduler
-	expirationScheduler := scheduler.NewScheduler(bookingService, time.Minute)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	go expirationScheduler.Start(ctx)
-	logrus.Info("Expiration scheduler started")
-
-	// Initialize cleanup worker
-	cleanupWorker := worker.NewBookingCleanupWorker(bookingService, 30*time.Minute)
-	go cleanupWorker.Start(ctx)
-	logrus.Info("Cleanup worker started")
-
-	// Initialize handlers
-	eventHandler := transport.NewEventHandler(eventService)
-	bookingHandler := transport.NewBookingHandler(bookingService)
-	userHandler := transport.NewUserHandler(userService)
-
-	// Setup HTTP server
-	if cfg.Server.Env == "production" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	go cleanupWorker.Start(ctx)
-
-	if cfg.Server.Mode == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	}
-
-	srv := new(Server)
-	go func() {
-		if err := srv.Run(cfg, transport.InitRoutes(eventHandler, bookingHandler, userHandler)); err != nil {
-			logrus.Fatalf("error occured while running http server: %s", err.Error())
-		}
-	}()
-
-	logrus.Print("App Started")
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
-
-	logrus.Print("App Shutting Down")
-
-	if err := srv.Shutdown(context.Background()); err != nil {
-		logrus.Errorf("error occured on server shutting down: %s", err.Error())
-	}
-}
+package appServer
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	redisdb "github.com/ds124wfegd/WB_L3/5/internal/database/redis"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport"
+	grpctransport "github.com/ds124wfegd/WB_L3/5/internal/transport/grpc"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/ds124wfegd/WB_L3/5/internal/worker"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/email"
+	"github.com/ds124wfegd/WB_L3/5/pkg/health"
+	"github.com/ds124wfegd/WB_L3/5/pkg/images"
+	"github.com/ds124wfegd/WB_L3/5/pkg/lifecycle"
+	"github.com/ds124wfegd/WB_L3/5/pkg/postgres"
+	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
+	"github.com/ds124wfegd/WB_L3/5/pkg/redis"
+	"github.com/ds124wfegd/WB_L3/5/pkg/scheduler"
+	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
+	"github.com/ds124wfegd/WB_L3/5/pkg/tracing"
+	"github.com/ds124wfegd/WB_L3/5/pkg/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+type Server struct {
+	httpServer *http.Server
+}
+
+func (s *Server) Run(cfg *config.Config, handler http.Handler) error {
+	s.httpServer = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           handler,
+		MaxHeaderBytes:    1 << 20,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      cfg.Server.Timeout,
+		IdleTimeout:       cfg.Server.Idle_timeout,
+		ReadHeaderTimeout: 3 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},           // ban on outdate TLS certificate
+		ErrorLog:          log.New(os.Stderr, "SERVER ERROR: ", log.LstdFlags), // os.Stderr can be replaced with ElsasticSearch in the feature
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+func NewServer(cfg *config.Config, watcher *config.Watcher) {
+
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetOutput(os.Stdout)
+	setLogLevel(cfg.Logging.Level)
+
+	// Трассировка поднимается первой, чтобы запросы и задачи попадали в трассы с самого старта
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Enabled:        cfg.Tracing.Enabled,
+		Endpoint:       cfg.Tracing.Endpoint,
+		Insecure:       cfg.Tracing.Insecure,
+		ServiceName:    cfg.Tracing.ServiceName,
+		ServiceVersion: cfg.Server.AppVersion,
+		Environment:    cfg.Server.Env,
+		SampleRatio:    cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Initialize database
+	db, err := postgres.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Run database migrations
+	if err := postgres.RunMigrations(db); err != nil {
+		logrus.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// Read replica for heavy admin reads; without replica_dsn they stay on the primary
+	replicaDB, err := postgres.NewReplicaDB(&cfg.Database)
+	if err != nil {
+		logrus.Fatalf("Failed to initialize database replica: %v", err)
+	}
+	if replicaDB != nil {
+		defer replicaDB.Close()
+	}
+
+	// Initialize repositories
+	eventRepo := repository.NewEventRepository(db, replicaDB)
+	bookingRepo := repository.NewBookingRepository(db, replicaDB)
+	userRepo := repository.NewUserRepository(db, replicaDB)
+	tierRepo := repository.NewTicketTierRepository(db)
+	poolRepo := repository.NewPartnerPoolRepository(db)
+	holdRepo := repository.NewEventHoldRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+	promoRepo := repository.NewPromoCodeRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	venueRepo := repository.NewVenueRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db, replicaDB)
+	capacityRepo := repository.NewCapacityRepository(db)
+	posterRepo := repository.NewEventPosterRepository(db)
+	txManager := repository.NewTxManager(db)
+
+	// Initialize Telegram bot
+	var telegramBot *telegram.Bot
+	if cfg.Telegram.BotToken != "" {
+		telegramBot = telegram.NewBot(cfg.Telegram.BotToken)
+		logrus.Info("Telegram bot initialized")
+	} else {
+		logrus.Warn("Telegram bot token not provided, notifications disabled")
+	}
+
+	// Initialize email sender
+	var emailSender queue.EmailSender
+	if cfg.Email.Enabled {
+		sender, err := email.NewSender(&cfg.Email)
+		if err != nil {
+			logrus.Fatalf("Failed to initialize email templates: %v", err)
+		}
+		emailSender = sender
+		logrus.Info("Email sender initialized")
+	} else {
+		logrus.Warn("Email disabled, email notifications will be skipped")
+	}
+
+	// Лента админки: события обработчиков задач и DLQ расходятся по репликам через Redis pub/sub
+	var adminFeedBus *redisdb.AdminFeedBus
+	var adminFeed *service.AdminFeed
+	if cfg.AdminFeed.Enabled && cfg.Redis.Enabled() {
+		feedClient := redis.NewRedisClient(&cfg.Redis)
+		defer feedClient.Close()
+
+		adminFeedBus = redisdb.NewAdminFeedBus(feedClient)
+		adminFeed = service.NewAdminFeed(adminFeedBus)
+		logrus.Info("Admin feed enabled")
+	}
+
+	var taskQueue queue.Queue
+	var taskPublisher service.TaskPublisher
+	// Без Redis блокировок нет: периодические задачи выполняет каждый экземпляр
+	var locker scheduler.Locker
+	var cronScheduler *queue.CronScheduler
+	var dlq queue.DLQHandler
+
+	// Метрики процесса отдаются всегда, метрики очереди - если она поднялась
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	typeConcurrency := make(map[queue.TaskType]int, len(cfg.Worker.QueueTypeConcurrency))
+	for taskType, limit := range cfg.Worker.QueueTypeConcurrency {
+		typeConcurrency[queue.TaskType(taskType)] = limit
+	}
+
+	// Драйвер redis используется и по умолчанию
+	redisDriver := cfg.Queue.Driver == "" || cfg.Queue.Driver == queue.DriverRedis
+
+	// В режимах sentinel и cluster очередь работает через общий клиент, а не по redis.url
+	sharedRedis := (cfg.Redis.Mode == config.RedisModeSentinel || cfg.Redis.Mode == config.RedisModeCluster) && cfg.Redis.Enabled()
+	if (cfg.Redis.URL != "" || sharedRedis) && redisDriver {
+		redisClient := redis.NewRedisClient(&cfg.Redis)
+		defer redisClient.Close()
+
+		redisConfig := &queue.RedisQueueConfig{
+			Addr:              cfg.Redis.URL,
+			Password:          "",
+			DB:                0,
+			Concurrency:       cfg.Worker.QueueConcurrency,
+			TypeConcurrency:   typeConcurrency,
+			DrainTimeout:      cfg.Worker.QueueDrainTimeout,
+			VisibilityTimeout: cfg.Worker.QueueVisibilityTimeout,
+			MaxRecoveries:     cfg.Worker.QueueMaxRecoveries,
+		}
+		redisConfig.WithKeyPrefix(queue.DefaultKeyPrefix)
+		if sharedRedis {
+			redisConfig.Client = redisClient
+		}
+		if cfg.Redis.Mode == config.RedisModeCluster {
+			redisConfig.WithKeyPrefix(queue.ClusterKeyPrefix)
+		}
+
+		retryManager := queue.NewRetryManager(3, 5*time.Second)
+		locker = scheduler.NewRedisLock(redisClient)
+		dlqHandler := queue.NewDefaultDLQHandler(redisClient, redisConfig.DLQ)
+		// Записи DLQ старого формата переносятся в индекс по ID задачи
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, err := dlqHandler.MigrateLegacyEntries(migrateCtx); err != nil {
+			logrus.Warnf("Failed to migrate DLQ entries: %v", err)
+		}
+		cancelMigrate()
+
+		// Ошибка не должна оставлять в интерфейсе nil-указатель, иначе проверки taskQueue != nil ломаются
+		var failedTasks queue.DLQHandler = dlqHandler
+		if adminFeedBus != nil {
+			failedTasks = service.WithDLQAlerts(dlqHandler, adminFeedBus)
+		}
+		rq, err := queue.NewRedisQueue(redisConfig, retryManager, failedTasks)
+		if err != nil {
+			logrus.Errorf("Failed to initialize Redis queue: %v. Continuing without queue...", err)
+		} else {
+			taskQueue = rq
+			dlq = dlqHandler
+			cronScheduler = queue.NewCronScheduler(rq, cfg.Worker.CronInterval)
+			metricsRegistry.MustRegister(rq.Collector())
+			logrus.Info("Redis queue initialized")
+			// Создаем адаптер для очереди
+			taskPublisher = service.NewQueueAdapter(taskQueue)
+		}
+	}
+
+	if !redisDriver {
+		// У брокеров своя DLQ; расписания cron, блокировки планировщика и админка DLQ работают только с Redis
+		q, err := newBrokerQueue(cfg.Queue, queue.DispatchConfig{
+			MaxRetries:      3,
+			BaseDelay:       5 * time.Second,
+			Concurrency:     cfg.Worker.QueueConcurrency,
+			TypeConcurrency: typeConcurrency,
+			DrainTimeout:    cfg.Worker.QueueDrainTimeout,
+		})
+		if err != nil {
+			logrus.Errorf("Failed to initialize %s queue: %v. Continuing without queue...", cfg.Queue.Driver, err)
+		} else {
+			taskQueue = q
+			logrus.Infof("%s queue initialized", cfg.Queue.Driver)
+			taskPublisher = service.NewQueueAdapter(taskQueue)
+		}
+	}
+
+	// Таймеры истечения бронирований на Redis; без них истечение остаётся на очереди и планировщике
+	var expiryTimer service.ExpiryTimer
+	var redisExpiryTimer *worker.RedisExpiryTimer
+	if cfg.Booking.ExpiryNotifications && cfg.Redis.Mode == config.RedisModeCluster {
+		logrus.Warn("Booking expiry notifications are not supported in Redis cluster mode, falling back to the scheduler")
+	} else if cfg.Booking.ExpiryNotifications && cfg.Redis.Enabled() {
+		expiryClient := redis.NewRedisClient(&cfg.Redis)
+		defer expiryClient.Close()
+
+		timer := worker.NewRedisExpiryTimer(expiryClient)
+		enableCtx, cancelEnable := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := timer.EnableNotifications(enableCtx); err != nil {
+			logrus.Warnf("Booking expiry notifications disabled, falling back to the scheduler: %v", err)
+		} else {
+			expiryTimer = timer
+			redisExpiryTimer = timer
+		}
+		cancelEnable()
+	}
+
+	// Кэш занятости мест оборачивает репозиторий до создания сервисов, чтобы они сбрасывали его
+	if cfg.Booking.AvailabilityCache && cfg.Redis.Enabled() {
+		cacheClient := redis.NewRedisClient(&cfg.Redis)
+		defer cacheClient.Close()
+
+		eventRepo = service.WithAvailabilityCache(eventRepo, redisdb.NewAvailabilityCache(cacheClient, cfg.Booking.AvailabilityCacheTTL))
+		logrus.Info("Event availability cache enabled")
+	}
+
+	// Уведомления о свободных местах оборачивают репозиторий поверх кэша: сервисы публикуют
+	// изменение там же, где сбрасывают кэш
+	var availabilityStream *service.AvailabilityStream
+	if cfg.Booking.AvailabilityStream && cfg.Redis.Enabled() {
+		streamClient := redis.NewRedisClient(&cfg.Redis)
+		defer streamClient.Close()
+
+		notifier := redisdb.NewAvailabilityNotifier(streamClient)
+		eventRepo = service.WithAvailabilityNotifications(eventRepo, notifier)
+		availabilityStream = service.NewAvailabilityStream(eventRepo, notifier)
+		logrus.Info("Event availability stream enabled")
+	}
+
+	// Initialize services
+	bookingService := service.NewBookingService(bookingRepo, eventRepo, userRepo, tierRepo, poolRepo, promoRepo, refundRepo, auditRepo, txManager, expiryTimer, taskPublisher, telegramBot)
+	bookingService.SetDefaultReservationTimeout(cfg.Booking.DefaultTimeout)
+	bookingService.SetCapacityAlertThresholds(cfg.Booking.CapacityAlertThresholds)
+	eventService := service.NewEventService(eventRepo, bookingRepo, venueRepo, poolRepo, posterRepo, taskPublisher)
+	userService := service.NewUserService(userRepo, bookingRepo, auditRepo)
+	tierService := service.NewTicketTierService(tierRepo, eventRepo)
+	poolService := service.NewPartnerPoolService(poolRepo, eventRepo)
+	holdService := service.NewEventHoldService(holdRepo, eventRepo)
+	cartService := service.NewCartService(cartRepo, eventRepo, bookingService, cfg.Booking.CartTTL)
+	promoService := service.NewPromoCodeService(promoRepo, eventRepo)
+	venueService := service.NewVenueService(venueRepo)
+	analyticsService := service.NewAnalyticsService(analyticsRepo)
+	capacityService := service.NewCapacityService(capacityRepo, eventRepo, venueRepo, taskPublisher)
+
+	// Без адреса сервиса изображений афиши только читаются, загрузка отвечает 503
+	var posterImages service.PosterImages
+	if cfg.Images.URL != "" {
+		posterImages = images.NewClient(cfg.Images.URL, cfg.Images.PublicURL, cfg.Images.TenantID, cfg.Images.Timeout)
+	}
+	posterService := service.NewEventPosterService(posterRepo, eventRepo, posterImages, taskPublisher, service.PosterOptions{
+		MaxSize:      cfg.Images.MaxSize,
+		PollInterval: cfg.Images.PollInterval,
+		PollTimeout:  cfg.Images.PollTimeout,
+	})
+
+	// Сверке нужны невыполненные задачи и DLQ; их умеет показать только очередь Redis
+	var taskInspector service.TaskInspector
+	if adapter, ok := taskPublisher.(*service.QueueAdapter); ok {
+		taskInspector = adapter
+	}
+	var deadLetters service.DeadLetterQueue
+	if dlq != nil {
+		deadLetters = service.NewDLQAdapter(dlq)
+	}
+	reconciliationService := service.NewReconciliationService(bookingRepo, outboxRepo, taskInspector, deadLetters)
+	webhookSender := webhook.NewSender(cfg.Webhook.Timeout, cfg.Webhook.MaxAttempts, cfg.Webhook.RetryDelay)
+	webhookService := service.NewWebhookService(webhookRepo, eventRepo, webhookSender)
+	if adminFeedBus != nil {
+		webhookService = service.WithAdminFeed(webhookService, adminFeedBus)
+	}
+	calendarService := service.NewCalendarService(userRepo, bookingRepo, cfg.JWT.Secret, cfg.App.BaseURL)
+	ticketService := service.NewTicketService(bookingRepo, userRepo, cfg.JWT.Secret)
+	apiTokenService := service.NewAPITokenService(apiTokenRepo, userRepo, cfg.APIToken.DefaultRateLimit)
+
+	// Компоненты останавливаются в обратном порядке регистрации: серверы, затем
+	// планировщики и воркеры, последней - очередь, которая дорабатывает начатые задачи
+	lc := lifecycle.New(cfg.Server.ShutdownTimeout)
+
+	// Экспорт трасс останавливается последним и отправляет spans остановки очереди
+	lc.OnStop("tracing", shutdownTracing)
+
+	// Initialize task handler if queue is available
+	if taskQueue != nil {
+		taskHandler := queue.NewTaskHandler(bookingService, eventService, userService, posterService, telegramBot, webhookService, emailSender)
+
+		handlerTimeouts := make(map[queue.TaskType]time.Duration, len(cfg.Worker.QueueTypeHandlerTimeout))
+		for taskType, timeout := range cfg.Worker.QueueTypeHandlerTimeout {
+			handlerTimeouts[queue.TaskType(taskType)] = timeout
+		}
+		handlerMetrics := queue.NewHandlerMetrics()
+		metricsRegistry.MustRegister(handlerMetrics)
+
+		// Паника превращается в ошибку до метрик, срок отсчитывается только для самого обработчика
+		taskRegistry := queue.NewRegistry().Use(
+			queue.RequestIDMiddleware(),
+			queue.TracingMiddleware(),
+			queue.LoggingMiddleware(),
+			handlerMetrics.Middleware(),
+			queue.RecoveryMiddleware(),
+			queue.TimeoutMiddleware(cfg.Worker.QueueHandlerTimeout, handlerTimeouts),
+		)
+		if err := taskHandler.Register(taskRegistry); err != nil {
+			logrus.Fatalf("Failed to register task handlers: %v", err)
+		}
+
+		// Очередь перестаёт брать задачи и дожидается уже выполняющихся
+		lc.OnStop("queue", func(ctx context.Context) error {
+			return taskQueue.Close()
+		})
+		lc.Go("queue subscriber", func(ctx context.Context) {
+			if err := taskQueue.Subscribe(ctx, taskRegistry.Handle); err != nil {
+				logrus.Errorf("Queue subscriber error: %v", err)
+				return
+			}
+			logrus.Info("Queue subscriber started")
+			<-ctx.Done()
+		})
+	}
+
+	checker := newHealthChecker(cfg, db, replicaDB, taskQueue, telegramBot)
+	if redisExpiryTimer != nil {
+		// Вместе с данными Redis теряется и notify-keyspace-events
+		checker.OnRecover("redis", redisExpiryTimer.EnableNotifications)
+	}
+	lc.Go("health checker", func(ctx context.Context) {
+		checker.Run(ctx, cfg.Health.Interval)
+	})
+
+	// Initialize and start scheduler
+	expirationScheduler := scheduler.NewScheduler(bookingService, time.Minute, locker).RequireHealthy(checker, "postgres")
+
+	lc.Go("expiration scheduler", expirationScheduler.Start)
+
+	// Планировщик остаётся страховкой: уведомления Redis не доставляются повторно,
+	// а таймеры теряются вместе с данными Redis
+	if redisExpiryTimer != nil {
+		lc.Go("expiry watcher", worker.NewBookingExpiryWatcher(redisExpiryTimer, bookingService).Start)
+	}
+
+	if cronScheduler != nil {
+		registerCronSchedules(context.Background(), cronScheduler, cfg)
+		lc.Go("cron scheduler", cronScheduler.Start)
+	}
+
+	// Initialize cleanup worker
+	cleanupWorker := worker.NewBookingCleanupWorker(bookingService, 30*time.Minute, locker)
+	lc.Go("cleanup worker", cleanupWorker.Start)
+
+	cartExpiryWorker := worker.NewCartExpiryWorker(cartService, cfg.Worker.CartExpiryInterval, locker)
+	lc.Go("cart expiry worker", cartExpiryWorker.Start)
+	watcher.Subscribe("cart expiry worker", func(cfg *config.Config) {
+		cartExpiryWorker.SetInterval(cfg.Worker.CartExpiryInterval)
+	})
+
+	// Задачи из outbox публикуются в очередь, как только она доступна
+	if taskPublisher != nil {
+		outboxRelay := worker.NewOutboxRelay(outboxRepo, taskPublisher, 2*time.Second)
+		lc.Go("outbox relay", outboxRelay.Start)
+
+		reminderPlanner := worker.NewReminderPlanner(eventService, bookingService, outboxRepo, cfg.Worker.ReminderPlanInterval, locker)
+		lc.Go("reminder planner", reminderPlanner.Start)
+		watcher.Subscribe("reminder planner", func(cfg *config.Config) {
+			reminderPlanner.SetInterval(cfg.Worker.ReminderPlanInterval)
+		})
+	}
+
+	// Initialize handlers
+	eventHandler := transport.NewEventHandler(eventService, posterService)
+	bookingHandler := transport.NewBookingHandler(bookingService)
+	userHandler := transport.NewUserHandler(userService)
+	tierHandler := transport.NewTicketTierHandler(tierService)
+	poolHandler := transport.NewPartnerPoolHandler(poolService)
+	holdHandler := transport.NewEventHoldHandler(holdService)
+	cartHandler := transport.NewCartHandler(cartService)
+	ticketHandler := transport.NewTicketHandler(ticketService)
+	promoHandler := transport.NewPromoCodeHandler(promoService)
+	webhookHandler := transport.NewWebhookHandler(webhookService)
+	calendarHandler := transport.NewCalendarHandler(calendarService)
+	venueHandler := transport.NewVenueHandler(venueService)
+	dlqAdminHandler := transport.NewDLQHandler(dlq)
+	apiTokenHandler := transport.NewAPITokenHandler(apiTokenService)
+	integrationHandler := transport.NewIntegrationHandler(eventService, bookingService)
+	analyticsHandler := transport.NewAnalyticsHandler(analyticsService)
+	capacityHandler := transport.NewCapacityHandler(capacityService)
+	reconciliationHandler := transport.NewReconciliationHandler(reconciliationService)
+	healthHandler := transport.NewHealthHandler(checker)
+	eventPageHandler := transport.NewEventPageHandler(eventService)
+	availabilityHandler := transport.NewAvailabilityHandler(availabilityStream)
+	adminFeedHandler := transport.NewAdminFeedHandler(adminFeed, cfg.AdminFeed.AllowedOrigins)
+
+	jwtManager := middleware.NewJWTManager(cfg.JWT)
+	authHandler := transport.NewAuthHandler(userService, jwtManager)
+
+	// Без Redis лимиты не применяются: счётчики в памяти разошлись бы между экземплярами
+	var rateLimiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled && cfg.Redis.Enabled() {
+		rateLimitClient := redis.NewRedisClient(&cfg.Redis)
+		defer rateLimitClient.Close()
+		rateLimiter = transport.NewRateLimiter(cfg.RateLimit, rateLimitClient, jwtManager)
+	}
+
+	// Setup HTTP server
+	if cfg.Server.Env == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	if cfg.Server.Mode == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := transport.InitRoutes(cfg, jwtManager, authHandler, eventHandler, bookingHandler, cartHandler, ticketHandler, userHandler, tierHandler, poolHandler, holdHandler, promoHandler, webhookHandler, calendarHandler, venueHandler, dlqAdminHandler, apiTokenHandler, integrationHandler, analyticsHandler, capacityHandler, reconciliationHandler, healthHandler, eventPageHandler, availabilityHandler, adminFeedHandler, apiTokenService, rateLimiter)
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})))
+
+	if telegramBot != nil {
+		telegramHandler := transport.NewTelegramHandler(telegramBot, bookingService, eventService, userService)
+		startTelegramUpdates(lc, cfg.Telegram, telegramBot, telegramHandler, router)
+	}
+
+	srv := new(Server)
+	lc.AddServer("http", func() error {
+		return srv.Run(cfg, router)
+	}, srv.Shutdown)
+
+	// Поток мест регистрируется после HTTP-сервера и останавливается раньше него: закрытые
+	// каналы завершают SSE-запросы, иначе Shutdown ждал бы их до конца срока остановки
+	if availabilityStream != nil {
+		lc.Go("availability stream", availabilityStream.Run)
+	}
+	// Shutdown не ждёт WebSocket-соединений; остановка ленты закрывает их кадром going away
+	if adminFeed != nil {
+		lc.Go("admin feed", adminFeed.Run)
+	}
+
+	// gRPC API работает поверх тех же сервисов, что и REST
+	if cfg.GRPC.Enabled {
+		port := cfg.GRPC.Port
+		if port == "" {
+			port = "9090"
+		}
+		grpcServer := grpctransport.NewServer(port, jwtManager, eventService, bookingService, userService)
+		lc.AddServer("grpc", grpcServer.Run, func(ctx context.Context) error {
+			shutdownTimeout := cfg.GRPC.ShutdownTimeout
+			if shutdownTimeout <= 0 {
+				shutdownTimeout = 10 * time.Second
+			}
+			grpcCtx, cancelGRPC := context.WithTimeout(ctx, shutdownTimeout)
+			defer cancelGRPC()
+			grpcServer.Shutdown(grpcCtx)
+			return nil
+		})
+	}
+
+	watcher.Subscribe("log level", func(cfg *config.Config) {
+		setLogLevel(cfg.Logging.Level)
+	})
+	watcher.Subscribe("booking", func(cfg *config.Config) {
+		bookingService.SetDefaultReservationTimeout(cfg.Booking.DefaultTimeout)
+		bookingService.SetCapacityAlertThresholds(cfg.Booking.CapacityAlertThresholds)
+	})
+	// Подписчики зарегистрированы, изменения config.yaml применяются с этого момента
+	if err := watcher.Start(); err != nil {
+		logrus.Warnf("Config hot reload disabled: %v", err)
+	}
+	defer watcher.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	logrus.Print("App Started")
+
+	if err := lc.Run(ctx); err != nil {
+		logrus.Errorf("error occured on app shutting down: %s", err.Error())
+	}
+
+	logrus.Print("App Stopped")
+}
+
+// setLogLevel применяет logging.level; пустой уровень - info. Уровень проверен в config.Validate
+func setLogLevel(level string) {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		parsed = logrus.InfoLevel
+	}
+	logrus.SetLevel(parsed)
+}
+
+// newBrokerQueue создаёт очередь на брокере, выбранном в queue.driver; незаданные параметры берутся по умолчанию
+func newBrokerQueue(cfg config.QueueConfig, dispatch queue.DispatchConfig) (queue.Queue, error) {
+	switch cfg.Driver {
+	case queue.DriverKafka:
+		kafkaConfig := queue.DefaultKafkaQueueConfig()
+		if len(cfg.Kafka.Brokers) > 0 {
+			kafkaConfig.Brokers = cfg.Kafka.Brokers
+		}
+		if cfg.Kafka.Topic != "" {
+			kafkaConfig.Topic = cfg.Kafka.Topic
+		}
+		if cfg.Kafka.GroupID != "" {
+			kafkaConfig.GroupID = cfg.Kafka.GroupID
+		}
+		if cfg.Kafka.DLQTopic != "" {
+			kafkaConfig.DLQTopic = cfg.Kafka.DLQTopic
+		}
+		if cfg.Kafka.DelayTopic != "" {
+			kafkaConfig.DelayTopic = cfg.Kafka.DelayTopic
+		}
+		kafkaConfig.Dispatch = dispatch
+
+		q, err := queue.NewKafkaQueue(kafkaConfig)
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	case queue.DriverRabbitMQ:
+		rabbitConfig := queue.DefaultRabbitMQQueueConfig()
+		if cfg.RabbitMQ.URL != "" {
+			rabbitConfig.URL = cfg.RabbitMQ.URL
+		}
+		if cfg.RabbitMQ.Queue != "" {
+			rabbitConfig.Queue = cfg.RabbitMQ.Queue
+		}
+		if cfg.RabbitMQ.DLQ != "" {
+			rabbitConfig.DLQ = cfg.RabbitMQ.DLQ
+		}
+		rabbitConfig.Dispatch = dispatch
+
+		q, err := queue.NewRabbitMQQueue(rabbitConfig)
+		if err != nil {
+			return nil, err
+		}
+		return q, nil
+	case queue.DriverMemory:
+		return queue.NewMemoryQueue(&dispatch), nil
+	default:
+		return nil, fmt.Errorf("unknown queue driver %q", cfg.Driver)
+	}
+}
+
+// newHealthChecker регистрирует проверки зависимостей: без Postgres сервис не готов,
+// без Redis, очереди и Telegram работает с ограничениями
+func newHealthChecker(cfg *config.Config, db, replicaDB *sql.DB, taskQueue queue.Queue, bot *telegram.Bot) *health.Checker {
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Register("postgres", true, db.PingContext)
+
+	// Без реплики медленнее только админка, поэтому она не влияет на готовность
+	if replicaDB != nil {
+		checker.Register("postgres_replica", false, replicaDB.PingContext)
+	}
+
+	if cfg.Redis.Enabled() {
+		client := redis.NewRedisClient(&cfg.Redis)
+		checker.Register("redis", false, func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		})
+	}
+
+	if q, ok := taskQueue.(interface{ HealthCheck(context.Context) error }); ok {
+		checker.Register("queue", false, q.HealthCheck)
+	}
+
+	if bot != nil {
+		checker.Register("telegram", false, bot.GetMe)
+	}
+
+	return checker
+}
+
+// startTelegramUpdates подключает получение команд и нажатий кнопок бота выбранным в telegram.updates способом
+func startTelegramUpdates(lc *lifecycle.Manager, cfg config.TelegramConfig, bot *telegram.Bot, handler *transport.TelegramHandler, router *gin.Engine) {
+	switch cfg.Updates {
+	case "":
+		return
+	case "polling":
+		lc.Go("telegram polling", func(ctx context.Context) {
+			bot.Poll(ctx, cfg.PollTimeout, handler.HandleUpdate)
+		})
+	case "webhook":
+		if cfg.WebhookURL == "" || cfg.WebhookSecret == "" {
+			logrus.Error("Telegram webhook requires webhook_url and webhook_secret, updates disabled")
+			return
+		}
+		router.POST("/telegram/webhook", gin.WrapH(telegram.WebhookHandler(cfg.WebhookSecret, handler.HandleUpdate)))
+		if err := bot.SetWebhook(cfg.WebhookURL, cfg.WebhookSecret); err != nil {
+			logrus.Errorf("Failed to set Telegram webhook: %v", err)
+			return
+		}
+	default:
+		logrus.Errorf("Unknown telegram.updates mode %q, updates disabled", cfg.Updates)
+		return
+	}
+
+	logrus.Infof("Telegram updates enabled (%s)", cfg.Updates)
+}
+
+// registerCronSchedules сохраняет повторяющиеся задачи из конфигурации; пустое выражение удаляет расписание
+func registerCronSchedules(ctx context.Context, cron *queue.CronScheduler, cfg *config.Config) {
+	schedules := []struct {
+		spec     string
+		schedule queue.CronSchedule
+	}{
+		{cfg.Worker.CleanupCron, queue.CronSchedule{
+			Name: "cleanup_expired",
+			Type: queue.TaskTypeCleanupExpired,
+		}},
+		{cfg.Worker.ReminderSweepCron, queue.CronSchedule{
+			Name: "event_reminder_sweep",
+			Type: queue.TaskTypeEventReminder,
+			Data: map[string]interface{}{"reminder_hours": 24, "window_minutes": 60},
+		}},
+	}
+
+	for _, s := range schedules {
+		if s.spec == "" {
+			if err := cron.Remove(ctx, s.schedule.Name); err != nil {
+				logrus.Errorf("Failed to remove cron schedule: %v", err)
+			}
+			continue
+		}
+
+		s.schedule.Spec = s.spec
+		if err := cron.Register(ctx, s.schedule); err != nil {
+			logrus.Errorf("Failed to register cron schedule: %v", err)
+		}
+	}
+}
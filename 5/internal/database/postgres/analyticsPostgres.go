@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type analyticsRepository struct {
+	db *conn
+}
+
+// NewAnalyticsRepository считает аналитику на replica, если она задана: запросы тяжёлые,
+// а отставание реплики на секунды для графиков не важно
+func NewAnalyticsRepository(db, replica *sql.DB) AnalyticsRepository {
+	return &analyticsRepository{db: newRoutedConn(db, replica)}
+}
+
+// GetSystemStats считает все показатели одним запросом, чтобы они были согласованы между собой
+func (r *analyticsRepository) GetSystemStats(ctx context.Context) (*entity.SystemStats, error) {
+	query := `
+		WITH event_seats AS (
+			SELECT e.id, e.date, e.total_seats,
+				COALESCE(SUM(b.seats) FILTER (WHERE b.status = 'confirmed'), 0) AS confirmed_seats
+			FROM events e
+			LEFT JOIN bookings b ON b.event_id = e.id AND b.deleted_at IS NULL
+			WHERE e.deleted_at IS NULL
+			GROUP BY e.id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM event_seats),
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM bookings WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM event_seats WHERE date >= NOW()),
+			(SELECT COUNT(*) FROM event_seats WHERE date < NOW()),
+			(SELECT COALESCE(SUM(total_price), 0) FROM bookings WHERE status = 'confirmed' AND deleted_at IS NULL),
+			(SELECT COALESCE(AVG(confirmed_seats::float8 / total_seats), 0) FROM event_seats WHERE total_seats > 0),
+			(SELECT date_trunc('hour', created_at) FROM bookings WHERE deleted_at IS NULL
+				GROUP BY 1 ORDER BY COUNT(*) DESC, 1 DESC LIMIT 1)
+	`
+
+	var stats entity.SystemStats
+	var peak sql.NullTime
+	err := r.db.read(ctx).QueryRowContext(ctx, query).Scan(
+		&stats.TotalEvents,
+		&stats.TotalUsers,
+		&stats.TotalBookings,
+		&stats.ActiveEvents,
+		&stats.CompletedEvents,
+		&stats.Revenue,
+		&stats.Utilization,
+		&peak,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system stats: %v", err)
+	}
+	if peak.Valid {
+		stats.PeakUsageTime = peak.Time
+	}
+
+	return &stats, nil
+}
+
+func (r *analyticsRepository) GetBookingTrends(ctx context.Context, period string, from, to time.Time) (*entity.BookingTrends, error) {
+	if err := entity.ValidateTrendPeriod(period); err != nil {
+		return nil, err
+	}
+
+	// Бронирования и средний размер - по времени создания; подтверждения, отмены и выручка -
+	// по записям журнала аудита о смене статуса, включая бронирования, созданные сразу подтверждёнными
+	query := `
+		WITH periods AS (
+			SELECT p.start
+			FROM generate_series(date_trunc($1::text, $2::timestamp), date_trunc($1::text, $3::timestamp - interval '1 microsecond'),
+				('1 ' || $1::text)::interval) AS p(start)
+		),
+		created AS (
+			SELECT date_trunc($1::text, created_at) AS start, COUNT(*) AS bookings, AVG(seats) AS average_seats
+			FROM bookings
+			WHERE created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+			GROUP BY 1
+		),
+		transitions AS (
+			SELECT date_trunc($1::text, a.created_at) AS start,
+				COUNT(*) FILTER (WHERE a.new_status = 'confirmed') AS confirmations,
+				COUNT(*) FILTER (WHERE a.new_status = 'cancelled') AS cancellations,
+				SUM(b.total_price) FILTER (WHERE a.new_status = 'confirmed') AS revenue
+			FROM audit_log a
+			JOIN bookings b ON b.id = a.entity_id
+			WHERE a.entity_type = 'booking' AND a.action IN ('created', 'status_changed')
+				AND a.new_status IN ('confirmed', 'cancelled')
+				AND a.created_at >= $2 AND a.created_at < $3
+			GROUP BY 1
+		)
+		SELECT to_char(p.start, 'YYYY-MM-DD'),
+			COALESCE(c.bookings, 0),
+			COALESCE(t.confirmations, 0),
+			COALESCE(t.cancellations, 0),
+			COALESCE(t.revenue, 0),
+			COALESCE(c.average_seats, 0)
+		FROM periods p
+		LEFT JOIN created c ON c.start = p.start
+		LEFT JOIN transitions t ON t.start = p.start
+		ORDER BY p.start
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, period, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking trends: %v", err)
+	}
+	defer rows.Close()
+
+	trends := &entity.BookingTrends{
+		Period:        period,
+		Dates:         []string{},
+		Bookings:      []int64{},
+		Confirmations: []int64{},
+		Cancellations: []int64{},
+		Revenue:       []float64{},
+		AverageSeats:  []float64{},
+	}
+	for rows.Next() {
+		var date string
+		var bookings, confirmations, cancellations int64
+		var revenue, averageSeats float64
+		if err := rows.Scan(&date, &bookings, &confirmations, &cancellations, &revenue, &averageSeats); err != nil {
+			return nil, fmt.Errorf("failed to scan booking trend: %v", err)
+		}
+		trends.Dates = append(trends.Dates, date)
+		trends.Bookings = append(trends.Bookings, bookings)
+		trends.Confirmations = append(trends.Confirmations, confirmations)
+		trends.Cancellations = append(trends.Cancellations, cancellations)
+		trends.Revenue = append(trends.Revenue, revenue)
+		trends.AverageSeats = append(trends.AverageSeats, averageSeats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate booking trends: %v", err)
+	}
+
+	return trends, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type apiTokenRepository struct {
+	db *conn
+}
+
+func NewAPITokenRepository(db *sql.DB) APITokenRepository {
+	return &apiTokenRepository{db: newConn(db)}
+}
+
+const apiTokenColumns = `id, organizer_id, name, prefix, token_hash, scopes, rate_limit, last_used_at, expires_at, revoked_at, created_at`
+
+func (r *apiTokenRepository) Create(ctx context.Context, token *entity.APIToken) error {
+	query := `
+		INSERT INTO api_tokens (organizer_id, name, prefix, token_hash, scopes, rate_limit, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		token.OrganizerID,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		pq.Array(token.Scopes),
+		token.RateLimit,
+		token.ExpiresAt,
+		now,
+	).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	token.CreatedAt = now
+	return nil
+}
+
+func (r *apiTokenRepository) GetByHash(ctx context.Context, hash string) (*entity.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = $1`
+
+	token, err := scanAPIToken(r.db.QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r *apiTokenRepository) GetAll(ctx context.Context) ([]*entity.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens ORDER BY created_at DESC`
+	return r.queryAPITokens(ctx, query)
+}
+
+func (r *apiTokenRepository) GetByOrganizer(ctx context.Context, organizerID int64) ([]*entity.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE organizer_id = $1 ORDER BY created_at DESC`
+	return r.queryAPITokens(ctx, query, organizerID)
+}
+
+// Revoke отзывает токен; повторный отзыв не меняет исходную дату
+func (r *apiTokenRepository) Revoke(ctx context.Context, id int64) (*entity.APIToken, error) {
+	query := `
+		UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+		RETURNING ` + apiTokenColumns
+
+	token, err := scanAPIToken(r.db.QueryRowContext(ctx, query, id, time.Now()))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke api token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r *apiTokenRepository) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update api token last use: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiTokenRepository) queryAPITokens(ctx context.Context, query string, args ...interface{}) ([]*entity.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*entity.APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func scanAPIToken(row rowScanner) (*entity.APIToken, error) {
+	var token entity.APIToken
+	err := row.Scan(
+		&token.ID,
+		&token.OrganizerID,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		pq.Array(&token.Scopes),
+		&token.RateLimit,
+		&token.LastUsedAt,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
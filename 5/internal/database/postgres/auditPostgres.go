@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type auditRepository struct {
+	db *conn
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: newConn(db)}
+}
+
+// insertAudit writes audit entries inside tx, so the journal never disagrees with the data
+func insertAudit(ctx context.Context, tx *repoTx, entries ...*entity.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (entity_type, entity_id, action, old_status, new_status, actor_id, actor, reason, impersonator_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	now := time.Now()
+	for _, entry := range entries {
+		entry.CreatedAt = now
+		err := tx.QueryRowContext(ctx, query,
+			entry.EntityType,
+			entry.EntityID,
+			entry.Action,
+			entry.OldStatus,
+			entry.NewStatus,
+			entry.ActorID,
+			entry.Actor,
+			entry.Reason,
+			entry.ImpersonatorID,
+			entry.CreatedAt,
+		).Scan(&entry.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert audit entry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Create записывает событие, которое не меняет данных сущности, например начало имперсонации
+func (r *auditRepository) Create(ctx context.Context, entry *entity.AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) GetByEntity(ctx context.Context, entityType string, entityID int64) ([]*entity.AuditEntry, error) {
+	query := `
+		SELECT id, entity_type, entity_id, action, old_status, new_status, actor_id, actor, reason, impersonator_id, created_at
+		FROM audit_log
+		WHERE entity_type = $1 AND entity_id = $2
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, entityType, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.AuditEntry, 0)
+	for rows.Next() {
+		var entry entity.AuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.EntityType,
+			&entry.EntityID,
+			&entry.Action,
+			&entry.OldStatus,
+			&entry.NewStatus,
+			&entry.ActorID,
+			&entry.Actor,
+			&entry.Reason,
+			&entry.ImpersonatorID,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "fmt"
+
+// holdsSeats возвращает условие, при котором бронирование занимает места: подтвержденное
+// или ожидающее с неистекшим резервом. Его используют создание и подтверждение бронирования
+// и подсчет занятых мест мероприятия, категории и пула, чтобы проверки вместимости не расходились.
+// alias - псевдоним таблицы bookings в запросе, пустая строка - без псевдонима.
+func holdsSeats(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return fmt.Sprintf("(%[1]sstatus = 'confirmed' OR (%[1]sstatus = 'pending' AND %[1]sexpires_at > NOW()))", alias)
+}
@@ -1,791 +1,1663 @@
-package repository
-
-import (
-	"context"
-	"database/sql"
-	"fmt"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-type bookingRepository struct {
-	db *sql.DB
-}
-
-func NewBookingRepository(db *sql.DB) BookingRepository {
-	return &bookingRepository{db: db}
-}
-
-// Create creates a new booking with transaction to ensure data consistency
-func (r *bookingRepository) Create(ctx context.Context, booking *entity.Booking) error {
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
-
-	// Check available seats
-	var confirmedSeats int
-	query := `SELECT COALESCE(SUM(seats), 0) FROM bookings WHERE event_id = $1 AND status = 'confirmed'`
-	err = tx.QueryRowContext(ctx, query, booking.EventID).Scan(&confirmedSeats)
-	if err != nil {
-		return fmt.Errorf("failed to check confirmed seats: %v", err)
-	}
-
-	var totalSeats int
-	query = `SELECT total_seats FROM events WHERE id = $1`
-	err = tx.QueryRowContext(ctx, query, booking.EventID).Scan(&totalSeats)
-	if err != nil {
-		return fmt.Errorf("failed to get event total seats: %v", err)
-	}
-
-	// Check if user already has a pending or confirmed booking for this event
-	var existingBookingCount int
-	query = `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND user_id = $2 AND status IN ('pending', 'confirmed')`
-	err = tx.QueryRowContext(ctx, query, booking.EventID, booking.UserID).Scan(&existingBookingCount)
-	if err != nil {
-		return fmt.Errorf("failed to check existing bookings: %v", err)
-	}
-	if existingBookingCount > 0 {
-		return fmt.Errorf("user already has a booking for this event")
-	}
-
-	// Validate available seats
-	if confirmedSeats+booking.Seats > totalSeats {
-		return fmt.Errorf("not enough available seats: requested %d, available %d",
-			booking.Seats, totalSeats-confirmedSeats)
-	}
-
-	// Create booking
-	query = `
-		INSERT INTO bookings (
-			event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id
-	`
-
-	now := time.Now()
-	expiresAt := now.Add(time.Duration(booking.ReservationTimeout) * time.Minute)
-
-	err = tx.QueryRowContext(ctx, query,
-		booking.EventID,
-		booking.UserID,
-		booking.Seats,
-		booking.Status,
-		expiresAt,
-		booking.ReservationTimeout,
-		now,
-		now,
-	).Scan(&booking.ID)
-
-	if err != nil {
-		return fmt.Errorf("failed to create booking: %v", err)
-	}
-
-	booking.ExpiresAt = expiresAt
-	booking.CreatedAt = now
-	booking.UpdatedAt = now
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
-
-	return nil
-}
-
-// GetByID retrieves a booking by its ID
-func (r *bookingRepository) GetByID(ctx context.Context, id int64) (*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE id = $1
-	`
-
-	var booking entity.Booking
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&booking.ID,
-		&booking.EventID,
-		&booking.UserID,
-		&booking.Seats,
-		&booking.Status,
-		&booking.ExpiresAt,
-		&booking.ReservationTimeout,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, entity.ErrBookingNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get booking: %v", err)
-	}
-
-	return &booking, nil
-}
-
-// GetByEventAndUser retrieves a booking by event and user
-func (r *bookingRepository) GetByEventAndUser(ctx context.Context, eventID, userID int64) (*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE event_id = $1 AND user_id = $2 AND status IN ('pending', 'confirmed')
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
-
-	var booking entity.Booking
-	err := r.db.QueryRowContext(ctx, query, eventID, userID).Scan(
-		&booking.ID,
-		&booking.EventID,
-		&booking.UserID,
-		&booking.Seats,
-		&booking.Status,
-		&booking.ExpiresAt,
-		&booking.ReservationTimeout,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get booking by event and user: %v", err)
-	}
-
-	return &booking, nil
-}
-
-// UpdateStatus updates the status of a booking
-func (r *bookingRepository) UpdateStatus(ctx context.Context, id int64, status entity.BookingStatus) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
-
-	// Get current booking to validate the update
-	var currentBooking entity.Booking
-	query := `SELECT event_id, seats, status FROM bookings WHERE id = $1`
-	err = tx.QueryRowContext(ctx, query, id).Scan(
-		&currentBooking.EventID,
-		&currentBooking.Seats,
-		&currentBooking.Status,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to get current booking: %v", err)
-	}
-
-	// If changing from pending to confirmed, check seat availability
-	if currentBooking.Status == entity.BookingStatusPending && status == entity.BookingStatusConfirmed {
-		var confirmedSeats int
-		query = `SELECT COALESCE(SUM(seats), 0) FROM bookings WHERE event_id = $1 AND status = 'confirmed'`
-		err = tx.QueryRowContext(ctx, query, currentBooking.EventID).Scan(&confirmedSeats)
-		if err != nil {
-			return fmt.Errorf("failed to check confirmed seats: %v", err)
-		}
-
-		var totalSeats int
-		query = `SELECT total_seats FROM events WHERE id = $1`
-		err = tx.QueryRowContext(ctx, query, currentBooking.EventID).Scan(&totalSeats)
-		if err != nil {
-			return fmt.Errorf("failed to get event total seats: %v", err)
-		}
-
-		if confirmedSeats+currentBooking.Seats > totalSeats {
-			return fmt.Errorf("not enough available seats to confirm booking")
-		}
-	}
-
-	// Update the status
-	query = `UPDATE bookings SET status = $1, updated_at = $2 WHERE id = $3`
-	result, err := tx.ExecContext(ctx, query, status, time.Now(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update booking status: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrBookingNotFound
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
-
-	return nil
-}
-
-// GetByEventID retrieves all bookings for a specific event
-func (r *bookingRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE event_id = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query bookings by event: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// GetByUserID retrieves all bookings for a specific user
-func (r *bookingRepository) GetByUserID(ctx context.Context, userID int64) ([]*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query bookings by user: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// GetByStatus retrieves all bookings with a specific status
-func (r *bookingRepository) GetByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE status = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, status)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query bookings by status: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// GetByEventAndStatus retrieves bookings for a specific event and status
-func (r *bookingRepository) GetByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) ([]*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE event_id = $1 AND status = $2
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, eventID, status)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query bookings by event and status: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// GetExpiredBookings retrieves expired bookings before a certain time
-func (r *bookingRepository) GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error) {
-	query := `
-		SELECT 
-			b.id, b.expires_at, b.user_id, b.event_id,
-			u.telegram_id, u.name as user_name,
-			e.title as event_title
-		FROM bookings b
-		JOIN users u ON b.user_id = u.id
-		JOIN events e ON b.event_id = e.id
-		WHERE b.status = 'pending' AND b.expires_at < $1
-		ORDER BY b.expires_at ASC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, before)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query expired bookings: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.BookingExpiration
-	for rows.Next() {
-		var booking entity.BookingExpiration
-		err := rows.Scan(
-			&booking.BookingID,
-			&booking.ExpiresAt,
-			&booking.UserID,
-			&booking.EventID,
-			&booking.TelegramID,
-			&booking.UserName,
-			&booking.EventTitle,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan expired booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating expired bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// GetExpiringBookings retrieves bookings that will expire within a time range
-func (r *bookingRepository) GetExpiringBookings(ctx context.Context, from, to time.Time) ([]*entity.BookingExpiration, error) {
-	query := `
-		SELECT 
-			b.id, b.expires_at, b.user_id, b.event_id,
-			u.telegram_id, u.name as user_name,
-			e.title as event_title
-		FROM bookings b
-		JOIN users u ON b.user_id = u.id
-		JOIN events e ON b.event_id = e.id
-		WHERE b.status = 'pending' AND b.expires_at BETWEEN $1 AND $2
-		ORDER BY b.expires_at ASC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, from, to)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query expiring bookings: %v", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.BookingExpiration
-	for rows.Next() {
-		var booking entity.BookingExpiration
-		err := rows.Scan(
-			&booking.BookingID,
-			&booking.ExpiresAt,
-			&booking.UserID,
-			&booking.EventID,
-			&booking.TelegramID,
-			&booking.UserName,
-			&booking.EventTitle,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan expiring booking: %v", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating expiring bookings: %v", err)
-	}
-
-	return bookings, nil
-}
-
-// DeleteExpired deletes expired bookings and returns the count of deleted rows
-func (r *bookingRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
-	query := `DELETE FROM bookings WHERE status = 'pending' AND expires_at < $1`
-	result, err := r.db.ExecContext(ctx, query, before)
-	if err != nil {
-		return 0, fmt.Errorf("failed to delete expired bookings: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rows affected: %v", err)
-	}
-
-	return rowsAffected, nil
-}
-
-// BulkUpdateStatus updates the status of multiple bookings in a single transaction
-func (r *bookingRepository) BulkUpdateStatus(ctx context.Context, ids []int64, status entity.BookingStatus) error {
-	if len(ids) == 0 {
-		return nil
-	}
-
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
-
-	// Build the query with placeholders
-	query := `UPDATE bookings SET status = $1, updated_at = $2 WHERE id IN (`
-	args := []interface{}{status, time.Now()}
-
-	for i, id := range ids {
-		if i > 0 {
-			query += ","
-		}
-		query += fmt.Sprintf("$%d", i+3)
-		args = append(args, id)
-	}
-	query += ")"
-
-	result, err := tx.ExecContext(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to bulk update booking status: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
-	}
-
-	if rowsAffected != int64(len(ids)) {
-		return fmt.Errorf("expected to update %d rows, but updated %d", len(ids), rowsAffected)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
-
-	return nil
-}
-
-// CountByEvent counts all bookings for a specific event
-func (r *bookingRepository) CountByEvent(ctx context.Context, eventID int64) (int, error) {
-	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1`
-	var count int
-	err := r.db.QueryRowContext(ctx, query, eventID).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count bookings by event: %v", err)
-	}
-	return count, nil
-}
-
-// CountByEventAndStatus counts bookings for a specific event and status
-func (r *bookingRepository) CountByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) (int, error) {
-	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND status = $2`
-	var count int
-	err := r.db.QueryRowContext(ctx, query, eventID, status).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count bookings by event and status: %v", err)
-	}
-	return count, nil
-}
-
-// GetEventBookingStats returns statistics for event bookings
-func (r *bookingRepository) GetEventBookingStats(ctx context.Context, eventID int64) (*entity.EventBookingStats, error) {
-	query := `
-		SELECT 
-			COUNT(*) as total_bookings,
-			COALESCE(SUM(CASE WHEN status = 'pending' THEN seats ELSE 0 END), 0) as pending_seats,
-			COALESCE(SUM(CASE WHEN status = 'confirmed' THEN seats ELSE 0 END), 0) as confirmed_seats,
-			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN seats ELSE 0 END), 0) as cancelled_seats,
-			COALESCE(SUM(CASE WHEN status = 'expired' THEN seats ELSE 0 END), 0) as expired_seats
-		FROM bookings 
-		WHERE event_id = $1
-	`
-
-	var stats entity.EventBookingStats
-	err := r.db.QueryRowContext(ctx, query, eventID).Scan(
-		&stats.TotalBookings,
-		&stats.PendingSeats,
-		&stats.ConfirmedSeats,
-		&stats.CancelledSeats,
-		&stats.ExpiredSeats,
-	)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to get event booking stats: %v", err)
-	}
-
-	return &stats, nil
-}
-
-// LockBooking locks a booking for update (for concurrency control)
-func (r *bookingRepository) LockBooking(ctx context.Context, id int64) error {
-	query := `SELECT 1 FROM bookings WHERE id = $1 FOR UPDATE`
-	var dummy int
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&dummy)
-	if err != nil {
-		return fmt.Errorf("failed to lock booking: %v", err)
-	}
-	return nil
-}
-
-// GetWithLock retrieves a booking with a lock for update
-func (r *bookingRepository) GetWithLock(ctx context.Context, id int64) (*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		WHERE id = $1
-		FOR UPDATE
-	`
-
-	var booking entity.Booking
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&booking.ID,
-		&booking.EventID,
-		&booking.UserID,
-		&booking.Seats,
-		&booking.Status,
-		&booking.ExpiresAt,
-		&booking.ReservationTimeout,
-		&booking.CreatedAt,
-		&booking.UpdatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, entity.ErrBookingNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get booking with lock: %v", err)
-	}
-
-	return &booking, nil
-}
-
-func (r *bookingRepository) Update(ctx context.Context, booking *entity.Booking) error {
-	query := `
-		UPDATE bookings 
-		SET event_id = $1, user_id = $2, seats = $3, status = $4, 
-		    expires_at = $5, reservation_timeout = $6, updated_at = $7
-		WHERE id = $8
-	`
-
-	result, err := r.db.ExecContext(ctx, query,
-		booking.EventID,
-		booking.UserID,
-		booking.Seats,
-		booking.Status,
-		booking.ExpiresAt,
-		booking.ReservationTimeout,
-		time.Now(),
-		booking.ID,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to update booking: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrBookingNotFound
-	}
-
-	booking.UpdatedAt = time.Now()
-	return nil
-}
-
-func (r *bookingRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM bookings WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete booking: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrBookingNotFound
-	}
-
-	return nil
-}
-
-func (r *bookingRepository) GetAll(ctx context.Context) ([]*entity.Booking, error) {
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query all bookings: %w", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %w", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating bookings: %w", err)
-	}
-
-	return bookings, nil
-}
-
-func (r *bookingRepository) GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error) {
-	if limit <= 0 {
-		limit = 50
-	}
-
-	query := `
-		SELECT 
-			id, event_id, user_id, seats, status, expires_at, 
-			reservation_timeout, created_at, updated_at
-		FROM bookings 
-		ORDER BY created_at DESC
-		LIMIT $1
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query recent bookings: %w", err)
-	}
-	defer rows.Close()
-
-	var bookings []*entity.Booking
-	for rows.Next() {
-		var booking entity.Booking
-		err := rows.Scan(
-			&booking.ID,
-			&booking.EventID,
-			&booking.UserID,
-			&booking.Seats,
-			&booking.Status,
-			&booking.ExpiresAt,
-			&booking.ReservationTimeout,
-			&booking.CreatedAt,
-			&booking.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan booking: %w", err)
-		}
-		bookings = append(bookings, &booking)
-	}
-
-	return bookings, nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type bookingRepository struct {
+	db *conn
+}
+
+// NewBookingRepository serves GetAll, recent bookings, event stats and export batches from
+// replica when it is not nil
+func NewBookingRepository(db, replica *sql.DB) BookingRepository {
+	return &bookingRepository{db: newRoutedConn(db, replica)}
+}
+
+// Create creates a new booking with transaction to ensure data consistency
+func (r *bookingRepository) Create(ctx context.Context, booking *entity.Booking) error {
+	return r.CreateWithOutbox(ctx, booking, nil)
+}
+
+// CreateWithOutbox creates a booking and, in the same transaction, stores the queue tasks
+// returned by outbox. The callback gets the booking with its ID and expiration already set.
+func (r *bookingRepository) CreateWithOutbox(ctx context.Context, booking *entity.Booking, outbox func(*entity.Booking) []*entity.OutboxMessage) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.createTx(ctx, tx, booking, outbox); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// CreateManyWithOutbox creates all bookings in one transaction: if any of them fails
+// availability or promo checks, none is created. Events are locked in ID order up front,
+// so concurrent checkouts touching the same events cannot deadlock.
+func (r *bookingRepository) CreateManyWithOutbox(ctx context.Context, bookings []*entity.Booking, outbox func(*entity.Booking) []*entity.OutboxMessage) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	eventIDs := make([]int64, 0, len(bookings))
+	for _, booking := range bookings {
+		eventIDs = append(eventIDs, booking.EventID)
+	}
+	sort.Slice(eventIDs, func(i, j int) bool { return eventIDs[i] < eventIDs[j] })
+
+	for _, eventID := range eventIDs {
+		var id int64
+		query := `SELECT id FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, eventID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("event %d: %w", eventID, entity.ErrEventNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock event %d: %v", eventID, err)
+		}
+	}
+
+	for _, booking := range bookings {
+		if err := r.createTx(ctx, tx, booking, outbox); err != nil {
+			return fmt.Errorf("event %d: %w", booking.EventID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// createTx validates availability and inserts the booking with its audit entry and outbox tasks
+func (r *bookingRepository) createTx(ctx context.Context, tx *repoTx, booking *entity.Booking, outbox func(*entity.Booking) []*entity.OutboxMessage) error {
+	// Lock the event row so that concurrent bookings of the same event are serialized:
+	// the availability check and the insert below happen atomically with respect to each other
+	var totalSeats int
+	query := `SELECT total_seats FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, booking.EventID).Scan(&totalSeats)
+	if err == sql.ErrNoRows {
+		return entity.ErrEventNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock event: %v", err)
+	}
+
+	// Check if user already has a pending or confirmed booking for this event
+	var existingBookingCount int
+	query = `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND user_id = $2 AND status IN ('pending', 'confirmed') AND deleted_at IS NULL`
+	err = tx.QueryRowContext(ctx, query, booking.EventID, booking.UserID).Scan(&existingBookingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check existing bookings: %v", err)
+	}
+	if existingBookingCount > 0 {
+		return fmt.Errorf("user already has a booking for this event")
+	}
+
+	// Validate available seats: a pool booking draws only from its pool,
+	// other bookings from the seats left after all partner pools
+	if booking.PoolID != nil {
+		var poolSeats, poolHeldSeats int
+		query = `SELECT seats FROM partner_pools WHERE id = $1 AND event_id = $2`
+		err = tx.QueryRowContext(ctx, query, *booking.PoolID, booking.EventID).Scan(&poolSeats)
+		if err == sql.ErrNoRows {
+			return entity.ErrPartnerPoolNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get partner pool: %v", err)
+		}
+
+		query = `
+			SELECT COALESCE(SUM(seats), 0) FROM bookings
+			WHERE pool_id = $1 AND deleted_at IS NULL
+			  AND ` + holdsSeats("") + `
+		`
+		err = tx.QueryRowContext(ctx, query, *booking.PoolID).Scan(&poolHeldSeats)
+		if err != nil {
+			return fmt.Errorf("failed to check pool held seats: %v", err)
+		}
+
+		if poolHeldSeats+booking.Seats > poolSeats {
+			return fmt.Errorf("%w in partner pool: requested %d, available %d",
+				entity.ErrNotEnoughSeats, booking.Seats, poolSeats-poolHeldSeats)
+		}
+	} else {
+		// Seats are held by confirmed bookings and by pending bookings that have not expired yet;
+		// partner pools and administrative holds are taken out of general sale entirely
+		var heldSeats, reservedSeats int
+		query = `
+			SELECT
+				COALESCE((SELECT SUM(seats) FROM bookings
+				          WHERE event_id = $1 AND pool_id IS NULL AND deleted_at IS NULL
+				            AND ` + holdsSeats("") + `), 0),
+				COALESCE((SELECT SUM(seats) FROM partner_pools WHERE event_id = $1), 0)
+				  + COALESCE((SELECT SUM(seats) FROM event_holds WHERE event_id = $1), 0)
+		`
+		err = tx.QueryRowContext(ctx, query, booking.EventID).Scan(&heldSeats, &reservedSeats)
+		if err != nil {
+			return fmt.Errorf("failed to check held seats: %v", err)
+		}
+
+		if heldSeats+reservedSeats+booking.Seats > totalSeats {
+			return fmt.Errorf("%w: requested %d, available %d",
+				entity.ErrNotEnoughSeats, booking.Seats, totalSeats-reservedSeats-heldSeats)
+		}
+	}
+
+	// Validate tier availability and fix the price at booking time
+	if booking.TierID != nil {
+		var tierSeats int
+		var tierPrice float64
+		query = `SELECT seats, price FROM ticket_tiers WHERE id = $1 AND event_id = $2`
+		err = tx.QueryRowContext(ctx, query, *booking.TierID, booking.EventID).Scan(&tierSeats, &tierPrice)
+		if err == sql.ErrNoRows {
+			return entity.ErrTicketTierNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get ticket tier: %v", err)
+		}
+
+		var tierHeldSeats int
+		query = `
+			SELECT COALESCE(SUM(seats), 0) FROM bookings
+			WHERE tier_id = $1 AND deleted_at IS NULL
+			  AND ` + holdsSeats("") + `
+		`
+		err = tx.QueryRowContext(ctx, query, *booking.TierID).Scan(&tierHeldSeats)
+		if err != nil {
+			return fmt.Errorf("failed to check tier held seats: %v", err)
+		}
+
+		if tierHeldSeats+booking.Seats > tierSeats {
+			return fmt.Errorf("%w in tier: requested %d, available %d",
+				entity.ErrNotEnoughSeats, booking.Seats, tierSeats-tierHeldSeats)
+		}
+
+		booking.TotalPrice = tierPrice * float64(booking.Seats)
+	}
+
+	// Redeem the promo code: the usage counter is incremented atomically so that
+	// concurrent bookings cannot exceed the limit
+	if booking.PromoCodeID != nil {
+		var promo entity.PromoCode
+		query = `
+			UPDATE promo_codes
+			SET used_count = used_count + 1, updated_at = NOW()
+			WHERE id = $1
+			  AND active
+			  AND (event_id IS NULL OR event_id = $2)
+			  AND (expires_at IS NULL OR expires_at > NOW())
+			  AND (max_uses = 0 OR used_count < max_uses)
+			RETURNING discount_type, discount_value
+		`
+		err = tx.QueryRowContext(ctx, query, *booking.PromoCodeID, booking.EventID).Scan(&promo.DiscountType, &promo.DiscountValue)
+		if err == sql.ErrNoRows {
+			return entity.ErrPromoCodeExhausted
+		}
+		if err != nil {
+			return fmt.Errorf("failed to redeem promo code: %v", err)
+		}
+
+		booking.DiscountAmount = promo.Discount(booking.TotalPrice)
+		booking.TotalPrice -= booking.DiscountAmount
+	}
+
+	// Create booking
+	query = `
+		INSERT INTO bookings (
+			event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, version
+	`
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(booking.ReservationTimeout) * time.Minute)
+
+	err = tx.QueryRowContext(ctx, query,
+		booking.EventID,
+		booking.UserID,
+		booking.Seats,
+		booking.Status,
+		expiresAt,
+		booking.ReservationTimeout,
+		booking.TierID,
+		booking.PoolID,
+		booking.TotalPrice,
+		booking.PromoCodeID,
+		booking.DiscountAmount,
+		now,
+		now,
+	).Scan(&booking.ID, &booking.Version)
+
+	if err != nil {
+		return fmt.Errorf("failed to create booking: %v", err)
+	}
+
+	booking.ExpiresAt = expiresAt
+	booking.CreatedAt = now
+	booking.UpdatedAt = now
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityBooking, booking.ID, entity.AuditActionCreated)
+	entry.NewStatus = string(booking.Status)
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if outbox != nil {
+		if err := insertOutbox(ctx, tx, outbox(booking)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByID retrieves a booking by its ID
+func (r *bookingRepository) GetByID(ctx context.Context, id int64) (*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, checked_in_at, version, created_at, updated_at
+		FROM bookings 
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	var booking entity.Booking
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&booking.ID,
+		&booking.EventID,
+		&booking.UserID,
+		&booking.Seats,
+		&booking.Status,
+		&booking.ExpiresAt,
+		&booking.ReservationTimeout,
+		&booking.TierID,
+		&booking.PoolID,
+		&booking.TotalPrice,
+		&booking.PromoCodeID,
+		&booking.DiscountAmount,
+		&booking.CheckedInAt,
+		&booking.Version,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %v", err)
+	}
+
+	return &booking, nil
+}
+
+// GetByEventAndUser retrieves a booking by event and user
+func (r *bookingRepository) GetByEventAndUser(ctx context.Context, eventID, userID int64) (*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE event_id = $1 AND user_id = $2 AND status IN ('pending', 'confirmed') AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var booking entity.Booking
+	err := r.db.QueryRowContext(ctx, query, eventID, userID).Scan(
+		&booking.ID,
+		&booking.EventID,
+		&booking.UserID,
+		&booking.Seats,
+		&booking.Status,
+		&booking.ExpiresAt,
+		&booking.ReservationTimeout,
+		&booking.TierID,
+		&booking.PoolID,
+		&booking.TotalPrice,
+		&booking.PromoCodeID,
+		&booking.DiscountAmount,
+		&booking.Version,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking by event and user: %v", err)
+	}
+
+	return &booking, nil
+}
+
+// UpdateStatus updates the status of a booking
+func (r *bookingRepository) UpdateStatus(ctx context.Context, id int64, status entity.BookingStatus) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := r.updateStatusTx(ctx, tx, id, status); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// CancelWithRefund cancels the booking and, in the same transaction, stores the refund built
+// by refund from the locked pre-cancellation state together with its outbox tasks.
+// refund returns nil when nothing was paid; the returned refund is nil in that case too.
+func (r *bookingRepository) CancelWithRefund(
+	ctx context.Context,
+	id int64,
+	refund func(locked *entity.Booking) *entity.Refund,
+	outbox func(*entity.Refund) []*entity.OutboxMessage,
+) (*entity.Refund, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	locked, err := r.updateStatusTx(ctx, tx, id, entity.BookingStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	result := refund(locked)
+	if result != nil {
+		if err := insertRefund(ctx, tx, result); err != nil {
+			return nil, err
+		}
+		if outbox != nil {
+			if err := insertOutbox(ctx, tx, outbox(result)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return result, nil
+}
+
+// updateStatusTx locks the booking, validates the transition and applies it inside tx.
+// It returns the booking as it was before the update.
+func (r *bookingRepository) updateStatusTx(ctx context.Context, tx *repoTx, id int64, status entity.BookingStatus) (*entity.Booking, error) {
+	// Lock the booking row so concurrent transitions are validated against the committed status
+	currentBooking := entity.Booking{ID: id}
+	query := `SELECT event_id, user_id, seats, status, tier_id, pool_id, promo_code_id, total_price, version FROM bookings WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&currentBooking.EventID,
+		&currentBooking.UserID,
+		&currentBooking.Seats,
+		&currentBooking.Status,
+		&currentBooking.TierID,
+		&currentBooking.PoolID,
+		&currentBooking.PromoCodeID,
+		&currentBooking.TotalPrice,
+		&currentBooking.Version,
+	)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current booking: %v", err)
+	}
+
+	// The client saw an older version: someone changed the booking since then
+	if expected, ok := entity.ExpectedVersionFromContext(ctx); ok && expected != currentBooking.Version {
+		return nil, entity.ErrConflict
+	}
+
+	// ErrStatusUnchanged and *entity.TransitionError are returned as is so callers can tell them apart
+	if err := entity.ValidateTransition(currentBooking.Status, status); err != nil {
+		return nil, err
+	}
+
+	// If changing from pending to confirmed, check seat availability
+	if currentBooking.Status == entity.BookingStatusPending && status == entity.BookingStatusConfirmed {
+		// Lock the event row first, the same way Create does, so concurrent confirmations cannot oversell
+		var totalSeats int
+		query = `SELECT total_seats FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, currentBooking.EventID).Scan(&totalSeats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock event: %v", err)
+		}
+
+		// The booking being confirmed already holds its seats while pending, so it is left out of the sums:
+		// the same availability condition as in Create, otherwise the two checks disagree
+		if currentBooking.PoolID != nil {
+			var poolHeldSeats, poolSeats int
+			query = `
+				SELECT p.seats, COALESCE(SUM(b.seats), 0)
+				FROM partner_pools p
+				LEFT JOIN bookings b ON b.pool_id = p.id AND b.id <> $2 AND b.deleted_at IS NULL AND ` + holdsSeats("b") + `
+				WHERE p.id = $1
+				GROUP BY p.id
+			`
+			err = tx.QueryRowContext(ctx, query, *currentBooking.PoolID, id).Scan(&poolSeats, &poolHeldSeats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check pool seats: %v", err)
+			}
+
+			if poolHeldSeats+currentBooking.Seats > poolSeats {
+				return nil, fmt.Errorf("%w in partner pool to confirm booking", entity.ErrNotEnoughSeats)
+			}
+		} else {
+			var heldSeats, reservedSeats int
+			query = `
+				SELECT
+					COALESCE((SELECT SUM(seats) FROM bookings
+					          WHERE event_id = $1 AND id <> $2 AND pool_id IS NULL AND deleted_at IS NULL
+					            AND ` + holdsSeats("") + `), 0),
+					COALESCE((SELECT SUM(seats) FROM partner_pools WHERE event_id = $1), 0)
+					  + COALESCE((SELECT SUM(seats) FROM event_holds WHERE event_id = $1), 0)
+			`
+			err = tx.QueryRowContext(ctx, query, currentBooking.EventID, id).Scan(&heldSeats, &reservedSeats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check held seats: %v", err)
+			}
+
+			if heldSeats+reservedSeats+currentBooking.Seats > totalSeats {
+				return nil, fmt.Errorf("%w to confirm booking", entity.ErrNotEnoughSeats)
+			}
+		}
+
+		if currentBooking.TierID != nil {
+			var tierHeldSeats, tierSeats int
+			query = `
+				SELECT t.seats, COALESCE(SUM(b.seats), 0)
+				FROM ticket_tiers t
+				LEFT JOIN bookings b ON b.tier_id = t.id AND b.id <> $2 AND b.deleted_at IS NULL AND ` + holdsSeats("b") + `
+				WHERE t.id = $1
+				GROUP BY t.id
+			`
+			err = tx.QueryRowContext(ctx, query, *currentBooking.TierID, id).Scan(&tierSeats, &tierHeldSeats)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check tier seats: %v", err)
+			}
+
+			if tierHeldSeats+currentBooking.Seats > tierSeats {
+				return nil, fmt.Errorf("%w in tier to confirm booking", entity.ErrNotEnoughSeats)
+			}
+		}
+	}
+
+	// Update the status; a confirmed booking gets its ticket code, kept if it is confirmed again
+	query = `
+		UPDATE bookings SET status = $1, updated_at = $2, version = version + 1,
+			ticket_code = CASE WHEN $4 THEN COALESCE(ticket_code, gen_random_uuid()::text) ELSE ticket_code END
+		WHERE id = $3
+	`
+	result, err := tx.ExecContext(ctx, query, status, time.Now(), id, status == entity.BookingStatusConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update booking status: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, entity.ErrBookingNotFound
+	}
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityBooking, id, entity.AuditActionStatusChanged)
+	entry.OldStatus = string(currentBooking.Status)
+	entry.NewStatus = string(status)
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	// A pending booking that never got confirmed gives its promo code usage back
+	if currentBooking.PromoCodeID != nil && currentBooking.Status == entity.BookingStatusPending &&
+		(status == entity.BookingStatusCancelled || status == entity.BookingStatusExpired) {
+		query = `UPDATE promo_codes SET used_count = GREATEST(used_count - 1, 0), updated_at = NOW() WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, *currentBooking.PromoCodeID); err != nil {
+			return nil, fmt.Errorf("failed to release promo code: %v", err)
+		}
+	}
+
+	return &currentBooking, nil
+}
+
+// GetByEventID retrieves all bookings for a specific event
+func (r *bookingRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE event_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings by event: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// GetByUserID retrieves all bookings for a specific user
+func (r *bookingRepository) GetByOrganizer(ctx context.Context, organizerID int64, eventID *int64) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			b.id, b.event_id, b.user_id, b.seats, b.status, b.expires_at, 
+			b.reservation_timeout, b.tier_id, b.pool_id, b.total_price, b.promo_code_id, b.discount_amount, b.version, b.created_at, b.updated_at
+		FROM bookings b
+		JOIN events e ON e.id = b.event_id
+		WHERE e.organizer_id = $1 AND ($2::INTEGER IS NULL OR b.event_id = $2) AND b.deleted_at IS NULL
+		ORDER BY b.created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, organizerID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings by organizer: %v", err)
+	}
+	defer rows.Close()
+
+	bookings := make([]*entity.Booking, 0)
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+func (r *bookingRepository) GetByUserID(ctx context.Context, userID int64) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings by user: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// GetByStatus retrieves all bookings with a specific status
+func (r *bookingRepository) GetByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE status = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings by status: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// GetByEventAndStatus retrieves bookings for a specific event and status
+func (r *bookingRepository) GetByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE event_id = $1 AND status = $2 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings by event and status: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// GetExpiredBookings retrieves expired bookings before a certain time
+func (r *bookingRepository) GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error) {
+	query := `
+		SELECT 
+			b.id, b.expires_at, b.user_id, b.event_id,
+			u.telegram_id, u.name as user_name,
+			e.title as event_title
+		FROM bookings b
+		JOIN users u ON b.user_id = u.id
+		JOIN events e ON b.event_id = e.id
+		WHERE b.status = 'pending' AND b.expires_at < $1 AND b.deleted_at IS NULL
+		ORDER BY b.expires_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired bookings: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.BookingExpiration
+	for rows.Next() {
+		var booking entity.BookingExpiration
+		err := rows.Scan(
+			&booking.BookingID,
+			&booking.ExpiresAt,
+			&booking.UserID,
+			&booking.EventID,
+			&booking.TelegramID,
+			&booking.UserName,
+			&booking.EventTitle,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expired booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expired bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// GetExpiringBookings retrieves bookings that will expire within a time range
+func (r *bookingRepository) GetExpiringBookings(ctx context.Context, from, to time.Time) ([]*entity.BookingExpiration, error) {
+	query := `
+		SELECT 
+			b.id, b.expires_at, b.user_id, b.event_id,
+			u.telegram_id, u.name as user_name,
+			e.title as event_title
+		FROM bookings b
+		JOIN users u ON b.user_id = u.id
+		JOIN events e ON b.event_id = e.id
+		WHERE b.status = 'pending' AND b.expires_at BETWEEN $1 AND $2 AND b.deleted_at IS NULL
+		ORDER BY b.expires_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expiring bookings: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.BookingExpiration
+	for rows.Next() {
+		var booking entity.BookingExpiration
+		err := rows.Scan(
+			&booking.BookingID,
+			&booking.ExpiresAt,
+			&booking.UserID,
+			&booking.EventID,
+			&booking.TelegramID,
+			&booking.UserName,
+			&booking.EventTitle,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expiring booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expiring bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// DeleteExpired soft-deletes expired pending bookings and returns how many were deleted.
+// Each deletion is written to the audit log by the same statement.
+func (r *bookingRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		WITH deleted AS (
+			UPDATE bookings SET deleted_at = $2
+			WHERE status = 'pending' AND expires_at < $1 AND deleted_at IS NULL
+			RETURNING id, status
+		)
+		INSERT INTO audit_log (entity_type, entity_id, action, old_status, new_status, actor_id, actor, reason, impersonator_id, created_at)
+		SELECT $3, id, $4, status, status, $5, $6, $7, $8, $2 FROM deleted
+	`
+	actor := entity.AuditActorFromContext(ctx)
+	result, err := r.db.ExecContext(ctx, query, before, time.Now(),
+		entity.AuditEntityBooking, entity.AuditActionDeleted, actor.ID, actor.Kind, entity.AuditReasonFromContext(ctx), actor.ImpersonatorID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired bookings: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// ExtendExpiration moves the expiration of a pending booking to expiresAt.
+// A booking can be extended only once; returns false if it is no longer pending or was already extended.
+func (r *bookingRepository) ExtendExpiration(ctx context.Context, id int64, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET expires_at = $2, extensions = extensions + 1, updated_at = $3, version = version + 1
+		WHERE id = $1 AND status = 'pending' AND extensions = 0 AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, expiresAt, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to extend booking expiration: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ClaimReminder records that the reminder was sent for the booking.
+// Returns false if it was already claimed, so the reminder must not be sent again.
+func (r *bookingRepository) ClaimReminder(ctx context.Context, bookingID int64, reminder string) (bool, error) {
+	query := `
+		INSERT INTO booking_reminders (booking_id, reminder)
+		VALUES ($1, $2)
+		ON CONFLICT (booking_id, reminder) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, bookingID, reminder)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim booking reminder: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReleaseReminder removes a claim whose reminder could not be sent, so a retry can send it
+func (r *bookingRepository) ReleaseReminder(ctx context.Context, bookingID int64, reminder string) error {
+	query := `DELETE FROM booking_reminders WHERE booking_id = $1 AND reminder = $2`
+	if _, err := r.db.ExecContext(ctx, query, bookingID, reminder); err != nil {
+		return fmt.Errorf("failed to release booking reminder: %v", err)
+	}
+	return nil
+}
+
+// RecordNotification marks the notification of the booking as handled; recording it again is a no-op
+func (r *bookingRepository) RecordNotification(ctx context.Context, bookingID int64, notification string) error {
+	query := `
+		INSERT INTO booking_notifications (booking_id, notification)
+		VALUES ($1, $2)
+		ON CONFLICT (booking_id, notification) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, bookingID, notification); err != nil {
+		return fmt.Errorf("failed to record booking notification: %v", err)
+	}
+	return nil
+}
+
+// GetMissingNotification returns bookings with the given status, last updated within [from, to),
+// for which the notification has not been recorded
+func (r *bookingRepository) GetMissingNotification(ctx context.Context, status entity.BookingStatus, notification string, from, to time.Time) ([]*entity.Booking, error) {
+	query := `
+		SELECT
+			b.id, b.event_id, b.user_id, b.seats, b.status, b.expires_at,
+			b.reservation_timeout, b.tier_id, b.pool_id, b.total_price, b.promo_code_id, b.discount_amount, b.version, b.created_at, b.updated_at
+		FROM bookings b
+		WHERE b.status = $1 AND b.deleted_at IS NULL
+			AND b.updated_at >= $3 AND b.updated_at < $4
+			AND NOT EXISTS (
+				SELECT 1 FROM booking_notifications n
+				WHERE n.booking_id = b.id AND n.notification = $2
+			)
+		ORDER BY b.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, notification, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings without notification: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return bookings, nil
+}
+
+// BulkUpdateStatus updates the status of multiple bookings in a single transaction
+func (r *bookingRepository) BulkUpdateStatus(ctx context.Context, ids []int64, status entity.BookingStatus) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Pending bookings that are cancelled or expired give their promo code usage back
+	if status == entity.BookingStatusCancelled || status == entity.BookingStatusExpired {
+		query := `
+			UPDATE promo_codes p
+			SET used_count = GREATEST(p.used_count - released.uses, 0), updated_at = NOW()
+			FROM (
+				SELECT promo_code_id, COUNT(*) AS uses
+				FROM bookings
+				WHERE id = ANY($1) AND status = 'pending' AND promo_code_id IS NOT NULL AND deleted_at IS NULL
+				GROUP BY promo_code_id
+			) released
+			WHERE p.id = released.promo_code_id
+		`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed to release promo codes: %v", err)
+		}
+	}
+
+	// Only bookings whose current status allows the transition are updated
+	var sources []string
+	for _, from := range entity.TransitionSources(status) {
+		sources = append(sources, string(from))
+	}
+
+	// The previous status of every row is returned for the audit log
+	query := `
+		WITH locked AS (
+			SELECT id, status FROM bookings
+			WHERE id = ANY($3) AND status = ANY($4) AND deleted_at IS NULL
+			FOR UPDATE
+		)
+		UPDATE bookings b SET status = $1, updated_at = $2, version = b.version + 1
+		FROM locked
+		WHERE b.id = locked.id
+		RETURNING b.id, locked.status
+	`
+
+	rows, err := tx.QueryContext(ctx, query, status, time.Now(), pq.Array(ids), pq.Array(sources))
+	if err != nil {
+		return fmt.Errorf("failed to bulk update booking status: %v", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.AuditEntry, 0, len(ids))
+	for rows.Next() {
+		var (
+			id  int64
+			old string
+		)
+		if err := rows.Scan(&id, &old); err != nil {
+			return fmt.Errorf("failed to scan updated booking: %v", err)
+		}
+		entry := entity.NewAuditEntry(ctx, entity.AuditEntityBooking, id, entity.AuditActionStatusChanged)
+		entry.OldStatus = old
+		entry.NewStatus = string(status)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating updated bookings: %v", err)
+	}
+
+	if len(entries) != len(ids) {
+		return fmt.Errorf("%w: expected to update %d rows, but updated %d", entity.ErrInvalidTransition, len(ids), len(entries))
+	}
+
+	if err := insertAudit(ctx, tx, entries...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// CountByEvent counts all bookings for a specific event
+func (r *bookingRepository) CountByEvent(ctx context.Context, eventID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND deleted_at IS NULL`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, eventID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count bookings by event: %v", err)
+	}
+	return count, nil
+}
+
+// CountByEventAndStatus counts bookings for a specific event and status
+func (r *bookingRepository) CountByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) (int, error) {
+	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND status = $2 AND deleted_at IS NULL`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, eventID, status).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count bookings by event and status: %v", err)
+	}
+	return count, nil
+}
+
+// GetEventBookingStats returns statistics for event bookings
+func (r *bookingRepository) GetEventBookingStats(ctx context.Context, eventID int64) (*entity.EventBookingStats, error) {
+	query := `
+		SELECT 
+			COUNT(*) as total_bookings,
+			COALESCE(SUM(CASE WHEN status = 'pending' THEN seats ELSE 0 END), 0) as pending_seats,
+			COALESCE(SUM(CASE WHEN status = 'confirmed' THEN seats ELSE 0 END), 0) as confirmed_seats,
+			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN seats ELSE 0 END), 0) as cancelled_seats,
+			COALESCE(SUM(CASE WHEN status = 'expired' THEN seats ELSE 0 END), 0) as expired_seats,
+			COALESCE(SUM(CASE WHEN status = 'confirmed' AND checked_in_at IS NOT NULL THEN seats ELSE 0 END), 0) as checked_in_seats,
+			COALESCE(SUM(CASE WHEN status = 'confirmed' AND checked_in_at IS NULL
+				AND (SELECT date FROM events WHERE id = $1) < NOW() THEN seats ELSE 0 END), 0) as no_show_seats,
+			COALESCE(SUM(CASE WHEN status = 'confirmed' THEN total_price ELSE 0 END), 0) as revenue,
+			COALESCE(SUM(CASE WHEN status = 'confirmed' THEN discount_amount ELSE 0 END), 0) as discounts
+		FROM bookings 
+		WHERE event_id = $1 AND deleted_at IS NULL
+	`
+
+	var stats entity.EventBookingStats
+	err := r.db.read(ctx).QueryRowContext(ctx, query, eventID).Scan(
+		&stats.TotalBookings,
+		&stats.PendingSeats,
+		&stats.ConfirmedSeats,
+		&stats.CancelledSeats,
+		&stats.ExpiredSeats,
+		&stats.CheckedInSeats,
+		&stats.NoShowSeats,
+		&stats.Revenue,
+		&stats.Discounts,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event booking stats: %v", err)
+	}
+
+	return &stats, nil
+}
+
+// LockBooking locks a booking for update (for concurrency control)
+func (r *bookingRepository) LockBooking(ctx context.Context, id int64) error {
+	query := `SELECT 1 FROM bookings WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	var dummy int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&dummy)
+	if err != nil {
+		return fmt.Errorf("failed to lock booking: %v", err)
+	}
+	return nil
+}
+
+// GetWithLock retrieves a booking with a lock for update
+func (r *bookingRepository) GetWithLock(ctx context.Context, id int64) (*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	var booking entity.Booking
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&booking.ID,
+		&booking.EventID,
+		&booking.UserID,
+		&booking.Seats,
+		&booking.Status,
+		&booking.ExpiresAt,
+		&booking.ReservationTimeout,
+		&booking.TierID,
+		&booking.PoolID,
+		&booking.TotalPrice,
+		&booking.PromoCodeID,
+		&booking.DiscountAmount,
+		&booking.Version,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking with lock: %v", err)
+	}
+
+	return &booking, nil
+}
+
+func (r *bookingRepository) Update(ctx context.Context, booking *entity.Booking) error {
+	query := `
+		UPDATE bookings 
+		SET event_id = $1, user_id = $2, seats = $3, status = $4, 
+		    expires_at = $5, reservation_timeout = $6, tier_id = $7, pool_id = $8, total_price = $9,
+		    promo_code_id = $10, discount_amount = $11, updated_at = $12, version = version + 1
+		WHERE id = $13 AND deleted_at IS NULL AND version = $14
+		RETURNING version
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		booking.EventID,
+		booking.UserID,
+		booking.Seats,
+		booking.Status,
+		booking.ExpiresAt,
+		booking.ReservationTimeout,
+		booking.TierID,
+		booking.PoolID,
+		booking.TotalPrice,
+		booking.PromoCodeID,
+		booking.DiscountAmount,
+		time.Now(),
+		booking.ID,
+		booking.Version,
+	).Scan(&booking.Version)
+
+	if err == sql.ErrNoRows {
+		return r.versionConflict(ctx, booking.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	booking.UpdatedAt = time.Now()
+	return nil
+}
+
+// versionConflict tells a stale version from a missing booking after a versioned update matched no rows
+func (r *bookingRepository) versionConflict(ctx context.Context, id int64) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM bookings WHERE id = $1 AND deleted_at IS NULL)`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check booking: %w", err)
+	}
+	if !exists {
+		return entity.ErrBookingNotFound
+	}
+	return entity.ErrConflict
+}
+
+// Delete soft-deletes the booking: the row stays for the audit log and reports but is hidden from queries
+func (r *bookingRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	query := `UPDATE bookings SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL RETURNING status`
+	err = tx.QueryRowContext(ctx, query, id, time.Now()).Scan(&status)
+	if err == sql.ErrNoRows {
+		return entity.ErrBookingNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete booking: %w", err)
+	}
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityBooking, id, entity.AuditActionDeleted)
+	entry.OldStatus = status
+	entry.NewStatus = status
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *bookingRepository) GetAll(ctx context.Context) ([]*entity.Booking, error) {
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %w", err)
+	}
+
+	return bookings, nil
+}
+
+func (r *bookingRepository) GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `
+		SELECT 
+			id, event_id, user_id, seats, status, expires_at, 
+			reservation_timeout, tier_id, pool_id, total_price, promo_code_id, discount_amount, version, created_at, updated_at
+		FROM bookings 
+		WHERE deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []*entity.Booking
+	for rows.Next() {
+		var booking entity.Booking
+		err := rows.Scan(
+			&booking.ID,
+			&booking.EventID,
+			&booking.UserID,
+			&booking.Seats,
+			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.ReservationTimeout,
+			&booking.TierID,
+			&booking.PoolID,
+			&booking.TotalPrice,
+			&booking.PromoCodeID,
+			&booking.DiscountAmount,
+			&booking.Version,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, &booking)
+	}
+
+	return bookings, nil
+}
+
+func (r *bookingRepository) GetExportBatch(ctx context.Context, eventID *int64, afterID int64, limit int) ([]*entity.BookingExportRow, error) {
+	query := `
+		SELECT
+			b.id, b.status, b.seats, COALESCE(t.name, ''), COALESCE(p.code, ''),
+			b.total_price, b.discount_amount, b.expires_at, b.created_at,
+			e.id, e.title, e.date,
+			u.id, u.name, u.email
+		FROM bookings b
+		JOIN events e ON e.id = b.event_id
+		JOIN users u ON u.id = b.user_id
+		LEFT JOIN ticket_tiers t ON t.id = b.tier_id
+		LEFT JOIN promo_codes p ON p.id = b.promo_code_id
+		WHERE b.id > $1 AND ($2::bigint IS NULL OR b.event_id = $2) AND b.deleted_at IS NULL
+		ORDER BY b.id
+		LIMIT $3
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, afterID, eventID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings for export: %w", err)
+	}
+	defer rows.Close()
+
+	batch := make([]*entity.BookingExportRow, 0, limit)
+	for rows.Next() {
+		var row entity.BookingExportRow
+		err := rows.Scan(
+			&row.BookingID,
+			&row.Status,
+			&row.Seats,
+			&row.TierName,
+			&row.PromoCode,
+			&row.TotalPrice,
+			&row.DiscountAmount,
+			&row.ExpiresAt,
+			&row.CreatedAt,
+			&row.EventID,
+			&row.EventTitle,
+			&row.EventDate,
+			&row.UserID,
+			&row.UserName,
+			&row.UserEmail,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking export row: %w", err)
+		}
+		batch = append(batch, &row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating booking export rows: %w", err)
+	}
+
+	return batch, nil
+}
+
+// GetCalendarEntries returns the user's confirmed bookings joined with their events, ordered by event date
+func (r *bookingRepository) GetCalendarEntries(ctx context.Context, userID int64) ([]*entity.CalendarEntry, error) {
+	query := `
+		SELECT
+			b.id, b.seats, GREATEST(b.updated_at, e.updated_at),
+			e.id, e.title, COALESCE(e.description, ''), e.location, e.date
+		FROM bookings b
+		JOIN events e ON e.id = b.event_id
+		WHERE b.user_id = $1 AND b.status = 'confirmed' AND b.deleted_at IS NULL
+		ORDER BY e.date
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*entity.CalendarEntry
+	for rows.Next() {
+		var entry entity.CalendarEntry
+		err := rows.Scan(
+			&entry.BookingID,
+			&entry.Seats,
+			&entry.UpdatedAt,
+			&entry.EventID,
+			&entry.EventTitle,
+			&entry.EventDescription,
+			&entry.EventLocation,
+			&entry.EventDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetUserEventBookings returns the user's bookings grouped by event with attendance:
+// a confirmed booking without a check-in counts as a no-show once the event has started
+func (r *bookingRepository) GetUserEventBookings(ctx context.Context, userID int64) ([]*entity.UserEventBookings, error) {
+	query := `
+		SELECT
+			e.id, e.title, e.date,
+			COUNT(*) as bookings,
+			COALESCE(SUM(CASE WHEN b.status = 'confirmed' THEN b.seats ELSE 0 END), 0) as seats,
+			COUNT(*) FILTER (WHERE b.status = 'confirmed' AND b.checked_in_at IS NOT NULL) as attended,
+			COUNT(*) FILTER (WHERE b.status = 'confirmed' AND b.checked_in_at IS NULL AND e.date < NOW()) as no_show
+		FROM bookings b
+		JOIN events e ON e.id = b.event_id
+		WHERE b.user_id = $1 AND b.deleted_at IS NULL
+		GROUP BY e.id, e.title, e.date
+		ORDER BY bookings DESC, seats DESC, e.date DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user event bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.UserEventBookings
+	for rows.Next() {
+		var event entity.UserEventBookings
+		err := rows.Scan(
+			&event.EventID,
+			&event.EventTitle,
+			&event.EventDate,
+			&event.Bookings,
+			&event.Seats,
+			&event.AttendedBookings,
+			&event.NoShowBookings,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user event bookings: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user event bookings: %w", err)
+	}
+
+	return events, nil
+}
+
+// IssueTicket returns the ticket code of a confirmed booking, generating it for bookings
+// confirmed before tickets existed
+func (r *bookingRepository) IssueTicket(ctx context.Context, id int64) (string, error) {
+	query := `
+		UPDATE bookings SET ticket_code = COALESCE(ticket_code, gen_random_uuid()::text)
+		WHERE id = $1 AND status = 'confirmed' AND deleted_at IS NULL
+		RETURNING ticket_code
+	`
+
+	var code string
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&code)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return "", err
+		}
+		return "", entity.ErrTicketUnavailable
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to issue ticket: %v", err)
+	}
+
+	return code, nil
+}
+
+// CheckIn marks attendance for the booking if code matches its ticket. The row is locked,
+// so the same ticket scanned at two entrances at once is accepted only once.
+func (r *bookingRepository) CheckIn(ctx context.Context, id int64, code string, at time.Time) (*entity.Booking, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		ticketCode sql.NullString
+		booking    entity.Booking
+	)
+	query := `
+		SELECT id, event_id, user_id, seats, status, ticket_code, checked_in_at, version, created_at, updated_at
+		FROM bookings
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, query, id).Scan(
+		&booking.ID,
+		&booking.EventID,
+		&booking.UserID,
+		&booking.Seats,
+		&booking.Status,
+		&ticketCode,
+		&booking.CheckedInAt,
+		&booking.Version,
+		&booking.CreatedAt,
+		&booking.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrInvalidTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking: %v", err)
+	}
+
+	// A cancelled booking keeps its code, so the status is checked as well
+	if !ticketCode.Valid || ticketCode.String != code || booking.Status != entity.BookingStatusConfirmed {
+		return &booking, entity.ErrInvalidTicket
+	}
+	if booking.CheckedInAt != nil {
+		return &booking, entity.ErrAlreadyCheckedIn
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE bookings SET checked_in_at = $2, updated_at = $2, version = version + 1 WHERE id = $1`, id, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check in booking: %v", err)
+	}
+	booking.CheckedInAt = &at
+	booking.UpdatedAt = at
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityBooking, id, entity.AuditActionCheckedIn)
+	entry.OldStatus = string(booking.Status)
+	entry.NewStatus = string(booking.Status)
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &booking, nil
+}
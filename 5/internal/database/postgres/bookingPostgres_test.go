@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/postgres"
+)
+
+// openTestDB подключается к PostgreSQL из TEST_POSTGRES_DSN и применяет миграции.
+// Без переменной окружения тест пропускается.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := postgres.RunMigrations(db); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	return db
+}
+
+// TestBookingCreateLastSeat проверяет, что при одновременных попытках забронировать
+// последнее место бронирование получает ровно один пользователь
+func TestBookingCreateLastSeat(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	const attempts = 20
+	suffix := time.Now().UnixNano()
+
+	var eventID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 1) RETURNING id`,
+		fmt.Sprintf("last seat %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	userIDs := make([]int64, attempts)
+	for i := range userIDs {
+		err := db.QueryRowContext(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+			fmt.Sprintf("last-seat-%d-%d@example.com", suffix, i), "Tester",
+		).Scan(&userIDs[i])
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		for _, id := range userIDs {
+			db.Exec(`DELETE FROM users WHERE id = $1`, id)
+		}
+	})
+
+	repo := NewBookingRepository(db, nil)
+
+	start := make(chan struct{})
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for _, userID := range userIDs {
+		wg.Add(1)
+		go func(userID int64) {
+			defer wg.Done()
+			<-start
+			errs <- repo.Create(ctx, &entity.Booking{
+				EventID:            eventID,
+				UserID:             userID,
+				Seats:              1,
+				Status:             entity.BookingStatusPending,
+				ReservationTimeout: 30,
+			})
+		}(userID)
+	}
+
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, entity.ErrNotEnoughSeats):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one successful booking, got %d", succeeded)
+	}
+
+	var heldSeats int
+	err = db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(seats), 0) FROM bookings WHERE event_id = $1 AND status IN ('pending', 'confirmed')`,
+		eventID,
+	).Scan(&heldSeats)
+	if err != nil {
+		t.Fatalf("failed to count held seats: %v", err)
+	}
+	if heldSeats != 1 {
+		t.Fatalf("expected 1 held seat, got %d", heldSeats)
+	}
+}
+
+// TestBookingUpdateVersionConflict проверяет, что правка по устаревшей версии
+// бронирования не затирает чужое изменение, а завершается ErrConflict
+func TestBookingUpdateVersionConflict(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var eventID, userID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 10) RETURNING id`,
+		fmt.Sprintf("version conflict %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+		fmt.Sprintf("version-conflict-%d@example.com", suffix), "Tester",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	repo := NewBookingRepository(db, nil)
+	booking := &entity.Booking{
+		EventID:            eventID,
+		UserID:             userID,
+		Seats:              1,
+		Status:             entity.BookingStatusPending,
+		ReservationTimeout: 30,
+	}
+	if err := repo.Create(ctx, booking); err != nil {
+		t.Fatalf("failed to create booking: %v", err)
+	}
+
+	first, err := repo.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatalf("failed to get booking: %v", err)
+	}
+	second, err := repo.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatalf("failed to get booking: %v", err)
+	}
+
+	first.Seats = 2
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("first update failed: %v", err)
+	}
+	if first.Version != second.Version+1 {
+		t.Fatalf("expected version %d after update, got %d", second.Version+1, first.Version)
+	}
+
+	second.Seats = 3
+	if err := repo.Update(ctx, second); !errors.Is(err, entity.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale version, got %v", err)
+	}
+
+	stale := entity.WithExpectedVersion(ctx, second.Version)
+	if err := repo.UpdateStatus(stale, booking.ID, entity.BookingStatusConfirmed); !errors.Is(err, entity.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale status change, got %v", err)
+	}
+}
+
+// TestBookingConfirmCountsPendingHolds проверяет, что подтверждение считает занятые места так же,
+// как создание: неистекшие ожидающие бронирования держат места, и просроченное бронирование
+// не подтверждается поверх них
+func TestBookingConfirmCountsPendingHolds(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var eventID, userID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 2) RETURNING id`,
+		fmt.Sprintf("pending holds %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+		fmt.Sprintf("pending-holds-%d@example.com", suffix), "Tester",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	// Резерв первого бронирования истек, но оно еще не снято фоновой задачей
+	var expiredID int64
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO bookings (event_id, user_id, seats, status, expires_at, reservation_timeout, total_price)
+		VALUES ($1, $2, 2, 'pending', $3, 30, 0) RETURNING id`,
+		eventID, userID, time.Now().Add(-time.Minute),
+	).Scan(&expiredID)
+	if err != nil {
+		t.Fatalf("failed to create expired booking: %v", err)
+	}
+
+	repo := NewBookingRepository(db, nil)
+	active := &entity.Booking{
+		EventID:            eventID,
+		UserID:             userID,
+		Seats:              2,
+		Status:             entity.BookingStatusPending,
+		ReservationTimeout: 30,
+	}
+	if err := repo.Create(ctx, active); err != nil {
+		t.Fatalf("seats of the expired booking should be free for Create: %v", err)
+	}
+
+	if err := repo.UpdateStatus(ctx, expiredID, entity.BookingStatusConfirmed); !errors.Is(err, entity.ErrNotEnoughSeats) {
+		t.Fatalf("confirming over a pending hold = %v, want %v", err, entity.ErrNotEnoughSeats)
+	}
+	if err := repo.UpdateStatus(ctx, active.ID, entity.BookingStatusConfirmed); err != nil {
+		t.Fatalf("pending booking must be confirmable within its own hold: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type capacityRepository struct {
+	db       *conn
+	bookings *bookingRepository
+}
+
+func NewCapacityRepository(db *sql.DB) CapacityRepository {
+	c := newConn(db)
+	return &capacityRepository{db: c, bookings: &bookingRepository{db: c}}
+}
+
+func (r *capacityRepository) GetDemand(ctx context.Context, eventID int64) (*entity.CapacityDemand, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	return loadCapacityDemand(ctx, tx, eventID, false)
+}
+
+// Apply locks the event and its active bookings, lets plan pick the affected ones against
+// the locked state and then, in one transaction, cancels them, records full refunds, puts
+// waitlisted owners on the waitlist, writes the outbox messages and sets the new capacity.
+// version, if set, is the event version the organizer saw: a mismatch returns ErrConflict.
+func (r *capacityRepository) Apply(
+	ctx context.Context,
+	eventID int64,
+	version *int,
+	plan func(*entity.CapacityDemand) (*entity.CapacityPlan, error),
+	outbox func(*entity.AffectedBooking, *entity.Refund) []*entity.OutboxMessage,
+) (*entity.CapacityPlan, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	demand, err := loadCapacityDemand(ctx, tx, eventID, true)
+	if err != nil {
+		return nil, err
+	}
+	if version != nil && *version != demand.Version {
+		return nil, entity.ErrConflict
+	}
+
+	result, err := plan(demand)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reason := entity.AuditReasonFromContext(ctx)
+	for i := range result.Affected {
+		affected := &result.Affected[i]
+
+		locked, err := r.bookings.updateStatusTx(ctx, tx, affected.BookingID, entity.BookingStatusCancelled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cancel booking %d: %w", affected.BookingID, err)
+		}
+
+		var refund *entity.Refund
+		if locked.Status == entity.BookingStatusConfirmed && locked.TotalPrice > 0 {
+			refund = &entity.Refund{
+				BookingID:  locked.ID,
+				EventID:    locked.EventID,
+				UserID:     locked.UserID,
+				PaidAmount: locked.TotalPrice,
+				Percent:    100,
+				Amount:     locked.TotalPrice,
+				Status:     entity.RefundStatusPending,
+				Reason:     reason,
+			}
+			if err := insertRefund(ctx, tx, refund); err != nil {
+				return nil, err
+			}
+		}
+
+		if affected.Resolution == entity.CapacityResolutionWaitlisted {
+			query := `INSERT INTO event_waitlist (event_id, user_id, booking_id, seats, created_at) VALUES ($1, $2, $3, $4, $5)`
+			if _, err := tx.ExecContext(ctx, query, eventID, locked.UserID, locked.ID, locked.Seats, now); err != nil {
+				return nil, fmt.Errorf("failed to add booking %d to waitlist: %v", locked.ID, err)
+			}
+		}
+
+		if outbox != nil {
+			if err := insertOutbox(ctx, tx, outbox(affected, refund)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	query := `UPDATE events SET total_seats = $1, updated_at = $2, version = version + 1 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, result.TotalSeats, now, eventID); err != nil {
+		return nil, fmt.Errorf("failed to update event capacity: %v", err)
+	}
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityEvent, eventID, entity.AuditActionCapacityChanged)
+	entry.OldStatus = fmt.Sprintf("%d seats", demand.TotalSeats)
+	entry.NewStatus = fmt.Sprintf("%d seats", result.TotalSeats)
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+func (r *capacityRepository) GetWaitlist(ctx context.Context, eventID int64) ([]*entity.WaitlistEntry, error) {
+	query := `
+		SELECT id, event_id, user_id, booking_id, seats, created_at
+		FROM event_waitlist
+		WHERE event_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query waitlist: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*entity.WaitlistEntry, 0)
+	for rows.Next() {
+		var entry entity.WaitlistEntry
+		if err := rows.Scan(&entry.ID, &entry.EventID, &entry.UserID, &entry.BookingID, &entry.Seats, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		entry.Position = len(entries) + 1
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating waitlist: %w", err)
+	}
+
+	return entries, nil
+}
+
+// loadCapacityDemand reads what takes the event seats. With lock the event row is locked
+// the same way bookingRepository does, so no booking can be created or confirmed meanwhile.
+func loadCapacityDemand(ctx context.Context, tx *repoTx, eventID int64, lock bool) (*entity.CapacityDemand, error) {
+	demand := &entity.CapacityDemand{EventID: eventID}
+
+	query := `SELECT total_seats, version FROM events WHERE id = $1 AND deleted_at IS NULL`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	err := tx.QueryRowContext(ctx, query, eventID).Scan(&demand.TotalSeats, &demand.Version)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %v", err)
+	}
+
+	query = `
+		SELECT COALESCE((SELECT SUM(seats) FROM partner_pools WHERE event_id = $1), 0)
+		     + COALESCE((SELECT SUM(seats) FROM event_holds WHERE event_id = $1), 0)
+	`
+	if err := tx.QueryRowContext(ctx, query, eventID).Scan(&demand.ReservedSeats); err != nil {
+		return nil, fmt.Errorf("failed to get reserved seats: %v", err)
+	}
+
+	query = `
+		SELECT id, user_id, seats, status, total_price, created_at
+		FROM bookings
+		WHERE event_id = $1 AND pool_id IS NULL AND deleted_at IS NULL
+		  AND ` + holdsSeats("") + `
+		ORDER BY created_at, id
+	`
+	rows, err := tx.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active bookings: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		booking := &entity.Booking{EventID: eventID}
+		if err := rows.Scan(&booking.ID, &booking.UserID, &booking.Seats, &booking.Status, &booking.TotalPrice, &booking.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		demand.Bookings = append(demand.Bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bookings: %v", err)
+	}
+
+	return demand, nil
+}
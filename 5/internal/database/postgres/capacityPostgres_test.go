@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// TestCapacityApplyWaitlist проверяет, что уменьшение вместимости с политикой waitlist отменяет
+// самое позднее бронирование, возвращает оплату полностью и ставит владельца в лист ожидания
+func TestCapacityApplyWaitlist(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var eventID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 6) RETURNING id`,
+		fmt.Sprintf("capacity %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	userIDs := make([]int64, 3)
+	bookingIDs := make([]int64, 3)
+	for i := range userIDs {
+		err := db.QueryRowContext(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+			fmt.Sprintf("capacity-%d-%d@example.com", suffix, i), "Tester",
+		).Scan(&userIDs[i])
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+
+		err = db.QueryRowContext(ctx, `
+			INSERT INTO bookings (event_id, user_id, seats, status, expires_at, reservation_timeout, total_price, created_at)
+			VALUES ($1, $2, 2, 'confirmed', $3, 30, 100, $4) RETURNING id`,
+			eventID, userIDs[i], time.Now().Add(time.Hour), time.Now().Add(time.Duration(i)*time.Second),
+		).Scan(&bookingIDs[i])
+		if err != nil {
+			t.Fatalf("failed to create booking: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM event_waitlist WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM refunds WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM audit_log WHERE (entity_type = 'event' AND entity_id = $1) OR (entity_type = 'booking' AND entity_id = ANY(SELECT id FROM bookings WHERE event_id = $1))`, eventID)
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		for _, id := range userIDs {
+			db.Exec(`DELETE FROM users WHERE id = $1`, id)
+		}
+	})
+
+	repo := NewCapacityRepository(db)
+	plan, err := repo.Apply(ctx, eventID, nil,
+		func(demand *entity.CapacityDemand) (*entity.CapacityPlan, error) {
+			return entity.PlanCapacityChange(demand, 5, entity.CapacityWaitlist)
+		}, nil)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if len(plan.Affected) != 1 || plan.Affected[0].BookingID != bookingIDs[2] {
+		t.Fatalf("affected = %+v, want only the latest booking %d", plan.Affected, bookingIDs[2])
+	}
+
+	var status string
+	db.QueryRowContext(ctx, `SELECT status FROM bookings WHERE id = $1`, bookingIDs[2]).Scan(&status)
+	if status != string(entity.BookingStatusCancelled) {
+		t.Fatalf("latest booking status = %s, want cancelled", status)
+	}
+
+	var refund float64
+	db.QueryRowContext(ctx, `SELECT amount FROM refunds WHERE booking_id = $1`, bookingIDs[2]).Scan(&refund)
+	if refund != 100 {
+		t.Fatalf("refund = %.2f, want full 100", refund)
+	}
+
+	var totalSeats int
+	db.QueryRowContext(ctx, `SELECT total_seats FROM events WHERE id = $1`, eventID).Scan(&totalSeats)
+	if totalSeats != 5 {
+		t.Fatalf("total_seats = %d, want 5", totalSeats)
+	}
+
+	waitlist, err := repo.GetWaitlist(ctx, eventID)
+	if err != nil {
+		t.Fatalf("GetWaitlist failed: %v", err)
+	}
+	if len(waitlist) != 1 || waitlist[0].UserID != userIDs[2] || waitlist[0].Position != 1 {
+		t.Fatalf("waitlist = %+v, want the owner of the latest booking", waitlist)
+	}
+}
+
+func TestCapacityApplyVersionConflict(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	var eventID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 10) RETURNING id`,
+		fmt.Sprintf("capacity conflict %d", time.Now().UnixNano()), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM events WHERE id = $1`, eventID) })
+
+	stale := 0
+	_, err = NewCapacityRepository(db).Apply(ctx, eventID, &stale,
+		func(demand *entity.CapacityDemand) (*entity.CapacityPlan, error) {
+			return entity.PlanCapacityChange(demand, 5, entity.CapacityRefundLatest)
+		}, nil)
+	if err != entity.ErrConflict {
+		t.Fatalf("Apply with a stale version = %v, want ErrConflict", err)
+	}
+}
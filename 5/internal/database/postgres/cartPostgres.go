@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type cartRepository struct {
+	db *conn
+}
+
+func NewCartRepository(db *sql.DB) CartRepository {
+	return &cartRepository{db: newConn(db)}
+}
+
+func (r *cartRepository) GetOpenByUser(ctx context.Context, userID int64, now time.Time) (*entity.Cart, error) {
+	query := `
+		SELECT id, user_id, status, expires_at, created_at, updated_at
+		FROM carts
+		WHERE user_id = $1 AND status = 'open' AND expires_at > $2
+	`
+
+	var cart entity.Cart
+	err := r.db.QueryRowContext(ctx, query, userID, now).Scan(
+		&cart.ID,
+		&cart.UserID,
+		&cart.Status,
+		&cart.ExpiresAt,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	items, err := r.getItems(ctx, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	cart.Items = items
+
+	return &cart, nil
+}
+
+// GetOrCreateOpen returns the user's open cart, creating one if needed. An open cart
+// that is already past its expiry is closed first, so it never gets new items.
+func (r *cartRepository) GetOrCreateOpen(ctx context.Context, userID int64, expiresAt time.Time) (*entity.Cart, error) {
+	now := time.Now()
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE carts SET status = 'expired', updated_at = $2
+		WHERE user_id = $1 AND status = 'open' AND expires_at <= $2
+	`, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire stale cart: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO carts (user_id, status, expires_at, created_at, updated_at)
+		VALUES ($1, 'open', $2, $3, $3)
+		ON CONFLICT (user_id) WHERE status = 'open' DO NOTHING
+	`, userID, expiresAt, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cart: %w", err)
+	}
+
+	return r.GetOpenByUser(ctx, userID, now)
+}
+
+// AddItem adds an item and extends the cart's expiry, since the user is still active
+func (r *cartRepository) AddItem(ctx context.Context, item *entity.CartItem, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO cart_items (cart_id, event_id, seats, tier_id, promo_code, pool_code, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, query,
+		item.CartID,
+		item.EventID,
+		item.Seats,
+		item.TierID,
+		item.PromoCode,
+		item.PoolCode,
+		now,
+	).Scan(&item.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrCartItemExists
+		}
+		return fmt.Errorf("failed to add cart item: %w", err)
+	}
+	item.CreatedAt = now
+
+	if err := touchCart(ctx, tx, item.CartID, expiresAt, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (r *cartRepository) RemoveItem(ctx context.Context, cartID, itemID int64, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE id = $1 AND cart_id = $2`, itemID, cartID)
+	if err != nil {
+		return fmt.Errorf("failed to remove cart item: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrCartItemNotFound
+	}
+
+	if err := touchCart(ctx, tx, cartID, expiresAt, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (r *cartRepository) ClearItems(ctx context.Context, cartID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = $1`, cartID)
+	if err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+
+	return nil
+}
+
+// MarkCheckedOut closes an open cart; false means the cart was already closed
+func (r *cartRepository) MarkCheckedOut(ctx context.Context, cartID int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE carts SET status = 'checked_out', updated_at = $2
+		WHERE id = $1 AND status = 'open'
+	`, cartID, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to check out cart: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ExpireAbandoned closes open carts whose expiry has passed
+func (r *cartRepository) ExpireAbandoned(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE carts SET status = 'expired', updated_at = $1
+		WHERE status = 'open' AND expires_at <= $1
+	`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire carts: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *cartRepository) getItems(ctx context.Context, cartID int64) ([]*entity.CartItem, error) {
+	query := `
+		SELECT id, cart_id, event_id, seats, tier_id, promo_code, pool_code, created_at
+		FROM cart_items
+		WHERE cart_id = $1
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*entity.CartItem, 0)
+	for rows.Next() {
+		var item entity.CartItem
+		var tierID sql.NullInt64
+		err := rows.Scan(
+			&item.ID,
+			&item.CartID,
+			&item.EventID,
+			&item.Seats,
+			&tierID,
+			&item.PromoCode,
+			&item.PoolCode,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		if tierID.Valid {
+			item.TierID = &tierID.Int64
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cart items: %w", err)
+	}
+
+	return items, nil
+}
+
+// touchCart extends an open cart's expiry after the user changed it
+func touchCart(ctx context.Context, tx *repoTx, cartID int64, expiresAt, now time.Time) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE carts SET expires_at = $2, updated_at = $3
+		WHERE id = $1 AND status = 'open'
+	`, cartID, expiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrCartNotFound
+	}
+
+	return nil
+}
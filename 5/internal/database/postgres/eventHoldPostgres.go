@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type eventHoldRepository struct {
+	db *conn
+}
+
+func NewEventHoldRepository(db *sql.DB) EventHoldRepository {
+	return &eventHoldRepository{db: newConn(db)}
+}
+
+func (r *eventHoldRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.EventHold, error) {
+	query := `
+		SELECT id, event_id, label, seats, reason, created_at
+		FROM event_holds
+		WHERE event_id = $1
+		ORDER BY label ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make([]*entity.EventHold, 0)
+	for rows.Next() {
+		var hold entity.EventHold
+		err := rows.Scan(
+			&hold.ID,
+			&hold.EventID,
+			&hold.Label,
+			&hold.Seats,
+			&hold.Reason,
+			&hold.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event hold: %w", err)
+		}
+		holds = append(holds, &hold)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating event holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+func (r *eventHoldRepository) ReplaceForEvent(ctx context.Context, eventID int64, holds []*entity.EventHold) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the event row the same way bookingRepository does, so new holds and
+	// concurrent bookings cannot both take the last free seats
+	var totalSeats int
+	query := `SELECT total_seats FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, eventID).Scan(&totalSeats)
+	if err == sql.ErrNoRows {
+		return entity.ErrEventNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock event: %v", err)
+	}
+
+	// Current holds are being replaced, so only bookings and partner pools count here
+	var heldSeats, reservedSeats int
+	query = `
+		SELECT
+			COALESCE((SELECT SUM(seats) FROM bookings
+			          WHERE event_id = $1 AND pool_id IS NULL AND deleted_at IS NULL
+			            AND ` + holdsSeats("") + `), 0),
+			COALESCE((SELECT SUM(seats) FROM partner_pools WHERE event_id = $1), 0)
+	`
+	err = tx.QueryRowContext(ctx, query, eventID).Scan(&heldSeats, &reservedSeats)
+	if err != nil {
+		return fmt.Errorf("failed to check held seats: %v", err)
+	}
+
+	requested := 0
+	for _, hold := range holds {
+		requested += hold.Seats
+	}
+	if available := totalSeats - heldSeats - reservedSeats; requested > available {
+		return fmt.Errorf("%w: requested %d, available %d",
+			entity.ErrHoldSeatsExceedEvent, requested, available)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_holds WHERE event_id = $1`, eventID); err != nil {
+		return fmt.Errorf("failed to delete event holds: %v", err)
+	}
+
+	now := time.Now()
+	query = `
+		INSERT INTO event_holds (event_id, label, seats, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	for _, hold := range holds {
+		err := tx.QueryRowContext(ctx, query, eventID, hold.Label, hold.Seats, hold.Reason, now).Scan(&hold.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return entity.ErrDuplicateHoldLabel
+			}
+			return fmt.Errorf("failed to create event hold: %v", err)
+		}
+		hold.EventID = eventID
+		hold.CreatedAt = now
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
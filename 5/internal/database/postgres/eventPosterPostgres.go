@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type eventPosterRepository struct {
+	db *conn
+}
+
+func NewEventPosterRepository(db *sql.DB) EventPosterRepository {
+	return &eventPosterRepository{db: newConn(db)}
+}
+
+const posterColumns = `event_id, image_id, status, formats, error, updated_at`
+
+// Save записывает новую афишу мероприятия вместо прежней
+func (r *eventPosterRepository) Save(ctx context.Context, poster *entity.EventPoster) error {
+	formats, err := json.Marshal(poster.Formats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal poster formats: %w", err)
+	}
+
+	query := `
+		INSERT INTO event_posters (event_id, image_id, status, formats, error, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (event_id) DO UPDATE
+		SET image_id = EXCLUDED.image_id, status = EXCLUDED.status, formats = EXCLUDED.formats,
+		    error = EXCLUDED.error, updated_at = EXCLUDED.updated_at
+	`
+
+	poster.UpdatedAt = time.Now()
+	_, err = r.db.ExecContext(ctx, query, poster.EventID, poster.ImageID, poster.Status, formats, poster.Error, poster.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save event poster: %w", err)
+	}
+	return nil
+}
+
+// UpdateResult записывает итог обработки, только если у мероприятия всё ещё афиша poster.ImageID:
+// пока шла обработка, организатор мог загрузить новую. Возвращает false, если афиша сменилась
+func (r *eventPosterRepository) UpdateResult(ctx context.Context, poster *entity.EventPoster) (bool, error) {
+	formats, err := json.Marshal(poster.Formats)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal poster formats: %w", err)
+	}
+
+	query := `
+		UPDATE event_posters
+		SET status = $1, formats = $2, error = $3, updated_at = $4
+		WHERE event_id = $5 AND image_id = $6
+	`
+
+	poster.UpdatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, query, poster.Status, formats, poster.Error, poster.UpdatedAt, poster.EventID, poster.ImageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update event poster: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *eventPosterRepository) GetByEventID(ctx context.Context, eventID int64) (*entity.EventPoster, error) {
+	query := `SELECT ` + posterColumns + ` FROM event_posters WHERE event_id = $1`
+
+	poster, err := scanPoster(r.db.QueryRowContext(ctx, query, eventID))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrPosterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event poster: %w", err)
+	}
+	return poster, nil
+}
+
+// GetByEventIDs загружает афиши списка мероприятий одним запросом
+func (r *eventPosterRepository) GetByEventIDs(ctx context.Context, eventIDs []int64) (map[int64]*entity.EventPoster, error) {
+	posters := make(map[int64]*entity.EventPoster, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return posters, nil
+	}
+
+	query := `SELECT ` + posterColumns + ` FROM event_posters WHERE event_id = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(eventIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event posters: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		poster, err := scanPoster(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event poster: %w", err)
+		}
+		posters[poster.EventID] = poster
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating event posters: %w", err)
+	}
+	return posters, nil
+}
+
+type posterScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPoster(row posterScanner) (*entity.EventPoster, error) {
+	var poster entity.EventPoster
+	var formats []byte
+	if err := row.Scan(&poster.EventID, &poster.ImageID, &poster.Status, &formats, &poster.Error, &poster.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(formats, &poster.Formats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal poster formats: %w", err)
+	}
+	return &poster, nil
+}
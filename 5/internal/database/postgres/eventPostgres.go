@@ -1,297 +1,697 @@
-package repository
-
-import (
-	"context"
-	"database/sql"
-	"fmt"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-type eventRepository struct {
-	db *sql.DB
-}
-
-func NewEventRepository(db *sql.DB) EventRepository {
-	return &eventRepository{db: db}
-}
-
-func (r *eventRepository) Create(ctx context.Context, event *entity.Event) error {
-	query := `
-		INSERT INTO events (title, description, date, total_seats, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
-	`
-
-	return r.db.QueryRowContext(ctx, query,
-		event.Title,
-		event.Description,
-		event.Date,
-		event.TotalSeats,
-		time.Now(),
-		time.Now(),
-	).Scan(&event.ID)
-}
-
-func (r *eventRepository) GetByID(ctx context.Context, id int64) (*entity.EventWithAvailability, error) {
-	query := `
-		SELECT 
-			e.id, e.title, e.description, e.date, e.total_seats, e.created_at, e.updated_at,
-			COALESCE(SUM(CASE WHEN b.status = 'confirmed' THEN b.seats ELSE 0 END), 0) as booked_seats
-		FROM events e
-		LEFT JOIN bookings b ON e.id = b.event_id
-		WHERE e.id = $1
-		GROUP BY e.id
-	`
-
-	var event entity.EventWithAvailability
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&event.ID,
-		&event.Title,
-		&event.Description,
-		&event.Date,
-		&event.TotalSeats,
-		&event.CreatedAt,
-		&event.UpdatedAt,
-		&event.BookedSeats,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	event.AvailableSeats = event.TotalSeats - event.BookedSeats
-	return &event, nil
-}
-
-func (r *eventRepository) GetAll(ctx context.Context) ([]*entity.EventWithAvailability, error) {
-	query := `
-		SELECT 
-			e.id, e.title, e.description, e.date, e.total_seats, e.created_at, e.updated_at,
-			COALESCE(SUM(CASE WHEN b.status = 'confirmed' THEN b.seats ELSE 0 END), 0) as booked_seats
-		FROM events e
-		LEFT JOIN bookings b ON e.id = b.event_id
-		GROUP BY e.id
-		ORDER BY e.date
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var events []*entity.EventWithAvailability
-	for rows.Next() {
-		var event entity.EventWithAvailability
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Description,
-			&event.Date,
-			&event.TotalSeats,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-			&event.BookedSeats,
-		)
-		if err != nil {
-			return nil, err
-		}
-		event.AvailableSeats = event.TotalSeats - event.BookedSeats
-		events = append(events, &event)
-	}
-
-	return events, nil
-}
-
-func (r *eventRepository) UpdateSeats(ctx context.Context, eventID int64, seats int) error {
-	query := `UPDATE events SET total_seats = $1, updated_at = $2 WHERE id = $3`
-	_, err := r.db.ExecContext(ctx, query, seats, time.Now(), eventID)
-	return err
-}
-
-func (r *eventRepository) Update(ctx context.Context, event *entity.Event) error {
-	query := `
-		UPDATE events 
-		SET title = $1, description = $2, date = $3, total_seats = $4, updated_at = $5
-		WHERE id = $6
-	`
-
-	result, err := r.db.ExecContext(ctx, query,
-		event.Title,
-		event.Description,
-		event.Date,
-		event.TotalSeats,
-		time.Now(),
-		event.ID,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to update event: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrEventNotFound
-	}
-
-	return nil
-}
-
-func (r *eventRepository) Delete(ctx context.Context, id int64) error {
-	// Сначала проверяем, есть ли у события бронирования
-	var bookingCount int
-	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&bookingCount)
-	if err != nil {
-		return fmt.Errorf("failed to check event bookings: %w", err)
-	}
-
-	if bookingCount > 0 {
-		return fmt.Errorf("cannot delete event with existing bookings")
-	}
-
-	// Удаляем событие
-	query = `DELETE FROM events WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete event: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrEventNotFound
-	}
-
-	return nil
-}
-
-func (r *eventRepository) GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error) {
-	if limit <= 0 {
-		limit = 10
-	}
-
-	query := `
-		SELECT 
-			e.id, e.title, e.description, e.date, e.total_seats, e.created_at, e.updated_at,
-			COALESCE(SUM(CASE WHEN b.status = 'confirmed' THEN b.seats ELSE 0 END), 0) as booked_seats
-		FROM events e
-		LEFT JOIN bookings b ON e.id = b.event_id
-		WHERE e.date > $1
-		GROUP BY e.id
-		ORDER BY e.date ASC
-		LIMIT $2
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, time.Now(), limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query upcoming events: %w", err)
-	}
-	defer rows.Close()
-
-	var events []*entity.EventWithAvailability
-	for rows.Next() {
-		var event entity.EventWithAvailability
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Description,
-			&event.Date,
-			&event.TotalSeats,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-			&event.BookedSeats,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-		event.AvailableSeats = event.TotalSeats - event.BookedSeats
-		events = append(events, &event)
-	}
-
-	return events, nil
-}
-
-func (r *eventRepository) SearchByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error) {
-	query := `
-		SELECT 
-			e.id, e.title, e.description, e.date, e.total_seats, e.created_at, e.updated_at,
-			COALESCE(SUM(CASE WHEN b.status = 'confirmed' THEN b.seats ELSE 0 END), 0) as booked_seats
-		FROM events e
-		LEFT JOIN bookings b ON e.id = b.event_id
-		WHERE e.title ILIKE $1
-		GROUP BY e.id
-		ORDER BY e.date ASC
-	`
-
-	searchPattern := "%" + title + "%"
-	rows, err := r.db.QueryContext(ctx, query, searchPattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search events by title: %w", err)
-	}
-	defer rows.Close()
-
-	var events []*entity.EventWithAvailability
-	for rows.Next() {
-		var event entity.EventWithAvailability
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Description,
-			&event.Date,
-			&event.TotalSeats,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-			&event.BookedSeats,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-		event.AvailableSeats = event.TotalSeats - event.BookedSeats
-		events = append(events, &event)
-	}
-
-	return events, nil
-}
-
-func (r *eventRepository) GetEventsByDateRange(ctx context.Context, from, to time.Time) ([]*entity.Event, error) {
-	query := `
-		SELECT id, title, description, date, total_seats, created_at, updated_at
-		FROM events
-		WHERE date BETWEEN $1 AND $2
-		ORDER BY date ASC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, from, to)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query events by date range: %w", err)
-	}
-	defer rows.Close()
-
-	var events []*entity.Event
-	for rows.Next() {
-		var event entity.Event
-		err := rows.Scan(
-			&event.ID,
-			&event.Title,
-			&event.Description,
-			&event.Date,
-			&event.TotalSeats,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-		events = append(events, &event)
-	}
-
-	return events, nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type eventRepository struct {
+	db *conn
+}
+
+// NewEventRepository читает списки, поиск и ближайшие мероприятия с replica, если она задана
+func NewEventRepository(db, replica *sql.DB) EventRepository {
+	return &eventRepository{db: newRoutedConn(db, replica)}
+}
+
+func (r *eventRepository) Create(ctx context.Context, event *entity.Event) error {
+	query := `
+		INSERT INTO events (
+			title, description, location, venue_id, organizer_id, date, total_seats,
+			free_cancellation_hours, late_refund_percent, refund_rules, confirmation_escalation, sale_schedule, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, version
+	`
+
+	return r.db.QueryRowContext(ctx, query,
+		event.Title,
+		event.Description,
+		event.Location,
+		event.VenueID,
+		event.OrganizerID,
+		event.Date,
+		event.TotalSeats,
+		event.CancellationPolicy.FreeCancellationHours,
+		event.CancellationPolicy.LateRefundPercent,
+		event.CancellationPolicy.RefundRules,
+		event.ConfirmationEscalation,
+		event.SaleSchedule,
+		time.Now(),
+		time.Now(),
+	).Scan(&event.ID, &event.Version)
+}
+
+func (r *eventRepository) GetByID(ctx context.Context, id int64) (*entity.EventWithAvailability, error) {
+	query := `
+		SELECT 
+			e.id, e.title, e.description, e.location, e.venue_id, e.organizer_id, e.date, e.total_seats,
+			e.free_cancellation_hours, e.late_refund_percent, e.refund_rules, e.confirmation_escalation, e.sale_schedule, e.created_at, e.updated_at, e.version,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+		WHERE e.id = $1 AND e.deleted_at IS NULL
+		GROUP BY e.id
+	`
+
+	var event entity.EventWithAvailability
+	var poolSeats, poolBookedSeats int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&event.ID,
+		&event.Title,
+		&event.Description,
+		&event.Location,
+		&event.VenueID,
+		&event.OrganizerID,
+		&event.Date,
+		&event.TotalSeats,
+		&event.CancellationPolicy.FreeCancellationHours,
+		&event.CancellationPolicy.LateRefundPercent,
+		&event.CancellationPolicy.RefundRules,
+		&event.ConfirmationEscalation,
+		&event.SaleSchedule,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+		&event.Version,
+		&event.BookedSeats,
+		&poolBookedSeats,
+		&poolSeats,
+		&event.HeldSeats,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event.ApplyPools(poolSeats, poolBookedSeats)
+	return &event, nil
+}
+
+func (r *eventRepository) GetAll(ctx context.Context) ([]*entity.EventWithAvailability, error) {
+	query := `
+		SELECT 
+			e.id, e.title, e.description, e.location, e.venue_id, e.organizer_id, e.date, e.total_seats,
+			e.free_cancellation_hours, e.late_refund_percent, e.refund_rules, e.confirmation_escalation, e.sale_schedule, e.created_at, e.updated_at, e.version,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+		WHERE e.deleted_at IS NULL
+		GROUP BY e.id
+		ORDER BY e.date
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*entity.EventWithAvailability
+	for rows.Next() {
+		var event entity.EventWithAvailability
+		var poolSeats, poolBookedSeats int
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+			&event.BookedSeats,
+			&poolBookedSeats,
+			&poolSeats,
+			&event.HeldSeats,
+		)
+		if err != nil {
+			return nil, err
+		}
+		event.ApplyPools(poolSeats, poolBookedSeats)
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) UpdateSeats(ctx context.Context, eventID int64, seats int) error {
+	query := `UPDATE events SET total_seats = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, seats, time.Now(), eventID)
+	return err
+}
+
+// ClaimCapacityAlerts отмечает пороги заполненности мероприятия сработавшими и возвращает те,
+// что срабатывают впервые; их уведомления пишутся в outbox в той же транзакции
+func (r *eventRepository) ClaimCapacityAlerts(ctx context.Context, eventID int64, thresholds []int, outbox func(threshold int) []*entity.OutboxMessage) ([]int, error) {
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	values := make([]int64, len(thresholds))
+	for i, threshold := range thresholds {
+		values[i] = int64(threshold)
+	}
+
+	query := `
+		INSERT INTO event_capacity_alerts (event_id, threshold)
+		SELECT $1, unnest($2::int[])
+		ON CONFLICT (event_id, threshold) DO NOTHING
+		RETURNING threshold
+	`
+	rows, err := tx.QueryContext(ctx, query, eventID, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim capacity alerts: %w", err)
+	}
+
+	var claimed []int
+	for rows.Next() {
+		var threshold int
+		if err := rows.Scan(&threshold); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan capacity alert: %w", err)
+		}
+		claimed = append(claimed, threshold)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if outbox != nil {
+		for _, threshold := range claimed {
+			if err := insertOutbox(ctx, tx, outbox(threshold)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return claimed, nil
+}
+
+// Update сохраняет событие, если с момента чтения его версия не менялась.
+// Иначе возвращает ErrConflict, чтобы правка администратора не затёрла чужую.
+func (r *eventRepository) Update(ctx context.Context, event *entity.Event) error {
+	query := `
+		UPDATE events 
+		SET title = $1, description = $2, location = $3, venue_id = $4, date = $5, total_seats = $6,
+		    free_cancellation_hours = $7, late_refund_percent = $8, refund_rules = $9,
+		    confirmation_escalation = $10, sale_schedule = $11, updated_at = $12, version = version + 1
+		WHERE id = $13 AND deleted_at IS NULL AND version = $14
+		RETURNING version
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		event.Title,
+		event.Description,
+		event.Location,
+		event.VenueID,
+		event.Date,
+		event.TotalSeats,
+		event.CancellationPolicy.FreeCancellationHours,
+		event.CancellationPolicy.LateRefundPercent,
+		event.CancellationPolicy.RefundRules,
+		event.ConfirmationEscalation,
+		event.SaleSchedule,
+		time.Now(),
+		event.ID,
+		event.Version,
+	).Scan(&event.Version)
+
+	if err == sql.ErrNoRows {
+		return r.versionConflict(ctx, event.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+
+	return nil
+}
+
+// versionConflict отличает устаревшую версию от удалённого события, когда обновление не нашло строку
+func (r *eventRepository) versionConflict(ctx context.Context, id int64) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM events WHERE id = $1 AND deleted_at IS NULL)`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check event: %w", err)
+	}
+	if !exists {
+		return entity.ErrEventNotFound
+	}
+	return entity.ErrConflict
+}
+
+// Delete помечает событие удалённым: строка остаётся для журнала аудита и старых бронирований
+func (r *eventRepository) Delete(ctx context.Context, id int64, outbox []*entity.OutboxMessage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Сначала проверяем, есть ли у события действующие бронирования; отменённые и истёкшие не мешают
+	var bookingCount int
+	query := `SELECT COUNT(*) FROM bookings WHERE event_id = $1 AND status IN ('pending', 'confirmed') AND deleted_at IS NULL`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&bookingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check event bookings: %w", err)
+	}
+
+	if bookingCount > 0 {
+		return entity.ErrEventHasBookings
+	}
+
+	// Помечаем событие удалённым
+	query = `UPDATE events SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrEventNotFound
+	}
+
+	if err := insertAudit(ctx, tx, entity.NewAuditEntry(ctx, entity.AuditEntityEvent, id, entity.AuditActionDeleted)); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, outbox); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *eventRepository) GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := `
+		SELECT 
+			e.id, e.title, e.description, e.location, e.venue_id, e.organizer_id, e.date, e.total_seats,
+			e.free_cancellation_hours, e.late_refund_percent, e.refund_rules, e.confirmation_escalation, e.sale_schedule, e.created_at, e.updated_at, e.version,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+		WHERE e.date > $1 AND e.deleted_at IS NULL
+		GROUP BY e.id
+		ORDER BY e.date ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query upcoming events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.EventWithAvailability
+	for rows.Next() {
+		var event entity.EventWithAvailability
+		var poolSeats, poolBookedSeats int
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+			&event.BookedSeats,
+			&poolBookedSeats,
+			&poolSeats,
+			&event.HeldSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.ApplyPools(poolSeats, poolBookedSeats)
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) SearchByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error) {
+	query := `
+		SELECT 
+			e.id, e.title, e.description, e.location, e.venue_id, e.organizer_id, e.date, e.total_seats,
+			e.free_cancellation_hours, e.late_refund_percent, e.refund_rules, e.confirmation_escalation, e.sale_schedule, e.created_at, e.updated_at, e.version,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+		WHERE e.title ILIKE $1 ESCAPE '\' AND e.deleted_at IS NULL
+		GROUP BY e.id
+		ORDER BY e.date ASC
+	`
+
+	searchPattern := "%" + likeEscaper.Replace(title) + "%"
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, searchPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events by title: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.EventWithAvailability
+	for rows.Next() {
+		var event entity.EventWithAvailability
+		var poolSeats, poolBookedSeats int
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+			&event.BookedSeats,
+			&poolBookedSeats,
+			&poolSeats,
+			&event.HeldSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.ApplyPools(poolSeats, poolBookedSeats)
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы подстрока из запроса искалась буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// eventSortColumns сопоставляет допустимые значения SortBy с колонками,
+// чтобы ORDER BY никогда не строился из пользовательского ввода напрямую
+var eventSortColumns = map[string]string{
+	"date":       "e.date",
+	"title":      "e.title",
+	"created_at": "e.created_at",
+}
+
+// Search выполняет поиск мероприятий с фильтрацией, сортировкой и пагинацией на стороне PostgreSQL
+// availableSeatsExpr - свободные места в запросе Search, так же как их считает
+// EventWithAvailability.ApplyPools: непроданные места пулов и холды недоступны
+var availableSeatsExpr = `(e.total_seats
+	- COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0)
+	- GREATEST(COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0)
+		- COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0), 0)
+	- COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0))`
+
+func (r *eventRepository) Search(ctx context.Context, filter *entity.EventFilter) ([]*entity.EventWithAvailability, error) {
+	if filter == nil {
+		filter = &entity.EventFilter{}
+	}
+
+	conditions := []string{"e.deleted_at IS NULL"}
+	var args []interface{}
+
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			"to_tsvector('simple', e.title || ' ' || COALESCE(e.description, '')) @@ plainto_tsquery('simple', %s)",
+			addArg(q),
+		))
+	}
+	if filter.Title != "" {
+		conditions = append(conditions, "e.title ILIKE "+addArg("%"+likeEscaper.Replace(filter.Title)+"%")+` ESCAPE '\'`)
+	}
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, "e.date >= "+addArg(filter.DateFrom))
+	}
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, "e.date <= "+addArg(filter.DateTo))
+	}
+	if filter.VenueID != nil {
+		conditions = append(conditions, "e.venue_id = "+addArg(*filter.VenueID))
+	}
+
+	sortColumn, ok := eventSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = eventSortColumns["date"]
+	}
+	sortOrder := "ASC"
+	if strings.EqualFold(filter.SortOrder, "desc") {
+		sortOrder = "DESC"
+	}
+
+	query := `
+		SELECT 
+			e.id, e.title, e.description, e.location, e.venue_id, e.organizer_id, e.date, e.total_seats,
+			e.free_cancellation_hours, e.late_refund_percent, e.refund_rules, e.confirmation_escalation, e.sale_schedule, e.created_at, e.updated_at, e.version,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+	`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " GROUP BY e.id"
+	if filter.OnlyAvailable {
+		query += " HAVING " + availableSeatsExpr + " > 0"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, e.id %s", sortColumn, sortOrder, sortOrder)
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + addArg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + addArg(filter.Offset)
+	}
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*entity.EventWithAvailability, 0)
+	for rows.Next() {
+		var event entity.EventWithAvailability
+		var poolSeats, poolBookedSeats int
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+			&event.BookedSeats,
+			&poolBookedSeats,
+			&poolSeats,
+			&event.HeldSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.ApplyPools(poolSeats, poolBookedSeats)
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListEvents возвращает мероприятия в порядке GetAll, но без подсчёта занятых мест
+func (r *eventRepository) ListEvents(ctx context.Context) ([]*entity.Event, error) {
+	query := `
+		SELECT id, title, description, location, venue_id, organizer_id, date, total_seats, free_cancellation_hours, late_refund_percent, refund_rules, confirmation_escalation, sale_schedule, created_at, updated_at, version
+		FROM events
+		WHERE deleted_at IS NULL
+		ORDER BY date
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.Event
+	for rows.Next() {
+		var event entity.Event
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetSeatUsage считает занятые места мероприятий ids. Читает основную базу, а не реплику:
+// результат кладётся в кэш сразу после его сброса и не должен отставать от записи
+func (r *eventRepository) GetSeatUsage(ctx context.Context, ids []int64) (map[int64]entity.SeatUsage, error) {
+	query := `
+		SELECT
+			e.id,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` AND b.pool_id IS NOT NULL THEN b.seats ELSE 0 END), 0) as pool_booked_seats,
+			COALESCE((SELECT SUM(p.seats) FROM partner_pools p WHERE p.event_id = e.id), 0) as pool_seats,
+			COALESCE((SELECT SUM(h.seats) FROM event_holds h WHERE h.event_id = e.id), 0) as held_seats
+		FROM events e
+		LEFT JOIN bookings b ON e.id = b.event_id AND b.deleted_at IS NULL
+		WHERE e.id = ANY($1)
+		GROUP BY e.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seat usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[int64]entity.SeatUsage, len(ids))
+	for rows.Next() {
+		var id int64
+		var u entity.SeatUsage
+		if err := rows.Scan(&id, &u.BookedSeats, &u.PoolBookedSeats, &u.PoolSeats, &u.HeldSeats); err != nil {
+			return nil, fmt.Errorf("failed to scan seat usage: %w", err)
+		}
+		usage[id] = u
+	}
+
+	return usage, rows.Err()
+}
+
+func (r *eventRepository) GetEventsByDateRange(ctx context.Context, from, to time.Time) ([]*entity.Event, error) {
+	query := `
+		SELECT id, title, description, location, venue_id, organizer_id, date, total_seats, free_cancellation_hours, late_refund_percent, refund_rules, confirmation_escalation, sale_schedule, created_at, updated_at, version
+		FROM events
+		WHERE date BETWEEN $1 AND $2 AND deleted_at IS NULL
+		ORDER BY date ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events by date range: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.Event
+	for rows.Next() {
+		var event entity.Event
+		err := rows.Scan(
+			&event.ID,
+			&event.Title,
+			&event.Description,
+			&event.Location,
+			&event.VenueID,
+			&event.OrganizerID,
+			&event.Date,
+			&event.TotalSeats,
+			&event.CancellationPolicy.FreeCancellationHours,
+			&event.CancellationPolicy.LateRefundPercent,
+			&event.CancellationPolicy.RefundRules,
+			&event.ConfirmationEscalation,
+			&event.SaleSchedule,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
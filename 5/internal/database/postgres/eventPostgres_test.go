@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// TestEventSearchOnlyAvailable проверяет, что фильтр по наличию мест отбрасывает распроданное мероприятие
+func TestEventSearchOnlyAvailable(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	title := fmt.Sprintf("search %d", time.Now().UnixNano())
+
+	eventIDs := make([]int64, 2)
+	for i := range eventIDs {
+		err := db.QueryRowContext(ctx,
+			`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 2) RETURNING id`,
+			fmt.Sprintf("%s #%d", title, i), time.Now().Add(time.Duration(i+1)*time.Hour),
+		).Scan(&eventIDs[i])
+		if err != nil {
+			t.Fatalf("failed to create event: %v", err)
+		}
+	}
+
+	var userID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+		fmt.Sprintf("search-%d@example.com", time.Now().UnixNano()), "Tester",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// Первое мероприятие распродано
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO bookings (event_id, user_id, seats, status, expires_at, reservation_timeout, total_price)
+		VALUES ($1, $2, 2, 'confirmed', $3, 30, 0)`,
+		eventIDs[0], userID, time.Now().Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("failed to create booking: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bookings WHERE event_id IN ($1, $2)`, eventIDs[0], eventIDs[1])
+		db.Exec(`DELETE FROM events WHERE title LIKE $1`, title+"%")
+		db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	repo := NewEventRepository(db, nil)
+
+	all, err := repo.Search(ctx, &entity.EventFilter{Title: title})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("found %d events, want 2", len(all))
+	}
+
+	available, err := repo.Search(ctx, &entity.EventFilter{Title: title, OnlyAvailable: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(available) != 1 || available[0].ID != eventIDs[1] {
+		t.Fatalf("available = %+v, want only event %d", available, eventIDs[1])
+	}
+	if available[0].AvailableSeats != 2 {
+		t.Fatalf("available seats = %d, want 2", available[0].AvailableSeats)
+	}
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type outboxRepository struct {
+	db *conn
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: newConn(db)}
+}
+
+// insertOutbox writes messages inside the caller's transaction so they are committed
+// (or rolled back) together with the data change that produced them
+func insertOutbox(ctx context.Context, tx *repoTx, messages []*entity.OutboxMessage) error {
+	query := `
+		INSERT INTO outbox (task_id, task_type, payload, execute_at, max_retries, priority)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (task_id) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	for _, msg := range messages {
+		payload, err := json.Marshal(msg.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox payload: %v", err)
+		}
+
+		err = tx.QueryRowContext(ctx, query, msg.TaskID, msg.TaskType, payload, msg.ExecuteAt, msg.MaxRetries, msg.Priority).
+			Scan(&msg.ID, &msg.CreatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to insert outbox message: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Relay locks up to limit unpublished messages, hands each to publish and marks
+// the successful ones as published in the same transaction. Rows are locked with
+// SKIP LOCKED, so several relays can run concurrently without picking the same message.
+// A crash between publish and commit republishes the message: delivery is at-least-once.
+func (r *outboxRepository) Relay(ctx context.Context, limit int, publish func(*entity.OutboxMessage) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, task_id, task_type, payload, execute_at, max_retries, priority, attempts, last_error, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch outbox messages: %v", err)
+	}
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		var msg entity.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(&msg.ID, &msg.TaskID, &msg.TaskType, &payload, &msg.ExecuteAt,
+			&msg.MaxRetries, &msg.Priority, &msg.Attempts, &msg.LastError, &msg.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox message: %v", err)
+		}
+		if err := json.Unmarshal(payload, &msg.Data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to unmarshal outbox payload %d: %v", msg.ID, err)
+		}
+		messages = append(messages, &msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate outbox messages: %v", err)
+	}
+
+	published := 0
+	for _, msg := range messages {
+		if err := publish(msg); err != nil {
+			query = `UPDATE outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2`
+			if _, err := tx.ExecContext(ctx, query, err.Error(), msg.ID); err != nil {
+				return published, fmt.Errorf("failed to record outbox failure: %v", err)
+			}
+			// Order matters for tasks of one booking: stop and retry the rest on the next run
+			break
+		}
+
+		query = `UPDATE outbox SET published_at = NOW(), attempts = attempts + 1, last_error = '' WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, msg.ID); err != nil {
+			return published, fmt.Errorf("failed to mark outbox message published: %v", err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return published, nil
+}
+
+// Enqueue writes messages that are not tied to a data change. Messages whose task_id
+// is already in the outbox are skipped; returns how many were actually added.
+func (r *outboxRepository) Enqueue(ctx context.Context, messages []*entity.OutboxMessage) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertOutbox(ctx, tx, messages); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	// insertOutbox fills ID only for inserted rows
+	added := 0
+	for _, msg := range messages {
+		if msg.ID != 0 {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// DeletePublished removes messages published before the given time
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox messages: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetPending returns the messages of taskType waiting to be published
+func (r *outboxRepository) GetPending(ctx context.Context, taskType string) ([]*entity.OutboxMessage, error) {
+	query := `
+		SELECT id, task_id, task_type, payload, execute_at, max_retries, priority, attempts, last_error, created_at
+		FROM outbox
+		WHERE published_at IS NULL AND task_type = $1
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, taskType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending outbox messages: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		var msg entity.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(&msg.ID, &msg.TaskID, &msg.TaskType, &payload, &msg.ExecuteAt,
+			&msg.MaxRetries, &msg.Priority, &msg.Attempts, &msg.LastError, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %v", err)
+		}
+		if err := json.Unmarshal(payload, &msg.Data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal outbox payload %d: %v", msg.ID, err)
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox messages: %v", err)
+	}
+
+	return messages, nil
+}
+
+// CountPending returns the number of messages waiting to be published
+func (r *outboxRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM outbox WHERE published_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox messages: %v", err)
+	}
+	return count, nil
+}
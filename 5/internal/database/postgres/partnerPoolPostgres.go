@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type partnerPoolRepository struct {
+	db *conn
+}
+
+func NewPartnerPoolRepository(db *sql.DB) PartnerPoolRepository {
+	return &partnerPoolRepository{db: newConn(db)}
+}
+
+func (r *partnerPoolRepository) Create(ctx context.Context, pool *entity.PartnerPool) error {
+	query := `
+		INSERT INTO partner_pools (event_id, name, code, seats, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		pool.EventID,
+		pool.Name,
+		pool.Code,
+		pool.Seats,
+		now,
+		now,
+	).Scan(&pool.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrPartnerPoolExists
+		}
+		return fmt.Errorf("failed to create partner pool: %w", err)
+	}
+
+	pool.CreatedAt = now
+	pool.UpdatedAt = now
+	return nil
+}
+
+func (r *partnerPoolRepository) GetByID(ctx context.Context, id int64) (*entity.PartnerPool, error) {
+	query := `
+		SELECT id, event_id, name, code, seats, created_at, updated_at
+		FROM partner_pools
+		WHERE id = $1
+	`
+
+	return r.get(ctx, query, id)
+}
+
+// GetByCode ищет пул мероприятия по коду без учёта регистра
+func (r *partnerPoolRepository) GetByCode(ctx context.Context, eventID int64, code string) (*entity.PartnerPool, error) {
+	query := `
+		SELECT id, event_id, name, code, seats, created_at, updated_at
+		FROM partner_pools
+		WHERE event_id = $1 AND UPPER(code) = UPPER($2)
+	`
+
+	return r.get(ctx, query, eventID, code)
+}
+
+func (r *partnerPoolRepository) get(ctx context.Context, query string, args ...interface{}) (*entity.PartnerPool, error) {
+	var pool entity.PartnerPool
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&pool.ID,
+		&pool.EventID,
+		&pool.Name,
+		&pool.Code,
+		&pool.Seats,
+		&pool.CreatedAt,
+		&pool.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrPartnerPoolNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partner pool: %w", err)
+	}
+
+	return &pool, nil
+}
+
+func (r *partnerPoolRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.PartnerPoolWithAvailability, error) {
+	query := `
+		SELECT
+			p.id, p.event_id, p.name, p.code, p.seats, p.created_at, p.updated_at,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats
+		FROM partner_pools p
+		LEFT JOIN bookings b ON p.id = b.pool_id AND b.deleted_at IS NULL
+		WHERE p.event_id = $1
+		GROUP BY p.id
+		ORDER BY p.name ASC, p.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partner pools: %w", err)
+	}
+	defer rows.Close()
+
+	pools := make([]*entity.PartnerPoolWithAvailability, 0)
+	for rows.Next() {
+		var pool entity.PartnerPoolWithAvailability
+		err := rows.Scan(
+			&pool.ID,
+			&pool.EventID,
+			&pool.Name,
+			&pool.Code,
+			&pool.Seats,
+			&pool.CreatedAt,
+			&pool.UpdatedAt,
+			&pool.BookedSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan partner pool: %w", err)
+		}
+		pool.AvailableSeats = pool.Seats - pool.BookedSeats
+		pools = append(pools, &pool)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partner pools: %w", err)
+	}
+
+	return pools, nil
+}
+
+func (r *partnerPoolRepository) Update(ctx context.Context, pool *entity.PartnerPool) error {
+	query := `
+		UPDATE partner_pools
+		SET name = $1, code = $2, seats = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		pool.Name,
+		pool.Code,
+		pool.Seats,
+		now,
+		pool.ID,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrPartnerPoolExists
+		}
+		return fmt.Errorf("failed to update partner pool: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrPartnerPoolNotFound
+	}
+
+	pool.UpdatedAt = now
+	return nil
+}
+
+func (r *partnerPoolRepository) Delete(ctx context.Context, id int64) error {
+	// Пул с бронированиями удалять нельзя: на него ссылаются бронирования,
+	// а их места после удаления пула посчитались бы в общей квоте
+	var bookingCount int
+	query := `SELECT COUNT(*) FROM bookings WHERE pool_id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&bookingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check pool bookings: %w", err)
+	}
+
+	if bookingCount > 0 {
+		return entity.ErrPartnerPoolHasBookings
+	}
+
+	query = `DELETE FROM partner_pools WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete partner pool: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrPartnerPoolNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type promoCodeRepository struct {
+	db *conn
+}
+
+func NewPromoCodeRepository(db *sql.DB) PromoCodeRepository {
+	return &promoCodeRepository{db: newConn(db)}
+}
+
+func (r *promoCodeRepository) Create(ctx context.Context, promo *entity.PromoCode) error {
+	query := `
+		INSERT INTO promo_codes (
+			code, event_id, discount_type, discount_value, max_uses,
+			used_count, expires_at, active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		promo.Code,
+		promo.EventID,
+		promo.DiscountType,
+		promo.DiscountValue,
+		promo.MaxUses,
+		promo.ExpiresAt,
+		promo.Active,
+		now,
+		now,
+	).Scan(&promo.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return entity.ErrPromoCodeExists
+		}
+		return fmt.Errorf("failed to create promo code: %w", err)
+	}
+
+	promo.UsedCount = 0
+	promo.CreatedAt = now
+	promo.UpdatedAt = now
+	return nil
+}
+
+func (r *promoCodeRepository) GetByID(ctx context.Context, id int64) (*entity.PromoCode, error) {
+	query := `
+		SELECT id, code, event_id, discount_type, discount_value, max_uses,
+		       used_count, expires_at, active, created_at, updated_at
+		FROM promo_codes
+		WHERE id = $1
+	`
+
+	var promo entity.PromoCode
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&promo.ID,
+		&promo.Code,
+		&promo.EventID,
+		&promo.DiscountType,
+		&promo.DiscountValue,
+		&promo.MaxUses,
+		&promo.UsedCount,
+		&promo.ExpiresAt,
+		&promo.Active,
+		&promo.CreatedAt,
+		&promo.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	return &promo, nil
+}
+
+// GetByCode ищет промокод без учета регистра
+func (r *promoCodeRepository) GetByCode(ctx context.Context, code string) (*entity.PromoCode, error) {
+	query := `
+		SELECT id, code, event_id, discount_type, discount_value, max_uses,
+		       used_count, expires_at, active, created_at, updated_at
+		FROM promo_codes
+		WHERE UPPER(code) = UPPER($1)
+	`
+
+	var promo entity.PromoCode
+	err := r.db.QueryRowContext(ctx, query, code).Scan(
+		&promo.ID,
+		&promo.Code,
+		&promo.EventID,
+		&promo.DiscountType,
+		&promo.DiscountValue,
+		&promo.MaxUses,
+		&promo.UsedCount,
+		&promo.ExpiresAt,
+		&promo.Active,
+		&promo.CreatedAt,
+		&promo.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) GetAll(ctx context.Context) ([]*entity.PromoCode, error) {
+	query := `
+		SELECT id, code, event_id, discount_type, discount_value, max_uses,
+		       used_count, expires_at, active, created_at, updated_at
+		FROM promo_codes
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promo codes: %w", err)
+	}
+	defer rows.Close()
+
+	promos := make([]*entity.PromoCode, 0)
+	for rows.Next() {
+		var promo entity.PromoCode
+		err := rows.Scan(
+			&promo.ID,
+			&promo.Code,
+			&promo.EventID,
+			&promo.DiscountType,
+			&promo.DiscountValue,
+			&promo.MaxUses,
+			&promo.UsedCount,
+			&promo.ExpiresAt,
+			&promo.Active,
+			&promo.CreatedAt,
+			&promo.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promo code: %w", err)
+		}
+		promos = append(promos, &promo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promo codes: %w", err)
+	}
+
+	return promos, nil
+}
+
+func (r *promoCodeRepository) Update(ctx context.Context, promo *entity.PromoCode) error {
+	query := `
+		UPDATE promo_codes
+		SET event_id = $1, discount_type = $2, discount_value = $3, max_uses = $4,
+		    expires_at = $5, active = $6, updated_at = $7
+		WHERE id = $8
+	`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		promo.EventID,
+		promo.DiscountType,
+		promo.DiscountValue,
+		promo.MaxUses,
+		promo.ExpiresAt,
+		promo.Active,
+		now,
+		promo.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update promo code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrPromoCodeNotFound
+	}
+
+	promo.UpdatedAt = now
+	return nil
+}
+
+func (r *promoCodeRepository) Delete(ctx context.Context, id int64) error {
+	// Использованный промокод удалять нельзя — на него ссылаются бронирования, его можно только отключить
+	var bookingCount int
+	query := `SELECT COUNT(*) FROM bookings WHERE promo_code_id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&bookingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check promo code bookings: %w", err)
+	}
+
+	if bookingCount > 0 {
+		return entity.ErrPromoCodeInUse
+	}
+
+	query = `DELETE FROM promo_codes WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promo code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrPromoCodeNotFound
+	}
+
+	return nil
+}
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением уникального ограничения
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation проверяет, что на удаляемую строку ещё ссылаются другие таблицы
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
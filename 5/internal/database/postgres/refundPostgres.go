@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type refundRepository struct {
+	db *conn
+}
+
+func NewRefundRepository(db *sql.DB) RefundRepository {
+	return &refundRepository{db: newConn(db)}
+}
+
+// insertRefund stores the refund inside the caller's transaction. A booking is refunded at most once,
+// the UNIQUE constraint on booking_id rejects a second refund.
+func insertRefund(ctx context.Context, tx *repoTx, refund *entity.Refund) error {
+	query := `
+		INSERT INTO refunds (booking_id, event_id, user_id, paid_amount, percent, amount, status, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	if refund.Status == "" {
+		refund.Status = entity.RefundStatusPending
+	}
+	refund.CreatedAt = time.Now()
+
+	err := tx.QueryRowContext(ctx, query,
+		refund.BookingID,
+		refund.EventID,
+		refund.UserID,
+		refund.PaidAmount,
+		refund.Percent,
+		refund.Amount,
+		refund.Status,
+		refund.Reason,
+		refund.CreatedAt,
+	).Scan(&refund.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert refund: %v", err)
+	}
+
+	return nil
+}
+
+const refundColumns = `id, booking_id, event_id, user_id, paid_amount, percent, amount, status, reason, created_at, processed_at`
+
+func (r *refundRepository) GetByID(ctx context.Context, id int64) (*entity.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE id = $1`
+
+	refund, err := scanRefund(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrRefundNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+
+	return refund, nil
+}
+
+func (r *refundRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE event_id = $1 ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make([]*entity.Refund, 0)
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refunds: %w", err)
+	}
+
+	return refunds, nil
+}
+
+// MarkProcessed переводит ожидающий возврат в итоговый статус; повторная обработка ничего не меняет
+func (r *refundRepository) MarkProcessed(ctx context.Context, id int64, status entity.RefundStatus) (bool, error) {
+	query := `UPDATE refunds SET status = $1, processed_at = $2 WHERE id = $3 AND status = 'pending'`
+
+	result, err := r.db.ExecContext(ctx, query, status, time.Now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update refund: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+type refundScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRefund(row refundScanner) (*entity.Refund, error) {
+	var refund entity.Refund
+	err := row.Scan(
+		&refund.ID,
+		&refund.BookingID,
+		&refund.EventID,
+		&refund.UserID,
+		&refund.PaidAmount,
+		&refund.Percent,
+		&refund.Amount,
+		&refund.Status,
+		&refund.Reason,
+		&refund.CreatedAt,
+		&refund.ProcessedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
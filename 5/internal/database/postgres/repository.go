@@ -1,75 +1,261 @@
-package repository
-
-import (
-	"context"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-type BookingRepository interface {
-	// Basic CRUD operations
-	Create(ctx context.Context, booking *entity.Booking) error
-	GetByID(ctx context.Context, id int64) (*entity.Booking, error)
-	GetByEventAndUser(ctx context.Context, eventID, userID int64) (*entity.Booking, error)
-	UpdateStatus(ctx context.Context, id int64, status entity.BookingStatus) error
-	Update(ctx context.Context, booking *entity.Booking) error
-	Delete(ctx context.Context, id int64) error
-
-	// Query operations
-	GetByEventID(ctx context.Context, eventID int64) ([]*entity.Booking, error)
-	GetByUserID(ctx context.Context, userID int64) ([]*entity.Booking, error)
-	GetByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error)
-	GetByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) ([]*entity.Booking, error)
-
-	// Expiration operations
-	GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error)
-	GetExpiringBookings(ctx context.Context, from, to time.Time) ([]*entity.BookingExpiration, error)
-	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
-	BulkUpdateStatus(ctx context.Context, ids []int64, status entity.BookingStatus) error
-
-	// Statistical operations
-	CountByEvent(ctx context.Context, eventID int64) (int, error)
-	CountByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) (int, error)
-	GetEventBookingStats(ctx context.Context, eventID int64) (*entity.EventBookingStats, error)
-
-	// Locking operations for concurrency control
-	LockBooking(ctx context.Context, id int64) error
-	GetWithLock(ctx context.Context, id int64) (*entity.Booking, error)
-
-	GetAll(ctx context.Context) ([]*entity.Booking, error)
-	GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error)
-}
-
-type EventRepository interface {
-	Create(ctx context.Context, event *entity.Event) error
-	GetByID(ctx context.Context, id int64) (*entity.EventWithAvailability, error)
-	GetAll(ctx context.Context) ([]*entity.EventWithAvailability, error)
-
-	// CRUD операции
-
-	Update(ctx context.Context, event *entity.Event) error
-	Delete(ctx context.Context, id int64) error
-
-	// Статистика и дополнительные методы
-	GetEventsByDateRange(ctx context.Context, from, to time.Time) ([]*entity.Event, error)
-	GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error)
-	SearchByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error)
-	UpdateSeats(ctx context.Context, eventID int64, seats int) error
-}
-
-type UserRepository interface {
-	Create(ctx context.Context, user *entity.User) error
-	GetByID(ctx context.Context, id int64) (*entity.User, error)
-	GetByEmail(ctx context.Context, email string) (*entity.User, error)
-	GetByTelegramID(ctx context.Context, telegramID string) (*entity.User, error)
-	UpdateTelegramID(ctx context.Context, userID int64, telegramID string) error
-
-	// CRUD операции
-	Update(ctx context.Context, user *entity.User) error
-	Delete(ctx context.Context, id int64) error
-
-	// Дополнительные методы
-	GetAll(ctx context.Context) ([]*entity.User, error)
-	SearchByName(ctx context.Context, name string) ([]*entity.User, error)
-}
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type BookingRepository interface {
+	// Basic CRUD operations
+	Create(ctx context.Context, booking *entity.Booking) error
+	CreateWithOutbox(ctx context.Context, booking *entity.Booking, outbox func(*entity.Booking) []*entity.OutboxMessage) error
+	CreateManyWithOutbox(ctx context.Context, bookings []*entity.Booking, outbox func(*entity.Booking) []*entity.OutboxMessage) error
+	GetByID(ctx context.Context, id int64) (*entity.Booking, error)
+	GetByEventAndUser(ctx context.Context, eventID, userID int64) (*entity.Booking, error)
+	UpdateStatus(ctx context.Context, id int64, status entity.BookingStatus) error
+	CancelWithRefund(ctx context.Context, id int64, refund func(locked *entity.Booking) *entity.Refund, outbox func(*entity.Refund) []*entity.OutboxMessage) (*entity.Refund, error)
+	Update(ctx context.Context, booking *entity.Booking) error
+	Delete(ctx context.Context, id int64) error
+
+	// Query operations
+	GetByEventID(ctx context.Context, eventID int64) ([]*entity.Booking, error)
+	GetByUserID(ctx context.Context, userID int64) ([]*entity.Booking, error)
+	GetByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error)
+	GetByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) ([]*entity.Booking, error)
+	// GetByOrganizer возвращает бронирования на мероприятия организатора, eventID сужает выборку до одного из них
+	GetByOrganizer(ctx context.Context, organizerID int64, eventID *int64) ([]*entity.Booking, error)
+
+	// Expiration operations
+	GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error)
+	GetExpiringBookings(ctx context.Context, from, to time.Time) ([]*entity.BookingExpiration, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	ExtendExpiration(ctx context.Context, id int64, expiresAt time.Time) (bool, error)
+
+	// Reminder operations: напоминание о мероприятии отправляется одному бронированию не больше одного раза
+	ClaimReminder(ctx context.Context, bookingID int64, reminder string) (bool, error)
+	ReleaseReminder(ctx context.Context, bookingID int64, reminder string) error
+	BulkUpdateStatus(ctx context.Context, ids []int64, status entity.BookingStatus) error
+
+	// Notification operations: сверка ищет бронирования, уведомление о которых не было обработано
+	RecordNotification(ctx context.Context, bookingID int64, notification string) error
+	GetMissingNotification(ctx context.Context, status entity.BookingStatus, notification string, from, to time.Time) ([]*entity.Booking, error)
+
+	// Statistical operations
+	CountByEvent(ctx context.Context, eventID int64) (int, error)
+	CountByEventAndStatus(ctx context.Context, eventID int64, status entity.BookingStatus) (int, error)
+	GetEventBookingStats(ctx context.Context, eventID int64) (*entity.EventBookingStats, error)
+
+	// Locking operations for concurrency control
+	LockBooking(ctx context.Context, id int64) error
+	GetWithLock(ctx context.Context, id int64) (*entity.Booking, error)
+
+	GetAll(ctx context.Context) ([]*entity.Booking, error)
+	GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error)
+
+	// Export operations: keyset-курсор по id, eventID == nil означает все мероприятия
+	GetExportBatch(ctx context.Context, eventID *int64, afterID int64, limit int) ([]*entity.BookingExportRow, error)
+
+	// GetCalendarEntries возвращает подтверждённые бронирования пользователя вместе с мероприятиями
+	GetCalendarEntries(ctx context.Context, userID int64) ([]*entity.CalendarEntry, error)
+
+	// GetUserEventBookings группирует бронирования пользователя по мероприятиям,
+	// сначала мероприятия с наибольшим числом бронирований
+	GetUserEventBookings(ctx context.Context, userID int64) ([]*entity.UserEventBookings, error)
+
+	// Ticket operations: код билета выдаётся при подтверждении, IssueTicket выдаёт его
+	// бронированиям, подтверждённым раньше; CheckIn отмечает проход по билету один раз
+	IssueTicket(ctx context.Context, id int64) (string, error)
+	CheckIn(ctx context.Context, id int64, code string, at time.Time) (*entity.Booking, error)
+}
+
+type EventRepository interface {
+	Create(ctx context.Context, event *entity.Event) error
+	GetByID(ctx context.Context, id int64) (*entity.EventWithAvailability, error)
+	GetAll(ctx context.Context) ([]*entity.EventWithAvailability, error)
+
+	// CRUD операции
+
+	Update(ctx context.Context, event *entity.Event) error
+	// Delete помечает мероприятие удалённым и в той же транзакции записывает outbox
+	Delete(ctx context.Context, id int64, outbox []*entity.OutboxMessage) error
+
+	// Статистика и дополнительные методы
+	GetEventsByDateRange(ctx context.Context, from, to time.Time) ([]*entity.Event, error)
+	GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error)
+	SearchByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error)
+	Search(ctx context.Context, filter *entity.EventFilter) ([]*entity.EventWithAvailability, error)
+	UpdateSeats(ctx context.Context, eventID int64, seats int) error
+
+	// ListEvents и GetSeatUsage вместе дают то же, что GetAll: так занятость мест можно кэшировать отдельно
+	ListEvents(ctx context.Context) ([]*entity.Event, error)
+	GetSeatUsage(ctx context.Context, ids []int64) (map[int64]entity.SeatUsage, error)
+
+	// ClaimCapacityAlerts - каждый порог заполненности срабатывает для мероприятия один раз
+	ClaimCapacityAlerts(ctx context.Context, eventID int64, thresholds []int, outbox func(threshold int) []*entity.OutboxMessage) ([]int, error)
+}
+
+// EventPosterRepository хранит афиши мероприятий; у мероприятия одна афиша
+type EventPosterRepository interface {
+	Save(ctx context.Context, poster *entity.EventPoster) error
+	UpdateResult(ctx context.Context, poster *entity.EventPoster) (bool, error)
+	GetByEventID(ctx context.Context, eventID int64) (*entity.EventPoster, error)
+	GetByEventIDs(ctx context.Context, eventIDs []int64) (map[int64]*entity.EventPoster, error)
+}
+
+type VenueRepository interface {
+	Create(ctx context.Context, venue *entity.Venue) error
+	GetByID(ctx context.Context, id int64) (*entity.Venue, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*entity.Venue, error)
+	GetAll(ctx context.Context) ([]*entity.Venue, error)
+	Update(ctx context.Context, venue *entity.Venue) error
+	Delete(ctx context.Context, id int64) error
+
+	// MaxEventSeats нужен, чтобы не уменьшить вместимость ниже уже созданных мероприятий
+	MaxEventSeats(ctx context.Context, id int64) (int, error)
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user *entity.User) error
+	GetByID(ctx context.Context, id int64) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetByTelegramID(ctx context.Context, telegramID string) (*entity.User, error)
+	UpdateTelegramID(ctx context.Context, userID int64, telegramID string) error
+	UpdateRole(ctx context.Context, userID int64, role string) error
+	// UpdateNotificationPreferences сохраняет включённые каналы и каналы по категориям уведомлений
+	UpdateNotificationPreferences(ctx context.Context, userID int64, email, telegram bool, categories entity.NotificationPreferences) error
+
+	// Версия токена календарной подписки; увеличение версии отзывает выданные токены
+	GetCalendarTokenVersion(ctx context.Context, userID int64) (int, error)
+	IncrementCalendarTokenVersion(ctx context.Context, userID int64) (int, error)
+
+	// CRUD операции
+	Update(ctx context.Context, user *entity.User) error
+	Delete(ctx context.Context, id int64) error
+
+	// Дополнительные методы
+	GetAll(ctx context.Context) ([]*entity.User, error)
+	SearchByName(ctx context.Context, name string) ([]*entity.User, error)
+	// Search возвращает страницу пользователей по фильтру и общее число найденных
+	Search(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error)
+}
+
+type TicketTierRepository interface {
+	Create(ctx context.Context, tier *entity.TicketTier) error
+	GetByID(ctx context.Context, id int64) (*entity.TicketTier, error)
+	GetByEventID(ctx context.Context, eventID int64) ([]*entity.TicketTierWithAvailability, error)
+	Update(ctx context.Context, tier *entity.TicketTier) error
+	Delete(ctx context.Context, id int64) error
+
+	// SumSeatsByEvent возвращает суммарную квоту мест всех категорий мероприятия
+	SumSeatsByEvent(ctx context.Context, eventID int64) (int, error)
+}
+
+// PartnerPoolRepository - партнёрские пулы мест; места пулов проверяются при создании
+// и подтверждении бронирований в bookingRepository
+type PartnerPoolRepository interface {
+	Create(ctx context.Context, pool *entity.PartnerPool) error
+	GetByID(ctx context.Context, id int64) (*entity.PartnerPool, error)
+	GetByCode(ctx context.Context, eventID int64, code string) (*entity.PartnerPool, error)
+	GetByEventID(ctx context.Context, eventID int64) ([]*entity.PartnerPoolWithAvailability, error)
+	Update(ctx context.Context, pool *entity.PartnerPool) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// EventHoldRepository - административные холды мест; их сумма вычитается из доступных мест
+// мероприятия и проверяется при создании и подтверждении бронирований в bookingRepository
+type EventHoldRepository interface {
+	GetByEventID(ctx context.Context, eventID int64) ([]*entity.EventHold, error)
+	// ReplaceForEvent атомарно заменяет все холды мероприятия, проверяя, что они
+	// помещаются в места, не занятые бронированиями и партнёрскими пулами
+	ReplaceForEvent(ctx context.Context, eventID int64, holds []*entity.EventHold) error
+}
+
+// CartRepository - корзины пользователей; у пользователя не больше одной открытой корзины
+type CartRepository interface {
+	GetOpenByUser(ctx context.Context, userID int64, now time.Time) (*entity.Cart, error)
+	GetOrCreateOpen(ctx context.Context, userID int64, expiresAt time.Time) (*entity.Cart, error)
+	// AddItem и RemoveItem продлевают срок жизни корзины до expiresAt
+	AddItem(ctx context.Context, item *entity.CartItem, expiresAt time.Time) error
+	RemoveItem(ctx context.Context, cartID, itemID int64, expiresAt time.Time) error
+	ClearItems(ctx context.Context, cartID int64) error
+	MarkCheckedOut(ctx context.Context, cartID int64) (bool, error)
+	ExpireAbandoned(ctx context.Context, now time.Time) (int64, error)
+}
+
+type PromoCodeRepository interface {
+	Create(ctx context.Context, promo *entity.PromoCode) error
+	GetByID(ctx context.Context, id int64) (*entity.PromoCode, error)
+	GetByCode(ctx context.Context, code string) (*entity.PromoCode, error)
+	GetAll(ctx context.Context) ([]*entity.PromoCode, error)
+	Update(ctx context.Context, promo *entity.PromoCode) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entity.Webhook) error
+	GetByID(ctx context.Context, id int64) (*entity.Webhook, error)
+	GetAll(ctx context.Context) ([]*entity.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+
+	// GetActiveForEvent возвращает активные вебхуки мероприятия и глобальные вебхуки
+	GetActiveForEvent(ctx context.Context, eventID int64) ([]*entity.Webhook, error)
+
+	// Журнал доставок
+	LogDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]*entity.WebhookDelivery, error)
+}
+
+// APITokenRepository - токены внешних интеграций организаторов; токены ищутся по SHA-256
+type APITokenRepository interface {
+	Create(ctx context.Context, token *entity.APIToken) error
+	GetByHash(ctx context.Context, hash string) (*entity.APIToken, error)
+	GetAll(ctx context.Context) ([]*entity.APIToken, error)
+	GetByOrganizer(ctx context.Context, organizerID int64) ([]*entity.APIToken, error)
+	Revoke(ctx context.Context, id int64) (*entity.APIToken, error)
+	TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+// AuditRepository - журнал изменений; записи добавляют репозитории сущностей в своих транзакциях
+type AuditRepository interface {
+	Create(ctx context.Context, entry *entity.AuditEntry) error
+	GetByEntity(ctx context.Context, entityType string, entityID int64) ([]*entity.AuditEntry, error)
+}
+
+// RefundRepository - возвраты за отменённые оплаченные бронирования; создаются в CancelWithRefund
+type RefundRepository interface {
+	GetByID(ctx context.Context, id int64) (*entity.Refund, error)
+	GetByEventID(ctx context.Context, eventID int64) ([]*entity.Refund, error)
+	MarkProcessed(ctx context.Context, id int64, status entity.RefundStatus) (bool, error)
+}
+
+// AnalyticsRepository - агрегаты по всей системе для административной статистики
+type AnalyticsRepository interface {
+	GetSystemStats(ctx context.Context) (*entity.SystemStats, error)
+	// GetBookingTrends возвращает ряд по периодам, пересекающим [from, to); периоды без бронирований
+	// идут с нулями. Подтверждения и отмены относятся к периоду, в котором сменился статус.
+	GetBookingTrends(ctx context.Context, period string, from, to time.Time) (*entity.BookingTrends, error)
+}
+
+// CapacityRepository - изменение вместимости мероприятия вместе с отменой непоместившихся бронирований
+type CapacityRepository interface {
+	GetDemand(ctx context.Context, eventID int64) (*entity.CapacityDemand, error)
+	// Apply выбирает затронутые бронирования через plan по заблокированному состоянию и применяет
+	// изменение одной транзакцией; outbox получает каждое затронутое бронирование и его возврат
+	Apply(ctx context.Context, eventID int64, version *int,
+		plan func(*entity.CapacityDemand) (*entity.CapacityPlan, error),
+		outbox func(*entity.AffectedBooking, *entity.Refund) []*entity.OutboxMessage) (*entity.CapacityPlan, error)
+	GetWaitlist(ctx context.Context, eventID int64) ([]*entity.WaitlistEntry, error)
+}
+
+// OutboxRepository - задачи для очереди, записанные транзакционно вместе с данными
+type OutboxRepository interface {
+	Relay(ctx context.Context, limit int, publish func(*entity.OutboxMessage) error) (int, error)
+	// Enqueue записывает задачи вне транзакции с данными; задачи с уже известным task_id пропускаются
+	Enqueue(ctx context.Context, messages []*entity.OutboxMessage) (int, error)
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+	CountPending(ctx context.Context) (int, error)
+	// GetPending возвращает неопубликованные задачи типа taskType
+	GetPending(ctx context.Context, taskType string) ([]*entity.OutboxMessage, error)
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type ticketTierRepository struct {
+	db *conn
+}
+
+func NewTicketTierRepository(db *sql.DB) TicketTierRepository {
+	return &ticketTierRepository{db: newConn(db)}
+}
+
+func (r *ticketTierRepository) Create(ctx context.Context, tier *entity.TicketTier) error {
+	query := `
+		INSERT INTO ticket_tiers (event_id, name, price, seats, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		tier.EventID,
+		tier.Name,
+		tier.Price,
+		tier.Seats,
+		now,
+		now,
+	).Scan(&tier.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create ticket tier: %w", err)
+	}
+
+	tier.CreatedAt = now
+	tier.UpdatedAt = now
+	return nil
+}
+
+func (r *ticketTierRepository) GetByID(ctx context.Context, id int64) (*entity.TicketTier, error) {
+	query := `
+		SELECT id, event_id, name, price, seats, created_at, updated_at
+		FROM ticket_tiers
+		WHERE id = $1
+	`
+
+	var tier entity.TicketTier
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&tier.ID,
+		&tier.EventID,
+		&tier.Name,
+		&tier.Price,
+		&tier.Seats,
+		&tier.CreatedAt,
+		&tier.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrTicketTierNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket tier: %w", err)
+	}
+
+	return &tier, nil
+}
+
+func (r *ticketTierRepository) GetByEventID(ctx context.Context, eventID int64) ([]*entity.TicketTierWithAvailability, error) {
+	query := `
+		SELECT 
+			t.id, t.event_id, t.name, t.price, t.seats, t.created_at, t.updated_at,
+			COALESCE(SUM(CASE WHEN ` + holdsSeats("b") + ` THEN b.seats ELSE 0 END), 0) as booked_seats
+		FROM ticket_tiers t
+		LEFT JOIN bookings b ON t.id = b.tier_id AND b.deleted_at IS NULL
+		WHERE t.event_id = $1
+		GROUP BY t.id
+		ORDER BY t.price ASC, t.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ticket tiers: %w", err)
+	}
+	defer rows.Close()
+
+	tiers := make([]*entity.TicketTierWithAvailability, 0)
+	for rows.Next() {
+		var tier entity.TicketTierWithAvailability
+		err := rows.Scan(
+			&tier.ID,
+			&tier.EventID,
+			&tier.Name,
+			&tier.Price,
+			&tier.Seats,
+			&tier.CreatedAt,
+			&tier.UpdatedAt,
+			&tier.BookedSeats,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ticket tier: %w", err)
+		}
+		tier.AvailableSeats = tier.Seats - tier.BookedSeats
+		tiers = append(tiers, &tier)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ticket tiers: %w", err)
+	}
+
+	return tiers, nil
+}
+
+func (r *ticketTierRepository) Update(ctx context.Context, tier *entity.TicketTier) error {
+	query := `
+		UPDATE ticket_tiers 
+		SET name = $1, price = $2, seats = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		tier.Name,
+		tier.Price,
+		tier.Seats,
+		now,
+		tier.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update ticket tier: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrTicketTierNotFound
+	}
+
+	tier.UpdatedAt = now
+	return nil
+}
+
+func (r *ticketTierRepository) Delete(ctx context.Context, id int64) error {
+	// Категорию с бронированиями удалять нельзя — на нее ссылается история и выручка
+	var bookingCount int
+	query := `SELECT COUNT(*) FROM bookings WHERE tier_id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&bookingCount)
+	if err != nil {
+		return fmt.Errorf("failed to check tier bookings: %w", err)
+	}
+
+	if bookingCount > 0 {
+		return fmt.Errorf("%w: %d bookings reference it", entity.ErrTierHasBookings, bookingCount)
+	}
+
+	query = `DELETE FROM ticket_tiers WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		// Бронирование могло появиться между проверкой и удалением
+		if isForeignKeyViolation(err) {
+			return entity.ErrTierHasBookings
+		}
+		return fmt.Errorf("failed to delete ticket tier: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrTicketTierNotFound
+	}
+
+	return nil
+}
+
+func (r *ticketTierRepository) SumSeatsByEvent(ctx context.Context, eventID int64) (int, error) {
+	query := `SELECT COALESCE(SUM(seats), 0) FROM ticket_tiers WHERE event_id = $1`
+	var seats int
+	err := r.db.QueryRowContext(ctx, query, eventID).Scan(&seats)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum tier seats: %w", err)
+	}
+	return seats, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// TestTicketTierDeleteWithBookings проверяет, что категорию с бронированиями удалить нельзя,
+// а ошибка типизирована, чтобы обработчик ответил 409, а не общей ошибкой внешнего ключа
+func TestTicketTierDeleteWithBookings(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var eventID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 10) RETURNING id`,
+		fmt.Sprintf("tier delete %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	var userID int64
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'Tester') RETURNING id`,
+		fmt.Sprintf("tier-delete-%d@example.com", suffix),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM ticket_tiers WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	repo := NewTicketTierRepository(db)
+
+	booked := &entity.TicketTier{EventID: eventID, Name: "VIP", Price: 100, Seats: 5}
+	empty := &entity.TicketTier{EventID: eventID, Name: "Standard", Price: 50, Seats: 5}
+	for _, tier := range []*entity.TicketTier{booked, empty} {
+		if err := repo.Create(ctx, tier); err != nil {
+			t.Fatalf("failed to create tier: %v", err)
+		}
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO bookings (event_id, user_id, tier_id, seats, status, expires_at, reservation_timeout, total_price)
+		VALUES ($1, $2, $3, 1, 'confirmed', $4, 30, 100)`,
+		eventID, userID, booked.ID, time.Now().Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("failed to create booking: %v", err)
+	}
+
+	if err := repo.Delete(ctx, booked.ID); !errors.Is(err, entity.ErrTierHasBookings) {
+		t.Fatalf("Delete(booked tier) = %v, want %v", err, entity.ErrTierHasBookings)
+	}
+	if _, err := repo.GetByID(ctx, booked.ID); err != nil {
+		t.Fatalf("booked tier must survive a rejected delete: %v", err)
+	}
+
+	if err := repo.Delete(ctx, empty.ID); err != nil {
+		t.Fatalf("Delete(empty tier) = %v, want nil", err)
+	}
+}
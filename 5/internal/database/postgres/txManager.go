@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+	"github.com/ds124wfegd/WB_L3/5/pkg/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TxManager runs several repository calls in one transaction. Repositories pick the
+// transaction up from ctx, so services keep calling them the usual way inside fn.
+type TxManager interface {
+	// WithinTransaction commits when fn returns nil and rolls back otherwise.
+	// A nested call joins the transaction that is already in ctx.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type txManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) TxManager {
+	return &txManager{db: db}
+}
+
+func (m *txManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// conn is the database handle of a repository. Queries run in the transaction carried
+// by ctx when there is one and directly on the pool otherwise.
+type conn struct {
+	db *sql.DB
+	// replica serves the queries of read(); nil when there is no read replica
+	replica *conn
+}
+
+func newConn(db *sql.DB) *conn {
+	return &conn{db: db}
+}
+
+// newRoutedConn is a conn whose read() queries go to replica. A nil replica
+// leaves every query on db.
+func newRoutedConn(db, replica *sql.DB) *conn {
+	c := newConn(db)
+	if replica != nil {
+		c.replica = newConn(replica)
+	}
+	return c
+}
+
+// read returns the handle for heavy reads that tolerate replication lag: lists,
+// search and statistics. Reads that must see the caller's own writes use conn
+// directly. Inside a transaction the replica is skipped, the query joins the
+// transaction on the primary.
+func (c *conn) read(ctx context.Context) *conn {
+	if c.replica == nil {
+		return c
+	}
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return c
+	}
+	return c.replica
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	var result sql.Result
+	var err error
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		result, err = tx.ExecContext(ctx, tagQuery(ctx, query), args...)
+	} else {
+		result, err = c.db.ExecContext(ctx, tagQuery(ctx, query), args...)
+	}
+	tracing.RecordError(span, err)
+	return result, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	var rows *sql.Rows
+	var err error
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		rows, err = tx.QueryContext(ctx, tagQuery(ctx, query), args...)
+	} else {
+		rows, err = c.db.QueryContext(ctx, tagQuery(ctx, query), args...)
+	}
+	tracing.RecordError(span, err)
+	return rows, err
+}
+
+func (c *conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	var row *sql.Row
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		row = tx.QueryRowContext(ctx, tagQuery(ctx, query), args...)
+	} else {
+		row = c.db.QueryRowContext(ctx, tagQuery(ctx, query), args...)
+	}
+	recordRowError(span, row)
+	return row
+}
+
+// BeginTx starts the transaction of a single repository method. Inside WithinTransaction
+// the method joins the outer transaction instead: its Commit and Rollback are no-ops and
+// opts are ignored, the outer transaction decides the outcome.
+func (c *conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*repoTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &repoTx{Tx: tx}, nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &repoTx{Tx: tx, owned: true}, nil
+}
+
+// repoTx is a transaction opened by a repository method, or the outer one it joined
+type repoTx struct {
+	*sql.Tx
+	owned bool
+}
+
+func (t *repoTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *repoTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// Queries of a repository transaction are tagged and traced the same way as conn queries
+
+func (t *repoTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := t.Tx.ExecContext(ctx, tagQuery(ctx, query), args...)
+	tracing.RecordError(span, err)
+	return result, err
+}
+
+func (t *repoTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.Tx.QueryContext(ctx, tagQuery(ctx, query), args...)
+	tracing.RecordError(span, err)
+	return rows, err
+}
+
+func (t *repoTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := t.Tx.QueryRowContext(ctx, tagQuery(ctx, query), args...)
+	recordRowError(span, row)
+	return row
+}
+
+// tagQuery prefixes the query with the request ID as an SQL comment, so a booking's
+// queries can be found in pg_stat_activity and the Postgres log next to its HTTP request
+// and queue tasks. Only IDs that pass requestid.Valid are used: they cannot close the comment.
+func tagQuery(ctx context.Context, query string) string {
+	id := requestid.FromContext(ctx)
+	if id == "" || !requestid.Valid(id) {
+		return query
+	}
+	return "/* request_id=" + id + " */ " + query
+}
+
+// startQuerySpan opens a client span for a query that is part of a traced request or task.
+// Queries of background workers without a trace are not traced: each of them would
+// become a separate single-span trace. The span ends when the call returns, so for
+// QueryContext it covers the query up to the first row, not the iteration over rows.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	operation := queryOperation(query)
+	return tracing.Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(strings.Join(strings.Fields(query), " ")),
+		),
+	)
+}
+
+// recordRowError marks the span of QueryRowContext; sql.ErrNoRows is an expected result, not an error
+func recordRowError(span trace.Span, row *sql.Row) {
+	if err := row.Err(); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tracing.RecordError(span, err)
+	}
+}
+
+// queryOperation returns the first keyword of the query: SELECT, INSERT, WITH and so on
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
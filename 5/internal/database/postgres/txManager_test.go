@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// TestTxManagerRollsBackRepositoryCalls проверяет, что методы репозиториев, вызванные
+// внутри WithinTransaction, откатываются вместе с ней, хотя каждый открывает свою транзакцию
+func TestTxManagerRollsBackRepositoryCalls(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	var eventID, userID int64
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO events (title, description, date, total_seats) VALUES ($1, '', $2, 5) RETURNING id`,
+		fmt.Sprintf("tx manager %d", suffix), time.Now().Add(24*time.Hour),
+	).Scan(&eventID)
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`,
+		fmt.Sprintf("tx-manager-%d@example.com", suffix), "Tester",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM audit_log WHERE entity_type = 'booking' AND entity_id IN (SELECT id FROM bookings WHERE event_id = $1)`, eventID)
+		db.Exec(`DELETE FROM bookings WHERE event_id = $1`, eventID)
+		db.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+		db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	repo := NewBookingRepository(db, nil)
+	txManager := NewTxManager(db)
+	errAbort := errors.New("abort")
+
+	create := func(ctx context.Context) error {
+		booking := &entity.Booking{
+			EventID:            eventID,
+			UserID:             userID,
+			Seats:              1,
+			Status:             entity.BookingStatusPending,
+			ReservationTimeout: 30,
+		}
+		if err := repo.Create(ctx, booking); err != nil {
+			return err
+		}
+		return repo.UpdateStatus(ctx, booking.ID, entity.BookingStatusConfirmed)
+	}
+
+	err = txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := create(ctx); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithinTransaction = %v, want %v", err, errAbort)
+	}
+
+	booking, err := repo.GetByEventAndUser(ctx, eventID, userID)
+	if err != nil {
+		t.Fatalf("GetByEventAndUser: %v", err)
+	}
+	if booking != nil {
+		t.Fatalf("booking %d survived the rolled back transaction", booking.ID)
+	}
+
+	if err := txManager.WithinTransaction(ctx, create); err != nil {
+		t.Fatalf("WithinTransaction: %v", err)
+	}
+
+	booking, err = repo.GetByEventAndUser(ctx, eventID, userID)
+	if err != nil || booking == nil {
+		t.Fatalf("GetByEventAndUser = %v, %v; want committed booking", booking, err)
+	}
+	if booking.Status != entity.BookingStatusConfirmed {
+		t.Fatalf("status = %s, want %s", booking.Status, entity.BookingStatusConfirmed)
+	}
+}
+
+// TestConnReadRouting проверяет, что тяжёлые чтения уходят на реплику, но внутри
+// транзакции остаются в основной базе, а без реплики всё идёт в основную базу
+func TestConnReadRouting(t *testing.T) {
+	primary, replica := new(sql.DB), new(sql.DB)
+	ctx := context.Background()
+
+	if got := newRoutedConn(primary, nil).read(ctx).db; got != primary {
+		t.Error("read() without replica must use the primary")
+	}
+
+	c := newRoutedConn(primary, replica)
+	if got := c.read(ctx).db; got != replica {
+		t.Error("read() outside a transaction must use the replica")
+	}
+
+	txCtx := context.WithValue(ctx, txKey{}, new(sql.Tx))
+	if got := c.read(txCtx).db; got != primary {
+		t.Error("read() inside a transaction must use the primary")
+	}
+}
@@ -1,243 +1,434 @@
-package repository
-
-import (
-	"context"
-	"database/sql"
-	"fmt"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-type userRepository struct {
-	db *sql.DB
-}
-
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
-}
-
-func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
-	query := `
-		INSERT INTO users (email, name, telegram_id, created_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
-	`
-
-	return r.db.QueryRowContext(ctx, query,
-		user.Email,
-		user.Name,
-		user.TelegramID,
-		user.CreatedAt,
-	).Scan(&user.ID)
-}
-
-func (r *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
-	query := `
-		SELECT id, email, name, telegram_id, created_at
-		FROM users 
-		WHERE id = $1
-	`
-
-	var user entity.User
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.TelegramID,
-		&user.CreatedAt,
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
-}
-
-func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	query := `
-		SELECT id, email, name, telegram_id, created_at
-		FROM users 
-		WHERE email = $1
-	`
-
-	var user entity.User
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.TelegramID,
-		&user.CreatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
-}
-
-func (r *userRepository) GetByTelegramID(ctx context.Context, telegramID string) (*entity.User, error) {
-	query := `
-		SELECT id, email, name, telegram_id, created_at
-		FROM users 
-		WHERE telegram_id = $1
-	`
-
-	var user entity.User
-	err := r.db.QueryRowContext(ctx, query, telegramID).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Name,
-		&user.TelegramID,
-		&user.CreatedAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
-}
-
-func (r *userRepository) UpdateTelegramID(ctx context.Context, userID int64, telegramID string) error {
-	query := `UPDATE users SET telegram_id = $1 WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, telegramID, userID)
-	return err
-}
-
-func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
-	query := `
-		UPDATE users 
-		SET email = $1, name = $2, telegram_id = $3
-		WHERE id = $4
-	`
-
-	result, err := r.db.ExecContext(ctx, query,
-		user.Email,
-		user.Name,
-		user.TelegramID,
-		user.ID,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrUserNotFound
-	}
-
-	return nil
-}
-
-func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	// Сначала проверяем, есть ли у пользователя активные бронирования
-	var activeBookingsCount int
-	query := `SELECT COUNT(*) FROM bookings WHERE user_id = $1 AND status IN ('pending', 'confirmed')`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&activeBookingsCount)
-	if err != nil {
-		return fmt.Errorf("failed to check user bookings: %w", err)
-	}
-
-	if activeBookingsCount > 0 {
-		return fmt.Errorf("cannot delete user with active bookings")
-	}
-
-	// Удаляем пользователя
-	query = `DELETE FROM users WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return entity.ErrUserNotFound
-	}
-
-	return nil
-}
-
-func (r *userRepository) GetAll(ctx context.Context) ([]*entity.User, error) {
-	query := `
-		SELECT id, email, name, telegram_id, created_at
-		FROM users 
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query all users: %w", err)
-	}
-	defer rows.Close()
-
-	var users []*entity.User
-	for rows.Next() {
-		var user entity.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.Name,
-			&user.TelegramID,
-			&user.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
-		}
-		users = append(users, &user)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating users: %w", err)
-	}
-
-	return users, nil
-}
-
-func (r *userRepository) SearchByName(ctx context.Context, name string) ([]*entity.User, error) {
-	query := `
-		SELECT id, email, name, telegram_id, created_at
-		FROM users 
-		WHERE name ILIKE $1
-		ORDER BY name ASC
-	`
-
-	searchPattern := "%" + name + "%"
-	rows, err := r.db.QueryContext(ctx, query, searchPattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search users by name: %w", err)
-	}
-	defer rows.Close()
-
-	var users []*entity.User
-	for rows.Next() {
-		var user entity.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.Name,
-			&user.TelegramID,
-			&user.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
-		}
-		users = append(users, &user)
-	}
-
-	return users, nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type userRepository struct {
+	db *conn
+}
+
+// NewUserRepository читает список и поиск пользователей с replica, если она задана
+func NewUserRepository(db, replica *sql.DB) UserRepository {
+	return &userRepository{db: newRoutedConn(db, replica)}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	query := `
+		INSERT INTO users (email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	if user.Role == "" {
+		user.Role = entity.RoleUser
+	}
+
+	return r.db.QueryRowContext(ctx, query,
+		user.Email,
+		user.Name,
+		user.TelegramID,
+		user.Role,
+		user.PasswordHash,
+		user.CreatedAt,
+		user.NotifyEmail,
+		user.NotifyTelegram,
+		user.NotificationPreferences,
+	).Scan(&user.ID)
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users 
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	var user entity.User
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.TelegramID,
+		&user.Role,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.NotifyEmail,
+		&user.NotifyTelegram,
+		&user.NotificationPreferences,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users 
+		WHERE email = $1 AND deleted_at IS NULL
+	`
+
+	var user entity.User
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.TelegramID,
+		&user.Role,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.NotifyEmail,
+		&user.NotifyTelegram,
+		&user.NotificationPreferences,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) GetByTelegramID(ctx context.Context, telegramID string) (*entity.User, error) {
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users 
+		WHERE telegram_id = $1 AND deleted_at IS NULL
+	`
+
+	var user entity.User
+	err := r.db.QueryRowContext(ctx, query, telegramID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.TelegramID,
+		&user.Role,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.NotifyEmail,
+		&user.NotifyTelegram,
+		&user.NotificationPreferences,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (r *userRepository) UpdateTelegramID(ctx context.Context, userID int64, telegramID string) error {
+	query := `UPDATE users SET telegram_id = $1 WHERE id = $2 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, telegramID, userID)
+	return err
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, userID int64, role string) error {
+	query := `UPDATE users SET role = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateNotificationPreferences(ctx context.Context, userID int64, email, telegram bool, categories entity.NotificationPreferences) error {
+	query := `
+		UPDATE users SET notify_email = $1, notify_telegram = $2, notification_preferences = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, email, telegram, categories, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	query := `
+		UPDATE users 
+		SET email = $1, name = $2, telegram_id = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		user.Email,
+		user.Name,
+		user.TelegramID,
+		user.ID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Delete помечает пользователя удалённым; email освобождается для новой регистрации
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Сначала проверяем, есть ли у пользователя активные бронирования
+	var activeBookingsCount int
+	query := `SELECT COUNT(*) FROM bookings WHERE user_id = $1 AND status IN ('pending', 'confirmed') AND deleted_at IS NULL`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&activeBookingsCount)
+	if err != nil {
+		return fmt.Errorf("failed to check user bookings: %w", err)
+	}
+
+	if activeBookingsCount > 0 {
+		return fmt.Errorf("cannot delete user with active bookings")
+	}
+
+	// Помечаем пользователя удалённым
+	query = `UPDATE users SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	if err := insertAudit(ctx, tx, entity.NewAuditEntry(ctx, entity.AuditEntityUser, id, entity.AuditActionDeleted)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]*entity.User, error) {
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users 
+		WHERE deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.TelegramID,
+			&user.Role,
+			&user.PasswordHash,
+			&user.CreatedAt,
+			&user.NotifyEmail,
+			&user.NotifyTelegram,
+			&user.NotificationPreferences,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) SearchByName(ctx context.Context, name string) ([]*entity.User, error) {
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users 
+		WHERE name ILIKE $1 AND deleted_at IS NULL
+		ORDER BY name ASC
+	`
+
+	searchPattern := "%" + name + "%"
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, searchPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users by name: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.TelegramID,
+			&user.Role,
+			&user.PasswordHash,
+			&user.CreatedAt,
+			&user.NotifyEmail,
+			&user.NotifyTelegram,
+			&user.NotificationPreferences,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// Search ищет пользователей по подстроке имени и email (ILIKE) с пагинацией;
+// общее число считается отдельным запросом, чтобы оно не терялось на пустой странице
+func (r *userRepository) Search(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error) {
+	if filter == nil {
+		filter = &entity.UserFilter{}
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if name := strings.TrimSpace(filter.Name); name != "" {
+		conditions = append(conditions, "name ILIKE "+addArg("%"+name+"%"))
+	}
+	if email := strings.TrimSpace(filter.Email); email != "" {
+		conditions = append(conditions, "email ILIKE "+addArg("%"+email+"%"))
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.read(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `
+		SELECT id, email, name, telegram_id, role, password_hash, created_at, notify_email, notify_telegram, notification_preferences
+		FROM users
+	` + where + " ORDER BY name ASC, id ASC"
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + addArg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + addArg(filter.Offset)
+	}
+
+	rows, err := r.db.read(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.TelegramID,
+			&user.Role,
+			&user.PasswordHash,
+			&user.CreatedAt,
+			&user.NotifyEmail,
+			&user.NotifyTelegram,
+			&user.NotificationPreferences,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) GetCalendarTokenVersion(ctx context.Context, userID int64) (int, error) {
+	var version int
+	query := `SELECT calendar_token_version FROM users WHERE id = $1 AND deleted_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, entity.ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get calendar token version: %w", err)
+	}
+	return version, nil
+}
+
+func (r *userRepository) IncrementCalendarTokenVersion(ctx context.Context, userID int64) (int, error) {
+	var version int
+	query := `UPDATE users SET calendar_token_version = calendar_token_version + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING calendar_token_version`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, entity.ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke calendar token: %w", err)
+	}
+	return version, nil
+}
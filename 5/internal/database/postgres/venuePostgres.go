@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type venueRepository struct {
+	db *conn
+}
+
+func NewVenueRepository(db *sql.DB) VenueRepository {
+	return &venueRepository{db: newConn(db)}
+}
+
+const venueColumns = `id, name, address, latitude, longitude, capacity, created_at, updated_at`
+
+func (r *venueRepository) Create(ctx context.Context, venue *entity.Venue) error {
+	query := `
+		INSERT INTO venues (name, address, latitude, longitude, capacity, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		venue.Name,
+		venue.Address,
+		venue.Latitude,
+		venue.Longitude,
+		venue.Capacity,
+		now,
+		now,
+	).Scan(&venue.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create venue: %w", err)
+	}
+
+	venue.CreatedAt = now
+	venue.UpdatedAt = now
+	venue.MapURL = venue.MapLink()
+	return nil
+}
+
+func (r *venueRepository) GetByID(ctx context.Context, id int64) (*entity.Venue, error) {
+	query := `SELECT ` + venueColumns + ` FROM venues WHERE id = $1`
+
+	venue, err := scanVenue(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrVenueNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get venue: %w", err)
+	}
+
+	return venue, nil
+}
+
+// GetByIDs загружает площадки одним запросом, чтобы не делать запрос на каждое мероприятие в списке
+func (r *venueRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*entity.Venue, error) {
+	venues := make(map[int64]*entity.Venue, len(ids))
+	if len(ids) == 0 {
+		return venues, nil
+	}
+
+	query := `SELECT ` + venueColumns + ` FROM venues WHERE id = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query venues: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		venue, err := scanVenue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan venue: %w", err)
+		}
+		venues[venue.ID] = venue
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating venues: %w", err)
+	}
+
+	return venues, nil
+}
+
+func (r *venueRepository) GetAll(ctx context.Context) ([]*entity.Venue, error) {
+	query := `SELECT ` + venueColumns + ` FROM venues ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query venues: %w", err)
+	}
+	defer rows.Close()
+
+	venues := make([]*entity.Venue, 0)
+	for rows.Next() {
+		venue, err := scanVenue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan venue: %w", err)
+		}
+		venues = append(venues, venue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating venues: %w", err)
+	}
+
+	return venues, nil
+}
+
+func (r *venueRepository) Update(ctx context.Context, venue *entity.Venue) error {
+	query := `
+		UPDATE venues
+		SET name = $1, address = $2, latitude = $3, longitude = $4, capacity = $5, updated_at = $6
+		WHERE id = $7
+	`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		venue.Name,
+		venue.Address,
+		venue.Latitude,
+		venue.Longitude,
+		venue.Capacity,
+		now,
+		venue.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update venue: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrVenueNotFound
+	}
+
+	venue.UpdatedAt = now
+	venue.MapURL = venue.MapLink()
+	return nil
+}
+
+func (r *venueRepository) Delete(ctx context.Context, id int64) error {
+	// Площадку с мероприятиями удалять нельзя — сначала нужно перенести или удалить мероприятия
+	var eventCount int
+	query := `SELECT COUNT(*) FROM events WHERE venue_id = $1`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&eventCount); err != nil {
+		return fmt.Errorf("failed to check venue events: %w", err)
+	}
+
+	if eventCount > 0 {
+		return entity.ErrVenueInUse
+	}
+
+	query = `DELETE FROM venues WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete venue: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrVenueNotFound
+	}
+
+	return nil
+}
+
+// MaxEventSeats возвращает наибольшее число мест среди мероприятий площадки
+func (r *venueRepository) MaxEventSeats(ctx context.Context, id int64) (int, error) {
+	var seats int
+	query := `SELECT COALESCE(MAX(total_seats), 0) FROM events WHERE venue_id = $1`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&seats); err != nil {
+		return 0, fmt.Errorf("failed to get venue event seats: %w", err)
+	}
+	return seats, nil
+}
+
+type venueScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVenue(row venueScanner) (*entity.Venue, error) {
+	var venue entity.Venue
+	err := row.Scan(
+		&venue.ID,
+		&venue.Name,
+		&venue.Address,
+		&venue.Latitude,
+		&venue.Longitude,
+		&venue.Capacity,
+		&venue.CreatedAt,
+		&venue.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	venue.MapURL = venue.MapLink()
+	return &venue, nil
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/lib/pq"
+)
+
+type webhookRepository struct {
+	db *conn
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: newConn(db)}
+}
+
+const webhookColumns = `id, event_id, url, secret, event_types, active, created_at, updated_at`
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *entity.Webhook) error {
+	query := `
+		INSERT INTO webhooks (event_id, url, secret, event_types, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query,
+		webhook.EventID,
+		webhook.URL,
+		webhook.Secret,
+		pq.Array(webhook.EventTypes),
+		webhook.Active,
+		now,
+		now,
+	).Scan(&webhook.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	return nil
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id int64) (*entity.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *webhookRepository) GetAll(ctx context.Context) ([]*entity.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks ORDER BY created_at DESC`
+	return r.queryWebhooks(ctx, query)
+}
+
+func (r *webhookRepository) GetActiveForEvent(ctx context.Context, eventID int64) ([]*entity.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE active = TRUE AND (event_id IS NULL OR event_id = $1)
+		ORDER BY id
+	`
+	return r.queryWebhooks(ctx, query, eventID)
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) LogDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			webhook_id, delivery_id, event_type, payload, status,
+			attempts, response_status, error, duration_ms, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	err := r.db.QueryRowContext(ctx, query,
+		delivery.WebhookID,
+		delivery.DeliveryID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMs,
+		delivery.CreatedAt,
+	).Scan(&delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]*entity.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, delivery_id, event_type, payload, status,
+		       attempts, response_status, error, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*entity.WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery entity.WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.DeliveryID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.ResponseStatus,
+			&delivery.Error,
+			&delivery.DurationMs,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]*entity.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*entity.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.EventID,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.EventTypes),
+		&webhook.Active,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// adminFeedChannel - канал pub/sub ленты админки
+const adminFeedChannel = "event_booking:admin_feed"
+
+// AdminFeedBus рассылает события ленты админки всем репликам: задачу очереди выполняет одна
+// реплика, а администратор может быть подключён к любой. Как и у AvailabilityNotifier,
+// события, опубликованные без подписчиков, теряются.
+type AdminFeedBus struct {
+	client redis.UniversalClient
+}
+
+func NewAdminFeedBus(client redis.UniversalClient) *AdminFeedBus {
+	return &AdminFeedBus{client: client}
+}
+
+func (b *AdminFeedBus) Publish(ctx context.Context, event *entity.AdminFeedEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, adminFeedChannel, payload).Err()
+}
+
+// Listen вызывает handle для каждого события и блокируется до отмены ctx (тогда возвращает nil)
+// или закрытия подписки
+func (b *AdminFeedBus) Listen(ctx context.Context, handle func(event *entity.AdminFeedEvent)) error {
+	pubsub := b.client.Subscribe(ctx, adminFeedChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return ErrSubscriptionClosed
+			}
+			var event entity.AdminFeedEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Пропущено некорректное событие ленты админки: %v", err)
+				continue
+			}
+			handle(&event)
+		}
+	}
+}
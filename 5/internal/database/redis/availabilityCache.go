@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// availabilityKeyPrefix - занятость мест мероприятия, ключ на каждое мероприятие
+const availabilityKeyPrefix = "event_booking:availability:"
+
+// AvailabilityCache хранит занятость мест мероприятий (entity.SeatUsage) в Redis.
+// Ключи читаются и пишутся конвейером по одному, а не MGET/MSET: в Redis Cluster
+// ключи разных мероприятий лежат в разных слотах.
+type AvailabilityCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewAvailabilityCache(client redis.UniversalClient, ttl time.Duration) *AvailabilityCache {
+	return &AvailabilityCache{client: client, ttl: ttl}
+}
+
+// Get возвращает занятость найденных в кэше мероприятий; отсутствующих в результате нет
+func (c *AvailabilityCache) Get(ctx context.Context, eventIDs []int64) (map[int64]entity.SeatUsage, error) {
+	usage := make(map[int64]entity.SeatUsage, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return usage, nil
+	}
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(eventIDs))
+	for i, id := range eventIDs {
+		cmds[i] = pipe.Get(ctx, availabilityKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		var u entity.SeatUsage
+		// Повреждённое значение считается промахом и будет перезаписано
+		if json.Unmarshal(data, &u) == nil {
+			usage[eventIDs[i]] = u
+		}
+	}
+	return usage, nil
+}
+
+func (c *AvailabilityCache) Set(ctx context.Context, usage map[int64]entity.SeatUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	for id, u := range usage {
+		data, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, availabilityKey(id), data, c.ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *AvailabilityCache) Invalidate(ctx context.Context, eventIDs ...int64) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	for _, id := range eventIDs {
+		pipe.Del(ctx, availabilityKey(id))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func availabilityKey(eventID int64) string {
+	return availabilityKeyPrefix + strconv.FormatInt(eventID, 10)
+}
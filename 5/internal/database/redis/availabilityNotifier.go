@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// availabilityChannel - канал pub/sub, в который публикуются id мероприятий с изменившимися местами
+const availabilityChannel = "event_booking:availability_changed"
+
+// ErrSubscriptionClosed - подписка на уведомления закрылась, например при обрыве соединения
+var ErrSubscriptionClosed = errors.New("pub/sub subscription closed")
+
+// AvailabilityNotifier рассылает всем репликам сервиса id мероприятий, у которых изменились
+// свободные места. Сообщения pub/sub не хранятся: реплика, не подписанная в момент публикации,
+// его не получит, поэтому после переподключения подписчику нужно перечитать места сам.
+type AvailabilityNotifier struct {
+	client redis.UniversalClient
+}
+
+func NewAvailabilityNotifier(client redis.UniversalClient) *AvailabilityNotifier {
+	return &AvailabilityNotifier{client: client}
+}
+
+// Publish отправляет по сообщению на каждое мероприятие
+func (n *AvailabilityNotifier) Publish(ctx context.Context, eventIDs ...int64) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	pipe := n.client.Pipeline()
+	for _, id := range eventIDs {
+		pipe.Publish(ctx, availabilityChannel, strconv.FormatInt(id, 10))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Listen вызывает handle для каждого полученного id мероприятия и блокируется до отмены ctx
+// (тогда возвращает nil) или закрытия подписки
+func (n *AvailabilityNotifier) Listen(ctx context.Context, handle func(eventID int64)) error {
+	pubsub := n.client.Subscribe(ctx, availabilityChannel)
+	defer pubsub.Close()
+
+	// Receive дожидается подтверждения подписки, чтобы ошибка соединения вернулась сразу
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return ErrSubscriptionClosed
+			}
+			id, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			handle(id)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Темы ленты админки, на которые подписывается клиент /ws/admin
+const (
+	AdminFeedBookings      = "bookings"      // новые бронирования
+	AdminFeedConfirmations = "confirmations" // подтверждения (оплаты)
+	AdminFeedExpirations   = "expirations"   // истёкшие бронирования
+	AdminFeedDLQ           = "dlq"           // задачи, исчерпавшие попытки
+)
+
+// AdminFeedTopics - все темы ленты
+var AdminFeedTopics = []string{AdminFeedBookings, AdminFeedConfirmations, AdminFeedExpirations, AdminFeedDLQ}
+
+// AdminFeedEvent - событие ленты админки. Для бронирований Data повторяет полезную нагрузку
+// вебхука, для DLQ содержит задачу и ошибку
+type AdminFeedEvent struct {
+	Topic     string                 `json:"topic"`
+	Type      string                 `json:"type"` // тип вебхука (booking.created) или dlq.task_failed
+	EventID   int64                  `json:"event_id,omitempty"`
+	BookingID int64                  `json:"booking_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	At        time.Time              `json:"at"`
+}
+
+// ParseAdminFeedTopics разбирает список тем через запятую; пустой список - все темы
+func ParseAdminFeedTopics(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return AdminFeedTopics, nil
+	}
+
+	var topics []string
+	for _, topic := range strings.Split(raw, ",") {
+		topic = strings.TrimSpace(topic)
+		if !IsAdminFeedTopic(topic) {
+			return nil, fmt.Errorf("%w: unknown admin feed topic %q", ErrInvalidInput, topic)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
+
+func IsAdminFeedTopic(topic string) bool {
+	for _, known := range AdminFeedTopics {
+		if topic == known {
+			return true
+		}
+	}
+	return false
+}
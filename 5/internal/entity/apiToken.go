@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+// Права API-токенов организаторов
+const (
+	ScopeEventsWrite  = "events:write"  // создание мероприятий от имени организатора
+	ScopeBookingsRead = "bookings:read" // чтение бронирований на мероприятия организатора
+)
+
+// APITokenScopes - все допустимые права
+var APITokenScopes = []string{
+	ScopeEventsWrite,
+	ScopeBookingsRead,
+}
+
+// APIToken - токен внешней интеграции организатора. Сам токен не хранится,
+// только его SHA-256; по Prefix токен можно узнать в списке.
+type APIToken struct {
+	ID          int64      `json:"id" db:"id"`
+	OrganizerID int64      `json:"organizer_id" db:"organizer_id"`
+	Name        string     `json:"name" db:"name"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	RateLimit   int        `json:"rate_limit" db:"rate_limit"` // запросов в минуту
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HasScope проверяет, выдано ли токену право
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active сообщает, можно ли ещё пользоваться токеном
+func (t *APIToken) Active(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// APITokenWithSecret возвращается один раз при выпуске токена. Поле token входит
+// в logging.redact_fields, поэтому открытое значение не попадает в журнал запросов.
+type APITokenWithSecret struct {
+	APIToken
+	Token string `json:"token"`
+}
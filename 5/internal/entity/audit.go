@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// Сущности и действия журнала аудита
+const (
+	AuditEntityBooking = "booking"
+	AuditEntityEvent   = "event"
+	AuditEntityUser    = "user"
+
+	AuditActionCreated         = "created"
+	AuditActionStatusChanged   = "status_changed"
+	AuditActionDeleted         = "deleted"
+	AuditActionCheckedIn       = "checked_in"
+	AuditActionImpersonated    = "impersonated"
+	AuditActionCapacityChanged = "capacity_changed"
+)
+
+// Виды инициаторов изменений помимо ролей пользователей
+const (
+	ActorSystem   = "system"    // планировщик, фоновые обработчики, неаутентифицированные запросы
+	ActorAPIToken = "api_token" // интеграция организатора
+	ActorTelegram = "telegram"  // кнопки и команды бота
+)
+
+// AuditEntry - запись журнала аудита: кто, когда и что сделал с сущностью
+type AuditEntry struct {
+	ID         int64     `json:"id" db:"id"`
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   int64     `json:"entity_id" db:"entity_id"`
+	Action     string    `json:"action" db:"action"`
+	OldStatus  string    `json:"old_status,omitempty" db:"old_status"`
+	NewStatus  string    `json:"new_status,omitempty" db:"new_status"`
+	ActorID    *int64    `json:"actor_id,omitempty" db:"actor_id"`
+	Actor      string    `json:"actor" db:"actor"` // роль пользователя или один из Actor*
+	Reason     string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	// Администратор, действовавший от имени ActorID по токену имперсонации
+	ImpersonatorID *int64 `json:"impersonator_id,omitempty" db:"impersonator_id"`
+}
+
+// AuditActor - инициатор изменения, который передаётся до репозиториев через контекст
+type AuditActor struct {
+	ID   *int64
+	Kind string
+
+	// Администратор, который действует от имени пользователя ID по токену имперсонации
+	ImpersonatorID *int64
+}
+
+// StaffAssisted сообщает, что изменение за пользователя выполнил сотрудник
+func (a AuditActor) StaffAssisted() bool {
+	return a.ImpersonatorID != nil
+}
+
+// StaffAssistedNotice добавляется в уведомления об изменениях, выполненных сотрудником за пользователя
+const StaffAssistedNotice = "Изменение оформил сотрудник поддержки по вашему обращению."
+
+type auditActorKey struct{}
+type auditReasonKey struct{}
+
+// WithAuditActor запоминает в контексте, от чьего имени выполняется запрос
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFromContext возвращает инициатора; без него изменение приписывается системе
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	if actor, ok := ctx.Value(auditActorKey{}).(AuditActor); ok && actor.Kind != "" {
+		return actor
+	}
+	return AuditActor{Kind: ActorSystem}
+}
+
+// WithAuditReason запоминает причину изменения, например отмены бронирования
+func WithAuditReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, auditReasonKey{}, reason)
+}
+
+// AuditReasonFromContext возвращает причину изменения или пустую строку
+func AuditReasonFromContext(ctx context.Context) string {
+	reason, _ := ctx.Value(auditReasonKey{}).(string)
+	return reason
+}
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion запоминает версию записи, которую видел клиент: изменение статуса
+// завершится ErrConflict, если с тех пор запись успели изменить
+func WithExpectedVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// ExpectedVersionFromContext возвращает ожидаемую версию записи, если клиент её передал
+func ExpectedVersionFromContext(ctx context.Context) (int, bool) {
+	version, ok := ctx.Value(expectedVersionKey{}).(int)
+	return version, ok
+}
+
+// NewAuditEntry заполняет запись инициатором и причиной из контекста
+func NewAuditEntry(ctx context.Context, entityType string, entityID int64, action string) *AuditEntry {
+	actor := AuditActorFromContext(ctx)
+	return &AuditEntry{
+		EntityType:     entityType,
+		EntityID:       entityID,
+		Action:         action,
+		ActorID:        actor.ID,
+		Actor:          actor.Kind,
+		Reason:         AuditReasonFromContext(ctx),
+		ImpersonatorID: actor.ImpersonatorID,
+	}
+}
@@ -1,37 +1,75 @@
-package entity
-
-import (
-	"time"
-)
-
-type BookingStatus string
-
-const (
-	BookingStatusPending   BookingStatus = "pending"
-	BookingStatusConfirmed BookingStatus = "confirmed"
-	BookingStatusCancelled BookingStatus = "cancelled"
-	BookingStatusExpired   BookingStatus = "expired"
-)
-
-type Booking struct {
-	ID                 int64         `json:"id" db:"id"`
-	EventID            int64         `json:"event_id" db:"event_id"`
-	UserID             int64         `json:"user_id" db:"user_id"`
-	Seats              int           `json:"seats" db:"seats"`
-	Status             BookingStatus `json:"status" db:"status"`
-	ExpiresAt          time.Time     `json:"expires_at" db:"expires_at"`
-	ReservationTimeout int           `json:"reservation_timeout" db:"reservation_timeout"`
-	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
-}
-
-type BookingExpiration struct {
-	BookingID  int64     `json:"booking_id"`
-	ExpiresAt  time.Time `json:"expires_at"`
-	UserID     int64     `json:"user_id"`
-	EventID    int64     `json:"event_id"`
-	TelegramID string    `json:"telegram_id"`
-	UserName   string    `json:"user_name"`
-	EventTitle string    `json:"event_title"`
-	Seats      int       `json:"seats"`
-}
+package entity
+
+import (
+	"time"
+)
+
+type BookingStatus string
+
+const (
+	BookingStatusPending   BookingStatus = "pending"
+	BookingStatusConfirmed BookingStatus = "confirmed"
+	BookingStatusCancelled BookingStatus = "cancelled"
+	BookingStatusExpired   BookingStatus = "expired"
+)
+
+type Booking struct {
+	ID                 int64         `json:"id" db:"id"`
+	EventID            int64         `json:"event_id" db:"event_id"`
+	UserID             int64         `json:"user_id" db:"user_id"`
+	Seats              int           `json:"seats" db:"seats"`
+	Status             BookingStatus `json:"status" db:"status"`
+	ExpiresAt          time.Time     `json:"expires_at" db:"expires_at"`
+	ReservationTimeout int           `json:"reservation_timeout" db:"reservation_timeout"`
+	TierID             *int64        `json:"tier_id,omitempty" db:"tier_id"`
+	PoolID             *int64        `json:"pool_id,omitempty" db:"pool_id"` // партнёрский пул, из которого выделены места
+	TotalPrice         float64       `json:"total_price" db:"total_price"`
+	PromoCodeID        *int64        `json:"promo_code_id,omitempty" db:"promo_code_id"`
+	DiscountAmount     float64       `json:"discount_amount" db:"discount_amount"`
+	CheckedInAt        *time.Time    `json:"checked_in_at,omitempty" db:"checked_in_at"` // отметка прохода по билету
+	Version            int           `json:"version" db:"version"`                       // растёт при каждом изменении брони
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type BookingExpiration struct {
+	BookingID  int64     `json:"booking_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserID     int64     `json:"user_id"`
+	EventID    int64     `json:"event_id"`
+	TelegramID string    `json:"telegram_id"`
+	UserName   string    `json:"user_name"`
+	EventTitle string    `json:"event_title"`
+	Seats      int       `json:"seats"`
+}
+
+// BookingExportRow - строка выгрузки бронирований вместе с данными пользователя и мероприятия
+type BookingExportRow struct {
+	BookingID      int64
+	Status         BookingStatus
+	Seats          int
+	TierName       string
+	PromoCode      string
+	TotalPrice     float64
+	DiscountAmount float64
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	EventID        int64
+	EventTitle     string
+	EventDate      time.Time
+	UserID         int64
+	UserName       string
+	UserEmail      string
+}
+
+// CalendarEntry - подтверждённое бронирование вместе с мероприятием для календарной подписки
+type CalendarEntry struct {
+	BookingID        int64
+	Seats            int
+	EventID          int64
+	EventTitle       string
+	EventDescription string
+	EventLocation    string
+	EventDate        time.Time
+	UpdatedAt        time.Time
+}
@@ -0,0 +1,70 @@
+package entity
+
+import "fmt"
+
+// bookingTransitions - единственное место, где описаны допустимые переходы статусов бронирования.
+// Cancelled и expired - конечные статусы, из них перейти никуда нельзя.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled, BookingStatusExpired},
+	BookingStatusConfirmed: {BookingStatusCancelled},
+}
+
+// Valid сообщает, является ли значение известным статусом бронирования
+func (s BookingStatus) Valid() bool {
+	switch s {
+	case BookingStatusPending, BookingStatusConfirmed, BookingStatusCancelled, BookingStatusExpired:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo сообщает, разрешён ли переход из статуса s в next
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError - запрещённый переход статуса; errors.Is(err, ErrInvalidTransition) == true
+type TransitionError struct {
+	From BookingStatus
+	To   BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// ValidateTransition проверяет переход from -> to.
+// Повторный перевод в тот же статус возвращает ErrStatusUnchanged, чтобы вызывающий
+// код мог ответить текущим состоянием без повторных побочных эффектов.
+func ValidateTransition(from, to BookingStatus) error {
+	if !to.Valid() {
+		return ErrInvalidBookingStatus
+	}
+	if from == to {
+		return ErrStatusUnchanged
+	}
+	if !from.CanTransitionTo(to) {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// TransitionSources возвращает статусы, из которых разрешён переход в to
+func TransitionSources(to BookingStatus) []BookingStatus {
+	var sources []BookingStatus
+	for from := range bookingTransitions {
+		if from.CanTransitionTo(to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
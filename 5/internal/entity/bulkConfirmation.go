@@ -0,0 +1,36 @@
+package entity
+
+// MaxBulkConfirmation - сколько бронирований подтверждается одним запросом кассы
+const MaxBulkConfirmation = 100
+
+// BulkConfirmOutcome - итог подтверждения одного бронирования из пакета
+type BulkConfirmOutcome string
+
+const (
+	BulkConfirmConfirmed        BulkConfirmOutcome = "confirmed"
+	BulkConfirmAlreadyConfirmed BulkConfirmOutcome = "already_confirmed"
+	BulkConfirmNotFound         BulkConfirmOutcome = "not_found"
+	BulkConfirmExpired          BulkConfirmOutcome = "expired"        // срок брони истёк, она переведена в expired
+	BulkConfirmInvalidStatus    BulkConfirmOutcome = "invalid_status" // отменена или истекла раньше
+	BulkConfirmNotEnoughSeats   BulkConfirmOutcome = "not_enough_seats"
+)
+
+// BulkConfirmResult - результат по одному ID в порядке запроса
+type BulkConfirmResult struct {
+	BookingID int64              `json:"booking_id"`
+	Outcome   BulkConfirmOutcome `json:"outcome"`
+	Status    BookingStatus      `json:"status,omitempty"` // статус бронирования после запроса
+	Error     string             `json:"error,omitempty"`
+}
+
+// Succeeded - бронирование подтверждено этим или более ранним запросом
+func (r BulkConfirmResult) Succeeded() bool {
+	return r.Outcome == BulkConfirmConfirmed || r.Outcome == BulkConfirmAlreadyConfirmed
+}
+
+// BulkConfirmReport - итог пакетного подтверждения оплат, принятых кассой офлайн
+type BulkConfirmReport struct {
+	Confirmed int                 `json:"confirmed"` // подтверждено этим запросом
+	Failed    int                 `json:"failed"`
+	Results   []BulkConfirmResult `json:"results"`
+}
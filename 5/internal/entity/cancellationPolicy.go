@@ -0,0 +1,98 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	DefaultFreeCancellationHours = 24
+	DefaultLateRefundPercent     = 50
+)
+
+// CancellationPolicy задаёт условия отмены бронирования пользователем:
+// до FreeCancellationHours часов до начала мероприятия отмена бесплатная,
+// позже возвращается LateRefundPercent процентов стоимости.
+// Если заданы RefundRules, возврат считается по ним, а два поля выше не используются.
+type CancellationPolicy struct {
+	FreeCancellationHours int         `json:"free_cancellation_hours" db:"free_cancellation_hours"`
+	LateRefundPercent     float64     `json:"late_refund_percent" db:"late_refund_percent"`
+	RefundRules           RefundRules `json:"refund_rules,omitempty" db:"refund_rules"`
+}
+
+// CancellationQuote - результат применения политики отмены к конкретному моменту
+type CancellationQuote struct {
+	Allowed       bool      `json:"allowed"`
+	Free          bool      `json:"free"`
+	RefundPercent float64   `json:"refund_percent"`
+	RefundAmount  float64   `json:"refund_amount"`
+	FreeUntil     time.Time `json:"free_until"`
+	EvaluatedAt   time.Time `json:"evaluated_at"`
+}
+
+func DefaultCancellationPolicy() CancellationPolicy {
+	return CancellationPolicy{
+		FreeCancellationHours: DefaultFreeCancellationHours,
+		LateRefundPercent:     DefaultLateRefundPercent,
+	}
+}
+
+func (p CancellationPolicy) Validate() error {
+	if p.FreeCancellationHours < 0 {
+		return fmt.Errorf("%w: free cancellation hours cannot be negative", ErrInvalidInput)
+	}
+	if p.LateRefundPercent < 0 || p.LateRefundPercent > 100 {
+		return fmt.Errorf("%w: late refund percent must be between 0 and 100", ErrInvalidInput)
+	}
+	return p.RefundRules.Validate()
+}
+
+// Rules возвращает действующие ступени возврата от самой ранней к самой поздней.
+// Без RefundRules политика сводится к двум ступеням: полный возврат и LateRefundPercent.
+func (p CancellationPolicy) Rules() RefundRules {
+	if len(p.RefundRules) > 0 {
+		return p.RefundRules.sorted()
+	}
+	return RefundRules{
+		{HoursBefore: p.FreeCancellationHours, Percent: 100},
+		{HoursBefore: 0, Percent: p.LateRefundPercent},
+	}
+}
+
+// FreeUntil возвращает последний момент отмены с полным возвратом;
+// нулевое время, если полного возврата политика не предусматривает
+func (p CancellationPolicy) FreeUntil(eventDate time.Time) time.Time {
+	var freeUntil time.Time
+	for _, rule := range p.Rules() {
+		if rule.Percent >= 100 {
+			freeUntil = eventDate.Add(-time.Duration(rule.HoursBefore) * time.Hour)
+		}
+	}
+	return freeUntil
+}
+
+// Evaluate рассчитывает возврат за оплаченную сумму paid при отмене в момент now.
+// После начала мероприятия отмена невозможна.
+func (p CancellationPolicy) Evaluate(eventDate time.Time, paid float64, now time.Time) CancellationQuote {
+	quote := CancellationQuote{
+		Allowed:     now.Before(eventDate),
+		FreeUntil:   p.FreeUntil(eventDate),
+		EvaluatedAt: now,
+	}
+	if !quote.Allowed {
+		return quote
+	}
+
+	// Действует самая ранняя ступень, срок которой ещё не прошёл
+	for _, rule := range p.Rules() {
+		if !now.After(eventDate.Add(-time.Duration(rule.HoursBefore) * time.Hour)) {
+			quote.RefundPercent = rule.Percent
+			break
+		}
+	}
+	quote.Free = quote.RefundPercent >= 100
+	quote.RefundAmount = math.Round(paid*quote.RefundPercent) / 100
+
+	return quote
+}
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestCancellationPolicyRules проверяет, что без RefundRules политика сводится к двум ступеням,
+// а заданные ступени возвращаются от самой ранней к самой поздней
+func TestCancellationPolicyRules(t *testing.T) {
+	legacy := CancellationPolicy{FreeCancellationHours: 48, LateRefundPercent: 30}
+	want := RefundRules{{HoursBefore: 48, Percent: 100}, {HoursBefore: 0, Percent: 30}}
+	if got := legacy.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("legacy Rules() = %v, want %v", got, want)
+	}
+
+	tiered := CancellationPolicy{
+		FreeCancellationHours: 48,
+		RefundRules:           RefundRules{{HoursBefore: 2, Percent: 10}, {HoursBefore: 72, Percent: 100}, {HoursBefore: 24, Percent: 50}},
+	}
+	want = RefundRules{{HoursBefore: 72, Percent: 100}, {HoursBefore: 24, Percent: 50}, {HoursBefore: 2, Percent: 10}}
+	if got := tiered.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("tiered Rules() = %v, want %v", got, want)
+	}
+	if tiered.RefundRules[0].HoursBefore != 2 {
+		t.Error("Rules() must not reorder the policy's own rules")
+	}
+}
+
+// TestCancellationPolicyEvaluate проверяет возврат на каждой ступени, на границе ступени
+// и после начала мероприятия
+func TestCancellationPolicyEvaluate(t *testing.T) {
+	eventDate := time.Date(2026, 11, 20, 19, 0, 0, 0, time.UTC)
+	policy := CancellationPolicy{
+		RefundRules: RefundRules{{HoursBefore: 72, Percent: 100}, {HoursBefore: 24, Percent: 50}, {HoursBefore: 2, Percent: 10}},
+	}
+
+	cases := []struct {
+		name        string
+		before      time.Duration
+		wantAllowed bool
+		wantPercent float64
+		wantAmount  float64
+	}{
+		{"a week before", 7 * 24 * time.Hour, true, 100, 99.99},
+		{"exactly at the free deadline", 72 * time.Hour, true, 100, 99.99},
+		{"two days before", 48 * time.Hour, true, 50, 50},
+		{"three hours before", 3 * time.Hour, true, 10, 10},
+		{"an hour before", time.Hour, true, 0, 0},
+		{"after the start", -time.Minute, false, 0, 0},
+	}
+
+	for _, tc := range cases {
+		quote := policy.Evaluate(eventDate, 99.99, eventDate.Add(-tc.before))
+		if quote.Allowed != tc.wantAllowed || quote.RefundPercent != tc.wantPercent || quote.RefundAmount != tc.wantAmount {
+			t.Errorf("%s: quote = %+v, want allowed=%v percent=%v amount=%v",
+				tc.name, quote, tc.wantAllowed, tc.wantPercent, tc.wantAmount)
+		}
+		if quote.Free != (tc.wantPercent >= 100) {
+			t.Errorf("%s: Free = %v", tc.name, quote.Free)
+		}
+		if want := eventDate.Add(-72 * time.Hour); !quote.FreeUntil.Equal(want) {
+			t.Errorf("%s: FreeUntil = %v, want %v", tc.name, quote.FreeUntil, want)
+		}
+	}
+
+	// Без полного возврата бесплатной отмены нет
+	partial := CancellationPolicy{RefundRules: RefundRules{{HoursBefore: 24, Percent: 80}}}
+	if freeUntil := partial.FreeUntil(eventDate); !freeUntil.IsZero() {
+		t.Errorf("FreeUntil without a full refund = %v, want zero", freeUntil)
+	}
+}
+
+// TestRefundRulesValidate проверяет границы ступеней и запрет двух ступеней с одним сроком
+func TestRefundRulesValidate(t *testing.T) {
+	valid := RefundRules{{HoursBefore: 24, Percent: 100}, {HoursBefore: 0, Percent: 0}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%v) = %v", valid, err)
+	}
+
+	for _, rules := range []RefundRules{
+		{{HoursBefore: -1, Percent: 50}},
+		{{HoursBefore: 24, Percent: 101}},
+		{{HoursBefore: 24, Percent: -5}},
+		{{HoursBefore: 24, Percent: 100}, {HoursBefore: 24, Percent: 50}},
+	} {
+		if err := rules.Validate(); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Validate(%v) = %v, want %v", rules, err, ErrInvalidInput)
+		}
+	}
+
+	policy := CancellationPolicy{LateRefundPercent: 50, RefundRules: RefundRules{{HoursBefore: -1, Percent: 50}}}
+	if err := policy.Validate(); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("policy with an invalid rule: Validate() = %v", err)
+	}
+}
+
+// TestRefundRulesValueScan проверяет хранение ступеней в JSONB: пустой список записывается
+// как [], а NULL читается как отсутствие ступеней
+func TestRefundRulesValueScan(t *testing.T) {
+	rules := RefundRules{{HoursBefore: 24, Percent: 100}, {HoursBefore: 2, Percent: 25}}
+	value, err := rules.Value()
+	if err != nil {
+		t.Fatalf("Value() = %v", err)
+	}
+
+	var scanned RefundRules
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("Scan(%s) = %v", value, err)
+	}
+	if !reflect.DeepEqual(scanned, rules) {
+		t.Errorf("round trip = %v, want %v", scanned, rules)
+	}
+
+	if value, _ := RefundRules(nil).Value(); string(value.([]byte)) != "[]" {
+		t.Errorf("nil rules stored as %s, want []", value)
+	}
+	if err := scanned.Scan(nil); err != nil || scanned != nil {
+		t.Errorf("Scan(nil) = %v, rules %v", err, scanned)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Error("Scan(int) must fail")
+	}
+}
@@ -0,0 +1,28 @@
+package entity
+
+// CapacitySoldOut - порог заполненности, при котором в общей продаже не осталось мест
+const CapacitySoldOut = 100
+
+// OccupiedSeats - места, недоступные в общей продаже: подтверждённые бронирования,
+// свободные квоты партнёрских пулов и холды. При 100% мероприятие распродано.
+func (e *EventWithAvailability) OccupiedSeats() int {
+	return e.TotalSeats - e.AvailableSeats
+}
+
+// CrossedCapacityThresholds возвращает пороги в процентах, которые заполненность мероприятия
+// вместимостью totalSeats пересекает при росте занятых мест с before до after
+func CrossedCapacityThresholds(thresholds []int, totalSeats, before, after int) []int {
+	if totalSeats <= 0 || after <= before {
+		return nil
+	}
+
+	var crossed []int
+	for _, threshold := range thresholds {
+		// Сравнение в целых числах: 80% от 7 мест - это 5.6, порог пересекает шестое место
+		limit := threshold * totalSeats
+		if before*100 < limit && after*100 >= limit {
+			crossed = append(crossed, threshold)
+		}
+	}
+	return crossed
+}
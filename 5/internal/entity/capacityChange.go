@@ -0,0 +1,155 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// CapacityPolicy - как разрешается уменьшение вместимости ниже уже занятых мест
+type CapacityPolicy string
+
+const (
+	// CapacityKeepEarliest оставляет бронирования в порядке оформления, пока они помещаются;
+	// непоместившееся отменяется, но более позднее и меньшее ещё может остаться
+	CapacityKeepEarliest CapacityPolicy = "keep_earliest"
+	// CapacityRefundLatest отменяет самые поздние бронирования, пока остальные не поместятся
+	CapacityRefundLatest CapacityPolicy = "refund_latest"
+	// CapacityWaitlist отменяет те же бронирования, что refund_latest, и ставит их владельцев в лист ожидания
+	CapacityWaitlist CapacityPolicy = "waitlist"
+)
+
+// Что стало с бронированием, не поместившимся в новую вместимость
+const (
+	CapacityResolutionCancelled  = "cancelled"
+	CapacityResolutionWaitlisted = "waitlisted"
+)
+
+func (p CapacityPolicy) Validate() error {
+	switch p {
+	case CapacityKeepEarliest, CapacityRefundLatest, CapacityWaitlist:
+		return nil
+	}
+	return ErrInvalidCapacityPolicy
+}
+
+// CapacityDemand - что занимает места мероприятия в момент изменения вместимости
+type CapacityDemand struct {
+	EventID       int64
+	TotalSeats    int
+	Version       int
+	ReservedSeats int        // партнёрские пулы и холды целиком: их организатор снимает сам
+	Bookings      []*Booking // подтверждённые и неистёкшие pending-бронирования вне пулов, от ранних к поздним
+}
+
+// RequiredSeats - сколько мест нужно, чтобы не трогать ни одно бронирование
+func (d *CapacityDemand) RequiredSeats() int {
+	seats := d.ReservedSeats
+	for _, booking := range d.Bookings {
+		seats += booking.Seats
+	}
+	return seats
+}
+
+// AffectedBooking - бронирование, которое не помещается в новую вместимость.
+// Оплаченное возвращается полностью: места отменяет организатор, а не покупатель.
+type AffectedBooking struct {
+	BookingID    int64         `json:"booking_id"`
+	UserID       int64         `json:"user_id"`
+	Seats        int           `json:"seats"`
+	Status       BookingStatus `json:"status"`
+	CreatedAt    time.Time     `json:"created_at"`
+	RefundAmount float64       `json:"refund_amount"`
+	Resolution   string        `json:"resolution"`
+}
+
+// CapacityPlan - изменение вместимости и его последствия; Applied == false - только предпросмотр
+type CapacityPlan struct {
+	EventID       int64             `json:"event_id"`
+	Policy        CapacityPolicy    `json:"policy"`
+	CurrentSeats  int               `json:"current_total_seats"`
+	TotalSeats    int               `json:"total_seats"`
+	RequiredSeats int               `json:"required_seats"`
+	ReleasedSeats int               `json:"released_seats"`
+	RefundTotal   float64           `json:"refund_total"`
+	Affected      []AffectedBooking `json:"affected"`
+	Applied       bool              `json:"applied"`
+}
+
+// PlanCapacityChange выбирает бронирования, которые придётся отменить при вместимости totalSeats.
+// Если места хватает всем, список пуст; ниже пулов и холдов вместимость не опускается.
+func PlanCapacityChange(demand *CapacityDemand, totalSeats int, policy CapacityPolicy) (*CapacityPlan, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if totalSeats < 1 {
+		return nil, ErrInvalidCapacity
+	}
+	if totalSeats < demand.ReservedSeats {
+		return nil, fmt.Errorf("%w: partner pools and holds take %d seats", ErrCapacityBelowReserved, demand.ReservedSeats)
+	}
+
+	plan := &CapacityPlan{
+		EventID:       demand.EventID,
+		Policy:        policy,
+		CurrentSeats:  demand.TotalSeats,
+		TotalSeats:    totalSeats,
+		RequiredSeats: demand.RequiredSeats(),
+		Affected:      []AffectedBooking{},
+	}
+	available := totalSeats - demand.ReservedSeats
+
+	var affected []*Booking
+	switch policy {
+	case CapacityKeepEarliest:
+		used := 0
+		for _, booking := range demand.Bookings {
+			if used+booking.Seats <= available {
+				used += booking.Seats
+				continue
+			}
+			affected = append(affected, booking)
+		}
+	default:
+		excess := plan.RequiredSeats - totalSeats
+		for i := len(demand.Bookings) - 1; i >= 0 && excess > 0; i-- {
+			affected = append(affected, demand.Bookings[i])
+			excess -= demand.Bookings[i].Seats
+		}
+	}
+
+	resolution := CapacityResolutionCancelled
+	if policy == CapacityWaitlist {
+		resolution = CapacityResolutionWaitlisted
+	}
+	for _, booking := range affected {
+		refund := 0.0
+		if booking.Status == BookingStatusConfirmed {
+			refund = booking.TotalPrice
+		}
+		plan.Affected = append(plan.Affected, AffectedBooking{
+			BookingID:    booking.ID,
+			UserID:       booking.UserID,
+			Seats:        booking.Seats,
+			Status:       booking.Status,
+			CreatedAt:    booking.CreatedAt,
+			RefundAmount: refund,
+			Resolution:   resolution,
+		})
+		plan.ReleasedSeats += booking.Seats
+		plan.RefundTotal += refund
+	}
+
+	return plan, nil
+}
+
+// WaitlistEntry - владелец бронирования, отменённого при уменьшении вместимости с политикой waitlist.
+// Position - место в очереди мероприятия, начиная с 1.
+type WaitlistEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	BookingID int64     `json:"booking_id" db:"booking_id"`
+	Seats     int       `json:"seats" db:"seats"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
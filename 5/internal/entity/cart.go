@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+)
+
+type CartStatus string
+
+const (
+	CartStatusOpen       CartStatus = "open"
+	CartStatusCheckedOut CartStatus = "checked_out"
+	CartStatusExpired    CartStatus = "expired"
+)
+
+// Cart - корзина пользователя: позиции на разные мероприятия, которые бронируются одной транзакцией.
+// Открытая корзина у пользователя одна; без изменений до ExpiresAt она считается брошенной.
+type Cart struct {
+	ID        int64       `json:"id" db:"id"`
+	UserID    int64       `json:"user_id" db:"user_id"`
+	Status    CartStatus  `json:"status" db:"status"`
+	ExpiresAt time.Time   `json:"expires_at" db:"expires_at"`
+	Items     []*CartItem `json:"items"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// CartItem - позиция корзины; места не резервируются до оформления
+type CartItem struct {
+	ID        int64     `json:"id" db:"id"`
+	CartID    int64     `json:"cart_id" db:"cart_id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	Seats     int       `json:"seats" db:"seats"`
+	TierID    *int64    `json:"tier_id,omitempty" db:"tier_id"`
+	PromoCode string    `json:"promo_code,omitempty" db:"promo_code"`
+	PoolCode  string    `json:"pool_code,omitempty" db:"pool_code"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
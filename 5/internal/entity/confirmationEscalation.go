@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultTelegramReminderMinutes = 15
+	DefaultFinalReminderMinutes    = 5
+	DefaultLoyaltyThreshold        = 70
+
+	// AutoExtendLead - за сколько до истечения решается вопрос о продлении брони
+	AutoExtendLead = time.Minute
+)
+
+// Шаги цепочки напоминаний о неподтверждённом бронировании
+const (
+	EscalationStepTelegram   = "telegram"    // напоминание в Telegram
+	EscalationStepFinal      = "final"       // последнее напоминание по второму каналу (email)
+	EscalationStepAutoExtend = "auto_extend" // однократное продление для лояльных пользователей
+)
+
+// ConfirmationEscalation задаёт для мероприятия цепочку напоминаний о неподтверждённом бронировании.
+// Нулевые сроки напоминаний заменяются значениями по умолчанию; AutoExtendMinutes == 0 отключает продление.
+// Хранится в events.confirmation_escalation как JSONB.
+type ConfirmationEscalation struct {
+	Disabled                bool    `json:"disabled,omitempty"`
+	TelegramReminderMinutes int     `json:"telegram_reminder_minutes,omitempty"`
+	FinalReminderMinutes    int     `json:"final_reminder_minutes,omitempty"`
+	AutoExtendMinutes       int     `json:"auto_extend_minutes,omitempty"`
+	LoyaltyThreshold        float64 `json:"loyalty_threshold,omitempty"` // минимальная оценка лояльности 0-100
+}
+
+// EscalationStep - шаг цепочки и момент, когда он выполняется
+type EscalationStep struct {
+	Name string
+	At   time.Time
+}
+
+// WithDefaults подставляет значения по умолчанию вместо незаданных полей
+func (e ConfirmationEscalation) WithDefaults() ConfirmationEscalation {
+	if e.TelegramReminderMinutes == 0 {
+		e.TelegramReminderMinutes = DefaultTelegramReminderMinutes
+	}
+	if e.FinalReminderMinutes == 0 {
+		e.FinalReminderMinutes = DefaultFinalReminderMinutes
+	}
+	if e.LoyaltyThreshold == 0 {
+		e.LoyaltyThreshold = DefaultLoyaltyThreshold
+	}
+	return e
+}
+
+func (e ConfirmationEscalation) Validate() error {
+	if e.TelegramReminderMinutes < 0 || e.FinalReminderMinutes < 0 || e.AutoExtendMinutes < 0 {
+		return fmt.Errorf("%w: escalation minutes cannot be negative", ErrInvalidInput)
+	}
+	if e.LoyaltyThreshold < 0 || e.LoyaltyThreshold > 100 {
+		return fmt.Errorf("%w: loyalty threshold must be between 0 and 100", ErrInvalidInput)
+	}
+	return nil
+}
+
+// Steps возвращает шаги цепочки для бронирования, истекающего в expiresAt, в порядке выполнения
+func (e ConfirmationEscalation) Steps(expiresAt time.Time) []EscalationStep {
+	if e.Disabled {
+		return nil
+	}
+	e = e.WithDefaults()
+
+	steps := []EscalationStep{
+		{Name: EscalationStepTelegram, At: expiresAt.Add(-time.Duration(e.TelegramReminderMinutes) * time.Minute)},
+		{Name: EscalationStepFinal, At: expiresAt.Add(-time.Duration(e.FinalReminderMinutes) * time.Minute)},
+	}
+	if e.AutoExtendMinutes > 0 {
+		steps = append(steps, EscalationStep{Name: EscalationStepAutoExtend, At: expiresAt.Add(-AutoExtendLead)})
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].At.Before(steps[j].At)
+	})
+	return steps
+}
+
+// NextStep возвращает первый шаг после шага after (или первый шаг, если after пуст),
+// который ещё не наступил к моменту now
+func (e ConfirmationEscalation) NextStep(expiresAt time.Time, after string, now time.Time) (EscalationStep, bool) {
+	steps := e.Steps(expiresAt)
+
+	start := 0
+	if after != "" {
+		for i, step := range steps {
+			if step.Name == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	for _, step := range steps[start:] {
+		if step.At.After(now) {
+			return step, true
+		}
+	}
+	return EscalationStep{}, false
+}
+
+func (e ConfirmationEscalation) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *ConfirmationEscalation) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = ConfirmationEscalation{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("cannot scan type %T into ConfirmationEscalation", value)
+	}
+}
@@ -1,31 +1,121 @@
-package entity
-
-import "errors"
-
-var (
-	// Event errors
-	ErrEventNotFound      = errors.New("event not found")
-	ErrEventAlreadyExists = errors.New("event already exists")
-	ErrEventFull          = errors.New("event is full")
-	ErrEventDatePast      = errors.New("event date cannot be in the past")
-
-	// Booking errors
-	ErrBookingNotFound      = errors.New("booking not found")
-	ErrBookingAlreadyExists = errors.New("booking already exists")
-	ErrNotEnoughSeats       = errors.New("not enough available seats")
-	ErrBookingExpired       = errors.New("booking has expired")
-	ErrInvalidBookingStatus = errors.New("invalid booking status")
-
-	// User errors
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUserAlreadyExists = errors.New("user already exists")
-	ErrInvalidEmail      = errors.New("invalid email format")
-	ErrTelegramIDExists  = errors.New("telegram ID already exists")
-
-	// General errors
-	ErrInvalidInput     = errors.New("invalid input")
-	ErrDatabaseError    = errors.New("database error")
-	ErrConcurrentUpdate = errors.New("concurrent update detected")
-	ErrUnauthorized     = errors.New("unauthorized access")
-	ErrForbidden        = errors.New("forbidden operation")
-)
+package entity
+
+import "errors"
+
+var (
+	// Event errors
+	ErrEventNotFound      = errors.New("event not found")
+	ErrEventAlreadyExists = errors.New("event already exists")
+	ErrEventFull          = errors.New("event is full")
+	ErrEventDatePast      = errors.New("event date cannot be in the past")
+	ErrEventHasBookings   = errors.New("event has pending or confirmed bookings")
+
+	// Capacity change errors
+	ErrInvalidCapacity       = errors.New("total seats must be positive")
+	ErrInvalidCapacityPolicy = errors.New("capacity policy must be keep_earliest, refund_latest or waitlist")
+	ErrCapacityBelowDemand   = errors.New("total seats are below booked, partner-reserved and held seats")
+	ErrCapacityBelowReserved = errors.New("total seats are below partner-reserved and held seats")
+
+	// Venue errors
+	ErrVenueNotFound         = errors.New("venue not found")
+	ErrInvalidVenue          = errors.New("invalid venue")
+	ErrVenueInUse            = errors.New("venue has events")
+	ErrVenueCapacityExceeded = errors.New("event seats exceed venue capacity")
+
+	// ErrConflict - запись изменили после того, как клиент её прочитал (не совпала версия)
+	ErrConflict = errors.New("record was modified concurrently, reload and retry")
+
+	// Booking errors
+	ErrBookingNotFound      = errors.New("booking not found")
+	ErrRefundNotFound       = errors.New("refund not found")
+	ErrRefundNotPending     = errors.New("refund is already settled")
+	ErrBookingAlreadyExists = errors.New("booking already exists")
+	ErrNotEnoughSeats       = errors.New("not enough available seats")
+	ErrBookingExpired       = errors.New("booking has expired")
+	ErrInvalidBookingStatus = errors.New("invalid booking status")
+	ErrCancellationClosed   = errors.New("booking can no longer be cancelled")
+	ErrInvalidTransition    = errors.New("invalid booking status transition")
+	ErrStatusUnchanged      = errors.New("booking already has this status")
+
+	// Ticket (QR) errors
+	ErrTicketUnavailable = errors.New("ticket is available only for confirmed bookings")
+	ErrInvalidTicket     = errors.New("invalid or revoked ticket")
+	ErrTicketWrongEvent  = errors.New("ticket is for another event")
+	ErrAlreadyCheckedIn  = errors.New("ticket has already been used for check-in")
+
+	// Ticket tier errors
+	ErrTicketTierNotFound   = errors.New("ticket tier not found")
+	ErrTicketTierRequired   = errors.New("ticket tier is required for this event")
+	ErrTierSeatsExceedEvent = errors.New("tier seats exceed event total seats")
+	ErrTierHasBookings      = errors.New("ticket tier has bookings")
+
+	// Partner pool errors
+	ErrPartnerPoolNotFound    = errors.New("partner pool not found")
+	ErrPartnerPoolExists      = errors.New("partner pool with this name or code already exists")
+	ErrPoolSeatsExceedEvent   = errors.New("partner pool seats exceed unbooked event seats")
+	ErrPartnerPoolHasBookings = errors.New("partner pool has bookings")
+
+	// Event hold errors
+	ErrHoldSeatsExceedEvent = errors.New("held seats exceed unbooked event seats")
+	ErrDuplicateHoldLabel   = errors.New("hold labels must be unique within an event")
+
+	// Sale phase errors
+	ErrSaleNotStarted     = errors.New("ticket sales for this event have not started yet")
+	ErrSaleClosed         = errors.New("ticket sales for this event are closed")
+	ErrAccessCodeRequired = errors.New("pre-sale requires an access code")
+	ErrInvalidAccessCode  = errors.New("invalid pre-sale access code")
+
+	// Cart errors
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrCartEmpty        = errors.New("cart is empty")
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrCartItemExists   = errors.New("cart already has an item for this event")
+
+	// Promo code errors
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeExists        = errors.New("promo code already exists")
+	ErrPromoCodeExpired       = errors.New("promo code has expired")
+	ErrPromoCodeExhausted     = errors.New("promo code usage limit reached")
+	ErrPromoCodeNotApplicable = errors.New("promo code is not valid for this event")
+	ErrPromoCodeInUse         = errors.New("promo code has bookings, deactivate it instead")
+
+	// Webhook errors
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+	ErrUnknownEventType  = errors.New("unknown webhook event type")
+
+	// Poster errors
+	ErrPosterNotFound    = errors.New("event has no poster")
+	ErrPostersDisabled   = errors.New("poster upload is not configured")
+	ErrInvalidPosterFile = errors.New("poster must be a jpg, jpeg, png or gif image within the size limit")
+
+	// API token errors
+	ErrAPITokenNotFound  = errors.New("api token not found")
+	ErrInvalidAPIToken   = errors.New("invalid, expired or revoked api token")
+	ErrUnknownScope      = errors.New("unknown api token scope")
+	ErrInsufficientScope = errors.New("api token lacks the required scope")
+
+	// User errors
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserAlreadyExists  = errors.New("user already exists")
+	ErrInvalidEmail       = errors.New("invalid email format")
+	ErrTelegramIDExists   = errors.New("telegram ID already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidRole        = errors.New("invalid user role")
+
+	ErrImpersonationNotAllowed = errors.New("administrators cannot be impersonated")
+
+	// Analytics errors
+	ErrInvalidTrendPeriod = errors.New("trend period must be day, week or month")
+	ErrInvalidTrendRange  = errors.New("trend range must start before it ends and span at most 366 periods")
+
+	// Calendar errors
+	ErrInvalidCalendarToken = errors.New("invalid or revoked calendar token")
+
+	// General errors
+	ErrInvalidInput     = errors.New("invalid input")
+	ErrDatabaseError    = errors.New("database error")
+	ErrConcurrentUpdate = errors.New("concurrent update detected")
+	ErrUnauthorized     = errors.New("unauthorized access")
+	ErrForbidden        = errors.New("forbidden operation")
+)
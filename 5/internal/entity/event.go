@@ -1,21 +1,97 @@
-package entity
-
-import (
-	"time"
-)
-
-type Event struct {
-	ID          int64     `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Date        time.Time `json:"date" db:"date"`
-	TotalSeats  int       `json:"total_seats" db:"total_seats"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-}
-
-type EventWithAvailability struct {
-	Event
-	AvailableSeats int `json:"available_seats"`
-	BookedSeats    int `json:"booked_seats"`
-}
+package entity
+
+import (
+	"time"
+)
+
+type Event struct {
+	ID          int64        `json:"id" db:"id"`
+	Title       string       `json:"title" db:"title"`
+	Description string       `json:"description" db:"description"`
+	Location    string       `json:"location" db:"location"`
+	VenueID     *int64       `json:"venue_id,omitempty" db:"venue_id"`
+	Venue       *Venue       `json:"venue,omitempty" db:"-"`
+	Poster      *EventPoster `json:"poster,omitempty" db:"-"`
+	OrganizerID *int64       `json:"organizer_id,omitempty" db:"organizer_id"` // заполняется для мероприятий, созданных по API-токену
+	Date        time.Time    `json:"date" db:"date"`
+	TotalSeats  int          `json:"total_seats" db:"total_seats"`
+	Version     int          `json:"version" db:"version"` // растёт при каждом изменении мероприятия
+
+	CancellationPolicy CancellationPolicy `json:"cancellation_policy"`
+
+	// Напоминания о неподтверждённых бронированиях и их продление
+	ConfirmationEscalation ConfirmationEscalation `json:"confirmation_escalation"`
+
+	// Фазы продаж: предпродажа по кодам доступа, открытая продажа и её окончание
+	SaleSchedule SaleSchedule `json:"sale_schedule"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type EventWithAvailability struct {
+	Event
+	AvailableSeats int `json:"available_seats"`
+	BookedSeats    int `json:"booked_seats"`
+	ReservedSeats  int `json:"reserved_seats,omitempty"` // свободные места партнёрских пулов
+	HeldSeats      int `json:"held_seats,omitempty"`     // места, придержанные организатором
+
+	Sale *SaleStatus `json:"sale,omitempty"` // текущая фаза продаж, заполняется сервисом
+}
+
+// SeatUsage - места мероприятия, занятые бронированиями, пулами и холдами. Вместимость в неё
+// не входит, поэтому кэш занятости не нужно сбрасывать при изменении total_seats
+type SeatUsage struct {
+	BookedSeats     int `json:"booked_seats"`
+	PoolSeats       int `json:"pool_seats"`
+	PoolBookedSeats int `json:"pool_booked_seats"`
+	HeldSeats       int `json:"held_seats"`
+}
+
+// WithSeatUsage дополняет мероприятие занятостью мест и считает свободные
+func (e Event) WithSeatUsage(usage SeatUsage) *EventWithAvailability {
+	event := &EventWithAvailability{Event: e, BookedSeats: usage.BookedSeats, HeldSeats: usage.HeldSeats}
+	event.ApplyPools(usage.PoolSeats, usage.PoolBookedSeats)
+	return event
+}
+
+// AvailabilityUpdate - свободные места мероприятия в потоке живых обновлений страницы.
+// Deleted означает, что мероприятие удалено и поток закрывается
+type AvailabilityUpdate struct {
+	EventID        int64     `json:"event_id"`
+	TotalSeats     int       `json:"total_seats"`
+	AvailableSeats int       `json:"available_seats"`
+	BookedSeats    int       `json:"booked_seats"`
+	ReservedSeats  int       `json:"reserved_seats,omitempty"`
+	HeldSeats      int       `json:"held_seats,omitempty"`
+	Deleted        bool      `json:"deleted,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AvailabilityUpdate возвращает текущие свободные места мероприятия для потока обновлений
+func (e *EventWithAvailability) AvailabilityUpdate() *AvailabilityUpdate {
+	return &AvailabilityUpdate{
+		EventID:        e.ID,
+		TotalSeats:     e.TotalSeats,
+		AvailableSeats: e.AvailableSeats,
+		BookedSeats:    e.BookedSeats,
+		ReservedSeats:  e.ReservedSeats,
+		HeldSeats:      e.HeldSeats,
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// EventFilter описывает параметры поиска мероприятий на стороне БД
+type EventFilter struct {
+	Query     string    // полнотекстовый поиск по названию и описанию
+	Title     string    // подстрока в названии (ILIKE)
+	DateFrom  time.Time // нижняя граница даты (включительно)
+	DateTo    time.Time // верхняя граница даты (включительно)
+	Limit     int
+	Offset    int
+	SortBy    string // "date", "title", "created_at"
+	SortOrder string // "asc", "desc"
+	VenueID   *int64 // только мероприятия площадки
+
+	OnlyAvailable bool // только мероприятия со свободными местами
+}
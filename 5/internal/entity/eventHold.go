@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+)
+
+// EventHold - блок мест, придержанный организатором (техническая зона, резерв под гостей).
+// В отличие от партнёрского пула, придержанные места не продаются вовсе, пока холд не снят.
+type EventHold struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	Label     string    `json:"label" db:"label"`
+	Seats     int       `json:"seats" db:"seats"`
+	Reason    string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
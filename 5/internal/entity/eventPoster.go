@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Статусы афиши: варианты строит сервис изображений, пока они не готовы - processing
+const (
+	PosterProcessing = "processing"
+	PosterReady      = "ready"
+	PosterFailed     = "failed"
+)
+
+// EventPoster - афиша мероприятия. Formats - ссылки на варианты изображения
+// (resized, thumbnail, watermark), заполняются, когда сервис изображений их построит
+type EventPoster struct {
+	EventID   int64             `json:"-" db:"event_id"`
+	ImageID   string            `json:"image_id" db:"image_id"`
+	Status    string            `json:"status" db:"status"`
+	Formats   map[string]string `json:"formats,omitempty" db:"formats"`
+	Error     string            `json:"error,omitempty" db:"error"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
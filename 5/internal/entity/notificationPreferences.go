@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Категории уведомлений пользователя
+const (
+	NotificationBookingUpdates = "booking_updates" // создание, подтверждение, истечение и отмена бронирований, отмена мероприятий
+	NotificationReminders      = "reminders"       // напоминания о подтверждении брони и о мероприятии
+	NotificationMarketing      = "marketing"       // рассылки организаторов
+)
+
+// NotificationCategories - все категории в порядке показа
+var NotificationCategories = []string{NotificationBookingUpdates, NotificationReminders, NotificationMarketing}
+
+// ChannelPreference - каналы, которыми приходят уведомления категории
+type ChannelPreference struct {
+	Email    bool `json:"email"`
+	Telegram bool `json:"telegram"`
+}
+
+// NotificationPreferences - каналы по категориям. Категория без записи получает значение
+// по умолчанию: бронирования и напоминания приходят во все каналы, рассылки - только
+// после согласия. Поэтому у существующих пользователей с пустыми настройками ничего не меняется.
+// Хранится в users.notification_preferences как JSONB.
+type NotificationPreferences map[string]ChannelPreference
+
+// IsNotificationCategory проверяет, что category - известная категория уведомлений
+func IsNotificationCategory(category string) bool {
+	for _, c := range NotificationCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Channels возвращает каналы категории с учётом значения по умолчанию
+func (p NotificationPreferences) Channels(category string) ChannelPreference {
+	if channels, ok := p[category]; ok {
+		return channels
+	}
+	if category == NotificationMarketing {
+		return ChannelPreference{}
+	}
+	return ChannelPreference{Email: true, Telegram: true}
+}
+
+// Resolved возвращает каналы всех категорий, подставляя значения по умолчанию
+func (p NotificationPreferences) Resolved() NotificationPreferences {
+	resolved := make(NotificationPreferences, len(NotificationCategories))
+	for _, category := range NotificationCategories {
+		resolved[category] = p.Channels(category)
+	}
+	return resolved
+}
+
+func (p NotificationPreferences) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *NotificationPreferences) Scan(value interface{}) error {
+	*p = nil
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan type %T into NotificationPreferences", value)
+	}
+}
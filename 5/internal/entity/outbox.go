@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// OutboxMessage - задача для очереди, записанная в той же транзакции, что и изменение данных.
+// Релей переносит её в очередь после коммита, поэтому задача не теряется при недоступном Redis.
+type OutboxMessage struct {
+	ID          int64                  `json:"id"`
+	TaskID      string                 `json:"task_id"`
+	TaskType    string                 `json:"task_type"`
+	Data        map[string]interface{} `json:"data"`
+	ExecuteAt   time.Time              `json:"execute_at"`
+	MaxRetries  int                    `json:"max_retries"`
+	Priority    string                 `json:"priority,omitempty"`
+	Attempts    int                    `json:"attempts"`
+	LastError   string                 `json:"last_error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	PublishedAt *time.Time             `json:"published_at,omitempty"`
+}
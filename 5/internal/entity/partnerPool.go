@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+)
+
+// PartnerPool - часть мест мероприятия, отложенная для партнёров (спонсоров, прессы).
+// Места пула может занять только бронирование с кодом пула, остальным они недоступны.
+type PartnerPool struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	Name      string    `json:"name" db:"name"`
+	Code      string    `json:"code" db:"code"`
+	Seats     int       `json:"seats" db:"seats"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type PartnerPoolWithAvailability struct {
+	PartnerPool
+	AvailableSeats int `json:"available_seats"`
+	BookedSeats    int `json:"booked_seats"`
+}
+
+// ApplyPools пересчитывает доступность мероприятия с учётом партнёрских пулов:
+// poolSeats - суммарная квота пулов, poolBookedSeats - занятые в них места (подтверждённые и неистекшие ожидающие).
+// Свободные места пулов не продаются без кода и показываются как ReservedSeats.
+// HeldSeats должен быть заполнен до вызова: придержанные места тоже не продаются.
+func (e *EventWithAvailability) ApplyPools(poolSeats, poolBookedSeats int) {
+	e.ReservedSeats = poolSeats - poolBookedSeats
+	if e.ReservedSeats < 0 {
+		e.ReservedSeats = 0
+	}
+	e.AvailableSeats = e.TotalSeats - e.BookedSeats - e.ReservedSeats - e.HeldSeats
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"math"
+	"time"
+)
+
+type DiscountType string
+
+const (
+	DiscountTypePercent DiscountType = "percent"
+	DiscountTypeFixed   DiscountType = "fixed"
+)
+
+// PromoCode описывает промокод со скидкой, сроком действия и лимитом использований
+type PromoCode struct {
+	ID            int64        `json:"id" db:"id"`
+	Code          string       `json:"code" db:"code"`
+	EventID       *int64       `json:"event_id,omitempty" db:"event_id"` // nil - действует на все мероприятия
+	DiscountType  DiscountType `json:"discount_type" db:"discount_type"`
+	DiscountValue float64      `json:"discount_value" db:"discount_value"`
+	MaxUses       int          `json:"max_uses" db:"max_uses"` // 0 - без ограничений
+	UsedCount     int          `json:"used_count" db:"used_count"`
+	ExpiresAt     *time.Time   `json:"expires_at,omitempty" db:"expires_at"`
+	Active        bool         `json:"active" db:"active"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// IsExpired проверяет, истек ли срок действия промокода
+func (p *PromoCode) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// IsExhausted проверяет, исчерпан ли лимит использований
+func (p *PromoCode) IsExhausted() bool {
+	return p.MaxUses > 0 && p.UsedCount >= p.MaxUses
+}
+
+// AppliesTo проверяет, действует ли промокод на мероприятие
+func (p *PromoCode) AppliesTo(eventID int64) bool {
+	return p.EventID == nil || *p.EventID == eventID
+}
+
+// Discount вычисляет скидку для указанной суммы; скидка не может превышать сумму
+func (p *PromoCode) Discount(price float64) float64 {
+	var discount float64
+	switch p.DiscountType {
+	case DiscountTypePercent:
+		discount = price * p.DiscountValue / 100
+	case DiscountTypeFixed:
+		discount = p.DiscountValue
+	}
+
+	discount = math.Round(math.Min(discount, price)*100) / 100
+	return math.Max(discount, 0)
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// Виды расхождений, которые находит сверка бронирований с очередью и уведомлениями
+const (
+	// ReconcilePendingWithoutExpiry - бронирование ждёт подтверждения, но задачи истечения нет
+	// ни в очереди, ни в outbox: места останутся занятыми до очистки по расписанию
+	ReconcilePendingWithoutExpiry = "pending_without_expiry"
+	// ReconcileMissingConfirmation - бронирование подтверждено, а уведомление о подтверждении не обработано
+	ReconcileMissingConfirmation = "missing_confirmation_notification"
+	// ReconcileDLQDeletedBooking - задача в DLQ ссылается на удалённое бронирование, повторять её бесполезно
+	ReconcileDLQDeletedBooking = "dlq_deleted_booking"
+)
+
+// Исправления, которые сверка предлагает и выполняет в режиме apply
+const (
+	RepairScheduleExpiry     = "schedule_expiry"
+	RepairResendConfirmation = "resend_confirmation"
+	RepairDeleteDLQTask      = "delete_dlq_task"
+)
+
+// ReconciliationIssue - одно расхождение и исправление для него
+type ReconciliationIssue struct {
+	Kind      string `json:"kind"`
+	BookingID int64  `json:"booking_id"`
+	TaskID    string `json:"task_id,omitempty"`
+	Details   string `json:"details"`
+	Action    string `json:"action"`
+	// Applied - исправление выполнено; Error - почему не удалось
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReconciliationReport - результат сверки. Skipped - проверки, которые нельзя выполнить
+// с текущей очередью, и причина
+type ReconciliationReport struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Apply       bool                   `json:"apply"`
+	Skipped     map[string]string      `json:"skipped,omitempty"`
+	Issues      []*ReconciliationIssue `json:"issues"`
+}
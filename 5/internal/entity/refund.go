@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RefundRule - ступень политики возврата: при отмене не позднее чем за HoursBefore
+// часов до начала мероприятия возвращается Percent процентов оплаты
+type RefundRule struct {
+	HoursBefore int     `json:"hours_before"`
+	Percent     float64 `json:"percent"`
+}
+
+// RefundRules хранится в events.refund_rules как JSONB
+type RefundRules []RefundRule
+
+func (r RefundRules) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *RefundRules) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("cannot scan type %T into RefundRules", value)
+	}
+}
+
+// Validate проверяет границы значений и отсутствие двух ступеней с одинаковым сроком
+func (r RefundRules) Validate() error {
+	seen := make(map[int]bool, len(r))
+	for _, rule := range r {
+		if rule.HoursBefore < 0 {
+			return fmt.Errorf("%w: refund rule hours cannot be negative", ErrInvalidInput)
+		}
+		if rule.Percent < 0 || rule.Percent > 100 {
+			return fmt.Errorf("%w: refund rule percent must be between 0 and 100", ErrInvalidInput)
+		}
+		if seen[rule.HoursBefore] {
+			return fmt.Errorf("%w: duplicate refund rule for %d hours", ErrInvalidInput, rule.HoursBefore)
+		}
+		seen[rule.HoursBefore] = true
+	}
+	return nil
+}
+
+// sorted возвращает копию ступеней от самой ранней к самой поздней
+func (r RefundRules) sorted() RefundRules {
+	rules := append(RefundRules(nil), r...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].HoursBefore > rules[j].HoursBefore })
+	return rules
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending RefundStatus = "pending"
+	// RefundStatusProcessed - возврат закрыт без выплаты: его сумма нулевая
+	RefundStatusProcessed RefundStatus = "processed"
+	// RefundStatusSettledManually - администратор подтвердил выплату, сделанную вне системы
+	RefundStatusSettledManually RefundStatus = "settled_manually"
+	RefundStatusFailed          RefundStatus = "failed"
+)
+
+// Refund - возврат за отменённое оплаченное бронирование; сумма фиксируется в момент отмены
+type Refund struct {
+	ID          int64        `json:"id" db:"id"`
+	BookingID   int64        `json:"booking_id" db:"booking_id"`
+	EventID     int64        `json:"event_id" db:"event_id"`
+	UserID      int64        `json:"user_id" db:"user_id"`
+	PaidAmount  float64      `json:"paid_amount" db:"paid_amount"`
+	Percent     float64      `json:"percent" db:"percent"`
+	Amount      float64      `json:"amount" db:"amount"`
+	Status      RefundStatus `json:"status" db:"status"`
+	Reason      string       `json:"reason" db:"reason"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	ProcessedAt *time.Time   `json:"processed_at,omitempty" db:"processed_at"`
+}
+
+// RefundReport - возвраты по мероприятию с итогами
+type RefundReport struct {
+	EventID       int64     `json:"event_id"`
+	Count         int       `json:"count"`
+	TotalPaid     float64   `json:"total_paid"`
+	TotalRefunded float64   `json:"total_refunded"`
+	Pending       float64   `json:"pending"`
+	Processed     float64   `json:"processed"`
+	Manual        float64   `json:"settled_manually"`
+	Refunds       []*Refund `json:"refunds"`
+}
+
+// NewRefundReport подводит итоги по списку возвратов
+func NewRefundReport(eventID int64, refunds []*Refund) *RefundReport {
+	report := &RefundReport{EventID: eventID, Count: len(refunds), Refunds: refunds}
+	for _, refund := range refunds {
+		report.TotalPaid += refund.PaidAmount
+		report.TotalRefunded += refund.Amount
+		switch refund.Status {
+		case RefundStatusPending:
+			report.Pending += refund.Amount
+		case RefundStatusProcessed:
+			report.Processed += refund.Amount
+		case RefundStatusSettledManually:
+			report.Manual += refund.Amount
+		}
+	}
+	return report
+}
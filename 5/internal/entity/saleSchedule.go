@@ -0,0 +1,232 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Фазы продаж мероприятия
+const (
+	SalePhaseUpcoming = "upcoming" // продажи ещё не начались
+	SalePhasePresale  = "presale"  // предпродажа по кодам доступа
+	SalePhaseGeneral  = "general"  // открытая продажа
+	SalePhaseClosed   = "closed"   // продажи завершены
+)
+
+const (
+	MinAccessCodeLength = 4
+	MaxAccessCodeLength = 64
+	MaxAccessCodes      = 100
+)
+
+// SaleSchedule задаёт фазы продаж мероприятия. Без PresaleStartsAt предпродажи нет, без
+// GeneralSaleStartsAt открытая продажа идёт с момента создания, без SalesEndAt - до начала
+// мероприятия; пустое расписание оставляет продажи открытыми, как раньше.
+// Хранится в events.sale_schedule как JSONB. Коды доступа хранятся хешами и в API не отдаются.
+type SaleSchedule struct {
+	PresaleStartsAt     *time.Time `json:"presale_starts_at,omitempty"`
+	GeneralSaleStartsAt *time.Time `json:"general_sale_starts_at,omitempty"`
+	SalesEndAt          *time.Time `json:"sales_end_at,omitempty"`
+
+	AccessCodeHashes []string `json:"-"`
+}
+
+// SaleTransition - начало фазы продаж
+type SaleTransition struct {
+	Phase string
+	At    time.Time
+}
+
+// SaleStatus - фаза продаж на момент ответа и обратный отсчёт до следующей.
+// SecondsUntilNext посчитан на ServerTime; для живого отсчёта клиенту нужен NextPhaseAt
+type SaleStatus struct {
+	Phase              string     `json:"phase"`
+	RequiresAccessCode bool       `json:"requires_access_code,omitempty"`
+	NextPhase          string     `json:"next_phase,omitempty"`
+	NextPhaseAt        *time.Time `json:"next_phase_at,omitempty"`
+	SecondsUntilNext   int64      `json:"seconds_until_next,omitempty"`
+	ServerTime         time.Time  `json:"server_time"`
+}
+
+// Validate проверяет порядок фаз: предпродажа раньше открытой продажи, продажи заканчиваются
+// не позже начала мероприятия, у предпродажи есть хотя бы один код
+func (s SaleSchedule) Validate(eventDate time.Time) error {
+	if s.PresaleStartsAt != nil {
+		if s.GeneralSaleStartsAt == nil {
+			return fmt.Errorf("%w: pre-sale requires general_sale_starts_at", ErrInvalidInput)
+		}
+		if !s.PresaleStartsAt.Before(*s.GeneralSaleStartsAt) {
+			return fmt.Errorf("%w: pre-sale must start before general sale", ErrInvalidInput)
+		}
+		if len(s.AccessCodeHashes) == 0 {
+			return fmt.Errorf("%w: pre-sale requires at least one access code", ErrInvalidInput)
+		}
+	}
+
+	salesEnd := eventDate
+	if s.SalesEndAt != nil {
+		if s.SalesEndAt.After(eventDate) {
+			return fmt.Errorf("%w: sales cannot end after the event starts", ErrInvalidInput)
+		}
+		salesEnd = *s.SalesEndAt
+	}
+	if s.GeneralSaleStartsAt != nil && !s.GeneralSaleStartsAt.Before(salesEnd) {
+		return fmt.Errorf("%w: general sale must start before sales end", ErrInvalidInput)
+	}
+	return nil
+}
+
+// SetAccessCodes заменяет коды доступа предпродажи; коды сравниваются без учёта регистра и пробелов по краям
+func (s *SaleSchedule) SetAccessCodes(codes []string) error {
+	if len(codes) > MaxAccessCodes {
+		return fmt.Errorf("%w: at most %d access codes", ErrInvalidInput, MaxAccessCodes)
+	}
+
+	hashes := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = normalizeAccessCode(code)
+		if len(code) < MinAccessCodeLength || len(code) > MaxAccessCodeLength {
+			return fmt.Errorf("%w: access codes must be %d-%d characters long", ErrInvalidInput, MinAccessCodeLength, MaxAccessCodeLength)
+		}
+		hash := hashAccessCode(code)
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	s.AccessCodeHashes = hashes
+	return nil
+}
+
+// CheckAccessCode сверяет код с кодами предпродажи
+func (s SaleSchedule) CheckAccessCode(code string) bool {
+	hash := []byte(hashAccessCode(normalizeAccessCode(code)))
+	for _, stored := range s.AccessCodeHashes {
+		if subtle.ConstantTimeCompare(hash, []byte(stored)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// PhaseAt возвращает фазу продаж в момент now
+func (s SaleSchedule) PhaseAt(now, eventDate time.Time) string {
+	switch {
+	case !now.Before(s.salesEnd(eventDate)):
+		return SalePhaseClosed
+	case s.GeneralSaleStartsAt == nil || !now.Before(*s.GeneralSaleStartsAt):
+		return SalePhaseGeneral
+	case s.PresaleStartsAt != nil && !now.Before(*s.PresaleStartsAt):
+		return SalePhasePresale
+	default:
+		return SalePhaseUpcoming
+	}
+}
+
+// CheckSale разрешает бронирование в момент now: в открытую продажу - всем,
+// в предпродажу - с верным кодом доступа
+func (s SaleSchedule) CheckSale(now, eventDate time.Time, accessCode string) error {
+	switch s.PhaseAt(now, eventDate) {
+	case SalePhaseUpcoming:
+		return ErrSaleNotStarted
+	case SalePhaseClosed:
+		return ErrSaleClosed
+	case SalePhasePresale:
+		if strings.TrimSpace(accessCode) == "" {
+			return ErrAccessCodeRequired
+		}
+		if !s.CheckAccessCode(accessCode) {
+			return ErrInvalidAccessCode
+		}
+	}
+	return nil
+}
+
+// Transitions возвращает начала фаз по порядку; открытая продажа без даты начала в них не входит
+func (s SaleSchedule) Transitions(eventDate time.Time) []SaleTransition {
+	var transitions []SaleTransition
+	if s.PresaleStartsAt != nil {
+		transitions = append(transitions, SaleTransition{Phase: SalePhasePresale, At: *s.PresaleStartsAt})
+	}
+	if s.GeneralSaleStartsAt != nil {
+		transitions = append(transitions, SaleTransition{Phase: SalePhaseGeneral, At: *s.GeneralSaleStartsAt})
+	}
+	return append(transitions, SaleTransition{Phase: SalePhaseClosed, At: s.salesEnd(eventDate)})
+}
+
+// Status описывает фазу продаж в момент now и ближайший переход
+func (s SaleSchedule) Status(now, eventDate time.Time) SaleStatus {
+	phase := s.PhaseAt(now, eventDate)
+	status := SaleStatus{
+		Phase:              phase,
+		RequiresAccessCode: phase == SalePhasePresale,
+		ServerTime:         now,
+	}
+
+	for _, transition := range s.Transitions(eventDate) {
+		if transition.At.After(now) {
+			at := transition.At
+			status.NextPhase = transition.Phase
+			status.NextPhaseAt = &at
+			status.SecondsUntilNext = int64(at.Sub(now).Seconds())
+			break
+		}
+	}
+	return status
+}
+
+func (s SaleSchedule) salesEnd(eventDate time.Time) time.Time {
+	if s.SalesEndAt != nil {
+		return *s.SalesEndAt
+	}
+	return eventDate
+}
+
+// saleScheduleRecord - представление в базе: в отличие от API, с хешами кодов
+type saleScheduleRecord struct {
+	SaleSchedule
+	AccessCodeHashes []string `json:"access_code_hashes,omitempty"`
+}
+
+func (s SaleSchedule) Value() (driver.Value, error) {
+	return json.Marshal(saleScheduleRecord{SaleSchedule: s, AccessCodeHashes: s.AccessCodeHashes})
+}
+
+func (s *SaleSchedule) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*s = SaleSchedule{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into SaleSchedule", value)
+	}
+
+	var record saleScheduleRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	*s = record.SaleSchedule
+	s.AccessCodeHashes = record.AccessCodeHashes
+	return nil
+}
+
+func normalizeAccessCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func hashAccessCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// TicketTier описывает категорию билетов мероприятия со своей ценой и квотой мест
+type TicketTier struct {
+	ID        int64     `json:"id" db:"id"`
+	EventID   int64     `json:"event_id" db:"event_id"`
+	Name      string    `json:"name" db:"name"`
+	Price     float64   `json:"price" db:"price"`
+	Seats     int       `json:"seats" db:"seats"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type TicketTierWithAvailability struct {
+	TicketTier
+	AvailableSeats int `json:"available_seats"`
+	BookedSeats    int `json:"booked_seats"`
+}
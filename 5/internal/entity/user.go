@@ -1,11 +1,52 @@
-package entity
-
-import "time"
-
-type User struct {
-	ID         int64     `json:"id" db:"id"`
-	Email      string    `json:"email" db:"email"`
-	Name       string    `json:"name" db:"name"`
-	TelegramID string    `json:"telegram_id" db:"telegram_id"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-}
+package entity
+
+import "time"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+type User struct {
+	ID           int64     `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	Name         string    `json:"name" db:"name"`
+	TelegramID   string    `json:"telegram_id" db:"telegram_id"`
+	Role         string    `json:"role" db:"role"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// Каналы уведомлений, которые выбрал пользователь
+	NotifyEmail    bool `json:"notify_email" db:"notify_email"`
+	NotifyTelegram bool `json:"notify_telegram" db:"notify_telegram"`
+	// Каналы по категориям уведомлений; действуют, только пока канал включён целиком
+	NotificationPreferences NotificationPreferences `json:"-" db:"notification_preferences"`
+}
+
+// WantsEmail проверяет, можно ли отправлять пользователю письма
+func (u *User) WantsEmail() bool {
+	return u.NotifyEmail && u.Email != ""
+}
+
+// WantsTelegram проверяет, можно ли отправлять пользователю сообщения в Telegram
+func (u *User) WantsTelegram() bool {
+	return u.NotifyTelegram && u.TelegramID != ""
+}
+
+// WantsEmailFor проверяет, можно ли отправить пользователю письмо категории category
+func (u *User) WantsEmailFor(category string) bool {
+	return u.WantsEmail() && u.NotificationPreferences.Channels(category).Email
+}
+
+// WantsTelegramFor проверяет, можно ли отправить пользователю сообщение категории category в Telegram
+func (u *User) WantsTelegramFor(category string) bool {
+	return u.WantsTelegram() && u.NotificationPreferences.Channels(category).Telegram
+}
+
+// UserFilter - условия поиска пользователей администратором; Name и Email ищутся как подстроки
+type UserFilter struct {
+	Email  string
+	Name   string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Venue - площадка, на которой проходят мероприятия
+type Venue struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Address   string    `json:"address" db:"address"`
+	Latitude  *float64  `json:"latitude,omitempty" db:"latitude"`
+	Longitude *float64  `json:"longitude,omitempty" db:"longitude"`
+	Capacity  int       `json:"capacity" db:"capacity"`
+	MapURL    string    `json:"map_url" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Validate проверяет обязательные поля и координаты
+func (v *Venue) Validate() error {
+	if strings.TrimSpace(v.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidVenue)
+	}
+	if v.Capacity <= 0 {
+		return fmt.Errorf("%w: capacity must be positive", ErrInvalidVenue)
+	}
+	if (v.Latitude == nil) != (v.Longitude == nil) {
+		return fmt.Errorf("%w: latitude and longitude must be set together", ErrInvalidVenue)
+	}
+	if v.Latitude != nil && (*v.Latitude < -90 || *v.Latitude > 90 || *v.Longitude < -180 || *v.Longitude > 180) {
+		return fmt.Errorf("%w: coordinates are out of range", ErrInvalidVenue)
+	}
+	return nil
+}
+
+// MapLink возвращает ссылку, которая открывается в картах на телефоне и в браузере:
+// по координатам, если они заданы, иначе по адресу
+func (v *Venue) MapLink() string {
+	var query string
+	switch {
+	case v.Latitude != nil && v.Longitude != nil:
+		query = fmt.Sprintf("%.6f,%.6f", *v.Latitude, *v.Longitude)
+	case v.Address != "":
+		query = v.Address
+	default:
+		return ""
+	}
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(query)
+}
@@ -0,0 +1,91 @@
+package entity
+
+import "time"
+
+// Типы событий, о которых сообщают вебхуки
+const (
+	WebhookEventBookingCreated   = "booking.created"
+	WebhookEventBookingConfirmed = "booking.confirmed"
+	WebhookEventBookingExpired   = "booking.expired"
+	WebhookEventBookingReminder  = "booking.reminder"
+	WebhookEventEventCancelled   = "event.cancelled"
+	WebhookEventEventReminder    = "event.reminder"
+	// WebhookEventEventCapacity - заполненность мероприятия достигла порога из booking.capacity_alert_thresholds
+	WebhookEventEventCapacity = "event.capacity_threshold"
+	// WebhookEventSalePhase - началась фаза продаж мероприятия: предпродажа, открытая продажа или закрытие
+	WebhookEventSalePhase = "event.sale_phase_started"
+)
+
+// WebhookEventTypes - все допустимые типы событий
+var WebhookEventTypes = []string{
+	WebhookEventBookingCreated,
+	WebhookEventBookingConfirmed,
+	WebhookEventBookingExpired,
+	WebhookEventBookingReminder,
+	WebhookEventEventCancelled,
+	WebhookEventEventReminder,
+	WebhookEventEventCapacity,
+	WebhookEventSalePhase,
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// Webhook - callback URL организатора, на который отправляются уведомления
+type Webhook struct {
+	ID         int64     `json:"id" db:"id"`
+	EventID    *int64    `json:"event_id,omitempty" db:"event_id"` // nil - все мероприятия
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"-" db:"secret"`                // ключ HMAC-подписи, показывается только при создании
+	EventTypes []string  `json:"event_types" db:"event_types"` // пусто - все типы событий
+	Active     bool      `json:"active" db:"active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribed проверяет, подписан ли вебхук на тип события
+func (w *Webhook) Subscribed(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookWithSecret возвращается один раз при создании вебхука
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery - запись журнала доставки
+type WebhookDelivery struct {
+	ID             int64                 `json:"id" db:"id"`
+	WebhookID      int64                 `json:"webhook_id" db:"webhook_id"`
+	DeliveryID     string                `json:"delivery_id" db:"delivery_id"`
+	EventType      string                `json:"event_type" db:"event_type"`
+	Payload        string                `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	ResponseStatus int                   `json:"response_status" db:"response_status"`
+	Error          string                `json:"error,omitempty" db:"error"`
+	DurationMs     int64                 `json:"duration_ms" db:"duration_ms"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+}
+
+// WebhookPayload - тело запроса, которое получает подписчик
+type WebhookPayload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	EventID   int64                  `json:"event_id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
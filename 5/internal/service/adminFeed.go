@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+const (
+	// adminFeedBuffer - сколько событий ждёт отправки клиенту; остальные отбрасываются
+	adminFeedBuffer = 64
+	// adminFeedResubscribeDelay - пауза перед повторной подпиской после обрыва
+	adminFeedResubscribeDelay = 3 * time.Second
+	// adminFeedPublishTimeout ограничивает публикацию события DLQ, у которой нет контекста запроса
+	adminFeedPublishTimeout = 5 * time.Second
+
+	// AdminFeedTaskFailed - тип события ленты о задаче, ушедшей в DLQ
+	AdminFeedTaskFailed = "dlq.task_failed"
+)
+
+// adminFeedTopics - в какую тему ленты попадает событие вебхука; остальные события в ленту не идут
+var adminFeedTopics = map[string]string{
+	entity.WebhookEventBookingCreated:   entity.AdminFeedBookings,
+	entity.WebhookEventBookingConfirmed: entity.AdminFeedConfirmations,
+	entity.WebhookEventBookingExpired:   entity.AdminFeedExpirations,
+}
+
+// AdminFeedPublisher рассылает событие ленты админки всем репликам
+type AdminFeedPublisher interface {
+	Publish(ctx context.Context, event *entity.AdminFeedEvent) error
+}
+
+// AdminFeedListener получает события, опубликованные AdminFeedPublisher.
+// Listen блокируется до отмены ctx или обрыва подписки.
+type AdminFeedListener interface {
+	Listen(ctx context.Context, handle func(event *entity.AdminFeedEvent)) error
+}
+
+// feedingWebhookService дублирует в ленту админки события бронирований, которые обработчики
+// задач очереди рассылают вебхукам. Обработчик вызывает Dispatch только на первой попытке
+// задачи, поэтому повторы задачи не дублируют события и в ленте.
+type feedingWebhookService struct {
+	WebhookService
+	feed AdminFeedPublisher
+}
+
+// WithAdminFeed оборачивает рассылку вебхуков публикацией в ленту админки
+func WithAdminFeed(webhooks WebhookService, feed AdminFeedPublisher) WebhookService {
+	return &feedingWebhookService{WebhookService: webhooks, feed: feed}
+}
+
+func (s *feedingWebhookService) Dispatch(ctx context.Context, eventID int64, eventType string, data map[string]interface{}) error {
+	if topic, ok := adminFeedTopics[eventType]; ok {
+		event := &entity.AdminFeedEvent{
+			Topic:   topic,
+			Type:    eventType,
+			EventID: eventID,
+			Data:    data,
+			At:      time.Now(),
+		}
+		if bookingID, ok := data["booking_id"].(int64); ok {
+			event.BookingID = bookingID
+		}
+		// Лента - вспомогательный канал: её сбой не мешает доставке вебхуков
+		if err := s.feed.Publish(ctx, event); err != nil {
+			log.Printf("Ошибка при публикации события %s в ленту админки: %v", eventType, err)
+		}
+	}
+
+	return s.WebhookService.Dispatch(ctx, eventID, eventType, data)
+}
+
+// AdminFeed раздаёт события ленты подключённым к этой реплике администраторам. Каждый
+// подписчик получает только выбранные темы; если он не успевает читать, новые события
+// для него отбрасываются, чтобы медленный клиент не задерживал остальных.
+type AdminFeed struct {
+	listener AdminFeedListener
+
+	mu          sync.Mutex
+	subscribers map[*AdminFeedSubscription]struct{}
+	closed      bool
+}
+
+func NewAdminFeed(listener AdminFeedListener) *AdminFeed {
+	return &AdminFeed{
+		listener:    listener,
+		subscribers: make(map[*AdminFeedSubscription]struct{}),
+	}
+}
+
+// AdminFeedSubscription - подписка одного клиента на темы ленты
+type AdminFeedSubscription struct {
+	feed   *AdminFeed
+	events chan *entity.AdminFeedEvent
+	topics map[string]bool
+	// dropped - сколько событий отброшено с последнего вызова Dropped
+	dropped int
+}
+
+// Run слушает события до отмены ctx и переподписывается при обрыве. После остановки
+// каналы всех подписок закрываются, чтобы HTTP-сервер мог дождаться завершения соединений.
+func (f *AdminFeed) Run(ctx context.Context) {
+	defer f.close()
+
+	for {
+		err := f.listener.Listen(ctx, f.broadcast)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Подписка на ленту админки прервалась: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(adminFeedResubscribeDelay):
+		}
+	}
+}
+
+// Subscribe подписывает клиента на темы ленты. Канал подписки закрывается при остановке сервиса.
+func (f *AdminFeed) Subscribe(topics []string) *AdminFeedSubscription {
+	sub := &AdminFeedSubscription{
+		feed:   f,
+		events: make(chan *entity.AdminFeedEvent, adminFeedBuffer),
+	}
+	sub.setTopics(topics)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(sub.events)
+	} else {
+		f.subscribers[sub] = struct{}{}
+	}
+	return sub
+}
+
+// Events возвращает канал событий подписки
+func (s *AdminFeedSubscription) Events() <-chan *entity.AdminFeedEvent {
+	return s.events
+}
+
+// SetTopics заменяет темы подписки
+func (s *AdminFeedSubscription) SetTopics(topics []string) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	s.setTopics(topics)
+}
+
+// Topics возвращает темы подписки в порядке entity.AdminFeedTopics
+func (s *AdminFeedSubscription) Topics() []string {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	topics := make([]string, 0, len(s.topics))
+	for _, topic := range entity.AdminFeedTopics {
+		if s.topics[topic] {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// Dropped возвращает число отброшенных событий и обнуляет счётчик
+func (s *AdminFeedSubscription) Dropped() int {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// Close отписывает клиента
+func (s *AdminFeedSubscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	delete(s.feed.subscribers, s)
+}
+
+func (s *AdminFeedSubscription) setTopics(topics []string) {
+	s.topics = make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+}
+
+func (f *AdminFeed) broadcast(event *entity.AdminFeedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subscribers {
+		if !sub.topics[event.Topic] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+func (f *AdminFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for sub := range f.subscribers {
+		close(sub.events)
+		delete(f.subscribers, sub)
+	}
+}
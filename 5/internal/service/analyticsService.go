@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// trendPoints - сколько периодов показывают тренды без явного начала: месяц по дням,
+// квартал по неделям, год по месяцам
+var trendPoints = map[string]int{
+	entity.TrendPeriodDay:   30,
+	entity.TrendPeriodWeek:  12,
+	entity.TrendPeriodMonth: 12,
+}
+
+// maxTrendPoints ограничивает длину ряда: год по дням
+const maxTrendPoints = 366
+
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+}
+
+// NewAnalyticsService creates a new instance of AnalyticsService
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository) AnalyticsService {
+	return &analyticsService{analyticsRepo: analyticsRepo}
+}
+
+func (s *analyticsService) GetSystemStats(ctx context.Context) (*entity.SystemStats, error) {
+	return s.analyticsRepo.GetSystemStats(ctx)
+}
+
+func (s *analyticsService) GetBookingTrends(ctx context.Context, period string, from, to time.Time) (*entity.BookingTrends, error) {
+	if err := entity.ValidateTrendPeriod(period); err != nil {
+		return nil, err
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		// Конец диапазона тоже попадает в ряд, поэтому отступаем на points-1 периодов
+		from = addPeriods(to, period, -(trendPoints[period] - 1))
+	}
+	if !from.Before(to) || addPeriods(from, period, maxTrendPoints).Before(to) {
+		return nil, entity.ErrInvalidTrendRange
+	}
+
+	return s.analyticsRepo.GetBookingTrends(ctx, period, from, to)
+}
+
+func addPeriods(t time.Time, period string, n int) time.Time {
+	switch period {
+	case entity.TrendPeriodWeek:
+		return t.AddDate(0, 0, 7*n)
+	case entity.TrendPeriodMonth:
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
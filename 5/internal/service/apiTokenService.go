@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// apiTokenPrefix отличает токены интеграций от JWT в заголовке Authorization
+	apiTokenPrefix = "ebk_"
+	// apiTokenTouchInterval - как часто записывать last_used_at, чтобы не обновлять строку на каждый запрос
+	apiTokenTouchInterval = time.Minute
+)
+
+// CreateAPITokenRequest represents the data needed to issue an organizer API token
+type CreateAPITokenRequest struct {
+	OrganizerID int64      `json:"organizer_id" binding:"required,min=1"`
+	Name        string     `json:"name" binding:"required,min=1,max=100"`
+	Scopes      []string   `json:"scopes" binding:"required,min=1"`
+	RateLimit   int        `json:"rate_limit,omitempty" binding:"omitempty,min=1,max=10000"` // запросов в минуту, по умолчанию из конфигурации
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+type apiTokenService struct {
+	tokenRepo        repository.APITokenRepository
+	userRepo         repository.UserRepository
+	defaultRateLimit int
+}
+
+// NewAPITokenService creates a new instance of APITokenService
+func NewAPITokenService(
+	tokenRepo repository.APITokenRepository,
+	userRepo repository.UserRepository,
+	defaultRateLimit int,
+) APITokenService {
+	if defaultRateLimit <= 0 {
+		defaultRateLimit = 60
+	}
+
+	return &apiTokenService{
+		tokenRepo:        tokenRepo,
+		userRepo:         userRepo,
+		defaultRateLimit: defaultRateLimit,
+	}
+}
+
+// CreateToken выпускает токен; открытое значение возвращается только здесь
+func (s *apiTokenService) CreateToken(ctx context.Context, req *CreateAPITokenRequest) (*entity.APITokenWithSecret, error) {
+	for _, scope := range req.Scopes {
+		if !knownAPITokenScope(scope) {
+			return nil, fmt.Errorf("%w: %s", entity.ErrUnknownScope, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", entity.ErrInvalidInput)
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, req.OrganizerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get organizer: %w", err)
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api token: %w", err)
+	}
+	plain := apiTokenPrefix + secret
+
+	token := &entity.APIToken{
+		OrganizerID: req.OrganizerID,
+		Name:        req.Name,
+		Prefix:      plain[:len(apiTokenPrefix)+8],
+		TokenHash:   hashAPIToken(plain),
+		Scopes:      req.Scopes,
+		RateLimit:   req.RateLimit,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if token.RateLimit == 0 {
+		token.RateLimit = s.defaultRateLimit
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &entity.APITokenWithSecret{APIToken: *token, Token: plain}, nil
+}
+
+// ListTokens возвращает токены организатора или все токены, если organizerID == nil
+func (s *apiTokenService) ListTokens(ctx context.Context, organizerID *int64) ([]*entity.APIToken, error) {
+	var (
+		tokens []*entity.APIToken
+		err    error
+	)
+	if organizerID != nil {
+		tokens, err = s.tokenRepo.GetByOrganizer(ctx, *organizerID)
+	} else {
+		tokens, err = s.tokenRepo.GetAll(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *apiTokenService) RevokeToken(ctx context.Context, id int64) (*entity.APIToken, error) {
+	return s.tokenRepo.Revoke(ctx, id)
+}
+
+// Authenticate находит действующий токен по открытому значению и отмечает его использование.
+// Неизвестный, отозванный и просроченный токены неотличимы для вызывающего.
+func (s *apiTokenService) Authenticate(ctx context.Context, plain string) (*entity.APIToken, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, hashAPIToken(plain))
+	if errors.Is(err, entity.ErrAPITokenNotFound) {
+		return nil, entity.ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !token.Active(now) {
+		return nil, entity.ErrInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.tokenRepo.TouchLastUsed(ctx, token.ID, now); err != nil {
+			// Учёт использования не должен блокировать запрос интеграции
+			logrus.Warnf("Failed to track api token %d usage: %v", token.ID, err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+
+	return token, nil
+}
+
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func knownAPITokenScope(scope string) bool {
+	for _, s := range entity.APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
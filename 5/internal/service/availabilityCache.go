@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// AvailabilityCache хранит занятость мест мероприятий между запросами списка мероприятий
+type AvailabilityCache interface {
+	Get(ctx context.Context, eventIDs []int64) (map[int64]entity.SeatUsage, error)
+	Set(ctx context.Context, usage map[int64]entity.SeatUsage) error
+	Invalidate(ctx context.Context, eventIDs ...int64) error
+}
+
+// AvailabilityInvalidator сбрасывает закэшированную занятость мест мероприятий.
+// Его реализует репозиторий мероприятий, обёрнутый WithAvailabilityCache.
+type AvailabilityInvalidator interface {
+	InvalidateAvailability(ctx context.Context, eventIDs ...int64)
+}
+
+// cachedEventRepository отдаёт список мероприятий с занятостью мест из кэша: подсчёт
+// бронирований по всем мероприятиям - самая тяжёлая часть GET /events.
+// GetByID не кэшируется: по нему подтверждение и изменение бронирований проверяют свободные места.
+type cachedEventRepository struct {
+	repository.EventRepository
+	cache AvailabilityCache
+}
+
+// WithAvailabilityCache оборачивает репозиторий мероприятий кэшем занятости мест.
+// Сервисы сбрасывают кэш после изменения бронирований, пулов и холдов; между подсчётом
+// и записью в кэш изменение может проскочить, поэтому срок жизни ключей должен быть коротким
+func WithAvailabilityCache(repo repository.EventRepository, cache AvailabilityCache) repository.EventRepository {
+	return &cachedEventRepository{EventRepository: repo, cache: cache}
+}
+
+func (r *cachedEventRepository) GetAll(ctx context.Context) ([]*entity.EventWithAvailability, error) {
+	events, err := r.EventRepository.ListEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	// Недоступный Redis не должен ломать список мероприятий
+	usage, err := r.cache.Get(ctx, ids)
+	if err != nil {
+		log.Printf("Кэш свободных мест недоступен, места считаются по базе: %v", err)
+		return r.EventRepository.GetAll(ctx)
+	}
+
+	var missing []int64
+	for _, id := range ids {
+		if _, ok := usage[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		counted, err := r.EventRepository.GetSeatUsage(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.cache.Set(ctx, counted); err != nil {
+			log.Printf("Ошибка при записи свободных мест в кэш: %v", err)
+		}
+		for id, u := range counted {
+			usage[id] = u
+		}
+	}
+
+	result := make([]*entity.EventWithAvailability, len(events))
+	for i, event := range events {
+		result[i] = event.WithSeatUsage(usage[event.ID])
+	}
+	return result, nil
+}
+
+// Delete сбрасывает кэш сам: удаление мероприятия проходит через этот репозиторий
+func (r *cachedEventRepository) Delete(ctx context.Context, id int64, outbox []*entity.OutboxMessage) error {
+	if err := r.EventRepository.Delete(ctx, id, outbox); err != nil {
+		return err
+	}
+	r.InvalidateAvailability(ctx, id)
+	return nil
+}
+
+func (r *cachedEventRepository) InvalidateAvailability(ctx context.Context, eventIDs ...int64) {
+	// Ключ, который не удалось удалить, истечёт по сроку жизни
+	if err := r.cache.Invalidate(ctx, eventIDs...); err != nil {
+		log.Printf("Ошибка при сбросе кэша свободных мест мероприятий %v: %v", eventIDs, err)
+	}
+}
+
+// invalidateAvailability сбрасывает кэш занятости мест, если репозиторий мероприятий кэширован
+func invalidateAvailability(ctx context.Context, eventRepo repository.EventRepository, eventIDs ...int64) {
+	if invalidator, ok := eventRepo.(AvailabilityInvalidator); ok {
+		invalidator.InvalidateAvailability(ctx, eventIDs...)
+	}
+}
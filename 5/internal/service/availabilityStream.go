@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// availabilityResubscribeDelay - пауза перед повторной подпиской после обрыва
+const availabilityResubscribeDelay = 3 * time.Second
+
+// AvailabilityPublisher сообщает всем репликам, что у мероприятий изменились свободные места
+type AvailabilityPublisher interface {
+	Publish(ctx context.Context, eventIDs ...int64) error
+}
+
+// AvailabilityListener получает id мероприятий, опубликованные AvailabilityPublisher.
+// Listen блокируется до отмены ctx или обрыва подписки.
+type AvailabilityListener interface {
+	Listen(ctx context.Context, handle func(eventID int64)) error
+}
+
+// notifyingEventRepository публикует изменение свободных мест везде, где сервисы сбрасывают
+// кэш занятости, а также после изменения и удаления мероприятия
+type notifyingEventRepository struct {
+	repository.EventRepository
+	publisher AvailabilityPublisher
+}
+
+// WithAvailabilityNotifications оборачивает репозиторий мероприятий публикацией изменений мест.
+// Оборачивать нужно поверх WithAvailabilityCache: сброс кэша передаётся внутреннему репозиторию
+func WithAvailabilityNotifications(repo repository.EventRepository, publisher AvailabilityPublisher) repository.EventRepository {
+	return &notifyingEventRepository{EventRepository: repo, publisher: publisher}
+}
+
+// Update публикует изменение: от вместимости зависят свободные места
+func (r *notifyingEventRepository) Update(ctx context.Context, event *entity.Event) error {
+	if err := r.EventRepository.Update(ctx, event); err != nil {
+		return err
+	}
+	r.publish(ctx, event.ID)
+	return nil
+}
+
+func (r *notifyingEventRepository) Delete(ctx context.Context, id int64, outbox []*entity.OutboxMessage) error {
+	if err := r.EventRepository.Delete(ctx, id, outbox); err != nil {
+		return err
+	}
+	r.publish(ctx, id)
+	return nil
+}
+
+func (r *notifyingEventRepository) InvalidateAvailability(ctx context.Context, eventIDs ...int64) {
+	invalidateAvailability(ctx, r.EventRepository, eventIDs...)
+	r.publish(ctx, eventIDs...)
+}
+
+func (r *notifyingEventRepository) publish(ctx context.Context, eventIDs ...int64) {
+	// Пропущенное уведомление не ломает бронирование: страница получит места при следующем изменении
+	if err := r.publisher.Publish(ctx, eventIDs...); err != nil {
+		log.Printf("Ошибка при публикации изменения свободных мест мероприятий %v: %v", eventIDs, err)
+	}
+}
+
+// AvailabilityStream раздаёт открытым на этой реплике потокам свободные места мероприятий.
+// На уведомление места перечитываются один раз на все потоки мероприятия и только если
+// такие потоки есть; медленный клиент получает последнее значение, промежуточные отбрасываются.
+type AvailabilityStream struct {
+	eventRepo repository.EventRepository
+	listener  AvailabilityListener
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan *entity.AvailabilityUpdate]struct{}
+	closed      bool
+}
+
+func NewAvailabilityStream(eventRepo repository.EventRepository, listener AvailabilityListener) *AvailabilityStream {
+	return &AvailabilityStream{
+		eventRepo:   eventRepo,
+		listener:    listener,
+		subscribers: make(map[int64]map[chan *entity.AvailabilityUpdate]struct{}),
+	}
+}
+
+// Run слушает уведомления до отмены ctx и переподписывается при обрыве. После остановки
+// каналы всех потоков закрываются, чтобы HTTP-сервер мог дождаться завершения запросов.
+func (s *AvailabilityStream) Run(ctx context.Context) {
+	defer s.close()
+
+	for {
+		err := s.listener.Listen(ctx, func(eventID int64) {
+			s.refresh(ctx, eventID)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Подписка на изменения свободных мест прервалась: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(availabilityResubscribeDelay):
+		}
+
+		// Пока подписки не было, уведомления терялись
+		for _, eventID := range s.subscribedEvents() {
+			s.refresh(ctx, eventID)
+		}
+	}
+}
+
+// Subscribe открывает поток мероприятия: возвращает текущие места, канал следующих
+// обновлений и функцию отписки. Канал закрывается при остановке сервиса.
+func (s *AvailabilityStream) Subscribe(ctx context.Context, eventID int64) (*entity.AvailabilityUpdate, <-chan *entity.AvailabilityUpdate, func(), error) {
+	updates := make(chan *entity.AvailabilityUpdate, 1)
+
+	// Подписка раньше чтения мест: изменение между ними придёт в канал, а не потеряется
+	s.mu.Lock()
+	if s.closed {
+		close(updates)
+	} else {
+		if s.subscribers[eventID] == nil {
+			s.subscribers[eventID] = make(map[chan *entity.AvailabilityUpdate]struct{})
+		}
+		s.subscribers[eventID][updates] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers[eventID], updates)
+		if len(s.subscribers[eventID]) == 0 {
+			delete(s.subscribers, eventID)
+		}
+	}
+
+	current, err := s.load(ctx, eventID)
+	if err != nil {
+		unsubscribe()
+		return nil, nil, nil, err
+	}
+	return current, updates, unsubscribe, nil
+}
+
+func (s *AvailabilityStream) load(ctx context.Context, eventID int64) (*entity.AvailabilityUpdate, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get event availability: %w", err)
+	}
+	return event.AvailabilityUpdate(), nil
+}
+
+func (s *AvailabilityStream) refresh(ctx context.Context, eventID int64) {
+	s.mu.Lock()
+	_, watched := s.subscribers[eventID]
+	s.mu.Unlock()
+	if !watched {
+		return
+	}
+
+	update, err := s.load(ctx, eventID)
+	if errors.Is(err, entity.ErrEventNotFound) {
+		update = &entity.AvailabilityUpdate{EventID: eventID, Deleted: true, UpdatedAt: time.Now()}
+	} else if err != nil {
+		log.Printf("Ошибка при чтении свободных мест мероприятия %d: %v", eventID, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for updates := range s.subscribers[eventID] {
+		// В буфере не больше одного значения: устаревшее заменяется свежим
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- update
+	}
+}
+
+func (s *AvailabilityStream) subscribedEvents() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int64, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (s *AvailabilityStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for eventID, subscribers := range s.subscribers {
+		for updates := range subscribers {
+			close(updates)
+		}
+		delete(s.subscribers, eventID)
+	}
+}
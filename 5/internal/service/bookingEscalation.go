@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/email"
+)
+
+// EscalateConfirmation выполняет шаг цепочки напоминаний о неподтверждённом бронировании
+// и планирует следующий. expiresAt - срок, от которого строилась цепочка: если бронирование
+// с тех пор продлено, цепочка устарела и шаг пропускается.
+func (s *bookingService) EscalateConfirmation(ctx context.Context, bookingID int64, step string, expiresAt time.Time) error {
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return fmt.Errorf("бронирование не найдено: %w", err)
+	}
+
+	if booking.Status != entity.BookingStatusPending {
+		return nil // Бронирование уже подтверждено или отменено
+	}
+	if booking.ExpiresAt.Unix() != expiresAt.Unix() {
+		log.Printf("Цепочка напоминаний для бронирования %d устарела, шаг %s пропущен", bookingID, step)
+		return nil
+	}
+
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("мероприятие не найдено: %w", err)
+	}
+	event := &eventWithAvailability.Event
+
+	escalation := event.ConfirmationEscalation
+	if escalation.Disabled {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("пользователь не найден: %w", err)
+	}
+
+	switch step {
+	case entity.EscalationStepTelegram:
+		if err := s.sendConfirmationReminder(booking, event, user); err != nil {
+			return err
+		}
+	case entity.EscalationStepFinal:
+		s.publishEmail(ctx, email.TemplateBookingReminder, booking.ID)
+	case entity.EscalationStepAutoExtend:
+		extended, err := s.autoExtendBooking(ctx, booking, event, user, escalation)
+		if err != nil {
+			return err
+		}
+		if extended {
+			return nil // Цепочка перезапущена от нового срока
+		}
+	default:
+		return fmt.Errorf("неизвестный шаг цепочки напоминаний: %s", step)
+	}
+
+	s.scheduleEscalation(ctx, booking.ID, expiresAt, escalation, step)
+	return nil
+}
+
+// sendConfirmationReminder напоминает в Telegram о необходимости подтвердить бронирование
+func (s *bookingService) sendConfirmationReminder(booking *entity.Booking, event *entity.Event, user *entity.User) error {
+	if s.telegramBot == nil || !user.WantsTelegramFor(entity.NotificationReminders) {
+		return nil
+	}
+
+	minutesLeft := int(time.Until(booking.ExpiresAt).Minutes())
+	if minutesLeft <= 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf(
+		"⏰ Напоминание о бронировании\n\n"+
+			"Мероприятие: %s\n"+
+			"Дата: %s\n"+
+			"Количество мест: %d\n"+
+			"Номер брони: #%d\n"+
+			"Осталось времени: %d минут\n\n"+
+			"Не забудьте подтвердить бронирование!",
+		event.Title,
+		event.Date.Format("02.01.2006 в 15:04"),
+		booking.Seats,
+		booking.ID,
+		minutesLeft,
+	)
+
+	if err := s.telegramBot.SendMessageWithKeyboard(user.TelegramID, message, BookingActionsKeyboard(booking.ID)); err != nil {
+		return fmt.Errorf("не удалось отправить напоминание о бронировании %d: %w", booking.ID, err)
+	}
+	return nil
+}
+
+// autoExtendBooking однократно продлевает бронирование лояльного пользователя,
+// переносит задачу истечения и перезапускает цепочку напоминаний от нового срока
+func (s *bookingService) autoExtendBooking(
+	ctx context.Context,
+	booking *entity.Booking,
+	event *entity.Event,
+	user *entity.User,
+	escalation entity.ConfirmationEscalation,
+) (bool, error) {
+	escalation = escalation.WithDefaults()
+	if escalation.AutoExtendMinutes <= 0 {
+		return false, nil
+	}
+
+	loyalty, err := s.loyaltyScore(ctx, booking.UserID)
+	if err != nil {
+		return false, err
+	}
+	if loyalty < escalation.LoyaltyThreshold {
+		log.Printf("Бронирование %d не продлено: оценка лояльности %.1f ниже порога %.1f",
+			booking.ID, loyalty, escalation.LoyaltyThreshold)
+		return false, nil
+	}
+
+	expiresAt := booking.ExpiresAt.Add(time.Duration(escalation.AutoExtendMinutes) * time.Minute)
+	extended, err := s.bookingRepo.ExtendExpiration(ctx, booking.ID, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при продлении бронирования: %w", err)
+	}
+	if !extended {
+		return false, nil // Бронирование уже продлевалось
+	}
+	booking.ExpiresAt = expiresAt
+	s.scheduleExpiry(ctx, booking)
+
+	log.Printf("Бронирование %d продлено до %s (оценка лояльности %.1f)",
+		booking.ID, expiresAt.Format(time.RFC3339), loyalty)
+
+	// Прежняя задача истечения пропустит бронирование, срок которого ещё не наступил
+	if s.queue != nil {
+		expireTask := &Task{
+			ID:   fmt.Sprintf("expire_booking_%d_%d", booking.ID, expiresAt.Unix()),
+			Type: TaskTypeExpireBooking,
+			Data: map[string]interface{}{
+				"booking_id": booking.ID,
+				"event_id":   booking.EventID,
+				"user_id":    booking.UserID,
+				"expires_at": expiresAt.Format(time.RFC3339),
+			},
+			ExecuteAt:  expiresAt,
+			MaxRetries: 3,
+		}
+		if err := s.queue.Publish(ctx, expireTask); err != nil {
+			return false, fmt.Errorf("ошибка при планировании истечения бронирования: %w", err)
+		}
+	}
+
+	if s.telegramBot != nil && user.WantsTelegramFor(entity.NotificationBookingUpdates) {
+		message := fmt.Sprintf(
+			"⏳ Бронирование #%d на мероприятие «%s» продлено.\n"+
+				"Подтвердите его до: %s",
+			booking.ID,
+			event.Title,
+			expiresAt.Format("02.01.2006 в 15:04"),
+		)
+		if err := s.telegramBot.SendMessageWithKeyboard(user.TelegramID, message, BookingActionsKeyboard(booking.ID)); err != nil {
+			log.Printf("Ошибка при отправке Telegram уведомления пользователю %d: %v", user.ID, err)
+		}
+	}
+
+	s.scheduleEscalation(ctx, booking.ID, expiresAt, escalation, "")
+	return true, nil
+}
+
+// loyaltyScore вычисляет оценку лояльности пользователя по истории его бронирований
+func (s *bookingService) loyaltyScore(ctx context.Context, userID int64) (float64, error) {
+	bookings, err := s.bookingRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении бронирований пользователя: %w", err)
+	}
+
+	stats := &entity.UserStats{TotalBookings: len(bookings)}
+	events := make(map[int64]bool)
+	for _, booking := range bookings {
+		switch booking.Status {
+		case entity.BookingStatusConfirmed:
+			stats.ConfirmedBookings++
+		case entity.BookingStatusPending:
+			stats.PendingBookings++
+		case entity.BookingStatusCancelled:
+			stats.CancelledBookings++
+		case entity.BookingStatusExpired:
+			stats.ExpiredBookings++
+		}
+
+		if !events[booking.EventID] {
+			events[booking.EventID] = true
+			stats.FavoriteEvents = append(stats.FavoriteEvents, &entity.EventBookingCount{EventID: booking.EventID})
+		}
+
+		if stats.LastActivity == nil || booking.UpdatedAt.After(*stats.LastActivity) {
+			lastActivity := booking.UpdatedAt
+			stats.LastActivity = &lastActivity
+		}
+	}
+
+	stats.AttendanceRate = stats.CalculateAttendanceRate()
+	return stats.CalculateLoyaltyScore(), nil
+}
+
+// scheduleEscalation планирует шаг цепочки, следующий за after
+func (s *bookingService) scheduleEscalation(ctx context.Context, bookingID int64, expiresAt time.Time, escalation entity.ConfirmationEscalation, after string) {
+	if s.queue == nil {
+		return
+	}
+
+	step, ok := escalation.NextStep(expiresAt, after, time.Now())
+	if !ok {
+		return
+	}
+
+	if err := s.queue.Publish(ctx, escalationTask(bookingID, expiresAt, step)); err != nil {
+		log.Printf("Ошибка при планировании шага %s для бронирования %d: %v", step.Name, bookingID, err)
+	}
+}
+
+// escalationTask возвращает задачу шага цепочки напоминаний
+func escalationTask(bookingID int64, expiresAt time.Time, step entity.EscalationStep) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("escalation_%s_%d_%d", step.Name, bookingID, expiresAt.Unix()),
+		Type: TaskTypeConfirmationEscalation,
+		Data: map[string]interface{}{
+			"booking_id": bookingID,
+			"step":       step.Name,
+			"expires_at": expiresAt.Format(time.RFC3339),
+		},
+		ExecuteAt:  step.At,
+		MaxRetries: 2,
+	}
+}
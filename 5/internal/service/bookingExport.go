@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/export"
+)
+
+// exportBatchSize ограничивает число строк, одновременно находящихся в памяти при выгрузке
+const exportBatchSize = 500
+
+var bookingExportHeader = []interface{}{
+	"booking_id", "status", "seats", "tier", "promo_code", "total_price", "discount_amount",
+	"expires_at", "created_at", "event_id", "event_title", "event_date", "user_id", "user_name", "user_email",
+}
+
+// ExportBookings выгружает бронирования в w в формате csv или xlsx.
+// Данные читаются пачками по курсору id, поэтому объём памяти не зависит от размера мероприятия.
+// Ошибки формата и отсутствующего мероприятия возвращаются до записи первого байта.
+func (s *bookingService) ExportBookings(ctx context.Context, eventID *int64, format string, w io.Writer) error {
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		return export.ErrUnsupportedFormat
+	}
+
+	if eventID != nil {
+		if _, err := s.eventRepo.GetByID(ctx, *eventID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return entity.ErrEventNotFound
+			}
+			return fmt.Errorf("ошибка при получении мероприятия: %w", err)
+		}
+	}
+
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.WriteRow(bookingExportHeader); err != nil {
+		return fmt.Errorf("ошибка записи заголовка выгрузки: %w", err)
+	}
+
+	var afterID int64
+	for {
+		batch, err := s.bookingRepo.GetExportBatch(ctx, eventID, afterID, exportBatchSize)
+		if err != nil {
+			return fmt.Errorf("ошибка при выгрузке бронирований: %w", err)
+		}
+
+		for _, row := range batch {
+			if err := writer.WriteRow(bookingExportValues(row)); err != nil {
+				return fmt.Errorf("ошибка записи бронирования %d: %w", row.BookingID, err)
+			}
+		}
+
+		if len(batch) < exportBatchSize {
+			break
+		}
+		afterID = batch[len(batch)-1].BookingID
+	}
+
+	return writer.Close()
+}
+
+func bookingExportValues(row *entity.BookingExportRow) []interface{} {
+	return []interface{}{
+		row.BookingID,
+		string(row.Status),
+		row.Seats,
+		row.TierName,
+		row.PromoCode,
+		row.TotalPrice,
+		row.DiscountAmount,
+		row.ExpiresAt,
+		row.CreatedAt,
+		row.EventID,
+		row.EventTitle,
+		row.EventDate,
+		row.UserID,
+		row.UserName,
+		row.UserEmail,
+	}
+}
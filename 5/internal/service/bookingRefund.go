@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// refundFor рассчитывает возврат по политике мероприятия. Оплаченным считается только
+// подтверждённое бронирование с ненулевой суммой, для остальных возврата нет.
+func refundFor(locked *entity.Booking, event *entity.Event, reason string, now time.Time) *entity.Refund {
+	if locked.Status != entity.BookingStatusConfirmed || locked.TotalPrice <= 0 {
+		return nil
+	}
+
+	quote := event.CancellationPolicy.Evaluate(event.Date, locked.TotalPrice, now)
+	return &entity.Refund{
+		BookingID:  locked.ID,
+		EventID:    locked.EventID,
+		UserID:     locked.UserID,
+		PaidAmount: locked.TotalPrice,
+		Percent:    quote.RefundPercent,
+		Amount:     quote.RefundAmount,
+		Status:     entity.RefundStatusPending,
+		Reason:     reason,
+	}
+}
+
+// refundOutbox ставит задачу на выплату возврата; без очереди возврат остаётся в статусе pending
+func (s *bookingService) refundOutbox(ctx context.Context, refund *entity.Refund) []*entity.OutboxMessage {
+	if s.queue == nil {
+		return nil
+	}
+
+	return outboxMessages(ctx, []*Task{{
+		ID:   fmt.Sprintf("refund_%d", refund.BookingID),
+		Type: TaskTypeProcessRefund,
+		Data: map[string]interface{}{
+			"refund_id":  refund.ID,
+			"booking_id": refund.BookingID,
+			"amount":     refund.Amount,
+		},
+		ExecuteAt:  time.Now(),
+		MaxRetries: 5,
+	}})
+}
+
+// ProcessRefund закрывает возврат, который не нужно выплачивать. Платёжного шлюза нет,
+// поэтому ненулевой возврат остаётся в pending, пока администратор не подтвердит
+// выплату вне системы (SettleRefundManually): без подтверждения выплаты возврат
+// не может считаться обработанным. Повторная задача для закрытого возврата ничего не делает.
+func (s *bookingService) ProcessRefund(ctx context.Context, refundID int64) (*entity.Refund, error) {
+	refund, err := s.refundRepo.GetByID(ctx, refundID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении возврата: %w", err)
+	}
+	if refund.Status != entity.RefundStatusPending {
+		return refund, nil
+	}
+
+	if refund.Amount > 0 {
+		log.Printf("Возврат ждёт ручной выплаты: ID=%d, бронирование=%d, сумма=%.2f (%.0f%% от %.2f)",
+			refund.ID, refund.BookingID, refund.Amount, refund.Percent, refund.PaidAmount)
+		return refund, nil
+	}
+
+	return s.settleRefund(ctx, refund, entity.RefundStatusProcessed)
+}
+
+// SettleRefundManually фиксирует выплату, которую администратор сделал вне системы
+func (s *bookingService) SettleRefundManually(ctx context.Context, refundID int64) (*entity.Refund, error) {
+	refund, err := s.refundRepo.GetByID(ctx, refundID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении возврата: %w", err)
+	}
+	if refund.Status != entity.RefundStatusPending {
+		return nil, entity.ErrRefundNotPending
+	}
+
+	settled, err := s.settleRefund(ctx, refund, entity.RefundStatusSettledManually)
+	if err != nil {
+		return nil, err
+	}
+	if settled.Status != entity.RefundStatusSettledManually {
+		// Возврат обработали параллельно
+		return nil, entity.ErrRefundNotPending
+	}
+	return settled, nil
+}
+
+// settleRefund переводит ожидающий возврат в итоговый статус
+func (s *bookingService) settleRefund(ctx context.Context, refund *entity.Refund, status entity.RefundStatus) (*entity.Refund, error) {
+	updated, err := s.refundRepo.MarkProcessed(ctx, refund.ID, status)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении возврата: %w", err)
+	}
+	if updated {
+		now := time.Now()
+		refund.Status = status
+		refund.ProcessedAt = &now
+	}
+
+	return refund, nil
+}
+
+// GetEventRefunds возвращает возвраты по мероприятию с итоговыми суммами
+func (s *bookingService) GetEventRefunds(ctx context.Context, eventID int64) (*entity.RefundReport, error) {
+	if _, err := s.eventRepo.GetByID(ctx, eventID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("ошибка при получении мероприятия: %w", err)
+	}
+
+	refunds, err := s.refundRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении возвратов: %w", err)
+	}
+
+	return entity.NewRefundReport(eventID, refunds), nil
+}
@@ -1,670 +1,1118 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"time"
-
-	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
-)
-
-// BookSeatsRequest представляет данные для бронирования мест
-type BookSeatsRequest struct {
-	EventID            int64 `json:"event_id" binding:"required"`
-	UserID             int64 `json:"user_id" binding:"required"`
-	Seats              int   `json:"seats" binding:"required,min=1,max=50"`
-	ReservationTimeout int   `json:"reservation_timeout" binding:"min=1,max=1440"`
-}
-
-// BookingStats представляет статистику по бронированиям
-type BookingStats struct {
-	TotalBookings    int64                          `json:"total_bookings"`
-	BookingsByStatus map[entity.BookingStatus]int64 `json:"bookings_by_status"`
-	AverageSeats     float64                        `json:"average_seats"`
-	PopularEvents    []*EventBookingCount           `json:"popular_events"`
-	DailyBookings    int64                          `json:"daily_bookings"`
-	WeeklyBookings   int64                          `json:"weekly_bookings"`
-	MonthlyBookings  int64                          `json:"monthly_bookings"`
-	Revenue          float64                        `json:"revenue"`
-}
-
-// EventBookingCount представляет мероприятие с количеством бронирований
-type EventBookingCount struct {
-	EventID    int64  `json:"event_id"`
-	EventTitle string `json:"event_title"`
-	Bookings   int64  `json:"bookings"`
-	Seats      int64  `json:"seats"`
-}
-
-// BookingDetails представляет детальную информацию о бронировании
-type BookingDetails struct {
-	Booking    *entity.Booking `json:"booking"`
-	Event      *entity.Event   `json:"event"`
-	User       *entity.User    `json:"user"`
-	TimeLeft   time.Duration   `json:"time_left,omitempty"`
-	IsExpired  bool            `json:"is_expired"`
-	CanConfirm bool            `json:"can_confirm"`
-}
-
-// TaskPublisher интерфейс для публикации задач в очередь
-type TaskPublisher interface {
-	Publish(ctx context.Context, task *Task) error
-}
-
-// Task представляет задачу для очереди
-type Task struct {
-	ID         string                 `json:"id"`
-	Type       string                 `json:"type"`
-	Data       map[string]interface{} `json:"data"`
-	ExecuteAt  time.Time              `json:"execute_at"`
-	MaxRetries int                    `json:"max_retries"`
-	Attempts   int                    `json:"attempts"`
-}
-
-// Константы типов задач
-const (
-	TaskTypeExpireBooking        = "expire_booking"
-	TaskTypeSendNotification     = "send_notification"
-	TaskTypeCleanupExpired       = "cleanup_expired"
-	TaskTypeReminderNotification = "reminder_notification"
-	TaskTypeEventReminder        = "event_reminder"
-)
-
-type bookingService struct {
-	bookingRepo repository.BookingRepository
-	eventRepo   repository.EventRepository
-	userRepo    repository.UserRepository
-	queue       TaskPublisher
-	telegramBot *telegram.Bot
-}
-
-// NewBookingService создает новый экземпляр BookingService
-func NewBookingService(
-	bookingRepo repository.BookingRepository,
-	eventRepo repository.EventRepository,
-	userRepo repository.UserRepository,
-	queue TaskPublisher,
-	telegramBot *telegram.Bot,
-) BookingService {
-	return &bookingService{
-		bookingRepo: bookingRepo,
-		eventRepo:   eventRepo,
-		userRepo:    userRepo,
-		queue:       queue,
-		telegramBot: telegramBot,
-	}
-}
-
-// BookSeats создает новое бронирование мест
-func (s *bookingService) BookSeats(ctx context.Context, req *BookSeatsRequest) (*entity.Booking, error) {
-	// Валидация мероприятия
-	eventWithAvailability, err := s.eventRepo.GetByID(ctx, req.EventID)
-	if err != nil {
-		return nil, fmt.Errorf("мероприятие не найдено: %w", err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	if event.Date.Before(time.Now()) {
-		return nil, fmt.Errorf("невозможно забронировать места на прошедшее мероприятие")
-	}
-
-	if eventWithAvailability.AvailableSeats < req.Seats {
-		return nil, fmt.Errorf("недостаточно доступных мест: запрошено %d, доступно %d",
-			req.Seats, eventWithAvailability.AvailableSeats)
-	}
-
-	// Валидация пользователя
-	user, err := s.userRepo.GetByID(ctx, req.UserID)
-	if err != nil {
-		return nil, fmt.Errorf("пользователь не найден: %w", err)
-	}
-
-	// Проверка существующего бронирования
-	existingBooking, err := s.bookingRepo.GetByEventAndUser(ctx, req.EventID, req.UserID)
-	if err != nil && err != entity.ErrBookingNotFound {
-		return nil, fmt.Errorf("ошибка при проверке существующих бронирований: %w", err)
-	}
-
-	if existingBooking != nil {
-		switch existingBooking.Status {
-		case entity.BookingStatusPending:
-			return nil, fmt.Errorf("у вас уже есть ожидающее бронирование на это мероприятие")
-		case entity.BookingStatusConfirmed:
-			return nil, fmt.Errorf("у вас уже есть подтвержденное бронирование на это мероприятие")
-		}
-	}
-
-	// Установка времени резервирования по умолчанию
-	timeout := req.ReservationTimeout
-	if timeout == 0 {
-		timeout = 30
-	}
-
-	// Создание бронирования
-	booking := &entity.Booking{
-		EventID:            req.EventID,
-		UserID:             req.UserID,
-		Seats:              req.Seats,
-		Status:             entity.BookingStatusPending,
-		ReservationTimeout: timeout,
-	}
-
-	if err := s.bookingRepo.Create(ctx, booking); err != nil {
-		return nil, fmt.Errorf("ошибка при создании бронирования: %w", err)
-	}
-
-	log.Printf("Бронирование создано: ID=%d, Event=%d, User=%d, Seats=%d",
-		booking.ID, booking.EventID, booking.UserID, booking.Seats)
-
-	// Планирование задач через очередь, если доступна
-	if s.queue != nil {
-		if err := s.scheduleBookingTasks(ctx, booking); err != nil {
-			log.Printf("Ошибка при планировании задач бронирования: %v", err)
-		}
-	}
-
-	// Отправка уведомления через Telegram
-	if s.telegramBot != nil && user.TelegramID != "" {
-		go s.sendBookingCreatedNotification(booking, event, user)
-	}
-
-	return booking, nil
-}
-
-// scheduleBookingTasks планирует задачи для бронирования
-func (s *bookingService) scheduleBookingTasks(ctx context.Context, booking *entity.Booking) error {
-	// Задача на истечение срока бронирования
-	expirationTask := &Task{
-		ID:   fmt.Sprintf("expire_booking_%d_%d", booking.ID, time.Now().Unix()),
-		Type: TaskTypeExpireBooking,
-		Data: map[string]interface{}{
-			"booking_id": booking.ID,
-			"event_id":   booking.EventID,
-			"user_id":    booking.UserID,
-			"expires_at": booking.ExpiresAt.Format(time.RFC3339),
-		},
-		ExecuteAt:  booking.ExpiresAt,
-		MaxRetries: 3,
-	}
-
-	if err := s.queue.Publish(ctx, expirationTask); err != nil {
-		return fmt.Errorf("ошибка при планировании задачи истечения: %w", err)
-	}
-
-	// Задача напоминания за 15 минут до истечения
-	reminderTime := booking.ExpiresAt.Add(-15 * time.Minute)
-	if reminderTime.After(time.Now()) {
-		reminderTask := &Task{
-			ID:   fmt.Sprintf("reminder_booking_%d_%d", booking.ID, time.Now().Unix()),
-			Type: TaskTypeReminderNotification,
-			Data: map[string]interface{}{
-				"booking_id": booking.ID,
-				"event_id":   booking.EventID,
-				"user_id":    booking.UserID,
-			},
-			ExecuteAt:  reminderTime,
-			MaxRetries: 2,
-		}
-
-		if err := s.queue.Publish(ctx, reminderTask); err != nil {
-			return fmt.Errorf("ошибка при планировании задачи напоминания: %w", err)
-		}
-	}
-
-	// Уведомление о создании бронирования
-	notificationTask := &Task{
-		ID:   fmt.Sprintf("notification_booking_created_%d_%d", booking.ID, time.Now().Unix()),
-		Type: TaskTypeSendNotification,
-		Data: map[string]interface{}{
-			"notification_type": "booking_created",
-			"booking_id":        booking.ID,
-			"event_id":          booking.EventID,
-			"user_id":           booking.UserID,
-		},
-		ExecuteAt:  time.Now().Add(5 * time.Second),
-		MaxRetries: 3,
-	}
-
-	if err := s.queue.Publish(ctx, notificationTask); err != nil {
-		return fmt.Errorf("ошибка при планировании задачи уведомления: %w", err)
-	}
-
-	return nil
-}
-
-// sendBookingCreatedNotification отправляет уведомление о создании бронирования
-func (s *bookingService) sendBookingCreatedNotification(booking *entity.Booking, event *entity.Event, user *entity.User) {
-	message := fmt.Sprintf(
-		"🎫 Бронирование создано!\n\n"+
-			"Мероприятие: %s\n"+
-			"Дата: %s\n"+
-			"Количество мест: %d\n"+
-			"Номер брони: #%d\n"+
-			"Статус: Ожидание оплаты\n"+
-			"Подтвердите бронирование до: %s\n\n"+
-			"Не забудьте подтвердить бронирование вовремя!",
-		event.Title,
-		event.Date.Format("02.01.2006 в 15:04"),
-		booking.Seats,
-		booking.ID,
-		booking.ExpiresAt.Format("02.01.2006 в 15:04"),
-	)
-
-	if err := s.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-		log.Printf("Ошибка при отправке Telegram уведомления пользователю %d: %v", user.ID, err)
-	}
-}
-
-// ConfirmBooking подтверждает бронирование
-func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID int64) error {
-	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
-	if err != nil {
-		return fmt.Errorf("бронирование не найдено: %w", err)
-	}
-
-	if booking.Status != entity.BookingStatusPending {
-		return fmt.Errorf("бронирование не в статусе ожидания")
-	}
-
-	if time.Now().After(booking.ExpiresAt) {
-		if err := s.bookingRepo.UpdateStatus(ctx, bookingID, entity.BookingStatusExpired); err != nil {
-			return fmt.Errorf("ошибка при обновлении статуса истекшего бронирования: %w", err)
-		}
-		return fmt.Errorf("бронирование истекло")
-	}
-
-	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
-	}
-
-	if eventWithAvailability.AvailableSeats < booking.Seats {
-		return fmt.Errorf("недостаточно доступных мест для подтверждения")
-	}
-
-	if err := s.bookingRepo.UpdateStatus(ctx, bookingID, entity.BookingStatusConfirmed); err != nil {
-		return fmt.Errorf("ошибка при подтверждении бронирования: %w", err)
-	}
-
-	log.Printf("Бронирование подтверждено: ID=%d", bookingID)
-
-	// Отправка уведомления о подтверждении
-	if s.queue != nil {
-		notificationTask := &Task{
-			ID:   fmt.Sprintf("notification_booking_confirmed_%d_%d", bookingID, time.Now().Unix()),
-			Type: TaskTypeSendNotification,
-			Data: map[string]interface{}{
-				"notification_type": "booking_confirmed",
-				"booking_id":        bookingID,
-				"event_id":          booking.EventID,
-				"user_id":           booking.UserID,
-			},
-			ExecuteAt:  time.Now().Add(2 * time.Second),
-			MaxRetries: 3,
-		}
-
-		if err := s.queue.Publish(ctx, notificationTask); err != nil {
-			log.Printf("Ошибка при планировании уведомления о подтверждении: %v", err)
-		}
-	}
-
-	return nil
-}
-
-// CancelBooking отменяет бронирование
-func (s *bookingService) CancelBooking(ctx context.Context, bookingID int64, reason string) error {
-	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
-	if err != nil {
-		return fmt.Errorf("бронирование не найдено: %w", err)
-	}
-
-	if booking.Status == entity.BookingStatusCancelled || booking.Status == entity.BookingStatusExpired {
-		return fmt.Errorf("бронирование уже отменено")
-	}
-
-	if err := s.bookingRepo.UpdateStatus(ctx, bookingID, entity.BookingStatusCancelled); err != nil {
-		return fmt.Errorf("ошибка при отмене бронирования: %w", err)
-	}
-
-	log.Printf("Бронирование отменено: ID=%d, Причина: %s", bookingID, reason)
-
-	// Отправка уведомления об отмене
-	if s.telegramBot != nil {
-		user, err := s.userRepo.GetByID(ctx, booking.UserID)
-		if err == nil && user.TelegramID != "" {
-			eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
-			if err == nil {
-				// Преобразуем в базовый Event
-				event := &eventWithAvailability.Event
-				message := fmt.Sprintf(
-					"❌ Бронирование отменено\n\n"+
-						"Мероприятие: %s\n"+
-						"Дата: %s\n"+
-						"Количество мест: %d\n"+
-						"Причина: %s\n\n"+
-						"Если это ошибка, свяжитесь с поддержкой.",
-					event.Title,
-					event.Date.Format("02.01.2006 в 15:04"),
-					booking.Seats,
-					reason,
-				)
-
-				go s.telegramBot.SendMessage(user.TelegramID, message)
-			}
-		}
-	}
-
-	return nil
-}
-
-// GetBooking возвращает бронирование по ID
-func (s *bookingService) GetBooking(ctx context.Context, id int64) (*entity.Booking, error) {
-	booking, err := s.bookingRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирования: %w", err)
-	}
-	return booking, nil
-}
-
-// GetUserBookings возвращает все бронирования пользователя
-func (s *bookingService) GetUserBookings(ctx context.Context, userID int64) ([]*entity.Booking, error) {
-	bookings, err := s.bookingRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирований пользователя: %w", err)
-	}
-	return bookings, nil
-}
-
-// GetEventBookings возвращает все бронирования мероприятия
-func (s *bookingService) GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
-	bookings, err := s.bookingRepo.GetByEventID(ctx, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирований мероприятия: %w", err)
-	}
-	return bookings, nil
-}
-
-// CancelExpiredBookings отменяет все истекшие бронирования
-func (s *bookingService) CancelExpiredBookings(ctx context.Context) error {
-	expiredBookings, err := s.bookingRepo.GetExpiredBookings(ctx, time.Now())
-	if err != nil {
-		return fmt.Errorf("ошибка при получении истекших бронирований: %w", err)
-	}
-
-	cancelledCount := 0
-	for _, expired := range expiredBookings {
-		if err := s.bookingRepo.UpdateStatus(ctx, expired.BookingID, entity.BookingStatusExpired); err != nil {
-			log.Printf("Ошибка при отмене истекшего бронирования %d: %v", expired.BookingID, err)
-			continue
-		}
-
-		if s.telegramBot != nil && expired.TelegramID != "" {
-			message := fmt.Sprintf(
-				"⏰ Бронирование истекло\n\n"+
-					"Мероприятие: %s\n"+
-					"Бронирование #%d было автоматически отменено.\n\n"+
-					"Вы можете создать новое бронирование, если места еще доступны.",
-				expired.EventTitle,
-				expired.BookingID,
-			)
-
-			go s.telegramBot.SendMessage(expired.TelegramID, message)
-		}
-
-		cancelledCount++
-	}
-
-	log.Printf("Отменено %d истекших бронирований", cancelledCount)
-	return nil
-}
-
-// GetExpiredBookings возвращает список истекших бронирований
-func (s *bookingService) GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error) {
-	bookings, err := s.bookingRepo.GetExpiredBookings(ctx, before)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении истекших бронирований: %w", err)
-	}
-	return bookings, nil
-}
-
-// ExpireBooking помечает бронирование как истекшее
-func (s *bookingService) ExpireBooking(ctx context.Context, bookingID int64) error {
-	return s.bookingRepo.UpdateStatus(ctx, bookingID, entity.BookingStatusExpired)
-}
-
-// GetBookingsByStatus возвращает бронирования по статусу
-func (s *bookingService) GetBookingsByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error) {
-	bookings, err := s.bookingRepo.GetByStatus(ctx, status)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирований по статусу: %w", err)
-	}
-	return bookings, nil
-}
-
-// UpdateBookingSeats обновляет количество мест в бронировании
-func (s *bookingService) UpdateBookingSeats(ctx context.Context, bookingID int64, seats int) error {
-	if seats <= 0 {
-		return fmt.Errorf("количество мест должно быть положительным")
-	}
-
-	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
-	if err != nil {
-		return fmt.Errorf("бронирование не найдено: %w", err)
-	}
-
-	if booking.Status != entity.BookingStatusPending {
-		return fmt.Errorf("можно обновлять места только для бронирований в статусе ожидания")
-	}
-
-	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
-	}
-
-	seatDifference := seats - booking.Seats
-	if eventWithAvailability.AvailableSeats+seatDifference < 0 {
-		return fmt.Errorf("недостаточно доступных мест")
-	}
-
-	booking.Seats = seats
-	if err := s.bookingRepo.Update(ctx, booking); err != nil {
-		return fmt.Errorf("ошибка при обновлении количества мест: %w", err)
-	}
-
-	return nil
-}
-
-// UpdateBookingStatus обновляет статус бронирования
-func (s *bookingService) UpdateBookingStatus(ctx context.Context, bookingID int64, status entity.BookingStatus) error {
-	switch status {
-	case entity.BookingStatusPending, entity.BookingStatusConfirmed,
-		entity.BookingStatusCancelled, entity.BookingStatusExpired:
-		// Valid status
-	default:
-		return fmt.Errorf("неверный статус бронирования")
-	}
-
-	if err := s.bookingRepo.UpdateStatus(ctx, bookingID, status); err != nil {
-		return fmt.Errorf("ошибка при обновлении статуса бронирования: %w", err)
-	}
-	return nil
-}
-
-// GetBookingStats возвращает статистику по бронированиям
-func (s *bookingService) GetBookingStats(ctx context.Context) (*BookingStats, error) {
-	allBookings, err := s.bookingRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирований для статистики: %w", err)
-	}
-
-	stats := &BookingStats{
-		TotalBookings:    int64(len(allBookings)),
-		BookingsByStatus: make(map[entity.BookingStatus]int64),
-		PopularEvents:    make([]*EventBookingCount, 0),
-	}
-
-	totalSeats := 0
-	eventBookings := make(map[int64]*EventBookingCount)
-	eventTitles := make(map[int64]string)
-
-	now := time.Now()
-	dailyCount := int64(0)
-	weeklyCount := int64(0)
-	monthlyCount := int64(0)
-
-	for _, booking := range allBookings {
-		stats.BookingsByStatus[booking.Status]++
-		totalSeats += booking.Seats
-
-		if _, exists := eventBookings[booking.EventID]; !exists {
-			eventBookings[booking.EventID] = &EventBookingCount{
-				EventID:  booking.EventID,
-				Bookings: 0,
-				Seats:    0,
-			}
-		}
-		eventBookings[booking.EventID].Bookings++
-		eventBookings[booking.EventID].Seats += int64(booking.Seats)
-
-		if _, exists := eventTitles[booking.EventID]; !exists {
-			event, err := s.eventRepo.GetByID(ctx, booking.EventID)
-			if err == nil {
-				eventTitles[booking.EventID] = event.Title
-			}
-		}
-
-		if booking.CreatedAt.After(now.AddDate(0, 0, -1)) {
-			dailyCount++
-		}
-		if booking.CreatedAt.After(now.AddDate(0, 0, -7)) {
-			weeklyCount++
-		}
-		if booking.CreatedAt.After(now.AddDate(0, -1, 0)) {
-			monthlyCount++
-		}
-	}
-
-	for eventID, eventCount := range eventBookings {
-		eventCount.EventTitle = eventTitles[eventID]
-		stats.PopularEvents = append(stats.PopularEvents, eventCount)
-	}
-
-	stats.sortPopularEvents()
-
-	if len(allBookings) > 0 {
-		stats.AverageSeats = float64(totalSeats) / float64(len(allBookings))
-	}
-
-	stats.DailyBookings = dailyCount
-	stats.WeeklyBookings = weeklyCount
-	stats.MonthlyBookings = monthlyCount
-	stats.Revenue = float64(totalSeats) * 1000.0
-
-	return stats, nil
-}
-
-// sortPopularEvents сортирует популярные мероприятия по количеству бронирований
-func (s *BookingStats) sortPopularEvents() {
-	for i := 0; i < len(s.PopularEvents)-1; i++ {
-		for j := i + 1; j < len(s.PopularEvents); j++ {
-			if s.PopularEvents[i].Bookings < s.PopularEvents[j].Bookings {
-				s.PopularEvents[i], s.PopularEvents[j] = s.PopularEvents[j], s.PopularEvents[i]
-			}
-		}
-	}
-}
-
-// GetAllBookings возвращает все бронирования
-func (s *bookingService) GetAllBookings(ctx context.Context) ([]*entity.Booking, error) {
-	bookings, err := s.bookingRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении всех бронирований: %w", err)
-	}
-	return bookings, nil
-}
-
-// DeleteBooking удаляет бронирование
-func (s *bookingService) DeleteBooking(ctx context.Context, bookingID int64) error {
-	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
-	if err != nil {
-		return fmt.Errorf("бронирование не найдено: %w", err)
-	}
-
-	if booking.Status == entity.BookingStatusConfirmed {
-		return fmt.Errorf("невозможно удалить подтвержденное бронирование")
-	}
-
-	if err := s.bookingRepo.Delete(ctx, bookingID); err != nil {
-		return fmt.Errorf("ошибка при удалении бронирования: %w", err)
-	}
-	return nil
-}
-
-// GetRecentBookings возвращает последние бронирования
-func (s *bookingService) GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error) {
-	if limit <= 0 {
-		limit = 50
-	}
-
-	bookings, err := s.bookingRepo.GetRecentBookings(ctx, limit)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении последних бронирований: %w", err)
-	}
-	return bookings, nil
-}
-
-// GetBookingWithDetails возвращает детальную информацию о бронировании
-func (s *bookingService) GetBookingWithDetails(ctx context.Context, bookingID int64) (*BookingDetails, error) {
-	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении бронирования: %w", err)
-	}
-
-	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
-	}
-
-	user, err := s.userRepo.GetByID(ctx, booking.UserID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении информации о пользователе: %w", err)
-	}
-
-	details := &BookingDetails{
-		Booking: booking,
-		Event:   &eventWithAvailability.Event, // Преобразуем в базовый Event
-		User:    user,
-	}
-
-	if booking.Status == entity.BookingStatusPending {
-		details.TimeLeft = time.Until(booking.ExpiresAt)
-		details.IsExpired = details.TimeLeft <= 0
-		details.CanConfirm = !details.IsExpired
-	}
-
-	return details, nil
-}
-
-// CheckBookingAvailability проверяет доступность мест для бронирования
-func (s *bookingService) CheckBookingAvailability(ctx context.Context, eventID int64, seats int) (bool, error) {
-	if seats <= 0 {
-		return false, fmt.Errorf("количество мест должно быть положительным")
-	}
-
-	eventWithAvailability, err := s.eventRepo.GetByID(ctx, eventID)
-	if err != nil {
-		return false, fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
-	}
-
-	if eventWithAvailability.Date.Before(time.Now()) {
-		return false, fmt.Errorf("мероприятие уже прошло")
-	}
-
-	available := eventWithAvailability.AvailableSeats >= seats
-	return available, nil
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/email"
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
+	"github.com/ds124wfegd/WB_L3/5/pkg/tracing"
+)
+
+// BookSeatsRequest представляет данные для бронирования мест
+type BookSeatsRequest struct {
+	EventID            int64  `json:"event_id" binding:"required"`
+	UserID             int64  `json:"-"` // берётся из JWT, а не из тела запроса
+	Seats              int    `json:"seats" binding:"required,min=1,max=50"`
+	ReservationTimeout int    `json:"reservation_timeout" binding:"min=1,max=1440"`
+	TierID             *int64 `json:"tier_id,omitempty"`
+	PromoCode          string `json:"promo_code,omitempty" binding:"omitempty,max=50"`
+	PoolCode           string `json:"pool_code,omitempty" binding:"omitempty,max=50"`   // код партнёрского пула
+	AccessCode         string `json:"access_code,omitempty" binding:"omitempty,max=64"` // код доступа на предпродажу
+}
+
+// BookingStats представляет статистику по бронированиям
+type BookingStats struct {
+	TotalBookings    int64                          `json:"total_bookings"`
+	BookingsByStatus map[entity.BookingStatus]int64 `json:"bookings_by_status"`
+	AverageSeats     float64                        `json:"average_seats"`
+	PopularEvents    []*EventBookingCount           `json:"popular_events"`
+	DailyBookings    int64                          `json:"daily_bookings"`
+	WeeklyBookings   int64                          `json:"weekly_bookings"`
+	MonthlyBookings  int64                          `json:"monthly_bookings"`
+	Revenue          float64                        `json:"revenue"`
+	Discounts        float64                        `json:"discounts"`
+}
+
+// EventBookingCount представляет мероприятие с количеством бронирований
+type EventBookingCount struct {
+	EventID    int64  `json:"event_id"`
+	EventTitle string `json:"event_title"`
+	Bookings   int64  `json:"bookings"`
+	Seats      int64  `json:"seats"`
+}
+
+// BookingDetails представляет детальную информацию о бронировании
+type BookingDetails struct {
+	Booking    *entity.Booking `json:"booking"`
+	Event      *entity.Event   `json:"event"`
+	User       *entity.User    `json:"user"`
+	TimeLeft   time.Duration   `json:"time_left,omitempty"`
+	IsExpired  bool            `json:"is_expired"`
+	CanConfirm bool            `json:"can_confirm"`
+}
+
+// TaskPublisher интерфейс для публикации задач в очередь
+type TaskPublisher interface {
+	Publish(ctx context.Context, task *Task) error
+}
+
+// ExpiryTimer взводит таймер истечения бронирования на его expires_at; повторный вызов
+// переносит таймер. Сработавший таймер - только подсказка: срок проверяется по базе
+type ExpiryTimer interface {
+	Schedule(ctx context.Context, bookingID int64, expiresAt time.Time) error
+}
+
+// Task представляет задачу для очереди
+type Task struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Data       map[string]interface{} `json:"data"`
+	ExecuteAt  time.Time              `json:"execute_at"`
+	MaxRetries int                    `json:"max_retries"`
+	Attempts   int                    `json:"attempts"`
+	// Priority - "high", "normal" или "low"; пустое значение - приоритет по типу задачи
+	Priority string `json:"priority,omitempty"`
+}
+
+// Константы типов задач
+const (
+	TaskTypeExpireBooking        = "expire_booking"
+	TaskTypeSendNotification     = "send_notification"
+	TaskTypeCleanupExpired       = "cleanup_expired"
+	TaskTypeReminderNotification = "reminder_notification"
+	TaskTypeEventReminder        = "event_reminder"
+	TaskTypeSendEmail            = "send_email"
+	TaskTypeProcessRefund        = "process_refund"
+
+	TaskTypeConfirmationEscalation = "confirmation_escalation"
+	TaskTypeSyncEventPoster        = "sync_event_poster"
+)
+
+// expiredReason записывается в журнал аудита при истечении брони
+const expiredReason = "reservation expired"
+
+type bookingService struct {
+	bookingRepo repository.BookingRepository
+	eventRepo   repository.EventRepository
+	userRepo    repository.UserRepository
+	tierRepo    repository.TicketTierRepository
+	poolRepo    repository.PartnerPoolRepository
+	promoRepo   repository.PromoCodeRepository
+	refundRepo  repository.RefundRepository
+	auditRepo   repository.AuditRepository
+	txManager   repository.TxManager
+	expiryTimer ExpiryTimer
+	queue       TaskPublisher
+	telegramBot *telegram.Bot
+
+	defaultTimeout atomic.Int64 // в минутах
+	capacityAlerts atomic.Pointer[[]int]
+}
+
+// defaultReservationTimeout - срок резервирования в минутах, если он не задан ни в запросе, ни в конфигурации
+const defaultReservationTimeout = 30
+
+// NewBookingService создает новый экземпляр BookingService
+func NewBookingService(
+	bookingRepo repository.BookingRepository,
+	eventRepo repository.EventRepository,
+	userRepo repository.UserRepository,
+	tierRepo repository.TicketTierRepository,
+	poolRepo repository.PartnerPoolRepository,
+	promoRepo repository.PromoCodeRepository,
+	refundRepo repository.RefundRepository,
+	auditRepo repository.AuditRepository,
+	txManager repository.TxManager,
+	expiryTimer ExpiryTimer,
+	queue TaskPublisher,
+	telegramBot *telegram.Bot,
+) BookingService {
+	s := &bookingService{
+		bookingRepo: bookingRepo,
+		eventRepo:   eventRepo,
+		userRepo:    userRepo,
+		tierRepo:    tierRepo,
+		poolRepo:    poolRepo,
+		promoRepo:   promoRepo,
+		refundRepo:  refundRepo,
+		auditRepo:   auditRepo,
+		txManager:   txManager,
+		expiryTimer: expiryTimer,
+		queue:       queue,
+		telegramBot: telegramBot,
+	}
+	s.defaultTimeout.Store(defaultReservationTimeout)
+	return s
+}
+
+func (s *bookingService) SetDefaultReservationTimeout(minutes int) {
+	if minutes <= 0 {
+		minutes = defaultReservationTimeout
+	}
+	s.defaultTimeout.Store(int64(minutes))
+}
+
+// BookSeats создает новое бронирование мест
+func (s *bookingService) BookSeats(ctx context.Context, req *BookSeatsRequest) (*entity.Booking, error) {
+	booking, event, user, err := s.prepareBooking(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Задачи бронирования пишутся в outbox в одной транзакции с ним,
+	// в очередь их переносит релей, поэтому недоступный Redis их не теряет
+	if s.queue != nil {
+		err = s.bookingRepo.CreateWithOutbox(ctx, booking, func(b *entity.Booking) []*entity.OutboxMessage {
+			return outboxMessages(ctx, markStaffAssisted(ctx, bookingTasks(b, event.ConfirmationEscalation)...))
+		})
+	} else {
+		err = s.bookingRepo.Create(ctx, booking)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании бронирования: %w", err)
+	}
+
+	s.bookingCreated(ctx, booking, event, user)
+
+	return booking, nil
+}
+
+// BookSeatsBatch бронирует места сразу на несколько мероприятий: либо создаются все
+// бронирования, либо ни одного. На каждое мероприятие допускается одна позиция.
+func (s *bookingService) BookSeatsBatch(ctx context.Context, reqs []*BookSeatsRequest) ([]*entity.Booking, error) {
+	if len(reqs) == 0 {
+		return nil, entity.ErrInvalidInput
+	}
+
+	bookings := make([]*entity.Booking, 0, len(reqs))
+	events := make(map[int64]*entity.Event, len(reqs))
+	users := make([]*entity.User, 0, len(reqs))
+	for _, req := range reqs {
+		if _, ok := events[req.EventID]; ok {
+			return nil, fmt.Errorf("мероприятие %d указано несколько раз: %w", req.EventID, entity.ErrInvalidInput)
+		}
+
+		booking, event, user, err := s.prepareBooking(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("мероприятие %d: %w", req.EventID, err)
+		}
+		bookings = append(bookings, booking)
+		events[req.EventID] = event
+		users = append(users, user)
+	}
+
+	var outbox func(*entity.Booking) []*entity.OutboxMessage
+	if s.queue != nil {
+		outbox = func(b *entity.Booking) []*entity.OutboxMessage {
+			return outboxMessages(ctx, markStaffAssisted(ctx, bookingTasks(b, events[b.EventID].ConfirmationEscalation)...))
+		}
+	}
+	if err := s.bookingRepo.CreateManyWithOutbox(ctx, bookings, outbox); err != nil {
+		return nil, fmt.Errorf("ошибка при создании бронирований: %w", err)
+	}
+
+	for i, booking := range bookings {
+		s.bookingCreated(ctx, booking, events[booking.EventID], users[i])
+	}
+
+	return bookings, nil
+}
+
+// bookingCreated логирует созданное бронирование, взводит таймер истечения и уведомляет пользователя
+func (s *bookingService) bookingCreated(ctx context.Context, booking *entity.Booking, event *entity.Event, user *entity.User) {
+	log.Printf("Бронирование создано: ID=%d, Event=%d, User=%d, Seats=%d",
+		booking.ID, booking.EventID, booking.UserID, booking.Seats)
+
+	s.scheduleExpiry(ctx, booking)
+	invalidateAvailability(ctx, s.eventRepo, booking.EventID)
+
+	// Отправка уведомления через Telegram
+	if s.telegramBot != nil && user.WantsTelegramFor(entity.NotificationBookingUpdates) {
+		go s.sendBookingCreatedNotification(booking, event, user, entity.AuditActorFromContext(ctx).StaffAssisted())
+	}
+}
+
+// scheduleExpiry взводит таймер истечения, если он включён. Без таймера бронирование
+// истечёт по задаче очереди или тику планировщика, поэтому ошибка только логируется
+func (s *bookingService) scheduleExpiry(ctx context.Context, booking *entity.Booking) {
+	if s.expiryTimer == nil {
+		return
+	}
+	if err := s.expiryTimer.Schedule(ctx, booking.ID, booking.ExpiresAt); err != nil {
+		log.Printf("Ошибка при установке таймера истечения бронирования %d: %v", booking.ID, err)
+	}
+}
+
+// prepareBooking проверяет запрос на бронирование и собирает бронирование для сохранения.
+// Доступность здесь проверяется предварительно, окончательно её проверяет репозиторий под блокировкой.
+func (s *bookingService) prepareBooking(ctx context.Context, req *BookSeatsRequest) (*entity.Booking, *entity.Event, *entity.User, error) {
+	// Валидация мероприятия
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, req.EventID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("мероприятие не найдено: %w", err)
+	}
+
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	if event.Date.Before(time.Now()) {
+		return nil, nil, nil, fmt.Errorf("невозможно забронировать места на прошедшее мероприятие")
+	}
+
+	// Вне открытой продажи бронировать можно только на предпродаже и только с кодом доступа
+	if err := event.SaleSchedule.CheckSale(time.Now(), event.Date, req.AccessCode); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Бронирование с кодом пула берёт места только из пула, остальные - из общей продажи
+	poolID, err := s.resolvePool(ctx, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if poolID == nil && eventWithAvailability.AvailableSeats < req.Seats {
+		return nil, nil, nil, fmt.Errorf("недостаточно доступных мест: запрошено %d, доступно %d",
+			req.Seats, eventWithAvailability.AvailableSeats)
+	}
+
+	// Валидация категории билетов
+	if err := s.validateTier(ctx, req); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Валидация промокода
+	var promoCodeID *int64
+	if req.PromoCode != "" && s.promoRepo != nil {
+		promo, err := resolvePromoCode(ctx, s.promoRepo, req.PromoCode, req.EventID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		promoCodeID = &promo.ID
+	}
+
+	// Валидация пользователя
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("пользователь не найден: %w", err)
+	}
+
+	// Проверка существующего бронирования
+	existingBooking, err := s.bookingRepo.GetByEventAndUser(ctx, req.EventID, req.UserID)
+	if err != nil && err != entity.ErrBookingNotFound {
+		return nil, nil, nil, fmt.Errorf("ошибка при проверке существующих бронирований: %w", err)
+	}
+
+	if existingBooking != nil {
+		switch existingBooking.Status {
+		case entity.BookingStatusPending:
+			return nil, nil, nil, fmt.Errorf("у вас уже есть ожидающее бронирование на это мероприятие")
+		case entity.BookingStatusConfirmed:
+			return nil, nil, nil, fmt.Errorf("у вас уже есть подтвержденное бронирование на это мероприятие")
+		}
+	}
+
+	// Установка времени резервирования по умолчанию
+	timeout := req.ReservationTimeout
+	if timeout == 0 {
+		timeout = int(s.defaultTimeout.Load())
+	}
+
+	// Создание бронирования
+	booking := &entity.Booking{
+		EventID:            req.EventID,
+		UserID:             req.UserID,
+		Seats:              req.Seats,
+		Status:             entity.BookingStatusPending,
+		ReservationTimeout: timeout,
+		TierID:             req.TierID,
+		PoolID:             poolID,
+		PromoCodeID:        promoCodeID,
+	}
+
+	return booking, event, user, nil
+}
+
+// validateTier проверяет категорию билетов: если у мероприятия есть категории,
+// бронирование обязано ссылаться на одну из них и укладываться в ее квоту
+func (s *bookingService) validateTier(ctx context.Context, req *BookSeatsRequest) error {
+	if s.tierRepo == nil {
+		return nil
+	}
+
+	tiers, err := s.tierRepo.GetByEventID(ctx, req.EventID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении категорий билетов: %w", err)
+	}
+
+	if len(tiers) == 0 {
+		if req.TierID != nil {
+			return entity.ErrTicketTierNotFound
+		}
+		return nil
+	}
+
+	if req.TierID == nil {
+		return entity.ErrTicketTierRequired
+	}
+
+	for _, tier := range tiers {
+		if tier.ID != *req.TierID {
+			continue
+		}
+		if tier.AvailableSeats < req.Seats {
+			return fmt.Errorf("недостаточно мест в категории %q: запрошено %d, доступно %d",
+				tier.Name, req.Seats, tier.AvailableSeats)
+		}
+		return nil
+	}
+
+	return entity.ErrTicketTierNotFound
+}
+
+// resolvePool находит партнёрский пул по коду из запроса и проверяет, что в нём хватает мест.
+// Без кода возвращает nil: бронирование идёт из общей продажи.
+func (s *bookingService) resolvePool(ctx context.Context, req *BookSeatsRequest) (*int64, error) {
+	code := strings.TrimSpace(req.PoolCode)
+	if code == "" {
+		return nil, nil
+	}
+	if s.poolRepo == nil {
+		return nil, entity.ErrPartnerPoolNotFound
+	}
+
+	pool, err := s.poolRepo.GetByCode(ctx, req.EventID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	pools, err := s.poolRepo.GetByEventID(ctx, req.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении партнёрских пулов: %w", err)
+	}
+	for _, p := range pools {
+		if p.ID == pool.ID && p.AvailableSeats < req.Seats {
+			return nil, fmt.Errorf("недостаточно мест в пуле %q: запрошено %d, доступно %d",
+				p.Name, req.Seats, p.AvailableSeats)
+		}
+	}
+
+	return &pool.ID, nil
+}
+
+// bookingTasks возвращает задачи, которые планируются при создании бронирования
+func bookingTasks(booking *entity.Booking, escalation entity.ConfirmationEscalation) []*Task {
+	now := time.Now()
+
+	tasks := []*Task{expireTask(booking, now)}
+
+	// Первый шаг цепочки напоминаний, следующие шаги планирует обработчик предыдущего
+	if step, ok := escalation.NextStep(booking.ExpiresAt, "", now); ok {
+		tasks = append(tasks, escalationTask(booking.ID, booking.ExpiresAt, step))
+	}
+
+	// Уведомление о создании бронирования
+	tasks = append(tasks, &Task{
+		ID:   fmt.Sprintf("notification_booking_created_%d_%d", booking.ID, now.Unix()),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": "booking_created",
+			"booking_id":        booking.ID,
+			"event_id":          booking.EventID,
+			"user_id":           booking.UserID,
+		},
+		ExecuteAt:  now.Add(5 * time.Second),
+		MaxRetries: 3,
+	})
+
+	// Письмо о создании бронирования
+	tasks = append(tasks, emailTask(email.TemplateBookingCreated, booking.ID, now))
+
+	return tasks
+}
+
+// expireTask возвращает задачу на истечение срока бронирования
+func expireTask(booking *entity.Booking, now time.Time) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("expire_booking_%d_%d", booking.ID, now.Unix()),
+		Type: TaskTypeExpireBooking,
+		Data: map[string]interface{}{
+			"booking_id": booking.ID,
+			"event_id":   booking.EventID,
+			"user_id":    booking.UserID,
+			"expires_at": booking.ExpiresAt.Format(time.RFC3339),
+		},
+		ExecuteAt:  booking.ExpiresAt,
+		MaxRetries: 3,
+	}
+}
+
+// confirmedNotificationTask возвращает задачу уведомления о подтверждении бронирования
+func confirmedNotificationTask(booking *entity.Booking, now time.Time) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("notification_booking_confirmed_%d_%d", booking.ID, now.Unix()),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": NotificationBookingConfirmed,
+			"booking_id":        booking.ID,
+			"event_id":          booking.EventID,
+			"user_id":           booking.UserID,
+		},
+		ExecuteAt:  now.Add(2 * time.Second),
+		MaxRetries: 3,
+	}
+}
+
+// markStaffAssisted помечает задачи уведомлений, если запрос выполняет сотрудник от имени
+// пользователя: обработчики добавляют к сообщению entity.StaffAssistedNotice
+func markStaffAssisted(ctx context.Context, tasks ...*Task) []*Task {
+	if !entity.AuditActorFromContext(ctx).StaffAssisted() {
+		return tasks
+	}
+	for _, task := range tasks {
+		task.Data["staff_assisted"] = true
+	}
+	return tasks
+}
+
+// outboxMessages преобразует задачи в записи outbox; ID запроса и контекст трассировки
+// из ctx сохраняются в данных задачи, чтобы её обработку можно было найти по запросу
+// в логах и продолжить его трассу
+func outboxMessages(ctx context.Context, tasks []*Task) []*entity.OutboxMessage {
+	messages := make([]*entity.OutboxMessage, 0, len(tasks))
+	for _, task := range tasks {
+		messages = append(messages, &entity.OutboxMessage{
+			TaskID:     task.ID,
+			TaskType:   task.Type,
+			Data:       tracing.InjectTask(ctx, requestid.SetTaskData(ctx, task.Data)),
+			ExecuteAt:  task.ExecuteAt,
+			MaxRetries: task.MaxRetries,
+			Priority:   task.Priority,
+		})
+	}
+	return messages
+}
+
+// publishEmail ставит в очередь письмо по шаблону; получатель и его согласие
+// на письма проверяются при обработке задачи
+func (s *bookingService) publishEmail(ctx context.Context, template string, bookingID int64) {
+	if s.queue == nil {
+		return
+	}
+
+	task := markStaffAssisted(ctx, emailTask(template, bookingID, time.Now()))[0]
+
+	if err := s.queue.Publish(ctx, task); err != nil {
+		log.Printf("Ошибка при планировании письма %s для бронирования %d: %v", template, bookingID, err)
+	}
+}
+
+// emailTask возвращает задачу письма по шаблону о бронировании
+func emailTask(template string, bookingID int64, now time.Time) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("email_%s_%d_%d", template, bookingID, now.Unix()),
+		Type: TaskTypeSendEmail,
+		Data: map[string]interface{}{
+			"template":   template,
+			"booking_id": bookingID,
+		},
+		ExecuteAt:  now.Add(5 * time.Second),
+		MaxRetries: 3,
+	}
+}
+
+// sendBookingCreatedNotification отправляет уведомление о создании бронирования
+func (s *bookingService) sendBookingCreatedNotification(booking *entity.Booking, event *entity.Event, user *entity.User, staffAssisted bool) {
+	message := fmt.Sprintf(
+		"🎫 Бронирование создано!\n\n"+
+			"Мероприятие: %s\n"+
+			"Дата: %s\n"+
+			"Количество мест: %d\n"+
+			"Номер брони: #%d\n"+
+			"Статус: Ожидание оплаты\n"+
+			"Подтвердите бронирование до: %s\n\n"+
+			"Не забудьте подтвердить бронирование вовремя!",
+		event.Title,
+		event.Date.Format("02.01.2006 в 15:04"),
+		booking.Seats,
+		booking.ID,
+		booking.ExpiresAt.Format("02.01.2006 в 15:04"),
+	)
+
+	if staffAssisted {
+		message += "\n\n" + entity.StaffAssistedNotice
+	}
+
+	if err := s.telegramBot.SendMessageWithKeyboard(user.TelegramID, message, BookingActionsKeyboard(booking.ID)); err != nil {
+		log.Printf("Ошибка при отправке Telegram уведомления пользователю %d: %v", user.ID, err)
+	}
+}
+
+// ConfirmBooking подтверждает бронирование и возвращает его текущее состояние.
+// Повторное подтверждение идемпотентно: уведомления не отправляются повторно, ошибки нет.
+// Подтверждение отменённого или истекшего бронирования возвращает *entity.TransitionError.
+func (s *bookingService) ConfirmBooking(ctx context.Context, bookingID int64) (*entity.Booking, error) {
+	// Статус, доступность мест и подтверждение проверяются в одной транзакции под блокировкой
+	// бронирования, поэтому параллельный запрос не изменит его между проверкой и записью
+	var booking *entity.Booking
+	var confirmed, expired bool
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		booking, err = s.bookingRepo.GetWithLock(ctx, bookingID)
+		if err != nil {
+			return fmt.Errorf("бронирование не найдено: %w", err)
+		}
+
+		if booking.Status == entity.BookingStatusConfirmed {
+			return nil
+		}
+		if err := entity.ValidateTransition(booking.Status, entity.BookingStatusConfirmed); err != nil {
+			return err
+		}
+
+		// Истечение фиксируется, поэтому транзакция завершается успешно, а ошибка возвращается после неё
+		if time.Now().After(booking.ExpiresAt) {
+			err := s.bookingRepo.UpdateStatus(entity.WithAuditReason(ctx, expiredReason), bookingID, entity.BookingStatusExpired)
+			if err != nil && !errors.Is(err, entity.ErrStatusUnchanged) {
+				return fmt.Errorf("ошибка при обновлении статуса истекшего бронирования: %w", err)
+			}
+			booking.Status = entity.BookingStatusExpired
+			expired = true
+			return nil
+		}
+
+		eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
+		if err != nil {
+			return fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
+		}
+
+		if eventWithAvailability.AvailableSeats < booking.Seats {
+			return entity.ErrNotEnoughSeats
+		}
+
+		if err := s.bookingRepo.UpdateStatus(ctx, bookingID, entity.BookingStatusConfirmed); err != nil {
+			return fmt.Errorf("ошибка при подтверждении бронирования: %w", err)
+		}
+		if err := s.claimCapacityAlerts(ctx, booking, eventWithAvailability); err != nil {
+			return err
+		}
+		booking.Status = entity.BookingStatusConfirmed
+		confirmed = true
+		return nil
+	})
+	if err != nil {
+		return booking, err
+	}
+	if expired {
+		return booking, &entity.TransitionError{From: entity.BookingStatusExpired, To: entity.BookingStatusConfirmed}
+	}
+	// Бронирование уже было подтверждено раньше - уведомления не повторяются
+	if !confirmed {
+		return booking, nil
+	}
+
+	log.Printf("Бронирование подтверждено: ID=%d", bookingID)
+	invalidateAvailability(ctx, s.eventRepo, booking.EventID)
+
+	// Отправка уведомления о подтверждении
+	if s.queue != nil {
+		notificationTask := confirmedNotificationTask(booking, time.Now())
+
+		markStaffAssisted(ctx, notificationTask)
+
+		if err := s.queue.Publish(ctx, notificationTask); err != nil {
+			log.Printf("Ошибка при планировании уведомления о подтверждении: %v", err)
+		}
+	}
+
+	s.publishEmail(ctx, email.TemplateBookingConfirmed, bookingID)
+
+	return booking, nil
+}
+
+// CancelBooking отменяет бронирование по политике отмены мероприятия и возвращает расчёт возврата.
+// Неоплаченное (pending) бронирование отменяется без возврата, после начала мероприятия отмена невозможна.
+// Повторная отмена идемпотентна и возвращает бронирование без расчёта возврата (quote == nil);
+// отмена истекшего бронирования возвращает *entity.TransitionError.
+func (s *bookingService) CancelBooking(ctx context.Context, bookingID int64, reason string) (*entity.Booking, *entity.CancellationQuote, error) {
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("бронирование не найдено: %w", err)
+	}
+
+	if booking.Status == entity.BookingStatusCancelled {
+		return booking, nil, nil
+	}
+	if err := entity.ValidateTransition(booking.Status, entity.BookingStatusCancelled); err != nil {
+		return booking, nil, err
+	}
+
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
+	if err != nil {
+		return booking, nil, fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
+	}
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	paid := 0.0
+	if booking.Status == entity.BookingStatusConfirmed {
+		paid = booking.TotalPrice
+	}
+	quote := event.CancellationPolicy.Evaluate(event.Date, paid, time.Now())
+	if !quote.Allowed {
+		return booking, nil, entity.ErrCancellationClosed
+	}
+
+	// Сумма возврата считается по заблокированной строке: бронирование могли подтвердить
+	// (оплатить) между чтением выше и отменой
+	now := time.Now()
+	refund, err := s.bookingRepo.CancelWithRefund(entity.WithAuditReason(ctx, reason), bookingID,
+		func(locked *entity.Booking) *entity.Refund {
+			return refundFor(locked, event, reason, now)
+		},
+		func(refund *entity.Refund) []*entity.OutboxMessage {
+			return s.refundOutbox(ctx, refund)
+		},
+	)
+	if err != nil {
+		var transitionErr *entity.TransitionError
+		switch {
+		case errors.Is(err, entity.ErrStatusUnchanged):
+			booking.Status = entity.BookingStatusCancelled
+			return booking, nil, nil
+		case errors.As(err, &transitionErr):
+			booking.Status = transitionErr.From
+			return booking, nil, err
+		}
+		return booking, nil, fmt.Errorf("ошибка при отмене бронирования: %w", err)
+	}
+	booking.Status = entity.BookingStatusCancelled
+	invalidateAvailability(ctx, s.eventRepo, booking.EventID)
+
+	paid = 0
+	if refund != nil {
+		paid = refund.PaidAmount
+	}
+	quote = event.CancellationPolicy.Evaluate(event.Date, paid, now)
+
+	log.Printf("Бронирование отменено: ID=%d, Причина: %s, Возврат: %.2f (%.0f%%)",
+		bookingID, reason, quote.RefundAmount, quote.RefundPercent)
+
+	// Отправка уведомления об отмене
+	if s.telegramBot != nil {
+		user, err := s.userRepo.GetByID(ctx, booking.UserID)
+		if err == nil && user.WantsTelegramFor(entity.NotificationBookingUpdates) {
+			refund := "Оплата не производилась."
+			if paid > 0 {
+				refund = fmt.Sprintf("Сумма возврата: %.2f (%.0f%%)", quote.RefundAmount, quote.RefundPercent)
+			}
+			message := fmt.Sprintf(
+				"❌ Бронирование отменено\n\n"+
+					"Мероприятие: %s\n"+
+					"Дата: %s\n"+
+					"Количество мест: %d\n"+
+					"Причина: %s\n"+
+					"%s\n\n"+
+					"Если это ошибка, свяжитесь с поддержкой.",
+				event.Title,
+				event.Date.Format("02.01.2006 в 15:04"),
+				booking.Seats,
+				reason,
+				refund,
+			)
+			if entity.AuditActorFromContext(ctx).StaffAssisted() {
+				message += "\n\n" + entity.StaffAssistedNotice
+			}
+
+			go s.telegramBot.SendMessage(user.TelegramID, message)
+		}
+	}
+
+	return booking, &quote, nil
+}
+
+// GetBooking возвращает бронирование по ID
+func (s *bookingService) GetBooking(ctx context.Context, id int64) (*entity.Booking, error) {
+	booking, err := s.bookingRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирования: %w", err)
+	}
+	return booking, nil
+}
+
+// GetUserBookings возвращает все бронирования пользователя
+func (s *bookingService) GetUserBookings(ctx context.Context, userID int64) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирований пользователя: %w", err)
+	}
+	return bookings, nil
+}
+
+// GetEventBookings возвращает все бронирования мероприятия
+func (s *bookingService) GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирований мероприятия: %w", err)
+	}
+	return bookings, nil
+}
+
+// GetOrganizerBookings возвращает бронирования только на мероприятия, созданные организатором
+func (s *bookingService) GetOrganizerBookings(ctx context.Context, organizerID int64, eventID *int64) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetByOrganizer(ctx, organizerID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирований организатора: %w", err)
+	}
+	return bookings, nil
+}
+
+func (s *bookingService) ClaimReminder(ctx context.Context, bookingID int64, reminder string) (bool, error) {
+	return s.bookingRepo.ClaimReminder(ctx, bookingID, reminder)
+}
+
+func (s *bookingService) ReleaseReminder(ctx context.Context, bookingID int64, reminder string) error {
+	return s.bookingRepo.ReleaseReminder(ctx, bookingID, reminder)
+}
+
+func (s *bookingService) RecordNotification(ctx context.Context, bookingID int64, notification string) error {
+	return s.bookingRepo.RecordNotification(ctx, bookingID, notification)
+}
+
+// wantsTelegram проверяет настройки уведомлений пользователя; если их не удалось прочитать, сообщение не отправляется
+func (s *bookingService) wantsTelegram(ctx context.Context, userID int64, category string) bool {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		log.Printf("Не удалось получить настройки уведомлений пользователя %d: %v", userID, err)
+		return false
+	}
+	return user.WantsTelegramFor(category)
+}
+
+// CancelExpiredBookings отменяет все истекшие бронирования
+func (s *bookingService) CancelExpiredBookings(ctx context.Context) error {
+	expiredBookings, err := s.bookingRepo.GetExpiredBookings(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("ошибка при получении истекших бронирований: %w", err)
+	}
+
+	ctx = entity.WithAuditReason(ctx, expiredReason)
+	cancelledCount := 0
+	for _, expired := range expiredBookings {
+		if err := s.bookingRepo.UpdateStatus(ctx, expired.BookingID, entity.BookingStatusExpired); err != nil {
+			// Бронирование уже истекло или успело смениться статусом - уведомлять не о чем
+			if !errors.Is(err, entity.ErrStatusUnchanged) && !errors.Is(err, entity.ErrInvalidTransition) {
+				log.Printf("Ошибка при отмене истекшего бронирования %d: %v", expired.BookingID, err)
+			}
+			continue
+		}
+
+		if s.telegramBot != nil && expired.TelegramID != "" && s.wantsTelegram(ctx, expired.UserID, entity.NotificationBookingUpdates) {
+			message := fmt.Sprintf(
+				"⏰ Бронирование истекло\n\n"+
+					"Мероприятие: %s\n"+
+					"Бронирование #%d было автоматически отменено.\n\n"+
+					"Вы можете создать новое бронирование, если места еще доступны.",
+				expired.EventTitle,
+				expired.BookingID,
+			)
+
+			go s.telegramBot.SendMessage(expired.TelegramID, message)
+		}
+		s.publishEmail(ctx, email.TemplateBookingExpired, expired.BookingID)
+
+		cancelledCount++
+	}
+
+	log.Printf("Отменено %d истекших бронирований", cancelledCount)
+	return nil
+}
+
+// GetExpiredBookings возвращает список истекших бронирований
+func (s *bookingService) GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error) {
+	bookings, err := s.bookingRepo.GetExpiredBookings(ctx, before)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истекших бронирований: %w", err)
+	}
+	return bookings, nil
+}
+
+// ExpireBooking помечает бронирование как истекшее
+func (s *bookingService) ExpireBooking(ctx context.Context, bookingID int64) error {
+	if err := s.bookingRepo.UpdateStatus(entity.WithAuditReason(ctx, expiredReason), bookingID, entity.BookingStatusExpired); err != nil {
+		if errors.Is(err, entity.ErrStatusUnchanged) {
+			return nil
+		}
+		return err
+	}
+
+	s.publishEmail(ctx, email.TemplateBookingExpired, bookingID)
+	return nil
+}
+
+// GetBookingsByStatus возвращает бронирования по статусу
+func (s *bookingService) GetBookingsByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetByStatus(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирований по статусу: %w", err)
+	}
+	return bookings, nil
+}
+
+// UpdateBookingSeats обновляет количество мест в бронировании
+func (s *bookingService) UpdateBookingSeats(ctx context.Context, bookingID int64, seats int) error {
+	if seats <= 0 {
+		return fmt.Errorf("количество мест должно быть положительным")
+	}
+
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return fmt.Errorf("бронирование не найдено: %w", err)
+	}
+
+	if booking.Status != entity.BookingStatusPending {
+		return fmt.Errorf("можно обновлять места только для бронирований в статусе ожидания")
+	}
+
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
+	}
+
+	seatDifference := seats - booking.Seats
+	if eventWithAvailability.AvailableSeats+seatDifference < 0 {
+		return fmt.Errorf("недостаточно доступных мест")
+	}
+
+	booking.Seats = seats
+	if err := s.bookingRepo.Update(ctx, booking); err != nil {
+		return fmt.Errorf("ошибка при обновлении количества мест: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBookingStatus переводит бронирование в статус status по правилам entity.ValidateTransition.
+// Перевод в текущий статус ничего не меняет и ошибкой не считается.
+func (s *bookingService) UpdateBookingStatus(ctx context.Context, bookingID int64, status entity.BookingStatus) error {
+	if !status.Valid() {
+		return entity.ErrInvalidBookingStatus
+	}
+
+	if err := s.bookingRepo.UpdateStatus(ctx, bookingID, status); err != nil {
+		if errors.Is(err, entity.ErrStatusUnchanged) {
+			return nil
+		}
+		return fmt.Errorf("ошибка при обновлении статуса бронирования: %w", err)
+	}
+
+	// Мероприятие бронирования нужно только для сброса кэша свободных мест
+	if _, ok := s.eventRepo.(AvailabilityInvalidator); ok {
+		if booking, err := s.bookingRepo.GetByID(ctx, bookingID); err == nil {
+			invalidateAvailability(ctx, s.eventRepo, booking.EventID)
+		}
+	}
+	return nil
+}
+
+// GetBookingStats возвращает статистику по бронированиям
+func (s *bookingService) GetBookingStats(ctx context.Context) (*BookingStats, error) {
+	allBookings, err := s.bookingRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирований для статистики: %w", err)
+	}
+
+	stats := &BookingStats{
+		TotalBookings:    int64(len(allBookings)),
+		BookingsByStatus: make(map[entity.BookingStatus]int64),
+		PopularEvents:    make([]*EventBookingCount, 0),
+	}
+
+	totalSeats := 0
+	eventBookings := make(map[int64]*EventBookingCount)
+	eventTitles := make(map[int64]string)
+
+	now := time.Now()
+	dailyCount := int64(0)
+	weeklyCount := int64(0)
+	monthlyCount := int64(0)
+
+	for _, booking := range allBookings {
+		stats.BookingsByStatus[booking.Status]++
+		totalSeats += booking.Seats
+		if booking.Status == entity.BookingStatusConfirmed {
+			stats.Revenue += booking.TotalPrice
+			stats.Discounts += booking.DiscountAmount
+		}
+
+		if _, exists := eventBookings[booking.EventID]; !exists {
+			eventBookings[booking.EventID] = &EventBookingCount{
+				EventID:  booking.EventID,
+				Bookings: 0,
+				Seats:    0,
+			}
+		}
+		eventBookings[booking.EventID].Bookings++
+		eventBookings[booking.EventID].Seats += int64(booking.Seats)
+
+		if _, exists := eventTitles[booking.EventID]; !exists {
+			event, err := s.eventRepo.GetByID(ctx, booking.EventID)
+			if err == nil {
+				eventTitles[booking.EventID] = event.Title
+			}
+		}
+
+		if booking.CreatedAt.After(now.AddDate(0, 0, -1)) {
+			dailyCount++
+		}
+		if booking.CreatedAt.After(now.AddDate(0, 0, -7)) {
+			weeklyCount++
+		}
+		if booking.CreatedAt.After(now.AddDate(0, -1, 0)) {
+			monthlyCount++
+		}
+	}
+
+	for eventID, eventCount := range eventBookings {
+		eventCount.EventTitle = eventTitles[eventID]
+		stats.PopularEvents = append(stats.PopularEvents, eventCount)
+	}
+
+	stats.sortPopularEvents()
+
+	if len(allBookings) > 0 {
+		stats.AverageSeats = float64(totalSeats) / float64(len(allBookings))
+	}
+
+	stats.DailyBookings = dailyCount
+	stats.WeeklyBookings = weeklyCount
+	stats.MonthlyBookings = monthlyCount
+
+	return stats, nil
+}
+
+// sortPopularEvents сортирует популярные мероприятия по количеству бронирований
+func (s *BookingStats) sortPopularEvents() {
+	for i := 0; i < len(s.PopularEvents)-1; i++ {
+		for j := i + 1; j < len(s.PopularEvents); j++ {
+			if s.PopularEvents[i].Bookings < s.PopularEvents[j].Bookings {
+				s.PopularEvents[i], s.PopularEvents[j] = s.PopularEvents[j], s.PopularEvents[i]
+			}
+		}
+	}
+}
+
+// GetAllBookings возвращает все бронирования
+func (s *bookingService) GetAllBookings(ctx context.Context) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении всех бронирований: %w", err)
+	}
+	return bookings, nil
+}
+
+// DeleteBooking удаляет бронирование
+func (s *bookingService) DeleteBooking(ctx context.Context, bookingID int64) error {
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return fmt.Errorf("бронирование не найдено: %w", err)
+	}
+
+	if booking.Status == entity.BookingStatusConfirmed {
+		return fmt.Errorf("невозможно удалить подтвержденное бронирование")
+	}
+
+	if err := s.bookingRepo.Delete(ctx, bookingID); err != nil {
+		return fmt.Errorf("ошибка при удалении бронирования: %w", err)
+	}
+	return nil
+}
+
+// GetBookingHistory возвращает журнал изменений бронирования, в том числе удалённого
+func (s *bookingService) GetBookingHistory(ctx context.Context, bookingID int64) ([]*entity.AuditEntry, error) {
+	entries, err := s.auditRepo.GetByEntity(ctx, entity.AuditEntityBooking, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истории бронирования: %w", err)
+	}
+
+	// У бронирований, созданных до появления журнала, истории может не быть
+	if len(entries) == 0 {
+		if _, err := s.bookingRepo.GetByID(ctx, bookingID); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// GetRecentBookings возвращает последние бронирования
+func (s *bookingService) GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	bookings, err := s.bookingRepo.GetRecentBookings(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении последних бронирований: %w", err)
+	}
+	return bookings, nil
+}
+
+// GetBookingWithDetails возвращает детальную информацию о бронировании
+func (s *bookingService) GetBookingWithDetails(ctx context.Context, bookingID int64) (*BookingDetails, error) {
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении бронирования: %w", err)
+	}
+
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, booking.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, booking.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении информации о пользователе: %w", err)
+	}
+
+	details := &BookingDetails{
+		Booking: booking,
+		Event:   &eventWithAvailability.Event, // Преобразуем в базовый Event
+		User:    user,
+	}
+
+	if booking.Status == entity.BookingStatusPending {
+		details.TimeLeft = time.Until(booking.ExpiresAt)
+		details.IsExpired = details.TimeLeft <= 0
+		details.CanConfirm = !details.IsExpired
+	}
+
+	return details, nil
+}
+
+// CheckBookingAvailability проверяет доступность мест для бронирования
+func (s *bookingService) CheckBookingAvailability(ctx context.Context, eventID int64, seats int) (bool, error) {
+	if seats <= 0 {
+		return false, fmt.Errorf("количество мест должно быть положительным")
+	}
+
+	eventWithAvailability, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении информации о мероприятии: %w", err)
+	}
+
+	if eventWithAvailability.Date.Before(time.Now()) {
+		return false, fmt.Errorf("мероприятие уже прошло")
+	}
+
+	available := eventWithAvailability.AvailableSeats >= seats
+	return available, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/email"
+)
+
+// ConfirmBookings подтверждает пакет бронирований, оплаченных в кассе. Проверки выполняются
+// по каждому ID под блокировкой строки, а подходящие бронирования переводятся одним
+// BulkUpdateStatus в той же транзакции. Бронирование, которое нельзя подтвердить, не мешает
+// остальным: причина попадает в отчёт. Ошибка возвращается, только если транзакция не удалась.
+func (s *bookingService) ConfirmBookings(ctx context.Context, bookingIDs []int64) (*entity.BulkConfirmReport, error) {
+	ids := uniqueIDs(bookingIDs)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: no booking ids", entity.ErrInvalidInput)
+	}
+	if len(ids) > entity.MaxBulkConfirmation {
+		return nil, fmt.Errorf("%w: at most %d bookings per request", entity.ErrInvalidInput, entity.MaxBulkConfirmation)
+	}
+
+	var (
+		results   map[int64]*entity.BulkConfirmResult
+		confirmed []*entity.Booking
+	)
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		results = make(map[int64]*entity.BulkConfirmResult, len(ids))
+		confirmed = confirmed[:0]
+
+		// Строки блокируются по возрастанию ID, чтобы встречные пакеты не ждали друг друга
+		locked := append([]int64(nil), ids...)
+		sort.Slice(locked, func(i, j int) bool { return locked[i] < locked[j] })
+
+		events := make(map[int64]*entity.EventWithAvailability)
+		var expiredIDs, confirmIDs []int64
+		for _, id := range locked {
+			result := &entity.BulkConfirmResult{BookingID: id}
+			results[id] = result
+
+			booking, err := s.bookingRepo.GetWithLock(ctx, id)
+			if errors.Is(err, entity.ErrBookingNotFound) {
+				result.Outcome = entity.BulkConfirmNotFound
+				result.Error = err.Error()
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("ошибка при получении бронирования %d: %w", id, err)
+			}
+			result.Status = booking.Status
+
+			if booking.Status == entity.BookingStatusConfirmed {
+				result.Outcome = entity.BulkConfirmAlreadyConfirmed
+				continue
+			}
+			if err := entity.ValidateTransition(booking.Status, entity.BookingStatusConfirmed); err != nil {
+				result.Outcome = entity.BulkConfirmInvalidStatus
+				result.Error = err.Error()
+				continue
+			}
+			if time.Now().After(booking.ExpiresAt) {
+				expiredIDs = append(expiredIDs, id)
+				result.Outcome = entity.BulkConfirmExpired
+				result.Status = entity.BookingStatusExpired
+				result.Error = (&entity.TransitionError{From: entity.BookingStatusExpired, To: entity.BookingStatusConfirmed}).Error()
+				continue
+			}
+
+			// Доступность читается один раз на мероприятие и уменьшается по мере подтверждений пакета
+			event, ok := events[booking.EventID]
+			if !ok {
+				event, err = s.eventRepo.GetByID(ctx, booking.EventID)
+				if err != nil {
+					return fmt.Errorf("ошибка при получении информации о мероприятии %d: %w", booking.EventID, err)
+				}
+				events[booking.EventID] = event
+			}
+			if event.AvailableSeats < booking.Seats {
+				result.Outcome = entity.BulkConfirmNotEnoughSeats
+				result.Error = entity.ErrNotEnoughSeats.Error()
+				continue
+			}
+
+			if err := s.claimCapacityAlerts(ctx, booking, event); err != nil {
+				return err
+			}
+			// Места пула уже вычтены из общей продажи его квотой
+			if booking.PoolID == nil {
+				event.AvailableSeats -= booking.Seats
+			}
+
+			confirmIDs = append(confirmIDs, id)
+			booking.Status = entity.BookingStatusConfirmed
+			confirmed = append(confirmed, booking)
+			result.Outcome = entity.BulkConfirmConfirmed
+			result.Status = entity.BookingStatusConfirmed
+		}
+
+		// Истечение фиксируется так же, как при одиночном подтверждении; пустой список BulkUpdateStatus пропускает
+		if err := s.bookingRepo.BulkUpdateStatus(entity.WithAuditReason(ctx, expiredReason), expiredIDs, entity.BookingStatusExpired); err != nil {
+			return fmt.Errorf("ошибка при обновлении статуса истекших бронирований: %w", err)
+		}
+		if err := s.bookingRepo.BulkUpdateStatus(ctx, confirmIDs, entity.BookingStatusConfirmed); err != nil {
+			return fmt.Errorf("ошибка при подтверждении бронирований: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &entity.BulkConfirmReport{Results: make([]entity.BulkConfirmResult, 0, len(ids))}
+	for _, id := range ids {
+		result := results[id]
+		switch {
+		case result.Outcome == entity.BulkConfirmConfirmed:
+			report.Confirmed++
+		case !result.Succeeded():
+			report.Failed++
+		}
+		report.Results = append(report.Results, *result)
+	}
+
+	s.notifyConfirmed(ctx, confirmed)
+	log.Printf("Пакетное подтверждение: подтверждено %d, с ошибкой %d из %d", report.Confirmed, report.Failed, len(ids))
+	return report, nil
+}
+
+// notifyConfirmed сбрасывает кэш доступности и уведомляет владельцев подтверждённых бронирований
+func (s *bookingService) notifyConfirmed(ctx context.Context, bookings []*entity.Booking) {
+	if len(bookings) == 0 {
+		return
+	}
+
+	eventIDs := make([]int64, 0, len(bookings))
+	seen := make(map[int64]bool, len(bookings))
+	for _, booking := range bookings {
+		if !seen[booking.EventID] {
+			seen[booking.EventID] = true
+			eventIDs = append(eventIDs, booking.EventID)
+		}
+	}
+	invalidateAvailability(ctx, s.eventRepo, eventIDs...)
+
+	now := time.Now()
+	for _, booking := range bookings {
+		if s.queue != nil {
+			task := confirmedNotificationTask(booking, now)
+			markStaffAssisted(ctx, task)
+			if err := s.queue.Publish(ctx, task); err != nil {
+				log.Printf("Ошибка при планировании уведомления о подтверждении бронирования %d: %v", booking.ID, err)
+			}
+		}
+		s.publishEmail(ctx, email.TemplateBookingConfirmed, booking.ID)
+	}
+}
+
+// uniqueIDs убирает повторы ID, сохраняя порядок первого появления
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
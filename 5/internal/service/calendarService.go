@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/ical"
+)
+
+const (
+	calendarProdID = "-//EventBooker//Bookings//RU"
+	// calendarEventDuration - у мероприятий нет времени окончания, в календаре они занимают два часа
+	calendarEventDuration = 2 * time.Hour
+)
+
+// CalendarSubscription - ссылка для подписки на календарь бронирований
+type CalendarSubscription struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+type calendarService struct {
+	userRepo    repository.UserRepository
+	bookingRepo repository.BookingRepository
+	secret      []byte
+	baseURL     string
+}
+
+// NewCalendarService создает сервис календарных подписок.
+// Токены подписываются secret, baseURL используется для ссылки на фид.
+func NewCalendarService(
+	userRepo repository.UserRepository,
+	bookingRepo repository.BookingRepository,
+	secret string,
+	baseURL string,
+) CalendarService {
+	return &calendarService{
+		userRepo:    userRepo,
+		bookingRepo: bookingRepo,
+		secret:      []byte(secret),
+		baseURL:     strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *calendarService) GetSubscription(ctx context.Context, userID int64) (*CalendarSubscription, error) {
+	version, err := s.userRepo.GetCalendarTokenVersion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscription(userID, version), nil
+}
+
+// RevokeSubscription отзывает все выданные токены пользователя и возвращает новую ссылку
+func (s *calendarService) RevokeSubscription(ctx context.Context, userID int64) (*CalendarSubscription, error) {
+	version, err := s.userRepo.IncrementCalendarTokenVersion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscription(userID, version), nil
+}
+
+// WriteFeed проверяет токен и записывает в w календарь подтверждённых бронирований пользователя
+func (s *calendarService) WriteFeed(ctx context.Context, userID int64, token string, w io.Writer) error {
+	if err := s.verifyToken(ctx, userID, token); err != nil {
+		return err
+	}
+
+	entries, err := s.bookingRepo.GetCalendarEntries(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении бронирований для календаря: %w", err)
+	}
+
+	calendar := &ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   "EventBooker",
+		Events: make([]ical.Event, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		description := fmt.Sprintf("Бронирование #%d, мест: %d", entry.BookingID, entry.Seats)
+		if entry.EventDescription != "" {
+			description = entry.EventDescription + "\n\n" + description
+		}
+
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("booking-%d@eventbooker", entry.BookingID),
+			Summary:     entry.EventTitle,
+			Description: description,
+			Location:    entry.EventLocation,
+			Start:       entry.EventDate,
+			End:         entry.EventDate.Add(calendarEventDuration),
+			Updated:     entry.UpdatedAt,
+		})
+	}
+
+	return calendar.Write(w)
+}
+
+func (s *calendarService) subscription(userID int64, version int) *CalendarSubscription {
+	token := s.signToken(userID, version)
+	return &CalendarSubscription{
+		Token: token,
+		URL:   fmt.Sprintf("%s/api/v1/users/%d/calendar.ics?token=%s", s.baseURL, userID, token),
+	}
+}
+
+// signToken возвращает токен вида "<версия>.<HMAC>"; подпись привязана к пользователю и версии,
+// поэтому увеличение версии в БД делает недействительными все ранее выданные токены
+func (s *calendarService) signToken(userID int64, version int) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "calendar:%d:%d", userID, version)
+	return strconv.Itoa(version) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *calendarService) verifyToken(ctx context.Context, userID int64, token string) error {
+	versionStr, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return entity.ErrInvalidCalendarToken
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		return entity.ErrInvalidCalendarToken
+	}
+
+	if !hmac.Equal([]byte(token), []byte(s.signToken(userID, version))) {
+		return entity.ErrInvalidCalendarToken
+	}
+
+	current, err := s.userRepo.GetCalendarTokenVersion(ctx, userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrUserNotFound) {
+			return entity.ErrInvalidCalendarToken
+		}
+		return err
+	}
+	if version != current {
+		return entity.ErrInvalidCalendarToken
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// NotificationCapacityThreshold - уведомление организатору о том, что заполненность мероприятия достигла порога
+const NotificationCapacityThreshold = "capacity_threshold"
+
+// SetCapacityAlertThresholds задаёт пороги заполненности в процентах; пустой список отключает уведомления
+func (s *bookingService) SetCapacityAlertThresholds(thresholds []int) {
+	thresholds = append([]int(nil), thresholds...)
+	s.capacityAlerts.Store(&thresholds)
+}
+
+// claimCapacityAlerts проверяет, какие пороги пересекает подтверждение бронирования. Заполненность
+// считается от прочитанной перед подтверждением, без пересчёта статистики мероприятия; повторно
+// порог не срабатывает благодаря отметке в event_capacity_alerts. Вызывается в транзакции
+// подтверждения, поэтому уведомления попадают в outbox, только если подтверждение сохранено.
+func (s *bookingService) claimCapacityAlerts(ctx context.Context, booking *entity.Booking, event *entity.EventWithAvailability) error {
+	thresholds := s.capacityAlerts.Load()
+	// Без очереди уведомления некому отправить, а отмеченный порог уже не сработал бы
+	if s.queue == nil || thresholds == nil || len(*thresholds) == 0 {
+		return nil
+	}
+	// Места пула уже заняты его квотой: подтверждение из пула заполненность не меняет
+	if booking.PoolID != nil {
+		return nil
+	}
+
+	before := event.OccupiedSeats()
+	after := before + booking.Seats
+	crossed := entity.CrossedCapacityThresholds(*thresholds, event.TotalSeats, before, after)
+	if len(crossed) == 0 {
+		return nil
+	}
+
+	claimed, err := s.eventRepo.ClaimCapacityAlerts(ctx, event.ID, crossed, func(threshold int) []*entity.OutboxMessage {
+		return outboxMessages(ctx, []*Task{capacityAlertTask(event, threshold, after)})
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка при отметке порогов заполненности: %w", err)
+	}
+
+	if len(claimed) > 0 {
+		log.Printf("Мероприятие %d достигло порогов заполненности %v%%: занято %d из %d мест",
+			event.ID, claimed, after, event.TotalSeats)
+	}
+	return nil
+}
+
+func capacityAlertTask(event *entity.EventWithAvailability, threshold, occupied int) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("notification_%s_%d_%d", NotificationCapacityThreshold, event.ID, threshold),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": NotificationCapacityThreshold,
+			"event_id":          event.ID,
+			"threshold":         threshold,
+			"occupied_seats":    occupied,
+			"total_seats":       event.TotalSeats,
+		},
+		ExecuteAt:  time.Now(),
+		MaxRetries: 3,
+	}
+}
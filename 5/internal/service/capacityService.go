@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// NotificationCapacityReduced - уведомление владельцу бронирования, отменённого при уменьшении вместимости
+const NotificationCapacityReduced = "capacity_reduced"
+
+// ChangeCapacityRequest - новая вместимость и политика для бронирований, которые в неё не помещаются
+type ChangeCapacityRequest struct {
+	TotalSeats int                   `json:"total_seats" binding:"required,min=1,max=10000"`
+	Policy     entity.CapacityPolicy `json:"policy" binding:"required"`
+	Reason     string                `json:"reason"`
+	Version    *int                  `json:"version,omitempty"` // версия мероприятия, которую видел организатор
+}
+
+type capacityService struct {
+	capacityRepo repository.CapacityRepository
+	eventRepo    repository.EventRepository
+	venueRepo    repository.VenueRepository
+	queue        TaskPublisher
+}
+
+func NewCapacityService(
+	capacityRepo repository.CapacityRepository,
+	eventRepo repository.EventRepository,
+	venueRepo repository.VenueRepository,
+	queue TaskPublisher,
+) CapacityService {
+	return &capacityService{
+		capacityRepo: capacityRepo,
+		eventRepo:    eventRepo,
+		venueRepo:    venueRepo,
+		queue:        queue,
+	}
+}
+
+// PreviewCapacityChange показывает, какие бронирования затронет изменение, ничего не меняя.
+// К моменту применения список может измениться: ChangeCapacity пересчитывает его под блокировкой.
+func (s *capacityService) PreviewCapacityChange(ctx context.Context, eventID int64, totalSeats int, policy entity.CapacityPolicy) (*entity.CapacityPlan, error) {
+	if err := s.checkVenueCapacity(ctx, eventID, totalSeats); err != nil {
+		return nil, err
+	}
+
+	demand, err := s.capacityRepo.GetDemand(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return entity.PlanCapacityChange(demand, totalSeats, policy)
+}
+
+// ChangeCapacity меняет вместимость и разрешает непоместившиеся бронирования по политике:
+// отмена с полным возвратом оплаты, для waitlist - ещё и запись в лист ожидания.
+// Уведомления и выплаты возвратов уходят через outbox вместе с изменением.
+func (s *capacityService) ChangeCapacity(ctx context.Context, eventID int64, req *ChangeCapacityRequest) (*entity.CapacityPlan, error) {
+	if err := req.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkVenueCapacity(ctx, eventID, req.TotalSeats); err != nil {
+		return nil, err
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "вместимость мероприятия уменьшена организатором"
+	}
+
+	plan, err := s.capacityRepo.Apply(entity.WithAuditReason(ctx, reason), eventID, req.Version,
+		func(demand *entity.CapacityDemand) (*entity.CapacityPlan, error) {
+			return entity.PlanCapacityChange(demand, req.TotalSeats, req.Policy)
+		},
+		func(affected *entity.AffectedBooking, refund *entity.Refund) []*entity.OutboxMessage {
+			return s.capacityOutbox(ctx, affected, refund, reason)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	invalidateAvailability(ctx, s.eventRepo, eventID)
+
+	log.Printf("Вместимость мероприятия %d изменена: %d -> %d, политика %s, отменено бронирований: %d, к возврату %.2f",
+		eventID, plan.CurrentSeats, plan.TotalSeats, plan.Policy, len(plan.Affected), plan.RefundTotal)
+
+	return plan, nil
+}
+
+func (s *capacityService) GetWaitlist(ctx context.Context, eventID int64) ([]*entity.WaitlistEntry, error) {
+	if _, err := s.eventRepo.GetByID(ctx, eventID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	return s.capacityRepo.GetWaitlist(ctx, eventID)
+}
+
+func (s *capacityService) checkVenueCapacity(ctx context.Context, eventID int64, totalSeats int) error {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrEventNotFound
+		}
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+	if event.VenueID == nil {
+		return nil
+	}
+
+	venue, err := s.venueRepo.GetByID(ctx, *event.VenueID)
+	if err != nil {
+		return err
+	}
+	if totalSeats > venue.Capacity {
+		return fmt.Errorf("%w: %d seats, venue capacity is %d", entity.ErrVenueCapacityExceeded, totalSeats, venue.Capacity)
+	}
+	return nil
+}
+
+// capacityOutbox - уведомление владельцу и выплата возврата; без очереди ничего не пишется,
+// как и для обычной отмены
+func (s *capacityService) capacityOutbox(ctx context.Context, affected *entity.AffectedBooking, refund *entity.Refund, reason string) []*entity.OutboxMessage {
+	if s.queue == nil {
+		return nil
+	}
+
+	now := time.Now()
+	tasks := []*Task{{
+		ID:   fmt.Sprintf("notification_%s_%d", NotificationCapacityReduced, affected.BookingID),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": NotificationCapacityReduced,
+			"booking_id":        affected.BookingID,
+			"user_id":           affected.UserID,
+			"resolution":        affected.Resolution,
+			"refund_amount":     affected.RefundAmount,
+			"reason":            reason,
+		},
+		ExecuteAt:  now.Add(2 * time.Second),
+		MaxRetries: 3,
+	}}
+
+	if refund != nil {
+		tasks = append(tasks, &Task{
+			ID:   fmt.Sprintf("refund_%d", refund.BookingID),
+			Type: TaskTypeProcessRefund,
+			Data: map[string]interface{}{
+				"refund_id":  refund.ID,
+				"booking_id": refund.BookingID,
+				"amount":     refund.Amount,
+			},
+			ExecuteAt:  now,
+			MaxRetries: 5,
+		})
+	}
+
+	return outboxMessages(ctx, markStaffAssisted(ctx, tasks...))
+}
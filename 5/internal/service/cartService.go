@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// defaultCartTTL - срок жизни корзины без изменений, если он не задан в конфигурации
+const defaultCartTTL = 30 * time.Minute
+
+// AddCartItemRequest - позиция корзины; поля совпадают с BookSeatsRequest
+type AddCartItemRequest struct {
+	EventID   int64  `json:"event_id" binding:"required"`
+	Seats     int    `json:"seats" binding:"required,min=1,max=50"`
+	TierID    *int64 `json:"tier_id,omitempty"`
+	PromoCode string `json:"promo_code,omitempty" binding:"omitempty,max=50"`
+	PoolCode  string `json:"pool_code,omitempty" binding:"omitempty,max=50"`
+}
+
+// CheckoutCartRequest - параметры оформления корзины; тело запроса необязательно
+type CheckoutCartRequest struct {
+	ReservationTimeout int `json:"reservation_timeout" binding:"omitempty,min=1,max=1440"`
+}
+
+type cartService struct {
+	cartRepo       repository.CartRepository
+	eventRepo      repository.EventRepository
+	bookingService BookingService
+	ttl            time.Duration
+}
+
+// NewCartService создает новый экземпляр CartService
+func NewCartService(cartRepo repository.CartRepository, eventRepo repository.EventRepository, bookingService BookingService, ttl time.Duration) CartService {
+	if ttl <= 0 {
+		ttl = defaultCartTTL
+	}
+	return &cartService{
+		cartRepo:       cartRepo,
+		eventRepo:      eventRepo,
+		bookingService: bookingService,
+		ttl:            ttl,
+	}
+}
+
+func (s *cartService) GetCart(ctx context.Context, userID int64) (*entity.Cart, error) {
+	return s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+}
+
+// AddItem добавляет мероприятие в корзину. Места при этом не резервируются:
+// доступность проверяется здесь для подсказки пользователю и повторно при оформлении.
+func (s *cartService) AddItem(ctx context.Context, userID int64, req *AddCartItemRequest) (*entity.Cart, error) {
+	event, err := s.eventRepo.GetByID(ctx, req.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Date.Before(time.Now()) {
+		return nil, fmt.Errorf("невозможно забронировать места на прошедшее мероприятие")
+	}
+	if req.PoolCode == "" && event.AvailableSeats < req.Seats {
+		return nil, fmt.Errorf("%w: запрошено %d, доступно %d",
+			entity.ErrNotEnoughSeats, req.Seats, event.AvailableSeats)
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+	cart, err := s.cartRepo.GetOrCreateOpen(ctx, userID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &entity.CartItem{
+		CartID:    cart.ID,
+		EventID:   req.EventID,
+		Seats:     req.Seats,
+		TierID:    req.TierID,
+		PromoCode: req.PromoCode,
+		PoolCode:  req.PoolCode,
+	}
+	if err := s.cartRepo.AddItem(ctx, item, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+}
+
+func (s *cartService) RemoveItem(ctx context.Context, userID, itemID int64) (*entity.Cart, error) {
+	cart, err := s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.RemoveItem(ctx, cart.ID, itemID, time.Now().Add(s.ttl)); err != nil {
+		return nil, err
+	}
+
+	return s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+}
+
+func (s *cartService) ClearCart(ctx context.Context, userID int64) error {
+	cart, err := s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return s.cartRepo.ClearItems(ctx, cart.ID)
+}
+
+// Checkout бронирует все позиции корзины одной транзакцией: если хотя бы на одно
+// мероприятие не хватает мест, не создаётся ни одно бронирование и корзина остаётся открытой
+func (s *cartService) Checkout(ctx context.Context, userID int64, req *CheckoutCartRequest) ([]*entity.Booking, error) {
+	cart, err := s.cartRepo.GetOpenByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, entity.ErrCartEmpty
+	}
+
+	reqs := make([]*BookSeatsRequest, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		reqs = append(reqs, &BookSeatsRequest{
+			EventID:            item.EventID,
+			UserID:             userID,
+			Seats:              item.Seats,
+			ReservationTimeout: req.ReservationTimeout,
+			TierID:             item.TierID,
+			PromoCode:          item.PromoCode,
+			PoolCode:           item.PoolCode,
+		})
+	}
+
+	bookings, err := s.bookingService.BookSeatsBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Бронирования уже созданы, поэтому сбой здесь не отменяет оформление:
+	// незакрытую корзину закроет обход брошенных корзин
+	if closed, err := s.cartRepo.MarkCheckedOut(ctx, cart.ID); err != nil || !closed {
+		log.Printf("Корзина %d не закрыта после оформления: closed=%t, err=%v", cart.ID, closed, err)
+	}
+
+	log.Printf("Корзина оформлена: ID=%d, User=%d, Bookings=%d", cart.ID, userID, len(bookings))
+
+	return bookings, nil
+}
+
+// ExpireAbandonedCarts закрывает корзины, которые не менялись дольше срока жизни
+func (s *cartService) ExpireAbandonedCarts(ctx context.Context) (int64, error) {
+	expired, err := s.cartRepo.ExpireAbandoned(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire abandoned carts: %w", err)
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// EventHoldRequest describes a single block of held seats
+type EventHoldRequest struct {
+	Label  string `json:"label" binding:"required,min=1,max=100"`
+	Seats  int    `json:"seats" binding:"required,min=1,max=10000"`
+	Reason string `json:"reason,omitempty" binding:"omitempty,max=500"`
+}
+
+// SetEventHoldsRequest replaces all holds of an event; an empty list releases them
+type SetEventHoldsRequest struct {
+	Holds []EventHoldRequest `json:"holds" binding:"required,dive"`
+}
+
+type eventHoldService struct {
+	holdRepo  repository.EventHoldRepository
+	eventRepo repository.EventRepository
+}
+
+// NewEventHoldService creates a new instance of EventHoldService
+func NewEventHoldService(holdRepo repository.EventHoldRepository, eventRepo repository.EventRepository) EventHoldService {
+	return &eventHoldService{holdRepo: holdRepo, eventRepo: eventRepo}
+}
+
+func (s *eventHoldService) GetEventHolds(ctx context.Context, eventID int64) ([]*entity.EventHold, error) {
+	holds, err := s.holdRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+// SetEventHolds заменяет холды мероприятия целиком. Проверка мест выполняется в репозитории
+// под блокировкой мероприятия, чтобы не гоняться с параллельными бронированиями.
+func (s *eventHoldService) SetEventHolds(ctx context.Context, eventID int64, req *SetEventHoldsRequest) ([]*entity.EventHold, error) {
+	holds := make([]*entity.EventHold, 0, len(req.Holds))
+	labels := make(map[string]struct{}, len(req.Holds))
+	for _, h := range req.Holds {
+		label := strings.TrimSpace(h.Label)
+		if label == "" {
+			return nil, fmt.Errorf("hold label must not be empty")
+		}
+		if _, ok := labels[label]; ok {
+			return nil, fmt.Errorf("%w: %q", entity.ErrDuplicateHoldLabel, label)
+		}
+		labels[label] = struct{}{}
+
+		holds = append(holds, &entity.EventHold{
+			EventID: eventID,
+			Label:   label,
+			Seats:   h.Seats,
+			Reason:  strings.TrimSpace(h.Reason),
+		})
+	}
+
+	if err := s.holdRepo.ReplaceForEvent(ctx, eventID, holds); err != nil {
+		return nil, err
+	}
+	invalidateAvailability(ctx, s.eventRepo, eventID)
+
+	return holds, nil
+}
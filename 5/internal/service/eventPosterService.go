@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/images"
+)
+
+// posterExtensions - форматы, которые принимает сервис изображений
+var posterExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
+
+// PosterImages - сервис изображений, который строит варианты афиши; реализуется *images.Client
+type PosterImages interface {
+	Upload(ctx context.Context, filename string, file io.Reader) (*images.Image, error)
+	Get(ctx context.Context, id string) (*images.Image, error)
+	FormatURL(id, format string) string
+}
+
+// PosterOptions - ограничения загрузки и опроса сервиса изображений
+type PosterOptions struct {
+	MaxSize      int64
+	PollInterval time.Duration
+	PollTimeout  time.Duration
+}
+
+type eventPosterService struct {
+	posterRepo repository.EventPosterRepository
+	eventRepo  repository.EventRepository
+	images     PosterImages
+	queue      TaskPublisher
+	opts       PosterOptions
+}
+
+// NewEventPosterService: без images загрузка афиш возвращает entity.ErrPostersDisabled,
+// без queue статус обработки обновляется при чтении афиши
+func NewEventPosterService(
+	posterRepo repository.EventPosterRepository,
+	eventRepo repository.EventRepository,
+	images PosterImages,
+	queue TaskPublisher,
+	opts PosterOptions,
+) EventPosterService {
+	return &eventPosterService{
+		posterRepo: posterRepo,
+		eventRepo:  eventRepo,
+		images:     images,
+		queue:      queue,
+		opts:       opts,
+	}
+}
+
+// UploadPoster передаёт файл сервису изображений и сохраняет афишу в статусе processing;
+// прежняя афиша заменяется сразу, её варианты больше не показываются
+func (s *eventPosterService) UploadPoster(ctx context.Context, eventID int64, filename string, size int64, file io.Reader) (*entity.EventPoster, error) {
+	if s.images == nil {
+		return nil, entity.ErrPostersDisabled
+	}
+	if !posterExtensions[strings.ToLower(filepath.Ext(filename))] || size <= 0 || size > s.opts.MaxSize {
+		return nil, entity.ErrInvalidPosterFile
+	}
+	if _, err := s.eventRepo.GetByID(ctx, eventID); err != nil {
+		return nil, err
+	}
+
+	image, err := s.images.Upload(ctx, filename, file)
+	if err != nil {
+		return nil, err
+	}
+
+	poster := &entity.EventPoster{
+		EventID: eventID,
+		ImageID: image.ID,
+		Status:  entity.PosterProcessing,
+	}
+	if err := s.posterRepo.Save(ctx, poster); err != nil {
+		return nil, err
+	}
+
+	s.scheduleSync(ctx, eventID, image.ID, time.Now().Add(s.opts.PollTimeout))
+	return poster, nil
+}
+
+// GetPoster возвращает афишу мероприятия; если варианты ещё строятся, статус сначала
+// запрашивается у сервиса изображений
+func (s *eventPosterService) GetPoster(ctx context.Context, eventID int64) (*entity.EventPoster, error) {
+	poster, err := s.posterRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if poster.Status != entity.PosterProcessing || s.images == nil {
+		return poster, nil
+	}
+
+	image, err := s.images.Get(ctx, poster.ImageID)
+	if err != nil {
+		log.Printf("Не удалось получить статус афиши мероприятия %d: %v", eventID, err)
+		return poster, nil
+	}
+	if s.applyImage(poster, image) {
+		if _, err := s.posterRepo.UpdateResult(ctx, poster); err != nil {
+			return nil, err
+		}
+	}
+	return poster, nil
+}
+
+// SyncPoster проверяет обработку изображения imageID и записывает её итог. Пока варианты
+// строятся, проверка планируется снова, а после deadline афиша считается неудавшейся
+func (s *eventPosterService) SyncPoster(ctx context.Context, eventID int64, imageID string, deadline time.Time) error {
+	if s.images == nil {
+		return entity.ErrPostersDisabled
+	}
+
+	poster := &entity.EventPoster{EventID: eventID, ImageID: imageID, Status: entity.PosterProcessing}
+	image, err := s.images.Get(ctx, imageID)
+	switch {
+	case errors.Is(err, images.ErrNotFound):
+		poster.Status = entity.PosterFailed
+		poster.Error = "image was removed from the image service"
+	case err != nil:
+		return err
+	default:
+		s.applyImage(poster, image)
+	}
+
+	if poster.Status == entity.PosterProcessing {
+		if time.Now().Before(deadline) {
+			s.scheduleSync(ctx, eventID, imageID, deadline)
+			return nil
+		}
+		poster.Status = entity.PosterFailed
+		poster.Error = "image processing timed out"
+	}
+
+	updated, err := s.posterRepo.UpdateResult(ctx, poster)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		log.Printf("Афиша мероприятия %d заменена, результат обработки изображения %s пропущен", eventID, imageID)
+	}
+	return nil
+}
+
+// applyImage переносит итог обработки в афишу; false - изображение ещё обрабатывается
+func (s *eventPosterService) applyImage(poster *entity.EventPoster, image *images.Image) bool {
+	switch image.Status {
+	case images.StatusCompleted:
+		poster.Status = entity.PosterReady
+		poster.Formats = make(map[string]string, len(image.Formats))
+		for format := range image.Formats {
+			poster.Formats[format] = s.images.FormatURL(image.ID, format)
+		}
+		return true
+	case images.StatusFailed:
+		poster.Status = entity.PosterFailed
+		poster.Error = image.Error
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *eventPosterService) scheduleSync(ctx context.Context, eventID int64, imageID string, deadline time.Time) {
+	if s.queue == nil {
+		return
+	}
+
+	now := time.Now()
+	task := &Task{
+		ID:   fmt.Sprintf("sync_event_poster_%d_%s_%d", eventID, imageID, now.UnixNano()),
+		Type: TaskTypeSyncEventPoster,
+		Data: map[string]interface{}{
+			"event_id": eventID,
+			"image_id": imageID,
+			"deadline": deadline.Format(time.RFC3339),
+		},
+		ExecuteAt:  now.Add(s.opts.PollInterval),
+		MaxRetries: 3,
+	}
+	if err := s.queue.Publish(ctx, task); err != nil {
+		log.Printf("Ошибка при планировании проверки афиши мероприятия %d: %v", eventID, err)
+	}
+}
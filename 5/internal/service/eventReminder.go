@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// EventReminderOffsets - за сколько до начала мероприятия напоминать владельцам подтверждённых бронирований
+var EventReminderOffsets = []time.Duration{24 * time.Hour, time.Hour}
+
+// ReminderKey - имя напоминания за reminderHours часов до мероприятия ("24h", "1h").
+// По нему отмечается отправка, поэтому запланированное напоминание и обход по расписанию
+// с тем же сроком не отправляют его дважды.
+func ReminderKey(reminderHours float64) string {
+	return fmt.Sprintf("%gh", reminderHours)
+}
+
+// EventReminderTask - задача напоминания одному бронированию за offset до мероприятия.
+// ID включает дату мероприятия: повторное планирование того же напоминания не создаёт
+// новую задачу, а после переноса мероприятия планируется напоминание по новой дате.
+func EventReminderTask(booking *entity.Booking, event *entity.Event, offset time.Duration) *Task {
+	reminderHours := offset.Hours()
+
+	return &Task{
+		ID:   fmt.Sprintf("event_reminder_%d_%s_%d", booking.ID, ReminderKey(reminderHours), event.Date.Unix()),
+		Type: TaskTypeEventReminder,
+		Data: map[string]interface{}{
+			"booking_id":     booking.ID,
+			"event_id":       event.ID,
+			"reminder_hours": reminderHours,
+			"event_date":     event.Date.Format(time.RFC3339),
+		},
+		ExecuteAt:  event.Date.Add(-offset),
+		MaxRetries: 3,
+	}
+}
@@ -1,321 +1,573 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-// CreateEventRequest represents the data needed to create an event
-type CreateEventRequest struct {
-	Title       string    `json:"title" binding:"required,min=1,max=255"`
-	Description string    `json:"description" binding:"max=1000"`
-	Date        time.Time `json:"date" binding:"required"`
-	TotalSeats  int       `json:"total_seats" binding:"required,min=1,max=10000"`
-}
-
-// UpdateEventRequest represents the data needed to update an event
-type UpdateEventRequest struct {
-	Title       *string    `json:"title,omitempty"`
-	Description *string    `json:"description,omitempty"`
-	Date        *time.Time `json:"date,omitempty"`
-	TotalSeats  *int       `json:"total_seats,omitempty"`
-}
-
-// EventFilter represents filters for searching events
-type EventFilter struct {
-	Title     string    `json:"title,omitempty"`
-	DateFrom  time.Time `json:"date_from,omitempty"`
-	DateTo    time.Time `json:"date_to,omitempty"`
-	Limit     int       `json:"limit,omitempty"`
-	Offset    int       `json:"offset,omitempty"`
-	SortBy    string    `json:"sort_by,omitempty"`    // "date", "title", "created_at"
-	SortOrder string    `json:"sort_order,omitempty"` // "asc", "desc"
-}
-
-type eventService struct {
-	eventRepo   repository.EventRepository
-	bookingRepo repository.BookingRepository
-}
-
-// NewEventService creates a new instance of EventService
-func NewEventService(
-	eventRepo repository.EventRepository,
-	bookingRepo repository.BookingRepository,
-) EventService {
-	return &eventService{
-		eventRepo:   eventRepo,
-		bookingRepo: bookingRepo,
-	}
-}
-
-func (s *eventService) CreateEvent(ctx context.Context, req *CreateEventRequest) (*entity.Event, error) {
-	// Validate date is in the future
-	if req.Date.Before(time.Now()) {
-		return nil, fmt.Errorf("event date must be in the future")
-	}
-
-	event := &entity.Event{
-		Title:       req.Title,
-		Description: req.Description,
-		Date:        req.Date,
-		TotalSeats:  req.TotalSeats,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	if err := s.eventRepo.Create(ctx, event); err != nil {
-		return nil, fmt.Errorf("failed to create event: %w", err)
-	}
-
-	return event, nil
-}
-
-func (s *eventService) GetEvent(ctx context.Context, id int64) (*entity.EventWithAvailability, error) {
-	event, err := s.eventRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get event: %w", err)
-	}
-
-	return event, nil
-}
-
-func (s *eventService) GetAllEvents(ctx context.Context) ([]*entity.EventWithAvailability, error) {
-	events, err := s.eventRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all events: %w", err)
-	}
-
-	return events, nil
-}
-
-func (s *eventService) UpdateEvent(ctx context.Context, id int64, req *UpdateEventRequest) (*entity.Event, error) {
-	// Get existing event
-	existingEvent, err := s.eventRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get existing event: %w", err)
-	}
-
-	// Update fields if provided
-	event := &entity.Event{
-		ID:          id,
-		Title:       existingEvent.Title,
-		Description: existingEvent.Description,
-		Date:        existingEvent.Date,
-		TotalSeats:  existingEvent.TotalSeats,
-		UpdatedAt:   time.Now(),
-	}
-
-	if req.Title != nil {
-		event.Title = *req.Title
-	}
-	if req.Description != nil {
-		event.Description = *req.Description
-	}
-	if req.Date != nil {
-		if req.Date.Before(time.Now()) {
-			return nil, fmt.Errorf("event date must be in the future")
-		}
-		event.Date = *req.Date
-	}
-	if req.TotalSeats != nil {
-		if *req.TotalSeats < existingEvent.BookedSeats {
-			return nil, fmt.Errorf("cannot reduce total seats below current booked seats (%d)", existingEvent.BookedSeats)
-		}
-		event.TotalSeats = *req.TotalSeats
-	}
-
-	// Update in repository
-	if err := s.eventRepo.Update(ctx, event); err != nil {
-		return nil, fmt.Errorf("failed to update event: %w", err)
-	}
-
-	return event, nil
-}
-
-func (s *eventService) GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
-	bookings, err := s.bookingRepo.GetByEventID(ctx, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get event bookings: %w", err)
-	}
-
-	return bookings, nil
-}
-
-func (s *eventService) GetEventStats(ctx context.Context, eventID int64) (*entity.EventStats, error) {
-	event, err := s.eventRepo.GetByID(ctx, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get event: %w", err)
-	}
-
-	stats, err := s.bookingRepo.GetEventBookingStats(ctx, eventID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get booking stats: %w", err)
-	}
-
-	eventStats := &entity.EventStats{
-		Event:           event.Event,
-		BookingStats:    *stats,
-		UtilizationRate: stats.UtilizationRate(event.TotalSeats),
-		AvailableSeats:  stats.AvailableSeats(event.TotalSeats),
-	}
-
-	return eventStats, nil
-}
-
-func (s *eventService) SearchEvents(ctx context.Context, filter *EventFilter) ([]*entity.EventWithAvailability, error) {
-	if filter == nil {
-		filter = &EventFilter{}
-	}
-
-	// Set default values
-	if filter.Limit <= 0 || filter.Limit > 100 {
-		filter.Limit = 50
-	}
-	if filter.SortBy == "" {
-		filter.SortBy = "date"
-	}
-	if filter.SortOrder == "" {
-		filter.SortOrder = "asc"
-	}
-
-	// This would typically call a specialized repository method
-	// For now, we'll get all events and filter in memory (not efficient for large datasets)
-	allEvents, err := s.eventRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get events for search: %w", err)
-	}
-
-	var filteredEvents []*entity.EventWithAvailability
-	for _, event := range allEvents {
-		if filter.Title != "" && !containsIgnoreCase(event.Title, filter.Title) {
-			continue
-		}
-		if !filter.DateFrom.IsZero() && event.Date.Before(filter.DateFrom) {
-			continue
-		}
-		if !filter.DateTo.IsZero() && event.Date.After(filter.DateTo) {
-			continue
-		}
-		filteredEvents = append(filteredEvents, event)
-	}
-
-	// Apply sorting
-	filteredEvents = s.sortEvents(filteredEvents, filter.SortBy, filter.SortOrder)
-
-	// Apply pagination
-	if filter.Offset > 0 {
-		if filter.Offset >= len(filteredEvents) {
-			return []*entity.EventWithAvailability{}, nil
-		}
-		filteredEvents = filteredEvents[filter.Offset:]
-	}
-	if len(filteredEvents) > filter.Limit {
-		filteredEvents = filteredEvents[:filter.Limit]
-	}
-
-	return filteredEvents, nil
-}
-
-func (s *eventService) sortEvents(events []*entity.EventWithAvailability, sortBy, sortOrder string) []*entity.EventWithAvailability {
-	switch sortBy {
-	case "title":
-		if sortOrder == "desc" {
-			// Sort by title descending
-			// Implementation would sort events by title
-		} else {
-			// Sort by title ascending
-			// Implementation would sort events by title
-		}
-	case "created_at":
-		if sortOrder == "desc" {
-			// Sort by created_at descending
-			// Implementation would sort events by created_at
-		} else {
-			// Sort by created_at ascending
-			// Implementation would sort events by created_at
-		}
-	default: // "date"
-		if sortOrder == "desc" {
-			// Sort by date descending
-			// Implementation would sort events by date
-		} else {
-			// Sort by date ascending
-			// Implementation would sort events by date
-		}
-	}
-	return events
-}
-
-// Helper function for case-insensitive contains check
-func containsIgnoreCase(s, substr string) bool {
-	// Simple implementation - in production you might want more robust matching
-	return len(s) >= len(substr) && s[:len(substr)] == substr
-}
-
-// Исправляем метод DeleteEvent в eventService
-func (s *eventService) DeleteEvent(ctx context.Context, id int64) error {
-	// Проверяем, есть ли у события активные бронирования
-	bookings, err := s.bookingRepo.GetByEventID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to check event bookings: %w", err)
-	}
-
-	// Проверяем наличие активных бронирований (pending или confirmed)
-	for _, booking := range bookings {
-		if booking.Status == entity.BookingStatusPending || booking.Status == entity.BookingStatusConfirmed {
-			return fmt.Errorf("cannot delete event with active bookings")
-		}
-	}
-
-	// Удаляем событие
-	if err := s.eventRepo.Delete(ctx, id); err != nil {
-		return fmt.Errorf("failed to delete event: %w", err)
-	}
-
-	return nil
-}
-
-// Добавляем метод для получения всех событий (без статистики)
-func (s *eventService) GetAllEventsSimple(ctx context.Context) ([]*entity.Event, error) {
-	// Этот метод должен быть добавлен в репозиторий
-	// Временно используем существующий метод и преобразуем результат
-	eventsWithAvailability, err := s.eventRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all events: %w", err)
-	}
-
-	var events []*entity.Event
-	for _, eventWithAvail := range eventsWithAvailability {
-		events = append(events, &eventWithAvail.Event)
-	}
-
-	return events, nil
-}
-
-// Добавляем метод для поиска событий по названию
-func (s *eventService) SearchEventsByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error) {
-	if title == "" {
-		return s.eventRepo.GetAll(ctx)
-	}
-
-	events, err := s.eventRepo.SearchByTitle(ctx, title)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search events by title: %w", err)
-	}
-
-	return events, nil
-}
-
-// Добавляем метод для получения предстоящих событий
-func (s *eventService) GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error) {
-	events, err := s.eventRepo.GetUpcomingEvents(ctx, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get upcoming events: %w", err)
-	}
-
-	return events, nil
-}
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// NotificationEventCancelled - уведомление держателям билетов и вебхукам об отмене мероприятия
+const NotificationEventCancelled = "event_cancelled"
+
+// CreateEventRequest represents the data needed to create an event
+type CreateEventRequest struct {
+	Title       string    `json:"title" binding:"required,min=1,max=255"`
+	Description string    `json:"description" binding:"max=1000"`
+	Location    string    `json:"location" binding:"max=500"`
+	VenueID     *int64    `json:"venue_id,omitempty"`
+	Date        time.Time `json:"date" binding:"required"`
+	TotalSeats  int       `json:"total_seats" binding:"required,min=1,max=10000"`
+
+	// Организатор берётся из API-токена, из тела запроса не читается
+	OrganizerID *int64 `json:"-"`
+
+	// Политика отмены, по умолчанию entity.DefaultCancellationPolicy
+	FreeCancellationHours *int     `json:"free_cancellation_hours,omitempty" binding:"omitempty,min=0"`
+	LateRefundPercent     *float64 `json:"late_refund_percent,omitempty" binding:"omitempty,min=0,max=100"`
+
+	// Ступени возврата; если заданы, заменяют два поля выше
+	RefundRules entity.RefundRules `json:"refund_rules,omitempty"`
+
+	// Напоминания о неподтверждённых бронированиях, по умолчанию за 15 и 5 минут без продления
+	ConfirmationEscalation *entity.ConfirmationEscalation `json:"confirmation_escalation,omitempty"`
+
+	// Фазы продаж; без расписания продажи открыты сразу и до начала мероприятия
+	SaleSchedule *SaleScheduleRequest `json:"sale_schedule,omitempty"`
+}
+
+// UpdateEventRequest represents the data needed to update an event
+type UpdateEventRequest struct {
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Location    *string    `json:"location,omitempty" binding:"omitempty,max=500"`
+	VenueID     *int64     `json:"venue_id,omitempty"` // 0 отвязывает мероприятие от площадки
+	Date        *time.Time `json:"date,omitempty"`
+	TotalSeats  *int       `json:"total_seats,omitempty"`
+
+	FreeCancellationHours *int     `json:"free_cancellation_hours,omitempty" binding:"omitempty,min=0"`
+	LateRefundPercent     *float64 `json:"late_refund_percent,omitempty" binding:"omitempty,min=0,max=100"`
+
+	// nil оставляет ступени как есть, пустой список [] удаляет их
+	RefundRules entity.RefundRules `json:"refund_rules"`
+
+	ConfirmationEscalation *entity.ConfirmationEscalation `json:"confirmation_escalation,omitempty"`
+
+	SaleSchedule *SaleScheduleRequest `json:"sale_schedule,omitempty"`
+
+	// Версия, которую видел клиент; без неё проверяется версия, прочитанная перед обновлением
+	Version *int `json:"version,omitempty"`
+}
+
+// CancellationPolicyEvaluation describes the event cancellation policy and its effect right now
+type CancellationPolicyEvaluation struct {
+	EventID   int64                     `json:"event_id"`
+	EventDate time.Time                 `json:"event_date"`
+	Policy    entity.CancellationPolicy `json:"policy"`
+	Current   entity.CancellationQuote  `json:"current"`
+}
+
+// EventFilter represents filters for searching events
+type EventFilter struct {
+	Query     string    `json:"query,omitempty"` // полнотекстовый поиск по названию и описанию
+	Title     string    `json:"title,omitempty"`
+	DateFrom  time.Time `json:"date_from,omitempty"`
+	DateTo    time.Time `json:"date_to,omitempty"`
+	Limit     int       `json:"limit,omitempty"`
+	Offset    int       `json:"offset,omitempty"`
+	SortBy    string    `json:"sort_by,omitempty"`    // "date", "title", "created_at"
+	SortOrder string    `json:"sort_order,omitempty"` // "asc", "desc"
+	VenueID   *int64    `json:"venue_id,omitempty"`
+
+	OnlyAvailable bool `json:"only_available,omitempty"`
+}
+
+type eventService struct {
+	eventRepo   repository.EventRepository
+	bookingRepo repository.BookingRepository
+	venueRepo   repository.VenueRepository
+	poolRepo    repository.PartnerPoolRepository
+	posterRepo  repository.EventPosterRepository
+	queue       TaskPublisher
+}
+
+// NewEventService creates a new instance of EventService
+func NewEventService(
+	eventRepo repository.EventRepository,
+	bookingRepo repository.BookingRepository,
+	venueRepo repository.VenueRepository,
+	poolRepo repository.PartnerPoolRepository,
+	posterRepo repository.EventPosterRepository,
+	queue TaskPublisher,
+) EventService {
+	return &eventService{
+		eventRepo:   eventRepo,
+		bookingRepo: bookingRepo,
+		venueRepo:   venueRepo,
+		poolRepo:    poolRepo,
+		posterRepo:  posterRepo,
+		queue:       queue,
+	}
+}
+
+func (s *eventService) CreateEvent(ctx context.Context, req *CreateEventRequest) (*entity.Event, error) {
+	// Validate date is in the future
+	if req.Date.Before(time.Now()) {
+		return nil, entity.ErrEventDatePast
+	}
+
+	event := &entity.Event{
+		Title:              req.Title,
+		Description:        req.Description,
+		Location:           req.Location,
+		VenueID:            req.VenueID,
+		OrganizerID:        req.OrganizerID,
+		Date:               req.Date,
+		TotalSeats:         req.TotalSeats,
+		CancellationPolicy: entity.DefaultCancellationPolicy(),
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+	}
+	applyCancellationPolicy(&event.CancellationPolicy, req.FreeCancellationHours, req.LateRefundPercent)
+	event.CancellationPolicy.RefundRules = req.RefundRules
+	if err := event.CancellationPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if req.ConfirmationEscalation != nil {
+		if err := req.ConfirmationEscalation.Validate(); err != nil {
+			return nil, err
+		}
+		event.ConfirmationEscalation = *req.ConfirmationEscalation
+	}
+	if req.SaleSchedule != nil {
+		if err := applySaleSchedule(&event.SaleSchedule, req.SaleSchedule); err != nil {
+			return nil, err
+		}
+	}
+	if err := event.SaleSchedule.Validate(event.Date); err != nil {
+		return nil, err
+	}
+	if err := s.attachVenueChecked(ctx, event); err != nil {
+		return nil, err
+	}
+
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+	s.scheduleSaleAnnouncements(ctx, event)
+
+	return event, nil
+}
+
+func (s *eventService) GetEvent(ctx context.Context, id int64) (*entity.EventWithAvailability, error) {
+	event, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	if err := s.attachDetails(ctx, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (s *eventService) GetAllEvents(ctx context.Context) ([]*entity.EventWithAvailability, error) {
+	events, err := s.eventRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all events: %w", err)
+	}
+
+	if err := s.attachDetails(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *eventService) UpdateEvent(ctx context.Context, id int64, req *UpdateEventRequest) (*entity.Event, error) {
+	// Get existing event
+	existingEvent, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get existing event: %w", err)
+	}
+
+	// Update fields if provided
+	event := &entity.Event{
+		ID:          id,
+		Title:       existingEvent.Title,
+		Description: existingEvent.Description,
+		Location:    existingEvent.Location,
+		VenueID:     existingEvent.VenueID,
+		Date:        existingEvent.Date,
+		TotalSeats:  existingEvent.TotalSeats,
+
+		CancellationPolicy:     existingEvent.CancellationPolicy,
+		ConfirmationEscalation: existingEvent.ConfirmationEscalation,
+		SaleSchedule:           existingEvent.SaleSchedule,
+
+		CreatedAt: existingEvent.CreatedAt,
+		UpdatedAt: time.Now(),
+		Version:   existingEvent.Version,
+	}
+	if req.Version != nil {
+		event.Version = *req.Version
+	}
+
+	if req.Title != nil {
+		event.Title = *req.Title
+	}
+	if req.Description != nil {
+		event.Description = *req.Description
+	}
+	if req.Location != nil {
+		event.Location = *req.Location
+	}
+	if req.VenueID != nil {
+		event.VenueID = req.VenueID
+		if *req.VenueID == 0 {
+			event.VenueID = nil
+		}
+	}
+	if req.Date != nil {
+		if req.Date.Before(time.Now()) {
+			return nil, entity.ErrEventDatePast
+		}
+		event.Date = *req.Date
+	}
+	if req.TotalSeats != nil {
+		// Свободные места партнёрских пулов и холды тоже заняты: сначала их нужно снять
+		if held := existingEvent.BookedSeats + existingEvent.ReservedSeats + existingEvent.HeldSeats; *req.TotalSeats < held {
+			return nil, fmt.Errorf("%w (%d), use the capacity change workflow to resolve affected bookings", entity.ErrCapacityBelowDemand, held)
+		}
+		event.TotalSeats = *req.TotalSeats
+	}
+	applyCancellationPolicy(&event.CancellationPolicy, req.FreeCancellationHours, req.LateRefundPercent)
+	if req.RefundRules != nil {
+		event.CancellationPolicy.RefundRules = req.RefundRules
+	}
+	if err := event.CancellationPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if req.ConfirmationEscalation != nil {
+		if err := req.ConfirmationEscalation.Validate(); err != nil {
+			return nil, err
+		}
+		event.ConfirmationEscalation = *req.ConfirmationEscalation
+	}
+	if req.SaleSchedule != nil {
+		if err := applySaleSchedule(&event.SaleSchedule, req.SaleSchedule); err != nil {
+			return nil, err
+		}
+	}
+	if err := event.SaleSchedule.Validate(event.Date); err != nil {
+		return nil, err
+	}
+	if err := s.attachVenueChecked(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// Update in repository
+	if err := s.eventRepo.Update(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+	s.scheduleSaleAnnouncements(ctx, event)
+
+	return event, nil
+}
+
+func (s *eventService) GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error) {
+	bookings, err := s.bookingRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event bookings: %w", err)
+	}
+
+	return bookings, nil
+}
+
+func (s *eventService) GetEventStats(ctx context.Context, eventID int64) (*entity.EventStats, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	stats, err := s.bookingRepo.GetEventBookingStats(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking stats: %w", err)
+	}
+
+	eventStats := &entity.EventStats{
+		Event:           event.Event,
+		BookingStats:    *stats,
+		UtilizationRate: stats.UtilizationRate(event.TotalSeats),
+		AvailableSeats:  event.AvailableSeats,
+		ReservedSeats:   event.ReservedSeats,
+		HeldSeats:       event.HeldSeats,
+		Revenue:         stats.Revenue,
+	}
+
+	if s.poolRepo != nil {
+		eventStats.Pools, err = s.poolRepo.GetByEventID(ctx, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partner pools: %w", err)
+		}
+	}
+
+	return eventStats, nil
+}
+
+func (s *eventService) SearchEvents(ctx context.Context, filter *EventFilter) ([]*entity.EventWithAvailability, error) {
+	if filter == nil {
+		filter = &EventFilter{}
+	}
+
+	// Set default values
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = "date"
+	}
+	if filter.SortOrder == "" {
+		filter.SortOrder = "asc"
+	}
+
+	switch filter.SortBy {
+	case "date", "title", "created_at":
+	default:
+		return nil, fmt.Errorf("%w: invalid sort_by value: %s", entity.ErrInvalidInput, filter.SortBy)
+	}
+	switch filter.SortOrder {
+	case "asc", "desc":
+	default:
+		return nil, fmt.Errorf("%w: invalid sort_order value: %s", entity.ErrInvalidInput, filter.SortOrder)
+	}
+
+	events, err := s.eventRepo.Search(ctx, &entity.EventFilter{
+		Query:     filter.Query,
+		Title:     filter.Title,
+		DateFrom:  filter.DateFrom,
+		DateTo:    filter.DateTo,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+		SortBy:    filter.SortBy,
+		SortOrder: filter.SortOrder,
+		VenueID:   filter.VenueID,
+
+		OnlyAvailable: filter.OnlyAvailable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+
+	if err := s.attachDetails(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// DeleteEvent удаляет мероприятие без действующих бронирований. Удаление ещё не прошедшего
+// мероприятия - его отмена: в той же транзакции планируется уведомление event_cancelled
+func (s *eventService) DeleteEvent(ctx context.Context, id int64) error {
+	event, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrEventNotFound
+		}
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+
+	var outbox []*entity.OutboxMessage
+	if now := time.Now(); event.Date.After(now) {
+		outbox = outboxMessages(ctx, []*Task{eventCancelledTask(&event.Event, now)})
+	}
+
+	if err := s.eventRepo.Delete(ctx, id, outbox); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	return nil
+}
+
+// eventCancelledTask - уведомление об отмене мероприятия. Название и дата передаются в задаче:
+// удалённое мероприятие обработчик уже не прочитает
+func eventCancelledTask(event *entity.Event, now time.Time) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("notification_event_cancelled_%d_%d", event.ID, now.Unix()),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": NotificationEventCancelled,
+			"event_id":          event.ID,
+			"event_title":       event.Title,
+			"event_date":        event.Date.Format(time.RFC3339),
+		},
+		ExecuteAt:  now,
+		MaxRetries: 3,
+	}
+}
+
+// Добавляем метод для получения всех событий (без статистики)
+func (s *eventService) GetAllEventsSimple(ctx context.Context) ([]*entity.Event, error) {
+	// Этот метод должен быть добавлен в репозиторий
+	// Временно используем существующий метод и преобразуем результат
+	eventsWithAvailability, err := s.eventRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all events: %w", err)
+	}
+
+	var events []*entity.Event
+	for _, eventWithAvail := range eventsWithAvailability {
+		events = append(events, &eventWithAvail.Event)
+	}
+
+	return events, nil
+}
+
+// Добавляем метод для поиска событий по названию
+func (s *eventService) SearchEventsByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error) {
+	if title == "" {
+		return s.GetAllEvents(ctx)
+	}
+
+	events, err := s.eventRepo.SearchByTitle(ctx, title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events by title: %w", err)
+	}
+
+	if err := s.attachDetails(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Добавляем метод для получения предстоящих событий
+func (s *eventService) GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error) {
+	events, err := s.eventRepo.GetUpcomingEvents(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upcoming events: %w", err)
+	}
+
+	if err := s.attachDetails(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetCancellationPolicy возвращает политику отмены мероприятия и её действие на текущий момент
+func (s *eventService) GetCancellationPolicy(ctx context.Context, eventID int64) (*CancellationPolicyEvaluation, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	policy := event.CancellationPolicy
+	return &CancellationPolicyEvaluation{
+		EventID:   event.ID,
+		EventDate: event.Date,
+		Policy:    policy,
+		Current:   policy.Evaluate(event.Date, 0, time.Now()),
+	}, nil
+}
+
+// attachVenueChecked загружает площадку мероприятия и проверяет, что мест не больше её вместимости
+func (s *eventService) attachVenueChecked(ctx context.Context, event *entity.Event) error {
+	if event.VenueID == nil {
+		event.Venue = nil
+		return nil
+	}
+
+	venue, err := s.venueRepo.GetByID(ctx, *event.VenueID)
+	if err != nil {
+		return err
+	}
+	if event.TotalSeats > venue.Capacity {
+		return fmt.Errorf("%w: %d seats, venue capacity is %d", entity.ErrVenueCapacityExceeded, event.TotalSeats, venue.Capacity)
+	}
+
+	event.Venue = venue
+	return nil
+}
+
+// attachDetails подставляет в мероприятия площадки и афиши, по одному запросу на весь список,
+// и текущую фазу продаж
+func (s *eventService) attachDetails(ctx context.Context, events ...*entity.EventWithAvailability) error {
+	attachSaleStatus(time.Now(), events...)
+	if err := s.attachVenues(ctx, events...); err != nil {
+		return err
+	}
+	return s.attachPosters(ctx, events...)
+}
+
+// attachVenues подставляет площадки в мероприятия одним запросом на весь список
+func (s *eventService) attachVenues(ctx context.Context, events ...*entity.EventWithAvailability) error {
+	var ids []int64
+	for _, event := range events {
+		if event.VenueID != nil {
+			ids = append(ids, *event.VenueID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	venues, err := s.venueRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get event venues: %w", err)
+	}
+	for _, event := range events {
+		if event.VenueID != nil {
+			event.Venue = venues[*event.VenueID]
+		}
+	}
+
+	return nil
+}
+
+func (s *eventService) attachPosters(ctx context.Context, events ...*entity.EventWithAvailability) error {
+	if s.posterRepo == nil || len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	posters, err := s.posterRepo.GetByEventIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get event posters: %w", err)
+	}
+	for _, event := range events {
+		event.Poster = posters[event.ID]
+	}
+
+	return nil
+}
+
+func applyCancellationPolicy(policy *entity.CancellationPolicy, freeHours *int, lateRefundPercent *float64) {
+	if freeHours != nil {
+		policy.FreeCancellationHours = *freeHours
+	}
+	if lateRefundPercent != nil {
+		policy.LateRefundPercent = *lateRefundPercent
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// CreatePartnerPoolRequest represents the data needed to create a partner pool
+type CreatePartnerPoolRequest struct {
+	Name  string `json:"name" binding:"required,min=1,max=100"`
+	Code  string `json:"code" binding:"required,min=3,max=50"`
+	Seats int    `json:"seats" binding:"required,min=1,max=10000"`
+}
+
+// UpdatePartnerPoolRequest represents the data needed to update a partner pool
+type UpdatePartnerPoolRequest struct {
+	Name  *string `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Code  *string `json:"code,omitempty" binding:"omitempty,min=3,max=50"`
+	Seats *int    `json:"seats,omitempty" binding:"omitempty,min=1,max=10000"`
+}
+
+type partnerPoolService struct {
+	poolRepo  repository.PartnerPoolRepository
+	eventRepo repository.EventRepository
+}
+
+// NewPartnerPoolService creates a new instance of PartnerPoolService
+func NewPartnerPoolService(
+	poolRepo repository.PartnerPoolRepository,
+	eventRepo repository.EventRepository,
+) PartnerPoolService {
+	return &partnerPoolService{
+		poolRepo:  poolRepo,
+		eventRepo: eventRepo,
+	}
+}
+
+func (s *partnerPoolService) CreatePool(ctx context.Context, eventID int64, req *CreatePartnerPoolRequest) (*entity.PartnerPool, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	// Пул забирает места из общей продажи, поэтому не может быть больше свободных мест
+	if req.Seats > event.AvailableSeats {
+		return nil, fmt.Errorf("%w: requested %d, available %d",
+			entity.ErrPoolSeatsExceedEvent, req.Seats, event.AvailableSeats)
+	}
+
+	pool := &entity.PartnerPool{
+		EventID: eventID,
+		Name:    req.Name,
+		Code:    strings.TrimSpace(req.Code),
+		Seats:   req.Seats,
+	}
+
+	if err := s.poolRepo.Create(ctx, pool); err != nil {
+		return nil, err
+	}
+	invalidateAvailability(ctx, s.eventRepo, eventID)
+
+	return pool, nil
+}
+
+func (s *partnerPoolService) GetEventPools(ctx context.Context, eventID int64) ([]*entity.PartnerPoolWithAvailability, error) {
+	pools, err := s.poolRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event partner pools: %w", err)
+	}
+
+	return pools, nil
+}
+
+func (s *partnerPoolService) UpdatePool(ctx context.Context, poolID int64, req *UpdatePartnerPoolRequest) (*entity.PartnerPool, error) {
+	pool, err := s.poolRepo.GetByID(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		pool.Name = *req.Name
+	}
+	if req.Code != nil {
+		pool.Code = strings.TrimSpace(*req.Code)
+	}
+	if req.Seats != nil && *req.Seats != pool.Seats {
+		if err := s.validateSeatsChange(ctx, pool, *req.Seats); err != nil {
+			return nil, err
+		}
+		pool.Seats = *req.Seats
+	}
+
+	if err := s.poolRepo.Update(ctx, pool); err != nil {
+		return nil, err
+	}
+	invalidateAvailability(ctx, s.eventRepo, pool.EventID)
+
+	return pool, nil
+}
+
+// validateSeatsChange проверяет, что новая квота не меньше уже подтвержденных мест пула
+// и что увеличение квоты покрывается свободными местами мероприятия
+func (s *partnerPoolService) validateSeatsChange(ctx context.Context, pool *entity.PartnerPool, seats int) error {
+	pools, err := s.poolRepo.GetByEventID(ctx, pool.EventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event partner pools: %w", err)
+	}
+
+	for _, p := range pools {
+		if p.ID == pool.ID && seats < p.BookedSeats {
+			return fmt.Errorf("cannot reduce partner pool seats below booked seats (%d)", p.BookedSeats)
+		}
+	}
+
+	if seats < pool.Seats {
+		return nil
+	}
+
+	event, err := s.eventRepo.GetByID(ctx, pool.EventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+	if seats-pool.Seats > event.AvailableSeats {
+		return fmt.Errorf("%w: requested %d more, available %d",
+			entity.ErrPoolSeatsExceedEvent, seats-pool.Seats, event.AvailableSeats)
+	}
+
+	return nil
+}
+
+func (s *partnerPoolService) DeletePool(ctx context.Context, poolID int64) error {
+	pool, err := s.poolRepo.GetByID(ctx, poolID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.poolRepo.Delete(ctx, poolID); err != nil {
+		return err
+	}
+	invalidateAvailability(ctx, s.eventRepo, pool.EventID)
+	return nil
+}
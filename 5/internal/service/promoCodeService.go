@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// CreatePromoCodeRequest represents the data needed to create a promo code
+type CreatePromoCodeRequest struct {
+	Code          string              `json:"code" binding:"required,min=3,max=50"`
+	EventID       *int64              `json:"event_id,omitempty"`
+	DiscountType  entity.DiscountType `json:"discount_type" binding:"required,oneof=percent fixed"`
+	DiscountValue float64             `json:"discount_value" binding:"required,gt=0"`
+	MaxUses       int                 `json:"max_uses" binding:"min=0"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	Active        *bool               `json:"active,omitempty"`
+}
+
+// UpdatePromoCodeRequest represents the data needed to update a promo code
+type UpdatePromoCodeRequest struct {
+	EventID       *int64               `json:"event_id,omitempty"`
+	DiscountType  *entity.DiscountType `json:"discount_type,omitempty" binding:"omitempty,oneof=percent fixed"`
+	DiscountValue *float64             `json:"discount_value,omitempty" binding:"omitempty,gt=0"`
+	MaxUses       *int                 `json:"max_uses,omitempty" binding:"omitempty,min=0"`
+	ExpiresAt     *time.Time           `json:"expires_at,omitempty"`
+	Active        *bool                `json:"active,omitempty"`
+}
+
+type promoCodeService struct {
+	promoRepo repository.PromoCodeRepository
+	eventRepo repository.EventRepository
+}
+
+// NewPromoCodeService creates a new instance of PromoCodeService
+func NewPromoCodeService(
+	promoRepo repository.PromoCodeRepository,
+	eventRepo repository.EventRepository,
+) PromoCodeService {
+	return &promoCodeService{
+		promoRepo: promoRepo,
+		eventRepo: eventRepo,
+	}
+}
+
+func (s *promoCodeService) CreatePromoCode(ctx context.Context, req *CreatePromoCodeRequest) (*entity.PromoCode, error) {
+	promo := &entity.PromoCode{
+		Code:          strings.ToUpper(strings.TrimSpace(req.Code)),
+		EventID:       req.EventID,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		MaxUses:       req.MaxUses,
+		ExpiresAt:     req.ExpiresAt,
+		Active:        true,
+	}
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+
+	if err := s.validate(ctx, promo); err != nil {
+		return nil, err
+	}
+
+	if err := s.promoRepo.Create(ctx, promo); err != nil {
+		return nil, err
+	}
+
+	return promo, nil
+}
+
+func (s *promoCodeService) GetPromoCode(ctx context.Context, id int64) (*entity.PromoCode, error) {
+	return s.promoRepo.GetByID(ctx, id)
+}
+
+func (s *promoCodeService) ListPromoCodes(ctx context.Context) ([]*entity.PromoCode, error) {
+	promos, err := s.promoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promo codes: %w", err)
+	}
+
+	return promos, nil
+}
+
+func (s *promoCodeService) UpdatePromoCode(ctx context.Context, id int64, req *UpdatePromoCodeRequest) (*entity.PromoCode, error) {
+	promo, err := s.promoRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EventID != nil {
+		promo.EventID = req.EventID
+	}
+	if req.DiscountType != nil {
+		promo.DiscountType = *req.DiscountType
+	}
+	if req.DiscountValue != nil {
+		promo.DiscountValue = *req.DiscountValue
+	}
+	if req.MaxUses != nil {
+		promo.MaxUses = *req.MaxUses
+	}
+	if req.ExpiresAt != nil {
+		promo.ExpiresAt = req.ExpiresAt
+	}
+	if req.Active != nil {
+		promo.Active = *req.Active
+	}
+
+	if err := s.validate(ctx, promo); err != nil {
+		return nil, err
+	}
+
+	if err := s.promoRepo.Update(ctx, promo); err != nil {
+		return nil, err
+	}
+
+	return promo, nil
+}
+
+func (s *promoCodeService) DeletePromoCode(ctx context.Context, id int64) error {
+	return s.promoRepo.Delete(ctx, id)
+}
+
+// validate проверяет размер скидки и мероприятие, к которому привязан промокод
+func (s *promoCodeService) validate(ctx context.Context, promo *entity.PromoCode) error {
+	if promo.DiscountType == entity.DiscountTypePercent && promo.DiscountValue > 100 {
+		return fmt.Errorf("%w: percent discount cannot exceed 100", entity.ErrInvalidInput)
+	}
+
+	if promo.EventID != nil {
+		if _, err := s.eventRepo.GetByID(ctx, *promo.EventID); err != nil {
+			return fmt.Errorf("failed to get event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resolvePromoCode находит промокод и проверяет, что его можно применить к бронированию.
+// Лимит использований окончательно проверяется атомарно при создании бронирования.
+func resolvePromoCode(ctx context.Context, promoRepo repository.PromoCodeRepository, code string, eventID int64) (*entity.PromoCode, error) {
+	promo, err := promoRepo.GetByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !promo.Active:
+		return nil, entity.ErrPromoCodeNotFound
+	case promo.IsExpired(time.Now()):
+		return nil, entity.ErrPromoCodeExpired
+	case promo.IsExhausted():
+		return nil, entity.ErrPromoCodeExhausted
+	case !promo.AppliesTo(eventID):
+		return nil, entity.ErrPromoCodeNotApplicable
+	}
+
+	return promo, nil
+}
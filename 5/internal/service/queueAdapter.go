@@ -1,35 +1,150 @@
-package service
-
-import (
-	"context"
-
-	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
-)
-
-// QueueAdapter адаптирует queue.Queue к TaskPublisher интерфейсу
-type QueueAdapter struct {
-	queue queue.Queue
-}
-
-// NewQueueAdapter создает новый адаптер для очереди
-func NewQueueAdapter(q queue.Queue) *QueueAdapter {
-	return &QueueAdapter{queue: q}
-}
-
-// Publish публикует задачу, преобразуя service.Task в queue.Task
-func (a *QueueAdapter) Publish(ctx context.Context, task *Task) error {
-	if a.queue == nil {
-		return nil // Если очередь не инициализирована, игнорируем
-	}
-
-	queueTask := &queue.Task{
-		ID:         task.ID,
-		Type:       task.Type,
-		Data:       task.Data,
-		ExecuteAt:  task.ExecuteAt,
-		MaxRetries: task.MaxRetries,
-		Attempts:   task.Attempts,
-	}
-
-	return a.queue.Publish(ctx, queueTask)
-}
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+	"github.com/ds124wfegd/WB_L3/5/pkg/tracing"
+)
+
+// QueueAdapter адаптирует queue.Queue к TaskPublisher интерфейсу
+type QueueAdapter struct {
+	queue queue.Queue
+}
+
+// NewQueueAdapter создает новый адаптер для очереди
+func NewQueueAdapter(q queue.Queue) *QueueAdapter {
+	return &QueueAdapter{queue: q}
+}
+
+// Publish публикует задачу, преобразуя service.Task в queue.Task
+func (a *QueueAdapter) Publish(ctx context.Context, task *Task) error {
+	if a.queue == nil {
+		return nil // Если очередь не инициализирована, игнорируем
+	}
+
+	ctx, span, data := tracing.StartPublish(ctx, task.ID, task.Type, requestid.SetTaskData(ctx, task.Data))
+	defer span.End()
+
+	queueTask := &queue.Task{
+		ID:         task.ID,
+		Type:       task.Type,
+		Data:       data,
+		ExecuteAt:  task.ExecuteAt,
+		MaxRetries: task.MaxRetries,
+		Attempts:   task.Attempts,
+		Priority:   queue.Priority(task.Priority),
+	}
+
+	err := a.queue.Publish(ctx, queueTask)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// PendingTasks перечисляет невыполненные задачи типа taskType. Умеет только очередь Redis,
+// для брокеров возвращает ErrTaskInspectionUnsupported
+func (a *QueueAdapter) PendingTasks(ctx context.Context, taskType string) ([]*Task, error) {
+	inspector, ok := a.queue.(interface {
+		PendingTasks(ctx context.Context, taskType queue.TaskType) ([]*queue.Task, error)
+	})
+	if !ok {
+		return nil, ErrTaskInspectionUnsupported
+	}
+
+	queued, err := inspector.PendingTasks(ctx, queue.TaskType(taskType))
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0, len(queued))
+	for _, task := range queued {
+		tasks = append(tasks, fromQueueTask(task))
+	}
+	return tasks, nil
+}
+
+// DLQAdapter адаптирует queue.DLQHandler к DeadLetterQueue интерфейсу
+type DLQAdapter struct {
+	dlq queue.DLQHandler
+}
+
+func NewDLQAdapter(dlq queue.DLQHandler) *DLQAdapter {
+	return &DLQAdapter{dlq: dlq}
+}
+
+func (a *DLQAdapter) DeadLetters(ctx context.Context, limit int) ([]*DeadLetter, error) {
+	failed, err := a.dlq.GetFailedTasks(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*DeadLetter, 0, len(failed))
+	for _, f := range failed {
+		if f.Task == nil {
+			continue
+		}
+		letters = append(letters, &DeadLetter{Task: fromQueueTask(f.Task), Error: f.Error, FailedAt: f.FailedAt})
+	}
+	return letters, nil
+}
+
+func (a *DLQAdapter) DeleteDeadLetter(ctx context.Context, taskID string) error {
+	return a.dlq.DeleteFailedTask(ctx, taskID)
+}
+
+func fromQueueTask(task *queue.Task) *Task {
+	return &Task{
+		ID:         task.ID,
+		Type:       string(task.Type),
+		Data:       task.Data,
+		ExecuteAt:  task.ExecuteAt,
+		MaxRetries: task.MaxRetries,
+		Attempts:   task.Attempts,
+		Priority:   string(task.Priority),
+	}
+}
+
+// alertingDLQHandler публикует в ленту админки каждую задачу, которую очередь отправляет в DLQ
+type alertingDLQHandler struct {
+	queue.DLQHandler
+	feed AdminFeedPublisher
+}
+
+// WithDLQAlerts оборачивает DLQ очереди Redis оповещением ленты админки
+func WithDLQAlerts(dlq queue.DLQHandler, feed AdminFeedPublisher) queue.DLQHandler {
+	return &alertingDLQHandler{DLQHandler: dlq, feed: feed}
+}
+
+func (h *alertingDLQHandler) HandleFailedTask(task *queue.Task, err error) {
+	h.DLQHandler.HandleFailedTask(task, err)
+
+	failed := fromQueueTask(task)
+	event := &entity.AdminFeedEvent{
+		Topic: entity.AdminFeedDLQ,
+		Type:  AdminFeedTaskFailed,
+		Data: map[string]interface{}{
+			"task_id":   failed.ID,
+			"task_type": failed.Type,
+			"attempts":  failed.Attempts,
+			"error":     err.Error(),
+		},
+		At: time.Now(),
+	}
+	// Данные задачи прошли через JSON, поэтому числа в них float64
+	if eventID, ok := failed.Data["event_id"].(float64); ok {
+		event.EventID = int64(eventID)
+	}
+	if bookingID, ok := failed.Data["booking_id"].(float64); ok {
+		event.BookingID = int64(bookingID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminFeedPublishTimeout)
+	defer cancel()
+	if err := h.feed.Publish(ctx, event); err != nil {
+		log.Printf("Ошибка при публикации задачи %s из DLQ в ленту админки: %v", task.ID, err)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// NotificationBookingConfirmed - уведомление о подтверждении бронирования; под этим же именем
+// обработанное уведомление отмечается в booking_notifications
+const NotificationBookingConfirmed = "booking_confirmed"
+
+const (
+	// reconcileLookback - за какой срок сверка проверяет подтверждения. Отметки об уведомлениях
+	// появились вместе со сверкой, поэтому подтверждения до её выпуска попадут в отчёт не дольше недели
+	reconcileLookback = 7 * 24 * time.Hour
+	// reconcileGrace - свежие бронирования и подтверждения не проверяются: их задачи ещё могут
+	// быть на пути в очередь
+	reconcileGrace = 10 * time.Minute
+	// reconcileDLQLimit - сколько последних задач DLQ просматривает сверка
+	reconcileDLQLimit = 1000
+)
+
+// ErrTaskInspectionUnsupported - очередь не умеет перечислять невыполненные задачи
+var ErrTaskInspectionUnsupported = errors.New("queue driver does not support listing pending tasks")
+
+// TaskInspector перечисляет задачи, которые очередь ещё не выполнила
+type TaskInspector interface {
+	PendingTasks(ctx context.Context, taskType string) ([]*Task, error)
+}
+
+// DeadLetter - задача, попавшая в DLQ, и последняя ошибка её обработки
+type DeadLetter struct {
+	Task     *Task
+	Error    string
+	FailedAt time.Time
+}
+
+// DeadLetterQueue - задачи DLQ, которые сверка проверяет на ссылки на удалённые бронирования
+type DeadLetterQueue interface {
+	DeadLetters(ctx context.Context, limit int) ([]*DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, taskID string) error
+}
+
+// ReconciliationService сверяет бронирования в Postgres с задачами очереди и уведомлениями
+type ReconciliationService interface {
+	// Reconcile строит отчёт о расхождениях; с apply выполняет предложенные исправления
+	Reconcile(ctx context.Context, apply bool) (*entity.ReconciliationReport, error)
+}
+
+type reconciliationService struct {
+	bookingRepo repository.BookingRepository
+	outboxRepo  repository.OutboxRepository
+	tasks       TaskInspector
+	dlq         DeadLetterQueue
+}
+
+// NewReconciliationService принимает nil вместо tasks и dlq, если очередь их не поддерживает:
+// соответствующие проверки тогда пропускаются и попадают в Skipped отчёта
+func NewReconciliationService(bookingRepo repository.BookingRepository, outboxRepo repository.OutboxRepository, tasks TaskInspector, dlq DeadLetterQueue) ReconciliationService {
+	return &reconciliationService{
+		bookingRepo: bookingRepo,
+		outboxRepo:  outboxRepo,
+		tasks:       tasks,
+		dlq:         dlq,
+	}
+}
+
+func (s *reconciliationService) Reconcile(ctx context.Context, apply bool) (*entity.ReconciliationReport, error) {
+	report := &entity.ReconciliationReport{
+		GeneratedAt: time.Now(),
+		Apply:       apply,
+		Skipped:     make(map[string]string),
+		Issues:      []*entity.ReconciliationIssue{},
+	}
+
+	if err := s.checkPendingExpiry(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := s.checkConfirmationNotifications(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeadLetters(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if apply {
+		for _, issue := range report.Issues {
+			if err := s.repair(ctx, issue); err != nil {
+				issue.Error = err.Error()
+				continue
+			}
+			issue.Applied = true
+		}
+	}
+
+	log.Printf("Сверка бронирований: найдено расхождений %d, пропущено проверок %d, исправления применены: %t",
+		len(report.Issues), len(report.Skipped), apply)
+	return report, nil
+}
+
+// checkPendingExpiry ищет ожидающие бронирования без задачи истечения в очереди и в outbox
+func (s *reconciliationService) checkPendingExpiry(ctx context.Context, report *entity.ReconciliationReport) error {
+	kind := entity.ReconcilePendingWithoutExpiry
+	if s.tasks == nil {
+		report.Skipped[kind] = "queue is not configured"
+		return nil
+	}
+
+	queued, err := s.tasks.PendingTasks(ctx, TaskTypeExpireBooking)
+	if errors.Is(err, ErrTaskInspectionUnsupported) {
+		report.Skipped[kind] = err.Error()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось получить задачи истечения из очереди: %w", err)
+	}
+
+	outbox, err := s.outboxRepo.GetPending(ctx, TaskTypeExpireBooking)
+	if err != nil {
+		return fmt.Errorf("не удалось получить задачи истечения из outbox: %w", err)
+	}
+
+	scheduled := make(map[int64]bool, len(queued)+len(outbox))
+	for _, task := range queued {
+		if id, ok := taskBookingID(task.Data); ok {
+			scheduled[id] = true
+		}
+	}
+	for _, msg := range outbox {
+		if id, ok := taskBookingID(msg.Data); ok {
+			scheduled[id] = true
+		}
+	}
+
+	pending, err := s.bookingRepo.GetByStatus(ctx, entity.BookingStatusPending)
+	if err != nil {
+		return fmt.Errorf("не удалось получить ожидающие бронирования: %w", err)
+	}
+
+	// Задачи прочитаны раньше бронирований: свежее бронирование могло появиться между
+	// чтениями вместе со своей задачей в outbox, поэтому оно не проверяется
+	createdBefore := report.GeneratedAt.Add(-reconcileGrace)
+	for _, booking := range pending {
+		if scheduled[booking.ID] || booking.CreatedAt.After(createdBefore) {
+			continue
+		}
+		report.Issues = append(report.Issues, &entity.ReconciliationIssue{
+			Kind:      kind,
+			BookingID: booking.ID,
+			Details:   fmt.Sprintf("pending booking expires at %s but has no expire task", booking.ExpiresAt.Format(time.RFC3339)),
+			Action:    entity.RepairScheduleExpiry,
+		})
+	}
+	return nil
+}
+
+// checkConfirmationNotifications ищет подтверждения последней недели без обработанного уведомления
+func (s *reconciliationService) checkConfirmationNotifications(ctx context.Context, report *entity.ReconciliationReport) error {
+	kind := entity.ReconcileMissingConfirmation
+	if s.tasks == nil {
+		// Без очереди уведомления не отправляются вовсе
+		report.Skipped[kind] = "queue is not configured"
+		return nil
+	}
+
+	to := report.GeneratedAt.Add(-reconcileGrace)
+	bookings, err := s.bookingRepo.GetMissingNotification(ctx, entity.BookingStatusConfirmed,
+		NotificationBookingConfirmed, to.Add(-reconcileLookback), to)
+	if err != nil {
+		return fmt.Errorf("не удалось получить подтверждения без уведомлений: %w", err)
+	}
+
+	for _, booking := range bookings {
+		report.Issues = append(report.Issues, &entity.ReconciliationIssue{
+			Kind:      kind,
+			BookingID: booking.ID,
+			Details:   fmt.Sprintf("booking confirmed at %s, confirmation notification was not handled", booking.UpdatedAt.Format(time.RFC3339)),
+			Action:    entity.RepairResendConfirmation,
+		})
+	}
+	return nil
+}
+
+// checkDeadLetters ищет задачи DLQ, бронирования которых удалены
+func (s *reconciliationService) checkDeadLetters(ctx context.Context, report *entity.ReconciliationReport) error {
+	kind := entity.ReconcileDLQDeletedBooking
+	if s.dlq == nil {
+		report.Skipped[kind] = "DLQ is available only with the Redis queue"
+		return nil
+	}
+
+	letters, err := s.dlq.DeadLetters(ctx, reconcileDLQLimit)
+	if err != nil {
+		return fmt.Errorf("не удалось получить задачи DLQ: %w", err)
+	}
+
+	for _, letter := range letters {
+		bookingID, ok := taskBookingID(letter.Task.Data)
+		if !ok {
+			continue
+		}
+		_, err := s.bookingRepo.GetByID(ctx, bookingID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, entity.ErrBookingNotFound) {
+			return fmt.Errorf("не удалось проверить бронирование %d: %w", bookingID, err)
+		}
+
+		report.Issues = append(report.Issues, &entity.ReconciliationIssue{
+			Kind:      kind,
+			BookingID: bookingID,
+			TaskID:    letter.Task.ID,
+			Details:   fmt.Sprintf("%s task failed at %s: %s", letter.Task.Type, letter.FailedAt.Format(time.RFC3339), letter.Error),
+			Action:    entity.RepairDeleteDLQTask,
+		})
+	}
+	return nil
+}
+
+// repair выполняет исправление одного расхождения. Задачи пишутся в outbox, а не сразу
+// в очередь: так исправление не теряется, если очередь временно недоступна
+func (s *reconciliationService) repair(ctx context.Context, issue *entity.ReconciliationIssue) error {
+	switch issue.Action {
+	case entity.RepairScheduleExpiry, entity.RepairResendConfirmation:
+		booking, err := s.bookingRepo.GetByID(ctx, issue.BookingID)
+		if err != nil {
+			return err
+		}
+
+		// Пока отчёт строился, бронирование могли подтвердить или отменить
+		task, status := expireTask(booking, time.Now()), entity.BookingStatusPending
+		if issue.Action == entity.RepairResendConfirmation {
+			task, status = confirmedNotificationTask(booking, time.Now()), entity.BookingStatusConfirmed
+		}
+		if booking.Status != status {
+			return fmt.Errorf("booking status changed to %s, repair is no longer needed", booking.Status)
+		}
+		_, err = s.outboxRepo.Enqueue(ctx, outboxMessages(ctx, []*Task{task}))
+		return err
+	case entity.RepairDeleteDLQTask:
+		return s.dlq.DeleteDeadLetter(ctx, issue.TaskID)
+	default:
+		return fmt.Errorf("неизвестное исправление: %s", issue.Action)
+	}
+}
+
+// taskBookingID читает booking_id из данных задачи; после JSON число приходит как float64
+func taskBookingID(data map[string]interface{}) (int64, bool) {
+	switch id := data["booking_id"].(type) {
+	case float64:
+		return int64(id), true
+	case int64:
+		return id, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// NotificationSalePhaseStarted - объявление подписчикам вебхуков и организатору о начале фазы продаж
+const NotificationSalePhaseStarted = "sale_phase_started"
+
+// SaleScheduleRequest задаёт фазы продаж мероприятия. При обновлении заменяет расписание целиком,
+// кроме кодов доступа: nil оставляет прежние коды, пустой список [] удаляет их
+type SaleScheduleRequest struct {
+	PresaleStartsAt     *time.Time `json:"presale_starts_at,omitempty"`
+	GeneralSaleStartsAt *time.Time `json:"general_sale_starts_at,omitempty"`
+	SalesEndAt          *time.Time `json:"sales_end_at,omitempty"`
+	AccessCodes         []string   `json:"access_codes,omitempty"`
+}
+
+// applySaleSchedule переносит расписание из запроса в мероприятие
+func applySaleSchedule(schedule *entity.SaleSchedule, req *SaleScheduleRequest) error {
+	codes := schedule.AccessCodeHashes
+	*schedule = entity.SaleSchedule{
+		PresaleStartsAt:     req.PresaleStartsAt,
+		GeneralSaleStartsAt: req.GeneralSaleStartsAt,
+		SalesEndAt:          req.SalesEndAt,
+		AccessCodeHashes:    codes,
+	}
+	if req.AccessCodes != nil {
+		return schedule.SetAccessCodes(req.AccessCodes)
+	}
+	return nil
+}
+
+// scheduleSaleAnnouncements планирует объявления о ещё не наступивших фазах продаж.
+// ID задачи включает время начала фазы: повторное сохранение того же расписания не создаёт
+// дублей, а задачи от изменённого расписания обработчик пропускает, сверившись с мероприятием.
+func (s *eventService) scheduleSaleAnnouncements(ctx context.Context, event *entity.Event) {
+	if s.queue == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, transition := range event.SaleSchedule.Transitions(event.Date) {
+		// Окончание продаж вместе с началом мероприятия объявлять незачем
+		if transition.Phase == entity.SalePhaseClosed && event.SaleSchedule.SalesEndAt == nil {
+			continue
+		}
+		if !transition.At.After(now) {
+			continue
+		}
+
+		if err := s.queue.Publish(ctx, salePhaseTask(event, transition)); err != nil {
+			log.Printf("Ошибка при планировании объявления о фазе продаж %s мероприятия %d: %v", transition.Phase, event.ID, err)
+		}
+	}
+}
+
+func salePhaseTask(event *entity.Event, transition entity.SaleTransition) *Task {
+	return &Task{
+		ID:   fmt.Sprintf("notification_%s_%d_%s_%d", NotificationSalePhaseStarted, event.ID, transition.Phase, transition.At.Unix()),
+		Type: TaskTypeSendNotification,
+		Data: map[string]interface{}{
+			"notification_type": NotificationSalePhaseStarted,
+			"event_id":          event.ID,
+			"phase":             transition.Phase,
+			"phase_at":          transition.At.Format(time.RFC3339),
+		},
+		ExecuteAt:  transition.At,
+		MaxRetries: 3,
+	}
+}
+
+// attachSaleStatus подставляет в мероприятия текущую фазу продаж
+func attachSaleStatus(now time.Time, events ...*entity.EventWithAvailability) {
+	for _, event := range events {
+		status := event.SaleSchedule.Status(now, event.Date)
+		event.Sale = &status
+	}
+}
@@ -1,73 +1,216 @@
-package service
-
-import (
-	"context"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-type EventService interface {
-	// Основные операции
-	CreateEvent(ctx context.Context, req *CreateEventRequest) (*entity.Event, error)
-	GetEvent(ctx context.Context, id int64) (*entity.EventWithAvailability, error)
-	GetAllEvents(ctx context.Context) ([]*entity.EventWithAvailability, error)
-	UpdateEvent(ctx context.Context, id int64, req *UpdateEventRequest) (*entity.Event, error)
-	DeleteEvent(ctx context.Context, id int64) error
-
-	// Дополнительные операции
-	GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error)
-	GetEventStats(ctx context.Context, eventID int64) (*entity.EventStats, error)
-	SearchEvents(ctx context.Context, filter *EventFilter) ([]*entity.EventWithAvailability, error)
-	GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error)
-	SearchEventsByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error)
-}
-
-// UserService defines the interface for user operations
-type UserService interface {
-	// Основные операции
-	RegisterUser(ctx context.Context, req *RegisterUserRequest) (*entity.User, error)
-	GetUserByID(ctx context.Context, id int64) (*entity.User, error) // ДОБАВЛЕНО
-	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
-	UpdateUser(ctx context.Context, id int64, req *UpdateUserRequest) (*entity.User, error)
-	LinkTelegram(ctx context.Context, userID int64, telegramID string) error
-	DeleteUser(ctx context.Context, id int64) error
-
-	// Статистика и аналитика
-	GetUserStats(ctx context.Context, userID int64) (*UserStats, error)
-
-	// Поиск и списки
-	GetAllUsers(ctx context.Context) ([]*entity.User, error)
-	SearchUsersByName(ctx context.Context, name string) ([]*entity.User, error)
-}
-
-// BookingService определяет интерфейс для операций с бронированиями
-type BookingService interface {
-	// Основные операции
-	BookSeats(ctx context.Context, req *BookSeatsRequest) (*entity.Booking, error)
-	ConfirmBooking(ctx context.Context, bookingID int64) error
-	CancelBooking(ctx context.Context, bookingID int64, reason string) error
-	GetBooking(ctx context.Context, id int64) (*entity.Booking, error)
-	GetUserBookings(ctx context.Context, userID int64) ([]*entity.Booking, error)
-	GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error)
-
-	// Операции истечения срока
-	CancelExpiredBookings(ctx context.Context) error
-	GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error)
-	ExpireBooking(ctx context.Context, bookingID int64) error
-
-	// Дополнительные операции
-	GetBookingsByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error)
-	UpdateBookingSeats(ctx context.Context, bookingID int64, seats int) error
-	UpdateBookingStatus(ctx context.Context, bookingID int64, status entity.BookingStatus) error
-	GetBookingStats(ctx context.Context) (*BookingStats, error)
-
-	// Административные операции
-	GetAllBookings(ctx context.Context) ([]*entity.Booking, error)
-	DeleteBooking(ctx context.Context, bookingID int64) error
-	GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error)
-
-	// Утилиты
-	GetBookingWithDetails(ctx context.Context, bookingID int64) (*BookingDetails, error)
-	CheckBookingAvailability(ctx context.Context, eventID int64, seats int) (bool, error)
-}
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+type EventService interface {
+	// Основные операции
+	CreateEvent(ctx context.Context, req *CreateEventRequest) (*entity.Event, error)
+	GetEvent(ctx context.Context, id int64) (*entity.EventWithAvailability, error)
+	GetAllEvents(ctx context.Context) ([]*entity.EventWithAvailability, error)
+	UpdateEvent(ctx context.Context, id int64, req *UpdateEventRequest) (*entity.Event, error)
+	DeleteEvent(ctx context.Context, id int64) error
+
+	// Дополнительные операции
+	GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error)
+	GetEventStats(ctx context.Context, eventID int64) (*entity.EventStats, error)
+	SearchEvents(ctx context.Context, filter *EventFilter) ([]*entity.EventWithAvailability, error)
+	GetUpcomingEvents(ctx context.Context, limit int) ([]*entity.EventWithAvailability, error)
+	SearchEventsByTitle(ctx context.Context, title string) ([]*entity.EventWithAvailability, error)
+	GetCancellationPolicy(ctx context.Context, eventID int64) (*CancellationPolicyEvaluation, error)
+}
+
+// EventPosterService загружает афиши мероприятий в сервис изображений и следит за их обработкой
+type EventPosterService interface {
+	UploadPoster(ctx context.Context, eventID int64, filename string, size int64, file io.Reader) (*entity.EventPoster, error)
+	GetPoster(ctx context.Context, eventID int64) (*entity.EventPoster, error)
+	SyncPoster(ctx context.Context, eventID int64, imageID string, deadline time.Time) error
+}
+
+// CapacityService - изменение вместимости мероприятия, когда места уже заняты
+type CapacityService interface {
+	PreviewCapacityChange(ctx context.Context, eventID int64, totalSeats int, policy entity.CapacityPolicy) (*entity.CapacityPlan, error)
+	ChangeCapacity(ctx context.Context, eventID int64, req *ChangeCapacityRequest) (*entity.CapacityPlan, error)
+	GetWaitlist(ctx context.Context, eventID int64) ([]*entity.WaitlistEntry, error)
+}
+
+// UserService defines the interface for user operations
+type UserService interface {
+	// Основные операции
+	RegisterUser(ctx context.Context, req *RegisterUserRequest) (*entity.User, error)
+	GetUserByID(ctx context.Context, id int64) (*entity.User, error) // ДОБАВЛЕНО
+	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID string) (*entity.User, error)
+	UpdateUser(ctx context.Context, id int64, req *UpdateUserRequest) (*entity.User, error)
+	LinkTelegram(ctx context.Context, userID int64, telegramID string) error
+	UpdateNotificationPreferences(ctx context.Context, userID int64, req *NotificationPreferencesRequest) (*entity.User, error)
+	DeleteUser(ctx context.Context, id int64) error
+
+	// Аутентификация и роли
+	Authenticate(ctx context.Context, email, password string) (*entity.User, error)
+	SetUserRole(ctx context.Context, userID int64, role string) error
+	// StartImpersonation проверяет, что администратор из ctx может действовать от имени
+	// пользователя, и записывает начало имперсонации в журнал аудита
+	StartImpersonation(ctx context.Context, userID int64) (*entity.User, error)
+
+	// Статистика и аналитика
+	GetUserStats(ctx context.Context, userID int64) (*entity.UserStats, error)
+
+	// Поиск и списки
+	GetAllUsers(ctx context.Context) ([]*entity.User, error)
+	SearchUsersByName(ctx context.Context, name string) ([]*entity.User, error)
+	// SearchUsers возвращает страницу пользователей по фильтру и общее число найденных
+	SearchUsers(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error)
+}
+
+// AnalyticsService - статистика по всей системе для администраторов
+type AnalyticsService interface {
+	GetSystemStats(ctx context.Context) (*entity.SystemStats, error)
+	// GetBookingTrends возвращает ряд по периодам day, week или month за [from, to);
+	// без to - до текущего момента, без from - за последние 30 дней, 12 недель или 12 месяцев
+	GetBookingTrends(ctx context.Context, period string, from, to time.Time) (*entity.BookingTrends, error)
+}
+
+// BookingService определяет интерфейс для операций с бронированиями
+type BookingService interface {
+	// Основные операции
+	BookSeats(ctx context.Context, req *BookSeatsRequest) (*entity.Booking, error)
+	BookSeatsBatch(ctx context.Context, reqs []*BookSeatsRequest) ([]*entity.Booking, error)
+	ConfirmBooking(ctx context.Context, bookingID int64) (*entity.Booking, error)
+	// ConfirmBookings подтверждает пакет оплат из кассы в одной транзакции с итогом по каждому ID
+	ConfirmBookings(ctx context.Context, bookingIDs []int64) (*entity.BulkConfirmReport, error)
+	CancelBooking(ctx context.Context, bookingID int64, reason string) (*entity.Booking, *entity.CancellationQuote, error)
+	GetBooking(ctx context.Context, id int64) (*entity.Booking, error)
+	GetUserBookings(ctx context.Context, userID int64) ([]*entity.Booking, error)
+	GetEventBookings(ctx context.Context, eventID int64) ([]*entity.Booking, error)
+	GetOrganizerBookings(ctx context.Context, organizerID int64, eventID *int64) ([]*entity.Booking, error)
+
+	// Операции истечения срока
+	CancelExpiredBookings(ctx context.Context) error
+	GetExpiredBookings(ctx context.Context, before time.Time) ([]*entity.BookingExpiration, error)
+	ExpireBooking(ctx context.Context, bookingID int64) error
+	EscalateConfirmation(ctx context.Context, bookingID int64, step string, expiresAt time.Time) error
+
+	// Напоминания о мероприятии: ClaimReminder возвращает false, если напоминание уже отправлено
+	ClaimReminder(ctx context.Context, bookingID int64, reminder string) (bool, error)
+	ReleaseReminder(ctx context.Context, bookingID int64, reminder string) error
+	// RecordNotification отмечает обработанное уведомление; по отметкам сверка находит потерянные
+	RecordNotification(ctx context.Context, bookingID int64, notification string) error
+
+	// Дополнительные операции
+	GetBookingsByStatus(ctx context.Context, status entity.BookingStatus) ([]*entity.Booking, error)
+	UpdateBookingSeats(ctx context.Context, bookingID int64, seats int) error
+	UpdateBookingStatus(ctx context.Context, bookingID int64, status entity.BookingStatus) error
+	GetBookingStats(ctx context.Context) (*BookingStats, error)
+
+	// Административные операции
+	GetAllBookings(ctx context.Context) ([]*entity.Booking, error)
+	DeleteBooking(ctx context.Context, bookingID int64) error
+	GetRecentBookings(ctx context.Context, limit int) ([]*entity.Booking, error)
+	GetBookingHistory(ctx context.Context, bookingID int64) ([]*entity.AuditEntry, error)
+	ExportBookings(ctx context.Context, eventID *int64, format string, w io.Writer) error
+
+	// Возвраты
+	ProcessRefund(ctx context.Context, refundID int64) (*entity.Refund, error)
+	SettleRefundManually(ctx context.Context, refundID int64) (*entity.Refund, error)
+	GetEventRefunds(ctx context.Context, eventID int64) (*entity.RefundReport, error)
+
+	// Утилиты
+	GetBookingWithDetails(ctx context.Context, bookingID int64) (*BookingDetails, error)
+	CheckBookingAvailability(ctx context.Context, eventID int64, seats int) (bool, error)
+
+	// SetDefaultReservationTimeout меняет срок резервирования в минутах для бронирований,
+	// в которых он не указан; вызывается при перезагрузке конфигурации
+	SetDefaultReservationTimeout(minutes int)
+	// SetCapacityAlertThresholds меняет пороги заполненности мероприятия в процентах,
+	// о достижении которых сообщается организатору
+	SetCapacityAlertThresholds(thresholds []int)
+}
+
+// TicketTierService определяет интерфейс для управления категориями билетов
+type TicketTierService interface {
+	CreateTier(ctx context.Context, eventID int64, req *CreateTicketTierRequest) (*entity.TicketTier, error)
+	GetEventTiers(ctx context.Context, eventID int64) ([]*entity.TicketTierWithAvailability, error)
+	UpdateTier(ctx context.Context, tierID int64, req *UpdateTicketTierRequest) (*entity.TicketTier, error)
+	DeleteTier(ctx context.Context, tierID int64) error
+}
+
+// PartnerPoolService определяет интерфейс для управления партнёрскими пулами мест
+type PartnerPoolService interface {
+	CreatePool(ctx context.Context, eventID int64, req *CreatePartnerPoolRequest) (*entity.PartnerPool, error)
+	GetEventPools(ctx context.Context, eventID int64) ([]*entity.PartnerPoolWithAvailability, error)
+	UpdatePool(ctx context.Context, poolID int64, req *UpdatePartnerPoolRequest) (*entity.PartnerPool, error)
+	DeletePool(ctx context.Context, poolID int64) error
+}
+
+// EventHoldService определяет интерфейс для административных холдов мест мероприятия
+type EventHoldService interface {
+	GetEventHolds(ctx context.Context, eventID int64) ([]*entity.EventHold, error)
+	SetEventHolds(ctx context.Context, eventID int64, req *SetEventHoldsRequest) ([]*entity.EventHold, error)
+}
+
+// CartService определяет интерфейс корзины: позиции на несколько мероприятий оформляются одной транзакцией
+type CartService interface {
+	GetCart(ctx context.Context, userID int64) (*entity.Cart, error)
+	AddItem(ctx context.Context, userID int64, req *AddCartItemRequest) (*entity.Cart, error)
+	RemoveItem(ctx context.Context, userID, itemID int64) (*entity.Cart, error)
+	ClearCart(ctx context.Context, userID int64) error
+	Checkout(ctx context.Context, userID int64, req *CheckoutCartRequest) ([]*entity.Booking, error)
+	ExpireAbandonedCarts(ctx context.Context) (int64, error)
+}
+
+// PromoCodeService определяет интерфейс для управления промокодами
+type PromoCodeService interface {
+	CreatePromoCode(ctx context.Context, req *CreatePromoCodeRequest) (*entity.PromoCode, error)
+	GetPromoCode(ctx context.Context, id int64) (*entity.PromoCode, error)
+	ListPromoCodes(ctx context.Context) ([]*entity.PromoCode, error)
+	UpdatePromoCode(ctx context.Context, id int64, req *UpdatePromoCodeRequest) (*entity.PromoCode, error)
+	DeletePromoCode(ctx context.Context, id int64) error
+}
+
+// VenueService определяет интерфейс для управления площадками
+type VenueService interface {
+	CreateVenue(ctx context.Context, req *CreateVenueRequest) (*entity.Venue, error)
+	GetVenue(ctx context.Context, id int64) (*entity.Venue, error)
+	ListVenues(ctx context.Context) ([]*entity.Venue, error)
+	UpdateVenue(ctx context.Context, id int64, req *UpdateVenueRequest) (*entity.Venue, error)
+	DeleteVenue(ctx context.Context, id int64) error
+}
+
+// TicketService определяет интерфейс QR-билетов и отметки прохода на мероприятие
+type TicketService interface {
+	GetTicket(ctx context.Context, bookingID int64) (*Ticket, error)
+	CheckIn(ctx context.Context, req *CheckInRequest) (*CheckInResult, error)
+}
+
+// CalendarService определяет интерфейс календарной подписки на подтверждённые бронирования
+type CalendarService interface {
+	GetSubscription(ctx context.Context, userID int64) (*CalendarSubscription, error)
+	RevokeSubscription(ctx context.Context, userID int64) (*CalendarSubscription, error)
+	WriteFeed(ctx context.Context, userID int64, token string, w io.Writer) error
+}
+
+// WebhookService определяет интерфейс для управления вебхуками и их доставки
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, req *CreateWebhookRequest) (*entity.WebhookWithSecret, error)
+	ListWebhooks(ctx context.Context) ([]*entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]*entity.WebhookDelivery, error)
+
+	Dispatch(ctx context.Context, eventID int64, eventType string, data map[string]interface{}) error
+}
+
+// APITokenService определяет интерфейс токенов внешних интеграций организаторов
+type APITokenService interface {
+	CreateToken(ctx context.Context, req *CreateAPITokenRequest) (*entity.APITokenWithSecret, error)
+	ListTokens(ctx context.Context, organizerID *int64) ([]*entity.APIToken, error)
+	RevokeToken(ctx context.Context, id int64) (*entity.APIToken, error)
+
+	// Authenticate возвращает entity.ErrInvalidAPIToken для неизвестных, отозванных и просроченных токенов
+	Authenticate(ctx context.Context, token string) (*entity.APIToken, error)
+}
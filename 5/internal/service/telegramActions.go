@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
+)
+
+// Действия с бронированием из inline-кнопок Telegram
+const (
+	TelegramActionConfirm = "confirm"
+	TelegramActionCancel  = "cancel"
+
+	telegramBookingPrefix = "booking"
+)
+
+var ErrUnknownTelegramAction = errors.New("неизвестное действие кнопки")
+
+// BookingActionsKeyboard - кнопки подтверждения и отмены под уведомлением о бронировании
+func BookingActionsKeyboard(bookingIDs ...int64) *telegram.InlineKeyboardMarkup {
+	keyboard := &telegram.InlineKeyboardMarkup{}
+	for _, id := range bookingIDs {
+		confirm, cancel := "✅ Подтвердить бронирование", "❌ Отменить"
+		if len(bookingIDs) > 1 {
+			confirm, cancel = fmt.Sprintf("✅ Подтвердить #%d", id), fmt.Sprintf("❌ Отменить #%d", id)
+		}
+
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []telegram.InlineKeyboardButton{
+			{Text: confirm, CallbackData: bookingActionData(TelegramActionConfirm, id)},
+			{Text: cancel, CallbackData: bookingActionData(TelegramActionCancel, id)},
+		})
+	}
+	return keyboard
+}
+
+// ParseBookingAction разбирает callback_data кнопки вида "booking:<действие>:<id>"
+func ParseBookingAction(data string) (string, int64, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != telegramBookingPrefix {
+		return "", 0, ErrUnknownTelegramAction
+	}
+	if parts[1] != TelegramActionConfirm && parts[1] != TelegramActionCancel {
+		return "", 0, ErrUnknownTelegramAction
+	}
+
+	bookingID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || bookingID <= 0 {
+		return "", 0, ErrUnknownTelegramAction
+	}
+	return parts[1], bookingID, nil
+}
+
+// WithoutBookingActions возвращает клавиатуру без кнопок бронирования bookingID или nil, если кнопок не осталось
+func WithoutBookingActions(keyboard *telegram.InlineKeyboardMarkup, bookingID int64) *telegram.InlineKeyboardMarkup {
+	if keyboard == nil {
+		return nil
+	}
+
+	rest := &telegram.InlineKeyboardMarkup{}
+	for _, row := range keyboard.InlineKeyboard {
+		var kept []telegram.InlineKeyboardButton
+		for _, button := range row {
+			if _, id, err := ParseBookingAction(button.CallbackData); err == nil && id == bookingID {
+				continue
+			}
+			kept = append(kept, button)
+		}
+		if len(kept) > 0 {
+			rest.InlineKeyboard = append(rest.InlineKeyboard, kept)
+		}
+	}
+
+	if len(rest.InlineKeyboard) == 0 {
+		return nil
+	}
+	return rest
+}
+
+func bookingActionData(action string, bookingID int64) string {
+	return fmt.Sprintf("%s:%s:%d", telegramBookingPrefix, action, bookingID)
+}
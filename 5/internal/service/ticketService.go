@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/ticket"
+)
+
+// Ticket - билет подтверждённого бронирования: подписанное содержимое QR-кода и его изображение
+type Ticket struct {
+	Booking *entity.Booking
+	Payload string
+	PNG     []byte
+}
+
+// CheckInRequest - отсканированный на входе QR-код; EventID, если указан,
+// не даёт пройти по билету на другое мероприятие
+type CheckInRequest struct {
+	Payload string `json:"payload" binding:"required,max=512"`
+	EventID int64  `json:"event_id,omitempty"`
+}
+
+// CheckInResult - ответ контролёру: чей билет и на сколько мест
+type CheckInResult struct {
+	Booking  *entity.Booking `json:"booking"`
+	UserName string          `json:"user_name"`
+}
+
+type ticketService struct {
+	bookingRepo repository.BookingRepository
+	userRepo    repository.UserRepository
+	signer      *ticket.Signer
+}
+
+// NewTicketService создает сервис билетов; QR-коды подписываются secret
+func NewTicketService(bookingRepo repository.BookingRepository, userRepo repository.UserRepository, secret string) TicketService {
+	return &ticketService{
+		bookingRepo: bookingRepo,
+		userRepo:    userRepo,
+		signer:      ticket.NewSigner(secret),
+	}
+}
+
+func (s *ticketService) GetTicket(ctx context.Context, bookingID int64) (*Ticket, error) {
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != entity.BookingStatusConfirmed {
+		return nil, entity.ErrTicketUnavailable
+	}
+
+	code, err := s.bookingRepo.IssueTicket(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := s.signer.Sign(ticket.Payload{BookingID: bookingID, Code: code})
+	png, err := ticket.PNG(payload, ticket.DefaultSize)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании QR-кода: %w", err)
+	}
+
+	return &Ticket{Booking: booking, Payload: payload, PNG: png}, nil
+}
+
+// CheckIn проверяет подпись QR-кода и отмечает проход. Повторный проход по тому же билету
+// возвращает entity.ErrAlreadyCheckedIn вместе с бронированием, чтобы контролёр видел время первого.
+func (s *ticketService) CheckIn(ctx context.Context, req *CheckInRequest) (*CheckInResult, error) {
+	payload, err := s.signer.Verify(req.Payload)
+	if err != nil {
+		return nil, entity.ErrInvalidTicket
+	}
+
+	if req.EventID != 0 {
+		booking, err := s.bookingRepo.GetByID(ctx, payload.BookingID)
+		if err != nil {
+			if errors.Is(err, entity.ErrBookingNotFound) {
+				return nil, entity.ErrInvalidTicket
+			}
+			return nil, err
+		}
+		if booking.EventID != req.EventID {
+			return nil, entity.ErrTicketWrongEvent
+		}
+	}
+
+	booking, err := s.bookingRepo.CheckIn(ctx, payload.BookingID, payload.Code, time.Now())
+	if booking == nil {
+		return nil, err
+	}
+
+	result := &CheckInResult{Booking: booking}
+	if user, userErr := s.userRepo.GetByID(ctx, booking.UserID); userErr == nil {
+		result.UserName = user.Name
+	}
+	if err != nil {
+		return result, err
+	}
+
+	log.Printf("Проход по билету: Booking=%d, Event=%d, Seats=%d", booking.ID, booking.EventID, booking.Seats)
+
+	return result, nil
+}
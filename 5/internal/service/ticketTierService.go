@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// CreateTicketTierRequest represents the data needed to create a ticket tier
+type CreateTicketTierRequest struct {
+	Name  string  `json:"name" binding:"required,min=1,max=100"`
+	Price float64 `json:"price" binding:"min=0"`
+	Seats int     `json:"seats" binding:"required,min=1,max=10000"`
+}
+
+// UpdateTicketTierRequest represents the data needed to update a ticket tier
+type UpdateTicketTierRequest struct {
+	Name  *string  `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Price *float64 `json:"price,omitempty" binding:"omitempty,min=0"`
+	Seats *int     `json:"seats,omitempty" binding:"omitempty,min=1,max=10000"`
+}
+
+type ticketTierService struct {
+	tierRepo  repository.TicketTierRepository
+	eventRepo repository.EventRepository
+}
+
+// NewTicketTierService creates a new instance of TicketTierService
+func NewTicketTierService(
+	tierRepo repository.TicketTierRepository,
+	eventRepo repository.EventRepository,
+) TicketTierService {
+	return &ticketTierService{
+		tierRepo:  tierRepo,
+		eventRepo: eventRepo,
+	}
+}
+
+func (s *ticketTierService) CreateTier(ctx context.Context, eventID int64, req *CreateTicketTierRequest) (*entity.TicketTier, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+
+	allocated, err := s.tierRepo.SumSeatsByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if allocated+req.Seats > event.TotalSeats {
+		return nil, fmt.Errorf("%w: allocated %d, requested %d, total %d",
+			entity.ErrTierSeatsExceedEvent, allocated, req.Seats, event.TotalSeats)
+	}
+
+	tier := &entity.TicketTier{
+		EventID: eventID,
+		Name:    req.Name,
+		Price:   req.Price,
+		Seats:   req.Seats,
+	}
+
+	if err := s.tierRepo.Create(ctx, tier); err != nil {
+		return nil, err
+	}
+
+	return tier, nil
+}
+
+func (s *ticketTierService) GetEventTiers(ctx context.Context, eventID int64) ([]*entity.TicketTierWithAvailability, error) {
+	tiers, err := s.tierRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event tiers: %w", err)
+	}
+
+	return tiers, nil
+}
+
+func (s *ticketTierService) UpdateTier(ctx context.Context, tierID int64, req *UpdateTicketTierRequest) (*entity.TicketTier, error) {
+	tier, err := s.tierRepo.GetByID(ctx, tierID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		tier.Name = *req.Name
+	}
+	if req.Price != nil {
+		tier.Price = *req.Price
+	}
+	if req.Seats != nil && *req.Seats != tier.Seats {
+		if err := s.validateSeatsChange(ctx, tier, *req.Seats); err != nil {
+			return nil, err
+		}
+		tier.Seats = *req.Seats
+	}
+
+	if err := s.tierRepo.Update(ctx, tier); err != nil {
+		return nil, err
+	}
+
+	return tier, nil
+}
+
+// validateSeatsChange проверяет, что новая квота не меньше уже подтвержденных мест
+// и что сумма квот всех категорий не превышает вместимость мероприятия
+func (s *ticketTierService) validateSeatsChange(ctx context.Context, tier *entity.TicketTier, seats int) error {
+	tiers, err := s.tierRepo.GetByEventID(ctx, tier.EventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event tiers: %w", err)
+	}
+
+	allocated := 0
+	for _, t := range tiers {
+		if t.ID == tier.ID {
+			if seats < t.BookedSeats {
+				return fmt.Errorf("cannot reduce tier seats below booked seats (%d)", t.BookedSeats)
+			}
+			continue
+		}
+		allocated += t.Seats
+	}
+
+	event, err := s.eventRepo.GetByID(ctx, tier.EventID)
+	if err != nil {
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+	if allocated+seats > event.TotalSeats {
+		return fmt.Errorf("%w: allocated %d, requested %d, total %d",
+			entity.ErrTierSeatsExceedEvent, allocated, seats, event.TotalSeats)
+	}
+
+	return nil
+}
+
+func (s *ticketTierService) DeleteTier(ctx context.Context, tierID int64) error {
+	return s.tierRepo.Delete(ctx, tierID)
+}
@@ -1,288 +1,416 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-)
-
-// RegisterUserRequest represents the data needed to register a user
-type RegisterUserRequest struct {
-	Email      string `json:"email" binding:"required,email"`
-	Name       string `json:"name" binding:"required,min=2,max=100"`
-	TelegramID string `json:"telegram_id,omitempty" binding:"max=100"`
-}
-
-// UpdateUserRequest represents the data needed to update a user
-type UpdateUserRequest struct {
-	Name       *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
-	TelegramID *string `json:"telegram_id,omitempty" binding:"omitempty,max=100"`
-}
-
-// UserFilter represents filters for searching users
-type UserFilter struct {
-	Email  string `json:"email,omitempty"`
-	Name   string `json:"name,omitempty"`
-	Limit  int    `json:"limit,omitempty"`
-	Offset int    `json:"offset,omitempty"`
-}
-
-// UserStats represents statistics about a user
-type UserStats struct {
-	User              *entity.User         `json:"user"`
-	TotalBookings     int                  `json:"total_bookings"`
-	ConfirmedBookings int                  `json:"confirmed_bookings"`
-	PendingBookings   int                  `json:"pending_bookings"`
-	CancelledBookings int                  `json:"cancelled_bookings"`
-	FavoriteEvents    []*EventBookingCount `json:"favorite_events"`
-	TotalSeatsBooked  int                  `json:"total_seats_booked"`
-}
-
-type userService struct {
-	userRepo    repository.UserRepository
-	bookingRepo repository.BookingRepository
-}
-
-// NewUserService creates a new instance of UserService
-func NewUserService(
-	userRepo repository.UserRepository,
-	bookingRepo repository.BookingRepository,
-) UserService {
-	return &userService{
-		userRepo:    userRepo,
-		bookingRepo: bookingRepo,
-	}
-}
-
-func (s *userService) RegisterUser(ctx context.Context, req *RegisterUserRequest) (*entity.User, error) {
-	// Check if user already exists
-	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
-	if err != nil && err != entity.ErrUserNotFound {
-		return nil, fmt.Errorf("failed to check existing user: %w", err)
-	}
-	if existingUser != nil {
-		return nil, fmt.Errorf("user with email %s already exists", req.Email)
-	}
-
-	user := &entity.User{
-		Email:      req.Email,
-		Name:       req.Name,
-		TelegramID: req.TelegramID,
-		CreatedAt:  time.Now(),
-	}
-
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
-	}
-
-	return user, nil
-}
-
-func (s *userService) GetUser(ctx context.Context, id int64) (*entity.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
-
-	return user, nil
-}
-
-func (s *userService) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
-	user, err := s.userRepo.GetByEmail(ctx, email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user by email: %w", err)
-	}
-
-	return user, nil
-}
-
-func (s *userService) UpdateUser(ctx context.Context, id int64, req *UpdateUserRequest) (*entity.User, error) {
-	// Get existing user
-	existingUser, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get existing user: %w", err)
-	}
-
-	// Update fields if provided
-	if req.Name != nil {
-		existingUser.Name = *req.Name
-	}
-	if req.TelegramID != nil {
-		existingUser.TelegramID = *req.TelegramID
-	}
-
-	// Update in repository
-	if err := s.userRepo.Update(ctx, existingUser); err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
-	}
-
-	return existingUser, nil
-}
-
-func (s *userService) LinkTelegram(ctx context.Context, userID int64, telegramID string) error {
-	if telegramID == "" {
-		return fmt.Errorf("telegram ID cannot be empty")
-	}
-
-	// Check if telegram ID is already linked to another user
-	existingUser, err := s.userRepo.GetByTelegramID(ctx, telegramID)
-	if err != nil && err != entity.ErrUserNotFound {
-		return fmt.Errorf("failed to check telegram ID: %w", err)
-	}
-	if existingUser != nil && existingUser.ID != userID {
-		return fmt.Errorf("telegram ID is already linked to another user")
-	}
-
-	if err := s.userRepo.UpdateTelegramID(ctx, userID, telegramID); err != nil {
-		return fmt.Errorf("failed to link telegram: %w", err)
-	}
-
-	return nil
-}
-
-func (s *userService) GetUserStats(ctx context.Context, userID int64) (*UserStats, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
-
-	// Get user's bookings
-	bookings, err := s.bookingRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user bookings: %w", err)
-	}
-
-	stats := &UserStats{
-		User: user,
-	}
-
-	// Calculate statistics
-	eventBookings := make(map[int64]int)
-	eventTitles := make(map[int64]string)
-
-	for _, booking := range bookings {
-		// Count by status
-		switch booking.Status {
-		case entity.BookingStatusConfirmed:
-			stats.ConfirmedBookings++
-			stats.TotalSeatsBooked += booking.Seats
-		case entity.BookingStatusPending:
-			stats.PendingBookings++
-		case entity.BookingStatusCancelled, entity.BookingStatusExpired:
-			stats.CancelledBookings++
-		}
-
-		// Count bookings per event for favorite events
-		eventBookings[booking.EventID]++
-
-		// Store event title if not already stored
-		if _, exists := eventTitles[booking.EventID]; !exists {
-			// In a real implementation, you'd get event details from eventRepo
-			eventTitles[booking.EventID] = fmt.Sprintf("Event #%d", booking.EventID)
-		}
-	}
-
-	stats.TotalBookings = len(bookings)
-
-	// Find favorite events (events with most bookings)
-	for eventID, count := range eventBookings {
-		stats.FavoriteEvents = append(stats.FavoriteEvents, &EventBookingCount{
-			EventID:    eventID,
-			EventTitle: eventTitles[eventID],
-			Bookings:   int64(count),
-		})
-	}
-
-	// Sort favorite events by booking count (descending)
-	// Implementation would sort stats.FavoriteEvents
-
-	return stats, nil
-}
-
-func (s *userService) SearchUsers(ctx context.Context, filter *UserFilter) ([]*entity.User, error) {
-	if filter == nil {
-		filter = &UserFilter{}
-	}
-
-	// Set default values
-	if filter.Limit <= 0 || filter.Limit > 100 {
-		filter.Limit = 50
-	}
-
-	// This would typically call a specialized repository method
-	// For now, we'll demonstrate with email search
-	if filter.Email != "" {
-		user, err := s.userRepo.GetByEmail(ctx, filter.Email)
-		if err != nil {
-			if err == entity.ErrUserNotFound {
-				return []*entity.User{}, nil
-			}
-			return nil, fmt.Errorf("failed to search user by email: %w", err)
-		}
-		return []*entity.User{user}, nil
-	}
-
-	// For name search or general search, you would implement a proper search method
-	// For now, return empty result
-	return []*entity.User{}, nil
-}
-
-// Исправляем метод DeleteUser в userService
-func (s *userService) DeleteUser(ctx context.Context, id int64) error {
-	// Проверяем, есть ли у пользователя активные бронирования
-	bookings, err := s.bookingRepo.GetByUserID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to check user bookings: %w", err)
-	}
-
-	// Проверяем наличие активных бронирований (pending или confirmed)
-	for _, booking := range bookings {
-		if booking.Status == entity.BookingStatusPending || booking.Status == entity.BookingStatusConfirmed {
-			return fmt.Errorf("cannot delete user with active bookings")
-		}
-	}
-
-	// Удаляем пользователя
-	if err := s.userRepo.Delete(ctx, id); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
-
-	return nil
-}
-
-// Добавляем метод для получения всех пользователей
-func (s *userService) GetAllUsers(ctx context.Context) ([]*entity.User, error) {
-	users, err := s.userRepo.GetAll(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all users: %w", err)
-	}
-
-	return users, nil
-}
-
-// Добавляем метод для поиска пользователей по имени
-func (s *userService) SearchUsersByName(ctx context.Context, name string) ([]*entity.User, error) {
-	if name == "" {
-		return s.userRepo.GetAll(ctx)
-	}
-
-	users, err := s.userRepo.SearchByName(ctx, name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search users by name: %w", err)
-	}
-
-	return users, nil
-}
-
-// Реализуем метод GetUserByID в userService
-func (s *userService) GetUserByID(ctx context.Context, id int64) (*entity.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
-
-	return user, nil
-}
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RegisterUserRequest represents the data needed to register a user
+type RegisterUserRequest struct {
+	Email      string `json:"email" binding:"required,email"`
+	Name       string `json:"name" binding:"required,min=2,max=100"`
+	Password   string `json:"password" binding:"required,min=8,max=72"`
+	TelegramID string `json:"telegram_id,omitempty" binding:"max=100"`
+}
+
+// UpdateUserRequest represents the data needed to update a user
+type UpdateUserRequest struct {
+	Name       *string `json:"name,omitempty" binding:"omitempty,min=2,max=100"`
+	TelegramID *string `json:"telegram_id,omitempty" binding:"omitempty,max=100"`
+}
+
+// NotificationPreferencesRequest represents the notification channels a user opts into.
+// Email and Telegram switch a channel off for all categories; Categories choose channels
+// per category (booking_updates, reminders, marketing). Omitted fields keep their values.
+type NotificationPreferencesRequest struct {
+	Email      *bool                               `json:"email,omitempty"`
+	Telegram   *bool                               `json:"telegram,omitempty"`
+	Categories map[string]ChannelPreferenceRequest `json:"categories,omitempty"`
+}
+
+// ChannelPreferenceRequest represents the channels of one notification category
+type ChannelPreferenceRequest struct {
+	Email    *bool `json:"email,omitempty"`
+	Telegram *bool `json:"telegram,omitempty"`
+}
+
+type userService struct {
+	userRepo    repository.UserRepository
+	bookingRepo repository.BookingRepository
+	auditRepo   repository.AuditRepository
+}
+
+// NewUserService creates a new instance of UserService
+func NewUserService(
+	userRepo repository.UserRepository,
+	bookingRepo repository.BookingRepository,
+	auditRepo repository.AuditRepository,
+) UserService {
+	return &userService{
+		userRepo:    userRepo,
+		bookingRepo: bookingRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+func (s *userService) RegisterUser(ctx context.Context, req *RegisterUserRequest) (*entity.User, error) {
+	// Check if user already exists
+	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && err != entity.ErrUserNotFound {
+		return nil, fmt.Errorf("failed to check existing user: %w", err)
+	}
+	if existingUser != nil {
+		return nil, fmt.Errorf("user with email %s already exists", req.Email)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &entity.User{
+		Email:        req.Email,
+		Name:         req.Name,
+		TelegramID:   req.TelegramID,
+		Role:         entity.RoleUser,
+		PasswordHash: string(passwordHash),
+		CreatedAt:    time.Now(),
+
+		NotifyEmail:    true,
+		NotifyTelegram: true,
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// Authenticate checks the user's credentials and returns the user on success
+func (s *userService) Authenticate(ctx context.Context, email, password string) (*entity.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	if user == nil || user.PasswordHash == "" {
+		return nil, entity.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, entity.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// StartImpersonation returns the user an admin is about to act for, e.g. to book over the phone.
+// Admins cannot be impersonated, so the token never grants more than the user's own rights.
+func (s *userService) StartImpersonation(ctx context.Context, userID int64) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Role == entity.RoleAdmin {
+		return nil, entity.ErrImpersonationNotAllowed
+	}
+
+	entry := entity.NewAuditEntry(ctx, entity.AuditEntityUser, user.ID, entity.AuditActionImpersonated)
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record impersonation: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserRole changes the role of a user, e.g. to grant admin access
+func (s *userService) SetUserRole(ctx context.Context, userID int64, role string) error {
+	if role != entity.RoleUser && role != entity.RoleAdmin {
+		return entity.ErrInvalidRole
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	return nil
+}
+
+func (s *userService) GetUser(ctx context.Context, id int64) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByTelegramID находит пользователя по привязанному чату Telegram
+func (s *userService) GetUserByTelegramID(ctx context.Context, telegramID string) (*entity.User, error) {
+	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by telegram ID: %w", err)
+	}
+	if user == nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id int64, req *UpdateUserRequest) (*entity.User, error) {
+	// Get existing user
+	existingUser, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing user: %w", err)
+	}
+
+	// Update fields if provided
+	if req.Name != nil {
+		existingUser.Name = *req.Name
+	}
+	if req.TelegramID != nil {
+		existingUser.TelegramID = *req.TelegramID
+	}
+
+	// Update in repository
+	if err := s.userRepo.Update(ctx, existingUser); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return existingUser, nil
+}
+
+// UpdateNotificationPreferences включает и выключает каналы уведомлений пользователя
+func (s *userService) UpdateNotificationPreferences(ctx context.Context, userID int64, req *NotificationPreferencesRequest) (*entity.User, error) {
+	for category := range req.Categories {
+		if !entity.IsNotificationCategory(category) {
+			return nil, fmt.Errorf("%w: unknown notification category %q", entity.ErrInvalidInput, category)
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, entity.ErrUserNotFound
+	}
+
+	if req.Email != nil {
+		user.NotifyEmail = *req.Email
+	}
+	if req.Telegram != nil {
+		user.NotifyTelegram = *req.Telegram
+	}
+
+	// Сохраняются только изменённые категории, остальные остаются на значениях по умолчанию
+	if len(req.Categories) > 0 {
+		categories := make(entity.NotificationPreferences, len(user.NotificationPreferences)+len(req.Categories))
+		for category, channels := range user.NotificationPreferences {
+			categories[category] = channels
+		}
+		for category, change := range req.Categories {
+			channels := categories.Channels(category)
+			if change.Email != nil {
+				channels.Email = *change.Email
+			}
+			if change.Telegram != nil {
+				channels.Telegram = *change.Telegram
+			}
+			categories[category] = channels
+		}
+		user.NotificationPreferences = categories
+	}
+
+	if err := s.userRepo.UpdateNotificationPreferences(ctx, userID, user.NotifyEmail, user.NotifyTelegram, user.NotificationPreferences); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *userService) LinkTelegram(ctx context.Context, userID int64, telegramID string) error {
+	if telegramID == "" {
+		return fmt.Errorf("telegram ID cannot be empty")
+	}
+
+	// Check if telegram ID is already linked to another user
+	existingUser, err := s.userRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil && err != entity.ErrUserNotFound {
+		return fmt.Errorf("failed to check telegram ID: %w", err)
+	}
+	if existingUser != nil && existingUser.ID != userID {
+		return fmt.Errorf("telegram ID is already linked to another user")
+	}
+
+	if err := s.userRepo.UpdateTelegramID(ctx, userID, telegramID); err != nil {
+		return fmt.Errorf("failed to link telegram: %w", err)
+	}
+
+	return nil
+}
+
+// favoriteEventsLimit - сколько любимых мероприятий показывать в статистике пользователя
+const favoriteEventsLimit = 5
+
+// GetUserStats собирает статистику пользователя: бронирования по статусам, любимые мероприятия,
+// посещаемость по проходам по билетам и оценку лояльности
+func (s *userService) GetUserStats(ctx context.Context, userID int64) (*entity.UserStats, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	bookings, err := s.bookingRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user bookings: %w", err)
+	}
+
+	events, err := s.bookingRepo.GetUserEventBookings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user event bookings: %w", err)
+	}
+
+	stats := &entity.UserStats{
+		User:           user,
+		TotalBookings:  len(bookings),
+		FavoriteEvents: make([]*entity.EventBookingCount, 0, favoriteEventsLimit),
+		JoinDate:       user.CreatedAt,
+	}
+
+	for _, booking := range bookings {
+		switch booking.Status {
+		case entity.BookingStatusConfirmed:
+			stats.ConfirmedBookings++
+			stats.TotalSeatsBooked += booking.Seats
+		case entity.BookingStatusPending:
+			stats.PendingBookings++
+		case entity.BookingStatusCancelled:
+			stats.CancelledBookings++
+		case entity.BookingStatusExpired:
+			stats.ExpiredBookings++
+		}
+
+		if stats.LastActivity == nil || booking.UpdatedAt.After(*stats.LastActivity) {
+			lastActivity := booking.UpdatedAt
+			stats.LastActivity = &lastActivity
+		}
+	}
+
+	// Мероприятия уже отсортированы по числу бронирований
+	for _, event := range events {
+		stats.AttendedBookings += event.AttendedBookings
+		stats.NoShowBookings += event.NoShowBookings
+		if len(stats.FavoriteEvents) < favoriteEventsLimit {
+			favorite := event.EventBookingCount
+			stats.FavoriteEvents = append(stats.FavoriteEvents, &favorite)
+		}
+	}
+
+	stats.AttendanceRate = stats.CalculateAttendanceRate()
+	stats.LoyaltyScore = stats.CalculateLoyaltyScore()
+
+	return stats, nil
+}
+
+// SearchUsers ищет пользователей по подстроке имени и email и возвращает страницу с общим числом найденных
+func (s *userService) SearchUsers(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error) {
+	if filter == nil {
+		filter = &entity.UserFilter{}
+	}
+
+	// Set default values
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	users, total, err := s.userRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, total, nil
+}
+
+// Исправляем метод DeleteUser в userService
+func (s *userService) DeleteUser(ctx context.Context, id int64) error {
+	// Проверяем, есть ли у пользователя активные бронирования
+	bookings, err := s.bookingRepo.GetByUserID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check user bookings: %w", err)
+	}
+
+	// Проверяем наличие активных бронирований (pending или confirmed)
+	for _, booking := range bookings {
+		if booking.Status == entity.BookingStatusPending || booking.Status == entity.BookingStatusConfirmed {
+			return fmt.Errorf("cannot delete user with active bookings")
+		}
+	}
+
+	// Удаляем пользователя
+	if err := s.userRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// Добавляем метод для получения всех пользователей
+func (s *userService) GetAllUsers(ctx context.Context) ([]*entity.User, error) {
+	users, err := s.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Добавляем метод для поиска пользователей по имени
+func (s *userService) SearchUsersByName(ctx context.Context, name string) ([]*entity.User, error) {
+	if name == "" {
+		return s.userRepo.GetAll(ctx)
+	}
+
+	users, err := s.userRepo.SearchByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users by name: %w", err)
+	}
+
+	return users, nil
+}
+
+// Реализуем метод GetUserByID в userService
+func (s *userService) GetUserByID(ctx context.Context, id int64) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
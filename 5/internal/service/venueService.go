@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+// CreateVenueRequest represents the data needed to create a venue
+type CreateVenueRequest struct {
+	Name      string   `json:"name" binding:"required,min=1,max=255"`
+	Address   string   `json:"address" binding:"max=500"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Capacity  int      `json:"capacity" binding:"required,min=1"`
+}
+
+// UpdateVenueRequest represents the data needed to update a venue
+type UpdateVenueRequest struct {
+	Name      *string  `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Address   *string  `json:"address,omitempty" binding:"omitempty,max=500"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Capacity  *int     `json:"capacity,omitempty" binding:"omitempty,min=1"`
+}
+
+type venueService struct {
+	venueRepo repository.VenueRepository
+}
+
+// NewVenueService creates a new instance of VenueService
+func NewVenueService(venueRepo repository.VenueRepository) VenueService {
+	return &venueService{venueRepo: venueRepo}
+}
+
+func (s *venueService) CreateVenue(ctx context.Context, req *CreateVenueRequest) (*entity.Venue, error) {
+	venue := &entity.Venue{
+		Name:      strings.TrimSpace(req.Name),
+		Address:   strings.TrimSpace(req.Address),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Capacity:  req.Capacity,
+	}
+
+	if err := venue.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.venueRepo.Create(ctx, venue); err != nil {
+		return nil, err
+	}
+
+	return venue, nil
+}
+
+func (s *venueService) GetVenue(ctx context.Context, id int64) (*entity.Venue, error) {
+	return s.venueRepo.GetByID(ctx, id)
+}
+
+func (s *venueService) ListVenues(ctx context.Context) ([]*entity.Venue, error) {
+	venues, err := s.venueRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list venues: %w", err)
+	}
+
+	return venues, nil
+}
+
+func (s *venueService) UpdateVenue(ctx context.Context, id int64, req *UpdateVenueRequest) (*entity.Venue, error) {
+	venue, err := s.venueRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		venue.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Address != nil {
+		venue.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.Latitude != nil {
+		venue.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		venue.Longitude = req.Longitude
+	}
+	if req.Capacity != nil {
+		// Вместимость нельзя опустить ниже мероприятий, которые уже запланированы на площадке
+		maxSeats, err := s.venueRepo.MaxEventSeats(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if *req.Capacity < maxSeats {
+			return nil, fmt.Errorf("%w: venue hosts an event with %d seats", entity.ErrVenueCapacityExceeded, maxSeats)
+		}
+		venue.Capacity = *req.Capacity
+	}
+
+	if err := venue.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.venueRepo.Update(ctx, venue); err != nil {
+		return nil, err
+	}
+
+	return venue, nil
+}
+
+func (s *venueService) DeleteVenue(ctx context.Context, id int64) error {
+	return s.venueRepo.Delete(ctx, id)
+}
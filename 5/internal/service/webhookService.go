@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/pkg/webhook"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultDeliveriesLimit = 50
+
+// CreateWebhookRequest represents the data needed to register a webhook
+type CreateWebhookRequest struct {
+	EventID    *int64   `json:"event_id,omitempty"`
+	URL        string   `json:"url" binding:"required,max=2048"`
+	EventTypes []string `json:"event_types,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	eventRepo   repository.EventRepository
+	sender      *webhook.Sender
+}
+
+// NewWebhookService creates a new instance of WebhookService
+func NewWebhookService(
+	webhookRepo repository.WebhookRepository,
+	eventRepo repository.EventRepository,
+	sender *webhook.Sender,
+) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		eventRepo:   eventRepo,
+		sender:      sender,
+	}
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, req *CreateWebhookRequest) (*entity.WebhookWithSecret, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !knownWebhookEvent(eventType) {
+			return nil, fmt.Errorf("%w: %s", entity.ErrUnknownEventType, eventType)
+		}
+	}
+
+	if req.EventID != nil {
+		if _, err := s.eventRepo.GetByID(ctx, *req.EventID); err != nil {
+			return nil, fmt.Errorf("failed to get event: %w", err)
+		}
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	hook := &entity.Webhook{
+		EventID:    req.EventID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+	}
+	if hook.EventTypes == nil {
+		hook.EventTypes = []string{}
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	if err := s.webhookRepo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	return &entity.WebhookWithSecret{Webhook: *hook, Secret: secret}, nil
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]*entity.Webhook, error) {
+	webhooks, err := s.webhookRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id int64) error {
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, webhookID int64, limit int) ([]*entity.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 500 {
+		limit = defaultDeliveriesLimit
+	}
+
+	return s.webhookRepo.GetDeliveries(ctx, webhookID, limit)
+}
+
+// Dispatch отправляет событие всем подписанным вебхукам мероприятия.
+// Каждая доставка записывается в журнал; ошибка возвращается, только если
+// не удалось получить список вебхуков, чтобы сбой подписчика не влиял на другие каналы.
+func (s *webhookService) Dispatch(ctx context.Context, eventID int64, eventType string, data map[string]interface{}) error {
+	webhooks, err := s.webhookRepo.GetActiveForEvent(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks for event %d: %w", eventID, err)
+	}
+
+	for _, hook := range webhooks {
+		if !hook.Subscribed(eventType) {
+			continue
+		}
+		s.deliver(ctx, hook, eventID, eventType, data)
+	}
+
+	return nil
+}
+
+func (s *webhookService) deliver(ctx context.Context, hook *entity.Webhook, eventID int64, eventType string, data map[string]interface{}) {
+	deliveryID, err := randomHex(16)
+	if err != nil {
+		logrus.Errorf("Failed to generate webhook delivery id: %v", err)
+		return
+	}
+
+	body, err := json.Marshal(&entity.WebhookPayload{
+		ID:        deliveryID,
+		Type:      eventType,
+		EventID:   eventID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		logrus.Errorf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	result, sendErr := s.sender.Send(ctx, &webhook.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		Event:      eventType,
+		DeliveryID: deliveryID,
+		Body:       body,
+	})
+
+	delivery := &entity.WebhookDelivery{
+		WebhookID:      hook.ID,
+		DeliveryID:     deliveryID,
+		EventType:      eventType,
+		Payload:        string(body),
+		Status:         entity.WebhookDeliveryDelivered,
+		Attempts:       result.Attempts,
+		ResponseStatus: result.StatusCode,
+		DurationMs:     result.Duration.Milliseconds(),
+	}
+	if sendErr != nil {
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.Error = sendErr.Error()
+		logrus.Warnf("Webhook %d delivery %s of %s failed after %d attempts: %v",
+			hook.ID, deliveryID, eventType, result.Attempts, sendErr)
+	}
+
+	if err := s.webhookRepo.LogDelivery(ctx, delivery); err != nil {
+		logrus.Errorf("Failed to log webhook delivery %s: %v", deliveryID, err)
+	}
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return entity.ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+func knownWebhookEvent(eventType string) bool {
+	for _, t := range entity.WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,256 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// adminFeedRoute - WebSocket ленты админки, общий таймаут запросов его не ограничивает
+const adminFeedRoute = "/ws/admin"
+
+const (
+	adminFeedWriteWait  = 10 * time.Second
+	adminFeedPingPeriod = 30 * time.Second
+	adminFeedPongWait   = adminFeedPingPeriod + 10*time.Second
+	adminFeedMaxMessage = 1024
+	// adminFeedLifetime - после него соединение закрывается, и клиент переподключается с новым
+	// токеном: роль проверяется только при подключении
+	adminFeedLifetime = time.Hour
+)
+
+// Сообщения сервера: событие ленты, текущие темы подписки, число отброшенных событий и ошибка
+const (
+	adminFeedMessageEvent      = "event"
+	adminFeedMessageSubscribed = "subscribed"
+	adminFeedMessageLagged     = "lagged"
+	adminFeedMessageError      = "error"
+)
+
+// Команды клиента
+const (
+	adminFeedActionSubscribe   = "subscribe"   // добавить темы
+	adminFeedActionUnsubscribe = "unsubscribe" // убрать темы
+	adminFeedActionSet         = "set"         // заменить темы
+)
+
+type adminFeedMessage struct {
+	Type    string                 `json:"type"`
+	Event   *entity.AdminFeedEvent `json:"event,omitempty"`
+	Topics  []string               `json:"topics,omitempty"`
+	Dropped int                    `json:"dropped,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+type adminFeedCommand struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// AdminFeedHandler отдаёт администраторам ленту бронирований, подтверждений, истечений и задач DLQ
+type AdminFeedHandler struct {
+	feed     *service.AdminFeed
+	upgrader websocket.Upgrader
+}
+
+// NewAdminFeedHandler принимает nil, если лента выключена или Redis не настроен.
+// Без allowedOrigins подключение разрешено только со страниц того же хоста.
+func NewAdminFeedHandler(feed *service.AdminFeed, allowedOrigins []string) *AdminFeedHandler {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{middleware.WebSocketTokenProtocol},
+	}
+	if len(allowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return originAllowed(r, allowedOrigins)
+		}
+	}
+	return &AdminFeedHandler{feed: feed, upgrader: upgrader}
+}
+
+// Feed подписывает соединение на темы из параметра topics (через запятую, по умолчанию все).
+// Темы меняются сообщениями {"action": "subscribe" | "unsubscribe" | "set", "topics": [...]};
+// в ответ сервер присылает сообщение subscribed с текущими темами.
+func (h *AdminFeedHandler) Feed(c *gin.Context) {
+	if h.feed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "admin feed is disabled"})
+		return
+	}
+
+	topics, err := entity.ParseAdminFeedTopics(c.Query("topics"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту ошибкой
+		return
+	}
+
+	sub := h.feed.Subscribe(topics)
+	defer sub.Close()
+
+	replies := make(chan adminFeedMessage, 4)
+	replies <- adminFeedMessage{Type: adminFeedMessageSubscribed, Topics: sub.Topics()}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.readCommands(conn, sub, replies)
+	}()
+
+	h.writeMessages(conn, sub, replies, done)
+	conn.Close()
+	<-done
+}
+
+// readCommands читает команды клиента до закрытия соединения
+func (h *AdminFeedHandler) readCommands(conn *websocket.Conn, sub *service.AdminFeedSubscription, replies chan<- adminFeedMessage) {
+	conn.SetReadLimit(adminFeedMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(adminFeedPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(adminFeedPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logrus.Debugf("Admin feed connection closed: %v", err)
+			}
+			return
+		}
+
+		reply := applyAdminFeedCommand(sub, data)
+		select {
+		case replies <- reply:
+		default:
+			// Клиент шлёт команды быстрее, чем читает ответы; ответ на последнюю всё равно придёт
+		}
+	}
+}
+
+func applyAdminFeedCommand(sub *service.AdminFeedSubscription, data []byte) adminFeedMessage {
+	var cmd adminFeedCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return adminFeedMessage{Type: adminFeedMessageError, Error: "invalid command"}
+	}
+	for _, topic := range cmd.Topics {
+		if !entity.IsAdminFeedTopic(topic) {
+			return adminFeedMessage{Type: adminFeedMessageError, Error: "unknown topic " + topic}
+		}
+	}
+
+	current := make(map[string]bool)
+	for _, topic := range sub.Topics() {
+		current[topic] = true
+	}
+
+	switch cmd.Action {
+	case adminFeedActionSubscribe:
+		for _, topic := range cmd.Topics {
+			current[topic] = true
+		}
+	case adminFeedActionUnsubscribe:
+		for _, topic := range cmd.Topics {
+			delete(current, topic)
+		}
+	case adminFeedActionSet:
+		current = make(map[string]bool, len(cmd.Topics))
+		for _, topic := range cmd.Topics {
+			current[topic] = true
+		}
+	default:
+		return adminFeedMessage{Type: adminFeedMessageError, Error: "unknown action " + cmd.Action}
+	}
+
+	topics := make([]string, 0, len(current))
+	for topic := range current {
+		topics = append(topics, topic)
+	}
+	sub.SetTopics(topics)
+	return adminFeedMessage{Type: adminFeedMessageSubscribed, Topics: sub.Topics()}
+}
+
+// writeMessages - единственный писатель соединения: события ленты, ответы на команды и ping.
+// Возвращается при ошибке записи, закрытии соединения клиентом, остановке ленты или по сроку.
+func (h *AdminFeedHandler) writeMessages(conn *websocket.Conn, sub *service.AdminFeedSubscription, replies <-chan adminFeedMessage, done <-chan struct{}) {
+	ping := time.NewTicker(adminFeedPingPeriod)
+	defer ping.Stop()
+	lifetime := time.NewTimer(adminFeedLifetime)
+	defer lifetime.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				closeFeed(conn, websocket.CloseGoingAway, "server is shutting down")
+				return
+			}
+			if dropped := sub.Dropped(); dropped > 0 {
+				if writeFeedMessage(conn, adminFeedMessage{Type: adminFeedMessageLagged, Dropped: dropped}) != nil {
+					return
+				}
+			}
+			if writeFeedMessage(conn, adminFeedMessage{Type: adminFeedMessageEvent, Event: event}) != nil {
+				return
+			}
+		case reply := <-replies:
+			if writeFeedMessage(conn, reply) != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(adminFeedWriteWait))
+			if conn.WriteMessage(websocket.PingMessage, nil) != nil {
+				return
+			}
+		case <-lifetime.C:
+			closeFeed(conn, websocket.CloseNormalClosure, "reconnect")
+			return
+		}
+	}
+}
+
+func writeFeedMessage(conn *websocket.Conn, msg adminFeedMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(adminFeedWriteWait))
+	return conn.WriteJSON(msg)
+}
+
+func closeFeed(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(adminFeedWriteWait))
+}
+
+// originAllowed сверяет Origin запроса со списком разрешённых адресов вида https://admin.example.com
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // не браузер
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
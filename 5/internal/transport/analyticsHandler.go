@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookingTrendsQuery - параметры трендов; даты включительно, в формате YYYY-MM-DD
+type BookingTrendsQuery struct {
+	Period string    `form:"period"`
+	From   time.Time `form:"from" time_format:"2006-01-02"`
+	To     time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+type AnalyticsHandler struct {
+	analyticsService service.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetSystemStats отдает общую статистику системы
+func (h *AnalyticsHandler) GetSystemStats(c *gin.Context) {
+	stats, err := h.analyticsService.GetSystemStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetBookingTrends отдает ряды бронирований для графиков: ?period=day|week|month&from=&to=
+func (h *AnalyticsHandler) GetBookingTrends(c *gin.Context) {
+	query := BookingTrendsQuery{Period: entity.TrendPeriodDay}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates in YYYY-MM-DD format"})
+		return
+	}
+	// to включительно: ряд идёт до конца указанного дня
+	if !query.To.IsZero() {
+		query.To = query.To.AddDate(0, 0, 1)
+	}
+
+	trends, err := h.analyticsService.GetBookingTrends(c.Request.Context(), query.Period, query.From, query.To)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidTrendPeriod) || errors.Is(err, entity.ErrInvalidTrendRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trends)
+}
@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	tokenService service.APITokenService
+}
+
+func NewAPITokenHandler(tokenService service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{tokenService: tokenService}
+}
+
+// ListTokens возвращает все токены, ?organizer_id= оставляет токены одного организатора
+func (h *APITokenHandler) ListTokens(c *gin.Context) {
+	var organizerID *int64
+	if param := c.Query("organizer_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer id"})
+			return
+		}
+		organizerID = &id
+	}
+
+	tokens, err := h.tokenService.ListTokens(c.Request.Context(), organizerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken возвращает открытое значение токена; повторно получить его нельзя
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	var req service.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(apiTokenErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api token id"})
+		return
+	}
+
+	token, err := h.tokenService.RevokeToken(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(apiTokenErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// apiTokenErrorStatus сопоставляет ошибки токенов с HTTP-статусами
+func apiTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrAPITokenNotFound), errors.Is(err, entity.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrUnknownScope), errors.Is(err, entity.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	userService service.UserService
+	jwtManager  *middleware.JWTManager
+}
+
+func NewAuthHandler(userService service.UserService, jwtManager *middleware.JWTManager) *AuthHandler {
+	return &AuthHandler{
+		userService: userService,
+		jwtManager:  jwtManager,
+	}
+}
+
+// LoginRequest представляет учетные данные для входа
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.Authenticate(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, expiresAt, err := h.jwtManager.GenerateToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_at":   expiresAt,
+		"user":         user,
+	})
+}
+
+// ImpersonateRequest - основание, по которому администратор действует от имени пользователя
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,min=1,max=500"`
+}
+
+// Impersonate выпускает администратору токен, по которому он бронирует и отменяет
+// от имени пользователя, например по звонку в поддержку
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	ctx := entity.WithAuditReason(c.Request.Context(), req.Reason)
+	user, err := h.userService.StartImpersonation(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrImpersonationNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	token, expiresAt, err := h.jwtManager.GenerateImpersonationToken(user, adminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":    token,
+		"token_type":      "Bearer",
+		"expires_at":      expiresAt,
+		"user":            user,
+		"impersonator_id": adminID,
+	})
+}
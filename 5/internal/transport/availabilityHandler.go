@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// availabilityHeartbeat - комментарий в пустом потоке, чтобы прокси не закрыли соединение по простою
+	availabilityHeartbeat = 15 * time.Second
+	// availabilityStreamLifetime - после него поток закрывается, EventSource переподключается сам
+	// и попадает на другую реплику, если их стало больше
+	availabilityStreamLifetime = 30 * time.Minute
+	// availabilityRetry - через сколько миллисекунд EventSource переподключится после обрыва
+	availabilityRetry = 3000
+)
+
+// AvailabilityHandler отдаёт свободные места мероприятия потоком Server-Sent Events
+type AvailabilityHandler struct {
+	stream *service.AvailabilityStream
+}
+
+// NewAvailabilityHandler принимает nil, если поток выключен или Redis не настроен
+func NewAvailabilityHandler(stream *service.AvailabilityStream) *AvailabilityHandler {
+	return &AvailabilityHandler{stream: stream}
+}
+
+// StreamAvailability сразу отправляет текущие места событием availability, затем - каждое
+// их изменение. Удаление мероприятия приходит событием deleted, после которого поток закрывается.
+func (h *AvailabilityHandler) StreamAvailability(c *gin.Context) {
+	if h.stream == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "availability stream is disabled"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	current, updates, unsubscribe, err := h.stream.Subscribe(ctx, id)
+	if err != nil {
+		if errors.Is(err, entity.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get event availability"})
+		return
+	}
+	defer unsubscribe()
+
+	// WriteTimeout сервера рассчитан на обычные ответы и оборвал бы поток
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming is not supported"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx иначе копит события в буфере
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", availabilityRetry)
+	c.SSEvent("availability", current)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(availabilityHeartbeat)
+	defer heartbeat.Stop()
+	lifetime := time.NewTimer(availabilityStreamLifetime)
+	defer lifetime.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lifetime.C:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		case update, ok := <-updates:
+			// Канал закрывается при остановке сервиса
+			if !ok {
+				return
+			}
+			if update.Deleted {
+				c.SSEvent("deleted", update)
+				c.Writer.Flush()
+				return
+			}
+			c.SSEvent("availability", update)
+		}
+		c.Writer.Flush()
+	}
+}
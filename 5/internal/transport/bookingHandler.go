@@ -1,384 +1,654 @@
-package transport
-
-import (
-	"fmt"
-	"net/http"
-	"strconv"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-	"github.com/gin-gonic/gin"
-)
-
-type BookingHandler struct {
-	bookingService service.BookingService
-}
-
-func NewBookingHandler(bookingService service.BookingService) *BookingHandler {
-	return &BookingHandler{bookingService: bookingService}
-}
-
-// SuccessResponse представляет успешный ответ
-type SuccessResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-	Meta    interface{} `json:"meta,omitempty"`
-}
-
-// ErrorResponse представляет ответ с ошибкой
-type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
-}
-
-// CancelBookingRequest представляет запрос на отмену бронирования
-type CancelBookingRequest struct {
-	Reason string `json:"reason" binding:"required,min=1,max=500"`
-}
-
-func (h *BookingHandler) BookSeats(c *gin.Context) {
-	eventIDStr := c.Param("id")
-	eventID, err := strconv.ParseInt(eventIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
-		return
-	}
-
-	var req service.BookSeatsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	req.EventID = eventID
-
-	booking, err := h.bookingService.BookSeats(c.Request.Context(), &req)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, booking)
-}
-
-func (h *BookingHandler) ConfirmBooking(c *gin.Context) {
-	eventIDStr := c.Param("id")
-	_, err := strconv.ParseInt(eventIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
-		return
-	}
-
-	var req struct {
-		BookingID int64 `json:"booking_id" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := h.bookingService.ConfirmBooking(c.Request.Context(), req.BookingID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "booking confirmed"})
-}
-
-func (h *BookingHandler) GetUserBookings(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
-		return
-	}
-
-	bookings, err := h.bookingService.GetUserBookings(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, bookings)
-}
-
-// GetAllBookings возвращает все бронирования
-func (h *BookingHandler) GetAllBookings(c *gin.Context) {
-	// Получаем параметры пагинации
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	if err != nil || limit <= 0 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
-
-	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	if err != nil || offset < 0 {
-		offset = 0
-	}
-
-	// Получаем фильтр по статусу
-	status := c.Query("status")
-
-	ctx := c.Request.Context()
-
-	// Если указан статус, получаем бронирования по статусу
-	if status != "" {
-		bookingStatus, err := h.parseBookingStatus(status)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Success: false,
-				Error:   "Invalid booking status",
-			})
-			return
-		}
-
-		bookings, err := h.bookingService.GetBookingsByStatus(ctx, bookingStatus)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Success: false,
-				Error:   "Failed to get bookings by status: " + err.Error(),
-			})
-			return
-		}
-
-		// Применяем пагинацию
-		start := offset
-		if start > len(bookings) {
-			start = len(bookings)
-		}
-		end := start + limit
-		if end > len(bookings) {
-			end = len(bookings)
-		}
-
-		paginatedBookings := bookings[start:end]
-
-		c.JSON(http.StatusOK, SuccessResponse{
-			Success: true,
-			Message: "Bookings retrieved successfully",
-			Data:    paginatedBookings,
-			Meta: map[string]interface{}{
-				"total":    len(bookings),
-				"limit":    limit,
-				"offset":   offset,
-				"has_more": end < len(bookings),
-			},
-		})
-		return
-	}
-
-	// Если статус не указан, получаем все бронирования
-	bookings, err := h.bookingService.GetAllBookings(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Success: false,
-			Error:   "Failed to get all bookings: " + err.Error(),
-		})
-		return
-	}
-
-	// Применяем пагинацию
-	start := offset
-	if start > len(bookings) {
-		start = len(bookings)
-	}
-	end := start + limit
-	if end > len(bookings) {
-		end = len(bookings)
-	}
-
-	paginatedBookings := bookings[start:end]
-
-	c.JSON(http.StatusOK, SuccessResponse{
-		Success: true,
-		Message: "Bookings retrieved successfully",
-		Data:    paginatedBookings,
-		Meta: map[string]interface{}{
-			"total":    len(bookings),
-			"limit":    limit,
-			"offset":   offset,
-			"has_more": end < len(bookings),
-		},
-	})
-}
-
-// GetEventBookings возвращает все бронирования для конкретного мероприятия
-func (h *BookingHandler) GetEventBookings(c *gin.Context) {
-	// Получаем ID мероприятия из пути
-	eventID, err := strconv.ParseInt(c.Param("event_id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Success: false,
-			Error:   "Invalid event ID",
-		})
-		return
-	}
-
-	// Получаем параметры пагинации
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	if err != nil || limit <= 0 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
-
-	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	if err != nil || offset < 0 {
-		offset = 0
-	}
-
-	// Получаем фильтр по статусу
-	status := c.Query("status")
-
-	ctx := c.Request.Context()
-
-	// Получаем все бронирования мероприятия
-	bookings, err := h.bookingService.GetEventBookings(ctx, eventID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Success: false,
-			Error:   "Failed to get event bookings: " + err.Error(),
-		})
-		return
-	}
-
-	// Фильтруем по статусу если указан
-	if status != "" {
-		bookingStatus, err := h.parseBookingStatus(status)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Success: false,
-				Error:   "Invalid booking status",
-			})
-			return
-		}
-
-		filteredBookings := make([]*entity.Booking, 0)
-		for _, booking := range bookings {
-			if booking.Status == bookingStatus {
-				filteredBookings = append(filteredBookings, booking)
-			}
-		}
-		bookings = filteredBookings
-	}
-
-	// Применяем пагинацию
-	start := offset
-	if start > len(bookings) {
-		start = len(bookings)
-	}
-	end := start + limit
-	if end > len(bookings) {
-		end = len(bookings)
-	}
-
-	paginatedBookings := bookings[start:end]
-
-	c.JSON(http.StatusOK, SuccessResponse{
-		Success: true,
-		Message: "Event bookings retrieved successfully",
-		Data:    paginatedBookings,
-		Meta: map[string]interface{}{
-			"event_id": eventID,
-			"total":    len(bookings),
-			"limit":    limit,
-			"offset":   offset,
-			"has_more": end < len(bookings),
-		},
-	})
-}
-
-// CancelBooking отменяет бронирование
-func (h *BookingHandler) CancelBooking(c *gin.Context) {
-	// Получаем ID бронирования из пути
-	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Success: false,
-			Error:   "Invalid booking ID",
-		})
-		return
-	}
-
-	// Парсим тело запроса
-	var req CancelBookingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Success: false,
-			Error:   "Invalid request body: " + err.Error(),
-		})
-		return
-	}
-
-	// Валидация причины отмены
-	if req.Reason == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Success: false,
-			Error:   "Cancellation reason is required",
-		})
-		return
-	}
-
-	if len(req.Reason) > 500 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Success: false,
-			Error:   "Cancellation reason too long (max 500 characters)",
-		})
-		return
-	}
-
-	ctx := c.Request.Context()
-
-	// Выполняем отмену бронирования
-	err = h.bookingService.CancelBooking(ctx, bookingID, req.Reason)
-	if err != nil {
-		// Проверяем тип ошибки для возврата соответствующего статуса
-		switch {
-		case err.Error() == "booking not found":
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Success: false,
-				Error:   "Booking not found",
-			})
-		case err.Error() == "booking already cancelled":
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Success: false,
-				Error:   "Booking is already cancelled",
-			})
-		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Success: false,
-				Error:   "Failed to cancel booking: " + err.Error(),
-			})
-		}
-		return
-	}
-
-	c.JSON(http.StatusOK, SuccessResponse{
-		Success: true,
-		Message: "Booking cancelled successfully",
-		Meta: map[string]interface{}{
-			"booking_id": bookingID,
-			"reason":     req.Reason,
-		},
-	})
-}
-
-// parseBookingStatus парсит строку в статус бронирования
-func (h *BookingHandler) parseBookingStatus(status string) (entity.BookingStatus, error) {
-	switch status {
-	case "pending":
-		return entity.BookingStatusPending, nil
-	case "confirmed":
-		return entity.BookingStatusConfirmed, nil
-	case "cancelled":
-		return entity.BookingStatusCancelled, nil
-	case "expired":
-		return entity.BookingStatusExpired, nil
-	default:
-		return "", fmt.Errorf("invalid booking status: %s", status)
-	}
-}
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/ds124wfegd/WB_L3/5/pkg/export"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type BookingHandler struct {
+	bookingService service.BookingService
+}
+
+func NewBookingHandler(bookingService service.BookingService) *BookingHandler {
+	return &BookingHandler{bookingService: bookingService}
+}
+
+// SuccessResponse представляет успешный ответ
+type SuccessResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"`
+}
+
+// ErrorResponse представляет ответ с ошибкой
+type ErrorResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+}
+
+// ConfirmBookingRequest представляет запрос на подтверждение бронирования
+type ConfirmBookingRequest struct {
+	BookingID int64 `json:"booking_id" binding:"required"`
+	// Версия бронирования, которую видел клиент; при расхождении ответ 409
+	Version *int `json:"version,omitempty"`
+}
+
+// CancelBookingRequest представляет запрос на отмену бронирования
+type CancelBookingRequest struct {
+	Reason string `json:"reason" binding:"required,min=1,max=500"`
+	// Версия бронирования, которую видел клиент; при расхождении ответ 409
+	Version *int `json:"version,omitempty"`
+}
+
+// withExpectedVersion передаёт в ctx версию из запроса, если клиент её указал
+func withExpectedVersion(ctx context.Context, version *int) context.Context {
+	if version == nil {
+		return ctx
+	}
+	return entity.WithExpectedVersion(ctx, *version)
+}
+
+func (h *BookingHandler) BookSeats(c *gin.Context) {
+	eventIDStr := c.Param("id")
+	eventID, err := strconv.ParseInt(eventIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.BookSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	req.EventID = eventID
+	req.UserID = userID
+
+	booking, err := h.bookingService.BookSeats(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrAccessCodeRequired), errors.Is(err, entity.ErrInvalidAccessCode):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrSaleNotStarted), errors.Is(err, entity.ErrSaleClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, booking)
+}
+
+func (h *BookingHandler) ConfirmBooking(c *gin.Context) {
+	eventIDStr := c.Param("id")
+	_, err := strconv.ParseInt(eventIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req ConfirmBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := h.bookingService.GetBooking(c.Request.Context(), req.BookingID)
+	if err != nil {
+		if errors.Is(err, entity.ErrBookingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrBookingNotFound.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canAccessBooking(c, current) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	// Повторное подтверждение не ошибка: отвечаем текущим состоянием бронирования
+	booking, err := h.bookingService.ConfirmBooking(withExpectedVersion(c.Request.Context(), req.Version), req.BookingID)
+	if err != nil {
+		var transitionErr *entity.TransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			c.JSON(http.StatusConflict, transitionConflict(transitionErr))
+		case errors.Is(err, entity.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": entity.ErrConflict.Error(), "code": "version_conflict"})
+		case errors.Is(err, entity.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrBookingNotFound.Error()})
+		case errors.Is(err, entity.ErrNotEnoughSeats):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "booking confirmed", "booking": booking})
+}
+
+// BulkConfirmRequest - оплаты, принятые кассой офлайн
+type BulkConfirmRequest struct {
+	BookingIDs []int64 `json:"booking_ids" binding:"required,min=1,max=100,dive,gt=0"`
+}
+
+// ConfirmBookingsBulk подтверждает пакет бронирований. Ответ 200 содержит итог по каждому ID,
+// в том числе по тем, что подтвердить не удалось; 500 - транзакция не применена целиком.
+func (h *BookingHandler) ConfirmBookingsBulk(c *gin.Context) {
+	var req BulkConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.bookingService.ConfirmBookings(c.Request.Context(), req.BookingIDs)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// transitionConflict - тело ответа 409 на запрещённый переход статуса бронирования
+func transitionConflict(err *entity.TransitionError) gin.H {
+	return gin.H{
+		"error":            err.Error(),
+		"code":             "invalid_transition",
+		"current_status":   err.From,
+		"requested_status": err.To,
+	}
+}
+
+// GetBooking возвращает бронирование с мероприятием, пользователем и оставшимся временем.
+// Чужое бронирование доступно только администратору.
+func (h *BookingHandler) GetBooking(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+
+	details, err := h.bookingService.GetBookingWithDetails(c.Request.Context(), bookingID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrBookingNotFound.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if !canAccessBooking(c, details.Booking) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// canAccessBooking - бронирование принадлежит вошедшему пользователю, или он администратор
+func canAccessBooking(c *gin.Context, booking *entity.Booking) bool {
+	return canAccessUser(c, booking.UserID)
+}
+
+// GetBookingHistory возвращает журнал смены статусов бронирования: кто, когда и почему
+func (h *BookingHandler) GetBookingHistory(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+
+	history, err := h.bookingService.GetBookingHistory(c.Request.Context(), bookingID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrBookingNotFound.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (h *BookingHandler) GetUserBookings(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if !canAccessUser(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	bookings, err := h.bookingService.GetUserBookings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookings)
+}
+
+// GetAllBookings возвращает все бронирования
+func (h *BookingHandler) GetAllBookings(c *gin.Context) {
+	// Получаем параметры пагинации
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// Получаем фильтр по статусу
+	status := c.Query("status")
+
+	ctx := c.Request.Context()
+
+	// Если указан статус, получаем бронирования по статусу
+	if status != "" {
+		bookingStatus, err := h.parseBookingStatus(status)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Success: false,
+				Error:   "Invalid booking status",
+			})
+			return
+		}
+
+		bookings, err := h.bookingService.GetBookingsByStatus(ctx, bookingStatus)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Success: false,
+				Error:   "Failed to get bookings by status: " + err.Error(),
+			})
+			return
+		}
+
+		// Применяем пагинацию
+		start := offset
+		if start > len(bookings) {
+			start = len(bookings)
+		}
+		end := start + limit
+		if end > len(bookings) {
+			end = len(bookings)
+		}
+
+		paginatedBookings := bookings[start:end]
+
+		c.JSON(http.StatusOK, SuccessResponse{
+			Success: true,
+			Message: "Bookings retrieved successfully",
+			Data:    paginatedBookings,
+			Meta: map[string]interface{}{
+				"total":    len(bookings),
+				"limit":    limit,
+				"offset":   offset,
+				"has_more": end < len(bookings),
+			},
+		})
+		return
+	}
+
+	// Если статус не указан, получаем все бронирования
+	bookings, err := h.bookingService.GetAllBookings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Success: false,
+			Error:   "Failed to get all bookings: " + err.Error(),
+		})
+		return
+	}
+
+	// Применяем пагинацию
+	start := offset
+	if start > len(bookings) {
+		start = len(bookings)
+	}
+	end := start + limit
+	if end > len(bookings) {
+		end = len(bookings)
+	}
+
+	paginatedBookings := bookings[start:end]
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Bookings retrieved successfully",
+		Data:    paginatedBookings,
+		Meta: map[string]interface{}{
+			"total":    len(bookings),
+			"limit":    limit,
+			"offset":   offset,
+			"has_more": end < len(bookings),
+		},
+	})
+}
+
+// GetEventBookings возвращает все бронирования для конкретного мероприятия
+func (h *BookingHandler) GetEventBookings(c *gin.Context) {
+	// Получаем ID мероприятия из пути
+	eventID, err := strconv.ParseInt(c.Param("event_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Invalid event ID",
+		})
+		return
+	}
+
+	// Получаем параметры пагинации
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// Получаем фильтр по статусу
+	status := c.Query("status")
+
+	ctx := c.Request.Context()
+
+	// Получаем все бронирования мероприятия
+	bookings, err := h.bookingService.GetEventBookings(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Success: false,
+			Error:   "Failed to get event bookings: " + err.Error(),
+		})
+		return
+	}
+
+	// Фильтруем по статусу если указан
+	if status != "" {
+		bookingStatus, err := h.parseBookingStatus(status)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Success: false,
+				Error:   "Invalid booking status",
+			})
+			return
+		}
+
+		filteredBookings := make([]*entity.Booking, 0)
+		for _, booking := range bookings {
+			if booking.Status == bookingStatus {
+				filteredBookings = append(filteredBookings, booking)
+			}
+		}
+		bookings = filteredBookings
+	}
+
+	// Применяем пагинацию
+	start := offset
+	if start > len(bookings) {
+		start = len(bookings)
+	}
+	end := start + limit
+	if end > len(bookings) {
+		end = len(bookings)
+	}
+
+	paginatedBookings := bookings[start:end]
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Event bookings retrieved successfully",
+		Data:    paginatedBookings,
+		Meta: map[string]interface{}{
+			"event_id": eventID,
+			"total":    len(bookings),
+			"limit":    limit,
+			"offset":   offset,
+			"has_more": end < len(bookings),
+		},
+	})
+}
+
+// CancelBooking отменяет бронирование
+func (h *BookingHandler) CancelBooking(c *gin.Context) {
+	// Получаем ID бронирования из пути
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Invalid booking ID",
+		})
+		return
+	}
+
+	// Парсим тело запроса
+	var req CancelBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	// Валидация причины отмены
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Cancellation reason is required",
+		})
+		return
+	}
+
+	if len(req.Reason) > 500 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Cancellation reason too long (max 500 characters)",
+		})
+		return
+	}
+
+	ctx := withExpectedVersion(c.Request.Context(), req.Version)
+
+	// Выполняем отмену бронирования
+	booking, quote, err := h.bookingService.CancelBooking(ctx, bookingID, req.Reason)
+	if err != nil {
+		// Проверяем тип ошибки для возврата соответствующего статуса
+		var transitionErr *entity.TransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			c.JSON(http.StatusConflict, transitionConflict(transitionErr))
+		case errors.Is(err, entity.ErrCancellationClosed), errors.Is(err, entity.ErrConflict):
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+		case errors.Is(err, entity.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Success: false,
+				Error:   "Booking not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Success: false,
+				Error:   "Failed to cancel booking: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	// Повторная отмена идемпотентна: расчёта возврата нет, возвращаем текущий статус
+	if quote == nil {
+		c.JSON(http.StatusOK, SuccessResponse{
+			Success: true,
+			Message: "Booking is already cancelled",
+			Meta: map[string]interface{}{
+				"booking_id": bookingID,
+				"status":     booking.Status,
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Booking cancelled successfully",
+		Data:    quote,
+		Meta: map[string]interface{}{
+			"booking_id": bookingID,
+			"reason":     req.Reason,
+			"status":     booking.Status,
+		},
+	})
+}
+
+// parseBookingStatus парсит строку в статус бронирования
+func (h *BookingHandler) parseBookingStatus(status string) (entity.BookingStatus, error) {
+	switch status {
+	case "pending":
+		return entity.BookingStatusPending, nil
+	case "confirmed":
+		return entity.BookingStatusConfirmed, nil
+	case "cancelled":
+		return entity.BookingStatusCancelled, nil
+	case "expired":
+		return entity.BookingStatusExpired, nil
+	default:
+		return "", fmt.Errorf("invalid booking status: %s", status)
+	}
+}
+
+// ExportBookings отдаёт все бронирования (или бронирования одного мероприятия) файлом csv/xlsx.
+// Файл пишется в ответ по мере чтения из базы; после начала передачи ошибку
+// можно только залогировать, поэтому клиент получит обрезанный файл.
+func (h *BookingHandler) ExportBookings(c *gin.Context) {
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+
+	filename := "bookings"
+	var eventID *int64
+	if raw := c.Query("event_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+		eventID = &id
+		filename = fmt.Sprintf("bookings-event-%d", id)
+	}
+	filename = fmt.Sprintf("%s-%s.%s", filename, time.Now().Format("20060102-150405"), format)
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+
+	err := h.bookingService.ExportBookings(c.Request.Context(), eventID, format, c.Writer)
+	if err == nil {
+		return
+	}
+
+	if c.Writer.Written() {
+		logrus.Errorf("Booking export interrupted: %v", err)
+		return
+	}
+
+	c.Header("Content-Disposition", "")
+	switch {
+	case errors.Is(err, entity.ErrEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrEventNotFound.Error()})
+	case errors.Is(err, export.ErrUnsupportedFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetEventRefunds возвращает отчёт о возвратах по мероприятию
+func (h *BookingHandler) GetEventRefunds(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	report, err := h.bookingService.GetEventRefunds(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, entity.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// SettleRefund подтверждает, что администратор выплатил возврат вне системы
+func (h *BookingHandler) SettleRefund(c *gin.Context) {
+	refundID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid refund id"})
+		return
+	}
+
+	refund, err := h.bookingService.SettleRefundManually(c.Request.Context(), refundID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrRefundNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrRefundNotPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, refund)
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmRecorder - сервис с одним бронированием пользователя 1, запоминающий подтверждения
+type confirmRecorder struct {
+	service.BookingService
+	confirmed []int64
+}
+
+func (s *confirmRecorder) GetBooking(ctx context.Context, id int64) (*entity.Booking, error) {
+	if id != 10 {
+		return nil, entity.ErrBookingNotFound
+	}
+	return &entity.Booking{ID: id, UserID: 1, Status: entity.BookingStatusPending}, nil
+}
+
+func (s *confirmRecorder) ConfirmBooking(ctx context.Context, bookingID int64) (*entity.Booking, error) {
+	s.confirmed = append(s.confirmed, bookingID)
+	return &entity.Booking{ID: bookingID, UserID: 1, Status: entity.BookingStatusConfirmed}, nil
+}
+
+// TestConfirmBookingOwnership проверяет, что подтвердить бронирование может только его владелец
+// или администратор, а чужое бронирование не подтверждается
+func TestConfirmBookingOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name      string
+		userID    int64
+		role      string
+		bookingID string
+		want      int
+	}{
+		{"owner", 1, entity.RoleUser, "10", http.StatusOK},
+		{"another user", 2, entity.RoleUser, "10", http.StatusForbidden},
+		{"admin", 3, entity.RoleAdmin, "10", http.StatusOK},
+		{"missing booking", 1, entity.RoleUser, "11", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		bookings := &confirmRecorder{}
+		router := gin.New()
+		router.POST("/bookings/events/:id/confirm", func(c *gin.Context) {
+			c.Set(middleware.ContextUserID, tc.userID)
+			c.Set(middleware.ContextUserRole, tc.role)
+		}, NewBookingHandler(bookings).ConfirmBooking)
+
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"booking_id": ` + tc.bookingID + `}`)
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/bookings/events/1/confirm", body))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (body %s)", tc.name, rec.Code, tc.want, rec.Body.String())
+		}
+		if confirmed := len(bookings.confirmed) > 0; confirmed != (tc.want == http.StatusOK) {
+			t.Errorf("%s: booking confirmed = %v", tc.name, confirmed)
+		}
+	}
+}
+
+func (s *confirmRecorder) GetUserBookings(ctx context.Context, userID int64) ([]*entity.Booking, error) {
+	return []*entity.Booking{{ID: 10, UserID: userID}}, nil
+}
+
+// TestGetUserBookingsOwnership проверяет, что чужой список бронирований видит только администратор
+func TestGetUserBookingsOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		userID int64
+		role   string
+		want   int
+	}{
+		{"owner", 1, entity.RoleUser, http.StatusOK},
+		{"another user", 2, entity.RoleUser, http.StatusForbidden},
+		{"admin", 3, entity.RoleAdmin, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		router := gin.New()
+		router.GET("/bookings/users/:user_id", func(c *gin.Context) {
+			c.Set(middleware.ContextUserID, tc.userID)
+			c.Set(middleware.ContextUserRole, tc.role)
+		}, NewBookingHandler(&confirmRecorder{}).GetUserBookings)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bookings/users/1", nil))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+// refundSettler - сервис с ожидающим возвратом 1 и уже закрытым возвратом 2
+type refundSettler struct {
+	service.BookingService
+}
+
+func (refundSettler) SettleRefundManually(ctx context.Context, refundID int64) (*entity.Refund, error) {
+	switch refundID {
+	case 1:
+		return &entity.Refund{ID: 1, Status: entity.RefundStatusSettledManually}, nil
+	case 2:
+		return nil, entity.ErrRefundNotPending
+	default:
+		return nil, fmt.Errorf("ошибка при получении возврата: %w", entity.ErrRefundNotFound)
+	}
+}
+
+// TestSettleRefundStatuses проверяет ответы ручного подтверждения выплаты возврата
+func TestSettleRefundStatuses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/admin/refunds/:id/settle", NewBookingHandler(refundSettler{}).SettleRefund)
+
+	for path, want := range map[string]int{
+		"/admin/refunds/1/settle":   http.StatusOK,
+		"/admin/refunds/2/settle":   http.StatusConflict,
+		"/admin/refunds/3/settle":   http.StatusNotFound,
+		"/admin/refunds/abc/settle": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/ds124wfegd/WB_L3/5/pkg/ical"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	calendarService service.CalendarService
+}
+
+func NewCalendarHandler(calendarService service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{calendarService: calendarService}
+}
+
+// GetSubscription возвращает ссылку для подписки на календарь; доступна самому пользователю или администратору
+func (h *CalendarHandler) GetSubscription(c *gin.Context) {
+	userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.calendarService.GetSubscription(c.Request.Context(), userID)
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// RevokeSubscription отзывает ранее выданные ссылки и возвращает новую
+func (h *CalendarHandler) RevokeSubscription(c *gin.Context) {
+	userID, ok := calendarOwner(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.calendarService.RevokeSubscription(c.Request.Context(), userID)
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Feed отдаёт iCalendar-фид подтверждённых бронирований. Календарные клиенты не умеют
+// передавать JWT, поэтому доступ проверяется по подписанному токену из query-параметра.
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	// Фид небольшой: собираем его целиком, чтобы при ошибке ответить корректным статусом
+	var buf bytes.Buffer
+	if err := h.calendarService.WriteFeed(c.Request.Context(), userID, c.Query("token"), &buf); err != nil {
+		calendarError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Content-Disposition", `inline; filename="calendar.ics"`)
+	c.Data(http.StatusOK, ical.ContentType, buf.Bytes())
+}
+
+func calendarOwner(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+
+	callerID, _ := middleware.UserIDFromContext(c)
+	if callerID != userID && c.GetString(middleware.ContextUserRole) != entity.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func calendarError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidCalendarToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
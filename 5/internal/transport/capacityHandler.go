@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CapacityHandler struct {
+	capacityService service.CapacityService
+}
+
+func NewCapacityHandler(capacityService service.CapacityService) *CapacityHandler {
+	return &CapacityHandler{capacityService: capacityService}
+}
+
+// PreviewCapacityChange показывает затронутые бронирования: ?total_seats=N&policy=keep_earliest|refund_latest|waitlist
+func (h *CapacityHandler) PreviewCapacityChange(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	totalSeats, err := strconv.Atoi(c.Query("total_seats"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid total_seats"})
+		return
+	}
+	policy := entity.CapacityPolicy(c.DefaultQuery("policy", string(entity.CapacityRefundLatest)))
+
+	plan, err := h.capacityService.PreviewCapacityChange(c.Request.Context(), eventID, totalSeats, policy)
+	if err != nil {
+		c.JSON(capacityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *CapacityHandler) ChangeCapacity(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.ChangeCapacityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.capacityService.ChangeCapacity(c.Request.Context(), eventID, &req)
+	if err != nil {
+		if errors.Is(err, entity.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": entity.ErrConflict.Error(), "code": "version_conflict"})
+			return
+		}
+		c.JSON(capacityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *CapacityHandler) GetWaitlist(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	entries, err := h.capacityService.GetWaitlist(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(capacityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// capacityErrorStatus сопоставляет ошибки изменения вместимости с HTTP-статусами
+func capacityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrInvalidCapacity), errors.Is(err, entity.ErrInvalidCapacityPolicy):
+		return http.StatusBadRequest
+	case errors.Is(err, entity.ErrCapacityBelowReserved), errors.Is(err, entity.ErrVenueCapacityExceeded),
+		errors.Is(err, entity.ErrInvalidTransition):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,141 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartHandler struct {
+	cartService service.CartService
+}
+
+func NewCartHandler(cartService service.CartService) *CartHandler {
+	return &CartHandler{cartService: cartService}
+}
+
+// cartCheckoutResponse - ответ на оформление корзины
+type cartCheckoutResponse struct {
+	Message  string            `json:"message"`
+	Bookings []*entity.Booking `json:"bookings"`
+}
+
+func (h *CartHandler) GetCart(c *gin.Context) {
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	cart, err := h.cartService.GetCart(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *CartHandler) AddItem(c *gin.Context) {
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req service.AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.cartService.AddItem(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cart)
+}
+
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	itemID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cart item id"})
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(c.Request.Context(), userID, itemID)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+func (h *CartHandler) ClearCart(c *gin.Context) {
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	if err := h.cartService.ClearCart(c.Request.Context(), userID); err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cart cleared"})
+}
+
+func (h *CartHandler) Checkout(c *gin.Context) {
+	userID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	// Тело необязательно: без него действует срок резервирования по умолчанию
+	var req service.CheckoutCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookings, err := h.cartService.Checkout(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cartCheckoutResponse{Message: "cart checked out", Bookings: bookings})
+}
+
+// cartErrorStatus сопоставляет ошибки корзины с HTTP-статусами
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrCartNotFound),
+		errors.Is(err, entity.ErrCartItemNotFound),
+		errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrCartItemExists),
+		errors.Is(err, entity.ErrNotEnoughSeats):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DLQHandler даёт администраторам доступ к задачам, упавшим в очередь недоставленных сообщений
+type DLQHandler struct {
+	dlq queue.DLQHandler
+}
+
+// NewDLQHandler принимает nil, если Redis не настроен: тогда маршруты отвечают 503
+func NewDLQHandler(dlq queue.DLQHandler) *DLQHandler {
+	return &DLQHandler{dlq: dlq}
+}
+
+func (h *DLQHandler) ListFailedTasks(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	tasks, err := h.dlq.GetFailedTasks(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if tasks == nil {
+		tasks = []*queue.FailedTask{}
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// RequeueFailedTask возвращает задачу в очередь её приоритета со сброшенным счётчиком попыток
+func (h *DLQHandler) RequeueFailedTask(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	taskID := c.Param("task_id")
+	if err := h.dlq.RequeueFailedTask(c.Request.Context(), taskID); err != nil {
+		c.JSON(dlqErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "task requeued", "task_id": taskID})
+}
+
+func (h *DLQHandler) DeleteFailedTask(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	taskID := c.Param("task_id")
+	if err := h.dlq.DeleteFailedTask(c.Request.Context(), taskID); err != nil {
+		c.JSON(dlqErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "task deleted", "task_id": taskID})
+}
+
+func (h *DLQHandler) GetStats(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	stats, err := h.dlq.GetDLQStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *DLQHandler) available(c *gin.Context) bool {
+	if h.dlq == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "task queue is not configured"})
+		return false
+	}
+	return true
+}
+
+// dlqErrorStatus сопоставляет ошибки DLQ с HTTP-статусами
+func dlqErrorStatus(err error) int {
+	if errors.Is(err, queue.ErrTaskNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Клиент может хранить ответ, но перед использованием обязан сверить его ETag:
+// свободные места меняются часто, поэтому ответ без проверки не переиспользуется
+const eventsCacheControl = "public, no-cache"
+
+// eventsETag строит слабый ETag по тому, от чего зависит ответ: время изменения мероприятия
+// и его площадки, занятые места и фаза продаж. Сериализация и обратный отсчёт до следующей фазы
+// не участвуют, поэтому ETag слабый.
+func eventsETag(events ...*entity.EventWithAvailability) string {
+	hash := sha256.New()
+	for _, event := range events {
+		fmt.Fprintf(hash, "%d:%d:%d:%d", event.ID, event.UpdatedAt.UnixNano(), event.BookedSeats, event.AvailableSeats)
+		if event.Venue != nil {
+			fmt.Fprintf(hash, ":%d", event.Venue.UpdatedAt.UnixNano())
+		}
+		if event.Sale != nil {
+			fmt.Fprintf(hash, ":%s", event.Sale.Phase)
+		}
+		if event.Poster != nil {
+			fmt.Fprintf(hash, ":%s:%d", event.Poster.ImageID, event.Poster.UpdatedAt.UnixNano())
+		}
+		hash.Write([]byte{';'})
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified ставит ETag и Cache-Control и, если версия клиента актуальна, отвечает 304
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", eventsCacheControl)
+
+	if !etagMatches(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches сравнивает If-None-Match с ETag слабым сравнением (RFC 9110, 13.1.2)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,61 +1,245 @@
-package transport
-
-import (
-	"net/http"
-	"strconv"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-
-	"github.com/gin-gonic/gin"
-)
-
-type EventHandler struct {
-	eventService service.EventService
-}
-
-func NewEventHandler(eventService service.EventService) *EventHandler {
-	return &EventHandler{eventService: eventService}
-}
-
-func (h *EventHandler) CreateEvent(c *gin.Context) {
-	var req service.CreateEventRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	event, err := h.eventService.CreateEvent(c.Request.Context(), &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, event)
-}
-
-func (h *EventHandler) GetEvent(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
-		return
-	}
-
-	event, err := h.eventService.GetEvent(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, event)
-}
-
-func (h *EventHandler) GetAllEvents(c *gin.Context) {
-	events, err := h.eventService.GetAllEvents(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, events)
-}
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventHandler struct {
+	eventService  service.EventService
+	posterService service.EventPosterService
+}
+
+func NewEventHandler(eventService service.EventService, posterService service.EventPosterService) *EventHandler {
+	return &EventHandler{eventService: eventService, posterService: posterService}
+}
+
+func (h *EventHandler) CreateEvent(c *gin.Context) {
+	var req service.CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.eventService.CreateEvent(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(eventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+func (h *EventHandler) GetEvent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	event, err := h.eventService.GetEvent(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	if notModified(c, eventsETag(event)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// UpdateEvent меняет переданные поля мероприятия
+func (h *EventHandler) UpdateEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.UpdateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.eventService.UpdateEvent(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(eventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// DeleteEvent удаляет мероприятие; с действующими бронированиями - 409
+func (h *EventHandler) DeleteEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	if err := h.eventService.DeleteEvent(c.Request.Context(), id); err != nil {
+		c.JSON(eventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "event deleted"})
+}
+
+// GetAllEvents возвращает все мероприятия. ?venue_id= оставляет только мероприятия площадки,
+// ?sort_by= (date, title, created_at) и ?sort_order= (asc, desc) задают порядок.
+func (h *EventHandler) GetAllEvents(c *gin.Context) {
+	var (
+		events []*entity.EventWithAvailability
+		err    error
+	)
+
+	filter := &service.EventFilter{
+		SortBy:    c.Query("sort_by"),
+		SortOrder: c.Query("sort_order"),
+	}
+	if venueParam := c.Query("venue_id"); venueParam != "" {
+		venueID, parseErr := strconv.ParseInt(venueParam, 10, 64)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue id"})
+			return
+		}
+		filter.VenueID = &venueID
+	}
+
+	if filter.VenueID != nil || filter.SortBy != "" || filter.SortOrder != "" {
+		events, err = h.eventService.SearchEvents(c.Request.Context(), filter)
+	} else {
+		events, err = h.eventService.GetAllEvents(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(eventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if notModified(c, eventsETag(events...)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetCancellationPolicy возвращает политику отмены мероприятия и её действие на текущий момент
+func (h *EventHandler) GetCancellationPolicy(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	evaluation, err := h.eventService.GetCancellationPolicy(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, entity.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get cancellation policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
+// UploadPoster принимает афишу в поле poster формы multipart. Загрузить её может администратор
+// или организатор мероприятия; варианты изображения появятся в poster.formats после обработки
+func (h *EventHandler) UploadPoster(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	event, err := h.eventService.GetEvent(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+	isOrganizer := event.OrganizerID != nil && *event.OrganizerID == userID
+	if !isOrganizer && c.GetString(middleware.ContextUserRole) != entity.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	header, err := c.FormFile("poster")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poster file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	poster, err := h.posterService.UploadPoster(c.Request.Context(), id, header.Filename, header.Size, file)
+	if err != nil {
+		c.JSON(posterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, poster)
+}
+
+// GetPoster возвращает афишу мероприятия и статус её обработки
+func (h *EventHandler) GetPoster(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	poster, err := h.posterService.GetPoster(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(posterErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, poster)
+}
+
+func posterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrInvalidPosterFile):
+		return http.StatusBadRequest
+	case errors.Is(err, entity.ErrEventNotFound), errors.Is(err, entity.ErrPosterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrPostersDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// eventErrorStatus сопоставляет ошибки создания и изменения мероприятия с HTTP-статусами
+func eventErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrEventNotFound), errors.Is(err, entity.ErrVenueNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrVenueCapacityExceeded), errors.Is(err, entity.ErrConflict),
+		errors.Is(err, entity.ErrEventHasBookings), errors.Is(err, entity.ErrCapacityBelowDemand):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrEventDatePast), errors.Is(err, entity.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
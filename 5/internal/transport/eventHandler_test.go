@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestGetAllEventsInvalidSort проверяет, что недопустимая сортировка - ошибка клиента (400),
+// а не сервера: сервис отклоняет её до обращения к базе, поэтому репозитории не нужны
+func TestGetAllEventsInvalidSort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewEventHandler(service.NewEventService(nil, nil, nil, nil, nil, nil), nil)
+	router := gin.New()
+	router.GET("/events", handler.GetAllEvents)
+
+	for _, query := range []string{"sort_by=price", "sort_order=sideways", "sort_by=title&sort_order=up"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?"+query, nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d (body %s)", query, rec.Code, http.StatusBadRequest, rec.Body.String())
+		}
+	}
+}
+
+// failingPolicyService - сервис, у которого политика отмены мероприятия 1 недоступна из-за сбоя базы,
+// а остальных мероприятий нет
+type failingPolicyService struct {
+	service.EventService
+}
+
+func (failingPolicyService) GetCancellationPolicy(ctx context.Context, eventID int64) (*service.CancellationPolicyEvaluation, error) {
+	if eventID == 1 {
+		return nil, fmt.Errorf("failed to get event: %w", errors.New("connection refused"))
+	}
+	return nil, entity.ErrEventNotFound
+}
+
+// TestGetCancellationPolicyErrors проверяет, что 404 отдаётся только для отсутствующего мероприятия,
+// а сбой при чтении - ошибка сервера
+func TestGetCancellationPolicyErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/events/:id/cancellation-policy", NewEventHandler(failingPolicyService{}, nil).GetCancellationPolicy)
+
+	for path, want := range map[string]int{
+		"/events/1/cancellation-policy": http.StatusInternalServerError,
+		"/events/2/cancellation-policy": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
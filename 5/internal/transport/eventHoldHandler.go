@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventHoldHandler struct {
+	holdService service.EventHoldService
+}
+
+func NewEventHoldHandler(holdService service.EventHoldService) *EventHoldHandler {
+	return &EventHoldHandler{holdService: holdService}
+}
+
+func (h *EventHoldHandler) GetEventHolds(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	holds, err := h.holdService.GetEventHolds(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+func (h *EventHoldHandler) SetEventHolds(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.SetEventHoldsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	holds, err := h.holdService.SetEventHolds(c.Request.Context(), eventID, &req)
+	if err != nil {
+		c.JSON(holdErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// holdErrorStatus сопоставляет ошибки холдов с HTTP-статусами
+func holdErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrHoldSeatsExceedEvent):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventsPageSize - мероприятий на странице публичного списка
+const eventsPageSize = 20
+
+// eventsPageDateLayout - формат дат в фильтрах, как у <input type="date">
+const eventsPageDateLayout = "2006-01-02"
+
+// EventPageHandler отдаёт публичный список мероприятий готовым HTML, без загрузки через API
+type EventPageHandler struct {
+	eventService service.EventService
+}
+
+func NewEventPageHandler(eventService service.EventService) *EventPageHandler {
+	return &EventPageHandler{eventService: eventService}
+}
+
+// eventsPageFilter - фильтры страницы в том виде, в каком они пришли в ссылке
+type eventsPageFilter struct {
+	From      string
+	To        string
+	Available bool
+	Page      int
+}
+
+// query собирает ссылку на страницу page с теми же фильтрами
+func (f eventsPageFilter) query(page int) string {
+	values := url.Values{}
+	if f.From != "" {
+		values.Set("from", f.From)
+	}
+	if f.To != "" {
+		values.Set("to", f.To)
+	}
+	if f.Available {
+		values.Set("available", "1")
+	}
+	if page > 1 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if len(values) == 0 {
+		return "/events"
+	}
+	return "/events?" + values.Encode()
+}
+
+// ListEvents рендерит страницу мероприятий: from и to - даты в формате YYYY-MM-DD включительно,
+// available=1 - только со свободными местами, page - номер страницы с 1.
+// Без from показываются только предстоящие мероприятия.
+func (h *EventPageHandler) ListEvents(c *gin.Context) {
+	filter := eventsPageFilter{
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		Available: c.Query("available") == "1" || c.Query("available") == "true",
+		Page:      1,
+	}
+
+	data := gin.H{"filter": filter}
+	renderError := func(message string) {
+		data["error"] = message
+		c.HTML(http.StatusBadRequest, "events.html", data)
+	}
+
+	if page := c.Query("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			renderError("Invalid page number")
+			return
+		}
+		filter.Page = n
+		data["filter"] = filter
+	}
+
+	searchFilter := &service.EventFilter{
+		DateFrom:      time.Now(),
+		Limit:         eventsPageSize + 1, // лишняя запись показывает, есть ли следующая страница
+		Offset:        (filter.Page - 1) * eventsPageSize,
+		SortBy:        "date",
+		SortOrder:     "asc",
+		OnlyAvailable: filter.Available,
+	}
+	if filter.From != "" {
+		from, err := time.ParseInLocation(eventsPageDateLayout, filter.From, time.Local)
+		if err != nil {
+			renderError("Invalid start date, expected YYYY-MM-DD")
+			return
+		}
+		searchFilter.DateFrom = from
+	}
+	if filter.To != "" {
+		to, err := time.ParseInLocation(eventsPageDateLayout, filter.To, time.Local)
+		if err != nil {
+			renderError("Invalid end date, expected YYYY-MM-DD")
+			return
+		}
+		searchFilter.DateTo = to.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !searchFilter.DateTo.IsZero() && searchFilter.DateTo.Before(searchFilter.DateFrom) {
+		renderError("End date is before start date")
+		return
+	}
+
+	events, err := h.eventService.SearchEvents(c.Request.Context(), searchFilter)
+	if err != nil {
+		data["error"] = "Failed to load events"
+		c.HTML(http.StatusInternalServerError, "events.html", data)
+		return
+	}
+
+	hasNext := len(events) > eventsPageSize
+	if hasNext {
+		events = events[:eventsPageSize]
+	}
+
+	data["events"] = events
+	data["canonical"] = filter.query(filter.Page)
+	if filter.Page > 1 {
+		data["prevURL"] = filter.query(filter.Page - 1)
+	}
+	if hasNext {
+		data["nextURL"] = filter.query(filter.Page + 1)
+	}
+
+	// Пустая страница за пределами списка не должна индексироваться как обычная
+	status := http.StatusOK
+	if len(events) == 0 && filter.Page > 1 {
+		status = http.StatusNotFound
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.HTML(status, "events.html", data)
+}
@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxCancelReason = 500
+
+type bookingServer struct {
+	pb.UnimplementedBookingServiceServer
+	bookingService service.BookingService
+}
+
+func (s *bookingServer) BookSeats(ctx context.Context, req *pb.BookSeatsRequest) (*pb.Booking, error) {
+	caller, ok := callerFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, entity.ErrUnauthorized.Error())
+	}
+
+	bookReq := &service.BookSeatsRequest{
+		EventID:            req.GetEventId(),
+		UserID:             caller.UserID,
+		Seats:              int(req.GetSeats()),
+		ReservationTimeout: int(req.GetReservationTimeout()),
+		TierID:             req.TierId,
+		PromoCode:          req.GetPromoCode(),
+	}
+	if err := validate(bookReq); err != nil {
+		return nil, err
+	}
+
+	booking, err := s.bookingService.BookSeats(ctx, bookReq)
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+
+	return toPBBooking(booking), nil
+}
+
+// ConfirmBooking идемпотентен, как и в REST: повторный вызов возвращает текущее состояние.
+// Подтвердить можно своё бронирование, чужое - только администратору
+func (s *bookingServer) ConfirmBooking(ctx context.Context, req *pb.ConfirmBookingRequest) (*pb.Booking, error) {
+	if req.GetBookingId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "booking_id is required")
+	}
+
+	current, err := s.bookingService.GetBooking(ctx, req.GetBookingId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+	if err := requireOwnerOrAdmin(ctx, current.UserID); err != nil {
+		return nil, err
+	}
+
+	booking, err := s.bookingService.ConfirmBooking(ctx, req.GetBookingId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBBooking(booking), nil
+}
+
+// GetBooking возвращает бронирование с подробностями; чужое - только администратору
+func (s *bookingServer) GetBooking(ctx context.Context, req *pb.GetBookingRequest) (*pb.BookingDetails, error) {
+	details, err := s.bookingService.GetBookingWithDetails(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	if err := requireOwnerOrAdmin(ctx, details.Booking.UserID); err != nil {
+		return nil, err
+	}
+
+	return toPBBookingDetails(details), nil
+}
+
+// ListUserBookings возвращает бронирования пользователя; чужие - только администратору
+func (s *bookingServer) ListUserBookings(ctx context.Context, req *pb.ListUserBookingsRequest) (*pb.ListBookingsResponse, error) {
+	if err := requireOwnerOrAdmin(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	bookings, err := s.bookingService.GetUserBookings(ctx, req.GetUserId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBBookings(bookings), nil
+}
+
+func (s *bookingServer) ListEventBookings(ctx context.Context, req *pb.ListEventBookingsRequest) (*pb.ListBookingsResponse, error) {
+	bookings, err := s.bookingService.GetEventBookings(ctx, req.GetEventId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBBookings(bookings), nil
+}
+
+func (s *bookingServer) CancelBooking(ctx context.Context, req *pb.CancelBookingRequest) (*pb.CancelBookingResponse, error) {
+	if req.GetReason() == "" {
+		return nil, status.Error(codes.InvalidArgument, "cancellation reason is required")
+	}
+	if len(req.GetReason()) > maxCancelReason {
+		return nil, status.Errorf(codes.InvalidArgument, "cancellation reason too long (max %d characters)", maxCancelReason)
+	}
+
+	booking, quote, err := s.bookingService.CancelBooking(ctx, req.GetBookingId(), req.GetReason())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	resp := &pb.CancelBookingResponse{Booking: toPBBooking(booking)}
+	if quote != nil {
+		resp.RefundAmount = quote.RefundAmount
+	}
+	return resp, nil
+}
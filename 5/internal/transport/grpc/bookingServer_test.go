@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestAuthInterceptorRequiresToken проверяет, что методы, меняющие или раскрывающие данные
+// пользователя, без токена не вызываются
+func TestAuthInterceptorRequiresToken(t *testing.T) {
+	interceptor := authInterceptor(middleware.NewJWTManager(config.JWTConfig{Secret: "test"}))
+
+	for _, method := range []string{
+		pb.BookingService_ConfirmBooking_FullMethodName,
+		pb.BookingService_ListUserBookings_FullMethodName,
+		pb.EventService_CreateEvent_FullMethodName,
+		pb.UserService_GetUser_FullMethodName,
+		pb.UserService_LinkTelegram_FullMethodName,
+	} {
+		called := false
+		_, err := interceptor(context.Background(), nil, &grpclib.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			})
+
+		if status.Code(err) != codes.Unauthenticated || called {
+			t.Errorf("%s: code = %v, handler called = %v", method, status.Code(err), called)
+		}
+	}
+}
+
+// confirmRecorder - сервис с одним бронированием пользователя 1, запоминающий подтверждения
+type confirmRecorder struct {
+	service.BookingService
+	confirmed []int64
+}
+
+func (s *confirmRecorder) GetBooking(ctx context.Context, id int64) (*entity.Booking, error) {
+	if id != 10 {
+		return nil, entity.ErrBookingNotFound
+	}
+	return &entity.Booking{ID: id, UserID: 1, Status: entity.BookingStatusPending}, nil
+}
+
+func (s *confirmRecorder) ConfirmBooking(ctx context.Context, bookingID int64) (*entity.Booking, error) {
+	s.confirmed = append(s.confirmed, bookingID)
+	return &entity.Booking{ID: bookingID, UserID: 1, Status: entity.BookingStatusConfirmed}, nil
+}
+
+// TestConfirmBookingOwnership проверяет, что чужое бронирование подтверждает только администратор
+func TestConfirmBookingOwnership(t *testing.T) {
+	cases := []struct {
+		name   string
+		caller *middleware.Claims
+		want   codes.Code
+	}{
+		{"owner", &middleware.Claims{UserID: 1, Role: entity.RoleUser}, codes.OK},
+		{"another user", &middleware.Claims{UserID: 2, Role: entity.RoleUser}, codes.PermissionDenied},
+		{"admin", &middleware.Claims{UserID: 3, Role: entity.RoleAdmin}, codes.OK},
+	}
+
+	for _, tc := range cases {
+		bookings := &confirmRecorder{}
+		server := &bookingServer{bookingService: bookings}
+		ctx := context.WithValue(context.Background(), claimsKey{}, tc.caller)
+
+		_, err := server.ConfirmBooking(ctx, &pb.ConfirmBookingRequest{BookingId: 10})
+		if status.Code(err) != tc.want {
+			t.Errorf("%s: code = %v, want %v", tc.name, status.Code(err), tc.want)
+		}
+		if confirmed := len(bookings.confirmed) > 0; confirmed != (tc.want == codes.OK) {
+			t.Errorf("%s: booking confirmed = %v", tc.name, confirmed)
+		}
+	}
+}
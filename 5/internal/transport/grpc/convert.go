@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"errors"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// validate проверяет запрос сервиса по тем же тегам binding, что и gin в REST
+func validate(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// statusError сопоставляет ошибки сервисов с кодами gRPC; fallback - код для остальных ошибок,
+// чтобы повторить ответ соответствующего REST-обработчика
+func statusError(err error, fallback codes.Code) error {
+	var transitionErr *entity.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, entity.ErrEventNotFound),
+		errors.Is(err, entity.ErrBookingNotFound),
+		errors.Is(err, entity.ErrUserNotFound),
+		errors.Is(err, entity.ErrVenueNotFound),
+		errors.Is(err, entity.ErrTicketTierNotFound),
+		errors.Is(err, entity.ErrPromoCodeNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, entity.ErrNotEnoughSeats),
+		errors.Is(err, entity.ErrEventFull),
+		errors.Is(err, entity.ErrBookingExpired),
+		errors.Is(err, entity.ErrCancellationClosed),
+		errors.Is(err, entity.ErrSaleNotStarted),
+		errors.Is(err, entity.ErrSaleClosed),
+		errors.Is(err, entity.ErrVenueCapacityExceeded):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, entity.ErrUserAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, entity.ErrInvalidCredentials), errors.Is(err, entity.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, entity.ErrForbidden),
+		errors.Is(err, entity.ErrAccessCodeRequired),
+		errors.Is(err, entity.ErrInvalidAccessCode):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(fallback, err.Error())
+	}
+}
+
+func toPBEvent(event *entity.EventWithAvailability) *pb.Event {
+	if event == nil {
+		return nil
+	}
+
+	msg := toPBBaseEvent(&event.Event)
+	msg.AvailableSeats = int32(event.AvailableSeats)
+	msg.BookedSeats = int32(event.BookedSeats)
+	return msg
+}
+
+func toPBBaseEvent(event *entity.Event) *pb.Event {
+	if event == nil {
+		return nil
+	}
+
+	return &pb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		VenueId:     event.VenueID,
+		Date:        timestamppb.New(event.Date),
+		TotalSeats:  int32(event.TotalSeats),
+		CreatedAt:   timestamppb.New(event.CreatedAt),
+		UpdatedAt:   timestamppb.New(event.UpdatedAt),
+	}
+}
+
+func toPBBooking(booking *entity.Booking) *pb.Booking {
+	if booking == nil {
+		return nil
+	}
+
+	return &pb.Booking{
+		Id:                 booking.ID,
+		EventId:            booking.EventID,
+		UserId:             booking.UserID,
+		Seats:              int32(booking.Seats),
+		Status:             string(booking.Status),
+		ExpiresAt:          timestamppb.New(booking.ExpiresAt),
+		ReservationTimeout: int32(booking.ReservationTimeout),
+		TierId:             booking.TierID,
+		TotalPrice:         booking.TotalPrice,
+		PromoCodeId:        booking.PromoCodeID,
+		DiscountAmount:     booking.DiscountAmount,
+		CreatedAt:          timestamppb.New(booking.CreatedAt),
+		UpdatedAt:          timestamppb.New(booking.UpdatedAt),
+	}
+}
+
+func toPBBookings(bookings []*entity.Booking) *pb.ListBookingsResponse {
+	resp := &pb.ListBookingsResponse{Bookings: make([]*pb.Booking, 0, len(bookings))}
+	for _, booking := range bookings {
+		resp.Bookings = append(resp.Bookings, toPBBooking(booking))
+	}
+	return resp
+}
+
+func toPBBookingDetails(details *service.BookingDetails) *pb.BookingDetails {
+	return &pb.BookingDetails{
+		Booking:    toPBBooking(details.Booking),
+		Event:      toPBBaseEvent(details.Event),
+		User:       toPBUser(details.User),
+		TimeLeft:   durationpb.New(details.TimeLeft),
+		IsExpired:  details.IsExpired,
+		CanConfirm: details.CanConfirm,
+	}
+}
+
+func toPBUser(user *entity.User) *pb.User {
+	if user == nil {
+		return nil
+	}
+
+	return &pb.User{
+		Id:             user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		TelegramId:     user.TelegramID,
+		Role:           user.Role,
+		CreatedAt:      timestamppb.New(user.CreatedAt),
+		NotifyEmail:    user.NotifyEmail,
+		NotifyTelegram: user.NotifyTelegram,
+	}
+}
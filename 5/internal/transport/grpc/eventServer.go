@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+
+	"google.golang.org/grpc/codes"
+)
+
+type eventServer struct {
+	pb.UnimplementedEventServiceServer
+	eventService service.EventService
+}
+
+func (s *eventServer) CreateEvent(ctx context.Context, req *pb.CreateEventRequest) (*pb.Event, error) {
+	createReq := &service.CreateEventRequest{
+		Title:             req.GetTitle(),
+		Description:       req.GetDescription(),
+		Location:          req.GetLocation(),
+		VenueID:           req.VenueId,
+		TotalSeats:        int(req.GetTotalSeats()),
+		LateRefundPercent: req.LateRefundPercent,
+	}
+	// Без даты остаётся нулевое время, и проверка required отклонит запрос, как в REST
+	if req.GetDate() != nil {
+		createReq.Date = req.GetDate().AsTime()
+	}
+	if req.FreeCancellationHours != nil {
+		hours := int(req.GetFreeCancellationHours())
+		createReq.FreeCancellationHours = &hours
+	}
+	if err := validate(createReq); err != nil {
+		return nil, err
+	}
+
+	event, err := s.eventService.CreateEvent(ctx, createReq)
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBEvent(&entity.EventWithAvailability{Event: *event, AvailableSeats: event.TotalSeats}), nil
+}
+
+func (s *eventServer) GetEvent(ctx context.Context, req *pb.GetEventRequest) (*pb.Event, error) {
+	event, err := s.eventService.GetEvent(ctx, req.GetId())
+	if err != nil {
+		// REST отвечает 404 на любую ошибку чтения мероприятия
+		return nil, statusError(err, codes.NotFound)
+	}
+
+	return toPBEvent(event), nil
+}
+
+// ListEvents возвращает все мероприятия или только мероприятия площадки venue_id
+func (s *eventServer) ListEvents(ctx context.Context, req *pb.ListEventsRequest) (*pb.ListEventsResponse, error) {
+	var (
+		events []*entity.EventWithAvailability
+		err    error
+	)
+	if req.VenueId != nil {
+		events, err = s.eventService.SearchEvents(ctx, &service.EventFilter{VenueID: req.VenueId})
+	} else {
+		events, err = s.eventService.GetAllEvents(ctx)
+	}
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	resp := &pb.ListEventsResponse{Events: make([]*pb.Event, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, toPBEvent(event))
+	}
+	return resp, nil
+}
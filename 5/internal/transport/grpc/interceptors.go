@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/sirupsen/logrus"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// access - кто может вызывать метод; методы без записи в methodAccess публичные, как и их REST-двойники
+type access int
+
+const (
+	accessPublic access = iota
+	accessUser
+	accessAdmin
+)
+
+var methodAccess = map[string]access{
+	pb.BookingService_BookSeats_FullMethodName:                  accessUser,
+	pb.BookingService_ConfirmBooking_FullMethodName:             accessUser,
+	pb.BookingService_GetBooking_FullMethodName:                 accessUser,
+	pb.BookingService_ListUserBookings_FullMethodName:           accessUser,
+	pb.BookingService_ListEventBookings_FullMethodName:          accessAdmin,
+	pb.BookingService_CancelBooking_FullMethodName:              accessAdmin,
+	pb.EventService_CreateEvent_FullMethodName:                  accessAdmin,
+	pb.UserService_GetUser_FullMethodName:                       accessUser,
+	pb.UserService_UpdateNotificationPreferences_FullMethodName: accessUser,
+	pb.UserService_LinkTelegram_FullMethodName:                  accessUser,
+}
+
+type claimsKey struct{}
+
+// authInterceptor проверяет токен из metadata "authorization: Bearer <token>" так же, как middleware.Auth
+func authInterceptor(manager *middleware.JWTManager) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		required := methodAccess[info.FullMethod]
+		if required == accessPublic {
+			return handler(ctx, req)
+		}
+
+		var header string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				header = values[0]
+			}
+		}
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenString == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		claims, err := manager.ParseToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if required == accessAdmin && claims.Role != entity.RoleAdmin {
+			return nil, status.Error(codes.PermissionDenied, entity.ErrForbidden.Error())
+		}
+
+		ctx = entity.WithAuditActor(ctx, claims.AuditActor())
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+// callerFromContext возвращает данные пользователя, установленные authInterceptor
+func callerFromContext(ctx context.Context) (*middleware.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*middleware.Claims)
+	return claims, ok
+}
+
+// requireOwnerOrAdmin пропускает вызов от самого пользователя userID или администратора,
+// как canAccessUser в REST
+func requireOwnerOrAdmin(ctx context.Context, userID int64) error {
+	caller, _ := callerFromContext(ctx)
+	if caller == nil || (caller.UserID != userID && caller.Role != entity.RoleAdmin) {
+		return status.Error(codes.PermissionDenied, entity.ErrForbidden.Error())
+	}
+	return nil
+}
+
+// recoveryInterceptor превращает панику обработчика в codes.Internal, как gin.Recovery
+func recoveryInterceptor(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("panic in gRPC method %s: %v", info.FullMethod, r)
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func loggingInterceptor(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	entry := logrus.WithFields(logrus.Fields{
+		"method":   info.FullMethod,
+		"code":     code.String(),
+		"duration": time.Since(start).String(),
+	})
+
+	switch code {
+	case codes.OK:
+		entry.Info("gRPC request")
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		entry.WithError(err).Error("gRPC request failed")
+	default:
+		entry.WithError(err).Warn("gRPC request rejected")
+	}
+
+	return resp, err
+}
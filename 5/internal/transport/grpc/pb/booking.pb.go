@@ -0,0 +1,808 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: eventbooker/v1/booking.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Booking struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	EventId            int64                  `protobuf:"varint,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	UserId             int64                  `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Seats              int32                  `protobuf:"varint,4,opt,name=seats,proto3" json:"seats,omitempty"`
+	Status             string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	ExpiresAt          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	ReservationTimeout int32                  `protobuf:"varint,7,opt,name=reservation_timeout,json=reservationTimeout,proto3" json:"reservation_timeout,omitempty"`
+	TierId             *int64                 `protobuf:"varint,8,opt,name=tier_id,json=tierId,proto3,oneof" json:"tier_id,omitempty"`
+	TotalPrice         float64                `protobuf:"fixed64,9,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	PromoCodeId        *int64                 `protobuf:"varint,10,opt,name=promo_code_id,json=promoCodeId,proto3,oneof" json:"promo_code_id,omitempty"`
+	DiscountAmount     float64                `protobuf:"fixed64,11,opt,name=discount_amount,json=discountAmount,proto3" json:"discount_amount,omitempty"`
+	CreatedAt          *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt          *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Booking) Reset() {
+	*x = Booking{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Booking) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Booking) ProtoMessage() {}
+
+func (x *Booking) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Booking.ProtoReflect.Descriptor instead.
+func (*Booking) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Booking) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Booking) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *Booking) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Booking) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *Booking) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Booking) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Booking) GetReservationTimeout() int32 {
+	if x != nil {
+		return x.ReservationTimeout
+	}
+	return 0
+}
+
+func (x *Booking) GetTierId() int64 {
+	if x != nil && x.TierId != nil {
+		return *x.TierId
+	}
+	return 0
+}
+
+func (x *Booking) GetTotalPrice() float64 {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return 0
+}
+
+func (x *Booking) GetPromoCodeId() int64 {
+	if x != nil && x.PromoCodeId != nil {
+		return *x.PromoCodeId
+	}
+	return 0
+}
+
+func (x *Booking) GetDiscountAmount() float64 {
+	if x != nil {
+		return x.DiscountAmount
+	}
+	return 0
+}
+
+func (x *Booking) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Booking) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type BookingDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Booking       *Booking               `protobuf:"bytes,1,opt,name=booking,proto3" json:"booking,omitempty"`
+	Event         *Event                 `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+	User          *User                  `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	TimeLeft      *durationpb.Duration   `protobuf:"bytes,4,opt,name=time_left,json=timeLeft,proto3" json:"time_left,omitempty"`
+	IsExpired     bool                   `protobuf:"varint,5,opt,name=is_expired,json=isExpired,proto3" json:"is_expired,omitempty"`
+	CanConfirm    bool                   `protobuf:"varint,6,opt,name=can_confirm,json=canConfirm,proto3" json:"can_confirm,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookingDetails) Reset() {
+	*x = BookingDetails{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookingDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookingDetails) ProtoMessage() {}
+
+func (x *BookingDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookingDetails.ProtoReflect.Descriptor instead.
+func (*BookingDetails) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BookingDetails) GetBooking() *Booking {
+	if x != nil {
+		return x.Booking
+	}
+	return nil
+}
+
+func (x *BookingDetails) GetEvent() *Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *BookingDetails) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *BookingDetails) GetTimeLeft() *durationpb.Duration {
+	if x != nil {
+		return x.TimeLeft
+	}
+	return nil
+}
+
+func (x *BookingDetails) GetIsExpired() bool {
+	if x != nil {
+		return x.IsExpired
+	}
+	return false
+}
+
+func (x *BookingDetails) GetCanConfirm() bool {
+	if x != nil {
+		return x.CanConfirm
+	}
+	return false
+}
+
+type BookSeatsRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	EventId int64                  `protobuf:"varint,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Seats   int32                  `protobuf:"varint,2,opt,name=seats,proto3" json:"seats,omitempty"`
+	// Минуты на подтверждение
+	ReservationTimeout int32  `protobuf:"varint,3,opt,name=reservation_timeout,json=reservationTimeout,proto3" json:"reservation_timeout,omitempty"`
+	TierId             *int64 `protobuf:"varint,4,opt,name=tier_id,json=tierId,proto3,oneof" json:"tier_id,omitempty"`
+	PromoCode          string `protobuf:"bytes,5,opt,name=promo_code,json=promoCode,proto3" json:"promo_code,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *BookSeatsRequest) Reset() {
+	*x = BookSeatsRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookSeatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookSeatsRequest) ProtoMessage() {}
+
+func (x *BookSeatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookSeatsRequest.ProtoReflect.Descriptor instead.
+func (*BookSeatsRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BookSeatsRequest) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *BookSeatsRequest) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *BookSeatsRequest) GetReservationTimeout() int32 {
+	if x != nil {
+		return x.ReservationTimeout
+	}
+	return 0
+}
+
+func (x *BookSeatsRequest) GetTierId() int64 {
+	if x != nil && x.TierId != nil {
+		return *x.TierId
+	}
+	return 0
+}
+
+func (x *BookSeatsRequest) GetPromoCode() string {
+	if x != nil {
+		return x.PromoCode
+	}
+	return ""
+}
+
+type ConfirmBookingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookingId     int64                  `protobuf:"varint,1,opt,name=booking_id,json=bookingId,proto3" json:"booking_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfirmBookingRequest) Reset() {
+	*x = ConfirmBookingRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfirmBookingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmBookingRequest) ProtoMessage() {}
+
+func (x *ConfirmBookingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmBookingRequest.ProtoReflect.Descriptor instead.
+func (*ConfirmBookingRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ConfirmBookingRequest) GetBookingId() int64 {
+	if x != nil {
+		return x.BookingId
+	}
+	return 0
+}
+
+type GetBookingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookingRequest) Reset() {
+	*x = GetBookingRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookingRequest) ProtoMessage() {}
+
+func (x *GetBookingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookingRequest.ProtoReflect.Descriptor instead.
+func (*GetBookingRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetBookingRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListUserBookingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserBookingsRequest) Reset() {
+	*x = ListUserBookingsRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserBookingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserBookingsRequest) ProtoMessage() {}
+
+func (x *ListUserBookingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserBookingsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserBookingsRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListUserBookingsRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type ListEventBookingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       int64                  `protobuf:"varint,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEventBookingsRequest) Reset() {
+	*x = ListEventBookingsRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEventBookingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEventBookingsRequest) ProtoMessage() {}
+
+func (x *ListEventBookingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEventBookingsRequest.ProtoReflect.Descriptor instead.
+func (*ListEventBookingsRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListEventBookingsRequest) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+type ListBookingsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bookings      []*Booking             `protobuf:"bytes,1,rep,name=bookings,proto3" json:"bookings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBookingsResponse) Reset() {
+	*x = ListBookingsResponse{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBookingsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBookingsResponse) ProtoMessage() {}
+
+func (x *ListBookingsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBookingsResponse.ProtoReflect.Descriptor instead.
+func (*ListBookingsResponse) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListBookingsResponse) GetBookings() []*Booking {
+	if x != nil {
+		return x.Bookings
+	}
+	return nil
+}
+
+type CancelBookingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BookingId     int64                  `protobuf:"varint,1,opt,name=booking_id,json=bookingId,proto3" json:"booking_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelBookingRequest) Reset() {
+	*x = CancelBookingRequest{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelBookingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBookingRequest) ProtoMessage() {}
+
+func (x *CancelBookingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBookingRequest.ProtoReflect.Descriptor instead.
+func (*CancelBookingRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CancelBookingRequest) GetBookingId() int64 {
+	if x != nil {
+		return x.BookingId
+	}
+	return 0
+}
+
+func (x *CancelBookingRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelBookingResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Booking *Booking               `protobuf:"bytes,1,opt,name=booking,proto3" json:"booking,omitempty"`
+	// Сумма возврата по политике отмены мероприятия
+	RefundAmount  float64 `protobuf:"fixed64,2,opt,name=refund_amount,json=refundAmount,proto3" json:"refund_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelBookingResponse) Reset() {
+	*x = CancelBookingResponse{}
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelBookingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBookingResponse) ProtoMessage() {}
+
+func (x *CancelBookingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_booking_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBookingResponse.ProtoReflect.Descriptor instead.
+func (*CancelBookingResponse) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_booking_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CancelBookingResponse) GetBooking() *Booking {
+	if x != nil {
+		return x.Booking
+	}
+	return nil
+}
+
+func (x *CancelBookingResponse) GetRefundAmount() float64 {
+	if x != nil {
+		return x.RefundAmount
+	}
+	return 0
+}
+
+var File_eventbooker_v1_booking_proto protoreflect.FileDescriptor
+
+const file_eventbooker_v1_booking_proto_rawDesc = "" +
+	"\n" +
+	"\x1ceventbooker/v1/booking.proto\x12\x0eeventbooker.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1aeventbooker/v1/event.proto\x1a\x19eventbooker/v1/user.proto\"\x8c\x04\n" +
+	"\aBooking\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\bevent_id\x18\x02 \x01(\x03R\aeventId\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\x03R\x06userId\x12\x14\n" +
+	"\x05seats\x18\x04 \x01(\x05R\x05seats\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12/\n" +
+	"\x13reservation_timeout\x18\a \x01(\x05R\x12reservationTimeout\x12\x1c\n" +
+	"\atier_id\x18\b \x01(\x03H\x00R\x06tierId\x88\x01\x01\x12\x1f\n" +
+	"\vtotal_price\x18\t \x01(\x01R\n" +
+	"totalPrice\x12'\n" +
+	"\rpromo_code_id\x18\n" +
+	" \x01(\x03H\x01R\vpromoCodeId\x88\x01\x01\x12'\n" +
+	"\x0fdiscount_amount\x18\v \x01(\x01R\x0ediscountAmount\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\n" +
+	"\n" +
+	"\b_tier_idB\x10\n" +
+	"\x0e_promo_code_id\"\x92\x02\n" +
+	"\x0eBookingDetails\x121\n" +
+	"\abooking\x18\x01 \x01(\v2\x17.eventbooker.v1.BookingR\abooking\x12+\n" +
+	"\x05event\x18\x02 \x01(\v2\x15.eventbooker.v1.EventR\x05event\x12(\n" +
+	"\x04user\x18\x03 \x01(\v2\x14.eventbooker.v1.UserR\x04user\x126\n" +
+	"\ttime_left\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\btimeLeft\x12\x1d\n" +
+	"\n" +
+	"is_expired\x18\x05 \x01(\bR\tisExpired\x12\x1f\n" +
+	"\vcan_confirm\x18\x06 \x01(\bR\n" +
+	"canConfirm\"\xbd\x01\n" +
+	"\x10BookSeatsRequest\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\x03R\aeventId\x12\x14\n" +
+	"\x05seats\x18\x02 \x01(\x05R\x05seats\x12/\n" +
+	"\x13reservation_timeout\x18\x03 \x01(\x05R\x12reservationTimeout\x12\x1c\n" +
+	"\atier_id\x18\x04 \x01(\x03H\x00R\x06tierId\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"promo_code\x18\x05 \x01(\tR\tpromoCodeB\n" +
+	"\n" +
+	"\b_tier_id\"6\n" +
+	"\x15ConfirmBookingRequest\x12\x1d\n" +
+	"\n" +
+	"booking_id\x18\x01 \x01(\x03R\tbookingId\"#\n" +
+	"\x11GetBookingRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"2\n" +
+	"\x17ListUserBookingsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\"5\n" +
+	"\x18ListEventBookingsRequest\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\x03R\aeventId\"K\n" +
+	"\x14ListBookingsResponse\x123\n" +
+	"\bbookings\x18\x01 \x03(\v2\x17.eventbooker.v1.BookingR\bbookings\"M\n" +
+	"\x14CancelBookingRequest\x12\x1d\n" +
+	"\n" +
+	"booking_id\x18\x01 \x01(\x03R\tbookingId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"o\n" +
+	"\x15CancelBookingResponse\x121\n" +
+	"\abooking\x18\x01 \x01(\v2\x17.eventbooker.v1.BookingR\abooking\x12#\n" +
+	"\rrefund_amount\x18\x02 \x01(\x01R\frefundAmount2\xa1\x04\n" +
+	"\x0eBookingService\x12F\n" +
+	"\tBookSeats\x12 .eventbooker.v1.BookSeatsRequest\x1a\x17.eventbooker.v1.Booking\x12P\n" +
+	"\x0eConfirmBooking\x12%.eventbooker.v1.ConfirmBookingRequest\x1a\x17.eventbooker.v1.Booking\x12O\n" +
+	"\n" +
+	"GetBooking\x12!.eventbooker.v1.GetBookingRequest\x1a\x1e.eventbooker.v1.BookingDetails\x12a\n" +
+	"\x10ListUserBookings\x12'.eventbooker.v1.ListUserBookingsRequest\x1a$.eventbooker.v1.ListBookingsResponse\x12c\n" +
+	"\x11ListEventBookings\x12(.eventbooker.v1.ListEventBookingsRequest\x1a$.eventbooker.v1.ListBookingsResponse\x12\\\n" +
+	"\rCancelBooking\x12$.eventbooker.v1.CancelBookingRequest\x1a%.eventbooker.v1.CancelBookingResponseB=Z;github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb;pbb\x06proto3"
+
+var (
+	file_eventbooker_v1_booking_proto_rawDescOnce sync.Once
+	file_eventbooker_v1_booking_proto_rawDescData []byte
+)
+
+func file_eventbooker_v1_booking_proto_rawDescGZIP() []byte {
+	file_eventbooker_v1_booking_proto_rawDescOnce.Do(func() {
+		file_eventbooker_v1_booking_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_eventbooker_v1_booking_proto_rawDesc), len(file_eventbooker_v1_booking_proto_rawDesc)))
+	})
+	return file_eventbooker_v1_booking_proto_rawDescData
+}
+
+var file_eventbooker_v1_booking_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_eventbooker_v1_booking_proto_goTypes = []any{
+	(*Booking)(nil),                  // 0: eventbooker.v1.Booking
+	(*BookingDetails)(nil),           // 1: eventbooker.v1.BookingDetails
+	(*BookSeatsRequest)(nil),         // 2: eventbooker.v1.BookSeatsRequest
+	(*ConfirmBookingRequest)(nil),    // 3: eventbooker.v1.ConfirmBookingRequest
+	(*GetBookingRequest)(nil),        // 4: eventbooker.v1.GetBookingRequest
+	(*ListUserBookingsRequest)(nil),  // 5: eventbooker.v1.ListUserBookingsRequest
+	(*ListEventBookingsRequest)(nil), // 6: eventbooker.v1.ListEventBookingsRequest
+	(*ListBookingsResponse)(nil),     // 7: eventbooker.v1.ListBookingsResponse
+	(*CancelBookingRequest)(nil),     // 8: eventbooker.v1.CancelBookingRequest
+	(*CancelBookingResponse)(nil),    // 9: eventbooker.v1.CancelBookingResponse
+	(*timestamppb.Timestamp)(nil),    // 10: google.protobuf.Timestamp
+	(*Event)(nil),                    // 11: eventbooker.v1.Event
+	(*User)(nil),                     // 12: eventbooker.v1.User
+	(*durationpb.Duration)(nil),      // 13: google.protobuf.Duration
+}
+var file_eventbooker_v1_booking_proto_depIdxs = []int32{
+	10, // 0: eventbooker.v1.Booking.expires_at:type_name -> google.protobuf.Timestamp
+	10, // 1: eventbooker.v1.Booking.created_at:type_name -> google.protobuf.Timestamp
+	10, // 2: eventbooker.v1.Booking.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: eventbooker.v1.BookingDetails.booking:type_name -> eventbooker.v1.Booking
+	11, // 4: eventbooker.v1.BookingDetails.event:type_name -> eventbooker.v1.Event
+	12, // 5: eventbooker.v1.BookingDetails.user:type_name -> eventbooker.v1.User
+	13, // 6: eventbooker.v1.BookingDetails.time_left:type_name -> google.protobuf.Duration
+	0,  // 7: eventbooker.v1.ListBookingsResponse.bookings:type_name -> eventbooker.v1.Booking
+	0,  // 8: eventbooker.v1.CancelBookingResponse.booking:type_name -> eventbooker.v1.Booking
+	2,  // 9: eventbooker.v1.BookingService.BookSeats:input_type -> eventbooker.v1.BookSeatsRequest
+	3,  // 10: eventbooker.v1.BookingService.ConfirmBooking:input_type -> eventbooker.v1.ConfirmBookingRequest
+	4,  // 11: eventbooker.v1.BookingService.GetBooking:input_type -> eventbooker.v1.GetBookingRequest
+	5,  // 12: eventbooker.v1.BookingService.ListUserBookings:input_type -> eventbooker.v1.ListUserBookingsRequest
+	6,  // 13: eventbooker.v1.BookingService.ListEventBookings:input_type -> eventbooker.v1.ListEventBookingsRequest
+	8,  // 14: eventbooker.v1.BookingService.CancelBooking:input_type -> eventbooker.v1.CancelBookingRequest
+	0,  // 15: eventbooker.v1.BookingService.BookSeats:output_type -> eventbooker.v1.Booking
+	0,  // 16: eventbooker.v1.BookingService.ConfirmBooking:output_type -> eventbooker.v1.Booking
+	1,  // 17: eventbooker.v1.BookingService.GetBooking:output_type -> eventbooker.v1.BookingDetails
+	7,  // 18: eventbooker.v1.BookingService.ListUserBookings:output_type -> eventbooker.v1.ListBookingsResponse
+	7,  // 19: eventbooker.v1.BookingService.ListEventBookings:output_type -> eventbooker.v1.ListBookingsResponse
+	9,  // 20: eventbooker.v1.BookingService.CancelBooking:output_type -> eventbooker.v1.CancelBookingResponse
+	15, // [15:21] is the sub-list for method output_type
+	9,  // [9:15] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_eventbooker_v1_booking_proto_init() }
+func file_eventbooker_v1_booking_proto_init() {
+	if File_eventbooker_v1_booking_proto != nil {
+		return
+	}
+	file_eventbooker_v1_event_proto_init()
+	file_eventbooker_v1_user_proto_init()
+	file_eventbooker_v1_booking_proto_msgTypes[0].OneofWrappers = []any{}
+	file_eventbooker_v1_booking_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventbooker_v1_booking_proto_rawDesc), len(file_eventbooker_v1_booking_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_eventbooker_v1_booking_proto_goTypes,
+		DependencyIndexes: file_eventbooker_v1_booking_proto_depIdxs,
+		MessageInfos:      file_eventbooker_v1_booking_proto_msgTypes,
+	}.Build()
+	File_eventbooker_v1_booking_proto = out.File
+	file_eventbooker_v1_booking_proto_goTypes = nil
+	file_eventbooker_v1_booking_proto_depIdxs = nil
+}
@@ -0,0 +1,496 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: eventbooker/v1/event.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Event struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title          string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description    string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Location       string                 `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	VenueId        *int64                 `protobuf:"varint,5,opt,name=venue_id,json=venueId,proto3,oneof" json:"venue_id,omitempty"`
+	Date           *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=date,proto3" json:"date,omitempty"`
+	TotalSeats     int32                  `protobuf:"varint,7,opt,name=total_seats,json=totalSeats,proto3" json:"total_seats,omitempty"`
+	AvailableSeats int32                  `protobuf:"varint,8,opt,name=available_seats,json=availableSeats,proto3" json:"available_seats,omitempty"`
+	BookedSeats    int32                  `protobuf:"varint,9,opt,name=booked_seats,json=bookedSeats,proto3" json:"booked_seats,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_eventbooker_v1_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Event) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Event) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Event) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Event) GetVenueId() int64 {
+	if x != nil && x.VenueId != nil {
+		return *x.VenueId
+	}
+	return 0
+}
+
+func (x *Event) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *Event) GetTotalSeats() int32 {
+	if x != nil {
+		return x.TotalSeats
+	}
+	return 0
+}
+
+func (x *Event) GetAvailableSeats() int32 {
+	if x != nil {
+		return x.AvailableSeats
+	}
+	return 0
+}
+
+func (x *Event) GetBookedSeats() int32 {
+	if x != nil {
+		return x.BookedSeats
+	}
+	return 0
+}
+
+func (x *Event) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Event) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CreateEventRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Title       string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Location    string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	VenueId     *int64                 `protobuf:"varint,4,opt,name=venue_id,json=venueId,proto3,oneof" json:"venue_id,omitempty"`
+	Date        *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=date,proto3" json:"date,omitempty"`
+	TotalSeats  int32                  `protobuf:"varint,6,opt,name=total_seats,json=totalSeats,proto3" json:"total_seats,omitempty"`
+	// Политика отмены, по умолчанию как в REST
+	FreeCancellationHours *int32   `protobuf:"varint,7,opt,name=free_cancellation_hours,json=freeCancellationHours,proto3,oneof" json:"free_cancellation_hours,omitempty"`
+	LateRefundPercent     *float64 `protobuf:"fixed64,8,opt,name=late_refund_percent,json=lateRefundPercent,proto3,oneof" json:"late_refund_percent,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *CreateEventRequest) Reset() {
+	*x = CreateEventRequest{}
+	mi := &file_eventbooker_v1_event_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateEventRequest) ProtoMessage() {}
+
+func (x *CreateEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_event_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateEventRequest.ProtoReflect.Descriptor instead.
+func (*CreateEventRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_event_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateEventRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateEventRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateEventRequest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *CreateEventRequest) GetVenueId() int64 {
+	if x != nil && x.VenueId != nil {
+		return *x.VenueId
+	}
+	return 0
+}
+
+func (x *CreateEventRequest) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *CreateEventRequest) GetTotalSeats() int32 {
+	if x != nil {
+		return x.TotalSeats
+	}
+	return 0
+}
+
+func (x *CreateEventRequest) GetFreeCancellationHours() int32 {
+	if x != nil && x.FreeCancellationHours != nil {
+		return *x.FreeCancellationHours
+	}
+	return 0
+}
+
+func (x *CreateEventRequest) GetLateRefundPercent() float64 {
+	if x != nil && x.LateRefundPercent != nil {
+		return *x.LateRefundPercent
+	}
+	return 0
+}
+
+type GetEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEventRequest) Reset() {
+	*x = GetEventRequest{}
+	mi := &file_eventbooker_v1_event_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEventRequest) ProtoMessage() {}
+
+func (x *GetEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_event_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEventRequest.ProtoReflect.Descriptor instead.
+func (*GetEventRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_event_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetEventRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Только мероприятия площадки
+	VenueId       *int64 `protobuf:"varint,1,opt,name=venue_id,json=venueId,proto3,oneof" json:"venue_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEventsRequest) Reset() {
+	*x = ListEventsRequest{}
+	mi := &file_eventbooker_v1_event_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEventsRequest) ProtoMessage() {}
+
+func (x *ListEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_event_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListEventsRequest) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_event_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListEventsRequest) GetVenueId() int64 {
+	if x != nil && x.VenueId != nil {
+		return *x.VenueId
+	}
+	return 0
+}
+
+type ListEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*Event               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEventsResponse) Reset() {
+	*x = ListEventsResponse{}
+	mi := &file_eventbooker_v1_event_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEventsResponse) ProtoMessage() {}
+
+func (x *ListEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventbooker_v1_event_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEventsResponse.ProtoReflect.Descriptor instead.
+func (*ListEventsResponse) Descriptor() ([]byte, []int) {
+	return file_eventbooker_v1_event_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListEventsResponse) GetEvents() []*Event {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+var File_eventbooker_v1_event_proto protoreflect.FileDescriptor
+
+const file_eventbooker_v1_event_proto_rawDesc = "" +
+	"\n" +
+	"\x1aeventbooker/v1/event.proto\x12\x0eeventbooker.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xab\x03\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\x12\x1e\n" +
+	"\bvenue_id\x18\x05 \x01(\x03H\x00R\avenueId\x88\x01\x01\x12.\n" +
+	"\x04date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x1f\n" +
+	"\vtotal_seats\x18\a \x01(\x05R\n" +
+	"totalSeats\x12'\n" +
+	"\x0favailable_seats\x18\b \x01(\x05R\x0eavailableSeats\x12!\n" +
+	"\fbooked_seats\x18\t \x01(\x05R\vbookedSeats\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\v\n" +
+	"\t_venue_id\"\x8c\x03\n" +
+	"\x12CreateEventRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\blocation\x18\x03 \x01(\tR\blocation\x12\x1e\n" +
+	"\bvenue_id\x18\x04 \x01(\x03H\x00R\avenueId\x88\x01\x01\x12.\n" +
+	"\x04date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x1f\n" +
+	"\vtotal_seats\x18\x06 \x01(\x05R\n" +
+	"totalSeats\x12;\n" +
+	"\x17free_cancellation_hours\x18\a \x01(\x05H\x01R\x15freeCancellationHours\x88\x01\x01\x123\n" +
+	"\x13late_refund_percent\x18\b \x01(\x01H\x02R\x11lateRefundPercent\x88\x01\x01B\v\n" +
+	"\t_venue_idB\x1a\n" +
+	"\x18_free_cancellation_hoursB\x16\n" +
+	"\x14_late_refund_percent\"!\n" +
+	"\x0fGetEventRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"@\n" +
+	"\x11ListEventsRequest\x12\x1e\n" +
+	"\bvenue_id\x18\x01 \x01(\x03H\x00R\avenueId\x88\x01\x01B\v\n" +
+	"\t_venue_id\"C\n" +
+	"\x12ListEventsResponse\x12-\n" +
+	"\x06events\x18\x01 \x03(\v2\x15.eventbooker.v1.EventR\x06events2\xf1\x01\n" +
+	"\fEventService\x12H\n" +
+	"\vCreateEvent\x12\".eventbooker.v1.CreateEventRequest\x1a\x15.eventbooker.v1.Event\x12B\n" +
+	"\bGetEvent\x12\x1f.eventbooker.v1.GetEventRequest\x1a\x15.eventbooker.v1.Event\x12S\n" +
+	"\n" +
+	"ListEvents\x12!.eventbooker.v1.ListEventsRequest\x1a\".eventbooker.v1.ListEventsResponseB=Z;github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb;pbb\x06proto3"
+
+var (
+	file_eventbooker_v1_event_proto_rawDescOnce sync.Once
+	file_eventbooker_v1_event_proto_rawDescData []byte
+)
+
+func file_eventbooker_v1_event_proto_rawDescGZIP() []byte {
+	file_eventbooker_v1_event_proto_rawDescOnce.Do(func() {
+		file_eventbooker_v1_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_eventbooker_v1_event_proto_rawDesc), len(file_eventbooker_v1_event_proto_rawDesc)))
+	})
+	return file_eventbooker_v1_event_proto_rawDescData
+}
+
+var file_eventbooker_v1_event_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_eventbooker_v1_event_proto_goTypes = []any{
+	(*Event)(nil),                 // 0: eventbooker.v1.Event
+	(*CreateEventRequest)(nil),    // 1: eventbooker.v1.CreateEventRequest
+	(*GetEventRequest)(nil),       // 2: eventbooker.v1.GetEventRequest
+	(*ListEventsRequest)(nil),     // 3: eventbooker.v1.ListEventsRequest
+	(*ListEventsResponse)(nil),    // 4: eventbooker.v1.ListEventsResponse
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_eventbooker_v1_event_proto_depIdxs = []int32{
+	5, // 0: eventbooker.v1.Event.date:type_name -> google.protobuf.Timestamp
+	5, // 1: eventbooker.v1.Event.created_at:type_name -> google.protobuf.Timestamp
+	5, // 2: eventbooker.v1.Event.updated_at:type_name -> google.protobuf.Timestamp
+	5, // 3: eventbooker.v1.CreateEventRequest.date:type_name -> google.protobuf.Timestamp
+	0, // 4: eventbooker.v1.ListEventsResponse.events:type_name -> eventbooker.v1.Event
+	1, // 5: eventbooker.v1.EventService.CreateEvent:input_type -> eventbooker.v1.CreateEventRequest
+	2, // 6: eventbooker.v1.EventService.GetEvent:input_type -> eventbooker.v1.GetEventRequest
+	3, // 7: eventbooker.v1.EventService.ListEvents:input_type -> eventbooker.v1.ListEventsRequest
+	0, // 8: eventbooker.v1.EventService.CreateEvent:output_type -> eventbooker.v1.Event
+	0, // 9: eventbooker.v1.EventService.GetEvent:output_type -> eventbooker.v1.Event
+	4, // 10: eventbooker.v1.EventService.ListEvents:output_type -> eventbooker.v1.ListEventsResponse
+	8, // [8:11] is the sub-list for method output_type
+	5, // [5:8] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_eventbooker_v1_event_proto_init() }
+func file_eventbooker_v1_event_proto_init() {
+	if File_eventbooker_v1_event_proto != nil {
+		return
+	}
+	file_eventbooker_v1_event_proto_msgTypes[0].OneofWrappers = []any{}
+	file_eventbooker_v1_event_proto_msgTypes[1].OneofWrappers = []any{}
+	file_eventbooker_v1_event_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventbooker_v1_event_proto_rawDesc), len(file_eventbooker_v1_event_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_eventbooker_v1_event_proto_goTypes,
+		DependencyIndexes: file_eventbooker_v1_event_proto_depIdxs,
+		MessageInfos:      file_eventbooker_v1_event_proto_msgTypes,
+	}.Build()
+	File_eventbooker_v1_event_proto = out.File
+	file_eventbooker_v1_event_proto_goTypes = nil
+	file_eventbooker_v1_event_proto_depIdxs = nil
+}
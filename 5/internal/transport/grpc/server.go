@@ -0,0 +1,62 @@
+// Package grpc - gRPC API сервиса рядом с REST: те же сервисы, права доступа и ошибки,
+// что у обработчиков gin. Описание API - proto/eventbooker/v1, сгенерированный код - pb (make proto).
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/sirupsen/logrus"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type Server struct {
+	server *grpclib.Server
+	port   string
+}
+
+func NewServer(port string, jwtManager *middleware.JWTManager, eventService service.EventService, bookingService service.BookingService, userService service.UserService) *Server {
+	server := grpclib.NewServer(grpclib.ChainUnaryInterceptor(
+		loggingInterceptor,
+		recoveryInterceptor,
+		authInterceptor(jwtManager),
+	))
+
+	pb.RegisterEventServiceServer(server, &eventServer{eventService: eventService})
+	pb.RegisterBookingServiceServer(server, &bookingServer{bookingService: bookingService})
+	pb.RegisterUserServiceServer(server, &userServer{userService: userService, jwtManager: jwtManager})
+	// Описание сервисов для grpcurl и подобных клиентов
+	reflection.Register(server)
+
+	return &Server{server: server, port: port}
+}
+
+func (s *Server) Run() error {
+	listener, err := net.Listen("tcp", ":"+s.port)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("gRPC server listening on :%s", s.port)
+	return s.server.Serve(listener)
+}
+
+// Shutdown перестаёт принимать вызовы и ждёт текущие; по истечении ctx обрывает их
+func (s *Server) Shutdown(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
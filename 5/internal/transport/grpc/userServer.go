@@ -0,0 +1,114 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/grpc/pb"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	userService service.UserService
+	jwtManager  *middleware.JWTManager
+}
+
+func (s *userServer) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.User, error) {
+	registerReq := &service.RegisterUserRequest{
+		Email:      req.GetEmail(),
+		Name:       req.GetName(),
+		Password:   req.GetPassword(),
+		TelegramID: req.GetTelegramId(),
+	}
+	if err := validate(registerReq); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.RegisterUser(ctx, registerReq)
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+
+	return toPBUser(user), nil
+}
+
+// GetUser возвращает профиль; чужой - только администратору
+func (s *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	if err := requireOwnerOrAdmin(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetUserByID(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err, codes.NotFound)
+	}
+
+	return toPBUser(user), nil
+}
+
+// LinkTelegram привязывает чат бота, который управляет бронированиями; привязать может сам пользователь или администратор
+func (s *userServer) LinkTelegram(ctx context.Context, req *pb.LinkTelegramRequest) (*pb.User, error) {
+	if err := requireOwnerOrAdmin(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	if req.GetTelegramId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "telegram_id is required")
+	}
+
+	if err := s.userService.LinkTelegram(ctx, req.GetUserId(), req.GetTelegramId()); err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	user, err := s.userService.GetUserByID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBUser(user), nil
+}
+
+// UpdateNotificationPreferences меняет каналы уведомлений; изменить их может сам пользователь или администратор
+func (s *userServer) UpdateNotificationPreferences(ctx context.Context, req *pb.UpdateNotificationPreferencesRequest) (*pb.User, error) {
+	if err := requireOwnerOrAdmin(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.UpdateNotificationPreferences(ctx, req.GetUserId(), &service.NotificationPreferencesRequest{
+		Email:    req.Email,
+		Telegram: req.Telegram,
+	})
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	return toPBUser(user), nil
+}
+
+func (s *userServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	user, err := s.userService.Authenticate(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, statusError(err, codes.Internal)
+	}
+
+	token, expiresAt, err := s.jwtManager.GenerateToken(user)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.LoginResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   timestamppb.New(expiresAt),
+		User:        toPBUser(user),
+	}, nil
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness отвечает, пока процесс обслуживает запросы; зависимости не проверяет,
+// чтобы оркестратор не перезапускал сервис из-за упавшей базы
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": health.StatusUp,
+		"time":   time.Now(),
+	})
+}
+
+// Readiness отдает состояние каждой зависимости по последней фоновой проверке
+// и 503, если недоступна критичная. Сам запрос зависимости не дёргает.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.checker.Last(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrationHandler - API внешних интеграций организаторов; запросы приходят с API-токеном,
+// все операции выполняются от имени организатора, которому выдан токен
+type IntegrationHandler struct {
+	eventService   service.EventService
+	bookingService service.BookingService
+}
+
+func NewIntegrationHandler(eventService service.EventService, bookingService service.BookingService) *IntegrationHandler {
+	return &IntegrationHandler{
+		eventService:   eventService,
+		bookingService: bookingService,
+	}
+}
+
+// CreateEvent создаёт мероприятие, закреплённое за организатором токена
+func (h *IntegrationHandler) CreateEvent(c *gin.Context) {
+	organizerID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	var req service.CreateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.OrganizerID = &organizerID
+
+	event, err := h.eventService.CreateEvent(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(eventErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// GetBookings возвращает бронирования на мероприятия организатора, ?event_id= сужает выборку
+func (h *IntegrationHandler) GetBookings(c *gin.Context) {
+	organizerID, ok := middleware.UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": entity.ErrUnauthorized.Error()})
+		return
+	}
+
+	var eventID *int64
+	if param := c.Query("event_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+			return
+		}
+		eventID = &id
+	}
+
+	bookings, err := h.bookingService.GetOrganizerBookings(c.Request.Context(), organizerID, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookings)
+}
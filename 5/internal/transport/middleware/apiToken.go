@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// ContextAPIToken - ключ контекста gin, под которым APITokenAuth сохраняет токен интеграции
+const ContextAPIToken = "api_token"
+
+// APITokenAuthenticator проверяет открытое значение токена интеграции
+type APITokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*entity.APIToken, error)
+}
+
+// APITokenAuth требует Authorization: Bearer <api token>, ограничивает частоту запросов
+// по лимиту токена и кладёт в контекст токен и ID организатора как ContextUserID.
+func APITokenAuth(authenticator APITokenAuthenticator) gin.HandlerFunc {
+	limiters := newTokenLimiters()
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		plain, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || plain == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="integrations"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		token, err := authenticator.Authenticate(c.Request.Context(), strings.TrimSpace(plain))
+		if err != nil {
+			if errors.Is(err, entity.ErrInvalidAPIToken) {
+				c.Header("WWW-Authenticate", `Bearer realm="integrations", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(token.RateLimit))
+		if wait := limiters.reserve(token); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(wait))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "api token rate limit exceeded"})
+			return
+		}
+
+		c.Set(ContextAPIToken, token)
+		c.Set(ContextUserID, token.OrganizerID)
+		c.Request = c.Request.WithContext(entity.WithAuditActor(c.Request.Context(),
+			entity.AuditActor{ID: &token.OrganizerID, Kind: entity.ActorAPIToken}))
+
+		c.Next()
+	}
+}
+
+// RequireScope пропускает запрос, только если токену выдано право. Должен стоять после APITokenAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := APITokenFromContext(c)
+		if !ok || !token.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": entity.ErrInsufficientScope.Error(), "scope": scope})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// APITokenFromContext возвращает токен интеграции, установленный APITokenAuth
+func APITokenFromContext(c *gin.Context) (*entity.APIToken, bool) {
+	value, exists := c.Get(ContextAPIToken)
+	if !exists {
+		return nil, false
+	}
+
+	token, ok := value.(*entity.APIToken)
+	return token, ok
+}
+
+// tokenLimiters хранит token bucket на каждый токен: RateLimit запросов в минуту
+// с запасом на всю минуту сразу. Лимиты живут в памяти экземпляра сервиса.
+type tokenLimiters struct {
+	mu       sync.Mutex
+	limiters map[int64]*rate.Limiter
+}
+
+func newTokenLimiters() *tokenLimiters {
+	return &tokenLimiters{limiters: make(map[int64]*rate.Limiter)}
+}
+
+// reserve списывает запрос и возвращает 0 или через сколько секунд повторить
+func (l *tokenLimiters) reserve(token *entity.APIToken) int {
+	if token.RateLimit <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	limiter, ok := l.limiters[token.ID]
+	if !ok || limiter.Burst() != token.RateLimit {
+		limiter = rate.NewLimiter(rate.Limit(float64(token.RateLimit)/60), token.RateLimit)
+		l.limiters[token.ID] = limiter
+	}
+	l.mu.Unlock()
+
+	reservation := limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return 0
+	}
+
+	reservation.Cancel()
+	return int(math.Ceil(delay.Seconds()))
+}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Ключи контекста gin, под которыми Auth сохраняет данные пользователя
+	ContextUserID    = "user_id"
+	ContextUserEmail = "user_email"
+	ContextUserRole  = "user_role"
+
+	// ID администратора, если запрос выполняется по токену имперсонации
+	ContextImpersonatorID = "impersonator_id"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims описывает полезную нагрузку токена доступа
+type Claims struct {
+	UserID int64  `json:"uid"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+
+	// Администратор, действующий от имени пользователя; 0 в обычном токене
+	ImpersonatorID int64 `json:"imp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AuditActor возвращает инициатора изменений для журнала аудита
+func (c *Claims) AuditActor() entity.AuditActor {
+	actor := entity.AuditActor{ID: &c.UserID, Kind: c.Role}
+	if c.ImpersonatorID > 0 {
+		actor.ImpersonatorID = &c.ImpersonatorID
+	}
+	return actor
+}
+
+// JWTManager выпускает и проверяет токены, подписанные HS256
+type JWTManager struct {
+	secret                  []byte
+	expiration              time.Duration
+	impersonationExpiration time.Duration
+}
+
+func NewJWTManager(cfg config.JWTConfig) *JWTManager {
+	expiration := cfg.Expiration
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+
+	impersonationExpiration := cfg.ImpersonationExpiration
+	if impersonationExpiration <= 0 {
+		impersonationExpiration = 30 * time.Minute
+	}
+
+	return &JWTManager{
+		secret:                  []byte(cfg.Secret),
+		expiration:              expiration,
+		impersonationExpiration: impersonationExpiration,
+	}
+}
+
+// GenerateToken выпускает токен для пользователя и возвращает момент его истечения
+func (m *JWTManager) GenerateToken(user *entity.User) (string, time.Time, error) {
+	return m.generate(user, 0, m.expiration)
+}
+
+// GenerateImpersonationToken выпускает короткоживущий токен, по которому администратор
+// adminID действует от имени user: права берутся у user, а журнал аудита и уведомления
+// отмечают, что изменение выполнил сотрудник
+func (m *JWTManager) GenerateImpersonationToken(user *entity.User, adminID int64) (string, time.Time, error) {
+	return m.generate(user, adminID, m.impersonationExpiration)
+}
+
+func (m *JWTManager) generate(user *entity.User, impersonatorID int64, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		Role:           user.Role,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// ParseToken проверяет подпись и срок действия токена
+func (m *JWTManager) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.UserID <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// Auth требует заголовок Authorization: Bearer <token> и кладёт данные пользователя в контекст
+func Auth(manager *JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenString == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims, err := manager.ParseToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(ContextUserID, claims.UserID)
+		c.Set(ContextUserEmail, claims.Email)
+		c.Set(ContextUserRole, claims.Role)
+
+		if claims.ImpersonatorID > 0 {
+			c.Set(ContextImpersonatorID, claims.ImpersonatorID)
+		}
+		c.Request = c.Request.WithContext(entity.WithAuditActor(c.Request.Context(), claims.AuditActor()))
+
+		c.Next()
+	}
+}
+
+// WebSocketTokenProtocol - подпротокол, которым браузерный WebSocket передаёт токен:
+// new WebSocket(url, ["bearer", token])
+const WebSocketTokenProtocol = "bearer"
+
+// WebSocketToken переносит токен из Sec-WebSocket-Protocol в заголовок Authorization: браузер
+// не даёт задать заголовки WebSocket, а токен в адресе попал бы в журналы. Ставится перед Auth.
+func WebSocketToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+			if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == WebSocketTokenProtocol {
+				c.Request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(protocols[1]))
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole пропускает запрос только если роль пользователя входит в список.
+// Должен стоять после Auth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(ContextUserRole)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+	}
+}
+
+// UserIDFromContext возвращает ID пользователя, установленный Auth
+func UserIDFromContext(c *gin.Context) (int64, bool) {
+	value, exists := c.Get(ContextUserID)
+	if !exists {
+		return 0, false
+	}
+
+	userID, ok := value.(int64)
+	return userID, ok
+}
+
+// ImpersonatorIDFromContext возвращает ID администратора, если запрос выполняется
+// по токену имперсонации
+func ImpersonatorIDFromContext(c *gin.Context) (int64, bool) {
+	value, exists := c.Get(ContextImpersonatorID)
+	if !exists {
+		return 0, false
+	}
+
+	adminID, ok := value.(int64)
+	return adminID, ok
+}
@@ -1,35 +1,260 @@
-package middleware
-
-import (
-	"time"
-
-	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
-)
-
-func Logger() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-
-		// Process request
-		c.Next()
-
-		// Log after request is processed
-		duration := time.Since(start)
-
-		entry := logrus.WithFields(logrus.Fields{
-			"method":     c.Request.Method,
-			"path":       c.Request.URL.Path,
-			"status":     c.Writer.Status(),
-			"duration":   duration,
-			"client_ip":  c.ClientIP(),
-			"user_agent": c.Request.UserAgent(),
-		})
-
-		if c.Writer.Status() >= 400 {
-			entry.Error("Request failed")
-		} else {
-			entry.Info("Request processed")
-		}
-	}
-}
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoggerConfig настраивает журналирование запросов
+type LoggerConfig struct {
+	// LogBodies включает запись тел запроса и ответа
+	LogBodies bool
+	// MaxBodySize ограничивает размер записываемого тела в байтах
+	MaxBodySize int
+	// RedactEmails маскирует email-адреса в телах
+	RedactEmails bool
+	// RedactFields — JSON-поля, значения которых всегда маскируются: персональные данные
+	// (email, telegram_id, ...) и секреты (токены доступа, пароли)
+	RedactFields []string
+	// SampleRate — доля успешных запросов, попадающих в журнал (0..1); при 0 пишутся
+	// только ошибки и медленные запросы
+	SampleRate float64
+	// RouteSampleRates переопределяет SampleRate для маршрутов вида "GET /api/v1/events"
+	RouteSampleRates map[string]float64
+	// SlowThreshold — запросы дольше порога журналируются всегда
+	SlowThreshold time.Duration
+}
+
+// DefaultLoggerConfig журналирует каждый запрос без тел
+func DefaultLoggerConfig() LoggerConfig {
+	return LoggerConfig{
+		MaxBodySize:  4096,
+		RedactEmails: true,
+		RedactFields: []string{
+			"email", "telegram_id", "chat_id", "password",
+			"access_token", "refresh_token", "token", "secret",
+		},
+		SampleRate: 1,
+	}
+}
+
+const redacted = "[REDACTED]"
+
+var emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+
+func Logger() gin.HandlerFunc {
+	return LoggerWithConfig(DefaultLoggerConfig())
+}
+
+func LoggerWithConfig(cfg LoggerConfig) gin.HandlerFunc {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 4096
+	}
+
+	redactFields := make(map[string]struct{}, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redactFields[strings.ToLower(field)] = struct{}{}
+	}
+
+	// viper приводит ключи карт к нижнему регистру, поэтому сравниваем без учета регистра
+	routeRates := make(map[string]float64, len(cfg.RouteSampleRates))
+	for route, rate := range cfg.RouteSampleRates {
+		routeRates[strings.ToLower(route)] = rate
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var requestBody []byte
+		if cfg.LogBodies && c.Request.Body != nil {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.MaxBodySize)+1))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), c.Request.Body))
+		}
+
+		var recorder *bodyRecorder
+		if cfg.LogBodies {
+			recorder = &bodyRecorder{ResponseWriter: c.Writer, limit: cfg.MaxBodySize}
+			c.Writer = recorder
+		}
+
+		// Process request
+		c.Next()
+
+		// Log after request is processed
+		duration := time.Since(start)
+		status := c.Writer.Status()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		if !shouldLog(cfg, routeRates, c.Request.Method+" "+route, status, duration) {
+			return
+		}
+
+		fields := logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      route,
+			"status":     status,
+			"duration":   duration,
+			"latency_ms": duration.Milliseconds(),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+			"bytes_out":  c.Writer.Size(),
+		}
+
+		if id := requestid.FromContext(c.Request.Context()); id != "" {
+			fields[requestid.Field] = id
+		}
+
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			fields["trace_id"] = span.TraceID().String()
+		}
+
+		if userID := requestUserID(c); userID != "" {
+			fields["user_id"] = userID
+		}
+
+		if cfg.LogBodies {
+			if len(requestBody) > 0 {
+				fields["request_body"] = redactBody(requestBody, cfg.MaxBodySize, cfg.RedactEmails, redactFields)
+			}
+			if recorder.body.Len() > 0 {
+				fields["response_body"] = redactBody(recorder.body.Bytes(), cfg.MaxBodySize, cfg.RedactEmails, redactFields)
+			}
+		}
+
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+
+		entry := logrus.WithFields(fields)
+
+		if status >= 400 {
+			entry.Error("Request failed")
+		} else {
+			entry.Info("Request processed")
+		}
+	}
+}
+
+// shouldLog решает, попадает ли запрос в журнал: ошибки и медленные запросы
+// пишутся всегда, остальные — с вероятностью, заданной для маршрута
+func shouldLog(cfg LoggerConfig, routeRates map[string]float64, route string, status int, duration time.Duration) bool {
+	if status >= 400 {
+		return true
+	}
+	if cfg.SlowThreshold > 0 && duration >= cfg.SlowThreshold {
+		return true
+	}
+
+	rate, ok := routeRates[strings.ToLower(route)]
+	if !ok {
+		rate = cfg.SampleRate
+	}
+
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return rand.Float64() < rate
+}
+
+// requestUserID берет ID пользователя из контекста (его кладет аутентификация) или из параметров маршрута
+func requestUserID(c *gin.Context) string {
+	if v, ok := c.Get(ContextUserID); ok {
+		switch id := v.(type) {
+		case string:
+			return id
+		case int64:
+			return strconv.FormatInt(id, 10)
+		}
+	}
+	return c.Param("user_id")
+}
+
+// redactBody маскирует персональные данные в теле: значения перечисленных JSON-полей
+// и email-адреса в любом месте текста
+func redactBody(body []byte, limit int, redactEmails bool, fields map[string]struct{}) string {
+	truncated := false
+	if len(body) > limit {
+		body = body[:limit]
+		truncated = true
+	}
+
+	var payload interface{}
+	if !truncated && json.Unmarshal(body, &payload) == nil {
+		payload = redactJSON(payload, fields)
+		if data, err := json.Marshal(payload); err == nil {
+			body = data
+		}
+	}
+
+	result := string(body)
+	if redactEmails {
+		result = emailPattern.ReplaceAllString(result, redacted)
+	}
+	if truncated {
+		result += "...(truncated)"
+	}
+	return result
+}
+
+func redactJSON(value interface{}, fields map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if _, ok := fields[strings.ToLower(key)]; ok {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(nested, fields)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactJSON(nested, fields)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// bodyRecorder копирует начало тела ответа для журнала
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	if remaining := w.limit + 1 - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// TestLoggerRedactsLoginResponse проверяет, что при записи тел в журнал не попадают
+// пароль из запроса входа, выданный токен доступа и email пользователя
+func TestLoggerRedactsLoginResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	cfg := DefaultLoggerConfig()
+	cfg.LogBodies = true
+
+	router := gin.New()
+	router.Use(LoggerWithConfig(cfg))
+	router.POST("/auth/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": "eyJhbGciOiJIUzI1NiJ9.secret-payload.signature",
+			"token_type":   "Bearer",
+			"user":         gin.H{"id": 1, "email": "admin@example.com"},
+		})
+	})
+
+	body := `{"email":"admin@example.com","password":"hunter2"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body)))
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("request was not logged")
+	}
+
+	logged := entry.Data["request_body"].(string) + entry.Data["response_body"].(string)
+	for _, secret := range []string{"secret-payload", "hunter2", "admin@example.com"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("logged bodies contain %q: %s", secret, logged)
+		}
+	}
+	if !strings.Contains(entry.Data["response_body"].(string), `"token_type":"Bearer"`) {
+		t.Errorf("non-secret fields should stay readable: %s", entry.Data["response_body"])
+	}
+}
+
+// TestLoggerRedactsAPIToken проверяет, что открытое значение выпущенного токена интеграции
+// маскируется, а его префикс для поиска в списке токенов остаётся в журнале
+func TestLoggerRedactsAPIToken(t *testing.T) {
+	fields := make(map[string]struct{})
+	for _, field := range DefaultLoggerConfig().RedactFields {
+		fields[field] = struct{}{}
+	}
+
+	issued := entity.APITokenWithSecret{
+		APIToken: entity.APIToken{ID: 7, Name: "crm", Prefix: "eb_abcd"},
+		Token:    "eb_abcd_plaintext-secret",
+	}
+	body, err := json.Marshal(issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := redactBody(body, 4096, true, fields)
+	if strings.Contains(logged, "plaintext-secret") {
+		t.Errorf("API token was logged: %s", logged)
+	}
+	if !strings.Contains(logged, "eb_abcd") {
+		t.Errorf("token prefix should stay in the log: %s", logged)
+	}
+}
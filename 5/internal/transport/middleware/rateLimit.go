@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitRule - token bucket: Requests запросов за Period, не больше Burst подряд.
+// Нулевой Requests отключает правило.
+type RateLimitRule struct {
+	Requests int
+	Period   time.Duration
+	Burst    int // 0 - равен Requests
+}
+
+func (r RateLimitRule) enabled() bool {
+	return r.Requests > 0 && r.Period > 0
+}
+
+func (r RateLimitRule) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Requests
+}
+
+// RateLimitPolicy - лимиты группы маршрутов: отдельно на IP и на пользователя
+type RateLimitPolicy struct {
+	PerIP   RateLimitRule
+	PerUser RateLimitRule
+}
+
+// tokenBucketScript пополняет корзину за прошедшее время и списывает один запрос.
+// Возвращает {разрешено, через сколько мс повторить, осталось запросов}.
+// Время передаётся из приложения, чтобы скрипт был детерминированным для репликации.
+var tokenBucketScript = redis.NewScript(`
+	local capacity = tonumber(ARGV[1])
+	local rate = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+
+	local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+	local tokens = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if tokens == nil or ts == nil then
+		tokens = capacity
+		ts = now
+	end
+
+	tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+	local allowed = 0
+	local retry = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	else
+		retry = math.ceil((1 - tokens) / rate)
+	end
+
+	redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+	redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate))
+
+	return {allowed, retry, math.floor(tokens)}
+`)
+
+// RateLimiter ограничивает частоту запросов по группам маршрутов. Корзины хранятся в Redis,
+// поэтому лимит общий для всех экземпляров сервиса. Если Redis недоступен, запросы пропускаются.
+type RateLimiter struct {
+	client   redis.Scripter
+	jwt      *JWTManager
+	policies map[string]RateLimitPolicy
+	prefix   string
+	now      func() time.Time
+}
+
+// NewRateLimiter создаёт ограничитель. jwtManager нужен, чтобы узнать пользователя
+// в группах, где Auth стоит на отдельных маршрутах; может быть nil.
+func NewRateLimiter(client redis.Scripter, jwtManager *JWTManager, policies map[string]RateLimitPolicy) *RateLimiter {
+	return &RateLimiter{
+		client:   client,
+		jwt:      jwtManager,
+		policies: policies,
+		prefix:   "event_booking:ratelimit",
+		now:      time.Now,
+	}
+}
+
+// Limit возвращает middleware для группы маршрутов. Без ограничителя или без правил группы
+// запросы проходят без проверки. Пользователь берётся из ContextUserID (Auth, APITokenAuth)
+// или из bearer-токена, если Auth ещё не отработал.
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+	var policy RateLimitPolicy
+	if l != nil {
+		policy = l.policies[group]
+	}
+	if !policy.PerIP.enabled() && !policy.PerUser.enabled() {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		if policy.PerIP.enabled() {
+			key := fmt.Sprintf("%s:%s:ip:%s", l.prefix, group, c.ClientIP())
+			if !l.allow(ctx, c, key, policy.PerIP) {
+				return
+			}
+		}
+
+		if policy.PerUser.enabled() {
+			if userID, ok := l.userID(c); ok {
+				key := fmt.Sprintf("%s:%s:user:%d", l.prefix, group, userID)
+				if !l.allow(ctx, c, key, policy.PerUser) {
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// allow списывает запрос из корзины и при превышении отвечает 429 с Retry-After
+func (l *RateLimiter) allow(ctx context.Context, c *gin.Context, key string, rule RateLimitRule) bool {
+	capacity := rule.burst()
+	perMs := float64(rule.Requests) / float64(rule.Period.Milliseconds())
+
+	result, err := tokenBucketScript.Run(ctx, l.client, []string{key},
+		capacity, strconv.FormatFloat(perMs, 'f', -1, 64), l.now().UnixMilli()).Int64Slice()
+	if err != nil || len(result) != 3 {
+		logrus.Warnf("Rate limiter unavailable, letting request through: %v", err)
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(capacity))
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(result[2], 10))
+
+	if result[0] == 1 {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(float64(result[1]) / 1000))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+	return false
+}
+
+// userID возвращает пользователя запроса, не отклоняя анонимные запросы
+func (l *RateLimiter) userID(c *gin.Context) (int64, bool) {
+	if userID, ok := UserIDFromContext(c); ok {
+		return userID, true
+	}
+	if l.jwt == nil {
+		return 0, false
+	}
+
+	tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return 0, false
+	}
+	claims, err := l.jwt.ParseToken(strings.TrimSpace(tokenString))
+	if err != nil {
+		return 0, false
+	}
+	return claims.UserID, true
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextRequestID - ключ gin.Context с ID запроса
+const ContextRequestID = "request_id"
+
+// RequestID берёт X-Request-ID клиента или балансировщика, если он похож на идентификатор,
+// иначе создаёт новый. ID возвращается в ответе и кладётся в контекст запроса, откуда его
+// берут журнал, запросы к БД и задачи очереди.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set(ContextRequestID, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+
+		c.Next()
+	}
+}
@@ -1,18 +1,30 @@
-package middleware
-
-import (
-	"context"
-	"time"
-
-	"github.com/gin-gonic/gin"
-)
-
-func Timeout(seconds int) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(seconds)*time.Second)
-		defer cancel()
-
-		c.Request = c.Request.WithContext(ctx)
-		c.Next()
-	}
-}
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout ограничивает контекст запроса. Маршруты из skipRoutes (шаблоны вида
+// "/api/v1/events/:id/availability/stream") - долгие потоки, их срок не ограничивается
+func Timeout(seconds int, skipRoutes ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipRoutes))
+	for _, route := range skipRoutes {
+		skip[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(seconds)*time.Second)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,575 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/queue"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/swaggo/swag"
+)
+
+const (
+	apiBasePath = "/api/v1"
+
+	accessPublic = ""
+	accessUser   = "user"
+	accessAdmin  = "admin"
+	// accessAPIToken - токен интеграции организатора из /admin/api-tokens вместо JWT
+	accessAPIToken = "api_token"
+
+	bearerAuth   = "bearerAuth"
+	apiTokenAuth = "apiTokenAuth"
+)
+
+// apiOperation описывает маршрут /api/v1 для OpenAPI. Схемы тел строятся из тех же типов,
+// что принимают и отдают обработчики, поэтому вручную поддерживаются только описания.
+type apiOperation struct {
+	Method  string
+	Path    string // как в gin, относительно /api/v1
+	Tag     string
+	Summary string
+	Access  string
+	Query   []apiParam
+
+	Request     interface{}
+	FileField   string // поле формы multipart/form-data с файлом вместо тела JSON
+	Status      int
+	Response    interface{}
+	ContentType string // для ответов не в JSON
+}
+
+type apiParam struct {
+	Name        string
+	Description string
+	Integer     bool
+}
+
+// apiError - тело ошибки обработчиков: {"error": "..."}
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type loginResponse struct {
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        *entity.User `json:"user"`
+}
+
+type impersonationResponse struct {
+	loginResponse
+	ImpersonatorID int64 `json:"impersonator_id"`
+}
+
+type confirmBookingResponse struct {
+	Message string          `json:"message"`
+	Booking *entity.Booking `json:"booking"`
+}
+
+type notificationPreferencesResponse struct {
+	NotifyEmail    bool                           `json:"notify_email"`
+	NotifyTelegram bool                           `json:"notify_telegram"`
+	Categories     entity.NotificationPreferences `json:"categories"`
+}
+
+type dlqTaskResponse struct {
+	Message string `json:"message"`
+	TaskID  string `json:"task_id"`
+}
+
+var paginationParams = []apiParam{
+	{Name: "status", Description: "pending, confirmed, cancelled или expired"},
+	{Name: "limit", Description: "По умолчанию 50, не больше 100", Integer: true},
+	{Name: "offset", Integer: true},
+}
+
+var apiOperations = []apiOperation{
+	{Method: http.MethodPost, Path: "/auth/login", Tag: "auth", Summary: "Вход по email и паролю, выдаёт JWT",
+		Request: LoginRequest{}, Response: loginResponse{}},
+
+	{Method: http.MethodPost, Path: "/events", Tag: "events", Summary: "Создать мероприятие; sale_schedule задаёт предпродажу по кодам доступа и окно продаж. Организаторы создают мероприятия через /integrations/events", Access: accessAdmin,
+		Request: service.CreateEventRequest{}, Status: http.StatusCreated, Response: entity.Event{}},
+	{Method: http.MethodGet, Path: "/events", Tag: "events", Summary: "Список мероприятий со свободными местами",
+		Query: []apiParam{
+			{Name: "venue_id", Description: "Только мероприятия площадки", Integer: true},
+			{Name: "sort_by", Description: "Поле сортировки: date, title или created_at"},
+			{Name: "sort_order", Description: "Направление сортировки: asc или desc"},
+		},
+		Response: []*entity.EventWithAvailability{}},
+	{Method: http.MethodGet, Path: "/events/:id", Tag: "events", Summary: "Мероприятие со свободными местами и текущей фазой продаж с обратным отсчётом до следующей",
+		Response: entity.EventWithAvailability{}},
+	{Method: http.MethodGet, Path: "/events/:id/tiers", Tag: "events", Summary: "Категории билетов мероприятия",
+		Response: []*entity.TicketTierWithAvailability{}},
+	{Method: http.MethodGet, Path: "/events/:id/cancellation-policy", Tag: "events", Summary: "Политика отмены и её действие на текущий момент",
+		Response: service.CancellationPolicyEvaluation{}},
+	{Method: http.MethodGet, Path: "/events/:id/poster", Tag: "events", Summary: "Афиша мероприятия и статус обработки её вариантов",
+		Response: entity.EventPoster{}},
+	{Method: http.MethodPost, Path: "/events/:id/poster", Tag: "events", Summary: "Загрузить афишу (jpg, png, gif); доступно организатору мероприятия и администратору", Access: accessUser,
+		FileField: "poster", Status: http.StatusAccepted, Response: entity.EventPoster{}},
+	{Method: http.MethodGet, Path: "/events/:id/availability/stream", Tag: "events", Summary: "Поток Server-Sent Events со свободными местами: событие availability сразу и при каждом изменении, deleted при удалении мероприятия; 503, если поток выключен",
+		ContentType: "text/event-stream"},
+
+	{Method: http.MethodGet, Path: "/venues", Tag: "venues", Summary: "Список площадок",
+		Response: []*entity.Venue{}},
+	{Method: http.MethodGet, Path: "/venues/:id", Tag: "venues", Summary: "Площадка",
+		Response: entity.Venue{}},
+
+	{Method: http.MethodPost, Path: "/bookings/events/:id/book", Tag: "bookings", Summary: "Забронировать места на мероприятие; на предпродаже нужен access_code, вне продаж - 409", Access: accessUser,
+		Request: service.BookSeatsRequest{}, Status: http.StatusCreated, Response: entity.Booking{}},
+	{Method: http.MethodPost, Path: "/bookings/events/:id/confirm", Tag: "bookings", Summary: "Подтвердить (оплатить) своё бронирование; чужое - только администратор", Access: accessUser,
+		Request: ConfirmBookingRequest{}, Response: confirmBookingResponse{}},
+	{Method: http.MethodGet, Path: "/bookings/users/:user_id", Tag: "bookings", Summary: "Бронирования пользователя; чужие доступны только администратору", Access: accessUser,
+		Response: []*entity.Booking{}},
+	{Method: http.MethodGet, Path: "/bookings/:id", Tag: "bookings", Summary: "Бронирование с мероприятием и оставшимся временем; чужое доступно только администратору", Access: accessUser,
+		Response: service.BookingDetails{}},
+	{Method: http.MethodGet, Path: "/bookings/:id/ticket.png", Tag: "bookings", Summary: "QR-код билета подтверждённого бронирования; чужой доступен только администратору", Access: accessUser,
+		ContentType: "image/png"},
+
+	{Method: http.MethodGet, Path: "/cart", Tag: "cart", Summary: "Открытая корзина пользователя", Access: accessUser,
+		Response: entity.Cart{}},
+	{Method: http.MethodPost, Path: "/cart/items", Tag: "cart", Summary: "Добавить мероприятие в корзину; места не резервируются до оформления", Access: accessUser,
+		Request: service.AddCartItemRequest{}, Status: http.StatusCreated, Response: entity.Cart{}},
+	{Method: http.MethodDelete, Path: "/cart/items/:id", Tag: "cart", Summary: "Убрать позицию из корзины", Access: accessUser,
+		Response: entity.Cart{}},
+	{Method: http.MethodDelete, Path: "/cart", Tag: "cart", Summary: "Очистить корзину", Access: accessUser,
+		Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/cart/checkout", Tag: "cart", Summary: "Оформить корзину: бронирования создаются все сразу или ни одного", Access: accessUser,
+		Request: service.CheckoutCartRequest{}, Status: http.StatusCreated, Response: cartCheckoutResponse{}},
+
+	{Method: http.MethodPost, Path: "/users/register", Tag: "users", Summary: "Регистрация",
+		Request: service.RegisterUserRequest{}, Status: http.StatusCreated, Response: entity.User{}},
+	{Method: http.MethodGet, Path: "/users/:id", Tag: "users", Summary: "Пользователь; чужой профиль доступен только администратору", Access: accessUser,
+		Response: entity.User{}},
+	{Method: http.MethodPost, Path: "/users/:id/telegram", Tag: "users", Summary: "Привязать Telegram; чат бота управляет бронированиями, поэтому привязывает сам пользователь или администратор", Access: accessUser,
+		Request: LinkTelegramRequest{}, Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/users/:id/notifications", Tag: "users", Summary: "Каналы уведомлений по категориям: booking_updates, reminders, marketing; видит сам пользователь или администратор", Access: accessUser,
+		Response: notificationPreferencesResponse{}},
+	{Method: http.MethodPut, Path: "/users/:id/notifications", Tag: "users", Summary: "Каналы уведомлений, общие и по категориям; меняет сам пользователь или администратор", Access: accessUser,
+		Request: service.NotificationPreferencesRequest{}, Response: notificationPreferencesResponse{}},
+	{Method: http.MethodGet, Path: "/users/:id/stats", Tag: "users", Summary: "Статистика пользователя: любимые мероприятия, посещаемость и лояльность; видит сам пользователь или администратор", Access: accessUser,
+		Response: entity.UserStats{}},
+	{Method: http.MethodGet, Path: "/users/:id/calendar", Tag: "calendar", Summary: "Ссылка на подписку календаря", Access: accessUser,
+		Response: service.CalendarSubscription{}},
+	{Method: http.MethodPost, Path: "/users/:id/calendar/revoke", Tag: "calendar", Summary: "Отозвать ссылки календаря и выдать новую", Access: accessUser,
+		Response: service.CalendarSubscription{}},
+	{Method: http.MethodGet, Path: "/users/:id/calendar.ics", Tag: "calendar", Summary: "iCalendar-фид подтверждённых бронирований",
+		Query:       []apiParam{{Name: "token", Description: "Подписанный токен из ссылки на подписку"}},
+		ContentType: "text/calendar"},
+
+	{Method: http.MethodGet, Path: "/admin/stats", Tag: "admin", Summary: "Общая статистика системы", Access: accessAdmin,
+		Response: entity.SystemStats{}},
+	{Method: http.MethodGet, Path: "/admin/stats/trends", Tag: "admin", Summary: "Тренды бронирований для графиков: подтверждения и отмены - по дате смены статуса", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "period", Description: "day (по умолчанию), week или month"},
+			{Name: "from", Description: "Первый день, YYYY-MM-DD; по умолчанию 30 дней, 12 недель или 12 месяцев до to"},
+			{Name: "to", Description: "Последний день включительно, YYYY-MM-DD; по умолчанию сегодня"},
+		},
+		Response: entity.BookingTrends{}},
+	{Method: http.MethodGet, Path: "/admin/bookings", Tag: "admin", Summary: "Все бронирования", Access: accessAdmin,
+		Query: paginationParams, Response: SuccessResponse{}},
+	{Method: http.MethodGet, Path: "/admin/bookings/export", Tag: "admin", Summary: "Выгрузка бронирований файлом", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "format", Description: "csv (по умолчанию) или xlsx"},
+			{Name: "event_id", Description: "Только бронирования мероприятия", Integer: true},
+		},
+		ContentType: "application/octet-stream"},
+	{Method: http.MethodPost, Path: "/admin/bookings/confirm-bulk", Tag: "admin", Summary: "Подтвердить пакет оплат из кассы одной транзакцией с итогом по каждому бронированию", Access: accessAdmin,
+		Request: BulkConfirmRequest{}, Response: entity.BulkConfirmReport{}},
+	{Method: http.MethodPost, Path: "/admin/checkin", Tag: "admin", Summary: "Отметить проход по QR-коду билета; повторный проход - 409", Access: accessAdmin,
+		Request: service.CheckInRequest{}, Response: service.CheckInResult{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/bookings", Tag: "admin", Summary: "Бронирования мероприятия", Access: accessAdmin,
+		Query: paginationParams, Response: SuccessResponse{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/refunds", Tag: "admin", Summary: "Отчёт о возвратах по мероприятию", Access: accessAdmin,
+		Response: entity.RefundReport{}},
+	{Method: http.MethodPost, Path: "/admin/refunds/:id/settle", Tag: "admin", Summary: "Подтвердить выплату возврата, сделанную вне системы; закрытый возврат - 409", Access: accessAdmin,
+		Response: entity.Refund{}},
+	{Method: http.MethodDelete, Path: "/admin/bookings/:id", Tag: "admin", Summary: "Отменить бронирование с расчётом возврата", Access: accessAdmin,
+		Request: CancelBookingRequest{}, Response: SuccessResponse{}},
+	{Method: http.MethodGet, Path: "/admin/bookings/:id/history", Tag: "admin", Summary: "История статусов бронирования", Access: accessAdmin,
+		Response: []*entity.AuditEntry{}},
+	{Method: http.MethodGet, Path: "/admin/users", Tag: "admin", Summary: "Поиск пользователей по имени и email; в meta общее число найденных", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "name", Description: "Подстрока имени"},
+			{Name: "email", Description: "Подстрока email"},
+			{Name: "limit", Description: "По умолчанию 50, не больше 100", Integer: true},
+			{Name: "offset", Integer: true},
+		},
+		Response: SuccessResponse{}},
+	{Method: http.MethodPost, Path: "/admin/users/:id/impersonate", Tag: "admin", Summary: "Токен для действий от имени пользователя; действия по нему помечаются в журнале аудита и уведомлениях", Access: accessAdmin,
+		Request: ImpersonateRequest{}, Response: impersonationResponse{}},
+	{Method: http.MethodPost, Path: "/admin/events/:id/tiers", Tag: "admin", Summary: "Создать категорию билетов", Access: accessAdmin,
+		Request: service.CreateTicketTierRequest{}, Status: http.StatusCreated, Response: entity.TicketTier{}},
+	{Method: http.MethodPut, Path: "/admin/tiers/:id", Tag: "admin", Summary: "Изменить категорию билетов", Access: accessAdmin,
+		Request: service.UpdateTicketTierRequest{}, Response: entity.TicketTier{}},
+	{Method: http.MethodDelete, Path: "/admin/tiers/:id", Tag: "admin", Summary: "Удалить категорию билетов", Access: accessAdmin,
+		Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/pools", Tag: "admin", Summary: "Партнёрские пулы мероприятия", Access: accessAdmin,
+		Response: []*entity.PartnerPoolWithAvailability{}},
+	{Method: http.MethodPost, Path: "/admin/events/:id/pools", Tag: "admin", Summary: "Отложить места для партнёров", Access: accessAdmin,
+		Request: service.CreatePartnerPoolRequest{}, Status: http.StatusCreated, Response: entity.PartnerPool{}},
+	{Method: http.MethodPut, Path: "/admin/pools/:id", Tag: "admin", Summary: "Изменить партнёрский пул", Access: accessAdmin,
+		Request: service.UpdatePartnerPoolRequest{}, Response: entity.PartnerPool{}},
+	{Method: http.MethodDelete, Path: "/admin/pools/:id", Tag: "admin", Summary: "Удалить партнёрский пул", Access: accessAdmin,
+		Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/holds", Tag: "admin", Summary: "Придержанные места мероприятия", Access: accessAdmin,
+		Response: []*entity.EventHold{}},
+	{Method: http.MethodPut, Path: "/admin/events/:id/holds", Tag: "admin", Summary: "Заменить холды мест мероприятия", Access: accessAdmin,
+		Request: service.SetEventHoldsRequest{}, Response: []*entity.EventHold{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/capacity", Tag: "admin", Summary: "Предпросмотр изменения вместимости: какие бронирования будут отменены", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "total_seats", Description: "Новая вместимость", Integer: true},
+			{Name: "policy", Description: "keep_earliest, refund_latest (по умолчанию) или waitlist"},
+		},
+		Response: entity.CapacityPlan{}},
+	{Method: http.MethodPost, Path: "/admin/events/:id/capacity", Tag: "admin", Summary: "Изменить вместимость: отменить непоместившиеся бронирования с полным возвратом и уведомить владельцев", Access: accessAdmin,
+		Request: service.ChangeCapacityRequest{}, Response: entity.CapacityPlan{}},
+	{Method: http.MethodGet, Path: "/admin/events/:id/waitlist", Tag: "admin", Summary: "Лист ожидания мероприятия после уменьшения вместимости", Access: accessAdmin,
+		Response: []*entity.WaitlistEntry{}},
+
+	{Method: http.MethodGet, Path: "/admin/promo-codes", Tag: "admin", Summary: "Список промокодов", Access: accessAdmin,
+		Response: []*entity.PromoCode{}},
+	{Method: http.MethodPost, Path: "/admin/promo-codes", Tag: "admin", Summary: "Создать промокод", Access: accessAdmin,
+		Request: service.CreatePromoCodeRequest{}, Status: http.StatusCreated, Response: entity.PromoCode{}},
+	{Method: http.MethodGet, Path: "/admin/promo-codes/:id", Tag: "admin", Summary: "Промокод", Access: accessAdmin,
+		Response: entity.PromoCode{}},
+	{Method: http.MethodPut, Path: "/admin/promo-codes/:id", Tag: "admin", Summary: "Изменить промокод", Access: accessAdmin,
+		Request: service.UpdatePromoCodeRequest{}, Response: entity.PromoCode{}},
+	{Method: http.MethodDelete, Path: "/admin/promo-codes/:id", Tag: "admin", Summary: "Удалить промокод", Access: accessAdmin,
+		Response: messageResponse{}},
+
+	{Method: http.MethodPut, Path: "/admin/events/:id", Tag: "admin", Summary: "Изменить мероприятие; версия из ответа GET защищает от одновременной правки", Access: accessAdmin,
+		Request: service.UpdateEventRequest{}, Response: entity.Event{}},
+	{Method: http.MethodDelete, Path: "/admin/events/:id", Tag: "admin", Summary: "Удалить мероприятие без действующих бронирований; предстоящее считается отменённым", Access: accessAdmin,
+		Response: messageResponse{}},
+
+	{Method: http.MethodPost, Path: "/admin/venues", Tag: "admin", Summary: "Создать площадку", Access: accessAdmin,
+		Request: service.CreateVenueRequest{}, Status: http.StatusCreated, Response: entity.Venue{}},
+	{Method: http.MethodPut, Path: "/admin/venues/:id", Tag: "admin", Summary: "Изменить площадку", Access: accessAdmin,
+		Request: service.UpdateVenueRequest{}, Response: entity.Venue{}},
+	{Method: http.MethodDelete, Path: "/admin/venues/:id", Tag: "admin", Summary: "Удалить площадку без мероприятий", Access: accessAdmin,
+		Response: messageResponse{}},
+
+	{Method: http.MethodGet, Path: "/admin/webhooks", Tag: "admin", Summary: "Список вебхуков", Access: accessAdmin,
+		Response: []*entity.Webhook{}},
+	{Method: http.MethodPost, Path: "/admin/webhooks", Tag: "admin", Summary: "Создать вебхук; секрет подписи возвращается только здесь", Access: accessAdmin,
+		Request: service.CreateWebhookRequest{}, Status: http.StatusCreated, Response: entity.WebhookWithSecret{}},
+	{Method: http.MethodDelete, Path: "/admin/webhooks/:id", Tag: "admin", Summary: "Удалить вебхук", Access: accessAdmin,
+		Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/admin/webhooks/:id/deliveries", Tag: "admin", Summary: "Журнал доставок вебхука", Access: accessAdmin,
+		Query: []apiParam{{Name: "limit", Integer: true}}, Response: []*entity.WebhookDelivery{}},
+
+	{Method: http.MethodGet, Path: "/admin/api-tokens", Tag: "admin", Summary: "API-токены организаторов", Access: accessAdmin,
+		Query: []apiParam{{Name: "organizer_id", Description: "Только токены организатора", Integer: true}}, Response: []*entity.APIToken{}},
+	{Method: http.MethodPost, Path: "/admin/api-tokens", Tag: "admin", Summary: "Выпустить API-токен; сам токен возвращается только здесь", Access: accessAdmin,
+		Request: service.CreateAPITokenRequest{}, Status: http.StatusCreated, Response: entity.APITokenWithSecret{}},
+	{Method: http.MethodDelete, Path: "/admin/api-tokens/:id", Tag: "admin", Summary: "Отозвать API-токен", Access: accessAdmin,
+		Response: entity.APIToken{}},
+
+	{Method: http.MethodPost, Path: "/integrations/events", Tag: "integrations", Summary: "Создать мероприятие организатора токена; право events:write", Access: accessAPIToken,
+		Request: service.CreateEventRequest{}, Status: http.StatusCreated, Response: entity.Event{}},
+	{Method: http.MethodGet, Path: "/integrations/bookings", Tag: "integrations", Summary: "Бронирования на мероприятия организатора; право bookings:read", Access: accessAPIToken,
+		Query: []apiParam{{Name: "event_id", Description: "Только бронирования мероприятия", Integer: true}}, Response: []*entity.Booking{}},
+
+	{Method: http.MethodGet, Path: "/admin/dlq", Tag: "admin", Summary: "Задачи в очереди недоставленных сообщений", Access: accessAdmin,
+		Query: []apiParam{{Name: "limit", Integer: true}}, Response: []*queue.FailedTask{}},
+	{Method: http.MethodGet, Path: "/admin/dlq/stats", Tag: "admin", Summary: "Статистика очереди недоставленных сообщений", Access: accessAdmin,
+		Response: queue.DLQStats{}},
+	{Method: http.MethodPost, Path: "/admin/dlq/:task_id/requeue", Tag: "admin", Summary: "Вернуть задачу в очередь", Access: accessAdmin,
+		Response: dlqTaskResponse{}},
+	{Method: http.MethodDelete, Path: "/admin/dlq/:task_id", Tag: "admin", Summary: "Удалить задачу из очереди", Access: accessAdmin,
+		Response: dlqTaskResponse{}},
+	{Method: http.MethodGet, Path: "/admin/reconciliation", Tag: "admin", Summary: "Сверка бронирований с задачами очереди, уведомлениями и DLQ: расхождения и предлагаемые исправления", Access: accessAdmin,
+		Response: entity.ReconciliationReport{}},
+	{Method: http.MethodPost, Path: "/admin/reconciliation/apply", Tag: "admin", Summary: "Сверка с выполнением исправлений; результат каждого - в applied и error", Access: accessAdmin,
+		Response: entity.ReconciliationReport{}},
+}
+
+// registerOpenAPI строит спецификацию по уже зарегистрированным маршрутам и отдаёт её
+// вместе со Swagger UI: /swagger/index.html, сама спецификация - /swagger/doc.json.
+// Вызывается последним в InitRoutes.
+func registerOpenAPI(router *gin.Engine) {
+	doc, err := buildOpenAPI(router.Routes())
+	if err != nil {
+		logrus.Errorf("OpenAPI spec is not served: %v", err)
+		return
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		logrus.Errorf("OpenAPI spec is not served: %v", err)
+		return
+	}
+
+	swag.Register(swag.Name, openAPIDoc(data))
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+}
+
+// openAPIDoc отдаёт готовую спецификацию gin-swagger вместо сгенерированного swag пакета docs
+type openAPIDoc []byte
+
+func (d openAPIDoc) ReadDoc() string {
+	return string(d)
+}
+
+// buildOpenAPI описывает каждый маршрут /api/v1. Маршрут без записи в apiOperations
+// тоже попадает в спецификацию, но без схем, и об этом пишется предупреждение.
+func buildOpenAPI(routes gin.RoutesInfo) (*openapi3.T, error) {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "EventBooker API",
+			Description: "Бронирование мест на мероприятия. Защищённые методы требуют заголовок Authorization: Bearer <token> из /auth/login, методы /integrations - API-токен организатора.",
+			Version:     "1.0",
+		},
+		Servers: openapi3.Servers{{URL: apiBasePath}},
+		Paths:   openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				bearerAuth: &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+				apiTokenAuth: &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("http").WithScheme("bearer").WithBearerFormat("API token").
+					WithDescription("Токен интеграции организатора, выпускается администратором")},
+			},
+		},
+	}
+
+	documented := make(map[string]apiOperation, len(apiOperations))
+	for _, op := range apiOperations {
+		documented[op.Method+" "+op.Path] = op
+	}
+
+	schemas := &schemaRegistry{schemas: doc.Components.Schemas, names: make(map[reflect.Type]string)}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, apiBasePath+"/") {
+			continue
+		}
+		path := strings.TrimPrefix(route.Path, apiBasePath)
+
+		op, ok := documented[route.Method+" "+path]
+		if !ok {
+			logrus.Warnf("Route %s %s is not described in OpenAPI spec", route.Method, route.Path)
+			op = apiOperation{Method: route.Method, Path: path, Tag: strings.Split(strings.TrimPrefix(path, "/"), "/")[0]}
+		}
+
+		operation, err := op.build(schemas)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		doc.AddOperation(openAPIPath(path), route.Method, operation)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (op apiOperation) build(schemas *schemaRegistry) (*openapi3.Operation, error) {
+	operation := openapi3.NewOperation()
+	operation.Tags = []string{op.Tag}
+	operation.Summary = op.Summary
+	operation.OperationID = op.Method + strings.NewReplacer("/", "_", ":", "", ".", "_", "-", "_").Replace(op.Path)
+
+	for _, segment := range strings.Split(op.Path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			schema := openapi3.NewInt64Schema()
+			if name == "task_id" {
+				schema = openapi3.NewStringSchema()
+			}
+			operation.AddParameter(openapi3.NewPathParameter(name).WithSchema(schema))
+		}
+	}
+	for _, param := range op.Query {
+		schema := openapi3.NewStringSchema()
+		if param.Integer {
+			schema = openapi3.NewIntegerSchema()
+		}
+		operation.AddParameter(openapi3.NewQueryParameter(param.Name).WithDescription(param.Description).WithSchema(schema))
+	}
+
+	if op.Request != nil {
+		ref, err := schemas.ref(reflect.TypeOf(op.Request))
+		if err != nil {
+			return nil, err
+		}
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(ref)}
+	}
+	if op.FileField != "" {
+		form := openapi3.NewObjectSchema().
+			WithProperty(op.FileField, openapi3.NewStringSchema().WithFormat("binary"))
+		form.Required = []string{op.FileField}
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).
+			WithContent(openapi3.NewContentWithFormDataSchema(form))}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := openapi3.NewResponse().WithDescription(http.StatusText(status))
+	switch {
+	case op.ContentType != "":
+		response.WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema().WithFormat("binary"), []string{op.ContentType}))
+	case op.Response != nil:
+		ref, err := schemas.ref(reflect.TypeOf(op.Response))
+		if err != nil {
+			return nil, err
+		}
+		response.WithJSONSchemaRef(ref)
+	}
+	operation.AddResponse(status, response)
+
+	errorRef, err := schemas.ref(reflect.TypeOf(apiError{}))
+	if err != nil {
+		return nil, err
+	}
+	switch op.Access {
+	case accessPublic:
+	case accessAPIToken:
+		operation.Security = &openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(apiTokenAuth)}
+	default:
+		operation.Security = &openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate(bearerAuth)}
+	}
+	if op.Access != accessPublic {
+		operation.AddResponse(http.StatusUnauthorized, openapi3.NewResponse().WithDescription("Нет или неверный токен").WithJSONSchemaRef(errorRef))
+	}
+	switch op.Access {
+	case accessAdmin:
+		operation.AddResponse(http.StatusForbidden, openapi3.NewResponse().WithDescription("Нужна роль admin").WithJSONSchemaRef(errorRef))
+	case accessAPIToken:
+		operation.AddResponse(http.StatusForbidden, openapi3.NewResponse().WithDescription("Токену не выдано право").WithJSONSchemaRef(errorRef))
+		operation.AddResponse(http.StatusTooManyRequests, openapi3.NewResponse().WithDescription("Превышен лимит запросов токена, см. Retry-After").WithJSONSchemaRef(errorRef))
+	}
+	operation.Responses.Set("default", &openapi3.ResponseRef{Value: openapi3.NewResponse().WithDescription("Ошибка").WithJSONSchemaRef(errorRef)})
+
+	return operation, nil
+}
+
+// openAPIPath переводит параметры пути gin (:id) в синтаксис OpenAPI ({id})
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// schemaRegistry складывает схемы структур в components/schemas, чтобы операции ссылались
+// на них по имени, а не повторяли одну и ту же схему
+type schemaRegistry struct {
+	schemas openapi3.Schemas
+	names   map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *schemaRegistry) ref(t reflect.Type) (*openapi3.SchemaRef, error) {
+	switch {
+	case t.Kind() == reflect.Ptr:
+		return r.ref(t.Elem())
+	case t.Kind() == reflect.Slice:
+		items, err := r.ref(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewArraySchema()
+		schema.Items = items
+		return openapi3.NewSchemaRef("", schema), nil
+	case t.Kind() != reflect.Struct || t == timeType:
+		return openapi3gen.NewSchemaRefForValue(reflect.New(t).Elem().Interface(), nil, openapi3gen.SchemaCustomizer(bindingConstraints))
+	}
+
+	name, ok := r.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := r.schemas[name]; taken {
+			// Одноимённые типы из разных пакетов, например запросы transport и service
+			name = strings.ReplaceAll(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:], ".", "") + name
+		}
+
+		schema, err := openapi3gen.NewSchemaRefForValue(reflect.New(t).Elem().Interface(), nil, openapi3gen.SchemaCustomizer(bindingConstraints))
+		if err != nil {
+			return nil, err
+		}
+		schema.Value.Required = requiredFields(t)
+
+		r.schemas[name] = schema
+		r.names[t] = name
+	}
+
+	// Value нужен валидатору; в JSON ссылка всё равно выводится одним $ref
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, r.schemas[name].Value), nil
+}
+
+// bindingConstraints переносит в схему ограничения min/max/oneof из тегов binding
+func bindingConstraints(_ string, _ reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	for _, rule := range strings.Split(tag.Get("binding"), ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "max":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case schema.Type.Is(openapi3.TypeString) && name == "min":
+				schema.MinLength = uint64(number)
+			case schema.Type.Is(openapi3.TypeString):
+				schema.MaxLength = openapi3.Uint64Ptr(uint64(number))
+			case name == "min":
+				schema.Min = &number
+			default:
+				schema.Max = &number
+			}
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		}
+	}
+	return nil
+}
+
+// requiredFields - JSON-имена полей с binding:"required", включая встроенные структуры
+func requiredFields(t reflect.Type) []string {
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && jsonName == "" && field.Type.Kind() == reflect.Struct {
+			required = append(required, requiredFields(field.Type)...)
+			continue
+		}
+		if jsonName == "-" || !strings.Contains(","+field.Tag.Get("binding")+",", ",required,") {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		required = append(required, jsonName)
+	}
+	return required
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PartnerPoolHandler struct {
+	poolService service.PartnerPoolService
+}
+
+func NewPartnerPoolHandler(poolService service.PartnerPoolService) *PartnerPoolHandler {
+	return &PartnerPoolHandler{poolService: poolService}
+}
+
+func (h *PartnerPoolHandler) GetEventPools(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	pools, err := h.poolService.GetEventPools(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pools)
+}
+
+func (h *PartnerPoolHandler) CreatePool(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.CreatePartnerPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.poolService.CreatePool(c.Request.Context(), eventID, &req)
+	if err != nil {
+		c.JSON(poolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, pool)
+}
+
+func (h *PartnerPoolHandler) UpdatePool(c *gin.Context) {
+	poolID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+
+	var req service.UpdatePartnerPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.poolService.UpdatePool(c.Request.Context(), poolID, &req)
+	if err != nil {
+		c.JSON(poolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pool)
+}
+
+func (h *PartnerPoolHandler) DeletePool(c *gin.Context) {
+	poolID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pool id"})
+		return
+	}
+
+	if err := h.poolService.DeletePool(c.Request.Context(), poolID); err != nil {
+		c.JSON(poolErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "partner pool deleted"})
+}
+
+// poolErrorStatus сопоставляет ошибки партнёрских пулов с HTTP-статусами
+func poolErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrPartnerPoolNotFound), errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrPoolSeatsExceedEvent), errors.Is(err, entity.ErrPartnerPoolExists),
+		errors.Is(err, entity.ErrPartnerPoolHasBookings):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
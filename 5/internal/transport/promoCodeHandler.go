@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromoCodeHandler struct {
+	promoService service.PromoCodeService
+}
+
+func NewPromoCodeHandler(promoService service.PromoCodeService) *PromoCodeHandler {
+	return &PromoCodeHandler{promoService: promoService}
+}
+
+func (h *PromoCodeHandler) ListPromoCodes(c *gin.Context) {
+	promos, err := h.promoService.ListPromoCodes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promos)
+}
+
+func (h *PromoCodeHandler) GetPromoCode(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code id"})
+		return
+	}
+
+	promo, err := h.promoService.GetPromoCode(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(promoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promo)
+}
+
+func (h *PromoCodeHandler) CreatePromoCode(c *gin.Context) {
+	var req service.CreatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo, err := h.promoService.CreatePromoCode(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(promoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, promo)
+}
+
+func (h *PromoCodeHandler) UpdatePromoCode(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code id"})
+		return
+	}
+
+	var req service.UpdatePromoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo, err := h.promoService.UpdatePromoCode(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(promoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promo)
+}
+
+func (h *PromoCodeHandler) DeletePromoCode(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid promo code id"})
+		return
+	}
+
+	if err := h.promoService.DeletePromoCode(c.Request.Context(), id); err != nil {
+		c.JSON(promoErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "promo code deleted"})
+}
+
+// promoErrorStatus сопоставляет ошибки промокодов с HTTP-статусами
+func promoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrPromoCodeNotFound), errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrPromoCodeExists), errors.Is(err, entity.ErrPromoCodeInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
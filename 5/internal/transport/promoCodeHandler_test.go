@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usedPromoCodeRepository - репозиторий, в котором каждый промокод уже использован в бронированиях
+type usedPromoCodeRepository struct {
+	repository.PromoCodeRepository
+}
+
+func (usedPromoCodeRepository) Delete(ctx context.Context, id int64) error {
+	return entity.ErrPromoCodeInUse
+}
+
+// TestDeleteUsedPromoCode проверяет, что удаление использованного промокода - конфликт (409)
+func TestDeleteUsedPromoCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewPromoCodeHandler(service.NewPromoCodeService(usedPromoCodeRepository{}, nil))
+	router := gin.New()
+	router.DELETE("/promo-codes/:id", handler.DeletePromoCode)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/promo-codes/1", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+}
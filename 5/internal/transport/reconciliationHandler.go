@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationHandler отдаёт администраторам сверку бронирований с очередью и уведомлениями
+type ReconciliationHandler struct {
+	reconciliationService service.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// GetReport строит отчёт о расхождениях, ничего не меняя
+func (h *ReconciliationHandler) GetReport(c *gin.Context) {
+	h.reconcile(c, false)
+}
+
+// ApplyRepairs строит отчёт и сразу выполняет предложенные в нём исправления
+func (h *ReconciliationHandler) ApplyRepairs(c *gin.Context) {
+	h.reconcile(c, true)
+}
+
+func (h *ReconciliationHandler) reconcile(c *gin.Context, apply bool) {
+	report, err := h.reconciliationService.Reconcile(c.Request.Context(), apply)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,236 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/telegram"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	telegramListLimit = 10
+	telegramCancelled = "Отменено через Telegram"
+)
+
+// TelegramHandler обрабатывает команды боту и нажатия кнопок под уведомлениями.
+// Пользователь определяется по чату: он должен быть привязан к аккаунту как telegram_id.
+type TelegramHandler struct {
+	bot            *telegram.Bot
+	bookingService service.BookingService
+	eventService   service.EventService
+	userService    service.UserService
+}
+
+func NewTelegramHandler(bot *telegram.Bot, bookingService service.BookingService, eventService service.EventService, userService service.UserService) *TelegramHandler {
+	return &TelegramHandler{
+		bot:            bot,
+		bookingService: bookingService,
+		eventService:   eventService,
+		userService:    userService,
+	}
+}
+
+// HandleUpdate - telegram.UpdateHandler для long polling и webhook
+func (h *TelegramHandler) HandleUpdate(ctx context.Context, update *telegram.Update) {
+	switch {
+	case update.CallbackQuery != nil:
+		h.handleCallback(ctx, update.CallbackQuery)
+	case update.Message != nil:
+		h.handleCommand(ctx, update.Message)
+	}
+}
+
+func (h *TelegramHandler) handleCommand(ctx context.Context, msg *telegram.Message) {
+	command, _ := msg.Command()
+	if command == "" {
+		return
+	}
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+
+	var err error
+	switch command {
+	case "/mybookings":
+		err = h.sendMyBookings(ctx, chatID)
+	case "/events":
+		err = h.sendEvents(ctx, chatID)
+	default:
+		err = h.bot.SendMessage(chatID, fmt.Sprintf(
+			"Команды:\n"+
+				"/events - ближайшие мероприятия\n"+
+				"/mybookings - ваши бронирования с кнопками подтверждения и отмены\n\n"+
+				"Чтобы бот узнавал вас, привяжите этот чат к аккаунту: ваш chat ID %s", chatID))
+	}
+
+	if err != nil {
+		logrus.Errorf("Failed to handle Telegram command %s: %v", command, err)
+	}
+}
+
+func (h *TelegramHandler) sendMyBookings(ctx context.Context, chatID string) error {
+	user, err := h.linkedUser(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	bookings, err := h.bookingService.GetUserBookings(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	var pending []int64
+	for _, booking := range bookings {
+		if booking.Status != entity.BookingStatusPending && booking.Status != entity.BookingStatusConfirmed {
+			continue
+		}
+		if len(lines) == telegramListLimit {
+			break
+		}
+
+		title := fmt.Sprintf("мероприятие #%d", booking.EventID)
+		if event, err := h.eventService.GetEvent(ctx, booking.EventID); err == nil {
+			title = fmt.Sprintf("%s, %s", event.Title, event.Date.Format("02.01.2006 в 15:04"))
+		}
+
+		line := fmt.Sprintf("#%d · %s · мест: %d", booking.ID, title, booking.Seats)
+		if booking.Status == entity.BookingStatusPending {
+			line += fmt.Sprintf("\n⏳ подтвердите до %s", booking.ExpiresAt.Format("02.01.2006 в 15:04"))
+			pending = append(pending, booking.ID)
+		} else {
+			line += "\n✅ подтверждено"
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return h.bot.SendMessage(chatID, "У вас нет активных бронирований")
+	}
+
+	message := "🎫 Ваши бронирования\n\n" + strings.Join(lines, "\n\n")
+	if len(pending) == 0 {
+		return h.bot.SendMessage(chatID, message)
+	}
+	return h.bot.SendMessageWithKeyboard(chatID, message, service.BookingActionsKeyboard(pending...))
+}
+
+func (h *TelegramHandler) sendEvents(ctx context.Context, chatID string) error {
+	events, err := h.eventService.GetUpcomingEvents(ctx, telegramListLimit)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return h.bot.SendMessage(chatID, "Ближайших мероприятий нет")
+	}
+
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		lines = append(lines, fmt.Sprintf("📅 %s\n%s · свободно мест: %d",
+			event.Title, event.Date.Format("02.01.2006 в 15:04"), event.AvailableSeats))
+	}
+
+	return h.bot.SendMessage(chatID, "Ближайшие мероприятия\n\n"+strings.Join(lines, "\n\n"))
+}
+
+// handleCallback выполняет действие кнопки, если бронирование принадлежит владельцу чата,
+// и убирает кнопки этого бронирования из сообщения
+func (h *TelegramHandler) handleCallback(ctx context.Context, query *telegram.CallbackQuery) {
+	answer, err := h.applyBookingAction(ctx, query)
+	if err != nil {
+		logrus.Errorf("Failed to handle Telegram callback %q: %v", query.Data, err)
+		answer = "Не удалось выполнить действие, попробуйте позже"
+	}
+
+	if err := h.bot.AnswerCallbackQuery(query.ID, answer, true); err != nil {
+		logrus.Errorf("Failed to answer Telegram callback: %v", err)
+	}
+}
+
+func (h *TelegramHandler) applyBookingAction(ctx context.Context, query *telegram.CallbackQuery) (string, error) {
+	action, bookingID, err := service.ParseBookingAction(query.Data)
+	if err != nil {
+		return "Кнопка устарела", nil
+	}
+
+	chatID := query.From.ID
+	if query.Message != nil {
+		chatID = query.Message.Chat.ID
+	}
+
+	user, err := h.userService.GetUserByTelegramID(ctx, strconv.FormatInt(chatID, 10))
+	if errors.Is(err, entity.ErrUserNotFound) {
+		return "Этот чат не привязан к аккаунту", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	booking, err := h.bookingService.GetBooking(ctx, bookingID)
+	if err != nil || booking.UserID != user.ID {
+		return "Бронирование не найдено", nil
+	}
+
+	ctx = entity.WithAuditActor(ctx, entity.AuditActor{ID: &user.ID, Kind: entity.ActorTelegram})
+
+	var answer string
+	switch action {
+	case service.TelegramActionConfirm:
+		_, err = h.bookingService.ConfirmBooking(ctx, bookingID)
+		answer = fmt.Sprintf("Бронирование #%d подтверждено", bookingID)
+	case service.TelegramActionCancel:
+		_, _, err = h.bookingService.CancelBooking(ctx, bookingID, telegramCancelled)
+		answer = fmt.Sprintf("Бронирование #%d отменено", bookingID)
+	}
+
+	var transitionErr *entity.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		answer = fmt.Sprintf("Бронирование #%d уже %s", bookingID, statusText(transitionErr.From))
+	case errors.Is(err, entity.ErrCancellationClosed):
+		return "Отмена этого бронирования уже недоступна", nil
+	case err != nil:
+		return "", err
+	}
+
+	// Кнопки выполненного действия больше не нужны
+	if query.Message != nil {
+		rest := service.WithoutBookingActions(query.Message.ReplyMarkup, bookingID)
+		if err := h.bot.EditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, rest); err != nil {
+			logrus.Warnf("Failed to update Telegram keyboard: %v", err)
+		}
+	}
+
+	return answer, nil
+}
+
+// linkedUser возвращает владельца чата; если чат не привязан, сообщает об этом и возвращает nil
+func (h *TelegramHandler) linkedUser(ctx context.Context, chatID string) (*entity.User, error) {
+	user, err := h.userService.GetUserByTelegramID(ctx, chatID)
+	if errors.Is(err, entity.ErrUserNotFound) {
+		return nil, h.bot.SendMessage(chatID, fmt.Sprintf(
+			"Этот чат не привязан к аккаунту. Укажите chat ID %s в профиле, чтобы видеть свои бронирования.", chatID))
+	}
+	return user, err
+}
+
+func statusText(status entity.BookingStatus) string {
+	switch status {
+	case entity.BookingStatusConfirmed:
+		return "подтверждено"
+	case entity.BookingStatusCancelled:
+		return "отменено"
+	case entity.BookingStatusExpired:
+		return "истекло"
+	default:
+		return string(status)
+	}
+}
@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/ds124wfegd/WB_L3/5/pkg/ticket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketHandler struct {
+	ticketService service.TicketService
+}
+
+func NewTicketHandler(ticketService service.TicketService) *TicketHandler {
+	return &TicketHandler{ticketService: ticketService}
+}
+
+// GetTicket отдаёт QR-код билета; чужой билет доступен только администратору
+func (h *TicketHandler) GetTicket(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking id"})
+		return
+	}
+
+	t, err := h.ticketService.GetTicket(c.Request.Context(), bookingID)
+	if err != nil {
+		c.JSON(ticketErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(c)
+	if t.Booking.UserID != userID && c.GetString(middleware.ContextUserRole) != entity.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, ticket.ContentType, t.PNG)
+}
+
+// CheckIn отмечает проход по отсканированному билету
+func (h *TicketHandler) CheckIn(c *gin.Context) {
+	var req service.CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.ticketService.CheckIn(c.Request.Context(), &req)
+	if err != nil {
+		// При повторном проходе отдаём и бронирование: контролёр видит, когда билет уже прошёл
+		if errors.Is(err, entity.ErrAlreadyCheckedIn) && result != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		c.JSON(ticketErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ticketErrorStatus сопоставляет ошибки билетов с HTTP-статусами
+func ticketErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrBookingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrTicketUnavailable),
+		errors.Is(err, entity.ErrAlreadyCheckedIn),
+		errors.Is(err, entity.ErrTicketWrongEvent):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrInvalidTicket):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
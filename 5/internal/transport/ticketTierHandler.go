@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TicketTierHandler struct {
+	tierService service.TicketTierService
+}
+
+func NewTicketTierHandler(tierService service.TicketTierService) *TicketTierHandler {
+	return &TicketTierHandler{tierService: tierService}
+}
+
+func (h *TicketTierHandler) GetEventTiers(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	tiers, err := h.tierService.GetEventTiers(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tiers)
+}
+
+func (h *TicketTierHandler) CreateTier(c *gin.Context) {
+	eventID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var req service.CreateTicketTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier, err := h.tierService.CreateTier(c.Request.Context(), eventID, &req)
+	if err != nil {
+		c.JSON(tierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tier)
+}
+
+func (h *TicketTierHandler) UpdateTier(c *gin.Context) {
+	tierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tier id"})
+		return
+	}
+
+	var req service.UpdateTicketTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tier, err := h.tierService.UpdateTier(c.Request.Context(), tierID, &req)
+	if err != nil {
+		c.JSON(tierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tier)
+}
+
+func (h *TicketTierHandler) DeleteTier(c *gin.Context) {
+	tierID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tier id"})
+		return
+	}
+
+	if err := h.tierService.DeleteTier(c.Request.Context(), tierID); err != nil {
+		c.JSON(tierErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ticket tier deleted"})
+}
+
+// tierErrorStatus сопоставляет ошибки категорий билетов с HTTP-статусами
+func tierErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrTicketTierNotFound), errors.Is(err, entity.ErrEventNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrTierSeatsExceedEvent), errors.Is(err, entity.ErrTierHasBookings):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
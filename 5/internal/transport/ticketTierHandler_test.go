@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bookedTierRepository - репозиторий, в котором на каждую категорию ссылаются бронирования
+type bookedTierRepository struct {
+	repository.TicketTierRepository
+}
+
+func (bookedTierRepository) Delete(ctx context.Context, id int64) error {
+	return entity.ErrTierHasBookings
+}
+
+// TestDeleteTierWithBookings проверяет, что удаление категории с бронированиями - конфликт (409)
+func TestDeleteTierWithBookings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewTicketTierHandler(service.NewTicketTierService(bookedTierRepository{}, nil))
+	router := gin.New()
+	router.DELETE("/tiers/:id", handler.DeleteTier)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/tiers/1", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+}
@@ -1,101 +1,250 @@
-package transport
-
-import (
-	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
-	"github.com/gin-gonic/gin"
-)
-
-func InitRoutes(eventHandler *EventHandler, bookingHandler *BookingHandler, userHandler *UserHandler) *gin.Engine {
-
-	router := gin.New()
-
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	router.Static("/static", "/app/internal/web/templates")
-	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
-
-	router.GET("/", func(c *gin.Context) {
-		c.File("/app/internal/web/templates/index.html")
-	})
-
-	// Middleware
-	router.Use(gin.Recovery())
-	router.Use(middleware.CORS())
-	router.Use(middleware.Logger())
-	router.Use(middleware.Timeout(30))
-
-	// API routes
-	api := router.Group("/api/v1")
-	{
-		// Event routes
-		events := api.Group("/events")
-		{
-			events.POST("", eventHandler.CreateEvent)
-			events.GET("", eventHandler.GetAllEvents)
-			events.GET("/:id", eventHandler.GetEvent)
-		}
-
-		// Booking routes
-		bookings := api.Group("/bookings")
-		{
-			bookings.POST("/events/:id/book", bookingHandler.BookSeats)
-			bookings.POST("/events/:id/confirm", bookingHandler.ConfirmBooking)
-			bookings.GET("/users/:user_id", bookingHandler.GetUserBookings)
-		}
-
-		// User routes
-		users := api.Group("/users")
-		{
-			users.POST("/register", userHandler.RegisterUser)
-			users.GET("/:id", userHandler.GetUser)
-			users.POST("/:id/telegram", userHandler.LinkTelegram)
-		}
-
-		// Admin routes
-		admin := api.Group("/admin")
-		{
-			admin.GET("/bookings", bookingHandler.GetAllBookings)
-			admin.GET("/events/:id/bookings", bookingHandler.GetEventBookings)
-			admin.DELETE("/bookings/:id", bookingHandler.CancelBooking)
-		}
-	}
-
-	// Web interface routes
-	router.Static("/static", "./web/static")
-	router.LoadHTMLGlob("web/templates/*")
-
-	router.GET("/", func(c *gin.Context) {
-		c.HTML(200, "user.html", nil)
-	})
-
-	router.GET("/admin", func(c *gin.Context) {
-		c.HTML(200, "admin.html", nil)
-	})
-
-	router.GET("/event/:id", func(c *gin.Context) {
-		c.HTML(200, "event.html", gin.H{
-			"eventID": c.Param("id"),
-		})
-	})
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":    "ok",
-			"timestamp": gin.H{"time": "server is running"},
-		})
-	})
-
-	return router
-}
+package transport
+
+import (
+	"github.com/ds124wfegd/WB_L3/5/config"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// availabilityStreamRoute - поток Server-Sent Events, общий таймаут запросов его не ограничивает
+const availabilityStreamRoute = "/api/v1/events/:id/availability/stream"
+
+func InitRoutes(cfg *config.Config, jwtManager *middleware.JWTManager, authHandler *AuthHandler, eventHandler *EventHandler, bookingHandler *BookingHandler, cartHandler *CartHandler, ticketHandler *TicketHandler, userHandler *UserHandler, tierHandler *TicketTierHandler, poolHandler *PartnerPoolHandler, holdHandler *EventHoldHandler, promoHandler *PromoCodeHandler, webhookHandler *WebhookHandler, calendarHandler *CalendarHandler, venueHandler *VenueHandler, dlqHandler *DLQHandler, apiTokenHandler *APITokenHandler, integrationHandler *IntegrationHandler, analyticsHandler *AnalyticsHandler, capacityHandler *CapacityHandler, reconciliationHandler *ReconciliationHandler, healthHandler *HealthHandler, eventPageHandler *EventPageHandler, availabilityHandler *AvailabilityHandler, adminFeedHandler *AdminFeedHandler, apiTokens middleware.APITokenAuthenticator, rateLimiter *middleware.RateLimiter) *gin.Engine {
+
+	router := gin.New()
+
+	router.Use(func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+
+	router.Static("/static", "/app/internal/web/templates")
+	router.LoadHTMLGlob("/app/internal/web/templates/*.html")
+
+	router.GET("/", func(c *gin.Context) {
+		c.File("/app/internal/web/templates/index.html")
+	})
+
+	// Middleware
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(gin.Recovery())
+	router.Use(middleware.CORS())
+	router.Use(middleware.LoggerWithConfig(loggerConfig(cfg.Logging)))
+	router.Use(middleware.Timeout(30, availabilityStreamRoute, adminFeedRoute))
+
+	// API routes
+	api := router.Group("/api/v1")
+	{
+		// Auth routes
+		auth := api.Group("/auth")
+		auth.Use(rateLimiter.Limit("auth"))
+		{
+			auth.POST("/login", authHandler.Login)
+		}
+
+		// Event routes
+		events := api.Group("/events")
+		events.Use(rateLimiter.Limit("events"))
+		{
+			events.POST("", middleware.Auth(jwtManager), middleware.RequireRole(entity.RoleAdmin), eventHandler.CreateEvent)
+			events.GET("", eventHandler.GetAllEvents)
+			events.GET("/:id", eventHandler.GetEvent)
+			events.GET("/:id/tiers", tierHandler.GetEventTiers)
+			events.GET("/:id/cancellation-policy", eventHandler.GetCancellationPolicy)
+			events.GET("/:id/poster", eventHandler.GetPoster)
+			events.GET("/:id/availability/stream", availabilityHandler.StreamAvailability)
+			events.POST("/:id/poster", middleware.Auth(jwtManager), eventHandler.UploadPoster)
+		}
+
+		// Venue routes
+		venues := api.Group("/venues")
+		venues.Use(rateLimiter.Limit("venues"))
+		{
+			venues.GET("", venueHandler.ListVenues)
+			venues.GET("/:id", venueHandler.GetVenue)
+		}
+
+		// Booking routes
+		bookings := api.Group("/bookings")
+		bookings.Use(rateLimiter.Limit("bookings"))
+		{
+			bookings.POST("/events/:id/book", middleware.Auth(jwtManager), bookingHandler.BookSeats)
+			bookings.POST("/events/:id/confirm", middleware.Auth(jwtManager), bookingHandler.ConfirmBooking)
+			bookings.GET("/users/:user_id", middleware.Auth(jwtManager), bookingHandler.GetUserBookings)
+			bookings.GET("/:id", middleware.Auth(jwtManager), bookingHandler.GetBooking)
+			bookings.GET("/:id/ticket.png", middleware.Auth(jwtManager), ticketHandler.GetTicket)
+		}
+
+		// Cart routes: позиции на несколько мероприятий оформляются одной транзакцией
+		cart := api.Group("/cart")
+		cart.Use(middleware.Auth(jwtManager), rateLimiter.Limit("bookings"))
+		{
+			cart.GET("", cartHandler.GetCart)
+			cart.POST("/items", cartHandler.AddItem)
+			cart.DELETE("/items/:id", cartHandler.RemoveItem)
+			cart.DELETE("", cartHandler.ClearCart)
+			cart.POST("/checkout", cartHandler.Checkout)
+		}
+
+		// User routes
+		users := api.Group("/users")
+		users.Use(rateLimiter.Limit("users"))
+		{
+			users.POST("/register", userHandler.RegisterUser)
+			users.GET("/:id", middleware.Auth(jwtManager), userHandler.GetUser)
+			users.POST("/:id/telegram", middleware.Auth(jwtManager), userHandler.LinkTelegram)
+			users.GET("/:id/notifications", middleware.Auth(jwtManager), userHandler.GetNotificationPreferences)
+			users.PUT("/:id/notifications", middleware.Auth(jwtManager), userHandler.UpdateNotificationPreferences)
+			users.GET("/:id/stats", middleware.Auth(jwtManager), userHandler.GetUserStats)
+			users.GET("/:id/calendar", middleware.Auth(jwtManager), calendarHandler.GetSubscription)
+			users.POST("/:id/calendar/revoke", middleware.Auth(jwtManager), calendarHandler.RevokeSubscription)
+			users.GET("/:id/calendar.ics", calendarHandler.Feed)
+		}
+
+		// Integration routes: доступ по API-токену организатора, не по JWT
+		integrations := api.Group("/integrations")
+		integrations.Use(middleware.APITokenAuth(apiTokens), rateLimiter.Limit("integrations"))
+		{
+			integrations.POST("/events", middleware.RequireScope(entity.ScopeEventsWrite), integrationHandler.CreateEvent)
+			integrations.GET("/bookings", middleware.RequireScope(entity.ScopeBookingsRead), integrationHandler.GetBookings)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.Auth(jwtManager), middleware.RequireRole(entity.RoleAdmin), rateLimiter.Limit("admin"))
+		{
+			admin.GET("/stats", analyticsHandler.GetSystemStats)
+			admin.GET("/stats/trends", analyticsHandler.GetBookingTrends)
+			admin.GET("/bookings", bookingHandler.GetAllBookings)
+			admin.GET("/bookings/export", bookingHandler.ExportBookings)
+			admin.POST("/bookings/confirm-bulk", bookingHandler.ConfirmBookingsBulk)
+			admin.POST("/checkin", ticketHandler.CheckIn)
+			admin.PUT("/events/:id", eventHandler.UpdateEvent)
+			admin.DELETE("/events/:id", eventHandler.DeleteEvent)
+			admin.GET("/events/:id/bookings", bookingHandler.GetEventBookings)
+			admin.GET("/events/:id/refunds", bookingHandler.GetEventRefunds)
+			admin.POST("/refunds/:id/settle", bookingHandler.SettleRefund)
+			admin.DELETE("/bookings/:id", bookingHandler.CancelBooking)
+			admin.GET("/bookings/:id/history", bookingHandler.GetBookingHistory)
+			admin.GET("/users", userHandler.SearchUsers)
+			admin.POST("/users/:id/impersonate", authHandler.Impersonate)
+			admin.POST("/events/:id/tiers", tierHandler.CreateTier)
+			admin.PUT("/tiers/:id", tierHandler.UpdateTier)
+			admin.DELETE("/tiers/:id", tierHandler.DeleteTier)
+			admin.GET("/events/:id/pools", poolHandler.GetEventPools)
+			admin.POST("/events/:id/pools", poolHandler.CreatePool)
+			admin.PUT("/pools/:id", poolHandler.UpdatePool)
+			admin.DELETE("/pools/:id", poolHandler.DeletePool)
+			admin.GET("/events/:id/holds", holdHandler.GetEventHolds)
+			admin.PUT("/events/:id/holds", holdHandler.SetEventHolds)
+			admin.GET("/events/:id/capacity", capacityHandler.PreviewCapacityChange)
+			admin.POST("/events/:id/capacity", capacityHandler.ChangeCapacity)
+			admin.GET("/events/:id/waitlist", capacityHandler.GetWaitlist)
+
+			admin.GET("/promo-codes", promoHandler.ListPromoCodes)
+			admin.POST("/promo-codes", promoHandler.CreatePromoCode)
+			admin.GET("/promo-codes/:id", promoHandler.GetPromoCode)
+			admin.PUT("/promo-codes/:id", promoHandler.UpdatePromoCode)
+			admin.DELETE("/promo-codes/:id", promoHandler.DeletePromoCode)
+
+			admin.POST("/venues", venueHandler.CreateVenue)
+			admin.PUT("/venues/:id", venueHandler.UpdateVenue)
+			admin.DELETE("/venues/:id", venueHandler.DeleteVenue)
+
+			admin.GET("/webhooks", webhookHandler.ListWebhooks)
+			admin.POST("/webhooks", webhookHandler.CreateWebhook)
+			admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			admin.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+
+			admin.GET("/api-tokens", apiTokenHandler.ListTokens)
+			admin.POST("/api-tokens", apiTokenHandler.CreateToken)
+			admin.DELETE("/api-tokens/:id", apiTokenHandler.RevokeToken)
+
+			admin.GET("/dlq", dlqHandler.ListFailedTasks)
+			admin.GET("/dlq/stats", dlqHandler.GetStats)
+			admin.POST("/dlq/:task_id/requeue", dlqHandler.RequeueFailedTask)
+			admin.DELETE("/dlq/:task_id", dlqHandler.DeleteFailedTask)
+
+			admin.GET("/reconciliation", reconciliationHandler.GetReport)
+			admin.POST("/reconciliation/apply", reconciliationHandler.ApplyRepairs)
+		}
+	}
+
+	// Лента админки: токен приходит в Sec-WebSocket-Protocol, заголовки браузер задать не даёт
+	router.GET(adminFeedRoute, middleware.WebSocketToken(), middleware.Auth(jwtManager), middleware.RequireRole(entity.RoleAdmin), rateLimiter.Limit("admin"), adminFeedHandler.Feed)
+
+	// Web interface routes
+	router.Static("/static", "./web/static")
+	router.LoadHTMLGlob("web/templates/*")
+
+	router.GET("/", func(c *gin.Context) {
+		c.HTML(200, "user.html", nil)
+	})
+
+	router.GET("/admin", func(c *gin.Context) {
+		c.HTML(200, "admin.html", nil)
+	})
+
+	// Публичный список мероприятий рендерится на сервере: быстрая первая отрисовка и индексация
+	router.GET("/events", eventPageHandler.ListEvents)
+
+	router.GET("/event/:id", func(c *gin.Context) {
+		c.HTML(200, "event.html", gin.H{
+			"eventID": c.Param("id"),
+		})
+	})
+
+	// Liveness и readiness; /health оставлен для старых проверок
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/health", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// OpenAPI и Swagger UI: /swagger/index.html
+	registerOpenAPI(router)
+
+	return router
+}
+
+// NewRateLimiter собирает ограничитель частоты запросов по настройкам групп из config.yaml
+func NewRateLimiter(cfg config.RateLimitConfig, client redis.Scripter, jwtManager *middleware.JWTManager) *middleware.RateLimiter {
+	policies := make(map[string]middleware.RateLimitPolicy, len(cfg.Groups))
+	for group, limits := range cfg.Groups {
+		policies[group] = middleware.RateLimitPolicy{
+			PerIP:   middleware.RateLimitRule{Requests: limits.PerIP, Period: limits.Period, Burst: limits.Burst},
+			PerUser: middleware.RateLimitRule{Requests: limits.PerUser, Period: limits.Period, Burst: limits.Burst},
+		}
+	}
+
+	return middleware.NewRateLimiter(client, jwtManager, policies)
+}
+
+// loggerConfig переносит настройки журналирования из config.yaml, подставляя значения по умолчанию
+func loggerConfig(cfg config.LoggingConfig) middleware.LoggerConfig {
+	loggerCfg := middleware.DefaultLoggerConfig()
+	loggerCfg.LogBodies = cfg.LogBodies
+	loggerCfg.RedactEmails = cfg.RedactEmails
+	// Значение по умолчанию (1) подставляет config, поэтому явный 0 исключает из журнала успешные запросы
+	loggerCfg.SampleRate = cfg.SampleRate
+	loggerCfg.RouteSampleRates = cfg.RouteSampleRates
+	loggerCfg.SlowThreshold = cfg.SlowThreshold
+
+	if cfg.MaxBodySize > 0 {
+		loggerCfg.MaxBodySize = cfg.MaxBodySize
+	}
+	if len(cfg.RedactFields) > 0 {
+		loggerCfg.RedactFields = cfg.RedactFields
+	}
+
+	return loggerCfg
+}
@@ -1,75 +1,237 @@
-package transport
-
-import (
-	"net/http"
-	"strconv"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-
-	"github.com/gin-gonic/gin"
-)
-
-type UserHandler struct {
-	userService service.UserService
-}
-
-func NewUserHandler(userService service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
-}
-
-func (h *UserHandler) RegisterUser(c *gin.Context) {
-	var req service.RegisterUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	user, err := h.userService.RegisterUser(c.Request.Context(), &req)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, user)
-}
-
-func (h *UserHandler) GetUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
-		return
-	}
-
-	user, err := h.userService.GetUserByID(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, user)
-}
-
-func (h *UserHandler) LinkTelegram(c *gin.Context) {
-	idStr := c.Param("id")
-	userID, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
-		return
-	}
-
-	var req struct {
-		TelegramID string `json:"telegram_id" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if err := h.userService.LinkTelegram(c.Request.Context(), userID, req.TelegramID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "telegram linked successfully"})
-}
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService service.UserService
+}
+
+func NewUserHandler(userService service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+func (h *UserHandler) RegisterUser(c *gin.Context) {
+	var req service.RegisterUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.RegisterUser(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *UserHandler) GetUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if !canAccessUser(c, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetUserStats отдает статистику пользователя; смотреть ее может сам пользователь или администратор
+func (h *UserHandler) GetUserStats(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if !canAccessUser(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	stats, err := h.userService.GetUserStats(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// SearchUsers ищет пользователей по подстроке имени и email для администратора
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter := &entity.UserFilter{
+		Name:   c.Query("name"),
+		Email:  c.Query("email"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	users, total, err := h.userService.SearchUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Success: false,
+			Error:   "Failed to search users: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Users retrieved successfully",
+		Data:    users,
+		Meta: map[string]interface{}{
+			"total":    total,
+			"count":    len(users),
+			"limit":    filter.Limit,
+			"offset":   filter.Offset,
+			"has_more": filter.Offset+len(users) < total,
+		},
+	})
+}
+
+// LinkTelegramRequest представляет запрос на привязку Telegram
+type LinkTelegramRequest struct {
+	TelegramID string `json:"telegram_id" binding:"required"`
+}
+
+// LinkTelegram привязывает чат к пользователю. Бот подтверждает и отменяет бронирования
+// пользователя привязанного чата, поэтому привязать чат может сам пользователь или администратор
+func (h *UserHandler) LinkTelegram(c *gin.Context) {
+	idStr := c.Param("id")
+	userID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if !canAccessUser(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	var req LinkTelegramRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.LinkTelegram(c.Request.Context(), userID, req.TelegramID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "telegram linked successfully"})
+}
+
+// notificationPreferences - включённые каналы и каналы по категориям с подставленными значениями по умолчанию
+func notificationPreferences(user *entity.User) notificationPreferencesResponse {
+	return notificationPreferencesResponse{
+		NotifyEmail:    user.NotifyEmail,
+		NotifyTelegram: user.NotifyTelegram,
+		Categories:     user.NotificationPreferences.Resolved(),
+	}
+}
+
+// GetNotificationPreferences отдает настройки уведомлений; смотреть их может сам пользователь или администратор
+func (h *UserHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	callerID, _ := middleware.UserIDFromContext(c)
+	if callerID != userID && c.GetString(middleware.ContextUserRole) != entity.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationPreferences(user))
+}
+
+// UpdateNotificationPreferences меняет каналы уведомлений; изменить их может сам пользователь или администратор
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	callerID, _ := middleware.UserIDFromContext(c)
+	if callerID != userID && c.GetString(middleware.ContextUserRole) != entity.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": entity.ErrForbidden.Error()})
+		return
+	}
+
+	var req service.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdateNotificationPreferences(c.Request.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationPreferences(user))
+}
+
+// canAccessUser - данные пользователя доступны ему самому и администратору
+func canAccessUser(c *gin.Context, userID int64) bool {
+	callerID, _ := middleware.UserIDFromContext(c)
+	return callerID == userID || c.GetString(middleware.ContextUserRole) == entity.RoleAdmin
+}
@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/internal/transport/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// telegramLinkRecorder - сервис, запоминающий привязанные чаты
+type telegramLinkRecorder struct {
+	service.UserService
+	linked map[int64]string
+}
+
+func (s *telegramLinkRecorder) LinkTelegram(ctx context.Context, userID int64, telegramID string) error {
+	s.linked[userID] = telegramID
+	return nil
+}
+
+// TestLinkTelegramOwnership проверяет, что привязать свой чат к чужому пользователю нельзя:
+// иначе через бота можно было бы подтверждать и отменять чужие бронирования
+func TestLinkTelegramOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		userID int64
+		role   string
+		want   int
+	}{
+		{"owner", 1, entity.RoleUser, http.StatusOK},
+		{"another user", 2, entity.RoleUser, http.StatusForbidden},
+		{"admin", 3, entity.RoleAdmin, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		users := &telegramLinkRecorder{linked: make(map[int64]string)}
+		router := gin.New()
+		router.POST("/users/:id/telegram", func(c *gin.Context) {
+			c.Set(middleware.ContextUserID, tc.userID)
+			c.Set(middleware.ContextUserRole, tc.role)
+		}, NewUserHandler(users).LinkTelegram)
+
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"telegram_id": "777"}`)
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users/1/telegram", body))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (body %s)", tc.name, rec.Code, tc.want, rec.Body.String())
+		}
+		if _, linked := users.linked[1]; linked != (tc.want == http.StatusOK) {
+			t.Errorf("%s: chat linked = %v", tc.name, linked)
+		}
+	}
+}
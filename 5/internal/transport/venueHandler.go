@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VenueHandler struct {
+	venueService service.VenueService
+}
+
+func NewVenueHandler(venueService service.VenueService) *VenueHandler {
+	return &VenueHandler{venueService: venueService}
+}
+
+func (h *VenueHandler) ListVenues(c *gin.Context) {
+	venues, err := h.venueService.ListVenues(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, venues)
+}
+
+func (h *VenueHandler) GetVenue(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue id"})
+		return
+	}
+
+	venue, err := h.venueService.GetVenue(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(venueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+func (h *VenueHandler) CreateVenue(c *gin.Context) {
+	var req service.CreateVenueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	venue, err := h.venueService.CreateVenue(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(venueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, venue)
+}
+
+func (h *VenueHandler) UpdateVenue(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue id"})
+		return
+	}
+
+	var req service.UpdateVenueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	venue, err := h.venueService.UpdateVenue(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(venueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+func (h *VenueHandler) DeleteVenue(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue id"})
+		return
+	}
+
+	if err := h.venueService.DeleteVenue(c.Request.Context(), id); err != nil {
+		c.JSON(venueErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "venue deleted"})
+}
+
+// venueErrorStatus сопоставляет ошибки площадок с HTTP-статусами
+func venueErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrVenueNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrVenueInUse), errors.Is(err, entity.ErrVenueCapacityExceeded):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrInvalidVenue):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// bookingExpiryKeyPrefix - ключи-таймеры бронирований; ключ живёт до expires_at бронирования
+const bookingExpiryKeyPrefix = "event_booking:expiry:"
+
+// RedisExpiryTimer взводит таймеры истечения бронирований ключами Redis с TTL.
+// Истечение ключа ловит BookingExpiryWatcher через keyspace notifications.
+//
+// В Redis Cluster уведомления публикуются только на узле, где истёк ключ, поэтому
+// таймеры там не включаются. С сентинелом CONFIG SET применяется к текущему мастеру:
+// notify-keyspace-events нужно задать и в конфигурации реплик на случай failover.
+type RedisExpiryTimer struct {
+	client redis.UniversalClient
+}
+
+func NewRedisExpiryTimer(client redis.UniversalClient) *RedisExpiryTimer {
+	return &RedisExpiryTimer{client: client}
+}
+
+// Schedule реализует service.ExpiryTimer
+func (t *RedisExpiryTimer) Schedule(ctx context.Context, bookingID int64, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	return t.client.Set(ctx, bookingExpiryKey(bookingID), expiresAt.Unix(), ttl).Err()
+}
+
+// EnableNotifications включает в Redis уведомления об истечении ключей (notify-keyspace-events Ex).
+// Управляемый Redis может запрещать CONFIG SET - тогда таймеры не работают и бронирования
+// истекают по задачам очереди и тику планировщика.
+func (t *RedisExpiryTimer) EnableNotifications(ctx context.Context) error {
+	current, err := t.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("failed to read notify-keyspace-events: %w", err)
+	}
+
+	flags := ""
+	if len(current) == 2 {
+		flags, _ = current[1].(string)
+	}
+	// E - события keyevent, x - истечение ключей; A включает x
+	if strings.Contains(flags, "E") && (strings.Contains(flags, "x") || strings.Contains(flags, "A")) {
+		return nil
+	}
+
+	if !strings.Contains(flags, "E") {
+		flags += "E"
+	}
+	if !strings.Contains(flags, "x") && !strings.Contains(flags, "A") {
+		flags += "x"
+	}
+	if err := t.client.ConfigSet(ctx, "notify-keyspace-events", flags).Err(); err != nil {
+		return fmt.Errorf("failed to enable keyspace notifications: %w", err)
+	}
+	return nil
+}
+
+// BookingExpiryWatcher истекает бронирования в момент истечения их ключей-таймеров.
+// Уведомление получает каждая реплика; повторное истечение безопасно, так как
+// UpdateStatus проверяет переход под блокировкой строки.
+type BookingExpiryWatcher struct {
+	timer          *RedisExpiryTimer
+	bookingService service.BookingService
+}
+
+func NewBookingExpiryWatcher(timer *RedisExpiryTimer, bookingService service.BookingService) *BookingExpiryWatcher {
+	return &BookingExpiryWatcher{
+		timer:          timer,
+		bookingService: bookingService,
+	}
+}
+
+func (w *BookingExpiryWatcher) Start(ctx context.Context) {
+	client := w.timer.client
+	db := 0
+	if c, ok := client.(*redis.Client); ok {
+		db = c.Options().DB
+	}
+	channel := fmt.Sprintf("__keyevent@%d__:expired", db)
+	pubsub := client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	logrus.Infof("Booking expiry watcher subscribed to %s", channel)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Booking expiry watcher stopped")
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			bookingID, ok := parseBookingExpiryKey(msg.Payload)
+			if !ok {
+				continue
+			}
+			w.expire(ctx, bookingID)
+		}
+	}
+}
+
+// expire проверяет бронирование так же, как задача истечения: таймер мог пережить
+// подтверждение брони или продление срока
+func (w *BookingExpiryWatcher) expire(ctx context.Context, bookingID int64) {
+	booking, err := w.bookingService.GetBooking(ctx, bookingID)
+	if err != nil {
+		if !errors.Is(err, entity.ErrBookingNotFound) {
+			logrus.Errorf("Failed to get booking %d on expiry notification: %v", bookingID, err)
+		}
+		return
+	}
+	if booking.Status != entity.BookingStatusPending {
+		return
+	}
+
+	// Срок перенесли, а ключ не успели обновить - взводим таймер заново
+	if time.Now().Before(booking.ExpiresAt) {
+		if err := w.timer.Schedule(ctx, booking.ID, booking.ExpiresAt); err != nil {
+			logrus.Errorf("Failed to reschedule expiry of booking %d: %v", booking.ID, err)
+		}
+		return
+	}
+
+	if err := w.bookingService.ExpireBooking(ctx, booking.ID); err != nil {
+		if !errors.Is(err, entity.ErrInvalidTransition) {
+			logrus.Errorf("Failed to expire booking %d: %v", booking.ID, err)
+		}
+		return
+	}
+	logrus.Infof("Booking %d expired on its expiry notification", booking.ID)
+}
+
+func bookingExpiryKey(bookingID int64) string {
+	return bookingExpiryKeyPrefix + strconv.FormatInt(bookingID, 10)
+}
+
+func parseBookingExpiryKey(key string) (int64, bool) {
+	if !strings.HasPrefix(key, bookingExpiryKeyPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(key, bookingExpiryKeyPrefix), 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/scheduler"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cartExpiryLockKey не даёт репликам одновременно закрывать одни и те же корзины
+const cartExpiryLockKey = "event_booking:lock:cart_expiry"
+
+// CartExpiryWorker закрывает брошенные корзины. Места корзина не держит,
+// поэтому закрытие только убирает её из выдачи и освобождает место под новую.
+type CartExpiryWorker struct {
+	cartService service.CartService
+	interval    time.Duration
+	locker      scheduler.Locker
+
+	intervalUpdates chan time.Duration
+}
+
+func NewCartExpiryWorker(cartService service.CartService, interval time.Duration, locker scheduler.Locker) *CartExpiryWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &CartExpiryWorker{
+		cartService: cartService,
+		interval:    interval,
+		locker:      locker,
+
+		intervalUpdates: make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval меняет интервал проверки с ближайшего тика; вызывается при перезагрузке конфигурации
+func (w *CartExpiryWorker) SetInterval(interval time.Duration) {
+	sendInterval(w.intervalUpdates, interval)
+}
+
+func (w *CartExpiryWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	logrus.Info("Cart expiry worker started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Cart expiry worker stopped")
+			return
+		case interval := <-w.intervalUpdates:
+			if interval != w.interval {
+				w.interval = interval
+				ticker.Reset(interval)
+				logrus.Infof("Cart expiry worker interval changed to %s", interval)
+			}
+		case <-ticker.C:
+			scheduler.RunExclusive(ctx, w.locker, cartExpiryLockKey, scheduler.LockTTL(w.interval), w.expireCarts)
+		}
+	}
+}
+
+func (w *CartExpiryWorker) expireCarts(ctx context.Context) {
+	expired, err := w.cartService.ExpireAbandonedCarts(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to expire abandoned carts: %v", err)
+		return
+	}
+
+	if expired > 0 {
+		logrus.Infof("Expired %d abandoned carts", expired)
+	}
+}
@@ -0,0 +1,18 @@
+package worker
+
+import "time"
+
+// sendInterval передаёт новый интервал циклу воркера, не дожидаясь его: непрочитанное
+// прежнее значение заменяется, так что цикл получит только последнее.
+// Отправитель один - подписчик перезагрузки конфигурации, поэтому отправка не блокируется.
+func sendInterval(updates chan time.Duration, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	select {
+	case <-updates:
+	default:
+	}
+	updates <- interval
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxRetention - сколько хранятся уже опубликованные записи
+	outboxRetention  = 7 * 24 * time.Hour
+	outboxPurgeEvery = time.Hour
+)
+
+// OutboxRelay переносит задачи из таблицы outbox в очередь.
+// Запись помечается опубликованной только после успешной публикации, поэтому
+// задача доставляется хотя бы один раз; обработчики задач должны быть идемпотентны.
+type OutboxRelay struct {
+	outboxRepo repository.OutboxRepository
+	publisher  service.TaskPublisher
+	interval   time.Duration
+}
+
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, publisher service.TaskPublisher, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		interval:   interval,
+	}
+}
+
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	purge := time.NewTicker(outboxPurgeEvery)
+	defer purge.Stop()
+
+	logrus.Info("Outbox relay started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			r.drain(ctx)
+		case <-purge.C:
+			r.purge(ctx)
+		}
+	}
+}
+
+// drain публикует пачки, пока outbox не опустеет или публикация не начнёт падать
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.outboxRepo.Relay(ctx, outboxBatchSize, func(msg *entity.OutboxMessage) error {
+			return r.publisher.Publish(ctx, &service.Task{
+				ID:         msg.TaskID,
+				Type:       msg.TaskType,
+				Data:       msg.Data,
+				ExecuteAt:  msg.ExecuteAt,
+				MaxRetries: msg.MaxRetries,
+				Priority:   msg.Priority,
+			})
+		})
+		if err != nil {
+			logrus.Errorf("Outbox relay failed: %v", err)
+			return
+		}
+		if published > 0 {
+			logrus.Debugf("Outbox relay published %d tasks", published)
+		}
+		if published < outboxBatchSize {
+			break
+		}
+	}
+
+	pending, err := r.outboxRepo.CountPending(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to count pending outbox messages: %v", err)
+		return
+	}
+	if pending > 0 {
+		logrus.Warnf("%d outbox messages are waiting to be published", pending)
+	}
+}
+
+func (r *OutboxRelay) purge(ctx context.Context) {
+	deleted, err := r.outboxRepo.DeletePublished(ctx, time.Now().Add(-outboxRetention))
+	if err != nil {
+		logrus.Errorf("Failed to purge outbox: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logrus.Infof("Purged %d published outbox messages", deleted)
+	}
+}
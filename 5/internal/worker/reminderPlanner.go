@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	repository "github.com/ds124wfegd/WB_L3/5/internal/database/postgres"
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/scheduler"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reminderPlannerLockKey не даёт репликам планировать напоминания одновременно
+	reminderPlannerLockKey = "event_booking:lock:reminder_planner"
+	reminderPlannerBatch   = 100
+)
+
+// ReminderPlanner раз в interval просматривает ближайшие мероприятия и ставит через outbox
+// задачи напоминаний (service.EventReminderOffsets) каждому подтверждённому бронированию.
+// Окно просмотра длиннее интервала, поэтому одно мероприятие попадает в несколько обходов:
+// повторные задачи отбрасывает outbox по task_id, а повторную отправку - отметка в booking_reminders.
+type ReminderPlanner struct {
+	eventService   service.EventService
+	bookingService service.BookingService
+	outboxRepo     repository.OutboxRepository
+	interval       time.Duration
+	locker         scheduler.Locker
+
+	intervalUpdates chan time.Duration
+}
+
+func NewReminderPlanner(eventService service.EventService, bookingService service.BookingService, outboxRepo repository.OutboxRepository, interval time.Duration, locker scheduler.Locker) *ReminderPlanner {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &ReminderPlanner{
+		eventService:   eventService,
+		bookingService: bookingService,
+		outboxRepo:     outboxRepo,
+		interval:       interval,
+		locker:         locker,
+
+		intervalUpdates: make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval меняет интервал обходов с ближайшего тика; вызывается при перезагрузке конфигурации
+func (p *ReminderPlanner) SetInterval(interval time.Duration) {
+	sendInterval(p.intervalUpdates, interval)
+}
+
+func (p *ReminderPlanner) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	logrus.Info("Reminder planner started")
+
+	// Первый обход сразу: после рестарта не ждём целый интервал
+	scheduler.RunExclusive(ctx, p.locker, reminderPlannerLockKey, scheduler.LockTTL(p.interval), p.plan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("Reminder planner stopped")
+			return
+		case interval := <-p.intervalUpdates:
+			if interval != p.interval {
+				p.interval = interval
+				ticker.Reset(interval)
+				logrus.Infof("Reminder planner interval changed to %s", interval)
+			}
+		case <-ticker.C:
+			scheduler.RunExclusive(ctx, p.locker, reminderPlannerLockKey, scheduler.LockTTL(p.interval), p.plan)
+		}
+	}
+}
+
+// plan планирует напоминания о мероприятиях, которые начнутся до следующего обхода
+// плюс самый ранний срок напоминания
+func (p *ReminderPlanner) plan(ctx context.Context) {
+	now := time.Now()
+	filter := &service.EventFilter{
+		DateFrom:  now,
+		DateTo:    now.Add(p.horizon()),
+		Limit:     reminderPlannerBatch,
+		SortBy:    "date",
+		SortOrder: "asc",
+	}
+
+	planned, events := 0, 0
+	for ctx.Err() == nil {
+		batch, err := p.eventService.SearchEvents(ctx, filter)
+		if err != nil {
+			logrus.Errorf("Failed to find events for reminders: %v", err)
+			return
+		}
+
+		for _, event := range batch {
+			added, err := p.planEvent(ctx, &event.Event, now)
+			if err != nil {
+				logrus.Errorf("Failed to plan reminders for event %d: %v", event.ID, err)
+				continue
+			}
+			planned += added
+			events++
+		}
+
+		if len(batch) < filter.Limit {
+			break
+		}
+		filter.Offset += len(batch)
+	}
+
+	logrus.Infof("Reminder planner: %d reminders planned for %d events", planned, events)
+}
+
+func (p *ReminderPlanner) planEvent(ctx context.Context, event *entity.Event, now time.Time) (int, error) {
+	bookings, err := p.bookingService.GetEventBookings(ctx, event.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var messages []*entity.OutboxMessage
+	for _, booking := range bookings {
+		if booking.Status != entity.BookingStatusConfirmed {
+			continue
+		}
+		for _, offset := range service.EventReminderOffsets {
+			// Срок напоминания прошёл - его заменит следующее, более близкое к началу
+			if event.Date.Add(-offset).Before(now) {
+				continue
+			}
+
+			task := service.EventReminderTask(booking, event, offset)
+			messages = append(messages, &entity.OutboxMessage{
+				TaskID:     task.ID,
+				TaskType:   task.Type,
+				Data:       task.Data,
+				ExecuteAt:  task.ExecuteAt,
+				MaxRetries: task.MaxRetries,
+				Priority:   task.Priority,
+			})
+		}
+	}
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	return p.outboxRepo.Enqueue(ctx, messages)
+}
+
+// horizon - насколько вперёд смотреть, чтобы самое раннее напоминание было запланировано
+// хотя бы одним обходом до наступления его срока
+func (p *ReminderPlanner) horizon() time.Duration {
+	var longest time.Duration
+	for _, offset := range service.EventReminderOffsets {
+		if offset > longest {
+			longest = offset
+		}
+	}
+	return longest + p.interval
+}
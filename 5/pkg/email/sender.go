@@ -0,0 +1,213 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+)
+
+// Message - письмо с текстовой и HTML-версией
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// defaultTimeout ограничивает весь SMTP-диалог, если email.timeout не задан
+const defaultTimeout = 30 * time.Second
+
+type Sender struct {
+	from     string
+	addr     string
+	host     string
+	username string
+	password string
+	timeout  time.Duration
+
+	templates *Templates
+}
+
+func NewSender(cfg *config.EmailConfig) (*Sender, error) {
+	templates, err := NewTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Sender{
+		from:      cfg.From,
+		addr:      net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:      cfg.Host,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		timeout:   timeout,
+		templates: templates,
+	}, nil
+}
+
+// SendTemplate заполняет шаблон name и отправляет письмо на адрес to
+func (s *Sender) SendTemplate(ctx context.Context, name, to string, data *TemplateData) error {
+	msg, err := s.templates.Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, msg)
+}
+
+// Send отправляет письмо через SMTP так же, как smtp.SendMail: STARTTLS включается, если сервер
+// его поддерживает, а PLAIN-аутентификация без TLS разрешена только для localhost.
+// Весь диалог ограничен таймаутом отправителя и ctx, чтобы зависший сервер не занимал воркер
+func (s *Sender) Send(ctx context.Context, msg *Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	body, err := s.build(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deliver(ctx, auth, msg.To, body); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+func (s *Sender) deliver(ctx context.Context, auth smtp.Auth, to string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// Отмена ctx прерывает чтение и запись, не дожидаясь дедлайна
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// build собирает multipart/alternative письмо, чтобы почтовые клиенты без HTML показывали текст
+func (s *Sender) build(msg *Message) ([]byte, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	header("From", s.from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		if part.content == "" {
+			continue
+		}
+
+		buf.WriteString("--" + boundary + "\r\n")
+		header("Content-Type", part.contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+func randomBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package email
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// stalledSMTP принимает подключения и ничего не отвечает, как зависший SMTP-сервер
+func stalledSMTP(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// TestSendStalledServer проверяет, что отправка на зависший сервер завершается по таймауту
+// отправителя или по отмене ctx, а не блокирует воркер навсегда
+func TestSendStalledServer(t *testing.T) {
+	msg := &Message{To: "user@example.com", Subject: "Test", Text: "body"}
+
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+	}{
+		{"sender timeout", 100 * time.Millisecond, func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		}},
+		{"context deadline", time.Minute, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		}},
+	}
+
+	for _, tc := range cases {
+		sender := &Sender{from: "noreply@example.com", addr: stalledSMTP(t), host: "127.0.0.1", timeout: tc.timeout}
+		ctx, cancel := tc.ctx()
+
+		start := time.Now()
+		err := sender.Send(ctx, msg)
+		cancel()
+
+		if err == nil {
+			t.Errorf("%s: Send to a stalled server succeeded", tc.name)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: Send returned after %v", tc.name, elapsed)
+		}
+	}
+}
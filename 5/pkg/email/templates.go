@@ -0,0 +1,195 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+)
+
+const (
+	TemplateBookingCreated   = "booking_created"
+	TemplateBookingConfirmed = "booking_confirmed"
+	TemplateBookingExpired   = "booking_expired"
+	TemplateBookingReminder  = "booking_reminder"
+	TemplateEventCancelled   = "event_cancelled"
+)
+
+// TemplateData - данные, доступные в шаблонах писем
+type TemplateData struct {
+	UserName   string
+	EventTitle string
+	EventDate  time.Time
+	BookingID  int64
+	Seats      int
+	TotalPrice float64
+	ExpiresAt  time.Time
+	Reason     string
+
+	// Изменение выполнил сотрудник от имени пользователя
+	StaffAssisted bool
+
+	// Площадка мероприятия; MapURL открывает её на карте
+	VenueName    string
+	VenueAddress string
+	MapURL       string
+}
+
+type templateSource struct {
+	subject string
+	text    string
+	html    string
+}
+
+var templateSources = map[string]templateSource{
+	TemplateBookingCreated: {
+		subject: `Бронирование #{{.BookingID}} создано: {{.EventTitle}}`,
+		text: `Здравствуйте, {{.UserName}}!
+
+Бронирование #{{.BookingID}} на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) создано.
+{{if .VenueName}}Место: {{.VenueName}}{{if .VenueAddress}}, {{.VenueAddress}}{{end}}
+{{end}}{{if .MapURL}}На карте: {{.MapURL}}
+{{end}}Количество мест: {{.Seats}}{{if .TotalPrice}}, сумма: {{money .TotalPrice}}{{end}}.
+
+Подтвердите бронирование до {{date .ExpiresAt}}, иначе оно будет отменено автоматически.{{if .StaffAssisted}}
+
+{{staffNotice}}{{end}}`,
+		html: `<p>Здравствуйте, {{.UserName}}!</p>
+<p>Бронирование <b>#{{.BookingID}}</b> на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) создано.</p>
+{{if .VenueName}}<p>Место: {{if .MapURL}}<a href="{{.MapURL}}">{{.VenueName}}</a>{{else}}{{.VenueName}}{{end}}{{if .VenueAddress}}, {{.VenueAddress}}{{end}}</p>
+{{end}}<p>Количество мест: {{.Seats}}{{if .TotalPrice}}, сумма: {{money .TotalPrice}}{{end}}.</p>
+<p>Подтвердите бронирование до <b>{{date .ExpiresAt}}</b>, иначе оно будет отменено автоматически.</p>{{if .StaffAssisted}}
+<p><i>{{staffNotice}}</i></p>{{end}}`,
+	},
+	TemplateBookingConfirmed: {
+		subject: `Бронирование #{{.BookingID}} подтверждено: {{.EventTitle}}`,
+		text: `Здравствуйте, {{.UserName}}!
+
+Бронирование #{{.BookingID}} подтверждено.
+Мероприятие: {{.EventTitle}}
+Дата: {{date .EventDate}}
+{{if .VenueName}}Место: {{.VenueName}}{{if .VenueAddress}}, {{.VenueAddress}}{{end}}
+{{end}}{{if .MapURL}}На карте: {{.MapURL}}
+{{end}}Количество мест: {{.Seats}}
+
+Ждем вас на мероприятии!{{if .StaffAssisted}}
+
+{{staffNotice}}{{end}}`,
+		html: `<p>Здравствуйте, {{.UserName}}!</p>
+<p>Бронирование <b>#{{.BookingID}}</b> подтверждено.</p>
+<ul>
+<li>Мероприятие: {{.EventTitle}}</li>
+<li>Дата: {{date .EventDate}}</li>
+{{if .VenueName}}<li>Место: {{if .MapURL}}<a href="{{.MapURL}}">{{.VenueName}}</a>{{else}}{{.VenueName}}{{end}}{{if .VenueAddress}}, {{.VenueAddress}}{{end}}</li>
+{{end}}<li>Количество мест: {{.Seats}}</li>
+</ul>
+<p>Ждем вас на мероприятии!</p>{{if .StaffAssisted}}
+<p><i>{{staffNotice}}</i></p>{{end}}`,
+	},
+	TemplateBookingExpired: {
+		subject: `Бронирование #{{.BookingID}} отменено: {{.EventTitle}}`,
+		text: `Здравствуйте, {{.UserName}}!
+
+Бронирование #{{.BookingID}} на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) было автоматически отменено,
+так как вы не подтвердили его вовремя.`,
+		html: `<p>Здравствуйте, {{.UserName}}!</p>
+<p>Бронирование <b>#{{.BookingID}}</b> на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) было автоматически отменено,
+так как вы не подтвердили его вовремя.</p>`,
+	},
+	TemplateBookingReminder: {
+		subject: `Бронирование #{{.BookingID}} ожидает подтверждения: {{.EventTitle}}`,
+		text: `Здравствуйте, {{.UserName}}!
+
+Бронирование #{{.BookingID}} на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) всё ещё не подтверждено.
+Количество мест: {{.Seats}}{{if .TotalPrice}}, сумма: {{money .TotalPrice}}{{end}}.
+
+Подтвердите его до {{date .ExpiresAt}}, иначе оно будет отменено автоматически.`,
+		html: `<p>Здравствуйте, {{.UserName}}!</p>
+<p>Бронирование <b>#{{.BookingID}}</b> на мероприятие «{{.EventTitle}}» ({{date .EventDate}}) всё ещё не подтверждено.</p>
+<p>Количество мест: {{.Seats}}{{if .TotalPrice}}, сумма: {{money .TotalPrice}}{{end}}.</p>
+<p>Подтвердите его до <b>{{date .ExpiresAt}}</b>, иначе оно будет отменено автоматически.</p>`,
+	},
+	TemplateEventCancelled: {
+		subject: `Мероприятие отменено: {{.EventTitle}}`,
+		text: `Здравствуйте, {{.UserName}}!
+
+Мероприятие «{{.EventTitle}}» ({{date .EventDate}}) отменено{{if .Reason}}: {{.Reason}}{{end}}.
+Средства за билеты будут возвращены в течение 3-5 рабочих дней.
+
+Приносим извинения за доставленные неудобства.`,
+		html: `<p>Здравствуйте, {{.UserName}}!</p>
+<p>Мероприятие «{{.EventTitle}}» ({{date .EventDate}}) отменено{{if .Reason}}: {{.Reason}}{{end}}.</p>
+<p>Средства за билеты будут возвращены в течение 3-5 рабочих дней.</p>
+<p>Приносим извинения за доставленные неудобства.</p>`,
+	},
+}
+
+var templateFuncs = map[string]interface{}{
+	"date":  func(t time.Time) string { return t.Format("02.01.2006 в 15:04") },
+	"money": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+
+	"staffNotice": func() string { return entity.StaffAssistedNotice },
+}
+
+type compiledTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Templates хранит скомпилированные шаблоны писем
+type Templates struct {
+	templates map[string]compiledTemplate
+}
+
+// NewTemplates компилирует встроенные шаблоны; ошибка означает опечатку в шаблоне
+func NewTemplates() (*Templates, error) {
+	templates := make(map[string]compiledTemplate, len(templateSources))
+	for name, src := range templateSources {
+		subject, err := texttemplate.New(name + ".subject").Funcs(templateFuncs).Parse(src.subject)
+		if err != nil {
+			return nil, fmt.Errorf("template %s subject: %w", name, err)
+		}
+		text, err := texttemplate.New(name + ".text").Funcs(templateFuncs).Parse(src.text)
+		if err != nil {
+			return nil, fmt.Errorf("template %s text: %w", name, err)
+		}
+		html, err := htmltemplate.New(name + ".html").Funcs(templateFuncs).Parse(src.html)
+		if err != nil {
+			return nil, fmt.Errorf("template %s html: %w", name, err)
+		}
+		templates[name] = compiledTemplate{subject: subject, text: text, html: html}
+	}
+
+	return &Templates{templates: templates}, nil
+}
+
+// Render заполняет шаблон и возвращает письмо для адресата to
+func (t *Templates) Render(name, to string, data *TemplateData) (*Message, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template: %s", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
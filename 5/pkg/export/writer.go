@@ -0,0 +1,95 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer построчно записывает табличные данные в поток, не накапливая их в памяти.
+// Поддерживаемые типы значений: string, int, int64, float64, bool, time.Time и nil.
+type Writer interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// NewWriter создаёт writer для формата csv или xlsx
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w, "Export")
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ContentType возвращает MIME-тип файла выгрузки
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+		// Табличные редакторы исполняют ячейки, начинающиеся с =, +, - или @, как формулы
+		if s, ok := value.(string); ok && s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+			record[i] = "'" + s
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(timeLayout)
+	default:
+		return ""
+	}
+}
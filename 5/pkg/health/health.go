@@ -0,0 +1,220 @@
+// Package health проверяет зависимости сервиса: liveness не зависит от них,
+// readiness и планировщики смотрят на результаты последней проверки.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded" // упала некритичная зависимость, сервис работает с ограничениями
+)
+
+// CheckFunc проверяет одну зависимость; ctx ограничен таймаутом проверки
+type CheckFunc func(ctx context.Context) error
+
+// RecoverFunc восстанавливает состояние, потерянное вместе с зависимостью, когда она снова доступна
+type RecoverFunc func(ctx context.Context) error
+
+// Result - итог проверки одной зависимости
+type Result struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report - итог проверки всех зависимостей. Status - down, если упала хотя бы одна критичная
+type Report struct {
+	Status    string            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Ready сообщает, может ли сервис принимать запросы
+func (r *Report) Ready() bool {
+	return r.Status != StatusDown
+}
+
+type check struct {
+	name     string
+	critical bool
+	fn       CheckFunc
+	recover  []RecoverFunc
+}
+
+// Checker хранит зарегистрированные проверки и результат последнего прогона
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []*check
+	last   *Report
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register добавляет проверку; critical - без зависимости сервис не готов принимать запросы
+func (c *Checker) Register(name string, critical bool, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, &check{name: name, critical: critical, fn: fn})
+}
+
+// OnRecover вызывает fn, когда упавшая зависимость name снова проходит проверку
+func (c *Checker) OnRecover(name string, fn RecoverFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ch := range c.checks {
+		if ch.name == name {
+			ch.recover = append(ch.recover, fn)
+			return
+		}
+	}
+	logrus.Warnf("Health check %s is not registered, recovery hook ignored", name)
+}
+
+// Check проверяет все зависимости параллельно, запоминает итог и запускает
+// восстановление для зависимостей, которые в прошлый раз были недоступны
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.RLock()
+	checks := c.checks
+	previous := c.last
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp, Checks: make(map[string]Result, len(checks)), CheckedAt: time.Now()}
+	for i, ch := range checks {
+		result := results[i]
+		report.Checks[ch.name] = result
+
+		wasDown := previous != nil && previous.Checks[ch.name].Status == StatusDown
+		if result.Status == StatusDown {
+			if !wasDown {
+				logrus.Warnf("Health check %s failed: %s", ch.name, result.Error)
+			}
+			if ch.critical {
+				report.Status = StatusDown
+			} else if report.Status == StatusUp {
+				report.Status = StatusDegraded
+			}
+			continue
+		}
+
+		if wasDown {
+			logrus.Infof("Dependency %s is available again", ch.name)
+			c.runRecovery(ctx, ch)
+		}
+	}
+
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, ch *check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result = Result{Status: StatusUp, Critical: ch.critical, CheckedAt: start}
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = StatusDown
+			result.Error = fmt.Sprintf("check panicked: %v", r)
+		}
+		result.LatencyMS = time.Since(start).Milliseconds()
+	}()
+
+	if err := ch.fn(ctx); err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) runRecovery(ctx context.Context, ch *check) {
+	for _, fn := range ch.recover {
+		recoverCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		if err := fn(recoverCtx); err != nil {
+			logrus.Errorf("Failed to recover after %s came back: %v", ch.name, err)
+		}
+		cancel()
+	}
+}
+
+// Last возвращает итог последней проверки, а если проверок ещё не было - проверяет сейчас
+func (c *Checker) Last(ctx context.Context) *Report {
+	c.mu.RLock()
+	last := c.last
+	c.mu.RUnlock()
+
+	if last != nil {
+		return last
+	}
+	return c.Check(ctx)
+}
+
+// Healthy сообщает, были ли зависимости names доступны при последней проверке.
+// До первой проверки и для незарегистрированных имён считает их доступными,
+// чтобы не останавливать работу из-за отсутствия данных
+func (c *Checker) Healthy(names ...string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.last == nil {
+		return true
+	}
+	for _, name := range names {
+		if result, ok := c.last.Checks[name]; ok && result.Status == StatusDown {
+			return false
+		}
+	}
+	return true
+}
+
+// Run проверяет зависимости раз в interval до отмены ctx; в журнал попадают только смены состояния
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
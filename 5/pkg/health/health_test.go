@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchCheck - проверка, результат которой переключает тест
+type switchCheck struct {
+	down atomic.Bool
+}
+
+func (s *switchCheck) check(ctx context.Context) error {
+	if s.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestCheckerStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		criticalDown bool
+		optionalDown bool
+		wantStatus   string
+		wantReady    bool
+	}{
+		{name: "all up", wantStatus: StatusUp, wantReady: true},
+		{name: "optional down", optionalDown: true, wantStatus: StatusDegraded, wantReady: true},
+		{name: "critical down", criticalDown: true, wantStatus: StatusDown, wantReady: false},
+		{name: "both down", criticalDown: true, optionalDown: true, wantStatus: StatusDown, wantReady: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			critical, optional := &switchCheck{}, &switchCheck{}
+			critical.down.Store(tt.criticalDown)
+			optional.down.Store(tt.optionalDown)
+
+			checker := NewChecker(time.Second)
+			checker.Register("postgres", true, critical.check)
+			checker.Register("telegram", false, optional.check)
+
+			report := checker.Check(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Fatalf("Status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if report.Ready() != tt.wantReady {
+				t.Fatalf("Ready() = %v, want %v", report.Ready(), tt.wantReady)
+			}
+			if got := checker.Healthy("postgres"); got == tt.criticalDown {
+				t.Fatalf("Healthy(postgres) = %v with postgres down = %v", got, tt.criticalDown)
+			}
+		})
+	}
+}
+
+// TestCheckerTimeout проверяет, что зависшая зависимость не задерживает проверку дольше таймаута
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Register("queue", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Check took %v, want about the timeout", elapsed)
+	}
+	if result := report.Checks["queue"]; result.Status != StatusDown || result.Error == "" {
+		t.Fatalf("queue result = %+v, want down with error", result)
+	}
+}
+
+// TestCheckerRecovery проверяет, что восстановление запускается один раз - когда зависимость вернулась
+func TestCheckerRecovery(t *testing.T) {
+	redis := &switchCheck{}
+	var recovered atomic.Int32
+
+	checker := NewChecker(time.Second)
+	checker.Register("redis", false, redis.check)
+	checker.OnRecover("redis", func(ctx context.Context) error {
+		recovered.Add(1)
+		return nil
+	})
+
+	ctx := context.Background()
+	checker.Check(ctx)
+	redis.down.Store(true)
+	checker.Check(ctx)
+	checker.Check(ctx)
+	if got := recovered.Load(); got != 0 {
+		t.Fatalf("recovered %d times while redis is down", got)
+	}
+
+	redis.down.Store(false)
+	checker.Check(ctx)
+	checker.Check(ctx)
+	if got := recovered.Load(); got != 1 {
+		t.Fatalf("recovered %d times, want 1", got)
+	}
+}
+
+func TestCheckerPanicIsDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("telegram", false, func(ctx context.Context) error {
+		panic("nil bot")
+	})
+
+	if result := checker.Check(context.Background()).Checks["telegram"]; result.Status != StatusDown {
+		t.Fatalf("telegram result = %+v, want down", result)
+	}
+}
+
+// TestHealthyBeforeFirstCheck - до первой проверки планировщики не должны останавливаться
+func TestHealthyBeforeFirstCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("postgres", true, func(ctx context.Context) error { return errors.New("down") })
+
+	if !checker.Healthy("postgres") {
+		t.Fatal("Healthy before the first check = false, want true")
+	}
+}
@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	timeLayout = "20060102T150405Z"
+	// maxLineOctets - предельная длина строки по RFC 5545, длинные строки переносятся
+	maxLineOctets = 75
+)
+
+// Event - одно событие календаря (VEVENT)
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	Updated     time.Time
+}
+
+// Calendar - календарь iCalendar (RFC 5545) для подписки из Google/Apple Calendar
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Write записывает календарь в w
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		stamp := e.Updated
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		lw.line("DTSTAMP:" + formatTime(stamp))
+		lw.line("DTSTART:" + formatTime(e.Start))
+		if !e.End.IsZero() {
+			lw.line("DTEND:" + formatTime(e.End))
+		}
+		lw.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			lw.line("LOCATION:" + escapeText(e.Location))
+		}
+		lw.line("END:VEVENT")
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escapeText экранирует значение типа TEXT
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// lineWriter пишет строки с CRLF и переносит их по 75 октетов, не разрывая UTF-8 символы
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		lw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Продолжение начинается с пробела, который тоже считается
+		limit = maxLineOctets - 1
+	}
+	lw.write(s + "\r\n")
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err == nil {
+		_, lw.err = lw.w.WriteString(s)
+	}
+}
@@ -0,0 +1,134 @@
+// Клиент сервиса обработки изображений (модуль 4): загрузка оригинала и статус его вариантов
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Статусы обработки изображения в сервисе
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+const (
+	tenantHeader   = "X-Tenant-ID"
+	maxErrorBody   = 1 << 10
+	defaultTimeout = 30 * time.Second
+)
+
+// ErrNotFound - сервис не знает изображения, например его удалили
+var ErrNotFound = errors.New("image not found")
+
+// Image - состояние изображения; Formats - пути вариантов в хранилище сервиса,
+// ссылки на них строит FormatURL
+type Image struct {
+	ID      string            `json:"id"`
+	Status  string            `json:"status"`
+	Formats map[string]string `json:"formats,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+type Client struct {
+	baseURL   string
+	publicURL string
+	tenantID  string
+	http      *http.Client
+}
+
+// NewClient: baseURL - адрес API для запросов сервиса, publicURL - для ссылок, которые
+// увидит браузер; пустой publicURL означает, что адрес один
+func NewClient(baseURL, publicURL, tenantID string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if publicURL == "" {
+		publicURL = baseURL
+	}
+
+	return &Client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		publicURL: strings.TrimRight(publicURL, "/"),
+		tenantID:  tenantID,
+		http:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Upload отправляет файл на обработку; варианты будут готовы позже, их статус возвращает Get
+func (c *Client) Upload(ctx context.Context, filename string, file io.Reader) (*Image, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/upload", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var image Image
+	if err := c.do(req, http.StatusAccepted, &image); err != nil {
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+	return &image, nil
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/image/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var image Image
+	if err := c.do(req, http.StatusOK, &image); err != nil {
+		return nil, fmt.Errorf("failed to get image %s: %w", id, err)
+	}
+	return &image, nil
+}
+
+// FormatURL - публичная ссылка на готовый вариант изображения
+func (c *Client) FormatURL(id, format string) string {
+	return c.publicURL + "/image/" + url.PathEscape(id) + "/" + url.PathEscape(format)
+}
+
+func (c *Client) do(req *http.Request, wantStatus int, out interface{}) error {
+	if c.tenantID != "" {
+		req.Header.Set(tenantHeader, c.tenantID)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != wantStatus {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,67 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientUploadAndGet(t *testing.T) {
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(tenantHeader); got != "event-booker" {
+			t.Errorf("tenant header = %q", got)
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			file, header, err := r.FormFile("image")
+			if err != nil {
+				t.Errorf("form file: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			uploaded = header.Filename + ":" + string(data)
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(Image{ID: "img-1", Status: StatusProcessing})
+		case r.Method == http.MethodGet && r.URL.Path == "/image/img-1":
+			json.NewEncoder(w).Encode(Image{ID: "img-1", Status: StatusCompleted, Formats: map[string]string{"thumbnail": "storage/processed/img-1/thumbnail"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "https://images.example.com", "event-booker", time.Second)
+	ctx := context.Background()
+
+	image, err := client.Upload(ctx, "poster.png", strings.NewReader("png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.ID != "img-1" || image.Status != StatusProcessing || uploaded != "poster.png:png" {
+		t.Fatalf("upload = %+v, server got %q", image, uploaded)
+	}
+
+	image, err = client.Get(ctx, "img-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Status != StatusCompleted || image.Formats["thumbnail"] == "" {
+		t.Fatalf("get = %+v", image)
+	}
+
+	if _, err := client.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing image error = %v, want ErrNotFound", err)
+	}
+
+	if got, want := client.FormatURL("img-1", "thumbnail"), "https://images.example.com/image/img-1/thumbnail"; got != want {
+		t.Fatalf("FormatURL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+// Package lifecycle запускает фоновые компоненты сервиса и останавливает их по порядку.
+// Компоненты запускаются в порядке регистрации, а останавливаются в обратном: сначала
+// перестают приниматься запросы, затем останавливаются производители задач, потом
+// очередь дорабатывает начатое. На всю остановку отводится общий срок.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrShutdownTimeout - компоненты не успели остановиться за отведённый срок
+var ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+
+type component struct {
+	name string
+	// run работает до отмены своего контекста; nil - у компонента есть только остановка
+	run func(ctx context.Context) error
+	// stop вызывается после отмены контекста run, например чтобы HTTP-сервер дождался запросов
+	stop func(ctx context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager хранит компоненты в порядке регистрации
+type Manager struct {
+	timeout time.Duration
+
+	mu         sync.Mutex
+	components []*component
+}
+
+func New(shutdownTimeout time.Duration) *Manager {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	return &Manager{timeout: shutdownTimeout}
+}
+
+// Add регистрирует компонент, который работает до отмены ctx. Ошибка, вернувшаяся
+// раньше отмены, останавливает весь сервис.
+func (m *Manager) Add(name string, run func(ctx context.Context) error) {
+	m.add(&component{name: name, run: run})
+}
+
+// Go регистрирует фоновый цикл без ошибок - планировщик или воркер с методом Start(ctx)
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	m.Add(name, func(ctx context.Context) error {
+		run(ctx)
+		return nil
+	})
+}
+
+// AddServer регистрирует сервер: start блокируется до остановки, stop завершает его,
+// дожидаясь начатых запросов. Ошибка start после вызова stop не считается сбоем.
+func (m *Manager) AddServer(name string, start func() error, stop func(ctx context.Context) error) {
+	m.add(&component{
+		name: name,
+		run: func(ctx context.Context) error {
+			return start()
+		},
+		stop: stop,
+	})
+}
+
+// OnStop регистрирует действие при остановке без фоновой работы, например закрытие очереди
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) {
+	m.add(&component{name: name, stop: stop})
+}
+
+func (m *Manager) add(c *component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, c)
+}
+
+// Run запускает компоненты и блокируется до отмены ctx (обычно по сигналу) или сбоя
+// одного из них, после чего останавливает все в обратном порядке. Возвращает ошибку
+// сбоя, ошибки остановки и ErrShutdownTimeout, если срок остановки истёк.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	components := m.components
+	m.mu.Unlock()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, c := range components {
+		c.done = make(chan struct{})
+		if c.run == nil {
+			close(c.done)
+			continue
+		}
+
+		// Контекст компонента не связан с ctx: его отменяет только остановка, в своём порядке
+		runCtx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		group.Go(func() error {
+			defer close(c.done)
+			err := c.run(runCtx)
+			if err != nil && runCtx.Err() == nil {
+				return fmt.Errorf("%s: %w", c.name, err)
+			}
+			return nil
+		})
+	}
+	logrus.Infof("Started %d components", len(components))
+
+	<-groupCtx.Done()
+	logrus.Info("Shutting down components")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		if err := m.stop(shutdownCtx, components[i]); err != nil {
+			errs = append(errs, err)
+			if errors.Is(err, ErrShutdownTimeout) {
+				break
+			}
+		}
+	}
+
+	// Все компоненты уже завершились, Wait только забирает ошибку сбоя
+	if len(errs) == 0 || !errors.Is(errs[len(errs)-1], ErrShutdownTimeout) {
+		if err := group.Wait(); err != nil {
+			errs = append([]error{err}, errs...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) stop(ctx context.Context, c *component) error {
+	start := time.Now()
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	// stop может не смотреть на ctx (например, Close очереди), поэтому срок проверяется снаружи
+	stopped := make(chan error, 1)
+	go func() {
+		var err error
+		if c.stop != nil {
+			err = c.stop(ctx)
+		}
+		<-c.done
+		stopped <- err
+	}()
+
+	var stopErr error
+	select {
+	case err := <-stopped:
+		if err != nil {
+			stopErr = fmt.Errorf("%s: %w", c.name, err)
+		}
+	case <-ctx.Done():
+		logrus.Errorf("Component %s did not stop before the shutdown deadline", c.name)
+		return fmt.Errorf("%s: %w", c.name, ErrShutdownTimeout)
+	}
+
+	if stopErr != nil {
+		logrus.Errorf("Component %s stopped with error: %v", c.name, stopErr)
+		return stopErr
+	}
+	logrus.Infof("Component %s stopped in %v", c.name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
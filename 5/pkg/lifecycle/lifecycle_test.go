@@ -0,0 +1,141 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder запоминает порядок остановки компонентов
+type recorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *recorder) add(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, name)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.names...)
+}
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	stopped := &recorder{}
+	m := New(time.Second)
+
+	m.OnStop("queue", func(ctx context.Context) error {
+		stopped.add("queue")
+		return nil
+	})
+	m.Go("scheduler", func(ctx context.Context) {
+		<-ctx.Done()
+		// Начатый тик дорабатывает после отмены
+		time.Sleep(20 * time.Millisecond)
+		stopped.add("scheduler")
+	})
+
+	serverStop := make(chan struct{})
+	m.AddServer("http", func() error {
+		<-serverStop
+		return http.ErrServerClosed
+	}, func(ctx context.Context) error {
+		close(serverStop)
+		stopped.add("http")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- m.Run(ctx) }()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-errs; err != nil {
+		t.Fatalf("Run returned %v, want nil", err)
+	}
+
+	want := []string{"http", "scheduler", "queue"}
+	got := stopped.get()
+	if len(got) != len(want) {
+		t.Fatalf("stop order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stop order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestManagerStopsOnComponentFailure(t *testing.T) {
+	failure := errors.New("address already in use")
+	m := New(time.Second)
+
+	stopped := false
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped = true
+	})
+	m.AddServer("http", func() error {
+		return failure
+	}, func(ctx context.Context) error {
+		return nil
+	})
+
+	err := m.Run(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("Run returned %v, want the server failure", err)
+	}
+	if !stopped {
+		t.Fatal("worker was not stopped after the server failed")
+	}
+}
+
+func TestManagerShutdownDeadline(t *testing.T) {
+	m := New(50 * time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := m.Run(ctx)
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("Run returned %v, want ErrShutdownTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %v, want about the shutdown timeout", elapsed)
+	}
+}
+
+// TestManagerDeadlineCoversStop проверяет, что срок действует и на stop, который не смотрит на ctx
+func TestManagerDeadlineCoversStop(t *testing.T) {
+	m := New(50 * time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	m.OnStop("queue", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := m.Run(ctx); !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("Run returned %v, want ErrShutdownTimeout", err)
+	}
+}
@@ -1,86 +1,360 @@
-package postgres
-
-import (
-	"database/sql"
-	"fmt"
-	"log"
-
-	"github.com/ds124wfegd/WB_L3/5/config"
-
-	_ "github.com/lib/pq"
-)
-
-func NewPostgresDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
-	)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	log.Println("Successfully connected to PostgreSQL")
-	return db, nil
-}
-
-func RunMigrations(db *sql.DB) error {
-	// Read migration files and execute them
-	// This is a simplified version - you might want to use a proper migration tool
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS events (
-			id SERIAL PRIMARY KEY,
-			title VARCHAR(255) NOT NULL,
-			description TEXT,
-			date TIMESTAMP NOT NULL,
-			total_seats INTEGER NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			name VARCHAR(255) NOT NULL,
-			telegram_id VARCHAR(100),
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		`CREATE TABLE IF NOT EXISTS bookings (
-			id SERIAL PRIMARY KEY,
-			event_id INTEGER REFERENCES events(id),
-			user_id INTEGER REFERENCES users(id),
-			seats INTEGER NOT NULL,
-			status VARCHAR(20) DEFAULT 'pending',
-			expires_at TIMESTAMP NOT NULL,
-			reservation_timeout INTEGER NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Indexes
-		`CREATE INDEX IF NOT EXISTS idx_bookings_event_id ON bookings(event_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_bookings_expires_at ON bookings(expires_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_bookings_event_status ON bookings(event_id, status)`,
-	}
-
-	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to execute migration: %v", err)
-		}
-	}
-
-	log.Println("Database migrations completed successfully")
-	return nil
-}
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+
+	_ "github.com/lib/pq"
+)
+
+func NewPostgresDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	connStr := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
+	)
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Println("Successfully connected to PostgreSQL")
+	return db, nil
+}
+
+// NewReplicaDB подключается к реплике только для чтения из cfg.ReplicaDSN с теми же
+// настройками пула, что и у основной базы. Без реплики возвращает nil
+func NewReplicaDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	if cfg.ReplicaDSN == "" {
+		return nil, nil
+	}
+
+	db, err := sql.Open("postgres", cfg.ReplicaDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping replica: %w", err)
+	}
+
+	log.Println("Successfully connected to PostgreSQL replica")
+	return db, nil
+}
+
+func RunMigrations(db *sql.DB) error {
+	// Read migration files and execute them
+	// This is a simplified version - you might want to use a proper migration tool
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS venues (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			address VARCHAR(500) NOT NULL DEFAULT '',
+			latitude DOUBLE PRECISION,
+			longitude DOUBLE PRECISION,
+			capacity INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS events (
+			id SERIAL PRIMARY KEY,
+			title VARCHAR(255) NOT NULL,
+			description TEXT,
+			date TIMESTAMP NOT NULL,
+			total_seats INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS users (
+			id SERIAL PRIMARY KEY,
+			email VARCHAR(255) UNIQUE NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			telegram_id VARCHAR(100),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS bookings (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER REFERENCES events(id),
+			user_id INTEGER REFERENCES users(id),
+			seats INTEGER NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			expires_at TIMESTAMP NOT NULL,
+			reservation_timeout INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS ticket_tiers (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			price NUMERIC(10, 2) NOT NULL DEFAULT 0,
+			seats INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (event_id, name)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS partner_pools (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			code VARCHAR(50) NOT NULL,
+			seats INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (event_id, name)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS event_holds (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			label VARCHAR(100) NOT NULL,
+			seats INTEGER NOT NULL CHECK (seats > 0),
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (event_id, label)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS promo_codes (
+			id SERIAL PRIMARY KEY,
+			code VARCHAR(50) NOT NULL,
+			event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+			discount_type VARCHAR(20) NOT NULL,
+			discount_value NUMERIC(10, 2) NOT NULL,
+			max_uses INTEGER NOT NULL DEFAULT 0,
+			used_count INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			secret VARCHAR(128) NOT NULL,
+			event_types TEXT[] NOT NULL DEFAULT '{}',
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			delivery_id VARCHAR(64) NOT NULL,
+			event_type VARCHAR(50) NOT NULL,
+			payload TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			response_status INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id BIGSERIAL PRIMARY KEY,
+			task_id VARCHAR(255) NOT NULL UNIQUE,
+			task_type VARCHAR(50) NOT NULL,
+			payload JSONB NOT NULL,
+			execute_at TIMESTAMP NOT NULL,
+			max_retries INTEGER NOT NULL DEFAULT 0,
+			priority VARCHAR(10) NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			published_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS refunds (
+			id SERIAL PRIMARY KEY,
+			booking_id INTEGER NOT NULL UNIQUE REFERENCES bookings(id),
+			event_id INTEGER NOT NULL REFERENCES events(id),
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			paid_amount NUMERIC(10, 2) NOT NULL,
+			percent NUMERIC(5, 2) NOT NULL,
+			amount NUMERIC(10, 2) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			processed_at TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS booking_reminders (
+			booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+			reminder VARCHAR(20) NOT NULL,
+			sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (booking_id, reminder)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS booking_notifications (
+			booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+			notification VARCHAR(40) NOT NULL,
+			sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (booking_id, notification)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS event_posters (
+			event_id INTEGER PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+			image_id VARCHAR(64) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'processing',
+			formats JSONB NOT NULL DEFAULT '{}',
+			error TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS event_capacity_alerts (
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			threshold INTEGER NOT NULL CHECK (threshold BETWEEN 1 AND 100),
+			fired_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (event_id, threshold)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id SERIAL PRIMARY KEY,
+			organizer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			prefix VARCHAR(16) NOT NULL,
+			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			rate_limit INTEGER NOT NULL,
+			last_used_at TIMESTAMP,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			entity_type VARCHAR(20) NOT NULL,
+			entity_id BIGINT NOT NULL,
+			action VARCHAR(30) NOT NULL,
+			old_status VARCHAR(20) NOT NULL DEFAULT '',
+			new_status VARCHAR(20) NOT NULL DEFAULT '',
+			actor_id INTEGER,
+			actor VARCHAR(20) NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS carts (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			status VARCHAR(20) NOT NULL DEFAULT 'open',
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS cart_items (
+			id SERIAL PRIMARY KEY,
+			cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			seats INTEGER NOT NULL CHECK (seats > 0),
+			tier_id INTEGER REFERENCES ticket_tiers(id) ON DELETE SET NULL,
+			promo_code VARCHAR(50) NOT NULL DEFAULT '',
+			pool_code VARCHAR(50) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (cart_id, event_id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS event_waitlist (
+			id SERIAL PRIMARY KEY,
+			event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			booking_id INTEGER NOT NULL UNIQUE REFERENCES bookings(id),
+			seats INTEGER NOT NULL CHECK (seats > 0),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT ''`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS tier_id INTEGER REFERENCES ticket_tiers(id)`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS pool_id INTEGER REFERENCES partner_pools(id)`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS total_price NUMERIC(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id)`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS extensions INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS ticket_code VARCHAR(64)`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMP`,
+		`ALTER TABLE bookings ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS location VARCHAR(500) NOT NULL DEFAULT ''`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS venue_id INTEGER REFERENCES venues(id)`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS free_cancellation_hours INTEGER NOT NULL DEFAULT 24`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS late_refund_percent NUMERIC(5, 2) NOT NULL DEFAULT 50`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS refund_rules JSONB NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS confirmation_escalation JSONB NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS sale_schedule JSONB NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS organizer_id INTEGER REFERENCES users(id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_email BOOLEAN NOT NULL DEFAULT TRUE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_telegram BOOLEAN NOT NULL DEFAULT TRUE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token_version INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_preferences JSONB NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonator_id INTEGER`,
+
+		// Indexes
+		`CREATE INDEX IF NOT EXISTS idx_bookings_event_id ON bookings(event_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_expires_at ON bookings(expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_event_status ON bookings(event_id, status)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_date ON events(date)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_venue_id ON events(venue_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_organizer_id ON events(organizer_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_ticket_tiers_event_id ON ticket_tiers(event_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_tier_id ON bookings(tier_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_pool_id ON bookings(pool_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_partner_pools_code ON partner_pools(event_id, UPPER(code))`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_promo_codes_code ON promo_codes(UPPER(code))`,
+		`CREATE INDEX IF NOT EXISTS idx_bookings_promo_code_id ON bookings(promo_code_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_event_id ON webhooks(event_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_refunds_event_id ON refunds(event_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_api_tokens_organizer_id ON api_tokens(organizer_id)`,
+		// Email удалённого пользователя можно зарегистрировать снова
+		`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users(email) WHERE deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_carts_user_open ON carts(user_id) WHERE status = 'open'`,
+		`CREATE INDEX IF NOT EXISTS idx_carts_expires_at ON carts(expires_at) WHERE status = 'open'`,
+		`CREATE INDEX IF NOT EXISTS idx_event_waitlist_event_id ON event_waitlist(event_id, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '')))`,
+	}
+
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			return fmt.Errorf("failed to execute migration: %v", err)
+		}
+	}
+
+	log.Println("Database migrations completed successfully")
+	return nil
+}
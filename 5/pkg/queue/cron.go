@@ -0,0 +1,277 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultCronInterval = 10 * time.Second
+)
+
+// cronClaimScript переносит next_run с ожидаемого значения на следующее.
+// Срабатывание публикует только реплика, которой удалось перенести next_run,
+// поэтому одна и та же итерация расписания не выполняется дважды.
+var cronClaimScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'next_run') ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'next_run', ARGV[2])
+if ARGV[3] ~= '' then
+	redis.call('HSET', KEYS[1], 'last_run', ARGV[3])
+end
+return 1
+`)
+
+// CronSchedule описывает повторяющуюся задачу
+type CronSchedule struct {
+	Name       string                 `json:"name"`
+	Spec       string                 `json:"spec"` // "*/10 * * * *", "@hourly", "@every 10m"
+	Type       TaskType               `json:"type"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Priority   Priority               `json:"priority,omitempty"`
+	MaxRetries int                    `json:"max_retries,omitempty"`
+}
+
+// CronScheduleState - сохраненное расписание с временем следующего и последнего срабатывания
+type CronScheduleState struct {
+	CronSchedule
+	NextRun time.Time `json:"next_run"`
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// CronScheduler публикует повторяющиеся задачи в RedisQueue по cron-выражениям.
+// Расписания и время следующего запуска хранятся в Redis, поэтому переживают рестарт
+// и видны всем репликам; каждая реплика проверяет все расписания, но срабатывание
+// забирает только одна из них.
+type CronScheduler struct {
+	queue    *RedisQueue
+	interval time.Duration
+}
+
+// NewCronScheduler создает планировщик, проверяющий расписания раз в interval
+func NewCronScheduler(queue *RedisQueue, interval time.Duration) *CronScheduler {
+	if interval <= 0 {
+		interval = defaultCronInterval
+	}
+	return &CronScheduler{queue: queue, interval: interval}
+}
+
+// keyPrefix - расписания хранятся под префиксом очереди, чтобы в Redis Cluster
+// индекс и расписание, которые меняются одной транзакцией, лежали в одном слоте
+func (c *CronScheduler) keyPrefix() string {
+	return c.queue.config.KeyPrefix + ":cron"
+}
+
+func (c *CronScheduler) indexKey() string {
+	return c.keyPrefix() + ":schedules"
+}
+
+func (c *CronScheduler) scheduleKey(name string) string {
+	return fmt.Sprintf("%s:schedule:%s", c.keyPrefix(), name)
+}
+
+// Register сохраняет расписание. Если выражение не изменилось, время следующего
+// запуска сохраняется, чтобы рестарт не сдвигал и не повторял срабатывания.
+func (c *CronScheduler) Register(ctx context.Context, schedule CronSchedule) error {
+	if schedule.Name == "" {
+		return fmt.Errorf("cron schedule name is required")
+	}
+	if schedule.Type == "" {
+		return fmt.Errorf("cron schedule %s: task type is required", schedule.Name)
+	}
+	expr, err := parseCron(schedule.Spec)
+	if err != nil {
+		return fmt.Errorf("cron schedule %s: %w", schedule.Name, err)
+	}
+
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cron schedule %s: %v", schedule.Name, err)
+	}
+
+	key := c.scheduleKey(schedule.Name)
+	existing, err := c.queue.client.HMGet(ctx, key, "schedule", "next_run").Result()
+	if err != nil {
+		return fmt.Errorf("failed to load cron schedule %s: %v", schedule.Name, err)
+	}
+
+	resetNextRun := existing[1] == nil
+	if raw, ok := existing[0].(string); ok {
+		var previous CronSchedule
+		if json.Unmarshal([]byte(raw), &previous) != nil || previous.Spec != schedule.Spec {
+			resetNextRun = true
+		}
+	}
+
+	pipe := c.queue.client.TxPipeline()
+	pipe.HSet(ctx, key, "schedule", data)
+	if resetNextRun {
+		pipe.HSet(ctx, key, "next_run", expr.Next(time.Now()).Unix())
+	}
+	pipe.SAdd(ctx, c.indexKey(), schedule.Name)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save cron schedule %s: %v", schedule.Name, err)
+	}
+
+	log.Printf("Cron schedule %s registered: %s -> %s", schedule.Name, schedule.Spec, schedule.Type)
+	return nil
+}
+
+// Remove удаляет расписание у всех реплик
+func (c *CronScheduler) Remove(ctx context.Context, name string) error {
+	pipe := c.queue.client.TxPipeline()
+	pipe.Del(ctx, c.scheduleKey(name))
+	pipe.SRem(ctx, c.indexKey(), name)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove cron schedule %s: %v", name, err)
+	}
+	return nil
+}
+
+// Schedules возвращает все сохраненные расписания
+func (c *CronScheduler) Schedules(ctx context.Context) ([]*CronScheduleState, error) {
+	names, err := c.queue.client.SMembers(ctx, c.indexKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cron schedules: %v", err)
+	}
+
+	states := make([]*CronScheduleState, 0, len(names))
+	for _, name := range names {
+		state, err := c.load(ctx, name)
+		if err != nil {
+			log.Printf("Skipping cron schedule %s: %v", name, err)
+			continue
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// Start проверяет расписания, пока не отменен ctx
+func (c *CronScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.tick(ctx)
+		}
+	}
+}
+
+func (c *CronScheduler) tick(ctx context.Context) {
+	names, err := c.queue.client.SMembers(ctx, c.indexKey()).Result()
+	if err != nil {
+		log.Printf("Failed to list cron schedules: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		if err := c.fire(ctx, name, now); err != nil {
+			log.Printf("Cron schedule %s: %v", name, err)
+		}
+	}
+}
+
+// fire публикует задачу расписания, если наступило время и срабатывание забрала эта реплика.
+// Пропущенные за время простоя запуски не догоняются: выполняется один, и расписание
+// продолжается от текущего момента.
+func (c *CronScheduler) fire(ctx context.Context, name string, now time.Time) error {
+	state, err := c.load(ctx, name)
+	if err != nil || state == nil {
+		return err
+	}
+	if state.NextRun.After(now) {
+		return nil
+	}
+
+	expr, err := parseCron(state.Spec)
+	if err != nil {
+		return err
+	}
+
+	due := strconv.FormatInt(state.NextRun.Unix(), 10)
+	next := strconv.FormatInt(expr.Next(now).Unix(), 10)
+	claimed, err := cronClaimScript.Run(ctx, c.queue.client, []string{c.scheduleKey(name)}, due, next, due).Int()
+	if err != nil {
+		return fmt.Errorf("failed to claim run: %v", err)
+	}
+	if claimed == 0 {
+		return nil // срабатывание забрала другая реплика
+	}
+
+	data := make(map[string]interface{}, len(state.Data)+1)
+	for k, v := range state.Data {
+		data[k] = v
+	}
+	data["scheduled_at"] = state.NextRun.Format(time.RFC3339)
+
+	task := &Task{
+		// Детерминированный ID позволяет отличить повтор итерации от следующей
+		ID:         fmt.Sprintf("cron_%s_%s", name, due),
+		Type:       state.Type,
+		Data:       data,
+		Priority:   state.Priority,
+		MaxRetries: state.MaxRetries,
+		CreatedAt:  now,
+	}
+
+	if err := c.queue.Publish(ctx, task); err != nil {
+		// Возвращаем next_run, чтобы итерацию повторила следующая проверка
+		if _, revertErr := cronClaimScript.Run(ctx, c.queue.client, []string{c.scheduleKey(name)}, next, due, "").Int(); revertErr != nil {
+			log.Printf("Failed to revert cron schedule %s: %v", name, revertErr)
+		}
+		return fmt.Errorf("failed to publish task: %v", err)
+	}
+
+	c.queue.incrementMetric(ctx, "cron_fired")
+	log.Printf("Cron schedule %s fired task %s", name, task.ID)
+	return nil
+}
+
+func (c *CronScheduler) load(ctx context.Context, name string) (*CronScheduleState, error) {
+	values, err := c.queue.client.HMGet(ctx, c.scheduleKey(name), "schedule", "next_run", "last_run").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cron schedule: %v", err)
+	}
+
+	raw, ok := values[0].(string)
+	if !ok {
+		return nil, nil // расписание удалили между SMEMBERS и HMGET
+	}
+
+	var state CronScheduleState
+	if err := json.Unmarshal([]byte(raw), &state.CronSchedule); err != nil {
+		return nil, fmt.Errorf("invalid cron schedule: %v", err)
+	}
+	state.NextRun = unixField(values[1])
+	state.LastRun = unixField(values[2])
+
+	return &state, nil
+}
+
+func unixField(value interface{}) time.Time {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
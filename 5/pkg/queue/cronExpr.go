@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronExpr - разобранное cron-выражение из пяти полей (минута, час, день месяца, месяц, день недели)
+// или интервал @every. Поля хранятся битовыми масками допустимых значений.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	// По правилам cron, если оба поля дней заданы, подходит любой из них
+	domStar, dowStar bool
+	every            time.Duration
+}
+
+type cronField struct {
+	min, max int
+}
+
+var (
+	minuteField = cronField{0, 59}
+	hourField   = cronField{0, 23}
+	domField    = cronField{1, 31}
+	monthField  = cronField{1, 12}
+	dowField    = cronField{0, 7} // 0 и 7 - воскресенье
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron разбирает выражение вида "*/10 * * * *", "0 9-18 * * 1-5", "@hourly" или "@every 90s"
+func parseCron(spec string) (*cronExpr, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("invalid cron spec %q: @every needs a duration of at least 1s", spec)
+		}
+		return &cronExpr{every: every}, nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var expr cronExpr
+	var err error
+	if expr.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: minute: %v", spec, err)
+	}
+	if expr.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: hour: %v", spec, err)
+	}
+	if expr.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: day of month: %v", spec, err)
+	}
+	if expr.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: month: %v", spec, err)
+	}
+	if expr.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: day of week: %v", spec, err)
+	}
+	if expr.dow&(1<<7) != 0 {
+		expr.dow |= 1
+	}
+	expr.domStar = strings.HasPrefix(fields[2], "*")
+	expr.dowStar = strings.HasPrefix(fields[4], "*")
+
+	// Иначе Next вернёт нулевое время, и расписание будет считаться наступившим на каждой проверке
+	if !expr.canFire() {
+		return nil, fmt.Errorf("invalid cron spec %q: no month has the given days, the schedule never fires", spec)
+	}
+
+	return &expr, nil
+}
+
+// daysInMonth - наибольшее число дней в месяце с учётом високосного февраля
+var daysInMonth = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// canFire сообщает, есть ли у выражения хотя бы одна дата. Не срабатывают только выражения
+// с днями месяца, которых нет в выбранных месяцах, вроде "0 0 31 2 *": если задан и день
+// недели, подходит любой из дней, а такой день есть в каждом месяце
+func (e *cronExpr) canFire() bool {
+	if e.every > 0 || e.domStar || !e.dowStar {
+		return true
+	}
+
+	for month := 1; month <= 12; month++ {
+		if e.month&(1<<uint(month)) == 0 {
+			continue
+		}
+		for day := 1; day <= daysInMonth[month]; day++ {
+			if e.dom&(1<<uint(day)) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parse разбирает список через запятую из "*", "n", "a-b" с необязательным шагом "/s"
+func (f cronField) parse(value string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(value, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], s
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" означает "с 5 до конца диапазона с шагом 15"
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next возвращает первый момент срабатывания строго после after
+func (e *cronExpr) Next(after time.Time) time.Time {
+	if e.every > 0 {
+		return after.Truncate(time.Second).Add(e.every)
+	}
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Выражения без дат parseCron отклоняет; пять лет покрывают любые високосные комбинации
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (e *cronExpr) matchDay(t time.Time) bool {
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+
+	if e.domStar || e.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestParseCronFields проверяет диапазоны, шаги, списки и их сочетания в полях выражения
+func TestParseCronFields(t *testing.T) {
+	cases := []struct {
+		field cronField
+		value string
+		want  []int
+	}{
+		{minuteField, "5", []int{5}},
+		{minuteField, "*/15", []int{0, 15, 30, 45}},
+		{minuteField, "5/20", []int{5, 25, 45}},
+		{hourField, "9-12", []int{9, 10, 11, 12}},
+		{hourField, "8-18/4", []int{8, 12, 16}},
+		{hourField, "1,3,22-23", []int{1, 3, 22, 23}},
+		{monthField, "2,6-7,*/6", []int{1, 2, 6, 7}},
+	}
+
+	for _, tc := range cases {
+		bits, err := tc.field.parse(tc.value)
+		if err != nil {
+			t.Errorf("parse(%q) = %v", tc.value, err)
+			continue
+		}
+		var want uint64
+		for _, v := range tc.want {
+			want |= 1 << uint(v)
+		}
+		if bits != want {
+			t.Errorf("parse(%q) = %b, want %b", tc.value, bits, want)
+		}
+	}
+}
+
+// TestParseCronRejectsInvalid проверяет синтаксические ошибки и выражения, которые никогда не срабатывают
+func TestParseCronRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"10-5 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every 500ms",
+		"@every soon",
+		// Дат нет ни в одном году
+		"0 0 31 2 *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+		"0 0 30-31 2 *",
+	} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) accepted an invalid spec", spec)
+		}
+	}
+
+	// День недели делает выражение выполнимым: подходит любой из дней
+	for _, spec := range []string{"0 0 29 2 *", "0 0 31 2 1", "0 0 31 1-2 *", "@yearly", "@every 90s"} {
+		if _, err := parseCron(spec); err != nil {
+			t.Errorf("parseCron(%q) = %v", spec, err)
+		}
+	}
+}
+
+// TestCronExprNext проверяет ближайшее срабатывание для разных видов выражений
+func TestCronExprNext(t *testing.T) {
+	after := time.Date(2026, 10, 17, 10, 7, 30, 0, time.UTC) // суббота
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/10 * * * *", time.Date(2026, 10, 17, 10, 10, 0, 0, time.UTC)},
+		{"0 9-18 * * 1-5", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1,15 * *", time.Date(2026, 11, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		// Заданы оба поля дней: подходит 20-е число или понедельник
+		{"0 12 20 * 1", time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2026, 10, 17, 10, 9, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		expr, err := parseCron(tc.spec)
+		if err != nil {
+			t.Errorf("parseCron(%q) = %v", tc.spec, err)
+			continue
+		}
+		if got := expr.Next(after); !got.Equal(tc.want) {
+			t.Errorf("Next(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+// TestCronRegisterRejectsNeverFiring проверяет, что расписание без дат не сохраняется
+// и не срабатывает на каждой проверке
+func TestCronRegisterRejectsNeverFiring(t *testing.T) {
+	q, mr := newTestRedisQueue(t)
+	scheduler := NewCronScheduler(q, time.Minute)
+
+	err := scheduler.Register(context.Background(), CronSchedule{Name: "feb31", Spec: "0 0 31 2 *", Type: TaskTypeSendEmail})
+	if err == nil {
+		t.Fatal("Register accepted a spec that never fires")
+	}
+	if mr.Exists(scheduler.scheduleKey("feb31")) {
+		t.Error("never-firing schedule was saved")
+	}
+}
@@ -0,0 +1,192 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Реализации очереди, выбираемые параметром queue.driver
+const (
+	DriverRedis    = "redis"
+	DriverKafka    = "kafka"
+	DriverRabbitMQ = "rabbitmq"
+	DriverMemory   = "memory"
+)
+
+// DispatchConfig - общие настройки выполнения задач для очередей без собственного планировщика
+// (Kafka, RabbitMQ, память): повторы, пул обработчиков и время ожидания при остановке
+type DispatchConfig struct {
+	MaxRetries      int
+	BaseDelay       time.Duration
+	Concurrency     int
+	TypeConcurrency map[TaskType]int
+	DrainTimeout    time.Duration
+}
+
+// DefaultDispatchConfig возвращает настройки по умолчанию, совпадающие с RedisQueue
+func DefaultDispatchConfig() DispatchConfig {
+	return DispatchConfig{
+		MaxRetries:   defaultMaxRetries,
+		BaseDelay:    defaultBaseDelay,
+		Concurrency:  defaultConcurrency,
+		DrainTimeout: defaultDrainTimeout,
+	}
+}
+
+// deliveryResult - чем закончилась доставка задачи; по нему брокер подтверждает сообщение
+type deliveryResult int
+
+const (
+	deliveryDone     deliveryResult = iota // выполнена или заново опубликована для повтора
+	deliveryFailed                         // исчерпала попытки
+	deliveryReturned                       // не выполнена из-за остановки, брокер должен доставить её снова
+)
+
+// dispatcher выполняет задачи, полученные от брокера без отложенной доставки:
+// ждёт ExecuteAt в памяти, не занимая слот пула, соблюдает лимиты пула,
+// повторную попытку публикует заново с задержкой, а исчерпавшую попытки задачу отдаёт onFailed
+type dispatcher struct {
+	config       DispatchConfig
+	pool         *workerPool
+	retryManager *RetryManager
+	republish    func(ctx context.Context, task *Task) error
+	onFailed     func(task *Task, err error)
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+}
+
+func newDispatcher(cfg DispatchConfig, republish func(ctx context.Context, task *Task) error, onFailed func(task *Task, err error)) *dispatcher {
+	defaults := DefaultDispatchConfig()
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaults.MaxRetries
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaults.BaseDelay
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaults.DrainTimeout
+	}
+
+	return &dispatcher{
+		config:       cfg,
+		pool:         newWorkerPool(cfg.Concurrency, cfg.TypeConcurrency),
+		retryManager: NewRetryManager(cfg.MaxRetries, cfg.BaseDelay),
+		republish:    republish,
+		onFailed:     onFailed,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// dispatch выполняет задачу в отдельной горутине и сообщает результат в done
+func (d *dispatcher) dispatch(ctx context.Context, task *Task, handler func(*Task) error, done func(deliveryResult)) {
+	if !d.pool.begin() {
+		done(deliveryReturned)
+		return
+	}
+	go func() {
+		defer d.pool.done()
+		done(d.run(ctx, task, handler))
+	}()
+}
+
+func (d *dispatcher) run(ctx context.Context, task *Task, handler func(*Task) error) deliveryResult {
+	if wait := time.Until(task.ExecuteAt); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return deliveryReturned
+		case <-d.stopChan:
+			return deliveryReturned
+		}
+	}
+
+	// Слот типа берётся первым: задача насыщенного типа не должна держать общий слот
+	releaseType, ok := d.pool.acquireType(ctx, d.stopChan, task.Type)
+	if !ok {
+		return deliveryReturned
+	}
+	defer releaseType()
+
+	if !d.pool.acquire(ctx, d.stopChan) {
+		return deliveryReturned
+	}
+	defer d.pool.release()
+
+	task.Attempts++
+	err := handler(task)
+	if err == nil {
+		return deliveryDone
+	}
+
+	if retry, delay := d.retryManager.ShouldRetry(task, err); retry {
+		log.Printf("Task %s failed (attempt %d/%d), retrying in %v: %v",
+			task.ID, task.Attempts, task.MaxRetries, delay, err)
+
+		task.ExecuteAt = time.Now().Add(delay)
+		if pubErr := d.republish(context.Background(), task); pubErr != nil {
+			// Сообщение не подтверждается, и брокер доставит его снова
+			log.Printf("Failed to schedule retry of task %s: %v", task.ID, pubErr)
+			return deliveryReturned
+		}
+		return deliveryDone
+	}
+
+	log.Printf("Task %s failed after %d attempts: %v", task.ID, task.Attempts, err)
+	d.onFailed(task, err)
+	return deliveryFailed
+}
+
+// fail отдаёт onFailed задачу, которую не удалось прочитать из сообщения брокера
+func (d *dispatcher) fail(raw []byte, err error) {
+	d.onFailed(&Task{
+		ID:        fmt.Sprintf("corrupted_%d", time.Now().UnixNano()),
+		Type:      "corrupted",
+		Data:      map[string]interface{}{"raw_data": string(raw)},
+		CreatedAt: time.Now(),
+	}, fmt.Errorf("corrupted task: %v", err))
+}
+
+// close перестаёт выполнять новые задачи и ждёт выполняющиеся не дольше DrainTimeout
+func (d *dispatcher) close() bool {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+	return d.pool.wait(d.config.DrainTimeout)
+}
+
+// setTaskDefaults проверяет задачу перед публикацией и заполняет незаданные поля
+func setTaskDefaults(task *Task, maxRetries int) error {
+	if task == nil {
+		return fmt.Errorf("task cannot be nil")
+	}
+	if task.ID == "" {
+		task.ID = generateTaskID()
+	}
+	if task.Type == "" {
+		return fmt.Errorf("task type is required")
+	}
+	if task.Data == nil {
+		task.Data = make(map[string]interface{})
+	}
+	if task.Priority == "" {
+		task.Priority = DefaultPriority(task.Type)
+	}
+	if !task.Priority.Valid() {
+		return fmt.Errorf("unknown task priority: %s", task.Priority)
+	}
+	if task.MaxRetries == 0 {
+		task.MaxRetries = maxRetries
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if task.ExecuteAt.IsZero() {
+		task.ExecuteAt = time.Now()
+	}
+
+	return nil
+}
@@ -1,256 +1,344 @@
-package queue
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"time"
-
-	"github.com/go-redis/redis/v8"
-)
-
-// DLQHandler handles failed tasks by moving them to Dead Letter Queue
-type DLQHandler interface {
-	HandleFailedTask(task *Task, err error)
-	GetFailedTasks(ctx context.Context, limit int) ([]*FailedTask, error)
-	RequeueFailedTask(ctx context.Context, taskID string) error
-	DeleteFailedTask(ctx context.Context, taskID string) error
-	GetDLQStats(ctx context.Context) (*DLQStats, error)
-}
-
-// DefaultDLQHandler is the default implementation of DLQHandler
-type DefaultDLQHandler struct {
-	client *redis.Client
-	dlq    string
-}
-
-// FailedTask represents a task that failed execution
-type FailedTask struct {
-	Task     *Task     `json:"task"`
-	Error    string    `json:"error"`
-	FailedAt time.Time `json:"failed_at"`
-	Attempts int       `json:"attempts"`
-}
-
-// DLQStats contains statistics about the Dead Letter Queue
-type DLQStats struct {
-	TotalFailed   int64     `json:"total_failed"`
-	OldestFailure time.Time `json:"oldest_failure"`
-	NewestFailure time.Time `json:"newest_failure"`
-	QueueSize     int64     `json:"queue_size"`
-}
-
-// NewDefaultDLQHandler creates a new DefaultDLQHandler
-func NewDefaultDLQHandler(client *redis.Client, dlq string) *DefaultDLQHandler {
-	return &DefaultDLQHandler{
-		client: client,
-		dlq:    dlq,
-	}
-}
-
-// HandleFailedTask stores a failed task in the DLQ
-func (d *DefaultDLQHandler) HandleFailedTask(task *Task, err error) {
-	failedTask := &FailedTask{
-		Task:     task,
-		Error:    err.Error(),
-		FailedAt: time.Now(),
-		Attempts: task.Attempts,
-	}
-
-	taskData, marshalErr := json.Marshal(failedTask)
-	if marshalErr != nil {
-		log.Printf("Failed to marshal failed task: %v", marshalErr)
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Store in DLQ with timestamp as score for sorting
-	score := float64(failedTask.FailedAt.UnixNano()) / 1e9
-	_, redisErr := d.client.ZAdd(ctx, d.dlq, &redis.Z{
-		Score:  score,
-		Member: taskData,
-	}).Result()
-
-	if redisErr != nil {
-		log.Printf("Failed to send task to DLQ: %v", redisErr)
-		return
-	}
-
-	log.Printf("Task %s moved to DLQ: %v", task.ID, err)
-}
-
-// GetFailedTasks retrieves failed tasks from DLQ
-func (d *DefaultDLQHandler) GetFailedTasks(ctx context.Context, limit int) ([]*FailedTask, error) {
-	if limit <= 0 {
-		limit = 50
-	}
-
-	// Get tasks sorted by failure time (newest first)
-	tasks, err := d.client.ZRevRangeByScore(ctx, d.dlq, &redis.ZRangeBy{
-		Min: "-inf",
-		Max: "+inf",
-	}).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get failed tasks: %v", err)
-	}
-
-	if len(tasks) > limit {
-		tasks = tasks[:limit]
-	}
-
-	var failedTasks []*FailedTask
-	for _, taskData := range tasks {
-		var failedTask FailedTask
-		if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil {
-			log.Printf("Failed to unmarshal failed task: %v", err)
-			continue
-		}
-		failedTasks = append(failedTasks, &failedTask)
-	}
-
-	return failedTasks, nil
-}
-
-// RequeueFailedTask moves a failed task back to the main queue for retry
-func (d *DefaultDLQHandler) RequeueFailedTask(ctx context.Context, taskID string) error {
-	// Get all tasks to find the one with matching ID
-	tasks, err := d.client.ZRangeByScore(ctx, d.dlq, &redis.ZRangeBy{
-		Min: "-inf",
-		Max: "+inf",
-	}).Result()
-	if err != nil {
-		return fmt.Errorf("failed to get DLQ tasks: %v", err)
-	}
-
-	for _, taskData := range tasks {
-		var failedTask FailedTask
-		if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil {
-			continue
-		}
-
-		if failedTask.Task.ID == taskID {
-			// Reset attempt count for retry
-			failedTask.Task.Attempts = 0
-			failedTask.Task.ExecuteAt = time.Now()
-
-			// Move to main queue
-			taskData, err := json.Marshal(failedTask.Task)
-			if err != nil {
-				return fmt.Errorf("failed to marshal task for requeue: %v", err)
-			}
-
-			pipe := d.client.Pipeline()
-			pipe.LPush(ctx, "event_booking:tasks", taskData)
-			pipe.ZRem(ctx, d.dlq, taskData)
-
-			_, err = pipe.Exec(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to requeue task: %v", err)
-			}
-
-			log.Printf("Task %s requeued from DLQ", taskID)
-			return nil
-		}
-	}
-
-	return fmt.Errorf("task %s not found in DLQ", taskID)
-}
-
-// DeleteFailedTask permanently removes a failed task from DLQ
-func (d *DefaultDLQHandler) DeleteFailedTask(ctx context.Context, taskID string) error {
-	tasks, err := d.client.ZRangeByScore(ctx, d.dlq, &redis.ZRangeBy{
-		Min: "-inf",
-		Max: "+inf",
-	}).Result()
-	if err != nil {
-		return fmt.Errorf("failed to get DLQ tasks: %v", err)
-	}
-
-	for _, taskData := range tasks {
-		var failedTask FailedTask
-		if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil {
-			continue
-		}
-
-		if failedTask.Task.ID == taskID {
-			if err := d.client.ZRem(ctx, d.dlq, taskData).Err(); err != nil {
-				return fmt.Errorf("failed to delete task from DLQ: %v", err)
-			}
-
-			log.Printf("Task %s deleted from DLQ", taskID)
-			return nil
-		}
-	}
-
-	return fmt.Errorf("task %s not found in DLQ", taskID)
-}
-
-// GetDLQStats returns statistics about the DLQ
-func (d *DefaultDLQHandler) GetDLQStats(ctx context.Context) (*DLQStats, error) {
-	// Get total count
-	count, err := d.client.ZCard(ctx, d.dlq).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get DLQ count: %v", err)
-	}
-
-	// Get oldest and newest failures
-	oldestTasks, err := d.client.ZRangeByScore(ctx, d.dlq, &redis.ZRangeBy{
-		Min:    "-inf",
-		Max:    "+inf",
-		Offset: 0,
-		Count:  1,
-	}).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get oldest task: %v", err)
-	}
-
-	newestTasks, err := d.client.ZRevRangeByScore(ctx, d.dlq, &redis.ZRangeBy{
-		Min:    "-inf",
-		Max:    "+inf",
-		Offset: 0,
-		Count:  1,
-	}).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get newest task: %v", err)
-	}
-
-	stats := &DLQStats{
-		QueueSize: count,
-	}
-
-	// Parse oldest failure time
-	if len(oldestTasks) > 0 {
-		var oldestTask FailedTask
-		if err := json.Unmarshal([]byte(oldestTasks[0]), &oldestTask); err == nil {
-			stats.OldestFailure = oldestTask.FailedAt
-		}
-	}
-
-	// Parse newest failure time
-	if len(newestTasks) > 0 {
-		var newestTask FailedTask
-		if err := json.Unmarshal([]byte(newestTasks[0]), &newestTask); err == nil {
-			stats.NewestFailure = newestTask.FailedAt
-		}
-	}
-
-	return stats, nil
-}
-
-// PurgeDLQ clears all tasks from the DLQ
-func (d *DefaultDLQHandler) PurgeDLQ(ctx context.Context) (int64, error) {
-	count, err := d.client.ZCard(ctx, d.dlq).Result()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get DLQ count: %v", err)
-	}
-
-	if err := d.client.Del(ctx, d.dlq).Err(); err != nil {
-		return 0, fmt.Errorf("failed to purge DLQ: %v", err)
-	}
-
-	log.Printf("DLQ purged, removed %d tasks", count)
-	return count, nil
-}
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrTaskNotFound is returned when a task with the given ID is not in the DLQ
+var ErrTaskNotFound = errors.New("task not found in DLQ")
+
+// DLQHandler handles failed tasks by moving them to Dead Letter Queue
+type DLQHandler interface {
+	HandleFailedTask(task *Task, err error)
+	GetFailedTasks(ctx context.Context, limit int) ([]*FailedTask, error)
+	RequeueFailedTask(ctx context.Context, taskID string) error
+	DeleteFailedTask(ctx context.Context, taskID string) error
+	GetDLQStats(ctx context.Context) (*DLQStats, error)
+}
+
+// DefaultDLQHandler is the default implementation of DLQHandler.
+// Failed tasks are stored in a hash keyed by task ID (<dlq>:tasks), and the sorted set <dlq>
+// indexes those IDs by failure time, so lookups by ID don't scan the whole DLQ.
+type DefaultDLQHandler struct {
+	client    redis.UniversalClient
+	dlq       string
+	tasks     string
+	mainQueue string
+}
+
+// FailedTask represents a task that failed execution
+type FailedTask struct {
+	Task     *Task     `json:"task"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	Attempts int       `json:"attempts"`
+}
+
+// DLQStats contains statistics about the Dead Letter Queue
+type DLQStats struct {
+	TotalFailed   int64     `json:"total_failed"`
+	OldestFailure time.Time `json:"oldest_failure"`
+	NewestFailure time.Time `json:"newest_failure"`
+	QueueSize     int64     `json:"queue_size"`
+}
+
+// NewDefaultDLQHandler creates a new DefaultDLQHandler
+func NewDefaultDLQHandler(client redis.UniversalClient, dlq string) *DefaultDLQHandler {
+	return &DefaultDLQHandler{
+		client:    client,
+		dlq:       dlq,
+		tasks:     dlqTasksKey(dlq),
+		mainQueue: dlqMainQueue(dlq),
+	}
+}
+
+// dlqMainQueue returns the queue requeued tasks go back to: <prefix>:tasks for the DLQ <prefix>:dlq,
+// so with ClusterKeyPrefix the requeue script touches keys of a single hash slot
+func dlqMainQueue(dlq string) string {
+	return strings.TrimSuffix(dlq, ":dlq") + ":tasks"
+}
+
+// dlqTasksKey returns the name of the hash holding failed tasks of the DLQ
+func dlqTasksKey(dlq string) string {
+	return dlq + ":tasks"
+}
+
+// isLegacyDLQEntry reports whether a sorted set member is a whole FailedTask,
+// as stored before the hash index existed, rather than a task ID
+func isLegacyDLQEntry(member string) bool {
+	return strings.HasPrefix(member, "{")
+}
+
+// HandleFailedTask stores a failed task in the DLQ
+func (d *DefaultDLQHandler) HandleFailedTask(task *Task, err error) {
+	failedTask := &FailedTask{
+		Task:     task,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+		Attempts: task.Attempts,
+	}
+
+	taskData, marshalErr := json.Marshal(failedTask)
+	if marshalErr != nil {
+		log.Printf("Failed to marshal failed task: %v", marshalErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Store in DLQ with timestamp as score for sorting
+	score := float64(failedTask.FailedAt.UnixNano()) / 1e9
+	pipe := d.client.TxPipeline()
+	pipe.HSet(ctx, d.tasks, task.ID, taskData)
+	pipe.ZAdd(ctx, d.dlq, &redis.Z{
+		Score:  score,
+		Member: task.ID,
+	})
+	_, redisErr := pipe.Exec(ctx)
+
+	if redisErr != nil {
+		log.Printf("Failed to send task to DLQ: %v", redisErr)
+		return
+	}
+
+	log.Printf("Task %s moved to DLQ: %v", task.ID, err)
+}
+
+// GetFailedTasks retrieves failed tasks from DLQ
+func (d *DefaultDLQHandler) GetFailedTasks(ctx context.Context, limit int) ([]*FailedTask, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	// Get task IDs sorted by failure time (newest first)
+	members, err := d.client.ZRevRange(ctx, d.dlq, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed tasks: %v", err)
+	}
+
+	var ids []string
+	for _, member := range members {
+		if !isLegacyDLQEntry(member) {
+			ids = append(ids, member)
+		}
+	}
+
+	stored := make(map[string]string, len(ids))
+	if len(ids) > 0 {
+		values, err := d.client.HMGet(ctx, d.tasks, ids...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get failed tasks: %v", err)
+		}
+		for i, value := range values {
+			if taskData, ok := value.(string); ok {
+				stored[ids[i]] = taskData
+			}
+		}
+	}
+
+	var failedTasks []*FailedTask
+	for _, member := range members {
+		taskData := member
+		if !isLegacyDLQEntry(member) {
+			var ok bool
+			if taskData, ok = stored[member]; !ok {
+				continue
+			}
+		}
+
+		var failedTask FailedTask
+		if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil {
+			log.Printf("Failed to unmarshal failed task: %v", err)
+			continue
+		}
+		failedTasks = append(failedTasks, &failedTask)
+	}
+
+	return failedTasks, nil
+}
+
+// requeueScript moves a failed task back to a queue only if it is still in the DLQ,
+// so two concurrent requeues of the same task don't enqueue it twice
+var requeueScript = redis.NewScript(`
+if redis.call("HDEL", KEYS[1], ARGV[1]) == 0 then
+    return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("LPUSH", KEYS[3], ARGV[2])
+return 1
+`)
+
+// RequeueFailedTask moves a failed task back to the main queue for retry
+func (d *DefaultDLQHandler) RequeueFailedTask(ctx context.Context, taskID string) error {
+	failedTask, err := d.getFailedTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	// Reset attempt count for retry
+	failedTask.Task.Attempts = 0
+	failedTask.Task.ExecuteAt = time.Now()
+
+	// Move to the queue of the task priority
+	taskData, err := json.Marshal(failedTask.Task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task for requeue: %v", err)
+	}
+
+	queueName := priorityQueueName(d.mainQueue, taskPriority(string(taskData)))
+	moved, err := requeueScript.Run(ctx, d.client, []string{d.tasks, d.dlq, queueName}, taskID, taskData).Int()
+	if err != nil {
+		return fmt.Errorf("failed to requeue task: %v", err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+
+	log.Printf("Task %s requeued from DLQ", taskID)
+	return nil
+}
+
+// DeleteFailedTask permanently removes a failed task from DLQ
+func (d *DefaultDLQHandler) DeleteFailedTask(ctx context.Context, taskID string) error {
+	pipe := d.client.TxPipeline()
+	deleted := pipe.HDel(ctx, d.tasks, taskID)
+	pipe.ZRem(ctx, d.dlq, taskID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete task from DLQ: %v", err)
+	}
+
+	if deleted.Val() == 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+
+	log.Printf("Task %s deleted from DLQ", taskID)
+	return nil
+}
+
+// getFailedTask reads a single failed task by its ID
+func (d *DefaultDLQHandler) getFailedTask(ctx context.Context, taskID string) (*FailedTask, error) {
+	taskData, err := d.client.HGet(ctx, d.tasks, taskID).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DLQ task: %v", err)
+	}
+
+	var failedTask FailedTask
+	if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil || failedTask.Task == nil {
+		return nil, fmt.Errorf("failed to unmarshal DLQ task %s: %v", taskID, err)
+	}
+	return &failedTask, nil
+}
+
+// MigrateLegacyEntries moves failed tasks stored as whole sorted set members into the hash,
+// keeping their failure time. If a task with the same ID has failed again since,
+// the newer entry wins. Returns the number of migrated entries.
+func (d *DefaultDLQHandler) MigrateLegacyEntries(ctx context.Context) (int, error) {
+	var legacy []redis.Z
+	iter := d.client.ZScan(ctx, d.dlq, 0, "{*", 100).Iterator()
+	for iter.Next(ctx) {
+		member := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+		score, err := strconv.ParseFloat(iter.Val(), 64)
+		if err != nil {
+			continue
+		}
+		legacy = append(legacy, redis.Z{Score: score, Member: member})
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to scan DLQ: %v", err)
+	}
+
+	migrated := 0
+	for _, entry := range legacy {
+		taskData := entry.Member.(string)
+
+		var failedTask FailedTask
+		if err := json.Unmarshal([]byte(taskData), &failedTask); err != nil || failedTask.Task == nil || failedTask.Task.ID == "" {
+			log.Printf("Skipping unreadable DLQ entry during migration: %v", err)
+			continue
+		}
+
+		pipe := d.client.TxPipeline()
+		pipe.HSetNX(ctx, d.tasks, failedTask.Task.ID, taskData)
+		pipe.ZAddNX(ctx, d.dlq, &redis.Z{Score: entry.Score, Member: failedTask.Task.ID})
+		pipe.ZRem(ctx, d.dlq, taskData)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return migrated, fmt.Errorf("failed to migrate DLQ task %s: %v", failedTask.Task.ID, err)
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("Migrated %d DLQ entries to the indexed format", migrated)
+	}
+	return migrated, nil
+}
+
+// GetDLQStats returns statistics about the DLQ
+func (d *DefaultDLQHandler) GetDLQStats(ctx context.Context) (*DLQStats, error) {
+	// Get total count
+	count, err := d.client.ZCard(ctx, d.dlq).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DLQ count: %v", err)
+	}
+
+	// Scores are failure times, so the ends of the index give the oldest and newest failures
+	oldest, err := d.client.ZRangeWithScores(ctx, d.dlq, 0, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oldest task: %v", err)
+	}
+
+	newest, err := d.client.ZRevRangeWithScores(ctx, d.dlq, 0, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get newest task: %v", err)
+	}
+
+	stats := &DLQStats{
+		QueueSize: count,
+	}
+	if len(oldest) > 0 {
+		stats.OldestFailure = scoreTime(oldest[0].Score)
+	}
+	if len(newest) > 0 {
+		stats.NewestFailure = scoreTime(newest[0].Score)
+	}
+
+	return stats, nil
+}
+
+// scoreTime converts a failure time score (seconds with a fractional part) back to time
+func scoreTime(score float64) time.Time {
+	sec, frac := math.Modf(score)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// PurgeDLQ clears all tasks from the DLQ
+func (d *DefaultDLQHandler) PurgeDLQ(ctx context.Context) (int64, error) {
+	count, err := d.client.ZCard(ctx, d.dlq).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get DLQ count: %v", err)
+	}
+
+	if err := d.client.Del(ctx, d.dlq, d.tasks).Err(); err != nil {
+		return 0, fmt.Errorf("failed to purge DLQ: %v", err)
+	}
+
+	log.Printf("DLQ purged, removed %d tasks", count)
+	return count, nil
+}
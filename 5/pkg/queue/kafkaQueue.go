@@ -0,0 +1,428 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaQueueConfig - настройки очереди на Kafka
+type KafkaQueueConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// DLQTopic получает задачи, исчерпавшие попытки
+	DLQTopic string
+	// DelayTopic - префикс топиков ступеней отложенной доставки: <DelayTopic>.1m, .10m, .1h, .12h
+	DelayTopic string
+
+	Dispatch DispatchConfig
+}
+
+// DefaultKafkaQueueConfig возвращает настройки по умолчанию
+func DefaultKafkaQueueConfig() *KafkaQueueConfig {
+	return &KafkaQueueConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topic:      "event_booking.tasks",
+		GroupID:    "event_booking",
+		DLQTopic:   "event_booking.dlq",
+		DelayTopic: "event_booking.tasks.delay",
+		Dispatch:   DefaultDispatchConfig(),
+	}
+}
+
+// kafkaDelayTiers - ступени отложенной доставки. Задача ждёт в топике наибольшей ступени,
+// не превышающей оставшуюся задержку, а затем уходит в основной топик или на следующую ступень.
+// Срок сообщения в топике ступени - время записи плюс длительность ступени, поэтому сроки растут
+// вместе со смещениями, и сообщение задерживает фиксацию не дольше своей ступени.
+var kafkaDelayTiers = []struct {
+	delay  time.Duration
+	suffix string
+}{
+	{time.Minute, "1m"},
+	{10 * time.Minute, "10m"},
+	{time.Hour, "1h"},
+	{12 * time.Hour, "12h"},
+}
+
+// delayTier возвращает номер ступени для оставшейся задержки или -1,
+// если задержка короче первой ступени и задачу можно выдержать в памяти обработчика
+func delayTier(remaining time.Duration) int {
+	tier := -1
+	for i, t := range kafkaDelayTiers {
+		if remaining >= t.delay {
+			tier = i
+		}
+	}
+	return tier
+}
+
+// KafkaQueue реализует Queue на Kafka. Отложенной доставки в Kafka нет: задача с задержкой
+// от минуты проходит через топики ступеней (kafkaDelayTiers), а более короткую задержку
+// обработчик выдерживает в памяти. Смещение партиции фиксируется, только когда обработаны
+// все сообщения до него: при рестарте незавершённые задачи (и выполненные после них)
+// будут прочитаны снова, обработчики должны быть идемпотентны.
+// Приоритеты задач Kafka не поддерживает.
+type KafkaQueue struct {
+	writer       *kafka.Writer
+	reader       *kafka.Reader
+	delayReaders []*kafka.Reader
+	config       *KafkaQueueConfig
+	dispatcher   *dispatcher
+	offsets      *offsetTracker
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewKafkaQueue создаёт очередь на Kafka; топики должны существовать или создаваться брокером автоматически
+func NewKafkaQueue(cfg *KafkaQueueConfig) (*KafkaQueue, error) {
+	if cfg == nil {
+		cfg = DefaultKafkaQueueConfig()
+	}
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are not configured")
+	}
+
+	// Проверяем доступность брокера, как RedisQueue проверяет Redis
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := kafka.DialContext(ctx, "tcp", cfg.Brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %v", err)
+	}
+	conn.Close()
+
+	if cfg.DelayTopic == "" {
+		cfg.DelayTopic = cfg.Topic + ".delay"
+	}
+
+	q := &KafkaQueue{
+		// Ключ сообщения - ID задачи, поэтому повторы задачи попадают в ту же партицию
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		},
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    cfg.Topic,
+			GroupID:  cfg.GroupID,
+			MinBytes: 1,
+			MaxBytes: 10e6, // 10MB
+		}),
+		config:  cfg,
+		offsets: newOffsetTracker(),
+	}
+	q.dispatcher = newDispatcher(cfg.Dispatch, q.Publish, q.moveToDLQ)
+
+	// Ступени читает отдельная группа: их сообщения не смешиваются с задачами основного топика
+	for i := range kafkaDelayTiers {
+		q.delayReaders = append(q.delayReaders, kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    q.delayTopic(i),
+			GroupID:  cfg.GroupID + ".delay",
+			MinBytes: 1,
+			MaxBytes: 10e6, // 10MB
+		}))
+	}
+
+	log.Printf("KafkaQueue initialized: topic=%s, group=%s, dlq=%s, delay=%s.*, workers=%d",
+		cfg.Topic, cfg.GroupID, cfg.DLQTopic, cfg.DelayTopic, cap(q.dispatcher.pool.slots))
+
+	return q, nil
+}
+
+// Publish отправляет задачу в топик очереди
+func (q *KafkaQueue) Publish(ctx context.Context, task *Task) error {
+	if err := setTaskDefaults(task, q.dispatcher.config.MaxRetries); err != nil {
+		return fmt.Errorf("invalid task: %v", err)
+	}
+
+	body, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %v", err)
+	}
+
+	topic := q.config.Topic
+	if tier := delayTier(time.Until(task.ExecuteAt)); tier >= 0 {
+		topic = q.delayTopic(tier)
+	}
+
+	err = q.writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(task.ID),
+		Value: body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish task: %v", err)
+	}
+
+	return nil
+}
+
+// Subscribe начинает читать топик в составе группы
+func (q *KafkaQueue) Subscribe(ctx context.Context, handler func(*Task) error) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	q.mu.Lock()
+	q.cancel = cancel
+	q.mu.Unlock()
+
+	q.wg.Add(1)
+	go q.consume(fetchCtx, ctx, handler)
+
+	for i, reader := range q.delayReaders {
+		q.wg.Add(1)
+		go q.consumeDelayed(fetchCtx, reader, kafkaDelayTiers[i].delay)
+	}
+
+	log.Println("KafkaQueue subscriber started")
+	return nil
+}
+
+func (q *KafkaQueue) consume(fetchCtx, ctx context.Context, handler func(*Task) error) {
+	defer q.wg.Done()
+
+	for {
+		msg, err := q.reader.FetchMessage(fetchCtx)
+		if err != nil {
+			if fetchCtx.Err() != nil {
+				log.Println("KafkaQueue consumer stopped")
+				return
+			}
+			log.Printf("Error reading task from %s: %v", q.config.Topic, err)
+			time.Sleep(time.Second) // Backoff on error
+			continue
+		}
+
+		tracked := q.offsets.track(msg)
+
+		var task Task
+		if err := json.Unmarshal(msg.Value, &task); err != nil {
+			log.Printf("Failed to unmarshal task: %v", err)
+			q.dispatcher.fail(msg.Value, err)
+			q.complete(tracked)
+			continue
+		}
+
+		// Задача с долгой задержкой, записанная в основной топик до появления ступеней,
+		// переводится на ступень, чтобы не держать фиксацию смещения партиции
+		if delayTier(time.Until(task.ExecuteAt)) >= 0 {
+			if err := q.Publish(ctx, &task); err == nil {
+				q.complete(tracked)
+				continue
+			}
+			log.Printf("Failed to move delayed task %s to a delay topic, waiting in memory: %v", task.ID, err)
+		}
+
+		q.dispatcher.dispatch(ctx, &task, handler, func(result deliveryResult) {
+			if result != deliveryReturned {
+				q.complete(tracked)
+			}
+		})
+	}
+}
+
+// delayTopic возвращает топик ступени отложенной доставки
+func (q *KafkaQueue) delayTopic(tier int) string {
+	return q.config.DelayTopic + "." + kafkaDelayTiers[tier].suffix
+}
+
+// consumeDelayed читает топик ступени и, когда сообщение выдержано, публикует задачу заново:
+// в основной топик или на следующую ступень. Смещение фиксируется после публикации,
+// поэтому при рестарте задача не теряется, а в худшем случае публикуется дважды.
+func (q *KafkaQueue) consumeDelayed(ctx context.Context, reader *kafka.Reader, delay time.Duration) {
+	defer q.wg.Done()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error reading delayed task from %s: %v", reader.Config().Topic, err)
+			time.Sleep(time.Second) // Backoff on error
+			continue
+		}
+
+		if !sleepContext(ctx, time.Until(msg.Time.Add(delay))) {
+			return
+		}
+
+		var task Task
+		if err := json.Unmarshal(msg.Value, &task); err != nil {
+			log.Printf("Failed to unmarshal delayed task: %v", err)
+			q.dispatcher.fail(msg.Value, err)
+		} else {
+			for {
+				err := q.Publish(ctx, &task)
+				if err == nil {
+					break
+				}
+				log.Printf("Failed to forward delayed task %s: %v", task.ID, err)
+				if !sleepContext(ctx, time.Second) {
+					return
+				}
+			}
+		}
+
+		commitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := reader.CommitMessages(commitCtx, msg); err != nil {
+			log.Printf("Failed to commit offset %d of %s: %v", msg.Offset, reader.Config().Topic, err)
+		}
+		cancel()
+	}
+}
+
+// sleepContext ждёт d и возвращает false, если ctx отменён раньше
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// complete отмечает сообщение обработанным и фиксирует смещение, если это возможно
+func (q *KafkaQueue) complete(tracked *trackedMessage) {
+	msg, ok := q.offsets.complete(tracked)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.reader.CommitMessages(ctx, msg); err != nil {
+		log.Printf("Failed to commit offset %d of partition %d: %v", msg.Offset, msg.Partition, err)
+	}
+}
+
+// moveToDLQ отправляет исчерпавшую попытки задачу в топик DLQ
+func (q *KafkaQueue) moveToDLQ(task *Task, err error) {
+	if q.config.DLQTopic == "" {
+		log.Printf("Task %s dropped, DLQ topic is not configured: %v", task.ID, err)
+		return
+	}
+
+	body, marshalErr := json.Marshal(&FailedTask{
+		Task:     task,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+		Attempts: task.Attempts,
+	})
+	if marshalErr != nil {
+		log.Printf("Failed to marshal failed task: %v", marshalErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	writeErr := q.writer.WriteMessages(ctx, kafka.Message{
+		Topic: q.config.DLQTopic,
+		Key:   []byte(task.ID),
+		Value: body,
+	})
+	if writeErr != nil {
+		log.Printf("Failed to send task %s to DLQ: %v", task.ID, writeErr)
+		return
+	}
+
+	log.Printf("Task %s moved to DLQ %s: %v", task.ID, q.config.DLQTopic, err)
+}
+
+// Close перестаёт читать топик, ждёт выполняющиеся задачи и закрывает соединения
+func (q *KafkaQueue) Close() error {
+	q.mu.Lock()
+	cancel := q.cancel
+	q.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		q.wg.Wait()
+	}
+
+	if !q.dispatcher.close() {
+		log.Printf("KafkaQueue drain timed out after %v, unfinished tasks will be read again", q.dispatcher.config.DrainTimeout)
+	}
+
+	if err := q.reader.Close(); err != nil {
+		return fmt.Errorf("failed to close Kafka reader: %v", err)
+	}
+	for _, reader := range q.delayReaders {
+		if err := reader.Close(); err != nil {
+			return fmt.Errorf("failed to close Kafka delay reader: %v", err)
+		}
+	}
+	if err := q.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close Kafka writer: %v", err)
+	}
+
+	log.Println("KafkaQueue closed successfully")
+	return nil
+}
+
+// trackedMessage - прочитанное сообщение, ожидающее завершения обработки
+type trackedMessage struct {
+	msg  kafka.Message
+	done bool
+}
+
+// offsetTracker хранит прочитанные, но не зафиксированные сообщения каждой партиции
+// в порядке смещений. Задачи завершаются не по порядку, а смещение Kafka фиксирует
+// сразу все предыдущие сообщения, поэтому фиксируется только непрерывный завершённый префикс.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int][]*trackedMessage
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int][]*trackedMessage)}
+}
+
+func (t *offsetTracker) track(msg kafka.Message) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked := &trackedMessage{msg: msg}
+	t.partitions[msg.Partition] = append(t.partitions[msg.Partition], tracked)
+	return tracked
+}
+
+// complete отмечает сообщение завершённым и возвращает последнее сообщение
+// завершённого префикса партиции, если префикс вырос
+func (t *offsetTracker) complete(tracked *trackedMessage) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked.done = true
+
+	pending := t.partitions[tracked.msg.Partition]
+	n := 0
+	for n < len(pending) && pending[n].done {
+		n++
+	}
+	if n == 0 {
+		return kafka.Message{}, false
+	}
+
+	t.partitions[tracked.msg.Partition] = pending[n:]
+	return pending[n-1].msg, true
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayTierPicksLargestTierWithinDelay(t *testing.T) {
+	cases := []struct {
+		remaining time.Duration
+		want      int
+	}{
+		{-time.Second, -1},
+		{0, -1},
+		{59 * time.Second, -1},
+		{time.Minute, 0},
+		{9 * time.Minute, 0},
+		{10 * time.Minute, 1},
+		{59 * time.Minute, 1},
+		{3 * time.Hour, 2},
+		{12 * time.Hour, 3},
+		{30 * 24 * time.Hour, 3},
+	}
+
+	for _, tc := range cases {
+		if got := delayTier(tc.remaining); got != tc.want {
+			t.Errorf("delayTier(%v) = %d, want %d", tc.remaining, got, tc.want)
+		}
+	}
+}
+
+// Задача проходит ступени, пока остаток не станет короче первой, и ни одно сообщение
+// не ждёт в топике ступени дольше самой ступени
+func TestDelayTiersReachDeadline(t *testing.T) {
+	for _, delay := range []time.Duration{90 * time.Second, 25 * time.Minute, 26*time.Hour + 7*time.Minute, 30 * 24 * time.Hour} {
+		remaining, hops := delay, 0
+		for tier := delayTier(remaining); tier >= 0; tier = delayTier(remaining) {
+			remaining -= kafkaDelayTiers[tier].delay
+			hops++
+			if hops > 100 {
+				t.Fatalf("delay %v: too many hops", delay)
+			}
+		}
+		if remaining < 0 || remaining >= kafkaDelayTiers[0].delay {
+			t.Errorf("delay %v: %v left for the in-memory wait", delay, remaining)
+		}
+	}
+}
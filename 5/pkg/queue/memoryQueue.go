@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// MemoryQueue хранит задачи в памяти процесса. Предназначена для тестов и локального запуска
+// без брокера: при остановке невыполненные задачи теряются.
+type MemoryQueue struct {
+	dispatcher *dispatcher
+	config     DispatchConfig
+
+	mu      sync.Mutex
+	ctx     context.Context
+	handler func(*Task) error
+	backlog []*Task
+	failed  []*FailedTask
+	closed  bool
+}
+
+// NewMemoryQueue создаёт очередь в памяти
+func NewMemoryQueue(cfg *DispatchConfig) *MemoryQueue {
+	if cfg == nil {
+		defaults := DefaultDispatchConfig()
+		cfg = &defaults
+	}
+
+	q := &MemoryQueue{}
+	q.dispatcher = newDispatcher(*cfg, q.Publish, q.recordFailure)
+	q.config = q.dispatcher.config
+	return q
+}
+
+// Publish ставит задачу в очередь. Задачи, опубликованные до Subscribe, ждут обработчика.
+func (q *MemoryQueue) Publish(ctx context.Context, task *Task) error {
+	if err := setTaskDefaults(task, q.config.MaxRetries); err != nil {
+		return fmt.Errorf("invalid task: %v", err)
+	}
+
+	// Задача копируется, чтобы изменения у вызывающего не влияли на очередь
+	queued := *task
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("queue is closed")
+	}
+	if q.handler == nil {
+		q.backlog = append(q.backlog, &queued)
+		return nil
+	}
+
+	q.dispatcher.dispatch(q.ctx, &queued, q.handler, func(deliveryResult) {})
+	return nil
+}
+
+// Subscribe начинает выполнять задачи; у очереди в памяти может быть только один обработчик
+func (q *MemoryQueue) Subscribe(ctx context.Context, handler func(*Task) error) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.handler != nil {
+		return fmt.Errorf("memory queue already has a subscriber")
+	}
+	q.ctx = ctx
+	q.handler = handler
+
+	for _, task := range q.backlog {
+		q.dispatcher.dispatch(ctx, task, handler, func(deliveryResult) {})
+	}
+	q.backlog = nil
+
+	log.Println("MemoryQueue subscriber started")
+	return nil
+}
+
+// Failed возвращает задачи, исчерпавшие попытки, - аналог DLQ для тестов
+func (q *MemoryQueue) Failed() []*FailedTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	failed := make([]*FailedTask, len(q.failed))
+	copy(failed, q.failed)
+	return failed
+}
+
+func (q *MemoryQueue) recordFailure(task *Task, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.failed = append(q.failed, &FailedTask{
+		Task:     task,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+		Attempts: task.Attempts,
+	})
+}
+
+// Close перестаёт принимать задачи и ждёт выполняющиеся
+func (q *MemoryQueue) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	if !q.dispatcher.close() {
+		log.Printf("MemoryQueue drain timed out after %v", q.config.DrainTimeout)
+	}
+	return nil
+}
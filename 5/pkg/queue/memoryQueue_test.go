@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestMemoryQueue(t *testing.T) *MemoryQueue {
+	t.Helper()
+
+	q := NewMemoryQueue(&DispatchConfig{
+		MaxRetries:   3,
+		BaseDelay:    10 * time.Millisecond,
+		Concurrency:  2,
+		DrainTimeout: time.Second,
+	})
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestMemoryQueueRunsDelayedTaskWhenDue(t *testing.T) {
+	q := newTestMemoryQueue(t)
+	ctx := context.Background()
+
+	executeAt := time.Now().Add(100 * time.Millisecond)
+	if err := q.Publish(ctx, &Task{Type: TaskTypeSendEmail, ExecuteAt: executeAt}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	ran := make(chan time.Time, 1)
+	if err := q.Subscribe(ctx, func(task *Task) error {
+		ran <- time.Now()
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	select {
+	case at := <-ran:
+		if at.Before(executeAt) {
+			t.Fatalf("task ran at %v, before ExecuteAt %v", at, executeAt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("delayed task was not executed")
+	}
+}
+
+func TestMemoryQueueRetriesThenFails(t *testing.T) {
+	q := newTestMemoryQueue(t)
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	if err := q.Subscribe(ctx, func(task *Task) error {
+		attempts.Add(1)
+		return errors.New("smtp timeout")
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := q.Publish(ctx, &Task{ID: "email_1", Type: TaskTypeSendEmail}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(q.Failed()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("task did not fail, attempts = %d", attempts.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	failed := q.Failed()[0]
+	if failed.Task.ID != "email_1" || failed.Attempts != 3 {
+		t.Fatalf("failed task = %s after %d attempts; want email_1 after 3", failed.Task.ID, failed.Attempts)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("handler called %d times, want 3", got)
+	}
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "event_booking_queue"
+
+// collectTimeout ограничивает чтение длин очередей из Redis при сборе метрик
+const collectTimeout = 3 * time.Second
+
+// Collector экспортирует метрики очереди в Prometheus. Длины очередей и размер DLQ
+// читаются из Redis при каждом сборе и поэтому общие для всех реплик; счётчики задач
+// и время работы обработчиков ведутся в памяти процесса, который их выполнял.
+type Collector struct {
+	queue *RedisQueue
+
+	depth        *prometheus.Desc
+	dlqSize      *prometheus.Desc
+	collectError *prometheus.Desc
+
+	tasks     *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	dlq       *prometheus.CounterVec
+	recovered *prometheus.CounterVec
+}
+
+func newCollector(queue *RedisQueue) *Collector {
+	return &Collector{
+		queue: queue,
+		depth: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "depth"),
+			"Number of tasks waiting in a queue list.",
+			[]string{"queue"}, nil,
+		),
+		dlqSize: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "dlq_size"),
+			"Number of tasks in the dead letter queue.",
+			nil, nil,
+		),
+		collectError: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "collect_error"),
+			"1 if queue lengths could not be read from Redis during this scrape.",
+			nil, nil,
+		),
+		tasks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tasks_total",
+			Help:      "Handler attempts by task type and outcome (success or failure).",
+		}, []string{"type", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Handler latency by task type and outcome.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"type", "status"}),
+		dlq: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "dlq_tasks_total",
+			Help:      "Tasks moved to the dead letter queue by task type.",
+		}, []string{"type"}),
+		recovered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "recovered_tasks_total",
+			Help:      "Stuck tasks returned to the queue after the visibility timeout.",
+		}, []string{"type"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+	ch <- c.dlqSize
+	ch <- c.collectError
+	c.tasks.Describe(ch)
+	c.duration.Describe(ch)
+	c.dlq.Describe(ch)
+	c.recovered.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	stats, err := c.queue.GetQueueStats(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.collectError, prometheus.GaugeValue, 1)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.collectError, prometheus.GaugeValue, 0)
+		for _, p := range priorities {
+			ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(stats.Priorities[p]), string(p))
+		}
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(stats.DelayedQueue), "delayed")
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(stats.ProcessingQueue), "processing")
+		ch <- prometheus.MustNewConstMetric(c.dlqSize, prometheus.GaugeValue, float64(stats.DLQ))
+	}
+
+	c.tasks.Collect(ch)
+	c.duration.Collect(ch)
+	c.dlq.Collect(ch)
+	c.recovered.Collect(ch)
+}
+
+func (c *Collector) observeTask(taskType TaskType, status string, duration time.Duration) {
+	c.tasks.WithLabelValues(string(taskType), status).Inc()
+	c.duration.WithLabelValues(string(taskType), status).Observe(duration.Seconds())
+}
+
+func (c *Collector) observeDLQ(taskType TaskType) {
+	c.dlq.WithLabelValues(string(taskType)).Inc()
+}
+
+func (c *Collector) observeRecovered(taskType TaskType) {
+	c.recovered.WithLabelValues(string(taskType)).Inc()
+}
+
+// Collector возвращает сборщик метрик очереди для регистрации в prometheus.Registry
+func (r *RedisQueue) Collector() *Collector {
+	return r.metrics
+}
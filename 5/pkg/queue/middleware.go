@@ -0,0 +1,193 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/pkg/requestid"
+	"github.com/ds124wfegd/WB_L3/5/pkg/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrTaskPanic - обработчик запаниковал; задача повторяется как после обычной ошибки
+	ErrTaskPanic = errors.New("паника в обработчике задачи")
+	// ErrTaskTimeout - обработчик не уложился в срок и вернул ошибку после отмены контекста
+	ErrTaskTimeout = errors.New("истек срок обработки задачи")
+)
+
+// RequestIDMiddleware возвращает в контекст обработчика ID запроса, породившего задачу.
+// Ставится первым: по нему пишет журнал, а задачи, которые опубликует обработчик, наследуют ID.
+func RequestIDMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) error {
+			return next(requestid.WithID(ctx, requestid.FromTaskData(task.Data)), task)
+		}
+	}
+}
+
+// TracingMiddleware открывает span обработки задачи, продолжая трассу, записанную в ней при публикации.
+// Ставится сразу после RequestIDMiddleware, чтобы span охватывал повторы и панику обработчика.
+func TracingMiddleware() Middleware {
+	tracer := tracing.Tracer()
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) error {
+			ctx, span := tracer.Start(tracing.ExtractTask(ctx, task.Data), "process "+string(task.Type),
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					semconv.MessagingOperationTypeProcess,
+					semconv.MessagingDestinationName(string(task.Type)),
+					semconv.MessagingMessageID(task.ID),
+					attribute.Int("messaging.task.attempt", task.Attempts),
+				),
+			)
+			defer span.End()
+
+			err := next(ctx, task)
+			tracing.RecordError(span, err)
+			return err
+		}
+	}
+}
+
+// LoggingMiddleware пишет в лог начало обработки задачи и ошибку, если она была;
+// записи содержат request_id, если задачу породил HTTP-запрос
+func LoggingMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) error {
+			entry := requestid.Entry(ctx).WithFields(logrus.Fields{
+				"task_id":   task.ID,
+				"task_type": task.Type,
+				"attempt":   task.Attempts,
+			})
+			entry.Infof("Обработка задачи %s типа %s (попытка %d/%d)",
+				task.ID, task.Type, task.Attempts, task.MaxRetries)
+
+			startTime := time.Now()
+			err := next(ctx, task)
+			if err != nil {
+				entry.WithField("duration", time.Since(startTime)).Errorf("Задача %s типа %s завершилась ошибкой за %v: %v",
+					task.ID, task.Type, time.Since(startTime), err)
+			}
+			return err
+		}
+	}
+}
+
+// RecoveryMiddleware превращает панику обработчика в ошибку ErrTaskPanic, чтобы она
+// не останавливала воркер, а задача повторялась и при необходимости уходила в DLQ
+func RecoveryMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					log.Printf("Паника при обработке задачи %s типа %s: %v\n%s", task.ID, task.Type, p, debug.Stack())
+					err = fmt.Errorf("%w: %v", ErrTaskPanic, p)
+				}
+			}()
+			return next(ctx, task)
+		}
+	}
+}
+
+// TimeoutMiddleware ограничивает время обработки задачи: по истечении срока контекст
+// обработчика отменяется. Срок типа из perType важнее общего; нулевой срок не ограничивает.
+// Общий срок стоит держать меньше таймаута видимости очереди, иначе задачу вернут в очередь
+// раньше, чем обработчик остановится.
+func TimeoutMiddleware(timeout time.Duration, perType map[TaskType]time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) error {
+			limit := timeout
+			if typeLimit, ok := perType[task.Type]; ok {
+				limit = typeLimit
+			}
+			if limit <= 0 {
+				return next(ctx, task)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, limit)
+			defer cancel()
+
+			err := next(ctx, task)
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w (%v): %v", ErrTaskTimeout, limit, err)
+			}
+			return err
+		}
+	}
+}
+
+// HandlerMetrics считает результаты обработчиков реестра. В отличие от метрик RedisQueue
+// работает с любой очередью и различает ошибки, панику и превышение срока.
+type HandlerMetrics struct {
+	runs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewHandlerMetrics создает метрики обработчиков для регистрации в prometheus.Registry
+func NewHandlerMetrics() *HandlerMetrics {
+	return &HandlerMetrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "handler",
+			Name:      "runs_total",
+			Help:      "Registry handler runs by task type and outcome (success, error, panic or timeout).",
+		}, []string{"type", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "handler",
+			Name:      "run_duration_seconds",
+			Help:      "Registry handler latency by task type.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"type"}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *HandlerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.runs.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *HandlerMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.runs.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+// Middleware возвращает middleware, записывающий результат и время каждого запуска
+func (m *HandlerMetrics) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, task *Task) error {
+			startTime := time.Now()
+			err := next(ctx, task)
+
+			m.runs.WithLabelValues(string(task.Type), handlerOutcome(err)).Inc()
+			m.duration.WithLabelValues(string(task.Type)).Observe(time.Since(startTime).Seconds())
+			return err
+		}
+	}
+}
+
+func handlerOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrTaskPanic):
+		return "panic"
+	case errors.Is(err, ErrTaskTimeout):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Priority определяет, из какого списка задача будет взята обработчиком
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// priorities перечислены от высшего приоритета к низшему
+var priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// DefaultPriorityWeights - доли, с которыми каждый список опрашивается первым.
+// Низкий приоритет тоже иногда идёт первым, поэтому массовые уведомления не голодают.
+func DefaultPriorityWeights() map[Priority]int {
+	return map[Priority]int{
+		PriorityHigh:   6,
+		PriorityNormal: 3,
+		PriorityLow:    1,
+	}
+}
+
+// Valid проверяет, что приоритет известен
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	}
+	return false
+}
+
+// DefaultPriority возвращает приоритет задачи, для которой он не указан явно:
+// истечение бронирований не должно ждать за массовыми уведомлениями
+func DefaultPriority(taskType TaskType) Priority {
+	switch taskType {
+	case TaskTypeExpireBooking, TaskTypeCleanupExpired:
+		return PriorityHigh
+	case TaskTypeSendNotification, TaskTypeSendEmail, TaskTypeEventReminder:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// priorityQueueName возвращает имя списка для приоритета. Обычный приоритет
+// использует основной список, поэтому задачи, опубликованные до появления
+// приоритетов, продолжают обрабатываться.
+func priorityQueueName(mainQueue string, p Priority) string {
+	if p == PriorityNormal {
+		return mainQueue
+	}
+	return fmt.Sprintf("%s:%s", mainQueue, p)
+}
+
+// weightedOrder возвращает приоритеты в порядке опроса: первый выбирается случайно
+// пропорционально весу, остальные следуют от высшего к низшему
+func weightedOrder(weights map[Priority]int) []Priority {
+	total := 0
+	for _, p := range priorities {
+		total += weights[p]
+	}
+
+	first := PriorityHigh
+	if total > 0 {
+		n := rand.Intn(total)
+		for _, p := range priorities {
+			if n < weights[p] {
+				first = p
+				break
+			}
+			n -= weights[p]
+		}
+	}
+
+	order := make([]Priority, 0, len(priorities))
+	order = append(order, first)
+	for _, p := range priorities {
+		if p != first {
+			order = append(order, p)
+		}
+	}
+	return order
+}
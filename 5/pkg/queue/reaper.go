@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultMaxRecoveries     = 3
+)
+
+// recoverScript атомарно забирает задачу из списка обработки: если запись ещё там,
+// она удаляется вместе с отметкой и, когда передан ARGV[2], возвращается в KEYS[3].
+// Если запись уже удалена завершившимся обработчиком, скрипт только чистит отметку.
+var recoverScript = redis.NewScript(`
+local removed = redis.call('LREM', KEYS[1], 1, ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+if removed == 0 then
+	return 0
+end
+if ARGV[2] ~= '' then
+	redis.call('LPUSH', KEYS[3], ARGV[2])
+end
+return 1
+`)
+
+// claimsKey - ZSET с временем последней отметки каждой задачи из списка обработки
+func (r *RedisQueue) claimsKey() string {
+	return r.processingQueue + ":claims"
+}
+
+// heartbeat продлевает отметку задачи, пока её выполняет обработчик,
+// чтобы долгие задачи не считались зависшими
+func (r *RedisQueue) heartbeat(ctx context.Context, taskData string) (stop func()) {
+	done := make(chan struct{})
+	interval := r.config.VisibilityTimeout / 3
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.client.ZAddXX(ctx, r.claimsKey(), &redis.Z{
+					Score:  float64(time.Now().Unix()),
+					Member: taskData,
+				})
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// reapStuckTasks периодически возвращает в очередь задачи, отметка которых не обновлялась
+// дольше VisibilityTimeout: обработчик, взявший их, завершился аварийно
+func (r *RedisQueue) reapStuckTasks(ctx context.Context) {
+	defer r.wg.Done()
+
+	interval := r.config.VisibilityTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			if _, err := r.RecoverStuckTasks(ctx); err != nil {
+				log.Printf("Failed to recover stuck tasks: %v", err)
+			}
+		}
+	}
+}
+
+// RecoverStuckTasks возвращает в очередь зависшие задачи и сообщает, сколько их было.
+// Задача, которая уже MaxRecoveries раз роняла обработчик, отправляется в DLQ.
+func (r *RedisQueue) RecoverStuckTasks(ctx context.Context) (int, error) {
+	if err := r.adoptUnclaimedTasks(ctx); err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(-r.config.VisibilityTimeout).Unix()
+	stuck, err := r.client.ZRangeByScore(ctx, r.claimsKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", deadline),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stuck tasks: %v", err)
+	}
+
+	recovered := 0
+	for _, taskData := range stuck {
+		ok, err := r.recoverTask(ctx, taskData)
+		if err != nil {
+			log.Printf("Failed to recover stuck task: %v", err)
+			continue
+		}
+		if ok {
+			recovered++
+		}
+	}
+
+	return recovered, nil
+}
+
+// adoptUnclaimedTasks ставит отметку записям списка обработки, у которых её нет: запись
+// могла остаться от версии, отмечавшей задачу отдельно от извлечения, или от версии
+// без отметок. Такие задачи будут возвращены через VisibilityTimeout.
+func (r *RedisQueue) adoptUnclaimedTasks(ctx context.Context) error {
+	entries, err := r.client.LRange(ctx, r.processingQueue, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read processing queue: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := float64(time.Now().Unix())
+	members := make([]*redis.Z, 0, len(entries))
+	for _, taskData := range entries {
+		members = append(members, &redis.Z{Score: now, Member: taskData})
+	}
+
+	if err := r.client.ZAddNX(ctx, r.claimsKey(), members...).Err(); err != nil {
+		return fmt.Errorf("failed to claim processing tasks: %v", err)
+	}
+	return nil
+}
+
+// recoverTask возвращает одну зависшую задачу в её приоритетный список или в DLQ
+func (r *RedisQueue) recoverTask(ctx context.Context, taskData string) (bool, error) {
+	var task Task
+	if err := json.Unmarshal([]byte(taskData), &task); err != nil {
+		removed, scriptErr := recoverScript.Run(ctx, r.client,
+			[]string{r.processingQueue, r.claimsKey(), r.processingQueue}, taskData, "").Int()
+		if scriptErr != nil {
+			return false, scriptErr
+		}
+		if removed == 1 {
+			r.moveToDLQ(ctx, taskData, fmt.Errorf("invalid task format: %v", err))
+		}
+		return removed == 1, nil
+	}
+
+	// Каждое восстановление считается попыткой, иначе задача, роняющая процесс, возвращалась бы бесконечно
+	task.Recoveries++
+	if task.Recoveries > r.config.MaxRecoveries {
+		removed, err := recoverScript.Run(ctx, r.client,
+			[]string{r.processingQueue, r.claimsKey(), r.processingQueue}, taskData, "").Int()
+		if err != nil {
+			return false, err
+		}
+		if removed == 1 {
+			r.moveToDLQ(ctx, taskData, fmt.Errorf("task was stuck in processing %d times", task.Recoveries))
+			r.incrementMetric(ctx, "tasks_recovered_dlq")
+			log.Printf("Stuck task %s moved to DLQ after %d recoveries", task.ID, task.Recoveries)
+		}
+		return removed == 1, nil
+	}
+
+	data, err := json.Marshal(&task)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task %s: %v", task.ID, err)
+	}
+
+	removed, err := recoverScript.Run(ctx, r.client,
+		[]string{r.processingQueue, r.claimsKey(), r.queueFor(task.Priority)}, taskData, string(data)).Int()
+	if err != nil {
+		return false, err
+	}
+	if removed == 1 {
+		r.metrics.observeRecovered(task.Type)
+		r.incrementMetric(ctx, "tasks_recovered")
+		r.incrementMetric(ctx, fmt.Sprintf("tasks_recovered_%s", task.Type))
+		log.Printf("Recovered stuck task %s (recovery %d/%d)", task.ID, task.Recoveries, r.config.MaxRecoveries)
+	}
+	return removed == 1, nil
+}
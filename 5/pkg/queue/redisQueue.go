@@ -1,645 +1,861 @@
-package queue
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"math/rand"
-	"sync"
-	"time"
-
-	"github.com/go-redis/redis/v8"
-)
-
-const (
-	defaultMaxRetries   = 3
-	defaultBaseDelay    = 5 * time.Second
-	defaultQueueTimeout = 5 * time.Second
-	defaultBatchSize    = 10
-	defaultDLQThreshold = 1000
-)
-
-// RedisQueue implements Queue interface using Redis
-type RedisQueue struct {
-	client          *redis.Client
-	mainQueue       string
-	delayedQueue    string
-	processingQueue string
-	dlq             string
-	retryManager    *RetryManager
-	dlqHandler      DLQHandler
-	config          *RedisQueueConfig
-	mu              sync.RWMutex
-	stopChan        chan struct{}
-	wg              sync.WaitGroup
-	subscribers     []func(*Task) error
-}
-
-// RedisQueueConfig contains configuration for RedisQueue
-type RedisQueueConfig struct {
-	// Redis connection
-	Addr     string
-	Password string
-	DB       int
-
-	// Queue names
-	MainQueue       string
-	DelayedQueue    string
-	ProcessingQueue string
-	DLQ             string
-
-	// Behavior
-	MaxRetries    int
-	BaseDelay     time.Duration
-	QueueTimeout  time.Duration
-	BatchSize     int
-	DLQThreshold  int
-	EnableDLQ     bool
-	EnableMetrics bool
-}
-
-// DefaultRedisQueueConfig returns default configuration
-func DefaultRedisQueueConfig() *RedisQueueConfig {
-	return &RedisQueueConfig{
-		Addr:            "localhost:6379",
-		Password:        "",
-		DB:              0,
-		MainQueue:       "event_booking:tasks",
-		DelayedQueue:    "event_booking:tasks:delayed",
-		ProcessingQueue: "event_booking:tasks:processing",
-		DLQ:             "event_booking:dlq",
-		MaxRetries:      defaultMaxRetries,
-		BaseDelay:       defaultBaseDelay,
-		QueueTimeout:    defaultQueueTimeout,
-		BatchSize:       defaultBatchSize,
-		DLQThreshold:    defaultDLQThreshold,
-		EnableDLQ:       true,
-		EnableMetrics:   true,
-	}
-}
-
-// NewRedisQueue creates a new RedisQueue instance
-func NewRedisQueue(cfg *RedisQueueConfig, retryManager *RetryManager, dlqHandler DLQHandler) (*RedisQueue, error) {
-	if cfg == nil {
-		cfg = DefaultRedisQueueConfig()
-	}
-
-	if retryManager == nil {
-		retryManager = NewRetryManager(cfg.MaxRetries, cfg.BaseDelay)
-	}
-
-	if dlqHandler == nil && cfg.EnableDLQ {
-		dlqHandler = NewDefaultDLQHandler(redis.NewClient(&redis.Options{
-			Addr:     cfg.Addr,
-			Password: cfg.Password,
-			DB:       cfg.DB,
-		}), cfg.DLQ)
-	}
-
-	client := redis.NewClient(&redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10,
-		MinIdleConns: 2,
-	})
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
-	}
-
-	queue := &RedisQueue{
-		client:          client,
-		mainQueue:       cfg.MainQueue,
-		delayedQueue:    cfg.DelayedQueue,
-		processingQueue: cfg.ProcessingQueue,
-		dlq:             cfg.DLQ,
-		retryManager:    retryManager,
-		dlqHandler:      dlqHandler,
-		config:          cfg,
-		stopChan:        make(chan struct{}),
-		subscribers:     make([]func(*Task) error, 0),
-	}
-
-	log.Printf("RedisQueue initialized: main=%s, delayed=%s, dlq=%s",
-		cfg.MainQueue, cfg.DelayedQueue, cfg.DLQ)
-
-	return queue, nil
-}
-
-// Publish sends a task to the queue
-func (r *RedisQueue) Publish(ctx context.Context, task *Task) error {
-	if task == nil {
-		return fmt.Errorf("task cannot be nil")
-	}
-
-	// Validate and set default values
-	if err := r.validateTask(task); err != nil {
-		return fmt.Errorf("invalid task: %v", err)
-	}
-
-	taskData, err := json.Marshal(task)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task: %v", err)
-	}
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Use Redis Sorted Set for delayed tasks
-	if !task.ExecuteAt.IsZero() && task.ExecuteAt.After(time.Now()) {
-		score := float64(task.ExecuteAt.UnixNano()) / 1e9
-		_, err = r.client.ZAdd(ctx, r.delayedQueue, &redis.Z{
-			Score:  score,
-			Member: taskData,
-		}).Result()
-		if err != nil {
-			return fmt.Errorf("failed to publish delayed task: %v", err)
-		}
-
-		if r.config.EnableMetrics {
-			r.incrementMetric(ctx, "tasks_delayed")
-		}
-
-		log.Printf("Task %s scheduled for execution at %s", task.ID, task.ExecuteAt.Format(time.RFC3339))
-	} else {
-		// Use Redis List for immediate tasks
-		_, err = r.client.LPush(ctx, r.mainQueue, taskData).Result()
-		if err != nil {
-			return fmt.Errorf("failed to publish immediate task: %v", err)
-		}
-
-		if r.config.EnableMetrics {
-			r.incrementMetric(ctx, "tasks_queued")
-		}
-
-		log.Printf("Task %s published to main queue", task.ID)
-	}
-
-	return nil
-}
-
-// PublishBatch sends multiple tasks in batch
-func (r *RedisQueue) PublishBatch(ctx context.Context, tasks []*Task) error {
-	if len(tasks) == 0 {
-		return nil
-	}
-
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	pipe := r.client.Pipeline()
-
-	for _, task := range tasks {
-		if err := r.validateTask(task); err != nil {
-			log.Printf("Skipping invalid task in batch: %v", err)
-			continue
-		}
-
-		taskData, err := json.Marshal(task)
-		if err != nil {
-			log.Printf("Failed to marshal task in batch: %v", err)
-			continue
-		}
-
-		if !task.ExecuteAt.IsZero() && task.ExecuteAt.After(time.Now()) {
-			score := float64(task.ExecuteAt.UnixNano()) / 1e9
-			pipe.ZAdd(ctx, r.delayedQueue, &redis.Z{
-				Score:  score,
-				Member: taskData,
-			})
-		} else {
-			pipe.LPush(ctx, r.mainQueue, taskData)
-		}
-	}
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to publish batch: %v", err)
-	}
-
-	if r.config.EnableMetrics {
-		r.incrementMetricBy(ctx, "tasks_queued", int64(len(tasks)))
-	}
-
-	log.Printf("Published %d tasks in batch", len(tasks))
-	return nil
-}
-
-// Subscribe starts consuming tasks from the queue
-func (r *RedisQueue) Subscribe(ctx context.Context, handler func(*Task) error) error {
-	if handler == nil {
-		return fmt.Errorf("handler cannot be nil")
-	}
-
-	r.mu.Lock()
-	r.subscribers = append(r.subscribers, handler)
-	r.mu.Unlock()
-
-	// Start background processors
-	r.wg.Add(3)
-	go r.processDelayedTasks(ctx)
-	go r.processMainQueue(ctx, handler)
-	go r.monitorQueueMetrics(ctx)
-
-	log.Println("RedisQueue subscriber started")
-	return nil
-}
-
-// processMainQueue processes tasks from the main queue
-func (r *RedisQueue) processMainQueue(ctx context.Context, handler func(*Task) error) {
-	defer r.wg.Done()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Main queue processor stopped by context")
-			return
-		case <-r.stopChan:
-			log.Println("Main queue processor stopped")
-			return
-		default:
-			if err := r.processBatch(ctx, handler); err != nil {
-				log.Printf("Error processing batch: %v", err)
-				time.Sleep(time.Second) // Backoff on error
-			}
-		}
-	}
-}
-
-// processBatch processes a batch of tasks from the main queue
-func (r *RedisQueue) processBatch(ctx context.Context, handler func(*Task) error) error {
-	// Move tasks from main queue to processing queue atomically
-	taskData, err := r.client.BRPopLPush(ctx, r.mainQueue, r.processingQueue, r.config.QueueTimeout).Result()
-	if err == redis.Nil {
-		return nil // Timeout, no tasks
-	}
-	if err != nil {
-		return fmt.Errorf("failed to move task to processing queue: %v", err)
-	}
-
-	var task Task
-	if err := json.Unmarshal([]byte(taskData), &task); err != nil {
-		// Move invalid task to DLQ
-		log.Printf("Failed to unmarshal task: %v", err)
-		r.moveToDLQ(ctx, taskData, fmt.Errorf("invalid task format: %v", err))
-		return nil
-	}
-
-	// Execute task with retry logic
-	if err := r.executeTaskWithRetry(ctx, &task, handler); err != nil {
-		log.Printf("Task %s failed after %d attempts: %v", task.ID, task.Attempts, err)
-		if r.dlqHandler != nil {
-			r.dlqHandler.HandleFailedTask(&task, err)
-		}
-	} else {
-		log.Printf("Task %s completed successfully", task.ID)
-	}
-
-	// Remove from processing queue regardless of outcome
-	if err := r.client.LRem(ctx, r.processingQueue, 1, taskData).Err(); err != nil {
-		log.Printf("Failed to remove task from processing queue: %v", err)
-	}
-
-	return nil
-}
-
-// processDelayedTasks moves ready delayed tasks to main queue
-func (r *RedisQueue) processDelayedTasks(ctx context.Context) {
-	defer r.wg.Done()
-
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Delayed tasks processor stopped by context")
-			return
-		case <-r.stopChan:
-			log.Println("Delayed tasks processor stopped")
-			return
-		case <-ticker.C:
-			if err := r.moveReadyDelayedTasks(ctx); err != nil {
-				log.Printf("Failed to process delayed tasks: %v", err)
-			}
-		}
-	}
-}
-
-// moveReadyDelayedTasks moves ready delayed tasks to main queue
-func (r *RedisQueue) moveReadyDelayedTasks(ctx context.Context) error {
-	now := float64(time.Now().UnixNano()) / 1e9
-
-	// Get tasks that are ready to execute
-	tasks, err := r.client.ZRangeByScore(ctx, r.delayedQueue, &redis.ZRangeBy{
-		Min: "0",
-		Max: fmt.Sprintf("%f", now),
-	}).Result()
-	if err != nil {
-		return fmt.Errorf("failed to get delayed tasks: %v", err)
-	}
-
-	if len(tasks) == 0 {
-		return nil
-	}
-
-	// Move to main queue in batch
-	pipe := r.client.Pipeline()
-	for _, taskData := range tasks {
-		pipe.LPush(ctx, r.mainQueue, taskData)
-	}
-	pipe.ZRemRangeByScore(ctx, r.delayedQueue, "0", fmt.Sprintf("%f", now))
-
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to move delayed tasks: %v", err)
-	}
-
-	if r.config.EnableMetrics {
-		r.incrementMetricBy(ctx, "tasks_delayed_processed", int64(len(tasks)))
-	}
-
-	log.Printf("Moved %d delayed tasks to main queue", len(tasks))
-	return nil
-}
-
-// executeTaskWithRetry executes a task with retry logic
-func (r *RedisQueue) executeTaskWithRetry(ctx context.Context, task *Task, handler func(*Task) error) error {
-	for {
-		task.Attempts++
-		startTime := time.Now()
-
-		err := handler(task)
-		if err == nil {
-			if r.config.EnableMetrics {
-				r.recordTaskSuccess(ctx, task, time.Since(startTime))
-			}
-			return nil // Success
-		}
-
-		if r.config.EnableMetrics {
-			r.recordTaskFailure(ctx, task, err, time.Since(startTime))
-		}
-
-		// Check if we should retry
-		shouldRetry, delay := r.retryManager.ShouldRetry(task, err)
-		if !shouldRetry {
-			return err // Final failure
-		}
-
-		log.Printf("Task %s failed (attempt %d/%d), retrying in %v: %v",
-			task.ID, task.Attempts, task.MaxRetries, delay, err)
-
-		// Wait before retry with jitter
-		jitteredDelay := delay + time.Duration(rand.Int63n(int64(delay/time.Millisecond)))*time.Millisecond
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(jitteredDelay):
-			// Continue to next attempt
-		}
-	}
-}
-
-// moveToDLQ moves a failed task to Dead Letter Queue
-func (r *RedisQueue) moveToDLQ(ctx context.Context, taskData string, err error) {
-	if !r.config.EnableDLQ || r.dlqHandler == nil {
-		return
-	}
-
-	var task Task
-	if jsonErr := json.Unmarshal([]byte(taskData), &task); jsonErr != nil {
-		// If we can't unmarshal, store the raw data
-		failedTask := &Task{
-			ID:        fmt.Sprintf("corrupted_%d", time.Now().UnixNano()),
-			Type:      "corrupted",
-			Data:      map[string]interface{}{"raw_data": taskData},
-			CreatedAt: time.Now(),
-		}
-		r.dlqHandler.HandleFailedTask(failedTask, fmt.Errorf("corrupted task: %v", jsonErr))
-	} else {
-		r.dlqHandler.HandleFailedTask(&task, err)
-	}
-
-	if r.config.EnableMetrics {
-		r.incrementMetric(ctx, "tasks_dlq")
-	}
-}
-
-// validateTask validates task structure and sets defaults
-func (r *RedisQueue) validateTask(task *Task) error {
-	if task.ID == "" {
-		task.ID = generateTaskID()
-	}
-	if task.Type == "" {
-		return fmt.Errorf("task type is required")
-	}
-	if task.Data == nil {
-		task.Data = make(map[string]interface{})
-	}
-	if task.MaxRetries == 0 {
-		task.MaxRetries = r.config.MaxRetries
-	}
-	if task.CreatedAt.IsZero() {
-		task.CreatedAt = time.Now()
-	}
-	if task.ExecuteAt.IsZero() {
-		task.ExecuteAt = time.Now()
-	}
-
-	return nil
-}
-
-// monitorQueueMetrics monitors queue metrics and health
-func (r *RedisQueue) monitorQueueMetrics(ctx context.Context) {
-	defer r.wg.Done()
-
-	if !r.config.EnableMetrics {
-		return
-	}
-
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-r.stopChan:
-			return
-		case <-ticker.C:
-			r.collectQueueMetrics(ctx)
-		}
-	}
-}
-
-// collectQueueMetrics collects various queue metrics
-func (r *RedisQueue) collectQueueMetrics(ctx context.Context) {
-	pipe := r.client.Pipeline()
-
-	mainLen := pipe.LLen(ctx, r.mainQueue)
-	delayedLen := pipe.ZCard(ctx, r.delayedQueue)
-	processingLen := pipe.LLen(ctx, r.processingQueue)
-	dlqLen := pipe.LLen(ctx, r.dlq)
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		log.Printf("Failed to collect queue metrics: %v", err)
-		return
-	}
-
-	metrics := map[string]interface{}{
-		"queue_main_len":       mainLen.Val(),
-		"queue_delayed_len":    delayedLen.Val(),
-		"queue_processing_len": processingLen.Val(),
-		"queue_dlq_len":        dlqLen.Val(),
-		"timestamp":            time.Now().Unix(),
-	}
-
-	// Store metrics in Redis
-	metricsData, err := json.Marshal(metrics)
-	if err == nil {
-		r.client.Set(ctx, "event_booking:queue:metrics", metricsData, 2*time.Minute)
-	}
-
-	// Log if queues are getting too large
-	if mainLen.Val() > int64(r.config.DLQThreshold) {
-		log.Printf("WARNING: Main queue size (%d) exceeds threshold (%d)",
-			mainLen.Val(), r.config.DLQThreshold)
-	}
-}
-
-// incrementMetric increments a counter metric
-func (r *RedisQueue) incrementMetric(ctx context.Context, metric string) {
-	if !r.config.EnableMetrics {
-		return
-	}
-
-	key := fmt.Sprintf("event_booking:metrics:%s", metric)
-	r.client.Incr(ctx, key)
-	r.client.Expire(ctx, key, 24*time.Hour)
-}
-
-// incrementMetricBy increments a counter metric by specific value
-func (r *RedisQueue) incrementMetricBy(ctx context.Context, metric string, value int64) {
-	if !r.config.EnableMetrics {
-		return
-	}
-
-	key := fmt.Sprintf("event_booking:metrics:%s", metric)
-	r.client.IncrBy(ctx, key, value)
-	r.client.Expire(ctx, key, 24*time.Hour)
-}
-
-// recordTaskSuccess records successful task execution metrics
-func (r *RedisQueue) recordTaskSuccess(ctx context.Context, task *Task, duration time.Duration) {
-	r.incrementMetric(ctx, "tasks_success")
-	r.incrementMetric(ctx, fmt.Sprintf("tasks_success_%s", task.Type))
-
-	// Record execution time
-	durationKey := fmt.Sprintf("event_booking:metrics:task_duration_%s", task.Type)
-	r.client.HIncrBy(ctx, "event_booking:metrics:task_timing", durationKey, int64(duration.Milliseconds()))
-}
-
-// recordTaskFailure records failed task execution metrics
-func (r *RedisQueue) recordTaskFailure(ctx context.Context, task *Task, err error, duration time.Duration) {
-	r.incrementMetric(ctx, "tasks_failure")
-	r.incrementMetric(ctx, fmt.Sprintf("tasks_failure_%s", task.Type))
-
-	// Record error type
-	errorType := "unknown"
-	if err != nil {
-		errorType = "generic"
-	}
-	r.incrementMetric(ctx, fmt.Sprintf("errors_%s", errorType))
-}
-
-// GetQueueStats returns current queue statistics
-func (r *RedisQueue) GetQueueStats(ctx context.Context) (*QueueStats, error) {
-	pipe := r.client.Pipeline()
-
-	mainLen := pipe.LLen(ctx, r.mainQueue)
-	delayedLen := pipe.ZCard(ctx, r.delayedQueue)
-	processingLen := pipe.LLen(ctx, r.processingQueue)
-	dlqLen := pipe.LLen(ctx, r.dlq)
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get queue stats: %v", err)
-	}
-
-	return &QueueStats{
-		MainQueue:       mainLen.Val(),
-		DelayedQueue:    delayedLen.Val(),
-		ProcessingQueue: processingLen.Val(),
-		DLQ:             dlqLen.Val(),
-		Timestamp:       time.Now(),
-	}, nil
-}
-
-// Purge clears all queues (use with caution!)
-func (r *RedisQueue) Purge(ctx context.Context) error {
-	pipe := r.client.Pipeline()
-
-	pipe.Del(ctx, r.mainQueue)
-	pipe.Del(ctx, r.delayedQueue)
-	pipe.Del(ctx, r.processingQueue)
-	pipe.Del(ctx, r.dlq)
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to purge queues: %v", err)
-	}
-
-	log.Println("All queues purged")
-	return nil
-}
-
-// Close gracefully shuts down the queue
-func (r *RedisQueue) Close() error {
-	close(r.stopChan)
-	r.wg.Wait()
-
-	if err := r.client.Close(); err != nil {
-		return fmt.Errorf("failed to close Redis client: %v", err)
-	}
-
-	log.Println("RedisQueue closed successfully")
-	return nil
-}
-
-// HealthCheck performs a health check on the queue
-func (r *RedisQueue) HealthCheck(ctx context.Context) error {
-	if err := r.client.Ping(ctx).Err(); err != nil {
-		return fmt.Errorf("redis connection failed: %v", err)
-	}
-
-	// Check if we can perform basic operations
-	if err := r.client.Ping(ctx).Err(); err != nil {
-		return fmt.Errorf("redis operation failed: %v", err)
-	}
-
-	return nil
-}
-
-// QueueStats contains statistics about queue state
-type QueueStats struct {
-	MainQueue       int64     `json:"main_queue"`
-	DelayedQueue    int64     `json:"delayed_queue"`
-	ProcessingQueue int64     `json:"processing_queue"`
-	DLQ             int64     `json:"dlq"`
-	Timestamp       time.Time `json:"timestamp"`
-}
-
-// generateTaskID generates a unique task ID
-func generateTaskID() string {
-	return fmt.Sprintf("task_%d_%d", time.Now().UnixNano(), rand.Int63())
-}
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultBaseDelay    = 5 * time.Second
+	defaultQueueTimeout = 5 * time.Second
+	claimPollInterval   = 100 * time.Millisecond
+	defaultBatchSize    = 10
+	defaultDLQThreshold = 1000
+
+	// DefaultKeyPrefix - префикс ключей очереди, расписаний cron и DLQ
+	DefaultKeyPrefix = "event_booking"
+	// ClusterKeyPrefix - тот же префикс как hash tag: Redis Cluster выполняет скрипты
+	// и транзакции только над ключами одного слота, а с тегом все ключи очереди
+	// попадают в слот строки event_booking
+	ClusterKeyPrefix = "{event_booking}"
+)
+
+// RedisQueue implements Queue interface using Redis
+type RedisQueue struct {
+	client          redis.UniversalClient
+	ownsClient      bool
+	mainQueue       string
+	delayedQueue    string
+	processingQueue string
+	dlq             string
+	retryManager    *RetryManager
+	dlqHandler      DLQHandler
+	config          *RedisQueueConfig
+	mu              sync.RWMutex
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+	pool            *workerPool
+	metrics         *Collector
+	subscribers     []func(*Task) error
+}
+
+// RedisQueueConfig contains configuration for RedisQueue
+type RedisQueueConfig struct {
+	// Redis connection. If Client is set, Addr, Password and DB are ignored:
+	// the queue uses the given client, which may be a sentinel or cluster client.
+	// The queue doesn't close a client it didn't create.
+	Client   redis.UniversalClient
+	Addr     string
+	Password string
+	DB       int
+
+	// Queue names; KeyPrefix also prefixes the cron schedules
+	KeyPrefix       string
+	MainQueue       string
+	DelayedQueue    string
+	ProcessingQueue string
+	DLQ             string
+
+	// Behavior
+	MaxRetries    int
+	BaseDelay     time.Duration
+	QueueTimeout  time.Duration
+	BatchSize     int
+	DLQThreshold  int
+	EnableDLQ     bool
+	EnableMetrics bool
+
+	// PriorityWeights задаёт, как часто каждый приоритетный список опрашивается первым
+	PriorityWeights map[Priority]int
+
+	// Worker pool: Concurrency tasks run at once across all subscribers,
+	// TypeConcurrency caps individual task types below that.
+	// Close waits up to DrainTimeout for running tasks to finish.
+	Concurrency     int
+	TypeConcurrency map[TaskType]int
+	DrainTimeout    time.Duration
+
+	// A task whose processing mark is older than VisibilityTimeout is considered lost
+	// by a crashed worker and is re-queued, up to MaxRecoveries times, then sent to the DLQ.
+	VisibilityTimeout time.Duration
+	MaxRecoveries     int
+}
+
+// DefaultRedisQueueConfig returns default configuration
+func DefaultRedisQueueConfig() *RedisQueueConfig {
+	return (&RedisQueueConfig{
+		Addr:              "localhost:6379",
+		Password:          "",
+		DB:                0,
+		MaxRetries:        defaultMaxRetries,
+		BaseDelay:         defaultBaseDelay,
+		QueueTimeout:      defaultQueueTimeout,
+		BatchSize:         defaultBatchSize,
+		DLQThreshold:      defaultDLQThreshold,
+		EnableDLQ:         true,
+		EnableMetrics:     true,
+		PriorityWeights:   DefaultPriorityWeights(),
+		Concurrency:       defaultConcurrency,
+		DrainTimeout:      defaultDrainTimeout,
+		VisibilityTimeout: defaultVisibilityTimeout,
+		MaxRecoveries:     defaultMaxRecoveries,
+	}).WithKeyPrefix(DefaultKeyPrefix)
+}
+
+// WithKeyPrefix names the queues <prefix>:tasks, <prefix>:tasks:delayed,
+// <prefix>:tasks:processing and <prefix>:dlq. Use ClusterKeyPrefix with Redis Cluster.
+func (c *RedisQueueConfig) WithKeyPrefix(prefix string) *RedisQueueConfig {
+	c.KeyPrefix = prefix
+	c.MainQueue = prefix + ":tasks"
+	c.DelayedQueue = prefix + ":tasks:delayed"
+	c.ProcessingQueue = prefix + ":tasks:processing"
+	c.DLQ = prefix + ":dlq"
+	return c
+}
+
+// NewRedisQueue creates a new RedisQueue instance
+func NewRedisQueue(cfg *RedisQueueConfig, retryManager *RetryManager, dlqHandler DLQHandler) (*RedisQueue, error) {
+	if cfg == nil {
+		cfg = DefaultRedisQueueConfig()
+	}
+
+	if retryManager == nil {
+		retryManager = NewRetryManager(cfg.MaxRetries, cfg.BaseDelay)
+	}
+
+	if len(cfg.PriorityWeights) == 0 {
+		cfg.PriorityWeights = DefaultPriorityWeights()
+	}
+
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+
+	if cfg.MaxRecoveries <= 0 {
+		cfg.MaxRecoveries = defaultMaxRecoveries
+	}
+
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultKeyPrefix
+	}
+	if cfg.MainQueue == "" {
+		cfg.WithKeyPrefix(cfg.KeyPrefix)
+	}
+
+	client, ownsClient := cfg.Client, false
+	if client == nil {
+		client, ownsClient = redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: 3 * time.Second,
+			PoolSize:     10,
+			MinIdleConns: 2,
+		}), true
+	}
+
+	if dlqHandler == nil && cfg.EnableDLQ {
+		dlqHandler = NewDefaultDLQHandler(client, cfg.DLQ)
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
+
+	queue := &RedisQueue{
+		client:          client,
+		ownsClient:      ownsClient,
+		mainQueue:       cfg.MainQueue,
+		delayedQueue:    cfg.DelayedQueue,
+		processingQueue: cfg.ProcessingQueue,
+		dlq:             cfg.DLQ,
+		retryManager:    retryManager,
+		dlqHandler:      dlqHandler,
+		config:          cfg,
+		stopChan:        make(chan struct{}),
+		pool:            newWorkerPool(cfg.Concurrency, cfg.TypeConcurrency),
+		subscribers:     make([]func(*Task) error, 0),
+	}
+	queue.metrics = newCollector(queue)
+
+	log.Printf("RedisQueue initialized: main=%s, delayed=%s, dlq=%s, workers=%d",
+		cfg.MainQueue, cfg.DelayedQueue, cfg.DLQ, cap(queue.pool.slots))
+
+	return queue, nil
+}
+
+// Publish sends a task to the queue
+func (r *RedisQueue) Publish(ctx context.Context, task *Task) error {
+	if task == nil {
+		return fmt.Errorf("task cannot be nil")
+	}
+
+	// Validate and set default values
+	if err := r.validateTask(task); err != nil {
+		return fmt.Errorf("invalid task: %v", err)
+	}
+
+	taskData, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Use Redis Sorted Set for delayed tasks
+	if !task.ExecuteAt.IsZero() && task.ExecuteAt.After(time.Now()) {
+		score := float64(task.ExecuteAt.UnixNano()) / 1e9
+		_, err = r.client.ZAdd(ctx, r.delayedQueue, &redis.Z{
+			Score:  score,
+			Member: taskData,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("failed to publish delayed task: %v", err)
+		}
+
+		if r.config.EnableMetrics {
+			r.incrementMetric(ctx, "tasks_delayed")
+		}
+
+		log.Printf("Task %s scheduled for execution at %s", task.ID, task.ExecuteAt.Format(time.RFC3339))
+	} else {
+		// Use the Redis List of the task priority for immediate tasks
+		_, err = r.client.LPush(ctx, r.queueFor(task.Priority), taskData).Result()
+		if err != nil {
+			return fmt.Errorf("failed to publish immediate task: %v", err)
+		}
+
+		if r.config.EnableMetrics {
+			r.incrementMetric(ctx, "tasks_queued")
+		}
+
+		log.Printf("Task %s published to %s priority queue", task.ID, task.Priority)
+	}
+
+	return nil
+}
+
+// PublishBatch sends multiple tasks in batch
+func (r *RedisQueue) PublishBatch(ctx context.Context, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pipe := r.client.Pipeline()
+
+	for _, task := range tasks {
+		if err := r.validateTask(task); err != nil {
+			log.Printf("Skipping invalid task in batch: %v", err)
+			continue
+		}
+
+		taskData, err := json.Marshal(task)
+		if err != nil {
+			log.Printf("Failed to marshal task in batch: %v", err)
+			continue
+		}
+
+		if !task.ExecuteAt.IsZero() && task.ExecuteAt.After(time.Now()) {
+			score := float64(task.ExecuteAt.UnixNano()) / 1e9
+			pipe.ZAdd(ctx, r.delayedQueue, &redis.Z{
+				Score:  score,
+				Member: taskData,
+			})
+		} else {
+			pipe.LPush(ctx, r.queueFor(task.Priority), taskData)
+		}
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to publish batch: %v", err)
+	}
+
+	if r.config.EnableMetrics {
+		r.incrementMetricBy(ctx, "tasks_queued", int64(len(tasks)))
+	}
+
+	log.Printf("Published %d tasks in batch", len(tasks))
+	return nil
+}
+
+// Subscribe starts consuming tasks from the queue
+func (r *RedisQueue) Subscribe(ctx context.Context, handler func(*Task) error) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+
+	r.mu.Lock()
+	r.subscribers = append(r.subscribers, handler)
+	r.mu.Unlock()
+
+	// Start background processors
+	r.wg.Add(4)
+	go r.processDelayedTasks(ctx)
+	go r.processMainQueue(ctx, handler)
+	go r.monitorQueueMetrics(ctx)
+	go r.reapStuckTasks(ctx)
+
+	log.Println("RedisQueue subscriber started")
+	return nil
+}
+
+// processMainQueue takes tasks while the worker pool has free slots and runs each one in its own goroutine
+func (r *RedisQueue) processMainQueue(ctx context.Context, handler func(*Task) error) {
+	defer r.wg.Done()
+
+	for {
+		if !r.pool.acquire(ctx, r.stopChan) {
+			log.Println("Main queue processor stopped")
+			return
+		}
+
+		taskData, err := r.popTask(ctx)
+		if err != nil || taskData == "" {
+			r.pool.release()
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Error processing batch: %v", err)
+				time.Sleep(time.Second) // Backoff on error
+			}
+			continue
+		}
+
+		if !r.pool.begin() {
+			// Close already waits for running tasks, so this one goes back to the queue
+			r.pool.release()
+			var task Task
+			json.Unmarshal([]byte(taskData), &task)
+			r.requeue(&task, taskData)
+			continue
+		}
+		go func() {
+			defer r.pool.done()
+			r.runTask(ctx, taskData, handler)
+		}()
+	}
+}
+
+// claimScript takes a task from the first non-empty list of KEYS[3..], pushes it to the
+// processing queue KEYS[1] and records its claim time ARGV[1] in KEYS[2] in one step,
+// so a crash can't lose a task between taking it and marking it as being processed
+var claimScript = redis.NewScript(`
+for i = 3, #KEYS do
+	local task = redis.call('RPOP', KEYS[i])
+	if task then
+		redis.call('LPUSH', KEYS[1], task)
+		redis.call('ZADD', KEYS[2], ARGV[1], task)
+		return task
+	end
+end
+return false
+`)
+
+// popTask takes the next task from the priority queues, moves it to the processing queue
+// and claims it. A script can't block, so an empty poll is repeated every claimPollInterval;
+// an empty result means QueueTimeout passed without tasks.
+func (r *RedisQueue) popTask(ctx context.Context) (string, error) {
+	deadline := time.Now().Add(r.config.QueueTimeout)
+	for {
+		// The lists are polled in a weighted order so higher priorities are served more often
+		order := weightedOrder(r.config.PriorityWeights)
+		keys := make([]string, 0, len(order)+2)
+		keys = append(keys, r.processingQueue, r.claimsKey())
+		for _, p := range order {
+			keys = append(keys, r.queueFor(p))
+		}
+
+		taskData, err := claimScript.Run(ctx, r.client, keys, time.Now().Unix()).Text()
+		if err == nil {
+			return taskData, nil
+		}
+		if err != redis.Nil {
+			return "", fmt.Errorf("failed to pop task: %v", err)
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return "", nil // Timeout, no tasks
+		}
+		if wait > claimPollInterval {
+			wait = claimPollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-r.stopChan:
+			return "", nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runTask executes a popped task within its type limit and removes it from the processing queue.
+// The caller's worker slot is released when the task finishes.
+func (r *RedisQueue) runTask(ctx context.Context, taskData string, handler func(*Task) error) {
+	var task Task
+	if err := json.Unmarshal([]byte(taskData), &task); err != nil {
+		// Move invalid task to DLQ
+		log.Printf("Failed to unmarshal task: %v", err)
+		r.moveToDLQ(ctx, taskData, fmt.Errorf("invalid task format: %v", err))
+		r.releaseProcessing(ctx, taskData)
+		r.pool.release()
+		return
+	}
+
+	stopHeartbeat := r.heartbeat(ctx, taskData)
+	defer stopHeartbeat()
+
+	// While the task type is saturated the worker slot is given to tasks of other types
+	releaseType, ok := r.pool.parkForType(ctx, r.stopChan, task.Type)
+	if !ok {
+		r.requeue(&task, taskData)
+		return
+	}
+	defer r.pool.release()
+	defer releaseType()
+
+	// Execute task with retry logic
+	err := r.executeTaskWithRetry(ctx, &task, handler)
+	switch {
+	case errors.Is(err, errQueueStopping):
+		r.requeue(&task, taskData)
+		return
+	case err != nil:
+		log.Printf("Task %s failed after %d attempts: %v", task.ID, task.Attempts, err)
+		if r.dlqHandler != nil {
+			r.dlqHandler.HandleFailedTask(&task, err)
+		}
+	default:
+		log.Printf("Task %s completed successfully", task.ID)
+	}
+
+	// Remove from processing queue regardless of outcome
+	r.releaseProcessing(ctx, taskData)
+}
+
+// releaseProcessing removes a finished task and its claim from the processing queue
+func (r *RedisQueue) releaseProcessing(ctx context.Context, taskData string) {
+	pipe := r.client.TxPipeline()
+	pipe.LRem(ctx, r.processingQueue, 1, taskData)
+	pipe.ZRem(ctx, r.claimsKey(), taskData)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to remove task from processing queue: %v", err)
+	}
+}
+
+// processDelayedTasks moves ready delayed tasks to main queue
+func (r *RedisQueue) processDelayedTasks(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Delayed tasks processor stopped by context")
+			return
+		case <-r.stopChan:
+			log.Println("Delayed tasks processor stopped")
+			return
+		case <-ticker.C:
+			if err := r.moveReadyDelayedTasks(ctx); err != nil {
+				log.Printf("Failed to process delayed tasks: %v", err)
+			}
+		}
+	}
+}
+
+// moveReadyDelayedTasks moves ready delayed tasks to main queue
+func (r *RedisQueue) moveReadyDelayedTasks(ctx context.Context) error {
+	now := float64(time.Now().UnixNano()) / 1e9
+
+	// Get tasks that are ready to execute
+	tasks, err := r.client.ZRangeByScore(ctx, r.delayedQueue, &redis.ZRangeBy{
+		Min: "0",
+		Max: fmt.Sprintf("%f", now),
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get delayed tasks: %v", err)
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	// Move to the priority queues in batch
+	pipe := r.client.Pipeline()
+	for _, taskData := range tasks {
+		pipe.LPush(ctx, r.queueFor(taskPriority(taskData)), taskData)
+	}
+	pipe.ZRemRangeByScore(ctx, r.delayedQueue, "0", fmt.Sprintf("%f", now))
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to move delayed tasks: %v", err)
+	}
+
+	if r.config.EnableMetrics {
+		r.incrementMetricBy(ctx, "tasks_delayed_processed", int64(len(tasks)))
+	}
+
+	log.Printf("Moved %d delayed tasks to main queue", len(tasks))
+	return nil
+}
+
+// executeTaskWithRetry executes a task with retry logic
+func (r *RedisQueue) executeTaskWithRetry(ctx context.Context, task *Task, handler func(*Task) error) error {
+	for {
+		task.Attempts++
+		startTime := time.Now()
+
+		err := handler(task)
+		if err == nil {
+			r.metrics.observeTask(task.Type, "success", time.Since(startTime))
+			if r.config.EnableMetrics {
+				r.recordTaskSuccess(ctx, task, time.Since(startTime))
+			}
+			return nil // Success
+		}
+
+		r.metrics.observeTask(task.Type, "failure", time.Since(startTime))
+		if r.config.EnableMetrics {
+			r.recordTaskFailure(ctx, task, err, time.Since(startTime))
+		}
+
+		// Check if we should retry
+		shouldRetry, delay := r.retryManager.ShouldRetry(task, err)
+		if !shouldRetry {
+			return err // Final failure
+		}
+
+		log.Printf("Task %s failed (attempt %d/%d), retrying in %v: %v",
+			task.ID, task.Attempts, task.MaxRetries, delay, err)
+
+		// Wait before retry with jitter
+		jitteredDelay := delay + time.Duration(rand.Int63n(int64(delay/time.Millisecond)))*time.Millisecond
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stopChan:
+			return errQueueStopping
+		case <-time.After(jitteredDelay):
+			// Continue to next attempt
+		}
+	}
+}
+
+// moveToDLQ moves a failed task to Dead Letter Queue
+func (r *RedisQueue) moveToDLQ(ctx context.Context, taskData string, err error) {
+	if !r.config.EnableDLQ || r.dlqHandler == nil {
+		return
+	}
+
+	var task Task
+	if jsonErr := json.Unmarshal([]byte(taskData), &task); jsonErr != nil {
+		// If we can't unmarshal, store the raw data
+		failedTask := &Task{
+			ID:        fmt.Sprintf("corrupted_%d", time.Now().UnixNano()),
+			Type:      "corrupted",
+			Data:      map[string]interface{}{"raw_data": taskData},
+			CreatedAt: time.Now(),
+		}
+		r.dlqHandler.HandleFailedTask(failedTask, fmt.Errorf("corrupted task: %v", jsonErr))
+		r.metrics.observeDLQ(failedTask.Type)
+	} else {
+		r.dlqHandler.HandleFailedTask(&task, err)
+		r.metrics.observeDLQ(task.Type)
+	}
+
+	if r.config.EnableMetrics {
+		r.incrementMetric(ctx, "tasks_dlq")
+	}
+}
+
+// validateTask validates task structure and sets defaults
+func (r *RedisQueue) validateTask(task *Task) error {
+	return setTaskDefaults(task, r.config.MaxRetries)
+}
+
+// monitorQueueMetrics monitors queue metrics and health
+func (r *RedisQueue) monitorQueueMetrics(ctx context.Context) {
+	defer r.wg.Done()
+
+	if !r.config.EnableMetrics {
+		return
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			r.collectQueueMetrics(ctx)
+		}
+	}
+}
+
+// collectQueueMetrics collects various queue metrics
+func (r *RedisQueue) collectQueueMetrics(ctx context.Context) {
+	stats, err := r.GetQueueStats(ctx)
+	if err != nil {
+		log.Printf("Failed to collect queue metrics: %v", err)
+		return
+	}
+
+	metrics := map[string]interface{}{
+		"queue_main_len":       stats.MainQueue,
+		"queue_high_len":       stats.Priorities[PriorityHigh],
+		"queue_normal_len":     stats.Priorities[PriorityNormal],
+		"queue_low_len":        stats.Priorities[PriorityLow],
+		"queue_delayed_len":    stats.DelayedQueue,
+		"queue_processing_len": stats.ProcessingQueue,
+		"queue_dlq_len":        stats.DLQ,
+		"timestamp":            time.Now().Unix(),
+	}
+
+	// Store metrics in Redis
+	metricsData, err := json.Marshal(metrics)
+	if err == nil {
+		r.client.Set(ctx, "event_booking:queue:metrics", metricsData, 2*time.Minute)
+	}
+
+	// Log if queues are getting too large
+	if stats.MainQueue > int64(r.config.DLQThreshold) {
+		log.Printf("WARNING: Main queue size (%d) exceeds threshold (%d)",
+			stats.MainQueue, r.config.DLQThreshold)
+	}
+}
+
+// incrementMetric increments a counter metric
+func (r *RedisQueue) incrementMetric(ctx context.Context, metric string) {
+	if !r.config.EnableMetrics {
+		return
+	}
+
+	key := fmt.Sprintf("event_booking:metrics:%s", metric)
+	r.client.Incr(ctx, key)
+	r.client.Expire(ctx, key, 24*time.Hour)
+}
+
+// incrementMetricBy increments a counter metric by specific value
+func (r *RedisQueue) incrementMetricBy(ctx context.Context, metric string, value int64) {
+	if !r.config.EnableMetrics {
+		return
+	}
+
+	key := fmt.Sprintf("event_booking:metrics:%s", metric)
+	r.client.IncrBy(ctx, key, value)
+	r.client.Expire(ctx, key, 24*time.Hour)
+}
+
+// recordTaskSuccess records successful task execution metrics
+func (r *RedisQueue) recordTaskSuccess(ctx context.Context, task *Task, duration time.Duration) {
+	r.incrementMetric(ctx, "tasks_success")
+	r.incrementMetric(ctx, fmt.Sprintf("tasks_success_%s", task.Type))
+
+	// Record execution time
+	durationKey := fmt.Sprintf("event_booking:metrics:task_duration_%s", task.Type)
+	r.client.HIncrBy(ctx, "event_booking:metrics:task_timing", durationKey, int64(duration.Milliseconds()))
+}
+
+// recordTaskFailure records failed task execution metrics
+func (r *RedisQueue) recordTaskFailure(ctx context.Context, task *Task, err error, duration time.Duration) {
+	r.incrementMetric(ctx, "tasks_failure")
+	r.incrementMetric(ctx, fmt.Sprintf("tasks_failure_%s", task.Type))
+
+	// Record error type
+	errorType := "unknown"
+	if err != nil {
+		errorType = "generic"
+	}
+	r.incrementMetric(ctx, fmt.Sprintf("errors_%s", errorType))
+}
+
+// GetQueueStats returns current queue statistics
+func (r *RedisQueue) GetQueueStats(ctx context.Context) (*QueueStats, error) {
+	pipe := r.client.Pipeline()
+
+	priorityLens := make(map[Priority]*redis.IntCmd, len(priorities))
+	for _, p := range priorities {
+		priorityLens[p] = pipe.LLen(ctx, r.queueFor(p))
+	}
+	delayedLen := pipe.ZCard(ctx, r.delayedQueue)
+	processingLen := pipe.LLen(ctx, r.processingQueue)
+	dlqLen := pipe.ZCard(ctx, r.dlq)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %v", err)
+	}
+
+	var mainLen int64
+	byPriority := make(map[Priority]int64, len(priorities))
+	for p, cmd := range priorityLens {
+		byPriority[p] = cmd.Val()
+		mainLen += cmd.Val()
+	}
+
+	return &QueueStats{
+		MainQueue:       mainLen,
+		Priorities:      byPriority,
+		DelayedQueue:    delayedLen.Val(),
+		ProcessingQueue: processingLen.Val(),
+		DLQ:             dlqLen.Val(),
+		Timestamp:       time.Now(),
+	}, nil
+}
+
+// PendingTasks returns the tasks of taskType that are delayed, waiting in a priority list
+// or being processed. The queues are read whole, so it is meant for occasional admin checks.
+func (r *RedisQueue) PendingTasks(ctx context.Context, taskType TaskType) ([]*Task, error) {
+	pipe := r.client.Pipeline()
+
+	delayed := pipe.ZRange(ctx, r.delayedQueue, 0, -1)
+	lists := make([]*redis.StringSliceCmd, 0, len(priorities)+1)
+	for _, p := range priorities {
+		lists = append(lists, pipe.LRange(ctx, r.queueFor(p), 0, -1))
+	}
+	lists = append(lists, pipe.LRange(ctx, r.processingQueue, 0, -1))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read queues: %v", err)
+	}
+
+	entries := delayed.Val()
+	for _, cmd := range lists {
+		entries = append(entries, cmd.Val()...)
+	}
+
+	var tasks []*Task
+	for _, taskData := range entries {
+		var task Task
+		if err := json.Unmarshal([]byte(taskData), &task); err != nil || task.Type != taskType {
+			continue
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+// Purge clears all queues (use with caution!)
+func (r *RedisQueue) Purge(ctx context.Context) error {
+	pipe := r.client.Pipeline()
+
+	for _, p := range priorities {
+		pipe.Del(ctx, r.queueFor(p))
+	}
+	pipe.Del(ctx, r.delayedQueue)
+	pipe.Del(ctx, r.processingQueue)
+	pipe.Del(ctx, r.claimsKey())
+	pipe.Del(ctx, r.dlq, dlqTasksKey(r.dlq))
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to purge queues: %v", err)
+	}
+
+	log.Println("All queues purged")
+	return nil
+}
+
+// Close gracefully shuts down the queue
+func (r *RedisQueue) Close() error {
+	close(r.stopChan)
+	r.wg.Wait()
+
+	// New tasks are no longer taken; running ones get DrainTimeout to finish
+	if !r.pool.wait(r.config.DrainTimeout) {
+		log.Printf("RedisQueue drain timed out after %v, unfinished tasks stay in %s",
+			r.config.DrainTimeout, r.processingQueue)
+	}
+
+	if r.ownsClient {
+		if err := r.client.Close(); err != nil {
+			return fmt.Errorf("failed to close Redis client: %v", err)
+		}
+	}
+
+	log.Println("RedisQueue closed successfully")
+	return nil
+}
+
+// HealthCheck performs a health check on the queue
+func (r *RedisQueue) HealthCheck(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis connection failed: %v", err)
+	}
+
+	// Check if we can perform basic operations
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis operation failed: %v", err)
+	}
+
+	return nil
+}
+
+// QueueStats contains statistics about queue state
+type QueueStats struct {
+	MainQueue       int64              `json:"main_queue"` // все приоритеты вместе
+	Priorities      map[Priority]int64 `json:"priorities"`
+	DelayedQueue    int64              `json:"delayed_queue"`
+	ProcessingQueue int64              `json:"processing_queue"`
+	DLQ             int64              `json:"dlq"`
+	Timestamp       time.Time          `json:"timestamp"`
+}
+
+// queueFor returns the list that holds ready tasks of the given priority
+func (r *RedisQueue) queueFor(p Priority) string {
+	if !p.Valid() {
+		p = PriorityNormal
+	}
+	return priorityQueueName(r.mainQueue, p)
+}
+
+// taskPriority reads the priority of a serialized task; tasks published before
+// priorities existed get the default priority of their type
+func taskPriority(taskData string) Priority {
+	var task struct {
+		Type     TaskType `json:"type"`
+		Priority Priority `json:"priority"`
+	}
+	if err := json.Unmarshal([]byte(taskData), &task); err != nil {
+		return PriorityNormal
+	}
+	if task.Priority.Valid() {
+		return task.Priority
+	}
+	return DefaultPriority(task.Type)
+}
+
+// generateTaskID generates a unique task ID
+func generateTaskID() string {
+	return fmt.Sprintf("task_%d_%d", time.Now().UnixNano(), rand.Int63())
+}
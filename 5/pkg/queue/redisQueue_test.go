@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisQueue(t *testing.T) (*RedisQueue, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cfg := DefaultRedisQueueConfig()
+	cfg.Addr = mr.Addr()
+	cfg.QueueTimeout = 300 * time.Millisecond
+	cfg.EnableMetrics = false
+
+	q, err := NewRedisQueue(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRedisQueue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q, mr
+}
+
+// TestRedisQueuePopTaskClaimsAtomically проверяет, что взятая задача сразу оказывается
+// в списке обработки с отметкой, а не только исчезает из очереди
+func TestRedisQueuePopTaskClaimsAtomically(t *testing.T) {
+	q, mr := newTestRedisQueue(t)
+	ctx := context.Background()
+
+	if err := q.Publish(ctx, &Task{ID: "low", Type: TaskTypeSendEmail, Priority: PriorityLow}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	taskData, err := q.popTask(ctx)
+	if err != nil || taskData == "" {
+		t.Fatalf("popTask = %q, %v", taskData, err)
+	}
+
+	if mr.Exists(q.queueFor(PriorityLow)) {
+		t.Error("task is still in the ready queue")
+	}
+	processing, err := mr.List(q.processingQueue)
+	if err != nil || len(processing) != 1 || processing[0] != taskData {
+		t.Errorf("processing queue = %v, %v, want the popped task", processing, err)
+	}
+	claims, err := mr.ZMembers(q.claimsKey())
+	if err != nil || len(claims) != 1 || claims[0] != taskData {
+		t.Errorf("claims = %v, %v, want the popped task", claims, err)
+	}
+
+	// После обработки задача и её отметка удаляются
+	q.releaseProcessing(ctx, taskData)
+	if mr.Exists(q.processingQueue) || mr.Exists(q.claimsKey()) {
+		t.Error("finished task left in the processing queue")
+	}
+}
+
+// TestRedisQueuePopTaskWaitsForTask проверяет, что пустая очередь ждёт задачу
+// до QueueTimeout, а не возвращается сразу
+func TestRedisQueuePopTaskWaitsForTask(t *testing.T) {
+	q, _ := newTestRedisQueue(t)
+	ctx := context.Background()
+
+	started := time.Now()
+	taskData, err := q.popTask(ctx)
+	if err != nil || taskData != "" {
+		t.Fatalf("popTask on empty queue = %q, %v", taskData, err)
+	}
+	if waited := time.Since(started); waited < q.config.QueueTimeout {
+		t.Errorf("popTask returned after %v, want at least %v", waited, q.config.QueueTimeout)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		q.Publish(ctx, &Task{ID: "late", Type: TaskTypeSendEmail})
+	}()
+	taskData, err = q.popTask(ctx)
+	if err != nil || taskData == "" {
+		t.Fatalf("popTask missed a task published while waiting: %q, %v", taskData, err)
+	}
+}
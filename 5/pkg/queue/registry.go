@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownTaskType возвращается для задач, тип которых не зарегистрирован
+var ErrUnknownTaskType = errors.New("неизвестный тип задачи")
+
+// HandlerFunc обрабатывает задачу одного типа. ctx отменяется, когда истекает срок
+// обработки, поэтому обработчик должен передавать его во все вызовы сервисов.
+type HandlerFunc func(ctx context.Context, task *Task) error
+
+// Middleware оборачивает обработчик: логирование, метрики, восстановление после паники и т.п.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Registry сопоставляет типу задачи обработчик и оборачивает все обработчики общей цепочкой
+// middleware. Новые типы задач регистрируются из своих пакетов без правки TaskHandler.
+type Registry struct {
+	mu         sync.RWMutex
+	handlers   map[TaskType]HandlerFunc
+	middleware []Middleware
+	chain      map[TaskType]HandlerFunc
+}
+
+// NewRegistry создает пустой реестр обработчиков
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[TaskType]HandlerFunc),
+		chain:    make(map[TaskType]HandlerFunc),
+	}
+}
+
+// RegisterHandler регистрирует обработчик типа задачи; повторная регистрация типа - ошибка
+func (r *Registry) RegisterHandler(taskType TaskType, fn HandlerFunc) error {
+	if taskType == "" {
+		return fmt.Errorf("task type is required")
+	}
+	if fn == nil {
+		return fmt.Errorf("handler for %s cannot be nil", taskType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.handlers[taskType]; exists {
+		return fmt.Errorf("handler for %s is already registered", taskType)
+	}
+	r.handlers[taskType] = fn
+	r.chain[taskType] = r.wrap(fn)
+	return nil
+}
+
+// Use добавляет middleware ко всем обработчикам, в том числе уже зарегистрированным.
+// Первый добавленный middleware - внешний: он выполняется первым и видит результат остальных.
+func (r *Registry) Use(middleware ...Middleware) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, middleware...)
+	for taskType, fn := range r.handlers {
+		r.chain[taskType] = r.wrap(fn)
+	}
+	return r
+}
+
+// Types возвращает зарегистрированные типы задач
+func (r *Registry) Types() []TaskType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]TaskType, 0, len(r.handlers))
+	for taskType := range r.handlers {
+		types = append(types, taskType)
+	}
+	return types
+}
+
+// Handle выполняет задачу обработчиком ее типа; подходит для Queue.Subscribe
+func (r *Registry) Handle(task *Task) error {
+	r.mu.RLock()
+	fn, ok := r.chain[task.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTaskType, task.Type)
+	}
+	return fn(context.Background(), task)
+}
+
+func (r *Registry) wrap(fn HandlerFunc) HandlerFunc {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		fn = r.middleware[i](fn)
+	}
+	return fn
+}
@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryAppliesMiddlewareInOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx context.Context, task *Task) error {
+				calls = append(calls, name)
+				return next(ctx, task)
+			}
+		}
+	}
+
+	registry := NewRegistry().Use(trace("outer"))
+	if err := registry.RegisterHandler(TaskTypeSendEmail, func(ctx context.Context, task *Task) error {
+		calls = append(calls, "handler")
+		return nil
+	}); err != nil {
+		t.Fatalf("RegisterHandler: %v", err)
+	}
+	// Middleware, добавленный после регистрации, тоже применяется
+	registry.Use(trace("inner"))
+
+	if err := registry.Handle(&Task{Type: TaskTypeSendEmail}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "outer,inner,handler" {
+		t.Fatalf("calls = %s, want outer,inner,handler", got)
+	}
+
+	if err := registry.RegisterHandler(TaskTypeSendEmail, func(context.Context, *Task) error { return nil }); err == nil {
+		t.Fatal("duplicate registration must fail")
+	}
+	if err := registry.Handle(&Task{Type: "unknown"}); !errors.Is(err, ErrUnknownTaskType) {
+		t.Fatalf("unknown type error = %v, want ErrUnknownTaskType", err)
+	}
+}
+
+func TestRegistryRecoversPanicAndEnforcesDeadline(t *testing.T) {
+	registry := NewRegistry().Use(
+		RecoveryMiddleware(),
+		TimeoutMiddleware(time.Second, map[TaskType]time.Duration{TaskTypeSendEmail: 20 * time.Millisecond}),
+	)
+	registry.RegisterHandler(TaskTypeProcessRefund, func(ctx context.Context, task *Task) error {
+		panic("nil refund")
+	})
+	registry.RegisterHandler(TaskTypeSendEmail, func(ctx context.Context, task *Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := registry.Handle(&Task{Type: TaskTypeProcessRefund}); !errors.Is(err, ErrTaskPanic) {
+		t.Fatalf("panic error = %v, want ErrTaskPanic", err)
+	}
+	if err := registry.Handle(&Task{Type: TaskTypeSendEmail}); !errors.Is(err, ErrTaskTimeout) {
+		t.Fatalf("timeout error = %v, want ErrTaskTimeout", err)
+	}
+}
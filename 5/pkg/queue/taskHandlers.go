@@ -1,545 +1,1181 @@
-package queue
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/entity"
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-)
-
-// TaskHandler обрабатывает задачи из очереди
-type TaskHandler struct {
-	bookingService service.BookingService
-	eventService   service.EventService
-	userService    service.UserService
-	telegramBot    TelegramBot
-}
-
-// TelegramBot интерфейс для Telegram бота
-type TelegramBot interface {
-	SendMessage(chatID, text string) error
-}
-
-// NewTaskHandler создает новый обработчик задач
-func NewTaskHandler(
-	bookingService service.BookingService,
-	eventService service.EventService,
-	userService service.UserService,
-	telegramBot TelegramBot,
-) *TaskHandler {
-	return &TaskHandler{
-		bookingService: bookingService,
-		eventService:   eventService,
-		userService:    userService,
-		telegramBot:    telegramBot,
-	}
-}
-
-// HandleTask обрабатывает задачу
-func (h *TaskHandler) HandleTask(task *Task) error {
-	log.Printf("Обработка задачи %s типа %s (попытка %d/%d)",
-		task.ID, task.Type, task.Attempts, task.MaxRetries)
-
-	switch task.Type {
-	case TaskTypeExpireBooking:
-		return h.handleExpireBooking(task)
-	case TaskTypeSendNotification:
-		return h.handleSendNotification(task)
-	case TaskTypeCleanupExpired:
-		return h.handleCleanupExpired(task)
-	case TaskTypeReminderNotification:
-		return h.handleReminderNotification(task)
-	case TaskTypeEventReminder:
-		return h.handleEventReminder(task)
-	default:
-		return fmt.Errorf("неизвестный тип задачи: %s", task.Type)
-	}
-}
-
-// handleExpireBooking обрабатывает истечение срока бронирования
-func (h *TaskHandler) handleExpireBooking(task *Task) error {
-	ctx := context.Background()
-
-	bookingID, ok := task.Data["booking_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный booking_id в данных задачи")
-	}
-
-	// Получаем информацию о бронировании
-	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
-	}
-
-	// Проверяем, что бронирование все еще в ожидании
-	if booking.Status != entity.BookingStatusPending {
-		log.Printf("Бронирование %d больше не в статусе ожидания (статус: %s), пропускаем истечение",
-			booking.ID, booking.Status)
-		return nil
-	}
-
-	// Проверяем, что срок действительно истек
-	if time.Now().Before(booking.ExpiresAt) {
-		log.Printf("Бронирование %d еще не истекло (истекает в: %s)",
-			booking.ID, booking.ExpiresAt.Format(time.RFC3339))
-		return nil
-	}
-
-	// Помечаем бронирование как истекшее
-	if err := h.bookingService.ExpireBooking(ctx, booking.ID); err != nil {
-		return fmt.Errorf("не удалось истечь бронирование %d: %v", booking.ID, err)
-	}
-
-	log.Printf("Бронирование %d успешно истекло", booking.ID)
-
-	// Отправляем уведомление об истечении
-	if err := h.sendExpirationNotification(ctx, booking); err != nil {
-		log.Printf("Не удалось отправить уведомление об истечении для бронирования %d: %v", booking.ID, err)
-	}
-
-	return nil
-}
-
-// handleSendNotification обрабатывает отправку уведомлений
-func (h *TaskHandler) handleSendNotification(task *Task) error {
-
-	notificationType, ok := task.Data["notification_type"].(string)
-	if !ok {
-		return fmt.Errorf("неверный notification_type в данных задачи")
-	}
-
-	switch notificationType {
-	case "booking_confirmed":
-		return h.handleBookingConfirmedNotification(task)
-	case "booking_created":
-		return h.handleBookingCreatedNotification(task)
-	case "event_cancelled":
-		return h.handleEventCancelledNotification(task)
-	case "custom_message":
-		return h.handleCustomMessageNotification(task)
-	default:
-		return fmt.Errorf("неизвестный тип уведомления: %s", notificationType)
-	}
-}
-
-// handleBookingConfirmedNotification отправляет уведомление о подтверждении бронирования
-func (h *TaskHandler) handleBookingConfirmedNotification(task *Task) error {
-	ctx := context.Background()
-
-	bookingID, ok := task.Data["booking_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный booking_id в данных задачи")
-	}
-
-	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
-	}
-
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	user, err := h.userService.GetUserByID(ctx, booking.UserID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
-	}
-
-	if user.TelegramID != "" && h.telegramBot != nil {
-		message := fmt.Sprintf(
-			"✅ Ваше бронирование подтверждено!\n\n"+
-				"Мероприятие: %s\n"+
-				"Дата: %s\n"+
-				"Количество мест: %d\n"+
-				"Номер брони: #%d\n\n"+
-				"Ждем вас на мероприятии!",
-			event.Title,
-			event.Date.Format("02.01.2006 в 15:04"),
-			booking.Seats,
-			booking.ID,
-		)
-
-		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-			return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
-		}
-	}
-
-	log.Printf("Отправлено уведомление о подтверждении для бронирования %d пользователю %d", booking.ID, user.ID)
-	return nil
-}
-
-// handleBookingCreatedNotification отправляет уведомление о создании бронирования
-func (h *TaskHandler) handleBookingCreatedNotification(task *Task) error {
-	ctx := context.Background()
-
-	bookingID, ok := task.Data["booking_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный booking_id в данных задачи")
-	}
-
-	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
-	}
-
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	user, err := h.userService.GetUserByID(ctx, booking.UserID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
-	}
-
-	if user.TelegramID != "" && h.telegramBot != nil {
-		expiresAt := booking.ExpiresAt.Format("02.01.2006 в 15:04")
-		message := fmt.Sprintf(
-			"🎫 Бронирование создано!\n\n"+
-				"Мероприятие: %s\n"+
-				"Дата: %s\n"+
-				"Количество мест: %d\n"+
-				"Номер брони: #%d\n"+
-				"Статус: Ожидание оплаты\n"+
-				"Подтвердите бронирование до: %s\n\n"+
-				"Не забудьте подтвердить бронирование вовремя!",
-			event.Title,
-			event.Date.Format("02.01.2006 в 15:04"),
-			booking.Seats,
-			booking.ID,
-			expiresAt,
-		)
-
-		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-			return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
-		}
-	}
-
-	log.Printf("Отправлено уведомление о создании для бронирования %d пользователю %d", booking.ID, user.ID)
-	return nil
-}
-
-// handleEventCancelledNotification отправляет уведомление об отмене мероприятия
-func (h *TaskHandler) handleEventCancelledNotification(task *Task) error {
-	ctx := context.Background()
-
-	eventID, ok := task.Data["event_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный event_id в данных задачи")
-	}
-
-	reason, _ := task.Data["reason"].(string)
-	if reason == "" {
-		reason = "по техническим причинам"
-	}
-
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	// Получаем все бронирования для этого мероприятия
-	bookings, err := h.bookingService.GetEventBookings(ctx, int64(eventID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирования для мероприятия %d: %v", int64(eventID), err)
-	}
-
-	// Отправляем уведомления всем пользователям с подтвержденными бронированиями
-	sentCount := 0
-	for _, booking := range bookings {
-		if booking.Status == entity.BookingStatusConfirmed {
-			user, err := h.userService.GetUserByID(ctx, booking.UserID)
-			if err != nil {
-				log.Printf("Не удалось получить пользователя %d для уведомления об отмене: %v", booking.UserID, err)
-				continue
-			}
-
-			if user.TelegramID != "" && h.telegramBot != nil {
-				message := fmt.Sprintf(
-					"❌ Мероприятие отменено\n\n"+
-						"Мероприятие: %s\n"+
-						"Дата: %s\n"+
-						"Причина: %s\n\n"+
-						"Приносим извинения за доставленные неудобства. "+
-						"Средства за билеты будут возвращены в течение 3-5 рабочих дней.",
-					event.Title,
-					event.Date.Format("02.01.2006 в 15:04"),
-					reason,
-				)
-
-				if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-					log.Printf("Не удалось отправить уведомление об отмене пользователю %d: %v", user.ID, err)
-				} else {
-					sentCount++
-				}
-			}
-		}
-	}
-
-	log.Printf("Отправлены уведомления об отмене мероприятия %d для %d пользователей", eventID, sentCount)
-	return nil
-}
-
-// handleCustomMessageNotification отправляет кастомные сообщения
-func (h *TaskHandler) handleCustomMessageNotification(task *Task) error {
-	ctx := context.Background()
-
-	messageText, ok := task.Data["message"].(string)
-	if !ok {
-		return fmt.Errorf("неверный message в данных задачи")
-	}
-
-	userIDsInterface, ok := task.Data["user_ids"].([]interface{})
-	if !ok {
-		return fmt.Errorf("неверный user_ids в данных задачи")
-	}
-
-	var userIDs []int64
-	for _, id := range userIDsInterface {
-		if idFloat, ok := id.(float64); ok {
-			userIDs = append(userIDs, int64(idFloat))
-		}
-	}
-
-	if len(userIDs) == 0 {
-		log.Printf("Не указаны конкретные ID пользователей, пропускаем широковещательное сообщение")
-		return nil
-	}
-
-	sentCount := 0
-	for _, userID := range userIDs {
-		user, err := h.userService.GetUserByID(ctx, userID)
-		if err != nil {
-			log.Printf("Не удалось получить пользователя %d для кастомного уведомления: %v", userID, err)
-			continue
-		}
-
-		if user.TelegramID != "" && h.telegramBot != nil {
-			if err := h.telegramBot.SendMessage(user.TelegramID, messageText); err != nil {
-				log.Printf("Не удалось отправить кастомное сообщение пользователю %d: %v", user.ID, err)
-			} else {
-				sentCount++
-			}
-		}
-	}
-
-	log.Printf("Отправлено кастомное сообщение %d/%d пользователям", sentCount, len(userIDs))
-	return nil
-}
-
-// handleCleanupExpired выполняет массовую очистку истекших бронирований
-func (h *TaskHandler) handleCleanupExpired(task *Task) error {
-	ctx := context.Background()
-
-	log.Printf("Начало массовой очистки истекших бронирований")
-
-	expiredBefore, ok := task.Data["expired_before"].(string)
-	if !ok {
-		// По умолчанию 1 час назад для безопасности
-		expiredBefore = time.Now().Add(-time.Hour).Format(time.RFC3339)
-	}
-
-	cutoffTime, err := time.Parse(time.RFC3339, expiredBefore)
-	if err != nil {
-		return fmt.Errorf("неверный формат expired_before: %v", err)
-	}
-
-	// Получаем истекшие бронирования
-	expiredBookings, err := h.bookingService.GetExpiredBookings(ctx, cutoffTime)
-	if err != nil {
-		return fmt.Errorf("не удалось получить истекшие бронирования: %v", err)
-	}
-
-	log.Printf("Найдено %d истекших бронирований для очистки", len(expiredBookings))
-
-	successCount := 0
-	for _, expired := range expiredBookings {
-		if err := h.bookingService.ExpireBooking(ctx, expired.BookingID); err != nil {
-			log.Printf("Не удалось истечь бронирование %d: %v", expired.BookingID, err)
-		} else {
-			successCount++
-		}
-	}
-
-	log.Printf("Успешно очищено %d/%d истекших бронирований", successCount, len(expiredBookings))
-	return nil
-}
-
-// handleReminderNotification отправляет напоминания о бронированиях
-func (h *TaskHandler) handleReminderNotification(task *Task) error {
-	ctx := context.Background()
-
-	bookingID, ok := task.Data["booking_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный booking_id в данных задачи")
-	}
-
-	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
-	}
-
-	// Проверяем, что бронирование все еще в ожидании
-	if booking.Status != entity.BookingStatusPending {
-		return nil // Напоминание не нужно
-	}
-
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	user, err := h.userService.GetUserByID(ctx, booking.UserID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
-	}
-
-	if user.TelegramID != "" && h.telegramBot != nil {
-		timeLeft := time.Until(booking.ExpiresAt)
-		minutesLeft := int(timeLeft.Minutes())
-
-		if minutesLeft <= 0 {
-			return nil // Напоминание для истекших бронирований не нужно
-		}
-
-		message := fmt.Sprintf(
-			"⏰ Напоминание о бронировании\n\n"+
-				"Мероприятие: %s\n"+
-				"Дата: %s\n"+
-				"Количество мест: %d\n"+
-				"Номер брони: #%d\n"+
-				"Осталось времени: %d минут\n\n"+
-				"Не забудьте подтвердить бронирование!",
-			event.Title,
-			event.Date.Format("02.01.2006 в 15:04"),
-			booking.Seats,
-			booking.ID,
-			minutesLeft,
-		)
-
-		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-			return fmt.Errorf("не удалось отправить сообщение напоминания: %v", err)
-		}
-	}
-
-	log.Printf("Отправлено напоминание для бронирования %d пользователю %d", booking.ID, user.ID)
-	return nil
-}
-
-// handleEventReminder отправляет напоминания о мероприятиях
-func (h *TaskHandler) handleEventReminder(task *Task) error {
-	ctx := context.Background()
-
-	eventID, ok := task.Data["event_id"].(float64)
-	if !ok {
-		return fmt.Errorf("неверный event_id в данных задачи")
-	}
-
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	// Получаем все подтвержденные бронирования для этого мероприятия
-	bookings, err := h.bookingService.GetEventBookings(ctx, int64(eventID))
-	if err != nil {
-		return fmt.Errorf("не удалось получить бронирования для мероприятия %d: %v", int64(eventID), err)
-	}
-
-	reminderHours, ok := task.Data["reminder_hours"].(float64)
-	if !ok {
-		reminderHours = 24 // По умолчанию 24 часа
-	}
-
-	sentCount := 0
-	for _, booking := range bookings {
-		if booking.Status == entity.BookingStatusConfirmed {
-			user, err := h.userService.GetUserByID(ctx, booking.UserID)
-			if err != nil {
-				log.Printf("Не удалось получить пользователя %d для напоминания о мероприятии: %v", booking.UserID, err)
-				continue
-			}
-
-			if user.TelegramID != "" && h.telegramBot != nil {
-				message := fmt.Sprintf(
-					"🔔 Напоминание о мероприятии\n\n"+
-						"Мероприятие: %s\n"+
-						"Дата и время: %s\n"+
-						"Количество мест: %d\n"+
-						"Номер брони: #%d\n\n"+
-						"Мероприятие начнется через %.0f часов. Ждем вас!",
-					event.Title,
-					event.Date.Format("02.01.2006 в 15:04"),
-					booking.Seats,
-					booking.ID,
-					reminderHours,
-				)
-
-				if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-					log.Printf("Не удалось отправить напоминание о мероприятии пользователю %d: %v", user.ID, err)
-				} else {
-					sentCount++
-				}
-			}
-		}
-	}
-
-	log.Printf("Отправлены напоминания о мероприятии %d для %d пользователей", eventID, sentCount)
-	return nil
-}
-
-// sendExpirationNotification отправляет уведомление об истечении бронирования
-func (h *TaskHandler) sendExpirationNotification(ctx context.Context, booking *entity.Booking) error {
-	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
-	}
-
-	// Преобразуем в базовый Event
-	event := &eventWithAvailability.Event
-
-	user, err := h.userService.GetUserByID(ctx, booking.UserID)
-	if err != nil {
-		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
-	}
-
-	if user.TelegramID != "" && h.telegramBot != nil {
-		message := fmt.Sprintf(
-			"❌ Бронирование отменено\n\n"+
-				"Мероприятие: %s\n"+
-				"Дата: %s\n"+
-				"Количество мест: %d\n"+
-				"Номер брони: #%d\n\n"+
-				"Бронирование было автоматически отменено, так как вы не подтвердили его вовремя.",
-			event.Title,
-			event.Date.Format("02.01.2006 в 15:04"),
-			booking.Seats,
-			booking.ID,
-		)
-
-		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
-			return fmt.Errorf("не удалось отправить уведомление об истечении: %v", err)
-		}
-	}
-
-	return nil
-}
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/entity"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/ds124wfegd/WB_L3/5/pkg/email"
+)
+
+// TaskHandler обрабатывает задачи из очереди
+type TaskHandler struct {
+	bookingService service.BookingService
+	eventService   service.EventService
+	userService    service.UserService
+	posterService  service.EventPosterService
+	telegramBot    TelegramBot
+	webhooks       WebhookDispatcher
+	emailSender    EmailSender
+}
+
+// TelegramBot интерфейс для Telegram бота
+type TelegramBot interface {
+	SendMessage(chatID, text string) error
+}
+
+// WebhookDispatcher рассылает события зарегистрированным вебхукам
+type WebhookDispatcher interface {
+	Dispatch(ctx context.Context, eventID int64, eventType string, data map[string]interface{}) error
+}
+
+// EmailSender интерфейс для отправки писем по шаблонам
+type EmailSender interface {
+	SendTemplate(ctx context.Context, name, to string, data *email.TemplateData) error
+}
+
+// NewTaskHandler создает новый обработчик задач
+func NewTaskHandler(
+	bookingService service.BookingService,
+	eventService service.EventService,
+	userService service.UserService,
+	posterService service.EventPosterService,
+	telegramBot TelegramBot,
+	webhooks WebhookDispatcher,
+	emailSender EmailSender,
+) *TaskHandler {
+	return &TaskHandler{
+		bookingService: bookingService,
+		eventService:   eventService,
+		userService:    userService,
+		posterService:  posterService,
+		telegramBot:    telegramBot,
+		webhooks:       webhooks,
+		emailSender:    emailSender,
+	}
+}
+
+// Register регистрирует в реестре обработчики встроенных типов задач
+func (h *TaskHandler) Register(registry *Registry) error {
+	handlers := map[TaskType]HandlerFunc{
+		TaskTypeExpireBooking:          h.handleExpireBooking,
+		TaskTypeSendNotification:       h.handleSendNotification,
+		TaskTypeCleanupExpired:         h.handleCleanupExpired,
+		TaskTypeReminderNotification:   h.handleReminderNotification,
+		TaskTypeEventReminder:          h.handleEventReminder,
+		TaskTypeSendEmail:              h.handleSendEmail,
+		TaskTypeProcessRefund:          h.handleProcessRefund,
+		TaskTypeConfirmationEscalation: h.handleConfirmationEscalation,
+		TaskTypeSyncEventPoster:        h.handleSyncEventPoster,
+	}
+
+	for taskType, fn := range handlers {
+		if err := registry.RegisterHandler(taskType, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleExpireBooking обрабатывает истечение срока бронирования
+func (h *TaskHandler) handleExpireBooking(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	// Получаем информацию о бронировании
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	// Проверяем, что бронирование все еще в ожидании
+	if booking.Status != entity.BookingStatusPending {
+		log.Printf("Бронирование %d больше не в статусе ожидания (статус: %s), пропускаем истечение",
+			booking.ID, booking.Status)
+		return nil
+	}
+
+	// Проверяем, что срок действительно истек
+	if time.Now().Before(booking.ExpiresAt) {
+		log.Printf("Бронирование %d еще не истекло (истекает в: %s)",
+			booking.ID, booking.ExpiresAt.Format(time.RFC3339))
+		return nil
+	}
+
+	// Помечаем бронирование как истекшее
+	if err := h.bookingService.ExpireBooking(ctx, booking.ID); err != nil {
+		return fmt.Errorf("не удалось истечь бронирование %d: %v", booking.ID, err)
+	}
+
+	log.Printf("Бронирование %d успешно истекло", booking.ID)
+
+	// Отправляем уведомление об истечении
+	h.dispatchBookingWebhook(ctx, task, entity.WebhookEventBookingExpired, booking)
+	if err := h.sendExpirationNotification(ctx, booking); err != nil {
+		log.Printf("Не удалось отправить уведомление об истечении для бронирования %d: %v", booking.ID, err)
+	}
+
+	return nil
+}
+
+// handleSendNotification обрабатывает отправку уведомлений
+func (h *TaskHandler) handleSendNotification(ctx context.Context, task *Task) error {
+
+	notificationType, ok := task.Data["notification_type"].(string)
+	if !ok {
+		return fmt.Errorf("неверный notification_type в данных задачи")
+	}
+
+	switch notificationType {
+	case service.NotificationBookingConfirmed:
+		return h.handleBookingConfirmedNotification(ctx, task)
+	case "booking_created":
+		return h.handleBookingCreatedNotification(ctx, task)
+	case service.NotificationEventCancelled:
+		return h.handleEventCancelledNotification(ctx, task)
+	case service.NotificationCapacityReduced:
+		return h.handleCapacityReducedNotification(ctx, task)
+	case service.NotificationCapacityThreshold:
+		return h.handleCapacityThresholdNotification(ctx, task)
+	case service.NotificationSalePhaseStarted:
+		return h.handleSalePhaseNotification(ctx, task)
+	case "custom_message":
+		return h.handleCustomMessageNotification(ctx, task)
+	default:
+		return fmt.Errorf("неизвестный тип уведомления: %s", notificationType)
+	}
+}
+
+// handleBookingConfirmedNotification отправляет уведомление о подтверждении бронирования
+func (h *TaskHandler) handleBookingConfirmedNotification(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	h.dispatchBookingWebhook(ctx, task, entity.WebhookEventBookingConfirmed, booking)
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if user.WantsTelegramFor(entity.NotificationBookingUpdates) && h.telegramBot != nil {
+		message := fmt.Sprintf(
+			"✅ Ваше бронирование подтверждено!\n\n"+
+				"Мероприятие: %s\n"+
+				"Дата: %s\n"+
+				"%s"+
+				"Количество мест: %d\n"+
+				"Номер брони: #%d\n\n"+
+				"Ждем вас на мероприятии!%s",
+			event.Title,
+			event.Date.Format("02.01.2006 в 15:04"),
+			venueText(event),
+			booking.Seats,
+			booking.ID,
+			staffAssistedText(task),
+		)
+
+		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+			return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
+		}
+	}
+
+	// Отметка нужна сверке; без неё уведомление попадёт в отчёт, но повторять задачу ради
+	// отметки нельзя - пользователь получил бы сообщение дважды
+	if err := h.bookingService.RecordNotification(ctx, booking.ID, service.NotificationBookingConfirmed); err != nil {
+		log.Printf("Не удалось отметить уведомление о подтверждении бронирования %d: %v", booking.ID, err)
+	}
+
+	log.Printf("Отправлено уведомление о подтверждении для бронирования %d пользователю %d", booking.ID, user.ID)
+	return nil
+}
+
+// handleBookingCreatedNotification отправляет уведомление о создании бронирования
+func (h *TaskHandler) handleBookingCreatedNotification(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	h.dispatchBookingWebhook(ctx, task, entity.WebhookEventBookingCreated, booking)
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if user.WantsTelegramFor(entity.NotificationBookingUpdates) && h.telegramBot != nil {
+		expiresAt := booking.ExpiresAt.Format("02.01.2006 в 15:04")
+		message := fmt.Sprintf(
+			"🎫 Бронирование создано!\n\n"+
+				"Мероприятие: %s\n"+
+				"Дата: %s\n"+
+				"%s"+
+				"Количество мест: %d\n"+
+				"Номер брони: #%d\n"+
+				"Статус: Ожидание оплаты\n"+
+				"Подтвердите бронирование до: %s\n\n"+
+				"Не забудьте подтвердить бронирование вовремя!%s",
+			event.Title,
+			event.Date.Format("02.01.2006 в 15:04"),
+			venueText(event),
+			booking.Seats,
+			booking.ID,
+			expiresAt,
+			staffAssistedText(task),
+		)
+
+		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+			return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
+		}
+	}
+
+	log.Printf("Отправлено уведомление о создании для бронирования %d пользователю %d", booking.ID, user.ID)
+	return nil
+}
+
+// handleEventCancelledNotification отправляет уведомление об отмене мероприятия
+func (h *TaskHandler) handleEventCancelledNotification(ctx context.Context, task *Task) error {
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный event_id в данных задачи")
+	}
+
+	reason, _ := task.Data["reason"].(string)
+	if reason == "" {
+		reason = "по техническим причинам"
+	}
+
+	event, err := h.cancelledEvent(ctx, task, int64(eventID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
+	}
+
+	// Получаем все бронирования для этого мероприятия
+	bookings, err := h.bookingService.GetEventBookings(ctx, int64(eventID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирования для мероприятия %d: %v", int64(eventID), err)
+	}
+
+	h.dispatchWebhook(ctx, task, event.ID, entity.WebhookEventEventCancelled, map[string]interface{}{
+		"event_id":    event.ID,
+		"event_title": event.Title,
+		"event_date":  event.Date,
+		"reason":      reason,
+		"bookings":    len(bookings),
+	})
+
+	// Отправляем уведомления всем пользователям с подтвержденными бронированиями
+	sentCount := 0
+	for _, booking := range bookings {
+		if booking.Status == entity.BookingStatusConfirmed {
+			user, err := h.userService.GetUserByID(ctx, booking.UserID)
+			if err != nil {
+				log.Printf("Не удалось получить пользователя %d для уведомления об отмене: %v", booking.UserID, err)
+				continue
+			}
+
+			if user.WantsTelegramFor(entity.NotificationBookingUpdates) && h.telegramBot != nil {
+				message := fmt.Sprintf(
+					"❌ Мероприятие отменено\n\n"+
+						"Мероприятие: %s\n"+
+						"Дата: %s\n"+
+						"Причина: %s\n\n"+
+						"Приносим извинения за доставленные неудобства. "+
+						"Средства за билеты будут возвращены в течение 3-5 рабочих дней.",
+					event.Title,
+					event.Date.Format("02.01.2006 в 15:04"),
+					reason,
+				)
+
+				if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+					log.Printf("Не удалось отправить уведомление об отмене пользователю %d: %v", user.ID, err)
+				} else {
+					sentCount++
+				}
+			}
+		}
+	}
+
+	log.Printf("Отправлены уведомления об отмене мероприятия %d для %d пользователей", eventID, sentCount)
+	return nil
+}
+
+// cancelledEvent читает отменённое мероприятие; удалённое уже не найти, тогда название и дата
+// берутся из данных задачи
+func (h *TaskHandler) cancelledEvent(ctx context.Context, task *Task, eventID int64) (*entity.Event, error) {
+	event, err := h.eventService.GetEvent(ctx, eventID)
+	if err == nil {
+		return &event.Event, nil
+	}
+
+	title := task.GetString("event_title")
+	if !errors.Is(err, entity.ErrEventNotFound) || title == "" {
+		return nil, err
+	}
+	return &entity.Event{ID: eventID, Title: title, Date: task.GetTime("event_date")}, nil
+}
+
+// handleCapacityReducedNotification сообщает владельцу, что его бронирование отменено из-за
+// уменьшения вместимости мероприятия, о возврате и о месте в листе ожидания
+func (h *TaskHandler) handleCapacityReducedNotification(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+	resolution, _ := task.Data["resolution"].(string)
+	refundAmount, _ := task.Data["refund_amount"].(float64)
+	reason, _ := task.Data["reason"].(string)
+
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+	event := &eventWithAvailability.Event
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if user.WantsTelegramFor(entity.NotificationBookingUpdates) && h.telegramBot != nil {
+		refund := "Оплата не производилась."
+		if refundAmount > 0 {
+			refund = fmt.Sprintf("Сумма возврата: %.2f (100%%)", refundAmount)
+		}
+		waitlist := ""
+		if resolution == entity.CapacityResolutionWaitlisted {
+			waitlist = "\nВы добавлены в лист ожидания: организатор свяжется с вами, если места освободятся."
+		}
+
+		message := fmt.Sprintf(
+			"⚠️ Бронирование отменено: мест на мероприятии стало меньше\n\n"+
+				"Мероприятие: %s\n"+
+				"Дата: %s\n"+
+				"Количество мест: %d\n"+
+				"Причина: %s\n"+
+				"%s%s\n\n"+
+				"Приносим извинения за доставленные неудобства.%s",
+			event.Title,
+			event.Date.Format("02.01.2006 в 15:04"),
+			booking.Seats,
+			reason,
+			refund,
+			waitlist,
+			staffAssistedText(task),
+		)
+
+		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+			return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
+		}
+	}
+
+	log.Printf("Отправлено уведомление об отмене бронирования %d из-за уменьшения вместимости пользователю %d", booking.ID, user.ID)
+	return nil
+}
+
+// handleCapacityThresholdNotification сообщает организатору и вебхукам мероприятия, что его
+// заполненность достигла порога. Мероприятия без организатора (созданные администратором)
+// получают только вебхук.
+func (h *TaskHandler) handleCapacityThresholdNotification(ctx context.Context, task *Task) error {
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный event_id в данных задачи")
+	}
+	threshold := task.GetInt("threshold")
+	occupied := task.GetInt("occupied_seats")
+	total := task.GetInt("total_seats")
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
+	}
+	event := &eventWithAvailability.Event
+
+	h.dispatchWebhook(ctx, task, event.ID, entity.WebhookEventEventCapacity, map[string]interface{}{
+		"event_id":       event.ID,
+		"threshold":      threshold,
+		"sold_out":       threshold >= entity.CapacitySoldOut,
+		"occupied_seats": occupied,
+		"total_seats":    total,
+	})
+
+	if event.OrganizerID == nil || h.telegramBot == nil {
+		return nil
+	}
+	organizer, err := h.userService.GetUserByID(ctx, *event.OrganizerID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить организатора %d: %v", *event.OrganizerID, err)
+	}
+	if !organizer.WantsTelegramFor(entity.NotificationBookingUpdates) {
+		return nil
+	}
+
+	headline := fmt.Sprintf("📈 Мероприятие заполнено на %d%%", threshold)
+	if threshold >= entity.CapacitySoldOut {
+		headline = "🎉 Все места на мероприятие распроданы"
+	}
+	message := fmt.Sprintf(
+		"%s\n\n"+
+			"Мероприятие: %s\n"+
+			"Дата: %s\n"+
+			"Занято мест: %d из %d",
+		headline,
+		event.Title,
+		event.Date.Format("02.01.2006 в 15:04"),
+		occupied,
+		total,
+	)
+
+	if err := h.telegramBot.SendMessage(organizer.TelegramID, message); err != nil {
+		return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
+	}
+
+	log.Printf("Отправлено уведомление о заполненности %d%% мероприятия %d организатору %d", threshold, event.ID, organizer.ID)
+	return nil
+}
+
+// handleSalePhaseNotification объявляет вебхукам и организатору о начале фазы продаж. Задача
+// планируется при сохранении расписания, поэтому перед отправкой фаза сверяется с текущим
+// расписанием мероприятия: после переноса или отмены фазы объявление пропускается.
+func (h *TaskHandler) handleSalePhaseNotification(ctx context.Context, task *Task) error {
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный event_id в данных задачи")
+	}
+	phase, _ := task.Data["phase"].(string)
+	phaseAtStr, _ := task.Data["phase_at"].(string)
+	phaseAt, err := time.Parse(time.RFC3339, phaseAtStr)
+	if err != nil {
+		return fmt.Errorf("неверный phase_at в данных задачи: %v", err)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
+	if errors.Is(err, entity.ErrEventNotFound) {
+		log.Printf("Мероприятие %d удалено, объявление о фазе продаж %s пропущено", int64(eventID), phase)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
+	}
+	event := &eventWithAvailability.Event
+
+	if !hasSaleTransition(event, phase, phaseAt) {
+		log.Printf("Расписание продаж мероприятия %d изменилось, объявление о фазе %s на %s пропущено",
+			event.ID, phase, phaseAt.Format(time.RFC3339))
+		return nil
+	}
+
+	h.dispatchWebhook(ctx, task, event.ID, entity.WebhookEventSalePhase, map[string]interface{}{
+		"event_id":        event.ID,
+		"phase":           phase,
+		"phase_at":        phaseAtStr,
+		"available_seats": eventWithAvailability.AvailableSeats,
+	})
+
+	if event.OrganizerID == nil || h.telegramBot == nil {
+		return nil
+	}
+	organizer, err := h.userService.GetUserByID(ctx, *event.OrganizerID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить организатора %d: %v", *event.OrganizerID, err)
+	}
+	if !organizer.WantsTelegramFor(entity.NotificationBookingUpdates) {
+		return nil
+	}
+
+	var headline string
+	switch phase {
+	case entity.SalePhasePresale:
+		headline = "🔑 Началась предпродажа"
+	case entity.SalePhaseGeneral:
+		headline = "🎟 Открыта продажа билетов"
+	default:
+		headline = "🔒 Продажа билетов завершена"
+	}
+	message := fmt.Sprintf(
+		"%s\n\n"+
+			"Мероприятие: %s\n"+
+			"Дата: %s\n"+
+			"Свободно мест: %d из %d",
+		headline,
+		event.Title,
+		event.Date.Format("02.01.2006 в 15:04"),
+		eventWithAvailability.AvailableSeats,
+		event.TotalSeats,
+	)
+
+	if err := h.telegramBot.SendMessage(organizer.TelegramID, message); err != nil {
+		return fmt.Errorf("не удалось отправить Telegram сообщение: %v", err)
+	}
+
+	log.Printf("Отправлено объявление о фазе продаж %s мероприятия %d организатору %d", phase, event.ID, organizer.ID)
+	return nil
+}
+
+// hasSaleTransition проверяет, что фаза phase по-прежнему начинается в at
+func hasSaleTransition(event *entity.Event, phase string, at time.Time) bool {
+	for _, transition := range event.SaleSchedule.Transitions(event.Date) {
+		if transition.Phase == phase && transition.At.Unix() == at.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// handleCustomMessageNotification отправляет кастомные сообщения
+func (h *TaskHandler) handleCustomMessageNotification(ctx context.Context, task *Task) error {
+	messageText, ok := task.Data["message"].(string)
+	if !ok {
+		return fmt.Errorf("неверный message в данных задачи")
+	}
+
+	userIDsInterface, ok := task.Data["user_ids"].([]interface{})
+	if !ok {
+		return fmt.Errorf("неверный user_ids в данных задачи")
+	}
+
+	var userIDs []int64
+	for _, id := range userIDsInterface {
+		if idFloat, ok := id.(float64); ok {
+			userIDs = append(userIDs, int64(idFloat))
+		}
+	}
+
+	if len(userIDs) == 0 {
+		log.Printf("Не указаны конкретные ID пользователей, пропускаем широковещательное сообщение")
+		return nil
+	}
+
+	sentCount := 0
+	for _, userID := range userIDs {
+		user, err := h.userService.GetUserByID(ctx, userID)
+		if err != nil {
+			log.Printf("Не удалось получить пользователя %d для кастомного уведомления: %v", userID, err)
+			continue
+		}
+
+		if user.WantsTelegramFor(entity.NotificationMarketing) && h.telegramBot != nil {
+			if err := h.telegramBot.SendMessage(user.TelegramID, messageText); err != nil {
+				log.Printf("Не удалось отправить кастомное сообщение пользователю %d: %v", user.ID, err)
+			} else {
+				sentCount++
+			}
+		}
+	}
+
+	log.Printf("Отправлено кастомное сообщение %d/%d пользователям", sentCount, len(userIDs))
+	return nil
+}
+
+// handleCleanupExpired выполняет массовую очистку истекших бронирований
+func (h *TaskHandler) handleCleanupExpired(ctx context.Context, task *Task) error {
+	log.Printf("Начало массовой очистки истекших бронирований")
+
+	expiredBefore, ok := task.Data["expired_before"].(string)
+	if !ok {
+		// По умолчанию 1 час назад для безопасности
+		expiredBefore = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	}
+
+	cutoffTime, err := time.Parse(time.RFC3339, expiredBefore)
+	if err != nil {
+		return fmt.Errorf("неверный формат expired_before: %v", err)
+	}
+
+	// Получаем истекшие бронирования
+	expiredBookings, err := h.bookingService.GetExpiredBookings(ctx, cutoffTime)
+	if err != nil {
+		return fmt.Errorf("не удалось получить истекшие бронирования: %v", err)
+	}
+
+	log.Printf("Найдено %d истекших бронирований для очистки", len(expiredBookings))
+
+	successCount := 0
+	for _, expired := range expiredBookings {
+		if err := h.bookingService.ExpireBooking(ctx, expired.BookingID); err != nil {
+			log.Printf("Не удалось истечь бронирование %d: %v", expired.BookingID, err)
+		} else {
+			successCount++
+		}
+	}
+
+	log.Printf("Успешно очищено %d/%d истекших бронирований", successCount, len(expiredBookings))
+	return nil
+}
+
+// handleReminderNotification отправляет напоминания о бронированиях
+func (h *TaskHandler) handleReminderNotification(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	// Проверяем, что бронирование все еще в ожидании
+	if booking.Status != entity.BookingStatusPending {
+		return nil // Напоминание не нужно
+	}
+
+	if time.Now().Before(booking.ExpiresAt) {
+		h.dispatchBookingWebhook(ctx, task, entity.WebhookEventBookingReminder, booking)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if user.WantsTelegramFor(entity.NotificationReminders) && h.telegramBot != nil {
+		timeLeft := time.Until(booking.ExpiresAt)
+		minutesLeft := int(timeLeft.Minutes())
+
+		if minutesLeft <= 0 {
+			return nil // Напоминание для истекших бронирований не нужно
+		}
+
+		message := fmt.Sprintf(
+			"⏰ Напоминание о бронировании\n\n"+
+				"Мероприятие: %s\n"+
+				"Дата: %s\n"+
+				"%s"+
+				"Количество мест: %d\n"+
+				"Номер брони: #%d\n"+
+				"Осталось времени: %d минут\n\n"+
+				"Не забудьте подтвердить бронирование!",
+			event.Title,
+			event.Date.Format("02.01.2006 в 15:04"),
+			venueText(event),
+			booking.Seats,
+			booking.ID,
+			minutesLeft,
+		)
+
+		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+			return fmt.Errorf("не удалось отправить сообщение напоминания: %v", err)
+		}
+	}
+
+	log.Printf("Отправлено напоминание для бронирования %d пользователю %d", booking.ID, user.ID)
+	return nil
+}
+
+// handleConfirmationEscalation выполняет шаг цепочки напоминаний о неподтверждённом бронировании
+func (h *TaskHandler) handleConfirmationEscalation(ctx context.Context, task *Task) error {
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	step := task.GetString("step")
+	expiresAt := task.GetTime("expires_at")
+	if step == "" || expiresAt.IsZero() {
+		return fmt.Errorf("неверный шаг цепочки в данных задачи")
+	}
+
+	if err := h.bookingService.EscalateConfirmation(ctx, int64(bookingID), step, expiresAt); err != nil {
+		return fmt.Errorf("не удалось выполнить шаг %s для бронирования %d: %v", step, int64(bookingID), err)
+	}
+
+	return nil
+}
+
+// handleSyncEventPoster проверяет, построил ли сервис изображений варианты афиши
+func (h *TaskHandler) handleSyncEventPoster(ctx context.Context, task *Task) error {
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный event_id в данных задачи")
+	}
+
+	imageID := task.GetString("image_id")
+	if imageID == "" {
+		return fmt.Errorf("неверный image_id в данных задачи")
+	}
+
+	if err := h.posterService.SyncPoster(ctx, int64(eventID), imageID, task.GetTime("deadline")); err != nil {
+		return fmt.Errorf("не удалось обновить афишу мероприятия %d: %v", int64(eventID), err)
+	}
+
+	return nil
+}
+
+// handleEventReminder отправляет напоминания о мероприятиях
+func (h *TaskHandler) handleEventReminder(ctx context.Context, task *Task) error {
+	reminderHours, ok := task.Data["reminder_hours"].(float64)
+	if !ok {
+		reminderHours = 24 // По умолчанию 24 часа
+	}
+
+	// Напоминание одному бронированию, запланированное ReminderPlanner
+	if _, ok := task.Data["booking_id"].(float64); ok {
+		return h.remindBooking(ctx, task, reminderHours)
+	}
+
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		// Без event_id это периодический обход по расписанию
+		return h.sweepEventReminders(ctx, task, reminderHours)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
+	}
+
+	return h.remindEvent(ctx, task, &eventWithAvailability.Event, reminderHours)
+}
+
+// sweepEventReminders рассылает напоминания о мероприятиях, которые начинаются через reminder_hours
+// в пределах окна window_minutes (по умолчанию 60, под ежечасное расписание). Окно отсчитывается
+// от запланированного времени запуска, чтобы соседние обходы не пропускали и не повторяли мероприятия.
+func (h *TaskHandler) sweepEventReminders(ctx context.Context, task *Task, reminderHours float64) error {
+	windowMinutes := task.GetInt("window_minutes")
+	if windowMinutes <= 0 {
+		windowMinutes = 60
+	}
+	scheduledAt := task.GetTime("scheduled_at")
+	if scheduledAt.IsZero() {
+		scheduledAt = time.Now()
+	}
+
+	from := scheduledAt.Add(time.Duration(reminderHours * float64(time.Hour)))
+	filter := &service.EventFilter{
+		DateFrom:  from,
+		DateTo:    from.Add(time.Duration(windowMinutes)*time.Minute - time.Second),
+		Limit:     100,
+		SortBy:    "date",
+		SortOrder: "asc",
+	}
+
+	reminded := 0
+	for {
+		events, err := h.eventService.SearchEvents(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("не удалось найти мероприятия для напоминаний: %v", err)
+		}
+
+		for _, event := range events {
+			if err := h.remindEvent(ctx, task, &event.Event, reminderHours); err != nil {
+				log.Printf("Не удалось отправить напоминания о мероприятии %d: %v", event.ID, err)
+				continue
+			}
+			reminded++
+		}
+
+		if len(events) < filter.Limit {
+			break
+		}
+		filter.Offset += len(events)
+	}
+
+	log.Printf("Обход напоминаний: обработано %d мероприятий", reminded)
+	return nil
+}
+
+// remindEvent отправляет напоминание всем подтвержденным бронированиям мероприятия
+func (h *TaskHandler) remindEvent(ctx context.Context, task *Task, event *entity.Event, reminderHours float64) error {
+	// Получаем все подтвержденные бронирования для этого мероприятия
+	bookings, err := h.bookingService.GetEventBookings(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирования для мероприятия %d: %v", event.ID, err)
+	}
+
+	h.dispatchWebhook(ctx, task, event.ID, entity.WebhookEventEventReminder, map[string]interface{}{
+		"event_id":       event.ID,
+		"event_title":    event.Title,
+		"event_date":     event.Date,
+		"reminder_hours": reminderHours,
+	})
+
+	sentCount := 0
+	for _, booking := range bookings {
+		if booking.Status != entity.BookingStatusConfirmed {
+			continue
+		}
+
+		sent, err := h.sendEventReminder(ctx, event, booking, reminderHours)
+		if err != nil {
+			log.Printf("Не удалось отправить напоминание о мероприятии по бронированию %d: %v", booking.ID, err)
+			continue
+		}
+		if sent {
+			sentCount++
+		}
+	}
+
+	log.Printf("Отправлены напоминания о мероприятии %d для %d пользователей", event.ID, sentCount)
+	return nil
+}
+
+// remindBooking отправляет запланированное напоминание владельцу бронирования, если бронирование
+// всё ещё подтверждено, а мероприятие не перенесли: по новой дате напоминание запланируется заново
+func (h *TaskHandler) remindBooking(ctx context.Context, task *Task, reminderHours float64) error {
+	bookingID := int64(task.GetInt("booking_id"))
+
+	booking, err := h.bookingService.GetBooking(ctx, bookingID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", bookingID, err)
+	}
+	if booking.Status != entity.BookingStatusConfirmed {
+		log.Printf("Бронирование %d не подтверждено (статус: %s), напоминание не отправляется", booking.ID, booking.Status)
+		return nil
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+	event := &eventWithAvailability.Event
+
+	if planned := task.GetTime("event_date"); !planned.IsZero() && planned.Unix() != event.Date.Unix() {
+		log.Printf("Мероприятие %d перенесено, напоминание по бронированию %d устарело", event.ID, booking.ID)
+		return nil
+	}
+	if !event.Date.After(time.Now()) {
+		return nil
+	}
+
+	_, err = h.sendEventReminder(ctx, event, booking, reminderHours)
+	return err
+}
+
+// sendEventReminder отправляет напоминание о мероприятии владельцу бронирования. Отправка
+// отмечается до сообщения, поэтому повторная доставка задачи и обход по расписанию с тем же
+// сроком напоминание не дублируют; если сообщение не ушло, отметка снимается для повтора.
+func (h *TaskHandler) sendEventReminder(ctx context.Context, event *entity.Event, booking *entity.Booking, reminderHours float64) (bool, error) {
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+	if !user.WantsTelegramFor(entity.NotificationReminders) || h.telegramBot == nil {
+		return false, nil
+	}
+
+	reminder := service.ReminderKey(reminderHours)
+	claimed, err := h.bookingService.ClaimReminder(ctx, booking.ID, reminder)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	message := fmt.Sprintf(
+		"🔔 Напоминание о мероприятии\n\n"+
+			"Мероприятие: %s\n"+
+			"Дата и время: %s\n"+
+			"Количество мест: %d\n"+
+			"Номер брони: #%d\n\n"+
+			"Мероприятие начнется через %.0f часов. Ждем вас!",
+		event.Title,
+		event.Date.Format("02.01.2006 в 15:04"),
+		booking.Seats,
+		booking.ID,
+		reminderHours,
+	)
+
+	if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+		if releaseErr := h.bookingService.ReleaseReminder(ctx, booking.ID, reminder); releaseErr != nil {
+			log.Printf("Не удалось снять отметку напоминания %s по бронированию %d: %v", reminder, booking.ID, releaseErr)
+		}
+		return false, fmt.Errorf("не удалось отправить напоминание пользователю %d: %v", user.ID, err)
+	}
+
+	return true, nil
+}
+
+// sendExpirationNotification отправляет уведомление об истечении бронирования
+func (h *TaskHandler) sendExpirationNotification(ctx context.Context, booking *entity.Booking) error {
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+
+	// Преобразуем в базовый Event
+	event := &eventWithAvailability.Event
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if user.WantsTelegramFor(entity.NotificationBookingUpdates) && h.telegramBot != nil {
+		message := fmt.Sprintf(
+			"❌ Бронирование отменено\n\n"+
+				"Мероприятие: %s\n"+
+				"Дата: %s\n"+
+				"Количество мест: %d\n"+
+				"Номер брони: #%d\n\n"+
+				"Бронирование было автоматически отменено, так как вы не подтвердили его вовремя.",
+			event.Title,
+			event.Date.Format("02.01.2006 в 15:04"),
+			booking.Seats,
+			booking.ID,
+		)
+
+		if err := h.telegramBot.SendMessage(user.TelegramID, message); err != nil {
+			return fmt.Errorf("не удалось отправить уведомление об истечении: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// dispatchBookingWebhook отправляет вебхук с данными бронирования
+func (h *TaskHandler) dispatchBookingWebhook(ctx context.Context, task *Task, eventType string, booking *entity.Booking) {
+	h.dispatchWebhook(ctx, task, booking.EventID, eventType, map[string]interface{}{
+		"booking_id":  booking.ID,
+		"event_id":    booking.EventID,
+		"user_id":     booking.UserID,
+		"seats":       booking.Seats,
+		"status":      booking.Status,
+		"total_price": booking.TotalPrice,
+		"expires_at":  booking.ExpiresAt,
+	})
+}
+
+// dispatchWebhook рассылает событие вебхукам только при первой попытке задачи:
+// у доставок свои повторы и журнал, а повтор задачи вызван сбоем другого канала
+// и не должен приводить к дублям у подписчиков. Ошибки вебхуков задачу не проваливают.
+func (h *TaskHandler) dispatchWebhook(ctx context.Context, task *Task, eventID int64, eventType string, data map[string]interface{}) {
+	if h.webhooks == nil || task.Attempts > 0 {
+		return
+	}
+
+	if err := h.webhooks.Dispatch(ctx, eventID, eventType, data); err != nil {
+		log.Printf("Не удалось разослать вебхуки %s для мероприятия %d: %v", eventType, eventID, err)
+	}
+}
+
+// handleSendEmail отправляет письмо по шаблону владельцу бронирования или,
+// для отмены мероприятия, всем пользователям с подтвержденными бронированиями
+func (h *TaskHandler) handleSendEmail(ctx context.Context, task *Task) error {
+	if h.emailSender == nil {
+		return nil // Отправка писем отключена
+	}
+
+	template := task.GetString("template")
+	if template == "" {
+		return fmt.Errorf("неверный template в данных задачи")
+	}
+
+	if template == email.TemplateEventCancelled {
+		return h.sendEventCancelledEmails(ctx, task)
+	}
+
+	bookingID, ok := task.Data["booking_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный booking_id в данных задачи")
+	}
+
+	booking, err := h.bookingService.GetBooking(ctx, int64(bookingID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирование %d: %v", int64(bookingID), err)
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, booking.EventID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", booking.EventID, err)
+	}
+	event := &eventWithAvailability.Event
+
+	user, err := h.userService.GetUserByID(ctx, booking.UserID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить пользователя %d: %v", booking.UserID, err)
+	}
+
+	if !user.WantsEmailFor(emailCategory(template)) {
+		return nil
+	}
+
+	data := &email.TemplateData{
+		UserName:   user.Name,
+		EventTitle: event.Title,
+		EventDate:  event.Date,
+		BookingID:  booking.ID,
+		Seats:      booking.Seats,
+		TotalPrice: booking.TotalPrice,
+		ExpiresAt:  booking.ExpiresAt,
+
+		StaffAssisted: task.GetBool("staff_assisted"),
+	}
+	setVenue(data, event)
+
+	if err := h.emailSender.SendTemplate(ctx, template, user.Email, data); err != nil {
+		return fmt.Errorf("не удалось отправить письмо %s: %v", template, err)
+	}
+
+	log.Printf("Отправлено письмо %s для бронирования %d пользователю %d", template, booking.ID, user.ID)
+	return nil
+}
+
+// emailCategory относит шаблон письма к категории уведомлений из настроек пользователя
+func emailCategory(template string) string {
+	if template == email.TemplateBookingReminder {
+		return entity.NotificationReminders
+	}
+	return entity.NotificationBookingUpdates
+}
+
+// sendEventCancelledEmails рассылает письма об отмене мероприятия; ошибки отдельных
+// адресатов не повторяют задачу, чтобы остальные не получили письмо дважды
+func (h *TaskHandler) sendEventCancelledEmails(ctx context.Context, task *Task) error {
+	eventID, ok := task.Data["event_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный event_id в данных задачи")
+	}
+
+	eventWithAvailability, err := h.eventService.GetEvent(ctx, int64(eventID))
+	if err != nil {
+		return fmt.Errorf("не удалось получить мероприятие %d: %v", int64(eventID), err)
+	}
+	event := &eventWithAvailability.Event
+
+	bookings, err := h.bookingService.GetEventBookings(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить бронирования для мероприятия %d: %v", event.ID, err)
+	}
+
+	sentCount := 0
+	for _, booking := range bookings {
+		if booking.Status != entity.BookingStatusConfirmed {
+			continue
+		}
+
+		user, err := h.userService.GetUserByID(ctx, booking.UserID)
+		if err != nil {
+			log.Printf("Не удалось получить пользователя %d для письма об отмене: %v", booking.UserID, err)
+			continue
+		}
+		if !user.WantsEmailFor(entity.NotificationBookingUpdates) {
+			continue
+		}
+
+		data := &email.TemplateData{
+			UserName:   user.Name,
+			EventTitle: event.Title,
+			EventDate:  event.Date,
+			BookingID:  booking.ID,
+			Seats:      booking.Seats,
+			Reason:     task.GetString("reason"),
+		}
+		setVenue(data, event)
+
+		if err := h.emailSender.SendTemplate(ctx, email.TemplateEventCancelled, user.Email, data); err != nil {
+			log.Printf("Не удалось отправить письмо об отмене пользователю %d: %v", user.ID, err)
+			continue
+		}
+		sentCount++
+	}
+
+	log.Printf("Отправлены письма об отмене мероприятия %d для %d пользователей", event.ID, sentCount)
+	return nil
+}
+
+// staffAssistedText возвращает пометку для Telegram-сообщения об изменении,
+// которое сотрудник выполнил от имени пользователя
+func staffAssistedText(task *Task) string {
+	if !task.GetBool("staff_assisted") {
+		return ""
+	}
+	return "\n\n" + entity.StaffAssistedNotice
+}
+
+// venueText возвращает строки о месте проведения для Telegram-сообщений:
+// площадку со ссылкой на карту или, если площадка не задана, текстовое место проведения
+func venueText(event *entity.Event) string {
+	if event.Venue == nil {
+		if event.Location == "" {
+			return ""
+		}
+		return fmt.Sprintf("Место: %s\n", event.Location)
+	}
+
+	text := "Место: " + event.Venue.Name
+	if event.Venue.Address != "" {
+		text += ", " + event.Venue.Address
+	}
+	text += "\n"
+	if mapURL := event.Venue.MapLink(); mapURL != "" {
+		text += "На карте: " + mapURL + "\n"
+	}
+	return text
+}
+
+// setVenue дополняет данные письма площадкой мероприятия
+func setVenue(data *email.TemplateData, event *entity.Event) {
+	if event.Venue == nil {
+		data.VenueName = event.Location
+		return
+	}
+	data.VenueName = event.Venue.Name
+	data.VenueAddress = event.Venue.Address
+	data.MapURL = event.Venue.MapLink()
+}
+
+// handleProcessRefund выплачивает возврат за отменённое бронирование
+func (h *TaskHandler) handleProcessRefund(ctx context.Context, task *Task) error {
+	refundID, ok := task.Data["refund_id"].(float64)
+	if !ok {
+		return fmt.Errorf("неверный refund_id в данных задачи")
+	}
+
+	refund, err := h.bookingService.ProcessRefund(ctx, int64(refundID))
+	if err != nil {
+		return fmt.Errorf("не удалось обработать возврат %d: %v", int64(refundID), err)
+	}
+
+	log.Printf("Возврат %d по бронированию %d: статус %s, сумма %.2f", refund.ID, refund.BookingID, refund.Status, refund.Amount)
+	return nil
+}
@@ -1,12 +1,13 @@
-package queue
-
-import (
-	"context"
-)
-
-// Queue интерфейс очереди
-type Queue interface {
-	Publish(ctx context.Context, task *Task) error
-	Subscribe(ctx context.Context, handler func(*Task) error) error
-	Close() error
-}
+package queue
+
+import (
+	"context"
+)
+
+// Queue интерфейс очереди, не зависящий от брокера. Реализации: RedisQueue, KafkaQueue,
+// RabbitMQQueue и MemoryQueue; сервисы публикуют задачи через него и не знают, какая выбрана.
+type Queue interface {
+	Publish(ctx context.Context, task *Task) error
+	Subscribe(ctx context.Context, handler func(*Task) error) error
+	Close() error
+}
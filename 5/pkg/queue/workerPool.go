@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency  = 1
+	defaultDrainTimeout = 30 * time.Second
+)
+
+// errQueueStopping прерывает ожидание повторной попытки при остановке очереди:
+// задача возвращается в свой список, а не уходит в DLQ
+var errQueueStopping = errors.New("queue is stopping")
+
+// workerPool ограничивает число одновременно выполняемых задач.
+// Слот берётся до извлечения задачи из Redis, поэтому задач в памяти не больше,
+// чем воркеров, а число горутин не растёт вместе с длиной очереди.
+type workerPool struct {
+	slots     chan struct{}
+	typeSlots map[TaskType]chan struct{}
+	// parked ограничивает задачи, которые ждут слот своего типа, отпустив общий слот
+	parked chan struct{}
+
+	mu       sync.Mutex
+	stopped  bool
+	inflight sync.WaitGroup
+}
+
+func newWorkerPool(concurrency int, typeLimits map[TaskType]int) *workerPool {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	pool := &workerPool{
+		slots:     make(chan struct{}, concurrency),
+		typeSlots: make(map[TaskType]chan struct{}, len(typeLimits)),
+		parked:    make(chan struct{}, concurrency),
+	}
+	for taskType, limit := range typeLimits {
+		// Лимит типа больше общего ничего не ограничивает
+		if limit > 0 && limit < concurrency {
+			pool.typeSlots[taskType] = make(chan struct{}, limit)
+		}
+	}
+
+	return pool
+}
+
+// acquire занимает общий слот; false - очередь останавливается
+func (p *workerPool) acquire(ctx context.Context, stop <-chan struct{}) bool {
+	// Остановка проверяется первой, иначе при свободном слоте select мог бы выбрать его
+	select {
+	case <-ctx.Done():
+		return false
+	case <-stop:
+		return false
+	default:
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-stop:
+		return false
+	}
+}
+
+func (p *workerPool) release() {
+	<-p.slots
+}
+
+// acquireType занимает слот типа задачи, если для типа задан лимит
+func (p *workerPool) acquireType(ctx context.Context, stop <-chan struct{}, taskType TaskType) (release func(), ok bool) {
+	sem, limited := p.typeSlots[taskType]
+	if !limited {
+		return func() {}, true
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, true
+	case <-ctx.Done():
+		return nil, false
+	case <-stop:
+		return nil, false
+	}
+}
+
+// parkForType занимает слот типа задачи, уже взятой под общий слот. Пока тип насыщен,
+// общий слот отпускается и берётся снова после слота типа: иначе задачи насыщенного типа
+// держали бы общие слоты и задерживали задачи остальных типов. Отпустивших слот задач
+// не больше, чем воркеров; сверх этого задача ждёт, не отпуская общий слот.
+// При false общий слот уже отпущен.
+func (p *workerPool) parkForType(ctx context.Context, stop <-chan struct{}, taskType TaskType) (release func(), ok bool) {
+	sem, limited := p.typeSlots[taskType]
+	if !limited {
+		return func() {}, true
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, true
+	default:
+	}
+
+	select {
+	case p.parked <- struct{}{}:
+	default:
+		// Ожидающих задач уже столько, сколько воркеров
+		if release, ok = p.acquireType(ctx, stop, taskType); !ok {
+			p.release()
+		}
+		return release, ok
+	}
+	defer func() { <-p.parked }()
+
+	p.release()
+	release, ok = p.acquireType(ctx, stop, taskType)
+	if !ok {
+		return nil, false
+	}
+	if !p.acquire(ctx, stop) {
+		release()
+		return nil, false
+	}
+	return release, true
+}
+
+// begin учитывает запускаемую задачу в wait; false - пул остановлен, и задачу нужно вернуть.
+// Add выполняется под той же блокировкой, что и остановка, поэтому не гонится с Wait
+func (p *workerPool) begin() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return false
+	}
+	p.inflight.Add(1)
+	return true
+}
+
+func (p *workerPool) done() {
+	p.inflight.Done()
+}
+
+// wait останавливает пул и ждёт завершения выполняющихся задач не дольше timeout
+func (p *workerPool) wait(timeout time.Duration) bool {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// requeue возвращает задачу, не выполненную из-за остановки, в её приоритетный список
+func (r *RedisQueue) requeue(task *Task, taskData string) {
+	// Подписочный контекст к этому моменту может быть уже отменён
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		data = []byte(taskData)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, r.queueFor(task.Priority), data)
+	pipe.LRem(ctx, r.processingQueue, 1, taskData)
+	pipe.ZRem(ctx, r.claimsKey(), taskData)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to requeue task %s on shutdown: %v", task.ID, err)
+		return
+	}
+
+	log.Printf("Task %s returned to the queue on shutdown", task.ID)
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const (
+	slowType TaskType = "slow"
+	fastType TaskType = "fast"
+)
+
+// TestParkForTypeFreesWorkerSlot проверяет, что задача насыщенного типа отпускает общий слот,
+// пока ждёт слот своего типа, и задачи других типов не стоят за ней
+func TestParkForTypeFreesWorkerSlot(t *testing.T) {
+	pool := newWorkerPool(2, map[TaskType]int{slowType: 1})
+	ctx := context.Background()
+	stop := make(chan struct{})
+
+	pool.acquire(ctx, stop)
+	releaseFirst, ok := pool.parkForType(ctx, stop, slowType)
+	if !ok {
+		t.Fatal("first slow task didn't get its type slot")
+	}
+
+	pool.acquire(ctx, stop)
+	parked := make(chan bool)
+	go func() {
+		release, ok := pool.parkForType(ctx, stop, slowType)
+		if ok {
+			release()
+			pool.release()
+		}
+		parked <- ok
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if !pool.acquire(waitCtx, stop) {
+		t.Fatal("fast task is blocked behind the saturated slow type")
+	}
+
+	// Первая медленная задача завершилась: вторая получает слот типа и ждёт общий слот
+	releaseFirst()
+	pool.release()
+
+	select {
+	case ok := <-parked:
+		if !ok {
+			t.Fatal("parked slow task wasn't resumed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("parked slow task didn't get its slots back")
+	}
+	pool.release()
+}
+
+// TestDispatcherTakesTypeSlotFirst проверяет, что задачи насыщенного типа не занимают
+// общие слоты, пока ждут слот своего типа
+func TestDispatcherTakesTypeSlotFirst(t *testing.T) {
+	d := newDispatcher(DispatchConfig{
+		Concurrency:     2,
+		TypeConcurrency: map[TaskType]int{slowType: 1},
+		DrainTimeout:    time.Second,
+	}, func(context.Context, *Task) error { return nil }, func(*Task, error) {})
+	defer d.close()
+
+	ctx := context.Background()
+	unblock := make(chan struct{})
+	slow := func(*Task) error {
+		<-unblock
+		return nil
+	}
+
+	results := make(chan deliveryResult, 4)
+	done := func(result deliveryResult) { results <- result }
+	for i := 0; i < 3; i++ {
+		d.dispatch(ctx, &Task{Type: slowType}, slow, done)
+	}
+
+	fastDone := make(chan struct{})
+	d.dispatch(ctx, &Task{Type: fastType}, func(*Task) error {
+		close(fastDone)
+		return nil
+	}, done)
+
+	select {
+	case <-fastDone:
+	case <-time.After(time.Second):
+		t.Fatal("fast task is blocked behind queued slow tasks")
+	}
+
+	close(unblock)
+	for i := 0; i < 4; i++ {
+		if result := <-results; result != deliveryDone {
+			t.Errorf("result = %v, want %v", result, deliveryDone)
+		}
+	}
+}
+
+// TestWorkerPoolBeginAfterWait проверяет, что после остановки пул не принимает новые задачи
+func TestWorkerPoolBeginAfterWait(t *testing.T) {
+	pool := newWorkerPool(1, nil)
+
+	if !pool.begin() {
+		t.Fatal("running pool rejected a task")
+	}
+	pool.done()
+
+	if !pool.wait(time.Second) {
+		t.Fatal("wait timed out with no running tasks")
+	}
+	if pool.begin() {
+		t.Error("stopped pool accepted a task")
+	}
+}
@@ -1,27 +1,82 @@
-package redis
-
-import (
-	"fmt"
-	"log"
-
-	"github.com/ds124wfegd/WB_L3/5/config"
-	"github.com/go-redis/redis/v8"
-)
-
-func NewRedisClient(cfg *config.RedisConfig) *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		MaxRetries:   cfg.MaxRetries,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdleConns,
-		DialTimeout:  cfg.DialTimeout,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		PoolTimeout:  cfg.PoolTimeout,
-	})
-
-	log.Println("Successfully connected to Redis")
-	return client
-}
+package redis
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ds124wfegd/WB_L3/5/config"
+	"github.com/go-redis/redis/v8"
+)
+
+// NewRedisClient создаёт клиента для режима из redis.mode. Для sentinel это клиент
+// с переключением на нового мастера после failover, для cluster - клиент, который
+// следит за слотами и перенаправлениями MOVED/ASK. Репозитории и очередь работают
+// через redis.UniversalClient и не зависят от режима.
+func NewRedisClient(cfg *config.RedisConfig) redis.UniversalClient {
+	var client redis.UniversalClient
+
+	switch cfg.Mode {
+	case config.RedisModeSentinel:
+		opts := &redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			RouteByLatency:   cfg.RouteByLatency,
+			RouteRandomly:    cfg.RouteRandomly,
+			MaxRetries:       cfg.MaxRetries,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+			PoolTimeout:      cfg.PoolTimeout,
+		}
+		// Чтение с реплик умеет только клиент поверх всех узлов группы; обычный
+		// клиент сентинела ходит к мастеру и переподключается к новому после failover
+		if cfg.RouteByLatency || cfg.RouteRandomly {
+			client = redis.NewFailoverClusterClient(opts)
+		} else {
+			client = redis.NewFailoverClient(opts)
+		}
+	case config.RedisModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:          cfg.Addrs,
+			Password:       cfg.Password,
+			ReadOnly:       cfg.ReadOnly,
+			RouteByLatency: cfg.RouteByLatency,
+			RouteRandomly:  cfg.RouteRandomly,
+			MaxRetries:     cfg.MaxRetries,
+			PoolSize:       cfg.PoolSize,
+			MinIdleConns:   cfg.MinIdleConns,
+			DialTimeout:    cfg.DialTimeout,
+			ReadTimeout:    cfg.ReadTimeout,
+			WriteTimeout:   cfg.WriteTimeout,
+			PoolTimeout:    cfg.PoolTimeout,
+		})
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			MaxRetries:   cfg.MaxRetries,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			PoolTimeout:  cfg.PoolTimeout,
+		})
+	}
+
+	log.Printf("Successfully connected to Redis (%s)", mode(cfg))
+	return client
+}
+
+func mode(cfg *config.RedisConfig) string {
+	if cfg.Mode == "" {
+		return config.RedisModeSingle
+	}
+	return cfg.Mode
+}
@@ -0,0 +1,94 @@
+// Package requestid передаёт идентификатор запроса через контекст, журнал и задачи очереди,
+// чтобы HTTP-запрос, его запросы к БД и порождённые им задачи находились в логах по одному ID.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Header - заголовок, в котором ID приходит от клиента или балансировщика и возвращается в ответе
+	Header = "X-Request-ID"
+	// Field - имя поля в журнале и ключ в Task.Data
+	Field = "request_id"
+
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New создаёт случайный ID из 32 шестнадцатеричных символов
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid проверяет ID, пришедший извне: он попадает в журнал, задачи и комментарии SQL,
+// поэтому допускаются только буквы, цифры и -_.: и не длиннее 128 символов
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext возвращает ID запроса или пустую строку, если его нет
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Entry - запись журнала с полем request_id, если ID есть в контексте
+func Entry(ctx context.Context) *logrus.Entry {
+	if id := FromContext(ctx); id != "" {
+		return logrus.WithField(Field, id)
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// FromTaskData достаёт ID из данных задачи очереди; чужое значение отбрасывается
+func FromTaskData(data map[string]interface{}) string {
+	id, _ := data[Field].(string)
+	if !Valid(id) {
+		return ""
+	}
+	return id
+}
+
+// SetTaskData записывает ID из контекста в данные задачи, не заменяя уже записанный:
+// повторно опубликованная задача сохраняет ID исходного запроса
+func SetTaskData(ctx context.Context, data map[string]interface{}) map[string]interface{} {
+	id := FromContext(ctx)
+	if id == "" {
+		return data
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if _, ok := data[Field]; !ok {
+		data[Field] = id
+	}
+	return data
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	cases := map[string]bool{
+		New():                    true,
+		"a1b2-c3_d4.e5:f6":       true,
+		"":                       false,
+		"id */ DROP TABLE users": false,
+		"id\nforged log line":    false,
+		strings.Repeat("a", 129): false,
+	}
+	for id, want := range cases {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// TestTaskData проверяет, что ID переходит из контекста в задачу и обратно,
+// а уже записанный в задаче ID не перезаписывается
+func TestTaskData(t *testing.T) {
+	ctx := WithID(context.Background(), "req-1")
+
+	data := SetTaskData(ctx, nil)
+	if got := FromTaskData(data); got != "req-1" {
+		t.Fatalf("FromTaskData = %q, want req-1", got)
+	}
+
+	data = SetTaskData(WithID(context.Background(), "req-2"), data)
+	if got := FromTaskData(data); got != "req-1" {
+		t.Fatalf("FromTaskData after republish = %q, want req-1", got)
+	}
+
+	if got := FromTaskData(map[string]interface{}{Field: "bad id"}); got != "" {
+		t.Fatalf("FromTaskData accepted invalid id %q", got)
+	}
+}
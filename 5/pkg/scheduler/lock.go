@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Locker выдаёт эксклюзивное право на выполнение периодической задачи одному экземпляру
+type Locker interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Extend(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key string) error
+}
+
+// Снимать и продлевать блокировку может только её владелец,
+// иначе экземпляр с истёкшей блокировкой удалил бы чужую
+var (
+	releaseScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`)
+	extendScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+)
+
+// RedisLock - блокировка на SET NX PX с уникальным токеном владельца
+type RedisLock struct {
+	client redis.UniversalClient
+	owner  string
+}
+
+func NewRedisLock(client redis.UniversalClient) *RedisLock {
+	return &RedisLock{client: client, owner: newOwnerID()}
+}
+
+func (l *RedisLock) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := l.client.SetNX(ctx, key, l.owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+func (l *RedisLock) Extend(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	res, err := extendScript.Run(ctx, l.client, []string{key}, l.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend lock %s: %w", key, err)
+	}
+	return res == 1, nil
+}
+
+func (l *RedisLock) Release(ctx context.Context, key string) error {
+	if err := releaseScript.Run(ctx, l.client, []string{key}, l.owner).Err(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", key, err)
+	}
+	return nil
+}
+
+// RunExclusive выполняет fn, только если удалось взять блокировку key.
+// Пока fn работает, блокировка продлевается; после завершения она не снимается,
+// а истекает сама, поэтому экземпляры, у которых тик наступил позже в том же
+// интервале, задачу пропускают. Без locker fn выполняется всегда.
+func RunExclusive(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context)) bool {
+	if locker == nil {
+		fn(ctx)
+		return true
+	}
+
+	acquired, err := locker.Acquire(ctx, key, ttl)
+	if err != nil {
+		// При недоступном Redis пропускаем тик: повторное истечение хуже задержки
+		logrus.Errorf("Skipping %s: %v", key, err)
+		return false
+	}
+	if !acquired {
+		logrus.Debugf("Skipping %s: lock is held by another instance", key)
+		return false
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keepLock(runCtx, locker, key, ttl, cancel)
+	}()
+
+	fn(runCtx)
+	cancel()
+	<-done
+	return true
+}
+
+// keepLock продлевает блокировку на треть TTL; если блокировку потеряли, задача отменяется
+func keepLock(ctx context.Context, locker Locker, key string, ttl time.Duration, cancel context.CancelFunc) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := locker.Extend(ctx, key, ttl)
+			if err != nil {
+				logrus.Warnf("Failed to extend lock %s: %v", key, err)
+				continue
+			}
+			if !ok {
+				logrus.Warnf("Lock %s lost, cancelling the running job", key)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", host, time.Now().UnixNano())
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,214 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return mr, client
+}
+
+// countingBookingService считает вызовы CancelExpiredBookings; остальные методы не используются
+type countingBookingService struct {
+	service.BookingService
+	calls atomic.Int32
+	delay time.Duration
+}
+
+func (s *countingBookingService) CancelExpiredBookings(ctx context.Context) error {
+	s.calls.Add(1)
+	if s.delay > 0 {
+		time.Sleep(s.delay)
+	}
+	return nil
+}
+
+func TestRedisLockAcquireIsExclusive(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+
+	first := NewRedisLock(client)
+	second := NewRedisLock(client)
+
+	ok, err := first.Acquire(ctx, "lock", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first Acquire = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, err = second.Acquire(ctx, "lock", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("second Acquire = %v, %v; want false, nil", ok, err)
+	}
+
+	// После истечения TTL блокировку может взять другой экземпляр
+	mr.FastForward(time.Minute + time.Second)
+
+	ok, err = second.Acquire(ctx, "lock", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("Acquire after expiry = %v, %v; want true, nil", ok, err)
+	}
+}
+
+func TestRedisLockReleaseOnlyByOwner(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+
+	owner := NewRedisLock(client)
+	other := NewRedisLock(client)
+
+	if ok, _ := owner.Acquire(ctx, "lock", time.Minute); !ok {
+		t.Fatal("owner failed to acquire lock")
+	}
+
+	if err := other.Release(ctx, "lock"); err != nil {
+		t.Fatalf("Release by other: %v", err)
+	}
+	if !mr.Exists("lock") {
+		t.Fatal("lock was released by a non-owner")
+	}
+
+	if err := owner.Release(ctx, "lock"); err != nil {
+		t.Fatalf("Release by owner: %v", err)
+	}
+	if mr.Exists("lock") {
+		t.Fatal("lock still exists after owner released it")
+	}
+}
+
+func TestRedisLockExtendOnlyByOwner(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+
+	owner := NewRedisLock(client)
+	other := NewRedisLock(client)
+
+	if ok, _ := owner.Acquire(ctx, "lock", 10*time.Second); !ok {
+		t.Fatal("owner failed to acquire lock")
+	}
+
+	ok, err := other.Extend(ctx, "lock", time.Hour)
+	if err != nil || ok {
+		t.Fatalf("Extend by other = %v, %v; want false, nil", ok, err)
+	}
+
+	ok, err = owner.Extend(ctx, "lock", time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("Extend by owner = %v, %v; want true, nil", ok, err)
+	}
+	if ttl := mr.TTL("lock"); ttl != time.Hour {
+		t.Fatalf("TTL after extend = %v, want %v", ttl, time.Hour)
+	}
+}
+
+// TestSchedulerSingleRunPerTick имитирует несколько реплик, у которых тик наступил одновременно
+func TestSchedulerSingleRunPerTick(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+
+	bookings := &countingBookingService{delay: 20 * time.Millisecond}
+	replicas := make([]*Scheduler, 5)
+	for i := range replicas {
+		replicas[i] = NewScheduler(bookings, time.Minute, NewRedisLock(client))
+	}
+
+	runTick := func() int32 {
+		var wg sync.WaitGroup
+		var ran atomic.Int32
+		for _, s := range replicas {
+			wg.Add(1)
+			go func(s *Scheduler) {
+				defer wg.Done()
+				if s.tick(ctx) {
+					ran.Add(1)
+				}
+			}(s)
+		}
+		wg.Wait()
+		return ran.Load()
+	}
+
+	if ran := runTick(); ran != 1 {
+		t.Fatalf("first tick ran on %d replicas, want 1", ran)
+	}
+
+	// Реплика, чей тик наступил позже в том же интервале, задачу пропускает
+	if ran := runTick(); ran != 0 {
+		t.Fatalf("repeated tick within interval ran on %d replicas, want 0", ran)
+	}
+
+	mr.FastForward(LockTTL(time.Minute))
+
+	if ran := runTick(); ran != 1 {
+		t.Fatalf("next interval ran on %d replicas, want 1", ran)
+	}
+	if calls := bookings.calls.Load(); calls != 2 {
+		t.Fatalf("CancelExpiredBookings called %d times, want 2", calls)
+	}
+}
+
+func TestSchedulerSkipsTickWhenRedisUnavailable(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.Close()
+
+	bookings := &countingBookingService{}
+	s := NewScheduler(bookings, time.Minute, NewRedisLock(client))
+
+	if s.tick(context.Background()) {
+		t.Fatal("tick ran without a lock")
+	}
+	if calls := bookings.calls.Load(); calls != 0 {
+		t.Fatalf("CancelExpiredBookings called %d times, want 0", calls)
+	}
+}
+
+func TestSchedulerWithoutLockerAlwaysRuns(t *testing.T) {
+	bookings := &countingBookingService{}
+	s := NewScheduler(bookings, time.Minute, nil)
+
+	s.tick(context.Background())
+	s.tick(context.Background())
+
+	if calls := bookings.calls.Load(); calls != 2 {
+		t.Fatalf("CancelExpiredBookings called %d times, want 2", calls)
+	}
+}
+
+// healthGate - результат проверки зависимостей, который переключает тест
+type healthGate struct {
+	down atomic.Bool
+}
+
+func (g *healthGate) Healthy(names ...string) bool {
+	return !g.down.Load()
+}
+
+func TestSchedulerSkipsTickWhileDependencyDown(t *testing.T) {
+	bookings := &countingBookingService{}
+	gate := &healthGate{}
+	s := NewScheduler(bookings, time.Minute, nil).RequireHealthy(gate, "postgres")
+
+	gate.down.Store(true)
+	if s.tick(context.Background()) {
+		t.Fatal("tick ran while postgres is down")
+	}
+
+	gate.down.Store(false)
+	s.tick(context.Background())
+	if calls := bookings.calls.Load(); calls != 1 {
+		t.Fatalf("CancelExpiredBookings called %d times, want 1", calls)
+	}
+}
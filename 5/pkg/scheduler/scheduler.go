@@ -1,37 +1,75 @@
-package scheduler
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	"github.com/ds124wfegd/WB_L3/5/internal/service"
-)
-
-type Scheduler struct {
-	bookingService service.BookingService
-	interval       time.Duration
-}
-
-func NewScheduler(bookingService service.BookingService, interval time.Duration) *Scheduler {
-	return &Scheduler{
-		bookingService: bookingService,
-		interval:       interval,
-	}
-}
-
-func (s *Scheduler) Start(ctx context.Context) {
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			if err := s.bookingService.CancelExpiredBookings(ctx); err != nil {
-				fmt.Printf("Error canceling expired bookings: %v\n", err)
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
-}
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ds124wfegd/WB_L3/5/internal/service"
+)
+
+// ExpireBookingsLockKey - блокировка, под которой выполняется тик планировщика истечения
+const ExpireBookingsLockKey = "event_booking:lock:expire_bookings"
+
+// HealthGate сообщает, доступны ли зависимости по результатам последней проверки
+type HealthGate interface {
+	Healthy(names ...string) bool
+}
+
+type Scheduler struct {
+	bookingService service.BookingService
+	interval       time.Duration
+	locker         Locker
+
+	health     HealthGate
+	healthDeps []string
+}
+
+// NewScheduler создаёт планировщик; с locker тик выполняет только один экземпляр из всех реплик
+func NewScheduler(bookingService service.BookingService, interval time.Duration, locker Locker) *Scheduler {
+	return &Scheduler{
+		bookingService: bookingService,
+		interval:       interval,
+		locker:         locker,
+	}
+}
+
+// RequireHealthy пропускает тики, пока недоступна любая из deps: незачем брать блокировку
+// и сыпать ошибками, когда база лежит. Пропущенное доделает первый тик после восстановления.
+func (s *Scheduler) RequireHealthy(gate HealthGate, deps ...string) *Scheduler {
+	s.health = gate
+	s.healthDeps = deps
+	return s
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.tick(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tick отменяет истёкшие бронирования, если блокировку этого интервала взял текущий экземпляр.
+// TTL чуть меньше интервала, чтобы следующий тик того же экземпляра снова мог её взять.
+func (s *Scheduler) tick(ctx context.Context) bool {
+	if s.health != nil && !s.health.Healthy(s.healthDeps...) {
+		return false
+	}
+	return RunExclusive(ctx, s.locker, ExpireBookingsLockKey, LockTTL(s.interval), func(ctx context.Context) {
+		if err := s.bookingService.CancelExpiredBookings(ctx); err != nil {
+			fmt.Printf("Error canceling expired bookings: %v\n", err)
+		}
+	})
+}
+
+// LockTTL возвращает TTL блокировки периодической задачи с заданным интервалом
+func LockTTL(interval time.Duration) time.Duration {
+	return interval * 9 / 10
+}
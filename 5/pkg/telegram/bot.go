@@ -1,39 +1,140 @@
-package telegram
-
-import (
-	"fmt"
-	"net/http"
-	"net/url"
-)
-
-type Bot struct {
-	token   string
-	baseURL string
-}
-
-func NewBot(token string) *Bot {
-	return &Bot{
-		token:   token,
-		baseURL: "https://api.telegram.org/bot" + token,
-	}
-}
-
-func (b *Bot) SendMessage(chatID, text string) error {
-	endpoint := b.baseURL + "/sendMessage"
-
-	params := url.Values{}
-	params.Add("chat_id", chatID)
-	params.Add("text", text)
-
-	resp, err := http.PostForm(endpoint, params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API error: %s", resp.Status)
-	}
-
-	return nil
-}
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Bot struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+func NewBot(token string) *Bot {
+	return &Bot{
+		token:   token,
+		baseURL: "https://api.telegram.org/bot" + token,
+		// Таймаут больше самого длинного long polling запроса
+		client: &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+func (b *Bot) SendMessage(chatID, text string) error {
+	endpoint := b.baseURL + "/sendMessage"
+
+	params := url.Values{}
+	params.Add("chat_id", chatID)
+	params.Add("text", text)
+
+	resp, err := b.client.PostForm(endpoint, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram API error: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SendMessageWithKeyboard отправляет сообщение с inline-кнопками под ним
+func (b *Bot) SendMessageWithKeyboard(chatID, text string, keyboard *InlineKeyboardMarkup) error {
+	return b.call(context.Background(), "sendMessage", map[string]interface{}{
+		"chat_id":      chatID,
+		"text":         text,
+		"reply_markup": keyboard,
+	}, nil)
+}
+
+// AnswerCallbackQuery отвечает на нажатие кнопки; без ответа клиент показывает индикатор загрузки
+func (b *Bot) AnswerCallbackQuery(callbackID, text string, alert bool) error {
+	return b.call(context.Background(), "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackID,
+		"text":              text,
+		"show_alert":        alert,
+	}, nil)
+}
+
+// EditMessageReplyMarkup заменяет кнопки отправленного сообщения; nil убирает их
+func (b *Bot) EditMessageReplyMarkup(chatID, messageID int64, keyboard *InlineKeyboardMarkup) error {
+	if keyboard == nil {
+		keyboard = &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{}}
+	}
+	return b.call(context.Background(), "editMessageReplyMarkup", map[string]interface{}{
+		"chat_id":      chatID,
+		"message_id":   messageID,
+		"reply_markup": keyboard,
+	}, nil)
+}
+
+// GetMe проверяет токен и доступность Telegram Bot API
+func (b *Bot) GetMe(ctx context.Context) error {
+	return b.call(ctx, "getMe", map[string]interface{}{}, nil)
+}
+
+// GetUpdates ждёт новые обновления до timeout (long polling)
+func (b *Bot) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	var updates []Update
+	err := b.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+// SetWebhook просит Telegram присылать обновления на url; secret возвращается
+// в заголовке X-Telegram-Bot-Api-Secret-Token каждого запроса
+func (b *Bot) SetWebhook(webhookURL, secret string) error {
+	return b.call(context.Background(), "setWebhook", map[string]interface{}{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message", "callback_query"},
+	}, nil)
+}
+
+// DeleteWebhook отключает webhook: пока он задан, getUpdates не работает
+func (b *Bot) DeleteWebhook() error {
+	return b.call(context.Background(), "deleteWebhook", map[string]interface{}{}, nil)
+}
+
+// call вызывает метод Bot API с JSON-телом и раскладывает поле result в result
+func (b *Bot) call(ctx context.Context, method string, payload interface{}, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("telegram API error: %s", resp.Status)
+	}
+	if !apiResp.OK {
+		return &APIError{Method: method, Code: apiResp.ErrorCode, Description: apiResp.Description}
+	}
+
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+	return nil
+}
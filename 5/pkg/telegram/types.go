@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Типы Bot API, нужные сервису; остальные поля Telegram отбрасываются при разборе
+
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type Message struct {
+	MessageID   int64                 `json:"message_id"`
+	From        *User                 `json:"from,omitempty"`
+	Chat        Chat                  `json:"chat"`
+	Text        string                `json:"text,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// Command возвращает команду сообщения без упоминания бота ("/events@my_bot" -> "/events") и её аргументы
+func (m *Message) Command() (string, string) {
+	if !strings.HasPrefix(m.Text, "/") {
+		return "", ""
+	}
+
+	command, args, _ := strings.Cut(m.Text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// CallbackQuery - нажатие inline-кнопки; Data - callback_data этой кнопки
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	ErrorCode   int             `json:"error_code,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+// APIError - ответ Bot API с ok=false
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram API error: %s: %d %s", e.Method, e.Code, e.Description)
+}
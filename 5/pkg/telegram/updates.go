@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UpdateHandler обрабатывает одно обновление от Telegram
+type UpdateHandler func(ctx context.Context, update *Update)
+
+const pollRetryDelay = 5 * time.Second
+
+// Poll получает обновления long polling и передаёт их handler по одному до отмены ctx.
+// Telegram отдаёт обновления только одному получателю, поэтому опрашивать бота
+// должен один экземпляр сервиса; нескольким нужен webhook.
+func (b *Bot) Poll(ctx context.Context, timeout time.Duration, handler UpdateHandler) {
+	if err := b.DeleteWebhook(); err != nil {
+		logrus.Warnf("Failed to delete Telegram webhook before polling: %v", err)
+	}
+
+	logrus.Info("Telegram long polling started")
+
+	var offset int64
+	for {
+		updates, err := b.GetUpdates(ctx, offset, timeout)
+		if ctx.Err() != nil {
+			logrus.Info("Telegram long polling stopped")
+			return
+		}
+		if err != nil {
+			logrus.Errorf("Failed to get Telegram updates: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+
+		for i := range updates {
+			handler(ctx, &updates[i])
+			offset = updates[i].UpdateID + 1
+		}
+	}
+}
+
+// WebhookHandler принимает обновления, которые Telegram присылает на адрес из SetWebhook.
+// Запросы без секрета, переданного в SetWebhook, отклоняются.
+func WebhookHandler(secret string, handler UpdateHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var update Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler(r.Context(), &update)
+		w.WriteHeader(http.StatusOK)
+	})
+}
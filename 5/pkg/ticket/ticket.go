@@ -0,0 +1,88 @@
+package ticket
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	ContentType = "image/png"
+
+	// prefix отличает билеты от других QR-кодов и позволяет сменить формат без путаницы
+	prefix = "EB1"
+	// DefaultSize - сторона PNG в пикселях; крупнее, чем нужно сканеру на входе, чтобы печатался без потерь
+	DefaultSize = 512
+)
+
+// ErrInvalidTicket - содержимое QR-кода не является билетом или подпись не сходится
+var ErrInvalidTicket = errors.New("invalid ticket")
+
+// Payload - то, что зашито в QR-код: бронирование и код, выданный ему при подтверждении.
+// Код хранится в БД, поэтому билет нельзя собрать по одному номеру бронирования.
+type Payload struct {
+	BookingID int64
+	Code      string
+}
+
+// Signer подписывает билеты HMAC-SHA256, так что подделать билет без секрета нельзя
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) *Signer {
+	return &Signer{secret: []byte(secret)}
+}
+
+// Sign возвращает строку для QR-кода вида "EB1.<бронирование>.<код>.<подпись>"
+func (s *Signer) Sign(p Payload) string {
+	body := fmt.Sprintf("%s.%d.%s", prefix, p.BookingID, p.Code)
+	return body + "." + s.mac(body)
+}
+
+// Verify проверяет подпись и разбирает содержимое QR-кода
+func (s *Signer) Verify(token string) (Payload, error) {
+	token = strings.TrimSpace(token)
+	body, sig, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(body))) {
+		return Payload{}, ErrInvalidTicket
+	}
+
+	parts := strings.SplitN(body, ".", 3)
+	if len(parts) != 3 || parts[0] != prefix || parts[2] == "" {
+		return Payload{}, ErrInvalidTicket
+	}
+	bookingID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || bookingID <= 0 {
+		return Payload{}, ErrInvalidTicket
+	}
+
+	return Payload{BookingID: bookingID, Code: parts[2]}, nil
+}
+
+func (s *Signer) mac(body string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("ticket:" + body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// PNG рисует QR-код с содержимым token
+func PNG(token string, size int) ([]byte, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return qrcode.Encode(token, qrcode.Medium, size)
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
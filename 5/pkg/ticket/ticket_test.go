@@ -0,0 +1,49 @@
+package ticket
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	signer := NewSigner("secret")
+	want := Payload{BookingID: 42, Code: "0b7c9f1e-3a52-4d7e-9a8b-1f2e3d4c5b6a"}
+
+	got, err := signer.Verify(signer.Sign(want))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != want {
+		t.Fatalf("Verify = %+v; want %+v", got, want)
+	}
+}
+
+func TestVerifyRejectsForgedTickets(t *testing.T) {
+	signer := NewSigner("secret")
+	token := signer.Sign(Payload{BookingID: 42, Code: "code"})
+
+	cases := map[string]string{
+		"other secret":   NewSigner("other").Sign(Payload{BookingID: 42, Code: "code"}),
+		"other booking":  "EB1.43.code." + token[len("EB1.42.code."):],
+		"missing parts":  "EB1.42",
+		"empty":          "",
+		"not a ticket":   "https://example.com",
+		"truncated sign": token[:len(token)-1],
+	}
+	for name, forged := range cases {
+		if _, err := signer.Verify(forged); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("%s: Verify error = %v; want ErrInvalidTicket", name, err)
+		}
+	}
+}
+
+func TestPNG(t *testing.T) {
+	png, err := PNG(NewSigner("secret").Sign(Payload{BookingID: 1, Code: "code"}), 0)
+	if err != nil {
+		t.Fatalf("PNG: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Fatalf("PNG did not return a PNG image")
+	}
+}
@@ -0,0 +1,160 @@
+// Package tracing настраивает OpenTelemetry: экспорт трасс по OTLP/HTTP и перенос контекста
+// трассировки через HTTP-заголовки и данные задач очереди, чтобы запрос, его запросы к БД
+// и порождённые им задачи складывались в одну трассу.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TaskDataKey - ключ Task.Data с заголовками W3C Trace Context задачи
+	TaskDataKey = "trace_context"
+
+	instrumentationName = "github.com/ds124wfegd/WB_L3/5"
+	defaultServiceName  = "event-booker"
+)
+
+// Options - настройки экспорта трасс
+type Options struct {
+	Enabled        bool
+	Endpoint       string // host:port коллектора OTLP/HTTP; пусто - OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4318
+	Insecure       bool   // без TLS, для коллектора в той же сети
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	SampleRatio    float64 // доля новых трасс; 0 или 1 - все, входящая трасса сохраняет решение вызывающего
+}
+
+// Init ставит глобальные провайдер трасс и пропагатор и возвращает функцию, которая
+// отправляет накопленные spans и останавливает экспорт. С выключенным экспортом
+// трассы не пишутся, но контекст из входящих заголовков по-прежнему передаётся дальше.
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !opts.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracehttp.Option{}
+	if opts.Endpoint != "" {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+	}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	attrs := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if opts.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(opts.ServiceVersion))
+	}
+	if opts.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentName(opts.Environment))
+	}
+	res, err := resource.New(ctx, resource.WithTelemetrySDK(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if opts.SampleRatio > 0 && opts.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик сервиса; до Init он ничего не записывает
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError отмечает span ошибкой; nil ничего не меняет
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// StartPublish открывает span публикации задачи и записывает его контекст в данные задачи,
+// чтобы обработка продолжила трассу. Задача из outbox публикуется без контекста запроса,
+// но уже несёт его в данных: тогда span продолжает трассу запроса, создавшего задачу.
+func StartPublish(ctx context.Context, taskID, taskType string, data map[string]interface{}) (context.Context, trace.Span, map[string]interface{}) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = ExtractTask(ctx, data)
+	}
+
+	ctx, span := Tracer().Start(ctx, "publish "+taskType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(taskType),
+			semconv.MessagingMessageID(taskID),
+		),
+	)
+	return ctx, span, InjectTask(ctx, data)
+}
+
+// InjectTask записывает контекст трассировки из ctx в данные задачи, заменяя прежний
+func InjectTask(ctx context.Context, data map[string]interface{}) map[string]interface{} {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return data
+	}
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data[TaskDataKey] = map[string]string(carrier)
+	return data
+}
+
+// ExtractTask возвращает ctx с контекстом трассировки из данных задачи. После JSON
+// заголовки приходят как map[string]interface{}, до него - как map[string]string.
+func ExtractTask(ctx context.Context, data map[string]interface{}) context.Context {
+	carrier := propagation.MapCarrier{}
+	switch headers := data[TaskDataKey].(type) {
+	case map[string]string:
+		for k, v := range headers {
+			carrier[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range headers {
+			if s, ok := v.(string); ok {
+				carrier[k] = s
+			}
+		}
+	default:
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
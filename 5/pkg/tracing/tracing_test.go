@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTaskContextSurvivesJSON проверяет, что обработчик задачи продолжает трассу
+// публикации после того, как задача прошла через очередь в JSON
+func TestTaskContextSurvivesJSON(t *testing.T) {
+	if _, err := Init(context.Background(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	ctx, request := Tracer().Start(context.Background(), "request")
+	defer request.End()
+
+	_, publish, data := StartPublish(ctx, "task-1", "send_email", map[string]interface{}{"booking_id": 1})
+	publish.End()
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	got := trace.SpanContextFromContext(ExtractTask(context.Background(), decoded))
+	if got.TraceID() != request.SpanContext().TraceID() {
+		t.Fatalf("trace ID = %s, want %s", got.TraceID(), request.SpanContext().TraceID())
+	}
+	if got.SpanID() != publish.SpanContext().SpanID() {
+		t.Fatalf("parent span = %s, want the publish span %s", got.SpanID(), publish.SpanContext().SpanID())
+	}
+}
+
+// TestStartPublishContinuesStoredTrace - задача из outbox публикуется без контекста
+// запроса и продолжает трассу, записанную в её данных
+func TestStartPublishContinuesStoredTrace(t *testing.T) {
+	if _, err := Init(context.Background(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	ctx, request := Tracer().Start(context.Background(), "request")
+	request.End()
+	data := InjectTask(ctx, nil)
+
+	_, publish, _ := StartPublish(context.Background(), "task-1", "send_email", data)
+	publish.End()
+
+	if publish.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Fatalf("publish trace ID = %s, want %s", publish.SpanContext().TraceID(), request.SpanContext().TraceID())
+	}
+}
+
+func TestExtractTaskWithoutContext(t *testing.T) {
+	ctx := ExtractTask(context.Background(), map[string]interface{}{TaskDataKey: "garbage"})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("expected no span context")
+	}
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	maxResponseBody = 1 << 10
+)
+
+// Request описывает одну доставку: куда, чем подписать и что отправить
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+// Result - итог доставки после всех попыток
+type Result struct {
+	Attempts     int
+	StatusCode   int
+	ResponseBody string
+	Duration     time.Duration
+}
+
+// Sender отправляет подписанные POST-запросы и повторяет их при сетевых ошибках,
+// ответах 5xx и 429 с экспоненциальной задержкой
+type Sender struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+func NewSender(timeout time.Duration, maxAttempts int, baseDelay time.Duration) *Sender {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	if baseDelay <= 0 {
+		baseDelay = time.Second
+	}
+
+	return &Sender{
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+}
+
+// Sign возвращает подпись sha256=<hex(HMAC-SHA256(secret, timestamp + "." + body))>.
+// Получатель должен пересчитать её и отбросить запросы со старым timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Sender) Send(ctx context.Context, req *Request) (*Result, error) {
+	start := time.Now()
+	result := &Result{}
+
+	var lastErr error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		result.Attempts = attempt
+
+		statusCode, body, err := s.post(ctx, req)
+		result.StatusCode = statusCode
+		result.ResponseBody = body
+
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("unexpected response status %d", statusCode)
+		}
+
+		if err == nil && !retryable(statusCode) {
+			break
+		}
+
+		if attempt < s.maxAttempts {
+			delay := s.baseDelay * time.Duration(1<<(attempt-1))
+			select {
+			case <-ctx.Done():
+				result.Duration = time.Since(start)
+				return result, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result, lastErr
+}
+
+func (s *Sender) post(ctx context.Context, req *Request) (int, string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	// timestamp обновляется на каждой попытке, чтобы повтор не выглядел как replay
+	timestamp := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "EventBooker-Webhook/1.0")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}